@@ -1,110 +1,399 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/audit"
+	"github.com/ahsmha/discounts/internal/auth"
+	"github.com/ahsmha/discounts/internal/catalog"
+	"github.com/ahsmha/discounts/internal/config"
+	"github.com/ahsmha/discounts/internal/demo"
+	"github.com/ahsmha/discounts/internal/edgesync"
+	"github.com/ahsmha/discounts/internal/health"
 	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/qos"
+	"github.com/ahsmha/discounts/internal/recording"
+	"github.com/ahsmha/discounts/internal/reports"
 	"github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/resilience"
 	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/breaker"
+	"github.com/ahsmha/discounts/pkg/clock"
 	"github.com/ahsmha/discounts/testdata"
-	"github.com/ahsmha/discounts/tests"
 )
 
 func main() {
-	repo := repositories.NewInMemoryDiscountRepository()
-	memoryRepo, ok := repo.(interfaces.DiscountSeeder)
+	configFile := flag.String("config", os.Getenv("DISCOUNTS_CONFIG"), "YAML file configuring the server, see internal/config; DISCOUNTS_* environment variables and the flags below override it")
+	httpAddr := flag.String("http", ":8080", "serve the HTTP API on this address")
+	redisAddr := flag.String("redis", "", "track discount spend caps in Redis at this address")
+	demoMode := flag.Bool("demo", false, "seed the demo catalog, price at a fixed instant and serve the demo scenarios under /demo/")
+	auditLog := flag.String("audit-log", "", "append discount changes and applications to this file as JSON lines (- for stdout) and serve them under /admin/audit")
+	recordTo := flag.String("record", "", "record sampled API requests and responses, with personal data redacted, to this file as JSON lines (- for stdout)")
+	recordSample := flag.Float64("record-sample", 0.01, "fraction of API requests recorded with -record")
+	lanes := flag.Bool("qos", false, "run checkouts and batch work on separate worker pools and serve lane metrics under /admin/qos")
+	jobs := flag.Bool("jobs", false, "serve background pricing jobs under /v2/jobs, kept in Redis with -redis so they resume after a restart")
+	editors := flag.String("editors", "", "comma-separated actors allowed to submit discounts for approval; with -approvers serves lifecycle transitions under /v2/discounts/{id}/transitions")
+	approvers := flag.String("approvers", "", "comma-separated actors allowed to approve, reject, pause, resume and expire discounts")
+	trustedGateway := flag.Bool("trusted-gateway", false, "take who a request acts for from the X-Actor-ID header, for servers reachable only through a gateway that authenticates callers and sets it")
+	apiKeys := flag.String("api-keys", "", "JSON file mapping API keys to principals ({\"key\": {\"id\": \"alice\", \"roles\": [\"admin\"]}}) required by the admin APIs")
+	jwtSecretFile := flag.String("jwt-secret-file", "", "file holding the secret of the HS256 JWTs accepted by the admin APIs")
+	minMargin := flag.String("min-margin", "", "never let discounts take a unit below its cost plus this percentage of it")
+	reportsFlag := flag.Bool("reports", false, "record experiment assignments and redemptions in memory, take placed orders and serve experiment and partner code reports under /admin/reports/")
+	pricingRecords := flag.Bool("pricing-records", false, "keep the pricing record of every order priced with an order id and serve their verification under /v2/orders/{id}/pricing/verification")
+	legacyPrices := flag.Bool("legacy-current-prices", false, "price carts from the current_price callers send, for callers that still take brand discounts off it themselves, instead of from base_price")
+	checkInvariants := flag.Bool("check-invariants", false, "debug: verify every calculation against the pricing invariants and log the ones that break them")
+	resilient := flag.Bool("resilience", false, "bound discount repository calls with timeouts, retry failed reads and stop calling a failing repository for a while, serving the counters under /admin/resilience")
+	syncFeed := flag.Bool("sync-feed", false, "serve discount changes to edge stores and take their usage under /sync/")
+	syncFrom := flag.String("sync-from", "", "run as an edge store: price from a local copy of the discounts served by the -sync-feed server at this URL, syncing every -sync-interval")
+	syncInterval := flag.Duration("sync-interval", 30*time.Second, "how often an edge store started with -sync-from syncs")
+	syncTokenFile := flag.String("sync-token-file", "", "file holding the API key or JWT, with the sync role, an edge store started with -sync-from authenticates to the feed with")
+	discountsDir := flag.String("discounts-dir", "", "serve the discounts defined in this directory's YAML and JSON files instead of the sample catalog, reloading them as the files change")
+	flag.Parse()
 
-	if !ok {
-		log.Fatal("Repository does not support seeding")
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "http":
+			cfg.HTTP.Addr = *httpAddr
+		case "redis":
+			cfg.Redis.Addr = *redisAddr
+		case "discounts-dir":
+			cfg.Repository.Backend, cfg.Repository.Dir = config.BackendFile, *discountsDir
+		case "min-margin":
+			if err := cfg.SetMinMargin(*minMargin); err != nil {
+				log.Fatalf("Invalid -min-margin: %v", err)
+			}
+		}
+	})
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
 	}
 
-	err := memoryRepo.SeedDiscounts(testdata.GetSampleDiscounts())
-	if err != nil {
-		log.Fatalf("Failed to seed discounts: %v", err)
+	ctx := context.Background()
+
+	seed := testdata.GetSampleDiscounts()
+	if *demoMode {
+		seed = demo.Discounts()
 	}
 
-	discountService := services.NewDiscountService(repo)
+	var repo interfaces.IDiscountRepository
+	switch cfg.Repository.Backend {
+	case config.BackendFile:
+		dir := cfg.Repository.Dir
+		fileRepo, err := repositories.NewFileDiscountRepository(dir)
+		if err != nil {
+			log.Fatalf("Failed to load discounts: %v", err)
+		}
+		fileRepo.OnReload = func(discounts int) {
+			log.Printf("Reloaded %d discounts from %s", discounts, dir)
+		}
+		fileRepo.OnError = func(err error) {
+			log.Printf("Kept the current discounts, reloading %s failed: %v", dir, err)
+		}
+		go func() {
+			if err := fileRepo.Watch(ctx); err != nil {
+				log.Printf("Stopped watching %s: %v", dir, err)
+			}
+		}()
+		repo = fileRepo
+		seed = nil
+	case config.BackendMemory:
+		repo = repositories.NewInMemoryDiscountRepository()
+		seed = nil
+	default:
+		repo = repositories.NewInMemoryDiscountRepository()
+		memoryRepo, ok := repo.(interfaces.DiscountSeeder)
 
-	runMultipleDiscountScenarioDemo(discountService)
-}
+		if !ok {
+			log.Fatal("Repository does not support seeding")
+		}
 
-func runMultipleDiscountScenarioDemo(discountService interfaces.IDiscountService) {
-	ctx := context.Background()
+		err = memoryRepo.SeedDiscounts(seed)
+		if err != nil {
+			log.Fatalf("Failed to seed discounts: %v", err)
+		}
+	}
 
-	fmt.Println("\n📋 Running Multiple Discount Scenario Demonstration")
-	fmt.Println("Scenario: PUMA T-shirt with brand, category, and bank discounts")
-	fmt.Println("---------------------------------------------------------------")
+	var guarded *resilience.Repository
+	if *resilient {
+		guarded = resilience.NewRepository(repo, resilience.DefaultConfig())
+		guarded.Breaker().OnStateChange = func(from, to breaker.State) {
+			log.Printf("Discount repository circuit breaker went from %s to %s", from, to)
+		}
+		repo = guarded
+	}
 
-	cartItems, customer, paymentInfo := testdata.GetMultipleDiscountScenario()
+	if *syncFrom != "" {
+		client := edgesync.Client{BaseURL: *syncFrom}
+		if *syncTokenFile != "" {
+			token, err := os.ReadFile(*syncTokenFile)
+			if err != nil {
+				log.Fatalf("Failed to read the sync token: %v", err)
+			}
+			client.Token = string(bytes.TrimSpace(token))
+		}
+		edge := edgesync.NewEdge(client)
+		edge.OnError = func(err error) {
+			log.Printf("Kept the local discounts, syncing from %s failed: %v", *syncFrom, err)
+		}
+		go edge.Run(ctx, *syncInterval)
+		repo = edge
+		seed = nil
+	}
 
-	// Reset the PUMA T-shirt price to base price for demonstration
-	tests.ResetCartPricesToBase(cartItems)
+	opts := cfg.ServiceOptions()
+	if ttl := time.Duration(cfg.Cache.IdempotencyTTL); ttl > 0 {
+		opts = append(opts, services.WithIdempotencyStore(repositories.NewInMemoryIdempotencyStore(ttl)))
+	}
+	var serverClock clock.Clock = clock.System()
+	var redisClient *redis.Client
+	var auditStore *audit.MemoryStore
+	var redemptions interfaces.IRedemptionRepository
+	if *auditLog != "" || *reportsFlag {
+		redemptions = repositories.NewInMemoryRedemptionRepository()
+	}
+	if *auditLog != "" {
+		sink := audit.NewJSONSink(os.Stdout)
+		if *auditLog != "-" {
+			if sink, err = audit.OpenFile(*auditLog); err != nil {
+				log.Fatal(err)
+			}
+			defer sink.Close()
+		}
+		auditStore = audit.NewMemoryStore()
+		logger := audit.NewLogger(sink, auditStore)
+		logger.OnError = func(event audit.Event, err error) {
+			log.Printf("Failed to write audit event %s of %s: %v", event.Action, event.DiscountID, err)
+		}
+		repo = audit.Discounts(repo, logger)
+		opts = append(opts, services.WithRedemptionRepository(audit.Redemptions(redemptions, logger)))
+	} else if redemptions != nil {
+		opts = append(opts, services.WithRedemptionRepository(redemptions))
+	}
+	var reporting interfaces.IReportingService
+	var orders interfaces.IOrderRepository
+	if *reportsFlag {
+		experiments := repositories.NewInMemoryExperimentRepository()
+		orders = repositories.NewInMemoryOrderRepository()
+		reporting = services.NewReportingService(experiments, orders, redemptions)
+		opts = append(opts, services.WithExperimentRepository(experiments))
+	}
+	var feed *edgesync.Feed
+	if *syncFeed {
+		feed = edgesync.NewFeed(repo)
+		repo = feed
+	}
+	if cfg.Redis.Addr != "" {
+		redisClient = redis.NewClient(&redis.Options{Addr: cfg.Redis.Addr})
+		if err := redisClient.Ping(ctx).Err(); err != nil {
+			log.Fatalf("Failed to connect to Redis: %v", err)
+		}
+		opts = append(opts, services.WithSpendTracker(repositories.NewRedisSpendTracker(redisClient, "discounts:")))
+	}
 
-	fmt.Printf("Cart Items:\n")
-	for _, item := range cartItems {
-		fmt.Printf("- %s %s (%s) x%d @ ₹%s each\n",
-			item.Product.Brand.ID,
-			item.Product.Category.ID,
-			item.Size,
-			item.Quantity,
-			item.Product.CurrentPrice.String())
+	if *demoMode {
+		campaigns := repositories.NewInMemoryCampaignRepository()
+		for _, campaign := range demo.Campaigns() {
+			if err := campaigns.CreateCampaign(ctx, &campaign); err != nil {
+				log.Fatalf("Failed to seed campaigns: %v", err)
+			}
+		}
+		serverClock = clock.NewFixed(demo.Now)
+		opts = append(opts, services.WithCampaignRepository(campaigns), services.WithClock(serverClock))
 	}
 
-	fmt.Printf("\nCustomer: %s (Tier: %s)\n", customer.ID, customer.Tier)
-	if paymentInfo != nil {
-		fmt.Printf("Payment: %s", paymentInfo.Method)
-		if paymentInfo.BankName != nil {
-			fmt.Printf(" (%s)", *paymentInfo.BankName)
+	var scheduler *qos.Scheduler
+	if *lanes {
+		if scheduler, err = qos.NewScheduler(qos.DefaultLanes()); err != nil {
+			log.Fatal(err)
 		}
-		fmt.Println()
+		opts = append(opts, services.WithScheduler(scheduler))
 	}
 
-	// Calculate discounts
-	result, err := discountService.CalculateCartDiscounts(ctx, cartItems, customer, paymentInfo)
-	if err != nil {
-		log.Fatalf("Failed to calculate discounts: %v", err)
+	if *pricingRecords {
+		opts = append(opts, services.WithPricingRecords(repositories.NewInMemoryPricingRecordRepository()))
+	}
+
+	if *legacyPrices {
+		opts = append(opts, services.WithLegacyCurrentPrices())
 	}
 
-	// Display results
-	fmt.Println("\n💰 Discount Calculation Results")
-	fmt.Println("------------------------------")
-	fmt.Printf("Original Price: ₹%s\n", result.OriginalPrice.String())
-	fmt.Printf("Final Price: ₹%s\n", result.FinalPrice.String())
-	fmt.Printf("Total Savings: ₹%s (%.2s%%)\n",
-		result.GetTotalDiscount().String(),
-		result.GetDiscountPercentage().String())
+	opts = append(opts, services.WithSpendCapErrorHandler(func(discountID string, err error) {
+		log.Printf("Discount %s reached its spend cap but was left active: %v", discountID, err)
+	}))
 
-	fmt.Println("\n🎯 Applied Discounts:")
-	for name, amount := range result.AppliedDiscounts {
-		fmt.Printf("- %s: ₹%s\n", name, amount.String())
+	if *checkInvariants {
+		opts = append(opts, services.WithInvariantChecks(func(v *services.InvariantViolation) {
+			log.Printf("Invariant violation: %v", v)
+		}))
 	}
 
-	fmt.Printf("\nMessage: %s\n", result.Message)
+	discountService := services.NewDiscountService(repo, opts...)
 
-	// Test discount code validation
-	fmt.Println("\n🔍 Testing Discount Code Validation")
-	fmt.Println("-----------------------------------")
+	var handlerOpts []api.HandlerOption
+	if *jobs {
+		jobRepo := repositories.NewInMemoryJobRepository()
+		if redisClient != nil {
+			jobRepo = repositories.NewRedisJobRepository(redisClient, "discounts:")
+		}
+		jobService := services.NewJobService(discountService, jobRepo)
+		jobService.Clock = serverClock
+		jobService.OnError = func(job *models.Job, err error) {
+			log.Printf("Job %s stopped at cart %d: %v", job.ID, job.Processed, err)
+		}
+		if err := jobService.Resume(ctx); err != nil {
+			log.Fatalf("Failed to resume jobs: %v", err)
+		}
+		handlerOpts = append(handlerOpts, api.WithJobs(jobService))
+	}
 
-	testCodes := []string{"SUPER69", "PREMIUM15", "INVALID123", ""}
+	authn, err := loadAuthenticator(*apiKeys, *jwtSecretFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if feed != nil && authn == nil {
+		log.Fatal("-sync-feed serves every discount, codes included, and counts the uses edges report: " +
+			"authenticate edge stores with -api-keys or -jwt-secret-file")
+	}
+	if *trustedGateway {
+		handlerOpts = append(handlerOpts, api.WithTrustedActorHeader())
+	}
+	if authn != nil {
+		handlerOpts = append(handlerOpts, api.WithLifecycle(services.NewLifecycleService(repo, auth.PrincipalAuthorizer{})))
+	} else if *editors != "" || *approvers != "" {
+		if !*trustedGateway {
+			log.Fatal("-editors and -approvers authorise the actor named by the X-Actor-ID header, which any client can send: " +
+				"authenticate callers with -api-keys or -jwt-secret-file, or add -trusted-gateway behind a gateway setting it")
+		}
+		roles := make(map[string][]models.Role)
+		for role, actors := range map[models.Role]string{models.RoleEditor: *editors, models.RoleApprover: *approvers} {
+			for _, who := range strings.Split(actors, ",") {
+				if who = strings.TrimSpace(who); who != "" {
+					roles[who] = append(roles[who], role)
+				}
+			}
+		}
+		lifecycle := services.NewLifecycleService(repo, services.RoleAuthorizer{Roles: roles})
+		handlerOpts = append(handlerOpts, api.WithLifecycle(lifecycle))
+	}
 
-	for _, code := range testCodes {
-		if code == "" {
-			fmt.Printf("Testing empty code: ")
-		} else {
-			fmt.Printf("Testing code '%s': ", code)
+	var apiHandler http.Handler = api.NewHandler(discountService, handlerOpts...)
+	if *recordTo != "" {
+		sink := recording.NewJSONSink(os.Stdout)
+		if *recordTo != "-" {
+			if sink, err = recording.OpenFile(*recordTo); err != nil {
+				log.Fatal(err)
+			}
+			defer sink.Close()
 		}
+		apiHandler = recording.Middleware(apiHandler, sink, recording.Config{
+			SampleRate: *recordSample,
+			OnError: func(exchange recording.Exchange, err error) {
+				log.Printf("Failed to record %s %s: %v", exchange.Method, exchange.Path, err)
+			},
+		})
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", apiHandler)
+	if *demoMode {
+		mux.Handle("/demo/", demo.NewHandler())
+		log.Printf("Demo mode: %d discounts priced at %s, scenarios at /demo/scenarios",
+			len(seed), demo.Now.Format(time.RFC3339))
+	}
+	if auditStore != nil {
+		mux.Handle("/admin/audit", audit.NewHandler(auditStore))
+	}
+	if scheduler != nil {
+		mux.Handle("/admin/qos", qos.NewHandler(scheduler))
+	}
+	mux.Handle("/admin/catalog/", catalog.NewHandler(repo, serverClock))
+	if reporting != nil {
+		mux.Handle("/admin/reports/", reports.NewHandler(reporting, orders))
+	}
+	if guarded != nil {
+		mux.Handle("/admin/resilience", resilience.NewHandler(guarded))
+	}
+	if feed != nil {
+		mux.Handle("/sync/", edgesync.NewHandler(feed, serverClock))
+	}
+
+	checker := health.NewChecker()
+	checker.Add("discounts", func(ctx context.Context) error {
+		_, err := repo.GetActiveDiscounts(ctx)
+		return err
+	})
+	if redisClient != nil {
+		checker.Add("redis", func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		})
+	}
+	probes := health.NewHandler(checker)
+	mux.Handle("/healthz", probes)
+	mux.Handle("/readyz", probes)
+	// Discounts are loaded and jobs resumed by now
+	checker.MarkReady()
 
-		isValid, err := discountService.ValidateDiscountCode(ctx, code, cartItems, customer)
+	var handler http.Handler = mux
+	if authn != nil {
+		handler = auth.Middleware(mux, authn, auth.DefaultPolicy())
+	}
+	serveHTTP(cfg.HTTP.Addr, handler)
+}
+
+// loadAuthenticator builds the authenticator of the admin APIs from the -api-keys and
+// -jwt-secret-file flags, nil when neither is set
+func loadAuthenticator(apiKeysFile, jwtSecretFile string) (auth.Authenticator, error) {
+	var chain auth.Chain
+	if apiKeysFile != "" {
+		raw, err := os.ReadFile(apiKeysFile)
 		if err != nil {
-			fmt.Printf("Error - %v\n", err)
-		} else if isValid {
-			fmt.Println("✅ Valid")
-		} else {
-			fmt.Println("❌ Invalid")
+			return nil, fmt.Errorf("failed to read API keys: %w", err)
+		}
+		var keys auth.APIKeys
+		if err := json.Unmarshal(raw, &keys); err != nil {
+			return nil, fmt.Errorf("failed to parse API keys: %w", err)
 		}
+		chain = append(chain, keys)
+	}
+	if jwtSecretFile != "" {
+		secret, err := os.ReadFile(jwtSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT secret: %w", err)
+		}
+		chain = append(chain, auth.JWT{Secret: bytes.TrimSpace(secret)})
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+func serveHTTP(addr string, handler http.Handler) {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
 	}
 
-	fmt.Println("\n✨ Demonstration completed successfully!")
+	log.Printf("Serving the discount API on %s", addr)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("Server stopped: %v", err)
+	}
 }
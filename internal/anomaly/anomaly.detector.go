@@ -0,0 +1,229 @@
+// Package anomaly watches redemptions for promotions that spike abnormally, such as a
+// misconfigured percentage or a leaked code, and raises alerts before finance notices.
+//
+// Every discount's redemptions are bucketed per minute. The current minute's redemption
+// count and giveaway are compared with the discount's recent minutes using a z-score,
+// and an alert fires once per metric and minute when either exceeds the threshold.
+package anomaly
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// Metric names a per-minute series watched by the detector
+type Metric string
+
+const (
+	MetricRedemptions Metric = "redemptions_per_minute"
+	MetricGiveaway    Metric = "giveaway_per_minute"
+)
+
+var metrics = [...]Metric{MetricRedemptions, MetricGiveaway}
+
+// Alert reports a minute whose activity is far above the discount's baseline
+type Alert struct {
+	TenantID   string    `json:"tenant_id,omitempty"`
+	DiscountID string    `json:"discount_id"`
+	Metric     Metric    `json:"metric"`
+	Minute     time.Time `json:"minute"`
+	Value      float64   `json:"value"`   // The minute's value so far
+	Mean       float64   `json:"mean"`    // Baseline mean per minute
+	StdDev     float64   `json:"std_dev"` // Baseline deviation, after the floor is applied
+	ZScore     float64   `json:"z_score"`
+
+	// DiscountRef is the stable ref of the redeemed discount, for labelling the series
+	DiscountRef models.DiscountRef `json:"discount_ref,omitempty"`
+}
+
+// Notifier delivers alerts, to a webhook or an in-process event bus
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// NotifierFunc adapts a function to Notifier
+type NotifierFunc func(ctx context.Context, alert Alert) error
+
+// Notify calls f
+func (f NotifierFunc) Notify(ctx context.Context, alert Alert) error {
+	return f(ctx, alert)
+}
+
+// Config tunes the detector. Zero fields take their defaults.
+type Config struct {
+	Window     int     // Minutes in the rolling baseline, default 60
+	MinHistory int     // Minutes a discount must have been observed before it can alert, default 15
+	Threshold  float64 // Z-score above which a minute is anomalous, default 3
+
+	// OnError is called when the notifier fails; alerts are not retried
+	OnError func(alert Alert, err error)
+}
+
+// Detector computes rolling z-scores over per-minute redemption activity. It is safe for
+// concurrent use.
+type Detector struct {
+	notifier Notifier
+	cfg      Config
+
+	mu     sync.Mutex
+	series map[string]*series // tenant-scoped discount id -> activity
+}
+
+// series is the recent activity of one discount
+type series struct {
+	first   time.Time // First minute observed
+	latest  time.Time // Latest minute observed
+	buckets map[time.Time]*bucket
+}
+
+type bucket struct {
+	values  [len(metrics)]float64
+	alerted [len(metrics)]bool
+}
+
+// NewDetector creates a detector delivering alerts to notifier
+func NewDetector(notifier Notifier, cfg Config) *Detector {
+	if cfg.Window <= 0 {
+		cfg.Window = 60
+	}
+	if cfg.MinHistory <= 0 {
+		cfg.MinHistory = 15
+	}
+	if cfg.MinHistory > cfg.Window {
+		cfg.MinHistory = cfg.Window
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 3
+	}
+	return &Detector{notifier: notifier, cfg: cfg, series: make(map[string]*series)}
+}
+
+// Observe adds the redemption to its discount's minute and notifies any alert it raises.
+// Alerts are also returned, for callers that handle them inline.
+func (d *Detector) Observe(ctx context.Context, r models.Redemption) []Alert {
+	alerts := d.record(tenant.FromContext(ctx), r)
+	for _, alert := range alerts {
+		if err := d.notifier.Notify(ctx, alert); err != nil && d.cfg.OnError != nil {
+			d.cfg.OnError(alert, err)
+		}
+	}
+	return alerts
+}
+
+func (d *Detector) record(tenantID string, r models.Redemption) []Alert {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	minute := r.RedeemedAt.UTC().Truncate(time.Minute)
+	key := tenant.Scoped(tenantID, r.DiscountID)
+	s := d.series[key]
+	if s == nil {
+		s = &series{first: minute, latest: minute, buckets: make(map[time.Time]*bucket)}
+		d.series[key] = s
+	}
+	if minute.After(s.latest) {
+		s.latest = minute
+		s.prune(d.cfg.Window)
+	}
+	if minute.Before(s.latest.Add(-time.Duration(d.cfg.Window) * time.Minute)) {
+		return nil // Too old to matter
+	}
+	if minute.Before(s.first) {
+		s.first = minute
+	}
+
+	b := s.buckets[minute]
+	if b == nil {
+		b = &bucket{}
+		s.buckets[minute] = b
+	}
+	b.values[0]++
+	b.values[1] += r.Amount.InexactFloat64()
+
+	base, ok := s.baseline(minute, d.cfg)
+	if !ok {
+		return nil
+	}
+
+	var alerts []Alert
+	for i, metric := range metrics {
+		if b.alerted[i] {
+			continue
+		}
+		if z := (b.values[i] - base.mean[i]) / base.stdDev[i]; z > d.cfg.Threshold {
+			b.alerted[i] = true
+			alerts = append(alerts, Alert{
+				TenantID:    tenantID,
+				DiscountID:  r.DiscountID,
+				DiscountRef: r.DiscountRef,
+				Metric:      metric,
+				Minute:      minute,
+				Value:       b.values[i],
+				Mean:        base.mean[i],
+				StdDev:      base.stdDev[i],
+				ZScore:      z,
+			})
+		}
+	}
+	return alerts
+}
+
+// baseline is the per-minute mean and deviation of every metric
+type baseline struct {
+	mean, stdDev [len(metrics)]float64
+}
+
+// baseline summarises the minutes before minute, counting minutes without redemptions as
+// zero. Deviations are floored at what one typical redemption adds, scaled like Poisson
+// noise by the square root of the mean count, so a flat or sparse history does not turn
+// every redemption into an anomaly.
+func (s *series) baseline(minute time.Time, cfg Config) (baseline, bool) {
+	start := minute.Add(-time.Duration(cfg.Window) * time.Minute)
+	if s.first.After(start) {
+		start = s.first
+	}
+	n := int(minute.Sub(start) / time.Minute)
+	if n < cfg.MinHistory {
+		return baseline{}, false
+	}
+
+	var sum, sumSquares [len(metrics)]float64
+	for m := start; m.Before(minute); m = m.Add(time.Minute) {
+		if b := s.buckets[m]; b != nil {
+			for i, v := range b.values {
+				sum[i] += v
+				sumSquares[i] += v * v
+			}
+		}
+	}
+
+	var result baseline
+	for i := range metrics {
+		result.mean[i] = sum[i] / float64(n)
+		result.stdDev[i] = math.Sqrt(math.Max(sumSquares[i]/float64(n)-result.mean[i]*result.mean[i], 0))
+	}
+
+	noise := math.Max(math.Sqrt(result.mean[0]), 1)
+	perRedemption := 1.0
+	if sum[0] > 0 {
+		perRedemption = sum[1] / sum[0]
+	}
+	result.stdDev[0] = math.Max(result.stdDev[0], noise)
+	result.stdDev[1] = math.Max(result.stdDev[1], noise*perRedemption)
+	return result, true
+}
+
+// prune drops minutes that have left the window
+func (s *series) prune(window int) {
+	cutoff := s.latest.Add(-time.Duration(window) * time.Minute)
+	for m := range s.buckets {
+		if m.Before(cutoff) {
+			delete(s.buckets, m)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package anomaly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// defaultWebhookTimeout bounds a webhook call, which runs on the checkout that raised it
+const defaultWebhookTimeout = 2 * time.Second
+
+// WebhookNotifier POSTs every alert as JSON to a URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client // Defaults to a client with a 2 second timeout
+}
+
+// Notify posts the alert, failing on any non-2xx response
+func (n WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// monitoredRedemptions feeds every recorded redemption to a detector
+type monitoredRedemptions struct {
+	interfaces.IRedemptionRepository
+	detector *Detector
+}
+
+// MonitorRedemptions wraps a redemption repository so every redemption it records is
+// observed by the detector. Pass the result to services.WithRedemptionRepository.
+func MonitorRedemptions(repo interfaces.IRedemptionRepository, detector *Detector) interfaces.IRedemptionRepository {
+	return &monitoredRedemptions{IRedemptionRepository: repo, detector: detector}
+}
+
+// RecordRedemption stores the redemption, then observes it
+func (m *monitoredRedemptions) RecordRedemption(ctx context.Context, redemption models.Redemption) error {
+	if err := m.IRedemptionRepository.RecordRedemption(ctx, redemption); err != nil {
+		return err
+	}
+	m.detector.Observe(ctx, redemption)
+	return nil
+}
@@ -0,0 +1,556 @@
+// Package api exposes the discount service over HTTP.
+//
+// Every endpoint is versioned. Clients pick a version through the path (/v1/..., /v2/...)
+// or, on unversioned paths, through the Accept-Version header; requests that specify
+// neither get v1 so existing consumers keep working. v1 responses keep the original
+// name-keyed result shape and are marked deprecated; v2 responses are structured.
+//
+// Batches too large for one request can be submitted as background jobs under /v2/jobs,
+// polled for progress and read back a page of results at a time.
+//
+// The routes are described by an OpenAPI 3 document served at /openapi.json, which client
+// teams can generate SDKs from, and browsable at /docs.
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/actor"
+	"github.com/ahsmha/discounts/pkg/channel"
+	"github.com/ahsmha/discounts/pkg/clientip"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/lane"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// Version is a supported API version
+type Version int
+
+const (
+	V1 Version = 1
+	V2 Version = 2
+
+	// LatestVersion is the version deprecated responses point their clients to
+	LatestVersion = V2
+)
+
+const (
+	// VersionHeader selects the API version on unversioned paths
+	VersionHeader = "Accept-Version"
+
+	// TenantHeader names the storefront a request is for; requests without it use the
+	// default tenant
+	TenantHeader = "X-Tenant-ID"
+
+	// ActorHeader names who a request acts for, as established by the gateway in front of
+	// the API, so lifecycle transitions can be authorised and audited. Any client can send
+	// it, so it is only read by handlers created WithTrustedActorHeader, and ignored once
+	// the request has an actor, see auth.Middleware.
+	ActorHeader = "X-Actor-ID"
+
+	// ServedVersionHeader reports the version that served every response
+	ServedVersionHeader = "API-Version"
+
+	// LaneHeader moves a request out of the checkout lane, e.g. "batch" for backfills, so a
+	// scheduler can keep it from slowing checkouts down
+	LaneHeader = "X-Traffic-Lane"
+
+	// ChannelHeader names the sales channel a request comes through, e.g. "app", for
+	// endpoints whose body cannot carry one such as code validation and offer listings
+	ChannelHeader = "X-Sales-Channel"
+
+	maxBodyBytes    = 1 << 20
+	maxJobBodyBytes = 256 << 20 // Jobs carry whole catalogs
+)
+
+// ParseVersion accepts "1", "v1", "2" and "v2"
+func ParseVersion(s string) (Version, bool) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "v") {
+	case "1":
+		return V1, true
+	case "2":
+		return V2, true
+	default:
+		return 0, false
+	}
+}
+
+func (v Version) String() string {
+	switch v {
+	case V1:
+		return "v1"
+	case V2:
+		return "v2"
+	default:
+		return "unknown"
+	}
+}
+
+// Handler serves the discount API
+type Handler struct {
+	service    interfaces.IDiscountService
+	jobs       interfaces.IJobService       // Nil when background jobs are not served
+	lifecycle  interfaces.ILifecycleService // Nil when lifecycle transitions are not served
+	trustActor bool                         // Read ActorHeader, see WithTrustedActorHeader
+	mux        *http.ServeMux
+	endpoints  []endpoint // Every route, in registration order, for the OpenAPI document
+}
+
+// HandlerOption configures optional routes of the handler
+type HandlerOption func(*Handler)
+
+// WithJobs serves the background job routes on top of the job service
+func WithJobs(jobs interfaces.IJobService) HandlerOption {
+	return func(h *Handler) {
+		h.jobs = jobs
+	}
+}
+
+// WithLifecycle serves the discount lifecycle transition route on top of the lifecycle service
+func WithLifecycle(lifecycle interfaces.ILifecycleService) HandlerOption {
+	return func(h *Handler) {
+		h.lifecycle = lifecycle
+	}
+}
+
+// WithTrustedActorHeader takes the actor of requests from ActorHeader, for handlers served
+// only behind a gateway that authenticates callers and sets the header itself
+func WithTrustedActorHeader() HandlerOption {
+	return func(h *Handler) {
+		h.trustActor = true
+	}
+}
+
+// NewHandler creates an HTTP handler serving every API version on top of the service
+func NewHandler(service interfaces.IDiscountService, opts ...HandlerOption) *Handler {
+	h := &Handler{service: service, mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.route(endpoint{
+		method: "POST", path: "/cart/calculate", id: "calculateCart",
+		summary: "Price a cart, applying and committing every eligible discount",
+		bodies: map[Version]payloads{
+			V1: {v1CalculateRequest{}, v1CalculateResponse{}},
+			V2: {v2CalculateRequest{}, v2CalculateResponse{}},
+		},
+	}, h.calculate)
+	h.route(endpoint{
+		method: "POST", path: "/cart/calculate/batch", id: "calculateCarts",
+		summary: "Price many carts at once, each succeeding or failing on its own",
+		bodies:  map[Version]payloads{V2: {batchCalculateRequest{}, batchCalculateResponse{}}},
+	}, h.calculateBatch)
+	h.route(endpoint{
+		method: "POST", path: "/cart/explain", id: "explainCart",
+		summary: "Price a cart without side effects and report the decision made about every discount",
+		bodies:  map[Version]payloads{V2: {v2CalculateRequest{}, explainResponse{}}},
+	}, h.explain)
+	h.route(endpoint{
+		method: "POST", path: "/codes/validate", id: "validateCode",
+		summary: "Check whether a discount code applies to a cart",
+		bodies: map[Version]payloads{
+			V1: {validateCodeRequest{}, validateCodeResponse{}},
+			V2: {validateCodeRequest{}, validateCodeResponse{}},
+		},
+	}, h.validateCode)
+	h.route(endpoint{
+		method: "POST", path: "/offers", id: "listOffers",
+		summary: "List the offers a customer can currently use",
+		bodies:  map[Version]payloads{V2: {offersRequest{}, offersResponse{}}},
+	}, h.listOffers)
+	h.route(endpoint{
+		method: "POST", path: "/coupons", id: "listCoupons",
+		summary: "List the voucher codes a customer could enter for a cart, and those the cart nearly qualifies for",
+		bodies:  map[Version]payloads{V2: {couponsRequest{}, couponsResponse{}}},
+	}, h.listCoupons)
+	h.route(endpoint{
+		method: "GET", path: "/orders/{id}/pricing/verification", id: "verifyOrderPricing",
+		summary: "Recalculate an order from its pricing record and compare the price with the one it got",
+		bodies:  map[Version]payloads{V2: {nil, models.PricingVerification{}}},
+	}, h.verifyOrderPricing)
+
+	if h.jobs != nil {
+		h.route(endpoint{
+			method: "POST", path: "/jobs", id: "submitJob",
+			summary:   "Start pricing a batch of carts too large for one request in the background",
+			bodies:    map[Version]payloads{V2: {batchCalculateRequest{}, models.Job{}}},
+			status:    http.StatusAccepted,
+			bodyLimit: maxJobBodyBytes,
+		}, h.submitJob)
+		h.route(endpoint{
+			method: "GET", path: "/jobs/{id}", id: "getJob",
+			summary: "Report a background job's status and progress",
+			bodies:  map[Version]payloads{V2: {nil, models.Job{}}},
+		}, h.getJob)
+		h.route(endpoint{
+			method: "GET", path: "/jobs/{id}/results", id: "listJobResults",
+			summary: "Page through the results of the carts a background job has processed so far",
+			bodies:  map[Version]payloads{V2: {nil, jobResultsResponse{}}},
+			query: []queryParam{
+				{"offset", "Index of the first cart to return, 0 when missing"},
+				{"limit", "Maximum results to return, 1000 when missing"},
+			},
+		}, h.listJobResults)
+	}
+
+	if h.lifecycle != nil {
+		h.route(endpoint{
+			method: "POST", path: "/discounts/{id}/transitions", id: "transitionDiscount",
+			summary: "Move a discount through its publication workflow, e.g. submit or approve it",
+			bodies:  map[Version]payloads{V2: {transitionRequest{}, models.Discount{}}},
+		}, h.transition)
+	}
+
+	h.serveDocs()
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// versionedHandler serves one endpoint for whichever version the request negotiated
+type versionedHandler func(w http.ResponseWriter, r *http.Request, version Version)
+
+// route registers the endpoint under /v1, /v2 and the unversioned path
+func (h *Handler) route(e endpoint, handler versionedHandler) {
+	h.endpoints = append(h.endpoints, e)
+	method, path := e.method, e.path
+
+	for _, version := range []Version{V1, V2} {
+		version := version
+		h.mux.HandleFunc(method+" /"+version.String()+path, func(w http.ResponseWriter, r *http.Request) {
+			h.serve(w, r, e, version, handler)
+		})
+	}
+
+	h.mux.HandleFunc(method+" "+path, func(w http.ResponseWriter, r *http.Request) {
+		version := V1
+		if requested := r.Header.Get(VersionHeader); requested != "" {
+			var ok bool
+			if version, ok = ParseVersion(requested); !ok {
+				writeError(w, errors.NewValidationError("unsupported API version: "+requested))
+				return
+			}
+		}
+		h.serve(w, r, e, version, handler)
+	})
+}
+
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, e endpoint, version Version, handler versionedHandler) {
+	w.Header().Set(ServedVersionHeader, version.String())
+	if version < LatestVersion {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", `</`+LatestVersion.String()+strings.TrimPrefix(r.URL.Path, "/"+version.String())+`>; rel="successor-version"`)
+	}
+
+	if requested := r.Header.Get(LaneHeader); requested != "" {
+		l, ok := lane.Parse(requested)
+		if !ok {
+			writeError(w, errors.NewValidationError("unknown traffic lane: "+requested))
+			return
+		}
+		r = r.WithContext(lane.NewContext(r.Context(), l))
+	}
+	if name := r.Header.Get(ChannelHeader); name != "" {
+		if err := models.SalesChannel(name).Validate(); err != nil {
+			writeError(w, errors.NewValidationError(err.Error()))
+			return
+		}
+		r = r.WithContext(channel.NewContext(r.Context(), name))
+	}
+	if tenantID := r.Header.Get(TenantHeader); tenantID != "" {
+		r = r.WithContext(tenant.NewContext(r.Context(), tenantID))
+	}
+	// Callers authenticated in front of the handler cannot act for someone else
+	if actorID := r.Header.Get(ActorHeader); h.trustActor && actorID != "" && actor.FromContext(r.Context()) == "" {
+		r = r.WithContext(actor.NewContext(r.Context(), actorID))
+	}
+	// Forwarding headers are not trusted, so behind a proxy every client shares its address
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		r = r.WithContext(clientip.NewContext(r.Context(), host))
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, e.maxBodyBytes())
+	handler(w, r, version)
+}
+
+func (h *Handler) calculate(w http.ResponseWriter, r *http.Request, version Version) {
+	switch version {
+	case V1:
+		var req v1CalculateRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		result, err := h.service.CalculateCartDiscounts(r.Context(), req.CartItems, req.Customer, req.PaymentInfo)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, newV1CalculateResponse(result))
+
+	default:
+		var req v2CalculateRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		result, err := h.service.CalculateCart(r.Context(), req.toModel())
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, newV2CalculateResponse(result))
+	}
+}
+
+func (h *Handler) calculateBatch(w http.ResponseWriter, r *http.Request, version Version) {
+	if version == V1 {
+		writeError(w, errors.NewNotFoundError("batch pricing is only available from "+V2.String()))
+		return
+	}
+
+	var req batchCalculateRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	batch, err := h.service.CalculateCartDiscountsBatch(r.Context(), req.toModel())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newBatchCalculateResponse(batch))
+}
+
+func (h *Handler) explain(w http.ResponseWriter, r *http.Request, version Version) {
+	if version == V1 {
+		writeError(w, errors.NewNotFoundError("explanations are only available from "+V2.String()))
+		return
+	}
+
+	var req v2CalculateRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	explanation, err := h.service.ExplainCartDiscounts(r.Context(), req.toModel())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, explainResponse{
+		Result:    newV2CalculateResponse(explanation.Result),
+		Decisions: explanation.Decisions,
+	})
+}
+
+func (h *Handler) validateCode(w http.ResponseWriter, r *http.Request, version Version) {
+	var req validateCodeRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	validation, err := h.service.DescribeDiscountCode(r.Context(), req.Code, req.CartItems, req.Customer)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, validateCodeResponse{CodeValidation: *validation})
+}
+
+func (h *Handler) listOffers(w http.ResponseWriter, r *http.Request, version Version) {
+	if version == V1 {
+		writeError(w, errors.NewNotFoundError("offers are only available from "+V2.String()))
+		return
+	}
+
+	var req offersRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	offers, err := h.service.ListOffers(r.Context(), req.Customer)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, offersResponse{Offers: offers})
+}
+
+func (h *Handler) listCoupons(w http.ResponseWriter, r *http.Request, version Version) {
+	if version == V1 {
+		writeError(w, errors.NewNotFoundError("coupons are only available from "+V2.String()))
+		return
+	}
+
+	var req couponsRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	coupons, err := h.service.GetAvailableCoupons(r.Context(), req.CartItems, req.Customer)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, couponsResponse{Coupons: coupons})
+}
+
+func (h *Handler) verifyOrderPricing(w http.ResponseWriter, r *http.Request, version Version) {
+	if version == V1 {
+		writeError(w, errors.NewNotFoundError("pricing verification is only available from "+V2.String()))
+		return
+	}
+
+	verification, err := h.service.VerifyOrderPricing(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, verification)
+}
+
+func (h *Handler) submitJob(w http.ResponseWriter, r *http.Request, version Version) {
+	if version == V1 {
+		writeError(w, errors.NewNotFoundError("jobs are only available from "+V2.String()))
+		return
+	}
+
+	var req batchCalculateRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	job, err := h.jobs.SubmitJob(r.Context(), req.toModel())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/"+version.String()+"/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (h *Handler) getJob(w http.ResponseWriter, r *http.Request, version Version) {
+	if version == V1 {
+		writeError(w, errors.NewNotFoundError("jobs are only available from "+V2.String()))
+		return
+	}
+
+	job, err := h.jobs.GetJob(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (h *Handler) transition(w http.ResponseWriter, r *http.Request, version Version) {
+	if version == V1 {
+		writeError(w, errors.NewNotFoundError("lifecycle transitions are only available from "+V2.String()))
+		return
+	}
+
+	var req transitionRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	discount, err := h.lifecycle.Transition(r.Context(), r.PathValue("id"), req.Action)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, discount)
+}
+
+func (h *Handler) listJobResults(w http.ResponseWriter, r *http.Request, version Version) {
+	if version == V1 {
+		writeError(w, errors.NewNotFoundError("jobs are only available from "+V2.String()))
+		return
+	}
+
+	var window [2]int
+	for i, name := range []string{"offset", "limit"} {
+		if v := r.URL.Query().Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeError(w, errors.NewValidationError("invalid "+name+": "+v))
+				return
+			}
+			window[i] = n
+		}
+	}
+
+	page, err := h.jobs.GetJobResults(r.Context(), r.PathValue("id"), window[0], window[1])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newJobResultsResponse(page))
+}
+
+// errorResponse is the body of every failed request
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, errors.NewValidationError("invalid request body: "+err.Error()))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError maps service errors onto HTTP statuses
+func writeError(w http.ResponseWriter, err error) {
+	status, message := errorStatus(err)
+	if retryAfter, ok := errors.RetryAfter(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+// errorStatus returns the HTTP status for a service error and the message safe to show
+// the client; unexpected errors are never described
+func errorStatus(err error) (int, string) {
+	status := http.StatusInternalServerError
+	message := "internal error"
+
+	switch {
+	case errors.IsValidationError(err):
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.IsTotalMismatchError(err):
+		status, message = http.StatusUnprocessableEntity, err.Error()
+	case errors.IsConflictError(err):
+		status, message = http.StatusConflict, err.Error()
+	case errors.IsNotFoundError(err):
+		status, message = http.StatusNotFound, err.Error()
+	case errors.IsForbiddenError(err):
+		status, message = http.StatusForbidden, err.Error()
+	case errors.IsTooManyAttemptsError(err):
+		status, message = http.StatusTooManyRequests, err.Error()
+	case errors.IsOverloadedError(err):
+		status, message = http.StatusServiceUnavailable, err.Error()
+	case errors.IsDeadlineExceededError(err):
+		status, message = http.StatusGatewayTimeout, err.Error()
+	}
+
+	return status, message
+}
@@ -0,0 +1,334 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// endpoint describes a route, both for the mux and for the OpenAPI document
+type endpoint struct {
+	method  string
+	path    string // Unversioned path, e.g. /cart/calculate
+	id      string // Operation ID, prefixed with the version in the document
+	summary string
+
+	// bodies holds the request and response payloads of every version serving the route.
+	// Versions without payloads answer 404 and are left out of the document.
+	bodies map[Version]payloads
+
+	query     []queryParam // Query parameters; path parameters are read from the path
+	status    int          // Status of successful responses, 200 when zero
+	bodyLimit int64        // Largest request body accepted, maxBodyBytes when zero
+}
+
+type payloads struct {
+	request  any // Nil for routes without a request body
+	response any
+}
+
+type queryParam struct {
+	name        string
+	description string
+}
+
+func (e endpoint) successStatus() int {
+	if e.status != 0 {
+		return e.status
+	}
+	return http.StatusOK
+}
+
+func (e endpoint) maxBodyBytes() int64 {
+	if e.bodyLimit != 0 {
+		return e.bodyLimit
+	}
+	return maxBodyBytes
+}
+
+// parameters lists the headers every route reads, then the endpoint's path and query
+// parameters
+func (e endpoint) parameters() []OpenAPIParameter {
+	params := []OpenAPIParameter{{
+		Name:        TenantHeader,
+		In:          "header",
+		Description: "Storefront the request is for, the default tenant when missing",
+		Schema:      &OpenAPISchema{Type: "string"},
+	}, {
+		Name:        LaneHeader,
+		In:          "header",
+		Description: "Traffic lane the request is scheduled in: checkout, the default, or batch",
+		Schema:      &OpenAPISchema{Type: "string"},
+	}, {
+		Name:        ActorHeader,
+		In:          "header",
+		Description: "Who the request acts for, as authenticated by the gateway",
+		Schema:      &OpenAPISchema{Type: "string"},
+	}, {
+		Name:        ChannelHeader,
+		In:          "header",
+		Description: "Sales channel the request comes through: app, web, in_store or marketplace",
+		Schema:      &OpenAPISchema{Type: "string"},
+	}}
+	for _, segment := range strings.Split(e.path, "/") {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			params = append(params, OpenAPIParameter{
+				Name:     strings.TrimSuffix(name, "}"),
+				In:       "path",
+				Required: true,
+				Schema:   &OpenAPISchema{Type: "string"},
+			})
+		}
+	}
+	for _, q := range e.query {
+		params = append(params, OpenAPIParameter{Name: q.name, In: "query", Description: q.description, Schema: &OpenAPISchema{Type: "integer"}})
+	}
+	return params
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Discount API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`
+
+// serveDocs serves the OpenAPI document of the registered routes at /openapi.json and a
+// Swagger UI for it at /docs
+func (h *Handler) serveDocs() {
+	doc, err := json.Marshal(h.OpenAPI())
+	if err != nil {
+		panic("api: cannot encode the OpenAPI document: " + err.Error())
+	}
+
+	h.mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(doc)
+	})
+	h.mux.HandleFunc("GET /docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(swaggerUIPage))
+	})
+}
+
+// OpenAPIDocument is an OpenAPI 3 description of the API
+type OpenAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"` // path -> lower-case method -> operation
+	Components OpenAPIComponents                       `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary"`
+	Tags        []string                    `json:"tags"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+	Parameters  []OpenAPIParameter          `json:"parameters"`
+	RequestBody *OpenAPIBody                `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*OpenAPISchema `json:"schemas"`
+}
+
+// OpenAPISchema is the subset of JSON Schema the API's payloads need
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+// OpenAPI describes every route registered on the handler. Schemas are derived from the
+// payload types, so the document cannot drift from what the handlers decode and encode.
+func (h *Handler) OpenAPI() *OpenAPIDocument {
+	schemas := newSchemaRegistry()
+	doc := &OpenAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfo{
+			Title: "Discount API",
+			Description: "Every path is also served without its version prefix, picking the version from the " +
+				VersionHeader + " header and defaulting to v1.",
+			Version: LatestVersion.String(),
+		},
+		Paths: make(map[string]map[string]*OpenAPIOperation),
+	}
+	errorBody := schemas.schemaFor(reflect.TypeOf(errorResponse{}))
+
+	for _, e := range h.endpoints {
+		for _, version := range []Version{V1, V2} {
+			body, ok := e.bodies[version]
+			if !ok {
+				continue
+			}
+
+			op := &OpenAPIOperation{
+				OperationID: version.String() + strings.ToUpper(e.id[:1]) + e.id[1:],
+				Summary:     e.summary,
+				Tags:        []string{version.String()},
+				Deprecated:  version < LatestVersion,
+				Parameters:  e.parameters(),
+				Responses: map[string]*OpenAPIResponse{
+					strconv.Itoa(e.successStatus()): {Description: "Success", Content: jsonContent(schemas.schemaFor(reflect.TypeOf(body.response)))},
+					"default":                       {Description: "The request failed; the message is safe to show", Content: jsonContent(errorBody)},
+				},
+			}
+			if body.request != nil {
+				op.RequestBody = &OpenAPIBody{Required: true, Content: jsonContent(schemas.schemaFor(reflect.TypeOf(body.request)))}
+			}
+
+			path := "/" + version.String() + e.path
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = make(map[string]*OpenAPIOperation)
+			}
+			doc.Paths[path][strings.ToLower(e.method)] = op
+		}
+	}
+
+	doc.Components.Schemas = schemas.schemas
+	return doc
+}
+
+func jsonContent(schema *OpenAPISchema) map[string]OpenAPIMediaType {
+	return map[string]OpenAPIMediaType{"application/json": {Schema: schema}}
+}
+
+var (
+	decimalType  = reflect.TypeOf(decimal.Decimal{})
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// schemaRegistry derives schemas from Go types the way encoding/json encodes them, keeping
+// one component per struct type
+type schemaRegistry struct {
+	schemas map[string]*OpenAPISchema
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: make(map[string]*OpenAPISchema), names: make(map[reflect.Type]string)}
+}
+
+func (s *schemaRegistry) schemaFor(t reflect.Type) *OpenAPISchema {
+	switch t {
+	case decimalType:
+		return &OpenAPISchema{Type: "string", Format: "decimal"}
+	case timeType:
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := *s.schemaFor(t.Elem())
+		if schema.Ref != "" {
+			return &schema // $ref siblings are ignored, so references cannot be nullable
+		}
+		schema.Nullable = true
+		return &schema
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &OpenAPISchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: s.schemaFor(t.Elem())}
+	case reflect.Struct:
+		return &OpenAPISchema{Ref: "#/components/schemas/" + s.component(t)}
+	default:
+		return &OpenAPISchema{} // Any value
+	}
+}
+
+// component registers the struct type's schema once and returns its name
+func (s *schemaRegistry) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, taken := s.schemas[name]; taken {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	schema := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+	s.names[t], s.schemas[name] = name, schema // Registered first so recursive types terminate
+
+	s.addFields(schema, t)
+	return name
+}
+
+func (s *schemaRegistry) addFields(schema *OpenAPISchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			s.addFields(schema, field.Type) // Promoted fields, as encoding/json flattens them
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = s.schemaFor(field.Type)
+	}
+}
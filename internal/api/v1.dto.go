@@ -0,0 +1,42 @@
+package api
+
+import (
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// v1CalculateRequest is the original calculation payload
+type v1CalculateRequest struct {
+	CartItems   []models.CartItem      `json:"cart_items"`
+	Customer    models.CustomerProfile `json:"customer"`
+	PaymentInfo *models.PaymentInfo    `json:"payment_info,omitempty"`
+}
+
+// v1CalculateResponse is frozen in the original result shape, with applied discounts keyed
+// by name. New result fields are only added to v2.
+type v1CalculateResponse struct {
+	OriginalPrice    decimal.Decimal            `json:"original_price"`
+	FinalPrice       decimal.Decimal            `json:"final_price"`
+	AppliedDiscounts map[string]decimal.Decimal `json:"applied_discounts"` // discount name -> amount
+	Message          string                     `json:"message"`
+}
+
+func newV1CalculateResponse(result *models.DiscountedPrice) v1CalculateResponse {
+	return v1CalculateResponse{
+		OriginalPrice:    result.OriginalPrice,
+		FinalPrice:       result.FinalPrice,
+		AppliedDiscounts: result.AppliedDiscounts,
+		Message:          result.Message,
+	}
+}
+
+// validateCodeRequest and validateCodeResponse are shared by every version
+type validateCodeRequest struct {
+	Code      string                 `json:"code"`
+	CartItems []models.CartItem      `json:"cart_items"`
+	Customer  models.CustomerProfile `json:"customer"`
+}
+
+type validateCodeResponse struct {
+	models.CodeValidation
+}
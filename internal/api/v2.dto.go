@@ -0,0 +1,209 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// v2CalculateRequest exposes every option of a calculation request
+type v2CalculateRequest struct {
+	CartItems      []models.CartItem      `json:"cart_items"`
+	Customer       models.CustomerProfile `json:"customer"`
+	PaymentInfo    *models.PaymentInfo    `json:"payment_info,omitempty"`
+	ExpectedTotal  *decimal.Decimal       `json:"expected_total,omitempty"`
+	ValidationMode models.ValidationMode  `json:"validation_mode,omitempty"`
+	RedeemPoints   bool                   `json:"redeem_points,omitempty"`
+	Codes          []string               `json:"codes,omitempty"`
+	GiftCardCodes  []string               `json:"gift_card_codes,omitempty"`
+	IdempotencyKey string                 `json:"idempotency_key,omitempty"`
+	AllowPartial   bool                   `json:"allow_partial,omitempty"`
+	OrderID        string                 `json:"order_id,omitempty"`
+	Location       *models.Location       `json:"location,omitempty"`
+	SalesChannel   models.SalesChannel    `json:"sales_channel,omitempty"`
+}
+
+func (r *v2CalculateRequest) toModel() *models.CalculationRequest {
+	return &models.CalculationRequest{
+		CartItems:      r.CartItems,
+		Customer:       r.Customer,
+		PaymentInfo:    r.PaymentInfo,
+		ExpectedTotal:  r.ExpectedTotal,
+		ValidationMode: r.ValidationMode,
+		RedeemPoints:   r.RedeemPoints,
+		Codes:          r.Codes,
+		GiftCardCodes:  r.GiftCardCodes,
+		IdempotencyKey: r.IdempotencyKey,
+		AllowPartial:   r.AllowPartial,
+		OrderID:        r.OrderID,
+		Location:       r.Location,
+		SalesChannel:   r.SalesChannel,
+	}
+}
+
+// v2CalculateResponse lists applied discounts as structured entries in application order
+type v2CalculateResponse struct {
+	OriginalPrice decimal.Decimal          `json:"original_price"`
+	FinalPrice    decimal.Decimal          `json:"final_price"`
+	TotalDiscount decimal.Decimal          `json:"total_discount"`
+	Discounts     []models.AppliedDiscount `json:"discounts"`
+	Message       string                   `json:"message"`
+	Warnings      []string                 `json:"warnings,omitempty"`
+	Partial       bool                     `json:"partial,omitempty"`
+
+	PriceWithout   map[string]decimal.Decimal `json:"price_without,omitempty"`
+	PointsRedeemed int64                      `json:"points_redeemed,omitempty"`
+	PointsEarned   int64                      `json:"points_earned,omitempty"`
+
+	GiftCards []v2GiftCardPayment `json:"gift_cards,omitempty"`
+	AmountDue decimal.Decimal     `json:"amount_due"`
+
+	TaxLines []models.TaxLine `json:"tax_lines,omitempty"`
+	TotalTax decimal.Decimal  `json:"total_tax"`
+
+	PriceFloorClamps       []models.PriceFloorClamp       `json:"price_floor_clamps,omitempty"`
+	DiscountCapAdjustments []models.DiscountCapAdjustment `json:"discount_cap_adjustments,omitempty"`
+
+	PendingCashback []models.PendingCashback `json:"pending_cashback,omitempty"`
+}
+
+type v2GiftCardPayment struct {
+	Code   string          `json:"code"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+func newV2CalculateResponse(result *models.DiscountedPrice) v2CalculateResponse {
+	resp := v2CalculateResponse{
+		OriginalPrice:  result.OriginalPrice,
+		FinalPrice:     result.FinalPrice,
+		TotalDiscount:  result.GetTotalDiscount(),
+		Discounts:      result.Breakdown,
+		Message:        result.Message,
+		Warnings:       result.Warnings,
+		Partial:        result.Partial,
+		PriceWithout:   result.PriceWithout,
+		PointsRedeemed: result.PointsRedeemed,
+		PointsEarned:   result.PointsEarned,
+		AmountDue:      result.AmountDue,
+		TaxLines:       result.TaxLines,
+		TotalTax:       result.TotalTax,
+
+		PriceFloorClamps:       result.PriceFloorClamps,
+		DiscountCapAdjustments: result.DiscountCapAdjustments,
+
+		PendingCashback: result.PendingCashback,
+	}
+	if resp.Discounts == nil {
+		resp.Discounts = []models.AppliedDiscount{}
+	}
+	for code, amount := range result.GiftCardsApplied {
+		resp.GiftCards = append(resp.GiftCards, v2GiftCardPayment{Code: code, Amount: amount})
+	}
+	sort.Slice(resp.GiftCards, func(i, j int) bool {
+		return resp.GiftCards[i].Code < resp.GiftCards[j].Code
+	})
+	return resp
+}
+
+// batchCalculateRequest prices many carts in one call
+type batchCalculateRequest struct {
+	Carts  []v2CalculateRequest `json:"carts"`
+	DryRun bool                 `json:"dry_run,omitempty"`
+}
+
+func (r *batchCalculateRequest) toModel() *models.BatchCalculationRequest {
+	req := &models.BatchCalculationRequest{
+		Carts:  make([]*models.CalculationRequest, len(r.Carts)),
+		DryRun: r.DryRun,
+	}
+	for i := range r.Carts {
+		req.Carts[i] = r.Carts[i].toModel()
+	}
+	return req
+}
+
+// batchCalculateResponse reports every cart in request order, each with its result or error
+type batchCalculateResponse struct {
+	Results   []batchItemResponse `json:"results"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+}
+
+type batchItemResponse struct {
+	Index  int                  `json:"index"`
+	Result *v2CalculateResponse `json:"result,omitempty"`
+	Status int                  `json:"status"` // HTTP status the cart would have had on its own
+	Error  string               `json:"error,omitempty"`
+}
+
+func newBatchCalculateResponse(batch *models.BatchCalculationResult) batchCalculateResponse {
+	resp := batchCalculateResponse{
+		Results:   make([]batchItemResponse, len(batch.Results)),
+		Succeeded: batch.Succeeded,
+		Failed:    batch.Failed,
+	}
+	for i, r := range batch.Results {
+		item := batchItemResponse{Index: r.Index, Status: http.StatusOK}
+		if r.Err != nil {
+			item.Status, item.Error = errorStatus(r.Err)
+		} else {
+			result := newV2CalculateResponse(r.Result)
+			item.Result = &result
+		}
+		resp.Results[i] = item
+	}
+	return resp
+}
+
+// jobResultsResponse is a page of a job's results, each with its cart's result or error
+type jobResultsResponse struct {
+	Results    []jobResultResponse `json:"results"`
+	NextOffset int                 `json:"next_offset,omitempty"` // Zero on the last page processed so far
+}
+
+type jobResultResponse struct {
+	Index  int                  `json:"index"`
+	Result *v2CalculateResponse `json:"result,omitempty"`
+	Error  string               `json:"error,omitempty"`
+}
+
+func newJobResultsResponse(page *models.JobResultPage) jobResultsResponse {
+	resp := jobResultsResponse{Results: make([]jobResultResponse, len(page.Results)), NextOffset: page.NextOffset}
+	for i, r := range page.Results {
+		resp.Results[i] = jobResultResponse{Index: r.Index, Error: r.Error}
+		if r.Result != nil {
+			result := newV2CalculateResponse(r.Result)
+			resp.Results[i].Result = &result
+		}
+	}
+	return resp
+}
+
+// explainResponse is a dry-run calculation with the decision made about every discount
+type explainResponse struct {
+	Result    v2CalculateResponse       `json:"result"`
+	Decisions []models.DiscountDecision `json:"decisions"`
+}
+
+type transitionRequest struct {
+	Action models.LifecycleAction `json:"action"`
+}
+
+type offersRequest struct {
+	Customer models.CustomerProfile `json:"customer"`
+}
+
+type offersResponse struct {
+	Offers []models.Offer `json:"offers"`
+}
+
+type couponsRequest struct {
+	CartItems []models.CartItem      `json:"cart_items"`
+	Customer  models.CustomerProfile `json:"customer"`
+}
+
+type couponsResponse struct {
+	Coupons []models.AvailableCoupon `json:"coupons"`
+}
@@ -0,0 +1,76 @@
+// Package audit records who changed which discount and every time a discount was applied,
+// writing each event to pluggable sinks. A Store also answers queries over the events it
+// kept, which the admin handler serves.
+package audit
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// Action is what an audit event records
+type Action string
+
+const (
+	ActionCreated Action = "discount.created"
+	ActionUpdated Action = "discount.updated"
+	ActionDeleted Action = "discount.deleted"
+	ActionApplied Action = "discount.applied"
+)
+
+// Event is one audited change to, or application of, a discount
+type Event struct {
+	At         time.Time `json:"at"`
+	Action     Action    `json:"action"`
+	Actor      string    `json:"actor,omitempty"` // Who made a change, see actor.NewContext
+	TenantID   string    `json:"tenant_id,omitempty"`
+	DiscountID string    `json:"discount_id"`
+
+	// DiscountRef is the discount's stable ref, see models.DiscountRef
+	DiscountRef models.DiscountRef `json:"discount_ref,omitempty"`
+
+	// Discount is the definition written by a create or update
+	Discount *models.Discount `json:"discount,omitempty"`
+
+	// OrderID, CustomerID and Amount describe an application
+	OrderID    string          `json:"order_id,omitempty"`
+	CustomerID string          `json:"customer_id,omitempty"`
+	Amount     decimal.Decimal `json:"amount"`
+}
+
+// Filter narrows an audit query; zero values match everything
+type Filter struct {
+	Action     Action
+	Actor      string
+	DiscountID string
+	OrderID    string
+	From       time.Time
+	To         time.Time
+	Limit      int // Most recent events kept when more match, 0 for all
+}
+
+// Matches reports whether the event satisfies the filter, regardless of Limit
+func (f Filter) Matches(e Event) bool {
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.DiscountID != "" && e.DiscountID != f.DiscountID {
+		return false
+	}
+	if f.OrderID != "" && e.OrderID != f.OrderID {
+		return false
+	}
+	if !f.From.IsZero() && e.At.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.At.Before(f.To) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// NewHandler serves the audit trail kept in store:
+//
+//	GET /admin/audit  lists events, oldest first
+//
+// narrowed by the query parameters action, actor, discount_id, order_id, from and to
+// (RFC 3339) and limit.
+func NewHandler(store Store) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/audit", func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseFilter(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		events, err := store.Query(r.Context(), filter)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"events": events})
+	})
+	return mux
+}
+
+func parseFilter(r *http.Request) (Filter, error) {
+	q := r.URL.Query()
+	filter := Filter{
+		Action:     Action(q.Get("action")),
+		Actor:      q.Get("actor"),
+		DiscountID: q.Get("discount_id"),
+		OrderID:    q.Get("order_id"),
+	}
+
+	for name, t := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := q.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return Filter{}, fmt.Errorf("invalid %s: %q is not an RFC 3339 time", name, v)
+			}
+			*t = parsed
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return Filter{}, fmt.Errorf("invalid limit: %q", v)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,163 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/actor"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// Logger writes audit events to every sink
+type Logger struct {
+	Sinks []Sink
+
+	// OnError is called when a sink fails to write an event. Auditing never fails the
+	// change or application it records: the event is only lost for that sink.
+	OnError func(event Event, err error)
+}
+
+// NewLogger creates a logger writing to the sinks
+func NewLogger(sinks ...Sink) *Logger {
+	return &Logger{Sinks: sinks}
+}
+
+// Record stamps the event with the context's instant, actor and tenant, then writes it
+func (l *Logger) Record(ctx context.Context, event Event) {
+	if event.At.IsZero() {
+		event.At = clock.FromContext(ctx)
+	}
+	event.Actor = actor.FromContext(ctx)
+	event.TenantID = tenant.FromContext(ctx)
+
+	for _, sink := range l.Sinks {
+		if err := sink.Write(ctx, event); err != nil && l.OnError != nil {
+			l.OnError(event, err)
+		}
+	}
+}
+
+// auditedDiscounts records every successful change made through a discount repository
+type auditedDiscounts struct {
+	interfaces.IDiscountRepository
+	logger *Logger
+}
+
+// Discounts wraps a discount repository so every discount created, updated or deleted
+// through it is recorded. The wrapper still lists discounts when repo does.
+func Discounts(repo interfaces.IDiscountRepository, logger *Logger) interfaces.IDiscountRepository {
+	return &auditedDiscounts{IDiscountRepository: repo, logger: logger}
+}
+
+func (a *auditedDiscounts) CreateDiscount(ctx context.Context, discount *models.Discount) error {
+	if err := a.IDiscountRepository.CreateDiscount(ctx, discount); err != nil {
+		return err
+	}
+	a.recordChange(ctx, ActionCreated, discount)
+	return nil
+}
+
+func (a *auditedDiscounts) UpdateDiscount(ctx context.Context, discount *models.Discount) error {
+	if err := a.IDiscountRepository.UpdateDiscount(ctx, discount); err != nil {
+		return err
+	}
+	a.recordChange(ctx, ActionUpdated, discount)
+	return nil
+}
+
+func (a *auditedDiscounts) DeleteDiscount(ctx context.Context, id string) error {
+	var ref models.DiscountRef
+	if existing, err := a.IDiscountRepository.GetDiscountByID(ctx, id); err == nil {
+		ref = existing.StableRef()
+	}
+	if err := a.IDiscountRepository.DeleteDiscount(ctx, id); err != nil {
+		return err
+	}
+	a.logger.Record(ctx, Event{Action: ActionDeleted, DiscountID: id, DiscountRef: ref})
+	return nil
+}
+
+// ListDiscounts lists through the wrapped repository, see interfaces.DiscountLister
+func (a *auditedDiscounts) ListDiscounts(ctx context.Context, filter models.ListFilter) (*models.DiscountPage, error) {
+	lister, ok := a.IDiscountRepository.(interfaces.DiscountLister)
+	if !ok {
+		return nil, fmt.Errorf("repository cannot list discounts")
+	}
+	return lister.ListDiscounts(ctx, filter)
+}
+
+// CatalogStats summarises through the wrapped repository, see interfaces.CatalogInspector
+func (a *auditedDiscounts) CatalogStats(ctx context.Context, window time.Duration) (*models.CatalogStats, error) {
+	inspector, ok := a.IDiscountRepository.(interfaces.CatalogInspector)
+	if !ok {
+		return nil, fmt.Errorf("repository cannot summarise its catalog")
+	}
+	return inspector.CatalogStats(ctx, window)
+}
+
+// AddUsage counts uses through the wrapped repository, see interfaces.UsageAdder
+func (a *auditedDiscounts) AddUsage(ctx context.Context, uses map[string]int) error {
+	adder, ok := a.IDiscountRepository.(interfaces.UsageAdder)
+	if !ok {
+		return fmt.Errorf("repository cannot add usage: %w", errors.ErrUnsupported)
+	}
+	return adder.AddUsage(ctx, uses)
+}
+
+// ListRevisions lists through the wrapped repository, see interfaces.DiscountHistory
+func (a *auditedDiscounts) ListRevisions(ctx context.Context, id string) ([]models.DiscountRevision, error) {
+	history, ok := a.IDiscountRepository.(interfaces.DiscountHistory)
+	if !ok {
+		return nil, fmt.Errorf("repository does not keep discount revisions")
+	}
+	return history.ListRevisions(ctx, id)
+}
+
+// GetDiscountAt looks up through the wrapped repository, see interfaces.DiscountHistory
+func (a *auditedDiscounts) GetDiscountAt(ctx context.Context, id string, at time.Time) (*models.Discount, error) {
+	history, ok := a.IDiscountRepository.(interfaces.DiscountHistory)
+	if !ok {
+		return nil, fmt.Errorf("repository does not keep discount revisions")
+	}
+	return history.GetDiscountAt(ctx, id, at)
+}
+
+func (a *auditedDiscounts) recordChange(ctx context.Context, action Action, discount *models.Discount) {
+	written := *discount
+	written.TenantID = tenant.FromContext(ctx)
+	a.logger.Record(ctx, Event{Action: action, DiscountID: discount.ID, DiscountRef: written.StableRef(), Discount: &written})
+}
+
+// auditedRedemptions records every redemption as an application of its discount
+type auditedRedemptions struct {
+	interfaces.IRedemptionRepository
+	logger *Logger
+}
+
+// Redemptions wraps a redemption repository so every redemption it records is audited as
+// an application. Pass the result to services.WithRedemptionRepository.
+func Redemptions(repo interfaces.IRedemptionRepository, logger *Logger) interfaces.IRedemptionRepository {
+	return &auditedRedemptions{IRedemptionRepository: repo, logger: logger}
+}
+
+// RecordRedemption stores the redemption, then audits it
+func (a *auditedRedemptions) RecordRedemption(ctx context.Context, redemption models.Redemption) error {
+	if err := a.IRedemptionRepository.RecordRedemption(ctx, redemption); err != nil {
+		return err
+	}
+	a.logger.Record(ctx, Event{
+		At:          redemption.RedeemedAt,
+		Action:      ActionApplied,
+		DiscountID:  redemption.DiscountID,
+		DiscountRef: redemption.DiscountRef,
+		OrderID:     redemption.OrderID,
+		CustomerID:  redemption.CustomerID,
+		Amount:      redemption.Amount,
+	})
+	return nil
+}
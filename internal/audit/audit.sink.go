@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// Sink persists audit events
+type Sink interface {
+	Write(ctx context.Context, event Event) error
+}
+
+// Store is a sink that can be queried back
+type Store interface {
+	Sink
+
+	// Query returns the context tenant's events matching the filter, oldest first
+	Query(ctx context.Context, filter Filter) ([]Event, error)
+}
+
+// JSONSink writes every event as one line of JSON, e.g. to stdout or a log file picked up
+// by a shipper
+type JSONSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONSink writes events to w
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+// OpenFile appends events to the file at path, creating it when missing. Close the sink
+// to close the file.
+func OpenFile(path string) (*JSONSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return NewJSONSink(f), nil
+}
+
+// Write appends the event as a line of JSON
+func (s *JSONSink) Write(ctx context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the underlying writer when it is closable
+func (s *JSONSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// MemoryStore keeps events in memory, for tests and single-instance deployments. It is
+// safe for concurrent use.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	events []Event
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// Write keeps the event
+func (s *MemoryStore) Write(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, event)
+	return nil
+}
+
+// Query returns the context tenant's events matching the filter, oldest first
+func (s *MemoryStore) Query(ctx context.Context, filter Filter) ([]Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+	events := []Event{}
+	for _, event := range s.events {
+		if event.TenantID == tenantID && filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[len(events)-filter.Limit:]
+	}
+	return events, nil
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// APIKeyHeader carries API keys; keys may also be sent as bearer tokens
+const APIKeyHeader = "X-API-Key"
+
+// Chain tries each authenticator in turn, authenticating with the first that finds
+// credentials on the request
+type Chain []Authenticator
+
+// Authenticate returns the first authenticator's answer other than ErrNoCredentials
+func (c Chain) Authenticate(r *http.Request) (*Principal, error) {
+	for _, authn := range c {
+		p, err := authn.Authenticate(r)
+		if err != ErrNoCredentials {
+			return p, err
+		}
+	}
+	return nil, ErrNoCredentials
+}
+
+// APIKeys authenticates requests by a static API key in APIKeyHeader or the Authorization
+// bearer token
+type APIKeys map[string]Principal // key -> principal
+
+// Authenticate looks the request's key up, comparing keys in constant time
+func (k APIKeys) Authenticate(r *http.Request) (*Principal, error) {
+	key, bearer := r.Header.Get(APIKeyHeader), false
+	if key == "" {
+		key, bearer = bearerToken(r), true
+	}
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	for candidate, p := range k {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+			return &p, nil
+		}
+	}
+	if bearer {
+		return nil, ErrNoCredentials // Possibly a token for another authenticator of a Chain
+	}
+	return nil, fmt.Errorf("unknown API key")
+}
+
+// JWT authenticates requests by an HS256-signed JSON Web Token in the Authorization bearer
+// token. The sub claim names the principal and the roles claim lists their roles; tokens
+// past their exp claim are refused.
+type JWT struct {
+	Secret []byte
+	Now    func() time.Time // Clock tokens expire by, time.Now when nil
+}
+
+type jwtClaims struct {
+	Subject string        `json:"sub"`
+	Roles   []models.Role `json:"roles"`
+	Expiry  int64         `json:"exp,omitempty"` // Unix seconds, zero for tokens that do not expire
+}
+
+// Authenticate verifies the token's signature and expiry
+func (j JWT) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, fmt.Errorf("unsupported token algorithm")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, j.sign(parts[0]+"."+parts[1])) {
+		return nil, fmt.Errorf("invalid token signature")
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims")
+	}
+	now := time.Now
+	if j.Now != nil {
+		now = j.Now
+	}
+	switch {
+	case claims.Subject == "":
+		return nil, fmt.Errorf("token names no subject")
+	case claims.Expiry != 0 && !now().Before(time.Unix(claims.Expiry, 0)):
+		return nil, fmt.Errorf("token expired")
+	}
+	return &Principal{ID: claims.Subject, Roles: claims.Roles}, nil
+}
+
+// Issue signs a token for the principal, expiring at expiresAt unless it is zero
+func (j JWT) Issue(p Principal, expiresAt time.Time) string {
+	claims := jwtClaims{Subject: p.ID, Roles: p.Roles}
+	if !expiresAt.IsZero() {
+		claims.Expiry = expiresAt.Unix()
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(j.sign(unsigned))
+}
+
+func (j JWT) sign(unsigned string) []byte {
+	mac := hmac.New(sha256.New, j.Secret)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
+
+func decodeSegment(segment string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// bearerToken returns the request's Authorization bearer token, empty when it has none
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// Rule requires Role of requests with the method, every method when empty, to paths
+// starting with PathPrefix
+type Rule struct {
+	Method     string      `json:"method,omitempty"`
+	PathPrefix string      `json:"path_prefix"`
+	Role       models.Role `json:"role"`
+}
+
+// Policy lists the roles endpoints require; the first matching rule applies and requests
+// matching none are public
+type Policy []Rule
+
+// DefaultPolicy protects the admin APIs: viewers read /admin/, only admins change anything
+// under it, and editors reach the lifecycle routes, whose service checks each action's own role.
+// The edge sync feed under /sync/ takes the sync role.
+func DefaultPolicy() Policy {
+	var policy Policy
+	for _, prefix := range []string{"/discounts/", "/v1/discounts/", "/v2/discounts/"} {
+		policy = append(policy, Rule{Method: http.MethodPost, PathPrefix: prefix, Role: models.RoleEditor})
+	}
+	return append(policy,
+		Rule{Method: http.MethodGet, PathPrefix: "/admin/", Role: models.RoleViewer},
+		Rule{PathPrefix: "/admin/", Role: models.RoleAdmin},
+		Rule{PathPrefix: "/sync/", Role: models.RoleSync},
+	)
+}
+
+// RoleFor returns the role the request requires, reporting false for public requests
+func (p Policy) RoleFor(r *http.Request) (models.Role, bool) {
+	for _, rule := range p {
+		if (rule.Method == "" || rule.Method == r.Method) && strings.HasPrefix(r.URL.Path, rule.PathPrefix) {
+			return rule.Role, true
+		}
+	}
+	return "", false
+}
+
+// Validate reports rules naming unknown roles
+func (p Policy) Validate() error {
+	for _, rule := range p {
+		if err := rule.Role.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Middleware authenticates requests with authn and enforces the policy: protected requests
+// without valid credentials get 401 and those lacking the role 403. The principal of every
+// authenticated request, public ones included, is put in its context, see FromContext.
+func Middleware(next http.Handler, authn Authenticator, policy Policy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role, protected := policy.RoleFor(r)
+		p, err := authn.Authenticate(r)
+		if err != nil {
+			if protected {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "authentication required: " + err.Error()})
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if protected && !p.Has(role) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": p.ID + " needs the " + string(role) + " role"})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), p)))
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
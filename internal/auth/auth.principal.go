@@ -0,0 +1,80 @@
+// Package auth authenticates callers of the admin APIs and enforces the roles each endpoint
+// and service method requires. Identity providers plug in through Authenticator; API keys
+// and HS256-signed JWTs are supported out of the box.
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/actor"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// Principal is an authenticated caller
+type Principal struct {
+	ID    string        `json:"id"`
+	Roles []models.Role `json:"roles"`
+}
+
+// Has reports whether the principal holds the role, see models.HasRole
+func (p *Principal) Has(role models.Role) bool {
+	return p != nil && models.HasRole(p.Roles, role)
+}
+
+// Authenticator is the integration point for an identity provider. Authenticate returns
+// the caller of the request, ErrNoCredentials when it carries none, or an error when its
+// credentials are not valid.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// ErrNoCredentials is returned by authenticators for requests without credentials
+var ErrNoCredentials = fmt.Errorf("no credentials")
+
+type principalKey struct{}
+
+// NewContext returns a context carrying the principal, who is also its actor
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return actor.NewContext(context.WithValue(ctx, principalKey{}, p), p.ID)
+}
+
+// FromContext returns the principal set by NewContext, or nil for unauthenticated requests
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Require returns a forbidden error unless the context's principal holds the role, for
+// service methods to check their callers
+func Require(ctx context.Context, role models.Role) error {
+	p := FromContext(ctx)
+	switch {
+	case p == nil:
+		return errors.NewForbiddenError(fmt.Sprintf("the %s role is required", role))
+	case !p.Has(role):
+		return errors.NewForbiddenError(fmt.Sprintf("%s needs the %s role", p.ID, role))
+	}
+	return nil
+}
+
+// PrincipalAuthorizer authorises lifecycle transitions by the roles of the context's
+// principal, so transitions follow the identity provider rather than a local role table.
+// Like services.RoleAuthorizer it keeps submitters from approving their own discounts.
+type PrincipalAuthorizer struct{}
+
+var _ interfaces.Authorizer = PrincipalAuthorizer{}
+
+// AuthorizeTransition checks the context's principal holds the role the action requires
+func (PrincipalAuthorizer) AuthorizeTransition(ctx context.Context, discount *models.Discount, action models.LifecycleAction) error {
+	if err := Require(ctx, action.RequiredRole()); err != nil {
+		return err
+	}
+	if p := FromContext(ctx); action == models.ActionApprove && discount.SubmittedBy == p.ID {
+		return errors.NewForbiddenError(fmt.Sprintf("%s cannot approve a discount they submitted: %s", p.ID, discount.ID))
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// Package catalog serves operational statistics about the discount catalog and the mapping
+// of stable discount refs to the discounts carrying them.
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// DefaultExpiryWindow is how far ahead expiring discounts are listed by default
+const DefaultExpiryWindow = 7 * 24 * time.Hour
+
+// NewHandler serves the repository's catalog, as of c's current time:
+//
+//	GET /admin/catalog/stats       counts, distinct brands and categories, expiries and index sizes
+//	GET /admin/catalog/refs        the ref, ID and current name of every discount
+//	GET /admin/catalog/refs/{ref}  the discount carrying the ref
+//
+// for the tenant named by the api.TenantHeader header. Statistics need an
+// interfaces.CatalogInspector and refs an interfaces.DiscountLister; routes the repository
+// cannot serve are left out. The window query parameter of stats (a Go duration such as
+// 72h) sets how far ahead expiring discounts are listed.
+func NewHandler(repo interfaces.IDiscountRepository, c clock.Clock) http.Handler {
+	mux := http.NewServeMux()
+	if inspector, ok := repo.(interfaces.CatalogInspector); ok {
+		mux.HandleFunc("GET /admin/catalog/stats", func(w http.ResponseWriter, r *http.Request) {
+			window := DefaultExpiryWindow
+			if v := r.URL.Query().Get("window"); v != "" {
+				parsed, err := time.ParseDuration(v)
+				if err != nil || parsed < 0 {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid window: %q", v)})
+					return
+				}
+				window = parsed
+			}
+
+			stats, err := inspector.CatalogStats(requestContext(r, c), window)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+				return
+			}
+			writeJSON(w, http.StatusOK, stats)
+		})
+	}
+
+	if lister, ok := repo.(interfaces.DiscountLister); ok {
+		mappings := func(w http.ResponseWriter, r *http.Request) ([]models.DiscountRefMapping, bool) {
+			page, err := lister.ListDiscounts(requestContext(r, c), models.ListFilter{})
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+				return nil, false
+			}
+			refs := make([]models.DiscountRefMapping, len(page.Discounts))
+			for i := range page.Discounts {
+				refs[i] = page.Discounts[i].RefMapping()
+			}
+			return refs, true
+		}
+		mux.HandleFunc("GET /admin/catalog/refs", func(w http.ResponseWriter, r *http.Request) {
+			if refs, ok := mappings(w, r); ok {
+				writeJSON(w, http.StatusOK, map[string]any{"refs": refs})
+			}
+		})
+		mux.HandleFunc("GET /admin/catalog/refs/{ref}", func(w http.ResponseWriter, r *http.Request) {
+			refs, ok := mappings(w, r)
+			if !ok {
+				return
+			}
+			for _, mapping := range refs {
+				if mapping.Ref == models.DiscountRef(r.PathValue("ref")) {
+					writeJSON(w, http.StatusOK, mapping)
+					return
+				}
+			}
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "discount ref not found: " + r.PathValue("ref")})
+		})
+	}
+	return mux
+}
+
+// requestContext pins the request to c's current time and the tenant it names
+func requestContext(r *http.Request, c clock.Clock) context.Context {
+	ctx := clock.NewContext(r.Context(), c.Now())
+	if tenantID := r.Header.Get(api.TenantHeader); tenantID != "" {
+		ctx = tenant.NewContext(ctx, tenantID)
+	}
+	return ctx
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+// Backend is a discount store the CLI operates on. Save persists changes made through the
+// repository and is not called by read-only commands.
+type Backend struct {
+	Repo interfaces.IDiscountRepository
+	Save func(ctx context.Context) error
+}
+
+// BackendFactory opens a backend from the --store location
+type BackendFactory func(ctx context.Context, location string) (*Backend, error)
+
+// backends lists the stores the CLI can open, keyed by --backend value
+var backends = map[string]BackendFactory{
+	"file":   openFileBackend,
+	"sample": openSampleBackend,
+}
+
+// RegisterBackend makes another store available through --backend
+func RegisterBackend(name string, factory BackendFactory) {
+	backends[name] = factory
+}
+
+func backendNames() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// openFileBackend keeps discounts in a JSON array on disk. A missing file is an empty store.
+func openFileBackend(ctx context.Context, path string) (*Backend, error) {
+	repo := repositories.NewInMemoryDiscountRepository()
+
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		var discounts []models.Discount
+		if err := json.Unmarshal(data, &discounts); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if err := repo.(interfaces.DiscountSeeder).SeedDiscounts(discounts); err != nil {
+			return nil, err
+		}
+	}
+
+	save := func(ctx context.Context) error {
+		page, err := repo.(interfaces.DiscountLister).ListDiscounts(ctx, models.ListFilter{})
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(page.Discounts, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, append(data, '\n'), 0o644)
+	}
+
+	return &Backend{Repo: repo, Save: save}, nil
+}
+
+// openSampleBackend serves the built-in sample discounts; changes are discarded
+func openSampleBackend(ctx context.Context, _ string) (*Backend, error) {
+	repo := repositories.NewInMemoryDiscountRepository()
+	if err := repo.(interfaces.DiscountSeeder).SeedDiscounts(testdata.GetSampleDiscounts()); err != nil {
+		return nil, err
+	}
+	return &Backend{Repo: repo, Save: func(context.Context) error { return nil }}, nil
+}
@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+func newListCommand(opts *options) *cobra.Command {
+	var (
+		filter models.ListFilter
+		active bool
+		sortBy string
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the discounts in the store",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("active") {
+				filter.Active = &active
+			}
+			filter.SortBy = models.ListSortField(sortBy)
+
+			backend, err := opts.open(cmd)
+			if err != nil {
+				return err
+			}
+			lister, ok := backend.Repo.(interfaces.DiscountLister)
+			if !ok {
+				return fmt.Errorf("backend %q cannot list discounts", opts.backend)
+			}
+			page, err := lister.ListDiscounts(cmd.Context(), filter)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAME\tTYPE\tVALUE\tCODE\tACTIVE\tUSED\tPRIORITY")
+			for _, d := range page.Discounts {
+				value := d.Value.String()
+				if d.IsPercentage {
+					value += "%"
+				}
+				used := fmt.Sprint(d.UsedCount)
+				if d.UsageLimit > 0 {
+					used += fmt.Sprintf("/%d", d.UsageLimit)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\t%s\t%d\n",
+					d.ID, d.Name, d.Type, value, d.Code, d.IsActive, used, d.Priority)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if page.NextCursor != "" {
+				fmt.Fprintf(cmd.ErrOrStderr(), "more discounts follow, continue with --cursor %s\n", page.NextCursor)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar((*string)(&filter.Type), "type", "", "only discounts of this type")
+	cmd.Flags().BoolVar(&active, "active", false, "only discounts switched on, or off with --active=false")
+	cmd.Flags().StringVar(&filter.CodePrefix, "code-prefix", "", "only discounts with a code or alias starting with the prefix")
+	cmd.Flags().StringVar(&sortBy, "sort", string(models.SortByID), "order by id, name, priority or valid_from")
+	cmd.Flags().IntVar(&filter.PageSize, "limit", 0, "discounts per page, 0 for all")
+	cmd.Flags().StringVar(&filter.Cursor, "cursor", "", "continue a listing from the cursor printed with its previous page")
+	return cmd
+}
+
+func newInspectCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "inspect <id>",
+		Short: "Print a discount as JSON",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backend, err := opts.open(cmd)
+			if err != nil {
+				return err
+			}
+			discount, err := backend.Repo.GetDiscountByID(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd, discount)
+		},
+	}
+}
+
+func newCreateCommand(opts *options) *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "create -f discount.json",
+		Short: "Create a discount from a JSON file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeDiscount(cmd, opts, file, interfaces.IDiscountRepository.CreateDiscount, "created")
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "discount definition (JSON)")
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func newUpdateCommand(opts *options) *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "update -f discount.json",
+		Short: "Replace a discount with the definition in a JSON file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeDiscount(cmd, opts, file, interfaces.IDiscountRepository.UpdateDiscount, "updated")
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "discount definition (JSON)")
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func newSetActiveCommand(opts *options, use string, active bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " <id>",
+		Short: fmt.Sprintf("Set a discount's active flag to %t", active),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backend, err := opts.open(cmd)
+			if err != nil {
+				return err
+			}
+			discount, err := backend.Repo.GetDiscountByID(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			updated := *discount
+			updated.IsActive = active
+			if err := backend.Repo.UpdateDiscount(cmd.Context(), &updated); err != nil {
+				return err
+			}
+			if err := backend.Save(cmd.Context()); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %sd\n", updated.ID, use)
+			return nil
+		},
+	}
+}
+
+func writeDiscount(cmd *cobra.Command, opts *options, file string,
+	write func(interfaces.IDiscountRepository, context.Context, *models.Discount) error, verb string) error {
+
+	var discount models.Discount
+	if err := readJSONFile(file, &discount); err != nil {
+		return err
+	}
+
+	backend, err := opts.open(cmd)
+	if err != nil {
+		return err
+	}
+	if err := write(backend.Repo, cmd.Context(), &discount); err != nil {
+		return err
+	}
+	if err := backend.Save(cmd.Context()); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", discount.ID, verb)
+	return nil
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+func printJSON(cmd *cobra.Command, v any) error {
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
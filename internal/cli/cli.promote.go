@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ahsmha/discounts/internal/promotion"
+)
+
+func newPromoteCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Promote a campaign's discounts from one store to another",
+		Long: "Promote a campaign tested in staging to production without re-keying it:\n\n" +
+			"  discountctl --store staging.json promote export --campaign winter -o winter.json\n" +
+			"  discountctl --store prod.json promote diff -f winter.json -o winter.plan.json\n" +
+			"  discountctl --store prod.json promote apply -f winter.plan.json\n\n" +
+			"The plan written by diff is the review artifact; apply refuses a plan once the\n" +
+			"production definitions it was made against have changed.",
+	}
+	cmd.AddCommand(newPromoteExportCommand(opts), newPromoteDiffCommand(opts), newPromoteApplyCommand(opts))
+	return cmd
+}
+
+func newPromoteExportCommand(opts *options) *cobra.Command {
+	var campaignID, output string
+	cmd := &cobra.Command{
+		Use:   "export --campaign <id> -o bundle.json",
+		Short: "Export a campaign's discount definitions from the store",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backend, err := opts.open(cmd)
+			if err != nil {
+				return err
+			}
+			bundle, err := promotion.Export(cmd.Context(), backend.Repo, campaignID, opts.store, time.Now().UTC())
+			if err != nil {
+				return err
+			}
+			if err := writeJSONFile(output, bundle); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "exported %d discounts of campaign %s to %s\n",
+				len(bundle.Discounts), campaignID, output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&campaignID, "campaign", "", "campaign to export")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "bundle file to write")
+	_ = cmd.MarkFlagRequired("campaign")
+	_ = cmd.MarkFlagRequired("output")
+	return cmd
+}
+
+func newPromoteDiffCommand(opts *options) *cobra.Command {
+	var file, output string
+	cmd := &cobra.Command{
+		Use:   "diff -f bundle.json [-o plan.json]",
+		Short: "Compare a bundle with the store and write the plan for review",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var bundle promotion.Bundle
+			if err := readJSONFile(file, &bundle); err != nil {
+				return err
+			}
+			backend, err := opts.open(cmd)
+			if err != nil {
+				return err
+			}
+			plan, err := promotion.Diff(cmd.Context(), backend.Repo, &bundle)
+			if err != nil {
+				return err
+			}
+
+			printPlan(cmd, plan)
+			if output != "" {
+				if err := writeJSONFile(output, plan); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "plan written to %s\n", output)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "bundle written by promote export")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "plan file to write for review and apply")
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func newPromoteApplyCommand(opts *options) *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "apply -f plan.json",
+		Short: "Apply a reviewed plan to the store",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var plan promotion.Plan
+			if err := readJSONFile(file, &plan); err != nil {
+				return err
+			}
+			backend, err := opts.open(cmd)
+			if err != nil {
+				return err
+			}
+			if err := promotion.Apply(cmd.Context(), backend.Repo, &plan); err != nil {
+				return err
+			}
+			if err := backend.Save(cmd.Context()); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "campaign %s promoted\n", plan.Bundle.CampaignID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "plan written by promote diff")
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func printPlan(cmd *cobra.Command, plan *promotion.Plan) {
+	out := cmd.OutOrStdout()
+	if !plan.HasChanges() {
+		fmt.Fprintf(out, "campaign %s is up to date\n", plan.Bundle.CampaignID)
+		return
+	}
+	for _, c := range plan.Changes {
+		if c.Action == promotion.ActionUnchanged {
+			continue
+		}
+		fmt.Fprintf(out, "%s %s\n", c.Action, c.DiscountID)
+		for _, f := range c.Fields {
+			fmt.Fprintf(out, "    %s: %s -> %s\n", f.Field, orNone(f.From), orNone(f.To))
+		}
+	}
+}
+
+func orNone(value json.RawMessage) string {
+	if len(value) == 0 {
+		return "(none)"
+	}
+	return string(value)
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,54 @@
+// Package cli implements discountctl, the administrative command line for managing
+// discounts in any supported store.
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// options are the flags shared by every command
+type options struct {
+	backend string
+	store   string
+}
+
+// NewRootCommand builds the discountctl command tree
+func NewRootCommand() *cobra.Command {
+	opts := &options{}
+
+	root := &cobra.Command{
+		Use:           "discountctl",
+		Short:         "Manage discounts and simulate pricing",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.PersistentFlags().StringVar(&opts.backend, "backend", "file",
+		"discount store to use: "+strings.Join(backendNames(), ", "))
+	root.PersistentFlags().StringVar(&opts.store, "store", "discounts.json", "location of the discount store")
+
+	root.AddCommand(
+		newListCommand(opts),
+		newInspectCommand(opts),
+		newCreateCommand(opts),
+		newUpdateCommand(opts),
+		newSetActiveCommand(opts, "activate", true),
+		newSetActiveCommand(opts, "deactivate", false),
+		newSimulateCommand(opts),
+		newScenarioCommand(),
+		newPromoteCommand(opts),
+		newValidateCommand(opts),
+	)
+	return root
+}
+
+// open connects to the store selected by the shared flags
+func (o *options) open(cmd *cobra.Command) (*Backend, error) {
+	factory, ok := backends[o.backend]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q, expected one of: %s", o.backend, strings.Join(backendNames(), ", "))
+	}
+	return factory(cmd.Context(), o.store)
+}
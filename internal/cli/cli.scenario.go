@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ahsmha/discounts/internal/scenario"
+)
+
+func newScenarioCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "scenario <file.yaml>...",
+		Short: "Run pricing acceptance scenarios written in YAML",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			failed := 0
+			total := 0
+
+			for _, file := range args {
+				scenarios, err := scenario.LoadFile(file)
+				if err != nil {
+					return err
+				}
+				for _, s := range scenarios {
+					total++
+					failures, err := scenario.Run(cmd.Context(), s)
+					if err != nil {
+						return fmt.Errorf("%s: %s: %w", file, s.Name, err)
+					}
+					if len(failures) == 0 {
+						fmt.Fprintf(out, "PASS  %s: %s\n", file, s.Name)
+						continue
+					}
+
+					failed++
+					fmt.Fprintf(out, "FAIL  %s: %s\n", file, s.Name)
+					for _, failure := range failures {
+						fmt.Fprintf(out, "      %s\n", failure)
+					}
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d scenarios failed", failed, total)
+			}
+			fmt.Fprintf(out, "%d scenarios passed\n", total)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/services"
+)
+
+func newSimulateCommand(opts *options) *cobra.Command {
+	var (
+		file   string
+		asJSON bool
+	)
+	cmd := &cobra.Command{
+		Use:   "simulate -f cart.json",
+		Short: "Price a cart against the store and print the breakdown",
+		Long: "Price a calculation request (cart_items, customer, payment_info, ...) read from a JSON\n" +
+			"file. Usage counts and other side effects are not saved to the store.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var req models.CalculationRequest
+			if err := readJSONFile(file, &req); err != nil {
+				return err
+			}
+
+			backend, err := opts.open(cmd)
+			if err != nil {
+				return err
+			}
+			result, err := services.NewDiscountService(backend.Repo).CalculateCart(cmd.Context(), &req)
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				return printJSON(cmd, result)
+			}
+			return printBreakdown(cmd, result)
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "calculation request (JSON)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the full result as JSON")
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func printBreakdown(cmd *cobra.Command, result *models.DiscountedPrice) error {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Original price\t\t%s\n", result.OriginalPrice.StringFixed(2))
+	for _, applied := range result.Breakdown {
+		fmt.Fprintf(w, "  %s\t%s\t-%s\n", applied.Name, applied.Type, applied.Amount.StringFixed(2))
+	}
+	fmt.Fprintf(w, "Final price\t\t%s\n", result.FinalPrice.StringFixed(2))
+	if len(result.GiftCardsApplied) > 0 {
+		fmt.Fprintf(w, "Amount due\t\t%s\n", result.AmountDue.StringFixed(2))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(cmd.OutOrStdout(), "warning: %s\n", warning)
+	}
+	return nil
+}
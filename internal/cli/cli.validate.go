@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ahsmha/discounts/internal/lint"
+)
+
+func newValidateCommand(opts *options) *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "validate [file.yaml|file.json]...",
+		Short: "Check discount definitions for errors before deploying them",
+		Long: "Check discount files, or the --store file when none are given, for schema errors,\n" +
+			"unknown types, impossible date ranges, negative amounts, percentages over 100 and\n" +
+			"IDs or codes defined twice. Every problem is reported, as JSON with --json, and the\n" +
+			"command fails when there are any.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files := args
+			if len(files) == 0 {
+				files = []string{opts.store}
+			}
+
+			diagnostics := lint.Files(files...)
+			if asJSON {
+				if diagnostics == nil {
+					diagnostics = []lint.Diagnostic{}
+				}
+				if err := printJSON(cmd, diagnostics); err != nil {
+					return err
+				}
+			} else {
+				for _, d := range diagnostics {
+					fmt.Fprintln(cmd.OutOrStdout(), d)
+				}
+			}
+
+			if len(diagnostics) > 0 {
+				return fmt.Errorf("%d problems found", len(diagnostics))
+			}
+			if !asJSON {
+				fmt.Fprintf(cmd.OutOrStdout(), "%d files valid\n", len(files))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the diagnostics as a JSON array")
+	return cmd
+}
@@ -0,0 +1,249 @@
+// Package config holds the settings of the server binary. Settings start from Default, are
+// overridden by a YAML file, then by DISCOUNTS_* environment variables, and are validated
+// together at startup so every problem is reported at once, naming the setting at fault.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/yamljson"
+)
+
+// Backend selects where the server's discounts come from
+type Backend string
+
+const (
+	BackendSample Backend = "sample" // The built-in sample catalog, or the demo catalog in demo mode
+	BackendFile   Backend = "file"   // A directory of YAML and JSON files, reloaded as they change
+	BackendMemory Backend = "memory" // An empty in-memory store, filled through the API
+)
+
+// Config is the server's configuration
+type Config struct {
+	HTTP       HTTPConfig       `json:"http"`
+	Repository RepositoryConfig `json:"repository"`
+	Redis      RedisConfig      `json:"redis"`
+	Cache      CacheConfig      `json:"cache"`
+	Pricing    PricingConfig    `json:"pricing"`
+}
+
+type HTTPConfig struct {
+	Addr string `json:"addr"` // Address the API is served on
+}
+
+type RepositoryConfig struct {
+	Backend Backend `json:"backend"`
+	Dir     string  `json:"dir,omitempty"` // Directory of the file backend
+}
+
+type RedisConfig struct {
+	Addr string `json:"addr,omitempty"` // Tracks spend caps and keeps jobs in Redis when set
+}
+
+type CacheConfig struct {
+	// IdempotencyTTL is how long responses to requests carrying an idempotency key are
+	// kept for replay, zero to not replay them
+	IdempotencyTTL Duration `json:"idempotency_ttl,omitempty"`
+}
+
+type PricingConfig struct {
+	Rounding models.RoundingMode      `json:"rounding,omitempty"` // Rounding of discount amounts, half_up when empty
+	Pipeline *services.PipelineConfig `json:"pipeline,omitempty"` // Order discount types are applied in, by priority alone when unset
+
+	// MinMarginPercent keeps discounts from taking a unit below its cost plus this share
+	// of it, see models.PriceFloorPolicy
+	MinMarginPercent *decimal.Decimal `json:"min_margin_percent,omitempty"`
+
+	// MaxDiscountPercent caps the discounts of a cart at this share of its original price,
+	// scaling back the lowest-priority ones, see services.WithMaxDiscountPercent
+	MaxDiscountPercent *decimal.Decimal `json:"max_discount_percent,omitempty"`
+}
+
+// Duration is a time.Duration written as in Go, e.g. "10m" or "24h"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Default is the configuration of a server started without settings
+func Default() Config {
+	return Config{
+		HTTP:       HTTPConfig{Addr: ":8080"},
+		Repository: RepositoryConfig{Backend: BackendSample},
+	}
+}
+
+// Load reads the configuration file at path, if any, over the defaults and applies the
+// environment, without validating the result
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	data, err = yamljson.ToJSON(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil // An empty file
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// envVars maps the environment variables read by Load to the settings they override
+var envVars = []struct {
+	name string
+	set  func(c *Config, value string) error
+}{
+	{"DISCOUNTS_HTTP_ADDR", func(c *Config, v string) error { c.HTTP.Addr = v; return nil }},
+	{"DISCOUNTS_REPOSITORY_BACKEND", func(c *Config, v string) error { c.Repository.Backend = Backend(v); return nil }},
+	{"DISCOUNTS_REPOSITORY_DIR", func(c *Config, v string) error { c.Repository.Dir = v; return nil }},
+	{"DISCOUNTS_REDIS_ADDR", func(c *Config, v string) error { c.Redis.Addr = v; return nil }},
+	{"DISCOUNTS_CACHE_IDEMPOTENCY_TTL", func(c *Config, v string) error { return c.Cache.IdempotencyTTL.UnmarshalText([]byte(v)) }},
+	{"DISCOUNTS_PRICING_ROUNDING", func(c *Config, v string) error { c.Pricing.Rounding = models.RoundingMode(v); return nil }},
+	{"DISCOUNTS_PRICING_MIN_MARGIN_PERCENT", func(c *Config, v string) error { return c.SetMinMargin(v) }},
+	{"DISCOUNTS_PRICING_MAX_DISCOUNT_PERCENT", func(c *Config, v string) error { return c.SetMaxDiscount(v) }},
+}
+
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	for _, env := range envVars {
+		if value, ok := lookup(env.name); ok {
+			if err := env.set(c, value); err != nil {
+				return fmt.Errorf("%s: %w", env.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// SetMinMargin sets Pricing.MinMarginPercent from its decimal text
+func (c *Config) SetMinMargin(value string) error {
+	margin, err := decimal.NewFromString(value)
+	if err != nil {
+		return fmt.Errorf("invalid margin %q: %w", value, err)
+	}
+	c.Pricing.MinMarginPercent = &margin
+	return nil
+}
+
+// SetMaxDiscount sets Pricing.MaxDiscountPercent from its decimal text
+func (c *Config) SetMaxDiscount(value string) error {
+	percent, err := decimal.NewFromString(value)
+	if err != nil {
+		return fmt.Errorf("invalid percentage %q: %w", value, err)
+	}
+	c.Pricing.MaxDiscountPercent = &percent
+	return nil
+}
+
+// Validate reports every invalid setting
+func (c Config) Validate() error {
+	var problems []error
+	problem := func(setting, format string, args ...any) {
+		problems = append(problems, fmt.Errorf("%s: %s", setting, fmt.Sprintf(format, args...)))
+	}
+
+	if c.HTTP.Addr == "" {
+		problem("http.addr", "an address to listen on is required, e.g. :8080")
+	}
+
+	switch c.Repository.Backend {
+	case BackendSample, BackendMemory:
+		if c.Repository.Dir != "" {
+			problem("repository.dir", "only the %s backend reads a directory", BackendFile)
+		}
+	case BackendFile:
+		if c.Repository.Dir == "" {
+			problem("repository.dir", "the %s backend needs the directory holding the discount files", BackendFile)
+		} else if info, err := os.Stat(c.Repository.Dir); err != nil {
+			problem("repository.dir", "%v", err)
+		} else if !info.IsDir() {
+			problem("repository.dir", "%s is not a directory", c.Repository.Dir)
+		}
+	default:
+		problem("repository.backend", "unknown backend %q, expected one of: %s", c.Repository.Backend,
+			strings.Join([]string{string(BackendSample), string(BackendFile), string(BackendMemory)}, ", "))
+	}
+
+	if c.Cache.IdempotencyTTL < 0 {
+		problem("cache.idempotency_ttl", "must not be negative")
+	}
+
+	if err := c.Pricing.Rounding.Validate(); err != nil {
+		problem("pricing.rounding", "%v, expected one of: half_up, half_even, floor, ceil", err)
+	}
+	if c.Pricing.Pipeline != nil {
+		if err := c.Pricing.Pipeline.Validate(); err != nil {
+			problem("pricing.pipeline", "%v", err)
+		}
+	}
+	if c.Pricing.MinMarginPercent != nil {
+		policy := models.PriceFloorPolicy{MinMarginPercent: *c.Pricing.MinMarginPercent}
+		if err := policy.Validate(); err != nil {
+			problem("pricing.min_margin_percent", "%v", err)
+		}
+	}
+	if c.Pricing.MaxDiscountPercent != nil {
+		if err := models.CheckMaxDiscountPercent(*c.Pricing.MaxDiscountPercent); err != nil {
+			problem("pricing.max_discount_percent", "%v", err)
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+// ServiceOptions are the pricing service options the configuration selects
+func (c Config) ServiceOptions() []services.Option {
+	var opts []services.Option
+	if c.Pricing.Rounding != "" {
+		opts = append(opts, services.WithRounding(c.Pricing.Rounding))
+	}
+	if c.Pricing.Pipeline != nil {
+		opts = append(opts, services.WithPipelineConfig(*c.Pricing.Pipeline))
+	}
+	if c.Pricing.MinMarginPercent != nil {
+		opts = append(opts, services.WithPriceFloor(models.PriceFloorPolicy{MinMarginPercent: *c.Pricing.MinMarginPercent}))
+	}
+	if c.Pricing.MaxDiscountPercent != nil {
+		opts = append(opts, services.WithMaxDiscountPercent(*c.Pricing.MaxDiscountPercent))
+	}
+	return opts
+}
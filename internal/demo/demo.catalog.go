@@ -0,0 +1,90 @@
+// Package demo serves a deterministic catalog and a set of named pricing scenarios for
+// onboarding and sales demos. Everything is priced at the fixed instant Now, so every run
+// of a scenario gives the same answer.
+package demo
+
+import (
+	"time"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Now is the instant every demo calculation is priced at
+var Now = time.Date(2024, time.November, 29, 12, 0, 0, 0, time.UTC)
+
+// Products returns the demo catalog
+func Products() []models.Product {
+	product := func(id string, brand, category string, tier models.BrandTier, price int64) models.Product {
+		return models.Product{
+			ID:           id,
+			Brand:        models.Brand{ID: brand, Name: brand, Tier: tier},
+			Category:     models.Category{ID: category, Name: category},
+			BasePrice:    decimal.NewFromInt(price),
+			CurrentPrice: decimal.NewFromInt(price),
+		}
+	}
+
+	return []models.Product{
+		product("demo-tee", "PUMA", "T-shirts", models.BrandTierPremium, 1000),
+		product("demo-sneaker", "Nike", "Shoes", models.BrandTierPremium, 5000),
+		product("demo-jacket", "Zara", "Jackets", models.BrandTierRegular, 4000),
+		product("demo-watch", "Fossil", "Watches", models.BrandTierPremium, 10000),
+	}
+}
+
+// Customers returns the demo customers, one per tier
+func Customers() []models.CustomerProfile {
+	return []models.CustomerProfile{
+		{ID: "demo-gold", Tier: "gold", OrderCount: 12},
+		{ID: "demo-regular", Tier: "regular", OrderCount: 2},
+		{ID: "demo-new", Tier: "regular"},
+	}
+}
+
+// Campaigns returns the demo campaigns
+func Campaigns() []models.Campaign {
+	return []models.Campaign{
+		{ID: "winter", Name: "Winter sale", Budget: decimal.NewFromInt(1000), IsActive: true},
+	}
+}
+
+// Discounts returns the demo discounts, each targeting its own corner of the catalog so
+// scenarios do not interfere with each other
+func Discounts() []models.Discount {
+	discount := func(id, name string, typ models.DiscountType, percent int64, priority int, applicableTo ...string) models.Discount {
+		return models.Discount{
+			ID:           id,
+			Name:         name,
+			Type:         typ,
+			Value:        decimal.NewFromInt(percent),
+			IsPercentage: true,
+			ApplicableTo: applicableTo,
+			ValidFrom:    Now.AddDate(0, -1, 0),
+			ValidTo:      Now.AddDate(0, 1, 0),
+			IsActive:     true,
+			Priority:     priority,
+		}
+	}
+
+	bank := discount("demo-icici", "ICICI card 10%", models.DiscountTypeBank, 10, 5, "ICICI")
+	bank.MaxAmount = decimal.NewFromInt(500)
+
+	watches := discount("demo-watch-week", "Watch week 30%", models.DiscountTypeVoucher, 30, 15, "Watches")
+	watches.MaxTotalSpend = decimal.NewFromInt(500)
+
+	zara := discount("demo-zara-winter", "Zara winter 25%", models.DiscountTypeBrand, 25, 25, "Zara")
+	zara.CampaignID = "winter"
+
+	jackets := discount("demo-jackets-winter", "Jackets winter 15%", models.DiscountTypeCategory, 15, 20, "Jackets")
+	jackets.CampaignID = "winter"
+
+	return []models.Discount{
+		discount("demo-puma", "PUMA 40% off", models.DiscountTypeBrand, 40, 30, "PUMA"),
+		discount("demo-tshirts", "T-shirts extra 10%", models.DiscountTypeCategory, 10, 20, "T-shirts"),
+		bank,
+		watches,
+		zara,
+		jackets,
+	}
+}
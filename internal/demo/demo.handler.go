@@ -0,0 +1,51 @@
+package demo
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// NewHandler serves the demo scenarios:
+//
+//	GET  /demo/scenarios         lists the scenarios
+//	POST /demo/scenarios/{name}  runs one and returns its priced outcome with explanations
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /demo/scenarios", listScenarios)
+	mux.HandleFunc("POST /demo/scenarios/{name}", runScenario)
+	return mux
+}
+
+type scenarioSummary struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func listScenarios(w http.ResponseWriter, r *http.Request) {
+	scenarios := Scenarios()
+	summaries := make([]scenarioSummary, len(scenarios))
+	for i, s := range scenarios {
+		summaries[i] = scenarioSummary{Name: s.Name, Description: s.Description}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"scenarios": summaries})
+}
+
+func runScenario(w http.ResponseWriter, r *http.Request) {
+	outcome, err := Run(r.Context(), r.PathValue("name"))
+	switch {
+	case errors.IsNotFoundError(err):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+	default:
+		writeJSON(w, http.StatusOK, outcome)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,102 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// Scenario is a named checkout against the demo catalog
+type Scenario struct {
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	Request     *models.CalculationRequest `json:"request"`
+}
+
+// Outcome is a scenario together with the engine's explanation of how it was priced
+type Outcome struct {
+	Scenario
+	Explanation *models.Explanation `json:"explanation"`
+}
+
+// Scenarios returns the named demo scenarios in display order
+func Scenarios() []Scenario {
+	products := Products()
+	customers := Customers()
+	bank, credit := "ICICI", models.Credit
+
+	return []Scenario{
+		{
+			Name:        "multiple-discount",
+			Description: "Two PUMA T-shirts paid with an ICICI credit card: brand, category and bank discounts stack",
+			Request: &models.CalculationRequest{
+				CartItems:   []models.CartItem{{Product: products[0], Quantity: 2, Size: "M"}},
+				Customer:    customers[0],
+				PaymentInfo: &models.PaymentInfo{Method: models.Card, BankName: &bank, CardType: &credit},
+			},
+		},
+		{
+			Name:        "cap-hit",
+			Description: "A Fossil watch under Watch week 30%: the discount is cut to the 500 left of its total spend cap",
+			Request: &models.CalculationRequest{
+				CartItems: []models.CartItem{{Product: products[3], Quantity: 1}},
+				Customer:  customers[1],
+			},
+		},
+		{
+			Name: "exclusivity-conflict",
+			Description: "A Zara jacket matching two Winter sale discounts: the higher priority one spends the " +
+				"campaign's whole budget, so the other is skipped",
+			Request: &models.CalculationRequest{
+				CartItems: []models.CartItem{{Product: products[2], Quantity: 1, Size: "L"}},
+				Customer:  customers[1],
+			},
+		},
+	}
+}
+
+// Find returns the scenario with the given name
+func Find(name string) (Scenario, bool) {
+	for _, s := range Scenarios() {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Scenario{}, false
+}
+
+// NewSandbox creates an isolated environment holding the demo catalog, priced at Now
+func NewSandbox(ctx context.Context) (*services.Sandbox, error) {
+	sandbox, err := services.NewSandbox(Discounts(), services.WithClock(clock.NewFixed(Now)))
+	if err != nil {
+		return nil, err
+	}
+	for _, campaign := range Campaigns() {
+		if err := sandbox.Campaigns().CreateCampaign(ctx, &campaign); err != nil {
+			return nil, fmt.Errorf("failed to seed demo campaign: %w", err)
+		}
+	}
+	return sandbox, nil
+}
+
+// Run prices the named scenario in a fresh sandbox, so runs never affect each other
+func Run(ctx context.Context, name string) (*Outcome, error) {
+	scenario, ok := Find(name)
+	if !ok {
+		return nil, errors.NewNotFoundError("unknown demo scenario: " + name)
+	}
+
+	sandbox, err := NewSandbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	explanation, err := sandbox.Service().ExplainCartDiscounts(ctx, scenario.Request)
+	if err != nil {
+		return nil, err
+	}
+	return &Outcome{Scenario: scenario, Explanation: explanation}, nil
+}
@@ -1,12 +1,16 @@
 package discount
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/ahsmha/discounts/internal/discount/strategies"
 	"github.com/ahsmha/discounts/internal/models"
 )
 
 // Factory holds a mapping from discount type to strategy instance.
 type StrategyFactory struct {
+	mu         sync.RWMutex
 	strategies map[models.DiscountType]DiscountStrategy
 }
 
@@ -17,10 +21,33 @@ func NewStrategyFactory() *StrategyFactory {
 			models.DiscountTypeCategory: &strategies.CategoryDiscountStrategy{},
 			models.DiscountTypeVoucher:  &strategies.VoucherDiscountStrategy{},
 			models.DiscountTypeBank:     &strategies.BankDiscountStrategy{},
+			models.DiscountTypeWallet:   &strategies.WalletDiscountStrategy{},
+
+			models.DiscountTypePointsRedemption: &strategies.PointsRedemptionStrategy{},
+			models.DiscountTypeReferral:         &strategies.ReferralDiscountStrategy{},
+			models.DiscountTypeSpendTier:        &strategies.SpendTierStrategy{},
+			models.DiscountTypeEmployee:         &strategies.EmployeeDiscountStrategy{},
 		},
 	}
 }
 
+// Register makes strategy price the discounts of discountType, replacing the strategy the
+// type had, built-in ones included. It is safe to call while the factory is in use.
+func (sf *StrategyFactory) Register(discountType models.DiscountType, strategy DiscountStrategy) error {
+	if discountType == "" {
+		return fmt.Errorf("discount type is required")
+	}
+	if strategy == nil {
+		return fmt.Errorf("discount type %q: strategy is nil", discountType)
+	}
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	sf.strategies[discountType] = strategy
+	return nil
+}
+
 func (sf *StrategyFactory) Get(discountType models.DiscountType) DiscountStrategy {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
 	return sf.strategies[discountType]
 }
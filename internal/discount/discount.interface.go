@@ -5,7 +5,29 @@ import (
 	"github.com/shopspring/decimal"
 )
 
+// DiscountStrategy decides whether and by how much a discount of one type reduces a cart.
+// Time-based validity is checked by the caller before a strategy is consulted.
 type DiscountStrategy interface {
 	IsApplicable(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) bool
 	Calculate(discount *models.Discount, cart []models.CartItem, currentTotal decimal.Decimal) decimal.Decimal
 }
+
+// CustomerCalculator is implemented by strategies whose amount depends on the customer,
+// for example on their loyalty balance. The service prefers it over Calculate.
+type CustomerCalculator interface {
+	CalculateForCustomer(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile,
+		currentTotal decimal.Decimal) decimal.Decimal
+}
+
+// SubtotalCalculator is implemented by strategies whose amount depends on the cart subtotal
+// left after brand discounts, such as spend tiers. The service prefers it over Calculate.
+type SubtotalCalculator interface {
+	CalculateOnSubtotal(discount *models.Discount, cart []models.CartItem, subtotal decimal.Decimal,
+		currentTotal decimal.Decimal) decimal.Decimal
+}
+
+// Explainer is implemented by strategies that can say why IsApplicable rejected a discount.
+// It is only consulted once IsApplicable has returned false, so it never changes what applies.
+type Explainer interface {
+	Explain(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) models.DecisionReason
+}
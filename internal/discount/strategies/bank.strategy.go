@@ -8,23 +8,35 @@ import (
 type BankDiscountStrategy struct{}
 
 func (s *BankDiscountStrategy) IsApplicable(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) bool {
-	if discount.Type != models.DiscountTypeBank || !discount.IsValid() || !discount.IsApplicableToCustomer(customer) {
+	if discount.Type != models.DiscountTypeBank || !discount.IsApplicableToCustomer(customer) {
 		return false
 	}
 
-	if payment == nil || payment.Method != "CARD" {
-		return false
-	}
-
-	if len(discount.ApplicableTo) > 0 && (payment.BankName == nil || !isInList(*payment.BankName, discount.ApplicableTo)) {
+	if !discount.MatchesPayment(payment) || !discount.MatchesCard(payment) || !hasPromotableItems(cart) {
 		return false
 	}
 
 	cartTotal := calculateCartTotal(cart)
-	return !discount.MinAmount.IsZero() || cartTotal.GreaterThanOrEqual(discount.MinAmount)
+	return discount.MinAmount.IsZero() || cartTotal.GreaterThanOrEqual(discount.MinAmount)
 }
 
 func (s *BankDiscountStrategy) Calculate(discount *models.Discount, cart []models.CartItem, currentTotal decimal.Decimal) decimal.Decimal {
-	eligibleAmount := currentTotal
+	eligibleAmount := eligibleTotal(cart, currentTotal)
 	return calculateDiscountValue(discount, eligibleAmount)
 }
+
+func (s *BankDiscountStrategy) Explain(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) models.DecisionReason {
+	if !discount.IsApplicableToCustomer(customer) {
+		return models.ReasonCustomerIneligible
+	}
+	if !discount.MatchesPayment(payment) {
+		return models.ReasonPaymentMismatch
+	}
+	if !discount.MatchesCard(payment) {
+		return models.ReasonCardMismatch
+	}
+	if !hasPromotableItems(cart) {
+		return models.ReasonItemsOptedOut
+	}
+	return models.ReasonMinAmountNotMet
+}
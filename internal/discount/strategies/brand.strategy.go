@@ -8,7 +8,7 @@ import (
 type BrandDiscountStrategy struct{}
 
 func (s *BrandDiscountStrategy) IsApplicable(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) bool {
-	if discount.Type != models.DiscountTypeBrand || !discount.IsValid() || !discount.IsApplicableToCustomer(customer) {
+	if discount.Type != models.DiscountTypeBrand || !discount.IsApplicableToCustomer(customer) {
 		return false
 	}
 
@@ -16,9 +16,12 @@ func (s *BrandDiscountStrategy) IsApplicable(discount *models.Discount, cart []m
 	if !discount.MinAmount.IsZero() && total.LessThan(discount.MinAmount) {
 		return false
 	}
+	if !meetsMinQuantity(discount, cart) {
+		return false
+	}
 
 	for _, item := range cart {
-		if discount.MatchesProduct(item.Product) {
+		if matchesItem(discount, item) {
 			return true
 		}
 	}
@@ -26,12 +29,9 @@ func (s *BrandDiscountStrategy) IsApplicable(discount *models.Discount, cart []m
 }
 
 func (s *BrandDiscountStrategy) Calculate(discount *models.Discount, cart []models.CartItem, currentTotal decimal.Decimal) decimal.Decimal {
-	var amount decimal.Decimal
-	for _, item := range cart {
-		if discount.MatchesProduct(item.Product) {
-			amount = amount.Add(item.GetTotalPrice())
-		}
-	}
+	return calculateDiscountValue(discount, matchingAmount(discount, cart))
+}
 
-	return calculateDiscountValue(discount, amount)
+func (s *BrandDiscountStrategy) Explain(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) models.DecisionReason {
+	return explainItems(discount, cart, customer)
 }
@@ -0,0 +1,43 @@
+package strategies
+
+import (
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// EmployeeDiscountStrategy applies staff discounts to the carts of employees, on the items
+// ApplicableTo targets or on the whole cart. The service holds them to their MonthlyCap.
+type EmployeeDiscountStrategy struct{}
+
+func (s *EmployeeDiscountStrategy) IsApplicable(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) bool {
+	if discount.Type != models.DiscountTypeEmployee || !customer.IsEmployee || !discount.IsApplicableToCustomer(customer) {
+		return false
+	}
+
+	total := calculateCartTotal(cart)
+	if !discount.MinAmount.IsZero() && total.LessThan(discount.MinAmount) {
+		return false
+	}
+
+	for _, item := range cart {
+		if matchesItem(discount, item) {
+			return true
+		}
+	}
+
+	return len(discount.ApplicableTo) == 0 && len(discount.ExcludedItems) == 0 && hasPromotableItems(cart)
+}
+
+func (s *EmployeeDiscountStrategy) Calculate(discount *models.Discount, cart []models.CartItem, currentTotal decimal.Decimal) decimal.Decimal {
+	if len(discount.ApplicableTo) == 0 && len(discount.ExcludedItems) == 0 {
+		return calculateDiscountValue(discount, eligibleTotal(cart, currentTotal))
+	}
+	return calculateDiscountValue(discount, decimal.Min(eligibleTotal(cart, currentTotal), matchingAmount(discount, cart)))
+}
+
+func (s *EmployeeDiscountStrategy) Explain(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) models.DecisionReason {
+	if !customer.IsEmployee {
+		return models.ReasonCustomerIneligible
+	}
+	return explainItems(discount, cart, customer)
+}
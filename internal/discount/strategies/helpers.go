@@ -28,19 +28,75 @@ func calculateDiscountValue(discount *models.Discount, baseAmount decimal.Decima
 	return discountAmount
 }
 
+// calculateCartTotal returns the total of the items open to promotions, which minimum
+// amounts are checked against
 func calculateCartTotal(cart []models.CartItem) decimal.Decimal {
-	total := decimal.Zero
-	for _, item := range cart {
-		total = total.Add(item.GetTotalPrice())
-	}
-	return total
+	return models.GetPromotableTotal(cart)
+}
+
+// eligibleTotal returns the part of the running cart total a cart-wide discount may be taken
+// off: lines excluded from promotions are never discounted, so they still count at full price
+func eligibleTotal(cart []models.CartItem, currentTotal decimal.Decimal) decimal.Decimal {
+	eligible := currentTotal.Sub(models.GetCartTotal(cart).Sub(models.GetPromotableTotal(cart)))
+	return decimal.Max(eligible, decimal.Zero)
 }
 
-func isInList(item string, list []string) bool {
-	for _, l := range list {
-		if l == item {
+// hasPromotableItems reports whether any line is open to promotions
+func hasPromotableItems(cart []models.CartItem) bool {
+	for _, item := range cart {
+		if !item.ExcludeFromPromotions {
 			return true
 		}
 	}
 	return false
 }
+
+// matchesItem reports whether the discount targets the line, which must be open to promotions
+func matchesItem(discount *models.Discount, item models.CartItem) bool {
+	return !item.ExcludeFromPromotions && discount.MatchesProduct(item.Product)
+}
+
+// matchingLines returns the lines the discount targets
+func matchingLines(discount *models.Discount, cart []models.CartItem) []models.CartItem {
+	var lines []models.CartItem
+	for _, item := range cart {
+		if matchesItem(discount, item) {
+			lines = append(lines, item)
+		}
+	}
+	return lines
+}
+
+// matchingAmount returns the price of the targeted units the discount is taken off, see
+// models.Discount.SelectedAmount
+func matchingAmount(discount *models.Discount, cart []models.CartItem) decimal.Decimal {
+	return discount.SelectedAmount(matchingLines(discount, cart))
+}
+
+// meetsMinQuantity reports whether the cart holds the targeted units the discount requires
+func meetsMinQuantity(discount *models.Discount, cart []models.CartItem) bool {
+	return discount.MinQuantity == 0 || models.CountUnits(matchingLines(discount, cart)) >= discount.MinQuantity
+}
+
+// explainItems reports why a discount with the usual customer, minimum amount and item
+// conditions rejected the cart
+func explainItems(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile) models.DecisionReason {
+	if !discount.IsApplicableToCustomer(customer) {
+		return models.ReasonCustomerIneligible
+	}
+	if !hasPromotableItems(cart) {
+		return models.ReasonItemsOptedOut
+	}
+	if !discount.MinAmount.IsZero() && calculateCartTotal(cart).LessThan(discount.MinAmount) {
+		return models.ReasonMinAmountNotMet
+	}
+	if len(matchingLines(discount, cart)) > 0 && !meetsMinQuantity(discount, cart) {
+		return models.ReasonMinQuantityNotMet
+	}
+	for _, item := range cart {
+		if !item.ExcludeFromPromotions && discount.IsExcluded(item.Product) {
+			return models.ReasonExcluded
+		}
+	}
+	return models.ReasonNoMatchingItems
+}
@@ -0,0 +1,50 @@
+package strategies
+
+import (
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+type PointsRedemptionStrategy struct{}
+
+func (s *PointsRedemptionStrategy) IsApplicable(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) bool {
+	if discount.Type != models.DiscountTypePointsRedemption || !discount.IsApplicableToCustomer(customer) {
+		return false
+	}
+
+	if customer.PointsBalance <= 0 || !discount.Value.IsPositive() {
+		return false
+	}
+
+	total := calculateCartTotal(cart)
+	return discount.MinAmount.IsZero() || total.GreaterThanOrEqual(discount.MinAmount)
+}
+
+// Calculate cannot know the customer's balance, so it reports no credit; the service
+// prices points through CalculateForCustomer
+func (s *PointsRedemptionStrategy) Calculate(discount *models.Discount, cart []models.CartItem, currentTotal decimal.Decimal) decimal.Decimal {
+	return decimal.Zero
+}
+
+func (s *PointsRedemptionStrategy) CalculateForCustomer(discount *models.Discount, cart []models.CartItem,
+	customer models.CustomerProfile, currentTotal decimal.Decimal) decimal.Decimal {
+
+	credit := decimal.NewFromInt(customer.PointsBalance).Mul(discount.Value)
+	if !discount.MaxAmount.IsZero() && credit.GreaterThan(discount.MaxAmount) {
+		credit = discount.MaxAmount
+	}
+	if eligible := eligibleTotal(cart, currentTotal); credit.GreaterThan(eligible) {
+		return eligible
+	}
+	return credit
+}
+
+func (s *PointsRedemptionStrategy) Explain(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) models.DecisionReason {
+	if !discount.IsApplicableToCustomer(customer) {
+		return models.ReasonCustomerIneligible
+	}
+	if customer.PointsBalance <= 0 || !discount.Value.IsPositive() {
+		return models.ReasonNoPoints
+	}
+	return models.ReasonMinAmountNotMet
+}
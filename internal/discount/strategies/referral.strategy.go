@@ -0,0 +1,42 @@
+package strategies
+
+import (
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+type ReferralDiscountStrategy struct{}
+
+func (s *ReferralDiscountStrategy) IsApplicable(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) bool {
+	if discount.Type != models.DiscountTypeReferral || !discount.IsApplicableToCustomer(customer) {
+		return false
+	}
+
+	// Referrers cannot reward themselves with their own code
+	if customer.ID == "" || customer.ID == discount.ReferrerID || !hasPromotableItems(cart) {
+		return false
+	}
+
+	total := calculateCartTotal(cart)
+	return discount.MinAmount.IsZero() || total.GreaterThanOrEqual(discount.MinAmount)
+}
+
+func (s *ReferralDiscountStrategy) Calculate(discount *models.Discount, cart []models.CartItem, currentTotal decimal.Decimal) decimal.Decimal {
+	return calculateDiscountValue(discount, eligibleTotal(cart, currentTotal))
+}
+
+func (s *ReferralDiscountStrategy) Explain(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) models.DecisionReason {
+	if !discount.IsApplicableToCustomer(customer) || customer.ID == "" {
+		return models.ReasonCustomerIneligible
+	}
+	if customer.ID == discount.ReferrerID {
+		return models.ReasonSelfReferral
+	}
+	if len(cart) == 0 {
+		return models.ReasonNoMatchingItems
+	}
+	if !hasPromotableItems(cart) {
+		return models.ReasonItemsOptedOut
+	}
+	return models.ReasonMinAmountNotMet
+}
@@ -0,0 +1,63 @@
+package strategies
+
+import (
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// SpendTierStrategy applies "spend more save more" discounts, taking off the value of the
+// highest tier the subtotal left after brand discounts reaches
+type SpendTierStrategy struct{}
+
+func (s *SpendTierStrategy) IsApplicable(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) bool {
+	if discount.Type != models.DiscountTypeSpendTier || !discount.IsApplicableToCustomer(customer) {
+		return false
+	}
+	if !hasPromotableItems(cart) {
+		return false
+	}
+
+	// Brand discounts only lower the subtotal, so carts below the lowest tier at full price never reach it
+	total := calculateCartTotal(cart)
+	if !discount.MinAmount.IsZero() && total.LessThan(discount.MinAmount) {
+		return false
+	}
+	_, reached := discount.ReachedSpendTier(total)
+	return reached
+}
+
+// Calculate picks the tier on the running total, for callers without the brand-discounted subtotal
+func (s *SpendTierStrategy) Calculate(discount *models.Discount, cart []models.CartItem, currentTotal decimal.Decimal) decimal.Decimal {
+	return s.CalculateOnSubtotal(discount, cart, eligibleTotal(cart, currentTotal), currentTotal)
+}
+
+func (s *SpendTierStrategy) CalculateOnSubtotal(discount *models.Discount, cart []models.CartItem, subtotal decimal.Decimal,
+	currentTotal decimal.Decimal) decimal.Decimal {
+
+	tier, reached := discount.ReachedSpendTier(subtotal)
+	if !reached {
+		return decimal.Zero
+	}
+
+	amount := tier.Value
+	if discount.IsPercentage {
+		amount = subtotal.Mul(tier.Value).Div(decimal.NewFromInt(models.PercentageBase))
+	}
+	if !discount.MaxAmount.IsZero() && amount.GreaterThan(discount.MaxAmount) {
+		amount = discount.MaxAmount
+	}
+	return decimal.Min(amount, eligibleTotal(cart, currentTotal))
+}
+
+func (s *SpendTierStrategy) Explain(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) models.DecisionReason {
+	if !discount.IsApplicableToCustomer(customer) {
+		return models.ReasonCustomerIneligible
+	}
+	if !hasPromotableItems(cart) {
+		return models.ReasonItemsOptedOut
+	}
+	if !discount.MinAmount.IsZero() && calculateCartTotal(cart).LessThan(discount.MinAmount) {
+		return models.ReasonMinAmountNotMet
+	}
+	return models.ReasonSpendTierNotReached
+}
@@ -9,7 +9,7 @@ type VoucherDiscountStrategy struct{}
 
 func (s *VoucherDiscountStrategy) IsApplicable(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) bool {
 
-	if discount.Type != models.DiscountTypeVoucher || !discount.IsValid() || !discount.IsApplicableToCustomer(customer) {
+	if discount.Type != models.DiscountTypeVoucher || !discount.IsApplicableToCustomer(customer) {
 		return false
 	}
 
@@ -17,16 +17,28 @@ func (s *VoucherDiscountStrategy) IsApplicable(discount *models.Discount, cart [
 	if !discount.MinAmount.IsZero() && total.LessThan(discount.MinAmount) {
 		return false
 	}
+	if !meetsMinQuantity(discount, cart) {
+		return false
+	}
 
 	for _, item := range cart {
-		if discount.MatchesProduct(item.Product) {
+		if matchesItem(discount, item) {
 			return true
 		}
 	}
 
-	return len(discount.ExcludedItems) == 0 && len(cart) > 0
+	return len(discount.ApplicableTo) == 0 && len(discount.ExcludedItems) == 0 && hasPromotableItems(cart)
 }
 
 func (s *VoucherDiscountStrategy) Calculate(discount *models.Discount, cart []models.CartItem, currentTotal decimal.Decimal) decimal.Decimal {
-	return calculateDiscountValue(discount, currentTotal)
+	eligible := eligibleTotal(cart, currentTotal)
+	if discount.TargetSelection != "" && discount.TargetSelection != models.TargetAll {
+		// Only the selected units, of what earlier discounts left of the cart
+		eligible = decimal.Min(eligible, matchingAmount(discount, cart))
+	}
+	return calculateDiscountValue(discount, eligible)
+}
+
+func (s *VoucherDiscountStrategy) Explain(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) models.DecisionReason {
+	return explainItems(discount, cart, customer)
 }
@@ -0,0 +1,41 @@
+package strategies
+
+import (
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// WalletDiscountStrategy applies offers for paying with a wallet, such as 5% off for
+// paying with Paytm, matching the payment's wallet provider against ApplicableTo
+type WalletDiscountStrategy struct{}
+
+func (s *WalletDiscountStrategy) IsApplicable(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) bool {
+	if discount.Type != models.DiscountTypeWallet || !discount.IsApplicableToCustomer(customer) {
+		return false
+	}
+
+	if !discount.MatchesPayment(payment) || !hasPromotableItems(cart) {
+		return false
+	}
+
+	cartTotal := calculateCartTotal(cart)
+	return discount.MinAmount.IsZero() || cartTotal.GreaterThanOrEqual(discount.MinAmount)
+}
+
+func (s *WalletDiscountStrategy) Calculate(discount *models.Discount, cart []models.CartItem, currentTotal decimal.Decimal) decimal.Decimal {
+	eligibleAmount := eligibleTotal(cart, currentTotal)
+	return calculateDiscountValue(discount, eligibleAmount)
+}
+
+func (s *WalletDiscountStrategy) Explain(discount *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) models.DecisionReason {
+	if !discount.IsApplicableToCustomer(customer) {
+		return models.ReasonCustomerIneligible
+	}
+	if !discount.MatchesPayment(payment) {
+		return models.ReasonPaymentMismatch
+	}
+	if !hasPromotableItems(cart) {
+		return models.ReasonItemsOptedOut
+	}
+	return models.ReasonMinAmountNotMet
+}
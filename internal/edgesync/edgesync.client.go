@@ -0,0 +1,92 @@
+package edgesync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// defaultClientTimeout bounds a sync call, so an edge on a failing link gives up and
+// prices from its local copy
+const defaultClientTimeout = 10 * time.Second
+
+// Client is a Source reached over HTTP, served by NewHandler at BaseURL
+type Client struct {
+	BaseURL string
+	Token   string       // Sent as the Authorization bearer token, an API key or JWT with the sync role
+	Client  *http.Client // Defaults to a client with a 10 second timeout
+}
+
+var _ Source = Client{}
+
+// Changes fetches the context tenant's changes after the cursor
+func (c Client) Changes(ctx context.Context, cursor int64, limit int) (*models.ChangePage, error) {
+	query := url.Values{"cursor": {strconv.FormatInt(cursor, 10)}}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var page models.ChangePage
+	if err := c.do(ctx, http.MethodGet, "/sync/changes?"+query.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ReportUsage posts the report for the context tenant
+func (c Client) ReportUsage(ctx context.Context, report models.UsageReport) error {
+	return c.do(ctx, http.MethodPost, "/sync/usage", report, nil)
+}
+
+// do sends body, when set, as JSON and decodes the response into out, when set, failing
+// on any non-2xx response
+func (c Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if tenantID := tenant.FromContext(ctx); tenantID != tenant.Default {
+		req.Header.Set(api.TenantHeader, tenantID)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultClientTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sync server answered %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
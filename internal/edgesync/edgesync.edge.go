@@ -0,0 +1,255 @@
+package edgesync
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// Edge is an in-memory copy of the central discounts that checkouts price from without
+// reaching the central store. Uses are counted locally, and reported on the next Sync, so
+// discounts near their usage limit may be granted a few times too often while the link is
+// down. Discounts are managed centrally, so the write methods fail.
+//
+// Sync is per tenant: each tenant priced at the edge keeps its own cursor and usage.
+type Edge struct {
+	interfaces.IDiscountRepository
+
+	source Source
+	local  interfaces.DiscountSeeder
+
+	// EdgeID names the edge in its usage reports, a random ID by default, and PageSize is
+	// how many changes it pulls at a time, DefaultPageSize when zero
+	EdgeID   string
+	PageSize int
+
+	// OnError, when set, is called by Run with every failed sync
+	OnError func(err error)
+
+	mu       sync.Mutex                     // Held while counting uses and applying changes, so neither loses the other
+	cursors  map[string]int64               // tenant -> cursor synced up to
+	pending  map[string]map[string]int      // tenant -> discount id -> uses not reported yet
+	unacked  map[string]*models.UsageReport // tenant -> report sent but not acknowledged
+	sequence int64                          // of the last report, starting at the edge's creation time
+}
+
+var (
+	_ interfaces.IDiscountRepository = (*Edge)(nil)
+	_ interfaces.UsageReleaser       = (*Edge)(nil)
+)
+
+// NewEdge creates an empty edge store syncing from source; Sync fills it
+func NewEdge(source Source) *Edge {
+	local := repositories.NewInMemoryDiscountRepository()
+	return &Edge{
+		IDiscountRepository: local,
+		source:              source,
+		local:               local.(interfaces.DiscountSeeder),
+		EdgeID:              newEdgeID(),
+		cursors:             make(map[string]int64),
+		pending:             make(map[string]map[string]int),
+		unacked:             make(map[string]*models.UsageReport),
+		// An edge restarted under the same EdgeID carries on above its earlier reports
+		sequence: time.Now().UnixNano(),
+	}
+}
+
+// CreateDiscount fails, discounts are created centrally
+func (e *Edge) CreateDiscount(ctx context.Context, discount *models.Discount) error {
+	return e.readOnly(discount.ID)
+}
+
+// UpdateDiscount fails, discounts are changed centrally
+func (e *Edge) UpdateDiscount(ctx context.Context, discount *models.Discount) error {
+	return e.readOnly(discount.ID)
+}
+
+// DeleteDiscount fails, discounts are deleted centrally
+func (e *Edge) DeleteDiscount(ctx context.Context, id string) error {
+	return e.readOnly(id)
+}
+
+func (e *Edge) readOnly(id string) error {
+	return errors.NewForbiddenError("discounts are managed by the central store and cannot be changed at the edge: " + id)
+}
+
+// IncrementUsageCount counts a use of the discount, reported on the next Sync
+func (e *Edge) IncrementUsageCount(ctx context.Context, id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.IDiscountRepository.IncrementUsageCount(ctx, id); err != nil {
+		return err
+	}
+	e.countUse(ctx, id)
+	return nil
+}
+
+// CheckAndIncrementUsage counts a use of the discount unless the local count says it is at
+// its usage limit, reported on the next Sync
+func (e *Edge) CheckAndIncrementUsage(ctx context.Context, id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.IDiscountRepository.CheckAndIncrementUsage(ctx, id); err != nil {
+		return err
+	}
+	e.countUse(ctx, id)
+	return nil
+}
+
+// ReleaseUsage gives back a use counted locally and not reported yet
+func (e *Edge) ReleaseUsage(ctx context.Context, id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	pending := e.pending[tenant.FromContext(ctx)]
+	if pending[id] == 0 {
+		return errors.NewConflictError("no unreported use of discount " + id + " to release")
+	}
+	if err := e.IDiscountRepository.(interfaces.UsageReleaser).ReleaseUsage(ctx, id); err != nil {
+		return err
+	}
+	if pending[id]--; pending[id] == 0 {
+		delete(pending, id)
+	}
+	return nil
+}
+
+// Pending returns the context tenant's uses not reported yet, by discount id
+func (e *Edge) Pending(ctx context.Context) map[string]int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.pendingOf(ctx)
+}
+
+// Cursor returns the position in the feed the context tenant has synced up to
+func (e *Edge) Cursor(ctx context.Context) int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.cursors[tenant.FromContext(ctx)]
+}
+
+// Sync reports the context tenant's pending uses, then pulls its changes until caught up.
+// Whatever was done before a failure is kept: the next Sync resumes from there. A report
+// that was not acknowledged is sent again as it was, so the feed can tell it was applied.
+func (e *Edge) Sync(ctx context.Context) error {
+	tenantID := tenant.FromContext(ctx)
+	e.mu.Lock()
+	report := e.unacked[tenantID]
+	if uses := e.pendingOf(ctx); report == nil && len(uses) > 0 {
+		e.sequence++
+		report = &models.UsageReport{EdgeID: e.EdgeID, Sequence: e.sequence, Uses: uses}
+		e.unacked[tenantID] = report
+	}
+	e.mu.Unlock()
+
+	if report != nil {
+		if err := e.source.ReportUsage(ctx, *report); err != nil {
+			return fmt.Errorf("failed to report usage: %w", err)
+		}
+		// Uses counted while reporting stay pending
+		e.mu.Lock()
+		for id, n := range report.Uses {
+			pending := e.pending[tenantID]
+			if pending[id] -= n; pending[id] <= 0 {
+				delete(pending, id)
+			}
+		}
+		delete(e.unacked, tenantID)
+		e.mu.Unlock()
+	}
+
+	for {
+		page, err := e.source.Changes(ctx, e.Cursor(ctx), e.PageSize)
+		if err != nil {
+			return fmt.Errorf("failed to pull changes: %w", err)
+		}
+		if err := e.apply(ctx, page); err != nil {
+			return err
+		}
+		if !page.More {
+			return nil
+		}
+	}
+}
+
+// Run syncs the context tenant every interval until ctx is done. Failed syncs are passed
+// to OnError and retried on the next tick, with the edge serving its last copy meanwhile.
+func (e *Edge) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := e.Sync(ctx); err != nil && ctx.Err() == nil && e.OnError != nil {
+			e.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// apply stores the page's changes locally and advances the cursor past them
+func (e *Edge) apply(ctx context.Context, page *models.ChangePage) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	pending := e.pending[tenant.FromContext(ctx)]
+	for _, change := range page.Changes {
+		if change.Deleted {
+			err := e.IDiscountRepository.DeleteDiscount(ctx, change.DiscountID)
+			if err != nil && !errors.IsNotFoundError(err) {
+				return fmt.Errorf("failed to delete discount %s: %w", change.DiscountID, err)
+			}
+			delete(pending, change.DiscountID)
+			continue
+		}
+		if change.Discount == nil {
+			return fmt.Errorf("change %d to discount %s carries no discount", change.Cursor, change.DiscountID)
+		}
+
+		// The central count does not hold the uses still to be reported
+		d := *change.Discount
+		d.TenantID = tenant.FromContext(ctx)
+		d.UsedCount += pending[change.DiscountID]
+		if err := e.local.SeedDiscounts([]models.Discount{d}); err != nil {
+			return fmt.Errorf("failed to store discount %s: %w", d.ID, err)
+		}
+	}
+	e.cursors[tenant.FromContext(ctx)] = page.NextCursor
+	return nil
+}
+
+// countUse adds a use of the discount to the context tenant's pending uses
+func (e *Edge) countUse(ctx context.Context, id string) {
+	tenantID := tenant.FromContext(ctx)
+	if e.pending[tenantID] == nil {
+		e.pending[tenantID] = make(map[string]int)
+	}
+	e.pending[tenantID][id]++
+}
+
+// pendingOf copies the context tenant's pending uses
+func (e *Edge) pendingOf(ctx context.Context) map[string]int {
+	uses := make(map[string]int, len(e.pending[tenant.FromContext(ctx)]))
+	for id, n := range e.pending[tenant.FromContext(ctx)] {
+		uses[id] = n
+	}
+	return uses
+}
+
+func newEdgeID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return "edge-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,323 @@
+// Package edgesync keeps edge discount stores, running next to checkouts, in step with the
+// central store over links that come and go. The central repository is wrapped in a Feed,
+// which remembers the latest change to every discount under an increasing cursor. Edges
+// pull the changes after their cursor, deletions arriving as tombstones, and push back the
+// uses they counted; an edge that cannot reach the central store keeps pricing from its
+// local copy and catches up on its next sync.
+package edgesync
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+const (
+	// DefaultPageSize is how many changes a page holds when the caller does not say
+	DefaultPageSize = 500
+
+	// MaxReportedUses bounds the uses of one discount a single usage report may carry
+	MaxReportedUses = 1_000_000
+)
+
+// Source is what an edge syncs from: a Feed in the same process or a Client of one
+// served over HTTP
+type Source interface {
+	// Changes returns the context tenant's changes after the cursor, at most limit of them
+	Changes(ctx context.Context, cursor int64, limit int) (*models.ChangePage, error)
+
+	// ReportUsage adds the uses an edge counted to the central usage counts
+	ReportUsage(ctx context.Context, report models.UsageReport) error
+}
+
+// Feed is the central repository recording a change for every discount written through it,
+// usage counts included, so edges see the counts of every other edge. Only the latest
+// change of each discount is kept: an edge far behind receives each discount once.
+//
+// Discounts stored before the feed wrapped the repository enter the feed the first time a
+// tenant's changes are requested, when the repository is an interfaces.DiscountLister.
+type Feed struct {
+	interfaces.IDiscountRepository
+
+	mu      sync.Mutex // Held across every write so cursors follow the order of the writes
+	cursor  int64
+	latest  map[string]models.DiscountChange // tenant-scoped discount id -> latest change
+	adopted map[string]bool                  // tenants whose stored discounts entered the feed
+	applied map[string]int64                 // tenant-scoped edge id -> sequence of its last report applied
+}
+
+var _ Source = (*Feed)(nil)
+
+// NewFeed wraps the central repository
+func NewFeed(repo interfaces.IDiscountRepository) *Feed {
+	return &Feed{
+		IDiscountRepository: repo,
+		latest:              make(map[string]models.DiscountChange),
+		adopted:             make(map[string]bool),
+		applied:             make(map[string]int64),
+	}
+}
+
+func (f *Feed) CreateDiscount(ctx context.Context, discount *models.Discount) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.IDiscountRepository.CreateDiscount(ctx, discount); err != nil {
+		return err
+	}
+	return f.record(ctx, discount.ID)
+}
+
+func (f *Feed) UpdateDiscount(ctx context.Context, discount *models.Discount) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.IDiscountRepository.UpdateDiscount(ctx, discount); err != nil {
+		return err
+	}
+	return f.record(ctx, discount.ID)
+}
+
+func (f *Feed) DeleteDiscount(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.IDiscountRepository.DeleteDiscount(ctx, id); err != nil {
+		return err
+	}
+	f.cursor++
+	f.latest[tenant.Key(ctx, id)] = models.DiscountChange{
+		Cursor:     f.cursor,
+		DiscountID: id,
+		Deleted:    true,
+		ChangedAt:  clock.FromContext(ctx),
+	}
+	return nil
+}
+
+func (f *Feed) IncrementUsageCount(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.IDiscountRepository.IncrementUsageCount(ctx, id); err != nil {
+		return err
+	}
+	return f.record(ctx, id)
+}
+
+func (f *Feed) CheckAndIncrementUsage(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.IDiscountRepository.CheckAndIncrementUsage(ctx, id); err != nil {
+		return err
+	}
+	return f.record(ctx, id)
+}
+
+// ReleaseUsage gives back a use through the wrapped repository, which must be an
+// interfaces.UsageReleaser
+func (f *Feed) ReleaseUsage(ctx context.Context, id string) error {
+	releaser, ok := f.IDiscountRepository.(interfaces.UsageReleaser)
+	if !ok {
+		return fmt.Errorf("repository cannot release usage")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := releaser.ReleaseUsage(ctx, id); err != nil {
+		return err
+	}
+	return f.record(ctx, id)
+}
+
+// ListDiscounts lists through the wrapped repository, see interfaces.DiscountLister
+func (f *Feed) ListDiscounts(ctx context.Context, filter models.ListFilter) (*models.DiscountPage, error) {
+	lister, ok := f.IDiscountRepository.(interfaces.DiscountLister)
+	if !ok {
+		return nil, fmt.Errorf("repository cannot list discounts")
+	}
+	return lister.ListDiscounts(ctx, filter)
+}
+
+// CatalogStats summarises through the wrapped repository, see interfaces.CatalogInspector
+func (f *Feed) CatalogStats(ctx context.Context, window time.Duration) (*models.CatalogStats, error) {
+	inspector, ok := f.IDiscountRepository.(interfaces.CatalogInspector)
+	if !ok {
+		return nil, fmt.Errorf("repository cannot summarise its catalog")
+	}
+	return inspector.CatalogStats(ctx, window)
+}
+
+// Changes returns the context tenant's changes after the cursor, oldest first. Limits of
+// zero or less return DefaultPageSize changes.
+func (f *Feed) Changes(ctx context.Context, cursor int64, limit int) (*models.ChangePage, error) {
+	if cursor < 0 {
+		return nil, errors.NewValidationError(fmt.Sprintf("invalid cursor: %d", cursor))
+	}
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.adopt(ctx); err != nil {
+		return nil, err
+	}
+
+	tenantID := tenant.FromContext(ctx)
+	var changes []models.DiscountChange
+	for key, change := range f.latest {
+		if change.Cursor > cursor && tenant.Owns(tenantID, key) {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Cursor < changes[j].Cursor })
+
+	page := &models.ChangePage{Changes: changes, NextCursor: cursor}
+	if len(changes) > limit {
+		page.Changes, page.More = changes[:limit], true
+	}
+	if n := len(page.Changes); n > 0 {
+		page.NextCursor = page.Changes[n-1].Cursor
+	}
+	return page, nil
+}
+
+// ReportUsage adds the reported uses to the central counts, all of them or none. Uses of
+// discounts deleted since are dropped; limits are not enforced, the edge already granted
+// the uses. A report of an edge at or below the sequence last applied for it is a resend
+// and is dropped.
+func (f *Feed) ReportUsage(ctx context.Context, report models.UsageReport) error {
+	ids := make([]string, 0, len(report.Uses))
+	for id, n := range report.Uses {
+		if n < 0 || n > MaxReportedUses {
+			return errors.NewValidationError(fmt.Sprintf("invalid use count %d of %s, must be 0 to %d", n, id, MaxReportedUses))
+		}
+		if n > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	edgeKey := tenant.Key(ctx, report.EdgeID)
+	sequenced := report.EdgeID != "" && report.Sequence > 0
+	if sequenced && report.Sequence <= f.applied[edgeKey] {
+		return nil
+	}
+	if err := f.addUsage(ctx, ids, report.Uses); err != nil {
+		return err
+	}
+	if sequenced {
+		f.applied[edgeKey] = report.Sequence
+	}
+	for _, id := range ids {
+		if err := f.record(ctx, id); err != nil && !errors.IsNotFoundError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// addUsage counts the uses in one call when the repository is an interfaces.UsageAdder,
+// and a use at a time otherwise, giving the uses counted back when one fails
+func (f *Feed) addUsage(ctx context.Context, ids []string, uses map[string]int) error {
+	if adder, ok := f.IDiscountRepository.(interfaces.UsageAdder); ok {
+		err := adder.AddUsage(ctx, uses)
+		if err == nil {
+			return nil
+		}
+		if !stderrors.Is(err, stderrors.ErrUnsupported) {
+			return fmt.Errorf("failed to count uses: %w", err)
+		}
+	}
+
+	counted := make(map[string]int)
+	for _, id := range ids {
+		for i := 0; i < uses[id]; i++ {
+			err := f.IDiscountRepository.IncrementUsageCount(ctx, id)
+			if errors.IsNotFoundError(err) {
+				break
+			}
+			if err != nil {
+				err = fmt.Errorf("failed to count uses of %s: %w", id, err)
+				if undoErr := f.releaseUsage(ctx, counted); undoErr != nil {
+					return fmt.Errorf("%w, and %v", err, undoErr)
+				}
+				return err
+			}
+			counted[id]++
+		}
+	}
+	return nil
+}
+
+// releaseUsage gives back uses counted by a report that failed part-way
+func (f *Feed) releaseUsage(ctx context.Context, counted map[string]int) error {
+	if len(counted) == 0 {
+		return nil
+	}
+	releaser, ok := f.IDiscountRepository.(interfaces.UsageReleaser)
+	if !ok {
+		return fmt.Errorf("the uses counted so far cannot be given back")
+	}
+	for id, n := range counted {
+		for ; n > 0; n-- {
+			if err := releaser.ReleaseUsage(ctx, id); err != nil {
+				return fmt.Errorf("failed to give back uses of %s: %w", id, err)
+			}
+		}
+	}
+	return nil
+}
+
+// record makes the discount as now stored the latest change to it
+func (f *Feed) record(ctx context.Context, id string) error {
+	discount, err := f.IDiscountRepository.GetDiscountByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to read back discount %s: %w", id, err)
+	}
+	f.cursor++
+	f.latest[tenant.Key(ctx, id)] = models.DiscountChange{
+		Cursor:     f.cursor,
+		DiscountID: id,
+		Discount:   discount,
+		ChangedAt:  clock.FromContext(ctx),
+	}
+	return nil
+}
+
+// adopt enters the context tenant's stored discounts into the feed the first time its
+// changes are requested
+func (f *Feed) adopt(ctx context.Context) error {
+	tenantID := tenant.FromContext(ctx)
+	lister, ok := f.IDiscountRepository.(interfaces.DiscountLister)
+	if f.adopted[tenantID] || !ok {
+		return nil
+	}
+
+	page, err := lister.ListDiscounts(ctx, models.ListFilter{})
+	if err != nil {
+		return fmt.Errorf("failed to list discounts: %w", err)
+	}
+	for i := range page.Discounts {
+		d := page.Discounts[i]
+		if _, known := f.latest[tenant.Key(ctx, d.ID)]; known {
+			continue
+		}
+		f.cursor++
+		f.latest[tenant.Key(ctx, d.ID)] = models.DiscountChange{
+			Cursor:     f.cursor,
+			DiscountID: d.ID,
+			Discount:   &d,
+			ChangedAt:  clock.FromContext(ctx),
+		}
+	}
+	f.adopted[tenantID] = true
+	return nil
+}
@@ -0,0 +1,99 @@
+package edgesync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// maxUsageReportBytes bounds the body of a usage report
+const maxUsageReportBytes = 1 << 20
+
+// NewHandler serves the feed to edges, as of c's current time:
+//
+//	GET  /sync/changes?cursor=N&limit=M  a models.ChangePage of the changes after cursor N
+//	POST /sync/usage                     adds the uses of a models.UsageReport body
+//
+// for the tenant named by the api.TenantHeader header. Client is the edge side. Serve it
+// behind auth.Middleware, whose DefaultPolicy requires the sync role: the changes carry
+// every discount's code and reported uses count against usage limits.
+func NewHandler(feed *Feed, c clock.Clock) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sync/changes", func(w http.ResponseWriter, r *http.Request) {
+		cursor, err := intParam(r, "cursor")
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		limit, err := intParam(r, "limit")
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		page, err := feed.Changes(requestContext(r, c), cursor, int(limit))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+	mux.HandleFunc("POST /sync/usage", func(w http.ResponseWriter, r *http.Request) {
+		var report models.UsageReport
+		r.Body = http.MaxBytesReader(w, r.Body, maxUsageReportBytes)
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			writeError(w, errors.NewValidationError("invalid usage report: "+err.Error()))
+			return
+		}
+		if err := feed.ReportUsage(requestContext(r, c), report); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// intParam reads an optional integer query parameter, zero when absent
+func intParam(r *http.Request, name string) (int64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, errors.NewValidationError(fmt.Sprintf("invalid %s: %q", name, v))
+	}
+	return n, nil
+}
+
+// requestContext pins the request to c's current time and the tenant it names
+func requestContext(r *http.Request, c clock.Clock) context.Context {
+	ctx := clock.NewContext(r.Context(), c.Now())
+	if tenantID := r.Header.Get(api.TenantHeader); tenantID != "" {
+		ctx = tenant.NewContext(ctx, tenantID)
+	}
+	return ctx
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.IsValidationError(err) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,102 @@
+// Package health reports whether the server is alive and ready for traffic, for load
+// balancers and Kubernetes liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeout bounds each readiness check, so a hung dependency fails the probe
+// instead of stalling it
+const DefaultTimeout = 2 * time.Second
+
+// Check reports why a dependency is unusable, nil when it works
+type Check func(ctx context.Context) error
+
+// Status is the state of the server or one of its checks
+type Status string
+
+const (
+	StatusOK          Status = "ok"
+	StatusUnavailable Status = "unavailable"
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the outcome of a readiness probe
+type Report struct {
+	Status Status   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Checker runs the readiness checks of the server's dependencies. It is not ready until
+// MarkReady is called, once caches are warmed and state restored at startup.
+type Checker struct {
+	Timeout time.Duration
+
+	mu     sync.RWMutex
+	checks []namedCheck
+	ready  atomic.Bool
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// NewChecker returns a checker without checks that is not ready yet
+func NewChecker() *Checker {
+	return &Checker{Timeout: DefaultTimeout}
+}
+
+// Add registers a readiness check
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// MarkReady records that startup finished, so readiness depends on the checks alone
+func (c *Checker) MarkReady() {
+	c.ready.Store(true)
+}
+
+// Ready runs every check, reporting the server unavailable when any fails or it is still
+// starting up
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]namedCheck(nil), c.checks...)
+	c.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make([]Result, 0, len(checks)+1)}
+	if !c.ready.Load() {
+		report.Status = StatusUnavailable
+		report.Checks = append(report.Checks, Result{Name: "startup", Status: StatusUnavailable, Error: "still starting up"})
+	}
+	for _, nc := range checks {
+		result := Result{Name: nc.name, Status: StatusOK}
+		if err := c.run(ctx, nc.check); err != nil {
+			result.Status, result.Error = StatusUnavailable, err.Error()
+			report.Status = StatusUnavailable
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	return check(ctx)
+}
@@ -0,0 +1,36 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// NewHandler serves the probes:
+//
+//	GET /healthz  liveness, ok while the process serves requests
+//	GET /readyz   readiness, 503 with the failing checks until every check passes
+//
+// Liveness does not run the checks, so an unavailable dependency takes the server out of
+// rotation without getting it restarted.
+func NewHandler(c *Checker) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]Status{"status": StatusOK})
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		report := c.Ready(r.Context())
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,71 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// ILoyaltyProvider is the integration point for an external loyalty programme holding
+// customers' point balances
+type ILoyaltyProvider interface {
+	// GetPointsBalance returns the customer's redeemable points
+	GetPointsBalance(ctx context.Context, customerID string) (int64, error)
+
+	// RedeemPoints deducts points from the customer's balance, failing when the balance
+	// is insufficient
+	RedeemPoints(ctx context.Context, customerID string, points int64) error
+}
+
+// ICustomerProvider is the integration point for the system of record assigning customers
+// to customer groups
+type ICustomerProvider interface {
+	// GetCustomerGroup returns the group the customer buys under, CustomerGroupRetail for
+	// customers without one
+	GetCustomerGroup(ctx context.Context, customerID string) (models.CustomerGroup, error)
+}
+
+// ProductCatalog is the integration point for the product catalog, so callers can price
+// carts naming products by ID only
+type ProductCatalog interface {
+	// GetProducts returns the products with the given IDs that exist, by ID
+	GetProducts(ctx context.Context, ids []string) (map[string]models.Product, error)
+}
+
+// InventoryProvider is the integration point for the inventory system, so discounts can
+// depend on stock levels
+type InventoryProvider interface {
+	// GetStock returns the units in stock of the products it tracks, by product ID
+	GetStock(ctx context.Context, productIDs []string) (map[string]int, error)
+}
+
+// PricingHook is the integration point for a dynamic pricing engine repricing carts, e.g.
+// surging prices in demand peaks or marking down slow sellers, before discounts apply
+type PricingHook interface {
+	// AdjustPrices returns the new unit prices of the cart lines to reprice, none to leave
+	// the cart as it is
+	AdjustPrices(ctx context.Context, cart []models.CartItem, customer models.CustomerProfile) ([]models.PriceAdjustment, error)
+}
+
+// SegmentResolver is the integration point for a customer data platform assigning customers
+// to marketing segments
+type SegmentResolver interface {
+	// ResolveSegments returns the segments the customer is in at the time of the call
+	ResolveSegments(ctx context.Context, customer models.CustomerProfile) ([]string, error)
+}
+
+// PersonalizationScorer is the integration point for a model personalising which of the
+// offers competing for a customer is shown first, and so auto-applied by storefronts
+// taking the top offer
+type PersonalizationScorer interface {
+	// ScoreOffers returns one score per offer, in the offers' order; higher scores rank first
+	ScoreOffers(ctx context.Context, customer models.CustomerProfile, offers []models.Offer) ([]float64, error)
+}
+
+// Authorizer is the integration point for the access control deciding who may move
+// discounts through their publication workflow
+type Authorizer interface {
+	// AuthorizeTransition returns a forbidden error when the actor of ctx, see
+	// actor.FromContext, may not take the action on the discount
+	AuthorizeTransition(ctx context.Context, discount *models.Discount, action models.LifecycleAction) error
+}
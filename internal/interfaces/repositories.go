@@ -2,8 +2,10 @@ package interfaces
 
 import (
 	"context"
+	"time"
 
 	"github.com/ahsmha/discounts/internal/models"
+	"github.com/shopspring/decimal"
 )
 
 // IDiscountRepository interface defines methods for discount data operations
@@ -11,16 +13,23 @@ type IDiscountRepository interface {
 	// GetActiveDiscounts retrieves all active discounts
 	GetActiveDiscounts(ctx context.Context) ([]models.Discount, error)
 
+	// GetApplicableDiscounts retrieves the discounts matching the filter, see
+	// models.DiscountFilter. The result may include discounts that turn out not to apply
+	// to a cart, but never omits one that matches.
+	GetApplicableDiscounts(ctx context.Context, filter models.DiscountFilter) ([]models.Discount, error)
+
 	// GetDiscountByCode retrieves a discount by its code
 	GetDiscountByCode(ctx context.Context, code string) (*models.Discount, error)
 
 	// GetDiscountByID retrieves a discount by its ID
 	GetDiscountByID(ctx context.Context, id string) (*models.Discount, error)
 
-	// CreateDiscount creates a new discount
+	// CreateDiscount creates a new discount at version 1, setting discount.Version
 	CreateDiscount(ctx context.Context, discount *models.Discount) error
 
-	// UpdateDiscount updates an existing discount
+	// UpdateDiscount updates an existing discount, returning a conflict error without
+	// changes when discount.Version is not the stored version, so concurrent edits cannot
+	// overwrite each other. On success discount.Version is set to the new version.
 	UpdateDiscount(ctx context.Context, discount *models.Discount) error
 
 	// DeleteDiscount deletes a discount by ID
@@ -28,8 +37,199 @@ type IDiscountRepository interface {
 
 	// IncrementUsageCount increments the usage count for a discount
 	IncrementUsageCount(ctx context.Context, id string) error
+
+	// CheckAndIncrementUsage atomically increments the usage count when the discount has
+	// uses left, returning a conflict error without changes once UsageLimit is reached, so
+	// concurrent checkouts can never redeem a discount more often than its limit allows
+	CheckAndIncrementUsage(ctx context.Context, id string) error
+}
+
+// ICampaignRepository interface defines methods for campaign data operations
+type ICampaignRepository interface {
+	// GetCampaignByID retrieves a campaign by its ID
+	GetCampaignByID(ctx context.Context, id string) (*models.Campaign, error)
+
+	// CreateCampaign creates a new campaign
+	CreateCampaign(ctx context.Context, campaign *models.Campaign) error
+
+	// UpdateCampaign updates an existing campaign
+	UpdateCampaign(ctx context.Context, campaign *models.Campaign) error
+
+	// ConsumeBudget atomically adds amount to the campaign's spend, failing when it
+	// would exceed the campaign's budget
+	ConsumeBudget(ctx context.Context, id string, amount decimal.Decimal) error
+}
+
+// ISpendTracker accumulates the money given away by each discount so monetary caps
+// hold across concurrent requests and service instances
+type ISpendTracker interface {
+	// GetSpend returns the total amount given away by the discount so far
+	GetSpend(ctx context.Context, discountID string) (decimal.Decimal, error)
+
+	// AddSpend atomically adds amount to the discount's spend and returns the new total.
+	// When limit is positive and the new total would exceed it, nothing is added and a
+	// validation error is returned.
+	AddSpend(ctx context.Context, discountID string, amount, limit decimal.Decimal) (decimal.Decimal, error)
+}
+
+// IAttemptCounter counts attempts per key in fixed windows, backing velocity limits that
+// hold across service instances
+type IAttemptCounter interface {
+	// Hit records one attempt under key and returns the attempts made in the current window,
+	// this one included, and how long until the window resets
+	Hit(ctx context.Context, key string, window time.Duration) (count int64, resetIn time.Duration, err error)
+}
+
+// IGiftCardRepository stores gift cards and their balances
+type IGiftCardRepository interface {
+	GetGiftCard(ctx context.Context, code string) (*models.GiftCard, error)
+	CreateGiftCard(ctx context.Context, card *models.GiftCard) error
+
+	// Redeem atomically deducts amount from the card, failing without side effects when
+	// the card is unusable or its balance is insufficient
+	Redeem(ctx context.Context, code string, amount decimal.Decimal) error
+
+	// Refund atomically returns amount to the card's balance
+	Refund(ctx context.Context, code string, amount decimal.Decimal) error
+}
+
+// ICouponCodeRepository stores single-use codes minted in bulk
+type ICouponCodeRepository interface {
+	// SaveCodes stores the codes in one batch, skipping and returning those that already exist
+	SaveCodes(ctx context.Context, codes []models.CouponCode) (duplicates []string, err error)
+	GetCode(ctx context.Context, code string) (*models.CouponCode, error)
+
+	// RedeemCode atomically marks the code used, failing with a conflict error when it
+	// already was
+	RedeemCode(ctx context.Context, code, customerID string, at time.Time) error
+
+	// ReleaseCode makes a redeemed code usable again, undoing a redemption whose checkout failed
+	ReleaseCode(ctx context.Context, code string) error
+}
+
+// IReferralLedger records the rewards owed to referrers
+type IReferralLedger interface {
+	RecordReward(ctx context.Context, reward models.ReferralReward) error
+	ListRewards(ctx context.Context, referrerID string) ([]models.ReferralReward, error)
+}
+
+// IIdempotencyStore remembers which payload each idempotency key was used with
+type IIdempotencyStore interface {
+	// Reserve claims key for a request with the given fingerprint. When the key is new it
+	// returns created=true; otherwise it returns the existing record untouched.
+	Reserve(ctx context.Context, key, fingerprint string) (record *models.IdempotencyRecord, created bool, err error)
+
+	// Complete stores the result of the request that reserved key
+	Complete(ctx context.Context, key string, result *models.DiscountedPrice) error
+
+	// Release forgets key, so a request that failed can be retried with it
+	Release(ctx context.Context, key string) error
+}
+
+// IJobRepository stores background jobs with their carts and results, so a job can be
+// resumed from its last checkpoint by any instance after a restart
+type IJobRepository interface {
+	// CreateJob stores a new job of the context tenant with the carts it prices
+	CreateJob(ctx context.Context, job *models.Job, carts []*models.CalculationRequest) error
+
+	// GetJob retrieves a job of the context tenant by its ID
+	GetJob(ctx context.Context, id string) (*models.Job, error)
+
+	// GetJobCarts returns up to limit of the job's carts, starting at the offset-th
+	GetJobCarts(ctx context.Context, id string, offset, limit int) ([]*models.CalculationRequest, error)
+
+	// SaveJobProgress stores the results, replacing any stored for the same carts, together
+	// with the job, so the job's checkpoint never runs ahead of its stored results
+	SaveJobProgress(ctx context.Context, job *models.Job, results []models.JobResult) error
+
+	// GetJobResults returns the stored results of up to limit carts, starting at the offset-th;
+	// carts without a stored result are left out
+	GetJobResults(ctx context.Context, id string, offset, limit int) ([]models.JobResult, error)
+
+	// ListUnfinishedJobs returns the queued and running jobs of every tenant
+	ListUnfinishedJobs(ctx context.Context) ([]*models.Job, error)
+}
+
+// UsageReleaser is implemented by repositories that can give back a use claimed with
+// CheckAndIncrementUsage, undoing the claim of a checkout that failed before committing
+type UsageReleaser interface {
+	// ReleaseUsage decrements the discount's usage count, never below zero
+	ReleaseUsage(ctx context.Context, id string) error
+}
+
+// UsageAdder is implemented by repositories that can count many uses at once, as edge
+// stores report them
+type UsageAdder interface {
+	// AddUsage adds the uses, by discount id, to the usage counts without checking usage
+	// limits, all of them or none. Discounts not stored are skipped. Wrappers of repositories
+	// that cannot add usage fail with an error matching errors.ErrUnsupported.
+	AddUsage(ctx context.Context, uses map[string]int) error
 }
 
 type DiscountSeeder interface {
 	SeedDiscounts([]models.Discount) error
 }
+
+// DiscountLister is implemented by repositories that can enumerate the discounts of the
+// context tenant, whatever their validity, a page at a time. A zero filter lists every
+// discount on a single page, ordered by ID.
+type DiscountLister interface {
+	ListDiscounts(ctx context.Context, filter models.ListFilter) (*models.DiscountPage, error)
+}
+
+// DiscountHistory is implemented by repositories keeping every revision of the context
+// tenant's discounts, so past calculations can be reproduced with the definitions they used.
+// Creating a discount writes its first revision and every update another, effective from
+// the instant pinned in ctx.
+type DiscountHistory interface {
+	// ListRevisions returns the discount's revisions ordered by version, a not found error
+	// for unknown discounts
+	ListRevisions(ctx context.Context, id string) ([]models.DiscountRevision, error)
+
+	// GetDiscountAt returns the discount as defined at the instant, a not found error when
+	// it did not exist yet
+	GetDiscountAt(ctx context.Context, id string, at time.Time) (*models.Discount, error)
+}
+
+// CatalogInspector is implemented by repositories that can summarise the context tenant's
+// discount catalog, listing the discounts expiring within window of the instant pinned in
+// ctx, together with the sizes of their lookup indexes
+type CatalogInspector interface {
+	CatalogStats(ctx context.Context, window time.Duration) (*models.CatalogStats, error)
+}
+
+// IPricingRecordRepository keeps the pricing record of every committed order
+type IPricingRecordRepository interface {
+	// SavePricingRecord stores the order's pricing record, replacing an earlier one
+	SavePricingRecord(ctx context.Context, record models.OrderPricingRecord) error
+
+	// GetPricingRecord returns the order's pricing record, a not found error when there is none
+	GetPricingRecord(ctx context.Context, orderID string) (*models.OrderPricingRecord, error)
+}
+
+// IRedemptionRepository stores discount redemptions
+type IRedemptionRepository interface {
+	// RecordRedemption stores a redemption
+	RecordRedemption(ctx context.Context, redemption models.Redemption) error
+
+	// ListRedemptions returns the redemptions matching the filter
+	ListRedemptions(ctx context.Context, filter models.RedemptionFilter) ([]models.Redemption, error)
+}
+
+// IExperimentRepository stores experiment assignments
+type IExperimentRepository interface {
+	// RecordAssignment stores the variant a customer was bucketed into
+	RecordAssignment(ctx context.Context, assignment models.ExperimentAssignment) error
+
+	// ListAssignments returns every assignment of an experiment
+	ListAssignments(ctx context.Context, experimentID string) ([]models.ExperimentAssignment, error)
+}
+
+// IOrderRepository stores placed orders
+type IOrderRepository interface {
+	// RecordOrder stores a placed order
+	RecordOrder(ctx context.Context, order models.Order) error
+
+	// ListOrders returns the orders placed in [from, to); zero bounds are open
+	ListOrders(ctx context.Context, from, to time.Time) ([]models.Order, error)
+}
@@ -2,8 +2,10 @@ package interfaces
 
 import (
 	"context"
+	"time"
 
 	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/codegen"
 )
 
 // IDiscountService interface defines the contract for discount service operations
@@ -15,6 +17,18 @@ type IDiscountService interface {
 	CalculateCartDiscounts(ctx context.Context, cartItems []models.CartItem,
 		customer models.CustomerProfile, paymentInfo *models.PaymentInfo) (*models.DiscountedPrice, error)
 
+	// CalculateCart is CalculateCartDiscounts driven by a full request, which also carries
+	// optional checks such as the client's expected cart total
+	CalculateCart(ctx context.Context, req *models.CalculationRequest) (*models.DiscountedPrice, error)
+
+	// CalculateCartDiscountsBatch prices many carts concurrently, reporting a result or an
+	// error for each one in request order; a failing cart does not fail the batch
+	CalculateCartDiscountsBatch(ctx context.Context, req *models.BatchCalculationRequest) (*models.BatchCalculationResult, error)
+
+	// ExplainCartDiscounts is a dry run of CalculateCart that also reports, for every
+	// discount, whether it was applied, skipped or rejected and the precise reason
+	ExplainCartDiscounts(ctx context.Context, req *models.CalculationRequest) (*models.Explanation, error)
+
 	// ValidateDiscountCode validates if a discount code can be applied.
 	// Handle specific cases like:
 	// - Brand exclusions
@@ -22,4 +36,83 @@ type IDiscountService interface {
 	// - Customer tier requirements
 	ValidateDiscountCode(ctx context.Context, code string, cartItems []models.CartItem,
 		customer models.CustomerProfile) (bool, error)
+
+	// DescribeDiscountCode is ValidateDiscountCode reporting, besides whether the code is
+	// valid, the discount's name, value, expiry and remaining uses and what it would save
+	// on the cart, so checkout can render the coupon from one call
+	DescribeDiscountCode(ctx context.Context, code string, cartItems []models.CartItem,
+		customer models.CustomerProfile) (*models.CodeValidation, error)
+
+	// GetAvailableCoupons lists the voucher codes the customer could enter for the cart,
+	// those that apply first and then those the cart only misses through its minimums
+	GetAvailableCoupons(ctx context.Context, cartItems []models.CartItem,
+		customer models.CustomerProfile) ([]models.AvailableCoupon, error)
+
+	// VerifyOrderPricing recalculates an order from its pricing record, against the discount
+	// definitions it was priced with, and reports whether the price still comes out the same
+	VerifyOrderPricing(ctx context.Context, orderID string) (*models.PricingVerification, error)
+
+	// ListOffers returns the discounts the customer can currently use, highest priority
+	// first unless an offer ranker is configured, each with urgency data for display
+	ListOffers(ctx context.Context, customer models.CustomerProfile) ([]models.Offer, error)
+}
+
+// IJobService runs batches of carts too large for one request in the background
+type IJobService interface {
+	// SubmitJob stores a job pricing the batch's carts and starts it, returning at once
+	SubmitJob(ctx context.Context, req *models.BatchCalculationRequest) (*models.Job, error)
+
+	// GetJob reports the job's status and progress
+	GetJob(ctx context.Context, id string) (*models.Job, error)
+
+	// GetJobResults returns up to limit results, starting with the offset-th cart, of the
+	// carts the job has processed so far
+	GetJobResults(ctx context.Context, id string, offset, limit int) (*models.JobResultPage, error)
+}
+
+// ILifecycleService moves discounts through their publication workflow
+type ILifecycleService interface {
+	// Transition takes the action on the discount once the actor of ctx is authorised to,
+	// returning the discount in its new state. Actions the discount's state does not allow
+	// fail with a conflict error.
+	Transition(ctx context.Context, id string, action models.LifecycleAction) (*models.Discount, error)
+}
+
+// ICouponCodeService mints and manages single-use codes for mailer-style campaigns
+type ICouponCodeService interface {
+	// Mint generates n new unique codes for a discount with GeneratedCodes set and stores
+	// them in bulk, returning the codes minted
+	Mint(ctx context.Context, discountID string, n int, gen codegen.Generator) ([]string, error)
+
+	// BulkValidate reports the state of every code, in the order given
+	BulkValidate(ctx context.Context, codes []string) ([]models.CouponCodeStatus, error)
+
+	// BulkRedeem marks every available code as used by the customer and reports the state
+	// of each code afterwards, flagging the codes it used; other codes are left untouched
+	BulkRedeem(ctx context.Context, customerID string, codes []string) ([]models.CouponCodeStatus, error)
+}
+
+// IReportingService interface defines read-only analytics over discount activity
+type IReportingService interface {
+	// GetIncrementalityReport computes conversion and average order value per experiment
+	// variant over [from, to) and the uplift of every variant relative to the control.
+	GetIncrementalityReport(ctx context.Context, experimentID, controlVariant string,
+		from, to time.Time) (*models.IncrementalityReport, error)
+
+	// GetCodeLeaderboard ranks the codes and aliases redeemed for the campaign over
+	// [from, to) by the revenue of the orders they were entered on
+	GetCodeLeaderboard(ctx context.Context, campaignID string, from, to time.Time) (*models.CodeLeaderboard, error)
+}
+
+// IOfferRanker orders offer listings for display. Rankers sort in place and must be
+// stable, so offers they consider equal keep their priority order.
+type IOfferRanker interface {
+	Rank(offers []models.Offer, customer models.CustomerProfile)
+}
+
+// IWorkScheduler runs calculations on the capacity reserved for their traffic lane, see
+// lane.FromContext. Run returns fn's error, or an overloaded error without calling fn when
+// the lane cannot take more work.
+type IWorkScheduler interface {
+	Run(ctx context.Context, fn func(ctx context.Context) error) error
 }
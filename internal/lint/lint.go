@@ -0,0 +1,154 @@
+// Package lint checks discount definitions before they are deployed, reporting every
+// problem found across a set of files as diagnostics tools can consume, rather than
+// stopping at the first error as loading them into a repository does.
+package lint
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ahsmha/discounts/internal/discount"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// Rule names the check a diagnostic comes from
+type Rule string
+
+const (
+	RuleSchema        Rule = "schema"         // The file does not parse, or a field is missing or unknown
+	RuleUnknownType   Rule = "unknown-type"   // No strategy prices the discount's type
+	RuleDateRange     Rule = "date-range"     // The discount is never valid
+	RuleNegative      Rule = "negative-value" // An amount or limit is below zero
+	RulePercentage    Rule = "percentage"     // A percentage discount takes more than everything off
+	RuleDuplicateID   Rule = "duplicate-id"
+	RuleDuplicateCode Rule = "duplicate-code" // A code or alias unlocks more than one discount
+	RuleInvalid       Rule = "invalid"        // The repository would refuse the discount
+)
+
+// Diagnostic is one problem with a discount definition
+type Diagnostic struct {
+	File       string `json:"file"`
+	DiscountID string `json:"discount_id,omitempty"` // Empty for problems with the file itself
+	Field      string `json:"field,omitempty"`       // JSON name of the offending field
+	Rule       Rule   `json:"rule"`
+	Message    string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	location := d.File
+	if d.DiscountID != "" {
+		location += ": " + d.DiscountID
+	}
+	if d.Field != "" {
+		location += ": " + d.Field
+	}
+	return fmt.Sprintf("%s: %s [%s]", location, d.Message, d.Rule)
+}
+
+// Files checks the discounts defined in YAML and JSON files, each holding a discount or a
+// list of them, together: IDs and codes must be unique across all of them. Files that do
+// not parse are reported first, then problems with discounts in the order they are defined.
+func Files(paths ...string) []Diagnostic {
+	var diagnostics []Diagnostic
+	var sources []source
+	for _, path := range paths {
+		discounts, err := repositories.LoadDiscountFile(path)
+		if err != nil {
+			diagnostics = append(diagnostics, Diagnostic{File: path, Rule: RuleSchema, Message: err.Error()})
+			continue
+		}
+		for _, d := range discounts {
+			sources = append(sources, source{file: path, discount: d})
+		}
+	}
+	return append(diagnostics, check(sources)...)
+}
+
+type source struct {
+	file     string
+	discount models.Discount
+}
+
+func check(sources []source) []Diagnostic {
+	strategies := discount.NewStrategyFactory()
+	ids := make(map[string]string)   // Tenant-scoped ID -> file defining it
+	codes := make(map[string]string) // Tenant-scoped code -> ID of the discount it unlocks
+
+	// Definitions free of problems are loaded into a scratch repository, which holds them
+	// to every rule the service enforces on create
+	scratch := repositories.NewInMemoryDiscountRepository()
+
+	var diagnostics []Diagnostic
+	for _, s := range sources {
+		d := s.discount
+		report := func(field string, rule Rule, format string, args ...any) {
+			diagnostics = append(diagnostics, Diagnostic{
+				File: s.file, DiscountID: d.ID, Field: field, Rule: rule, Message: fmt.Sprintf(format, args...),
+			})
+		}
+		found := len(diagnostics)
+
+		if d.ID == "" {
+			report("id", RuleSchema, "discount has no id")
+		} else if other, exists := ids[tenant.Scoped(d.TenantID, d.ID)]; exists {
+			report("id", RuleDuplicateID, "id is already defined in %s", other)
+		} else {
+			ids[tenant.Scoped(d.TenantID, d.ID)] = s.file
+		}
+
+		if d.Type == "" {
+			report("type", RuleSchema, "discount has no type")
+		} else if strategies.Get(d.Type) == nil {
+			report("type", RuleUnknownType, "unknown discount type %q", d.Type)
+		}
+
+		if !d.ValidTo.After(d.ValidFrom) {
+			report("valid_to", RuleDateRange, "valid_to %s is not after valid_from %s, the discount is never valid",
+				d.ValidTo.Format(time.RFC3339), d.ValidFrom.Format(time.RFC3339))
+		}
+
+		for _, amount := range []struct {
+			field string
+			value decimal.Decimal
+		}{
+			{"value", d.Value}, {"min_amount", d.MinAmount}, {"max_amount", d.MaxAmount}, {"max_total_spend", d.MaxTotalSpend},
+		} {
+			if amount.value.IsNegative() {
+				report(amount.field, RuleNegative, "%s is negative: %s", amount.field, amount.value)
+			}
+		}
+		if d.UsageLimit < 0 {
+			report("usage_limit", RuleNegative, "usage_limit is negative: %d", d.UsageLimit)
+		}
+
+		if d.IsPercentage && d.Value.GreaterThan(decimal.NewFromInt(models.PercentageBase)) {
+			report("value", RulePercentage, "percentage %s is over %d", d.Value, models.PercentageBase)
+		}
+
+		for _, code := range append([]string{d.Code}, d.CodeAliases...) {
+			if code == "" {
+				continue
+			}
+			key := tenant.Scoped(d.TenantID, code)
+			if other, exists := codes[key]; exists && other != d.ID {
+				report("code", RuleDuplicateCode, "code %q already unlocks %s", code, other)
+				continue
+			}
+			codes[key] = d.ID
+		}
+
+		if len(diagnostics) > found {
+			continue
+		}
+		if err := scratch.CreateDiscount(tenant.NewContext(context.Background(), d.TenantID), &d); err != nil {
+			report("", RuleInvalid, "%s", err.Error())
+		}
+	}
+
+	return diagnostics
+}
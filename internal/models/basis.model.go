@@ -0,0 +1,53 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// DiscountBasis is the amount a cart-wide discount is calculated on, e.g. a campaign promising
+// 10% of the original price however many discounts stack before it
+type DiscountBasis string
+
+const (
+	BasisRunningTotal     DiscountBasis = "RUNNING_TOTAL"     // What earlier discounts left of the cart, the default
+	BasisOriginalPrice    DiscountBasis = "ORIGINAL_PRICE"    // The cart before any discount
+	BasisEligibleSubtotal DiscountBasis = "ELIGIBLE_SUBTOTAL" // The lines the discount targets, before any discount
+)
+
+// CheckBasis reports a Basis the discount cannot honour. Brand and category discounts are
+// always priced on the items they target, so they take none.
+func (d *Discount) CheckBasis() error {
+	switch d.Basis {
+	case "", BasisRunningTotal:
+		return nil
+	case BasisOriginalPrice, BasisEligibleSubtotal:
+	default:
+		return fmt.Errorf("unknown basis %q", d.Basis)
+	}
+	if d.Type == DiscountTypeBrand || d.Type == DiscountTypeCategory {
+		return fmt.Errorf("brand and category discounts are priced on their items and take no basis")
+	}
+	return nil
+}
+
+// PricingBase returns the cart and the total the discount is calculated on, given the cart
+// total before any discount and what earlier discounts left of it, see Basis. For
+// ELIGIBLE_SUBTOTAL the cart is cut down to the lines open to promotions the discount targets.
+func (d *Discount) PricingBase(cart []CartItem, original, running decimal.Decimal) ([]CartItem, decimal.Decimal) {
+	switch d.Basis {
+	case BasisOriginalPrice:
+		return cart, original
+	case BasisEligibleSubtotal:
+		var lines []CartItem
+		for _, item := range cart {
+			if !item.ExcludeFromPromotions && d.MatchesProduct(item.Product) {
+				lines = append(lines, item)
+			}
+		}
+		return lines, GetCartTotal(lines)
+	default:
+		return cart, running
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+// BatchCalculationRequest prices many carts in one call, for back-office repricing
+type BatchCalculationRequest struct {
+	Carts []*CalculationRequest `json:"carts"`
+
+	// DryRun prices every cart without recording usage, spend, redemptions or points, so
+	// historical carts can be repriced without touching live discounts
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// BatchItemResult is the outcome of one cart of a batch: a result or the error pricing it
+type BatchItemResult struct {
+	Index  int              `json:"index"` // Position of the cart in the request
+	Result *DiscountedPrice `json:"result,omitempty"`
+	Err    error            `json:"-"`
+}
+
+// BatchCalculationResult holds one entry per cart, in request order
+type BatchCalculationResult struct {
+	Results   []BatchItemResult `json:"results"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+}
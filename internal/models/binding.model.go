@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IsBoundToCustomer reports whether the customer may use the discount: when
+// AllowedCustomerIDs or AllowedEmailDomains is set, the customer must be one of the
+// customers or have an email address at one of the domains. Domains match whole,
+// whatever their case, so "acme.com" admits "jo@ACME.com" but not "jo@mail.acme.com".
+func (d *Discount) IsBoundToCustomer(customer CustomerProfile) bool {
+	if len(d.AllowedCustomerIDs) == 0 && len(d.AllowedEmailDomains) == 0 {
+		return true
+	}
+	if customer.ID != "" && containsAny(d.AllowedCustomerIDs, []string{customer.ID}) {
+		return true
+	}
+	domain := customer.EmailDomain()
+	if domain == "" {
+		return false
+	}
+	for _, allowed := range d.AllowedEmailDomains {
+		if strings.EqualFold(normalizeDomain(allowed), domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCustomerBound reports whether the discount is limited to named customers or email
+// domains
+func (d *Discount) IsCustomerBound() bool {
+	return len(d.AllowedCustomerIDs) > 0 || len(d.AllowedEmailDomains) > 0
+}
+
+// CheckCustomerBinding reports customer bindings on discounts other than vouchers, and
+// empty or malformed entries
+func (d *Discount) CheckCustomerBinding() error {
+	if !d.IsCustomerBound() {
+		return nil
+	}
+	if d.Type != DiscountTypeVoucher {
+		return fmt.Errorf("only voucher discounts can be bound to customers or email domains")
+	}
+	for _, id := range d.AllowedCustomerIDs {
+		if strings.TrimSpace(id) == "" {
+			return fmt.Errorf("allowed customer ids cannot be empty")
+		}
+	}
+	for _, domain := range d.AllowedEmailDomains {
+		name := normalizeDomain(domain)
+		if name == "" || strings.ContainsAny(name, "@ \t") || !strings.Contains(name, ".") ||
+			strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+			return fmt.Errorf("invalid email domain %q", domain)
+		}
+	}
+	return nil
+}
+
+// EmailDomain returns the lower-cased domain of the customer's email address, empty when
+// the profile carries no valid address
+func (c CustomerProfile) EmailDomain() string {
+	at := strings.LastIndex(c.Email, "@")
+	if at <= 0 || at == len(c.Email)-1 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(c.Email[at+1:]))
+}
+
+// normalizeDomain accepts domains written as "@acme.com" too
+func normalizeDomain(domain string) string {
+	return strings.TrimPrefix(strings.TrimSpace(domain), "@")
+}
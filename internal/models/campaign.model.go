@@ -0,0 +1,28 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// Campaign groups discounts under a shared monetary budget and a single on/off switch.
+// Discounts join a campaign through Discount.CampaignID.
+type Campaign struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Budget   decimal.Decimal `json:"budget"` // Total amount the campaign may give away, zero for no limit
+	Spent    decimal.Decimal `json:"spent"`  // Amount already given away
+	IsActive bool            `json:"is_active"`
+}
+
+// HasBudgetLimit reports whether the campaign caps its total spend
+func (c *Campaign) HasBudgetLimit() bool {
+	return !c.Budget.IsZero()
+}
+
+// RemainingBudget returns how much the campaign can still give away. It is only
+// meaningful when HasBudgetLimit is true.
+func (c *Campaign) RemainingBudget() decimal.Decimal {
+	remaining := c.Budget.Sub(c.Spent)
+	if remaining.IsNegative() {
+		return decimal.Zero
+	}
+	return remaining
+}
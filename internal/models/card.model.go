@@ -0,0 +1,179 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CardNetwork is the scheme a card is issued on
+type CardNetwork string
+
+const (
+	Visa       CardNetwork = "VISA"
+	Mastercard CardNetwork = "MASTERCARD"
+	RuPay      CardNetwork = "RUPAY"
+	Amex       CardNetwork = "AMEX"
+)
+
+// BIN lengths in use: six digits historically, eight since the 2022 ISO 7812 change
+const (
+	minBINLength = 6
+	maxBINLength = 8
+)
+
+// networkPrefixes lists the leading digits issued to every network. Longer prefixes are
+// listed first so the most specific one wins.
+var networkPrefixes = []struct {
+	prefix  string
+	network CardNetwork
+}{
+	{"508", RuPay},
+	{"34", Amex}, {"37", Amex},
+	{"51", Mastercard}, {"52", Mastercard}, {"53", Mastercard}, {"54", Mastercard}, {"55", Mastercard},
+	{"60", RuPay}, {"65", RuPay}, {"81", RuPay}, {"82", RuPay},
+	{"4", Visa},
+}
+
+// DetectCardNetwork returns the network that issued cards with the BIN, empty when unknown
+func DetectCardNetwork(bin string) CardNetwork {
+	if len(bin) >= 4 && bin[:4] >= "2221" && bin[:4] <= "2720" {
+		return Mastercard
+	}
+	for _, p := range networkPrefixes {
+		if strings.HasPrefix(bin, p.prefix) {
+			return p.network
+		}
+	}
+	return ""
+}
+
+// Network returns the card network of the payment: the one given, or else the one its BIN
+// belongs to
+func (p *PaymentInfo) Network() CardNetwork {
+	if p.CardNetwork != "" {
+		return CardNetwork(strings.ToUpper(string(p.CardNetwork)))
+	}
+	return DetectCardNetwork(p.CardBIN)
+}
+
+// HasCardRules reports whether the discount only accepts some payment cards
+func (d *Discount) HasCardRules() bool {
+	return len(d.CardTypes) > 0 || len(d.CardBINs) > 0 || len(d.CardNetworks) > 0
+}
+
+// MatchesCard reports whether the payment is made with a card the discount accepts. A
+// payment that does not say its card type, BIN or network fails the rules that need it, as
+// does a payment not charged to a card.
+func (d *Discount) MatchesCard(payment *PaymentInfo) bool {
+	if !d.HasCardRules() {
+		return true
+	}
+	if payment == nil || !payment.Method.UsesCard() {
+		return false
+	}
+
+	if len(d.CardTypes) > 0 {
+		if payment.CardType == nil || !containsCardType(d.CardTypes, *payment.CardType) {
+			return false
+		}
+	}
+	if len(d.CardNetworks) > 0 {
+		network := payment.Network()
+		if network == "" || !containsNetwork(d.CardNetworks, network) {
+			return false
+		}
+	}
+	if len(d.CardBINs) > 0 && !matchesAnyBIN(d.CardBINs, payment.CardBIN) {
+		return false
+	}
+	return true
+}
+
+// CheckCardRules reports card rules that could never match a card
+func (d *Discount) CheckCardRules() error {
+	if d.HasCardRules() && d.Type != DiscountTypeBank {
+		return fmt.Errorf("only bank discounts can be restricted to cards")
+	}
+	for _, t := range d.CardTypes {
+		if t != Credit && t != Debit {
+			return fmt.Errorf("unknown card type %q", t)
+		}
+	}
+	for _, n := range d.CardNetworks {
+		switch n {
+		case Visa, Mastercard, RuPay, Amex:
+		default:
+			return fmt.Errorf("unknown card network %q", n)
+		}
+	}
+	for _, entry := range d.CardBINs {
+		if _, _, err := parseBINRange(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseBINRange parses a BIN rule: either leading digits such as "414", or an inclusive
+// range of equally long digit strings such as "414000-414999"
+func parseBINRange(entry string) (lo, hi string, err error) {
+	lo, hi, isRange := strings.Cut(entry, "-")
+	if !isRange {
+		hi = lo
+	}
+	if !isDigits(lo) || !isDigits(hi) || len(lo) > maxBINLength {
+		return "", "", fmt.Errorf("invalid card BIN rule %q: expected digits or a range of them", entry)
+	}
+	if len(lo) != len(hi) || lo > hi {
+		return "", "", fmt.Errorf("invalid card BIN range %q: bounds must be equally long and ordered", entry)
+	}
+	return lo, hi, nil
+}
+
+// matchesAnyBIN reports whether the card BIN starts with a prefix, or with digits falling
+// in a range, of the rules
+func matchesAnyBIN(rules []string, bin string) bool {
+	if len(bin) < minBINLength || len(bin) > maxBINLength || !isDigits(bin) {
+		return false
+	}
+	for _, rule := range rules {
+		lo, hi, err := parseBINRange(rule)
+		if err != nil || len(bin) < len(lo) {
+			continue
+		}
+		if lead := bin[:len(lo)]; lead >= lo && lead <= hi {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func containsCardType(types []CardType, t CardType) bool {
+	for _, candidate := range types {
+		if strings.EqualFold(string(candidate), string(t)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsNetwork(networks []CardNetwork, n CardNetwork) bool {
+	for _, candidate := range networks {
+		if candidate == n {
+			return true
+		}
+	}
+	return false
+}
@@ -1,25 +1,99 @@
 package models
 
-import "github.com/shopspring/decimal"
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
 
 type Product struct {
 	ID           string          `json:"id"`
+	SKU          string          `json:"sku,omitempty"` // Stock keeping unit of the exact variant
 	Brand        Brand           `json:"brand"`
 	Category     Category        `json:"category"`
 	BasePrice    decimal.Decimal `json:"base_price"`
-	CurrentPrice decimal.Decimal `json:"current_price"` // After brand/category discount
+	CurrentPrice decimal.Decimal `json:"current_price"` // Derived by the service from BasePrice, see StartFromBasePrices
+
+	// TaxRate is the percentage of tax, e.g. GST, charged on the product. Prices are before
+	// tax, which is charged on what is left of them after discounts, see TaxLine.
+	TaxRate decimal.Decimal `json:"tax_rate,omitempty"`
+
+	// CostPrice is what a unit costs the seller and MinSellingPrice the lowest price a unit
+	// may be sold at. Discounts never take a unit below either, see PriceFloorPolicy.
+	CostPrice       decimal.Decimal `json:"cost_price,omitempty"`
+	MinSellingPrice decimal.Decimal `json:"min_selling_price,omitempty"`
+}
+
+// IsBare reports whether the product carries nothing but its ID and SKU, leaving its brand,
+// category and prices to be looked up in a product catalog
+func (p Product) IsBare() bool {
+	return p.ID != "" && p.Brand == Brand{} && p.Category == Category{} &&
+		p.BasePrice.IsZero() && p.CurrentPrice.IsZero() && p.TaxRate.IsZero() &&
+		p.CostPrice.IsZero() && p.MinSellingPrice.IsZero()
+}
+
+// StartFromBasePrices returns a copy of the cart with every line's CurrentPrice reset to
+// its BasePrice, so brand and category discounts are taken off by the engine rather than
+// already baked into the price. Lines without a BasePrice keep their CurrentPrice.
+func StartFromBasePrices(cart []CartItem) []CartItem {
+	priced := make([]CartItem, len(cart))
+	for i, item := range cart {
+		priced[i] = item
+		if item.Product.BasePrice.IsPositive() {
+			priced[i].Product.CurrentPrice = item.Product.BasePrice
+		}
+	}
+	return priced
 }
 
 type CartItem struct {
 	Product  Product `json:"product"`
 	Quantity int     `json:"quantity"`
 	Size     string  `json:"size"`
+
+	// ExcludeFromPromotions opts the line out of every discount, e.g. because it was already
+	// price matched. It still counts towards the cart total, but not towards any discount's
+	// minimum amount or the amount a discount is taken off.
+	ExcludeFromPromotions bool `json:"exclude_from_promotions,omitempty"`
 }
 
 func (ci *CartItem) GetTotalPrice() decimal.Decimal {
 	return ci.Product.CurrentPrice.Mul(decimal.NewFromInt(int64(ci.Quantity)))
 }
 
+// Validate reports why the item cannot be priced, or nil when it is well formed
+func (ci *CartItem) Validate() error {
+	if ci.Product.ID == "" {
+		return fmt.Errorf("product id is missing")
+	}
+	if ci.Quantity <= 0 {
+		return fmt.Errorf("product %s has non-positive quantity %d", ci.Product.ID, ci.Quantity)
+	}
+	if ci.Product.BasePrice.IsNegative() {
+		return fmt.Errorf("product %s has negative base price %s", ci.Product.ID, ci.Product.BasePrice.String())
+	}
+	if ci.Product.CurrentPrice.IsNegative() {
+		return fmt.Errorf("product %s has negative current price %s", ci.Product.ID, ci.Product.CurrentPrice.String())
+	}
+	if ci.Product.CostPrice.IsNegative() || ci.Product.MinSellingPrice.IsNegative() {
+		return fmt.Errorf("product %s has a negative cost or minimum selling price", ci.Product.ID)
+	}
+	if ci.Product.TaxRate.IsNegative() || ci.Product.TaxRate.GreaterThan(decimal.NewFromInt(PercentageBase)) {
+		return fmt.Errorf("product %s has tax rate %s outside 0 to 100 percent", ci.Product.ID, ci.Product.TaxRate.String())
+	}
+	return nil
+}
+
+// ValidationMode controls how malformed cart items are handled
+type ValidationMode string
+
+const (
+	// ValidationStrict rejects the whole request when any item is malformed
+	ValidationStrict ValidationMode = "strict"
+	// ValidationLenient drops malformed items and reports them as warnings in the result
+	ValidationLenient ValidationMode = "lenient"
+)
+
 // CardType represents the type of card payment.
 type CardType string
 
@@ -33,12 +107,91 @@ const (
 type PaymentMethod string
 
 const (
-	UPI  PaymentMethod = "UPI"
-	Card PaymentMethod = "CARD"
+	UPI        PaymentMethod = "UPI"
+	Card       PaymentMethod = "CARD"
+	EMI        PaymentMethod = "EMI" // Card instalments, carrying the card's bank and BIN
+	Wallet     PaymentMethod = "WALLET"
+	NetBanking PaymentMethod = "NETBANKING"
 )
 
 type PaymentInfo struct {
 	Method   PaymentMethod `json:"method"`
 	BankName *string       `json:"bank_name"`
 	CardType *CardType     `json:"card_type"`
+
+	// CardBIN is the card's bank identification number, its first six to eight digits, and
+	// CardNetwork the scheme it runs on. The network is detected from the BIN when missing.
+	CardBIN     string      `json:"card_bin,omitempty"`
+	CardNetwork CardNetwork `json:"card_network,omitempty"`
+
+	// WalletProvider names the wallet paying a WALLET order, e.g. PAYTM
+	WalletProvider *string `json:"wallet_provider,omitempty"`
+}
+
+// CalculationRequest carries everything needed to price a cart
+type CalculationRequest struct {
+	CartItems   []CartItem      `json:"cart_items"`
+	Customer    CustomerProfile `json:"customer"`
+	PaymentInfo *PaymentInfo    `json:"payment_info,omitempty"`
+
+	// OrderID identifies the order or cart being priced, recorded on its redemptions
+	OrderID string `json:"order_id,omitempty"`
+
+	// Location is where the order ships to, required by region-restricted discounts
+	Location *Location `json:"location,omitempty"`
+
+	// SalesChannel is the channel the order is placed through, the channel of the request
+	// context when empty, see channel.NewContext
+	SalesChannel SalesChannel `json:"sales_channel,omitempty"`
+
+	// ExpectedTotal is the cart total the client displayed before checkout. When set, the
+	// request is rejected if the engine's own total differs by more than the tolerance.
+	ExpectedTotal *decimal.Decimal `json:"expected_total,omitempty"`
+
+	// ValidationMode overrides the service's handling of malformed items for this request
+	ValidationMode ValidationMode `json:"validation_mode,omitempty"`
+
+	// RedeemPoints opts the customer into burning loyalty points for cart credit
+	RedeemPoints bool `json:"redeem_points,omitempty"`
+
+	// Codes are the discount codes the customer entered at checkout
+	Codes []string `json:"codes,omitempty"`
+
+	// GiftCardCodes are gift cards paying for the order, drawn down in the given order once
+	// every discount has been applied
+	GiftCardCodes []string `json:"gift_card_codes,omitempty"`
+
+	// IdempotencyKey identifies the checkout attempt. Retries with the same key and payload
+	// get the first result back instead of applying discounts again; reusing the key with a
+	// different payload is rejected as a conflict.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
+	// AllowPartial lets a calculation whose context deadline passes while discounts are being
+	// applied return the discounts applied so far, flagged Partial, instead of running on
+	AllowPartial bool `json:"allow_partial,omitempty"`
+}
+
+// GetCartTotal returns the undiscounted total of the request's cart
+func (r *CalculationRequest) GetCartTotal() decimal.Decimal {
+	return GetCartTotal(r.CartItems)
+}
+
+// GetCartTotal returns the undiscounted total of the given items
+func GetCartTotal(items []CartItem) decimal.Decimal {
+	total := decimal.Zero
+	for _, item := range items {
+		total = total.Add(item.GetTotalPrice())
+	}
+	return total
+}
+
+// GetPromotableTotal returns the undiscounted total of the given items open to promotions
+func GetPromotableTotal(items []CartItem) decimal.Decimal {
+	total := decimal.Zero
+	for _, item := range items {
+		if !item.ExcludeFromPromotions {
+			total = total.Add(item.GetTotalPrice())
+		}
+	}
+	return total
 }
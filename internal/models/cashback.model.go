@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PendingCashback is a cashback discount earned by an order, to be credited on CreditOn,
+// e.g. "₹200 cashback in 30 days"
+type PendingCashback struct {
+	DiscountID  string          `json:"discount_id"`
+	DiscountRef DiscountRef     `json:"discount_ref"`
+	Name        string          `json:"name"`
+	Type        DiscountType    `json:"type"`
+	Amount      decimal.Decimal `json:"amount"`
+	DelayDays   int             `json:"delay_days"`
+	CreditOn    time.Time       `json:"credit_on"`
+}
+
+// GetPendingCashback returns the cashback the order earned in total
+func (dp *DiscountedPrice) GetPendingCashback() decimal.Decimal {
+	total := decimal.Zero
+	for _, cashback := range dp.PendingCashback {
+		total = total.Add(cashback.Amount)
+	}
+	return total
+}
+
+// CashbackCreditOn returns when cashback earned by a purchase at the instant is credited
+func (d *Discount) CashbackCreditOn(purchasedAt time.Time) time.Time {
+	return purchasedAt.AddDate(0, 0, d.CashbackDelayDays)
+}
+
+// CheckCashback reports cashback settings that cannot be honoured
+func (d *Discount) CheckCashback() error {
+	if d.CashbackDelayDays < 0 {
+		return fmt.Errorf("cashback delay cannot be negative")
+	}
+	if d.CashbackDelayDays > 0 && !d.Cashback {
+		return fmt.Errorf("only cashback discounts can have a cashback delay")
+	}
+	if d.Cashback && d.Type == DiscountTypePointsRedemption {
+		return fmt.Errorf("points redemption cannot be paid as cashback")
+	}
+	return nil
+}
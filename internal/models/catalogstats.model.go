@@ -0,0 +1,112 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// StatusAvailable is the catalog status of discounts usable at the time of the report
+const StatusAvailable = "available"
+
+// CatalogStats summarises a tenant's discount catalog for operational dashboards
+type CatalogStats struct {
+	Total int `json:"total"`
+
+	// ByType counts the discounts of each type
+	ByType map[DiscountType]int `json:"by_type"`
+
+	// ByStatus counts the available discounts under StatusAvailable and every other
+	// discount under the reason it is unavailable, see Discount.UnavailableReasonAt
+	ByStatus map[string]int `json:"by_status"`
+
+	// DistinctBrands and DistinctCategories count the brands and categories named by the
+	// discounts' applicability and exclusion lists. Untyped entries count for every
+	// dimension they may refer to.
+	DistinctBrands     int `json:"distinct_brands"`
+	DistinctCategories int `json:"distinct_categories"`
+
+	// Expiring lists the available discounts ending within the report's window, soonest first
+	Expiring []ExpiringDiscount `json:"expiring"`
+
+	// Indexes reports the size of each of the repository's lookup indexes for the tenant,
+	// when the repository keeps any
+	Indexes map[string]int `json:"indexes,omitempty"`
+
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// ExpiringDiscount is an available discount about to reach the end of its validity window
+type ExpiringDiscount struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	ValidTo time.Time `json:"valid_to"`
+}
+
+// ComputeCatalogStats summarises the discounts as of now, listing those expiring before
+// now+window. Indexes is left for the repository to fill in.
+func ComputeCatalogStats(discounts []Discount, now time.Time, window time.Duration) *CatalogStats {
+	stats := &CatalogStats{
+		Total:       len(discounts),
+		ByType:      make(map[DiscountType]int),
+		ByStatus:    make(map[string]int),
+		Expiring:    []ExpiringDiscount{},
+		GeneratedAt: now,
+	}
+	brands := make(map[string]struct{})
+	categories := make(map[string]struct{})
+	horizon := now.Add(window)
+
+	for i := range discounts {
+		d := &discounts[i]
+		stats.ByType[d.Type]++
+
+		status := StatusAvailable
+		if reason := d.UnavailableReasonAt(now); reason != "" {
+			status = string(reason)
+		}
+		stats.ByStatus[status]++
+		if status == StatusAvailable && d.ValidTo.Before(horizon) {
+			stats.Expiring = append(stats.Expiring, ExpiringDiscount{ID: d.ID, Name: d.Name, ValidTo: d.ValidTo})
+		}
+
+		collect := func(entries []string, untyped ...ItemRefKind) {
+			for _, entry := range entries {
+				kind, value := parseItemRef(entry)
+				kinds := []ItemRefKind{kind}
+				if kind == "" {
+					kinds = untyped
+				}
+				for _, k := range kinds {
+					switch k {
+					case ItemRefBrand:
+						brands[value] = struct{}{}
+					case ItemRefCategory:
+						categories[value] = struct{}{}
+					}
+				}
+			}
+		}
+		switch d.Type {
+		case DiscountTypeBrand:
+			collect(d.ApplicableTo, ItemRefBrand)
+		case DiscountTypeCategory:
+			collect(d.ApplicableTo, ItemRefCategory)
+		case DiscountTypeVoucher:
+			collect(d.ApplicableTo, ItemRefBrand, ItemRefCategory)
+		default:
+			collect(d.ApplicableTo) // Untyped entries name banks, wallets and the like
+		}
+		collect(d.ExcludedItems, ItemRefBrand, ItemRefCategory)
+	}
+
+	stats.DistinctBrands = len(brands)
+	stats.DistinctCategories = len(categories)
+	sort.Slice(stats.Expiring, func(i, j int) bool {
+		a, b := stats.Expiring[i], stats.Expiring[j]
+		if !a.ValidTo.Equal(b.ValidTo) {
+			return a.ValidTo.Before(b.ValidTo)
+		}
+		return a.ID < b.ID
+	})
+	return stats
+}
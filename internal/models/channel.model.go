@@ -0,0 +1,48 @@
+package models
+
+import "fmt"
+
+// SalesChannel is the storefront an order is placed through
+type SalesChannel string
+
+const (
+	ChannelApp         SalesChannel = "app"
+	ChannelWeb         SalesChannel = "web"
+	ChannelInStore     SalesChannel = "in_store"
+	ChannelMarketplace SalesChannel = "marketplace"
+)
+
+// Validate reports channels other than the known ones
+func (c SalesChannel) Validate() error {
+	switch c {
+	case ChannelApp, ChannelWeb, ChannelInStore, ChannelMarketplace:
+		return nil
+	default:
+		return fmt.Errorf("unknown sales channel %q", c)
+	}
+}
+
+// IsSoldOn reports whether the discount may be used through the channel. Discounts without
+// Channels are sold on every channel; restricted ones never apply to orders of unknown
+// channel, so app-only promotions cannot leak to callers that name none.
+func (d *Discount) IsSoldOn(channel SalesChannel) bool {
+	if len(d.Channels) == 0 {
+		return true
+	}
+	for _, c := range d.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckChannels reports unknown channels
+func (d *Discount) CheckChannels() error {
+	for _, c := range d.Channels {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CodeValidation is what checkout shows for an entered code: whether it applies to the
+// cart and, when the code names a discount, what that discount offers
+type CodeValidation struct {
+	Code  string `json:"code"`
+	Valid bool   `json:"valid"`
+
+	DiscountID   string       `json:"discount_id,omitempty"`
+	Name         string       `json:"name,omitempty"`
+	Type         DiscountType `json:"type,omitempty"`
+	ValueSummary string       `json:"value_summary,omitempty"` // e.g. "20% off up to INR 300", see ValueSummary
+	ExpiresAt    *time.Time   `json:"expires_at,omitempty"`
+
+	// RemainingUses is how many more times the discount can be redeemed, nil when its usage
+	// is unlimited
+	RemainingUses *int `json:"remaining_uses,omitempty"`
+
+	// EstimatedSavings is what the discount would take off the cart on its own, zero unless
+	// the code is valid. Discounts stacked before it at checkout may lower it.
+	EstimatedSavings decimal.Decimal `json:"estimated_savings"`
+}
+
+// DescribeCode describes the discount as unlocked by code, without saying whether it
+// applies to a cart: the result is not Valid and saves nothing
+func (d *Discount) DescribeCode(code string) *CodeValidation {
+	expiresAt := d.ValidTo
+	return &CodeValidation{
+		Code:             code,
+		DiscountID:       d.ID,
+		Name:             d.Name,
+		Type:             d.Type,
+		ValueSummary:     d.ValueSummary(),
+		ExpiresAt:        &expiresAt,
+		RemainingUses:    d.RemainingUses(),
+		EstimatedSavings: decimal.Zero,
+	}
+}
+
+// ValueSummary describes what the discount takes off in a short label, such as
+// "20% off up to INR 300", "INR 150 off" or "up to 15% off" for spend tiers
+func (d *Discount) ValueSummary() string {
+	value := d.Value
+	prefix := ""
+	if d.Type == DiscountTypeSpendTier && len(d.SpendTiers) > 0 {
+		value = d.SpendTiers[len(d.SpendTiers)-1].Value
+		prefix = "up to "
+	}
+
+	suffix := " off"
+	if d.Cashback {
+		suffix = " cashback"
+	}
+	if !d.IsPercentage {
+		return prefix + d.CurrencyCode() + " " + value.String() + suffix
+	}
+	summary := fmt.Sprintf("%s%s%%%s", prefix, value.String(), suffix)
+	if d.MaxAmount.IsPositive() {
+		summary += " up to " + d.CurrencyCode() + " " + d.MaxAmount.String()
+	}
+	return summary
+}
+
+// RemainingUses is how many more times the discount can be redeemed, nil when its usage is
+// unlimited
+func (d *Discount) RemainingUses() *int {
+	if d.UsageLimit <= 0 {
+		return nil
+	}
+	remaining := d.UsageLimit - d.UsedCount
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &remaining
+}
+
+// AvailableCoupon is a voucher listed in checkout's available offers, either Valid for the
+// cart or with NearMiss saying what the cart lacks to unlock it
+type AvailableCoupon struct {
+	CodeValidation
+	NearMiss *NearMissDiscount `json:"near_miss,omitempty"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// CouponCode is a single-use code minted in bulk for a discount with GeneratedCodes set.
+// Entering it at checkout unlocks the discount once.
+type CouponCode struct {
+	Code       string    `json:"code"`
+	DiscountID string    `json:"discount_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	RedeemedBy string    `json:"redeemed_by,omitempty"` // Customer who used the code
+	RedeemedAt time.Time `json:"redeemed_at,omitempty"` // Zero until the code is used
+}
+
+// IsRedeemed reports whether the code has been used
+func (c *CouponCode) IsRedeemed() bool {
+	return !c.RedeemedAt.IsZero()
+}
+
+// CouponCodeState is the outcome of checking or redeeming one code in bulk
+type CouponCodeState string
+
+const (
+	CouponCodeAvailable   CouponCodeState = "available"   // Unused and its discount is valid
+	CouponCodeRedeemed    CouponCodeState = "redeemed"    // Already used
+	CouponCodeUnknown     CouponCodeState = "unknown"     // Never minted
+	CouponCodeUnavailable CouponCodeState = "unavailable" // Unused, but its discount cannot be used now
+)
+
+// CouponCodeStatus reports the state of one code in a bulk operation
+type CouponCodeStatus struct {
+	Code       string          `json:"code"`
+	DiscountID string          `json:"discount_id,omitempty"`
+	State      CouponCodeState `json:"state"`
+
+	// RedeemedNow is set by bulk redemption for the codes it used, as opposed to codes that
+	// had been used before
+	RedeemedNow bool `json:"redeemed_now,omitempty"`
+}
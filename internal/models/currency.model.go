@@ -0,0 +1,137 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Currency is an ISO 4217 currency and the decimal places of its minor unit, e.g. 2 for
+// paise, 0 for currencies without a minor unit such as the yen
+type Currency struct {
+	Code       string `json:"code"`
+	MinorUnits int32  `json:"minor_units"`
+}
+
+var (
+	INR = Currency{Code: "INR", MinorUnits: 2}
+	USD = Currency{Code: "USD", MinorUnits: 2}
+	EUR = Currency{Code: "EUR", MinorUnits: 2}
+	GBP = Currency{Code: "GBP", MinorUnits: 2}
+	JPY = Currency{Code: "JPY", MinorUnits: 0}
+	KWD = Currency{Code: "KWD", MinorUnits: 3}
+
+	// DefaultCurrency is the currency of discounts that do not name one
+	DefaultCurrency = INR
+)
+
+// RoundingMode says how applied amounts are rounded to the currency's minor unit
+type RoundingMode string
+
+const (
+	RoundHalfUp   RoundingMode = "half_up"   // The engine default
+	RoundHalfEven RoundingMode = "half_even" // Ties go to the even minor unit
+	RoundFloor    RoundingMode = "floor"     // Down, never giving away more than computed
+	RoundCeil     RoundingMode = "ceil"      // Up
+)
+
+// Validate reports unknown rounding modes; the empty mode stands for the default
+func (m RoundingMode) Validate() error {
+	switch m {
+	case "", RoundHalfUp, RoundHalfEven, RoundFloor, RoundCeil:
+		return nil
+	default:
+		return fmt.Errorf("unknown rounding mode %q", m)
+	}
+}
+
+// maxMinorUnits is the finest minor unit in ISO 4217
+const maxMinorUnits = 4
+
+var knownCurrencies = map[string]Currency{
+	INR.Code: INR,
+	USD.Code: USD,
+	EUR.Code: EUR,
+	GBP.Code: GBP,
+	JPY.Code: JPY,
+	KWD.Code: KWD,
+}
+
+// LookupCurrency returns the known currency with the code, DefaultCurrency for an empty code
+func LookupCurrency(code string) (Currency, bool) {
+	if code == "" {
+		return DefaultCurrency, true
+	}
+	c, ok := knownCurrencies[code]
+	return c, ok
+}
+
+// Validate reports currencies amounts cannot be expressed in
+func (c Currency) Validate() error {
+	if c.Code == "" {
+		return fmt.Errorf("currency has no code")
+	}
+	if c.MinorUnits < 0 || c.MinorUnits > maxMinorUnits {
+		return fmt.Errorf("currency %s cannot have %d minor unit digits", c.Code, c.MinorUnits)
+	}
+	return nil
+}
+
+// Round rounds the amount half-up to the currency's minor unit
+func (c Currency) Round(amount decimal.Decimal) decimal.Decimal {
+	return c.RoundWith(amount, RoundHalfUp)
+}
+
+// RoundWith rounds the amount to the currency's minor unit in the given mode, half-up for
+// an empty mode
+func (c Currency) RoundWith(amount decimal.Decimal, mode RoundingMode) decimal.Decimal {
+	switch mode {
+	case RoundHalfEven:
+		return amount.RoundBank(c.MinorUnits)
+	case RoundFloor:
+		return amount.RoundFloor(c.MinorUnits)
+	case RoundCeil:
+		return amount.RoundCeil(c.MinorUnits)
+	default:
+		return amount.Round(c.MinorUnits)
+	}
+}
+
+// IsExact reports whether the amount is a whole number of minor units, so it can be paid
+// as is: 10.50 is exact in rupees but not in yen, 10.005 in neither
+func (c Currency) IsExact(amount decimal.Decimal) bool {
+	return amount.Equal(amount.Truncate(c.MinorUnits))
+}
+
+// CheckMinorUnits reports the first of the discount's fixed amounts that is finer than the
+// currency's minor unit. Percentages and the per-point credit of points redemption
+// discounts are rates rather than amounts, and may be finer.
+func (d *Discount) CheckMinorUnits(c Currency) error {
+	amounts := []namedAmount{
+		{"min_amount", d.MinAmount},
+		{"max_amount", d.MaxAmount},
+		{"max_total_spend", d.MaxTotalSpend},
+		{"referrer_reward", d.ReferrerReward},
+	}
+	if !d.IsPercentage && d.Type != DiscountTypePointsRedemption {
+		amounts = append(amounts, namedAmount{"value", d.Value})
+	}
+	for i, tier := range d.SpendTiers {
+		amounts = append(amounts, namedAmount{fmt.Sprintf("spend_tiers[%d].threshold", i), tier.Threshold})
+		if !d.IsPercentage {
+			amounts = append(amounts, namedAmount{fmt.Sprintf("spend_tiers[%d].value", i), tier.Value})
+		}
+	}
+
+	for _, a := range amounts {
+		if !c.IsExact(a.value) {
+			return fmt.Errorf("%s %s is finer than the %s minor unit", a.name, a.value.String(), c.Code)
+		}
+	}
+	return nil
+}
+
+type namedAmount struct {
+	name  string
+	value decimal.Decimal
+}
@@ -2,6 +2,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -31,9 +32,88 @@ type Category struct {
 
 type DiscountedPrice struct {
 	OriginalPrice    decimal.Decimal            `json:"original_price"`
-	FinalPrice       decimal.Decimal            `json:"final_price"`
+	FinalPrice       decimal.Decimal            `json:"final_price"`       // Before tax
 	AppliedDiscounts map[string]decimal.Decimal `json:"applied_discounts"` // discount_id -> amount
 	Message          string                     `json:"message"`
+
+	// PriceWithout holds, per applied discount, the final price had that discount alone
+	// not been applied. Only populated when the service is built with counterfactual pricing.
+	PriceWithout map[string]decimal.Decimal `json:"price_without,omitempty"`
+
+	// Warnings lists cart items that were skipped because they could not be priced
+	Warnings []string `json:"warnings,omitempty"`
+
+	PointsRedeemed int64 `json:"points_redeemed,omitempty"` // Loyalty points burnt for cart credit
+	PointsEarned   int64 `json:"points_earned,omitempty"`   // Loyalty points earned on the final price
+
+	// GiftCardsApplied holds, per gift card code, the amount it paid of the final price;
+	// AmountDue is what is left to pay once gift cards have been applied
+	GiftCardsApplied map[string]decimal.Decimal `json:"gift_cards_applied,omitempty"`
+	AmountDue        decimal.Decimal            `json:"amount_due"`
+
+	// TaxLines hold the tax charged at each rate of the cart's products, in ascending rate
+	// order, and TotalTax their sum, which is added to FinalPrice to make the amount due.
+	// Both are empty for carts of untaxed products.
+	TaxLines []TaxLine       `json:"tax_lines,omitempty"`
+	TotalTax decimal.Decimal `json:"total_tax"`
+
+	// PriceFloorClamps lists the discounts cut down to keep items at or above their price
+	// floors, in the order they were applied
+	PriceFloorClamps []PriceFloorClamp `json:"price_floor_clamps,omitempty"`
+
+	// DiscountCapAdjustments lists the discounts scaled back, lowest priority first, to keep
+	// the cart's total discount within the service's cap, see services.WithMaxDiscountPercent
+	DiscountCapAdjustments []DiscountCapAdjustment `json:"discount_cap_adjustments,omitempty"`
+
+	// Breakdown lists the applied discounts in the order they were applied. Unlike
+	// AppliedDiscounts it identifies each discount by ID, so same-named discounts stay apart.
+	Breakdown []AppliedDiscount `json:"breakdown,omitempty"`
+
+	// CustomerGroup is the group whose price tiers priced the cart, empty without group
+	// pricing. OriginalPrice is already at the group's prices.
+	CustomerGroup CustomerGroup `json:"customer_group,omitempty"`
+
+	// PriceAdjustments lists the lines the service's pricing hook repriced, in the order it
+	// returned them. OriginalPrice is already at the adjusted prices; the adjustments are
+	// not discounts and are not in AppliedDiscounts or Breakdown.
+	PriceAdjustments []PriceAdjustment `json:"price_adjustments,omitempty"`
+
+	// MemberSavings is what members-only discounts took off the cart, nil when none applied
+	MemberSavings *MemberSavings `json:"member_savings,omitempty"`
+
+	// Partial is set when the request's deadline cut the calculation short, so lower
+	// priority discounts were not considered
+	Partial bool `json:"partial,omitempty"`
+
+	// PendingCashback lists the cashback discounts earned by the order, in the order they
+	// were applied. They are paid back later and are not part of FinalPrice or Breakdown.
+	PendingCashback []PendingCashback `json:"pending_cashback,omitempty"`
+
+	// SpendHints tell the customer how much more to spend to reach the next tier of each
+	// spend tier discount open to them
+	SpendHints []SpendHint `json:"spend_hints,omitempty"`
+
+	// NearMissDiscounts lists the discounts the cart only missed through their minimum
+	// amount or quantity
+	NearMissDiscounts []NearMissDiscount `json:"near_miss_discounts,omitempty"`
+
+	// Experiments lists the variant the customer was in for every discount experiment the
+	// calculation considered, for analytics
+	Experiments []ExperimentExposure `json:"experiments,omitempty"`
+}
+
+// AppliedDiscount is one discount's contribution to a calculation
+type AppliedDiscount struct {
+	DiscountID     string          `json:"discount_id"`
+	DiscountRef    DiscountRef     `json:"discount_ref"`
+	Version        int             `json:"version"` // Revision of the discount applied, see DiscountRevision
+	Name           string          `json:"name"`
+	Type           DiscountType    `json:"type"`
+	Amount         decimal.Decimal `json:"amount"`
+	PointsRedeemed int64           `json:"points_redeemed,omitempty"`
+
+	// Rounding is how Amount was rounded to the minor unit
+	Rounding RoundingMode `json:"rounding"`
 }
 
 func (dp *DiscountedPrice) GetTotalDiscount() decimal.Decimal {
@@ -44,6 +124,16 @@ func (dp *DiscountedPrice) GetTotalDiscount() decimal.Decimal {
 	return total
 }
 
+// GetMarginalContribution returns how much the named discount actually saved once the
+// interaction with every other stacked discount is taken into account.
+func (dp *DiscountedPrice) GetMarginalContribution(name string) decimal.Decimal {
+	without, ok := dp.PriceWithout[name]
+	if !ok {
+		return decimal.Zero
+	}
+	return without.Sub(dp.FinalPrice)
+}
+
 func (dp *DiscountedPrice) GetDiscountPercentage() decimal.Decimal {
 	if dp.OriginalPrice.IsZero() {
 		return decimal.Zero
@@ -52,8 +142,28 @@ func (dp *DiscountedPrice) GetDiscountPercentage() decimal.Decimal {
 }
 
 type CustomerProfile struct {
-	ID   string `json:"id"`
-	Tier string `json:"tier"`
+	ID           string    `json:"id"`
+	Tier         string    `json:"tier"`
+	OrderCount   int       `json:"order_count"`             // Orders placed before the current one
+	RegisteredAt time.Time `json:"registered_at,omitempty"` // When the customer signed up
+	DateOfBirth  time.Time `json:"date_of_birth,omitempty"` // Only the month and day are used
+	IsEmployee   bool      `json:"is_employee,omitempty"`   // Staff buying for themselves, see DiscountTypeEmployee
+	Email        string    `json:"email,omitempty"`         // Matched against the AllowedEmailDomains of vouchers
+
+	// PointsBalance is the loyalty balance available for redemption in this calculation,
+	// filled in by the service from its loyalty provider when the request opts in
+	PointsBalance int64 `json:"points_balance,omitempty"`
+
+	// Group selects the price tiers the cart is priced from, filled in by the service from
+	// its customer provider when group pricing is configured
+	Group CustomerGroup `json:"group,omitempty"`
+
+	// Membership is the customer's membership plan, nil for customers without one
+	Membership *Membership `json:"membership,omitempty"`
+
+	// Segments are the marketing segments the customer belongs to, e.g. "lapsed" or
+	// "high-value". The service adds those its segment resolver supplies.
+	Segments []string `json:"segments,omitempty"`
 }
 
 type DiscountType string
@@ -63,45 +173,290 @@ const (
 	DiscountTypeCategory DiscountType = "category"
 	DiscountTypeBank     DiscountType = "bank"
 	DiscountTypeVoucher  DiscountType = "voucher"
+
+	// DiscountTypePointsRedemption converts loyalty points into cart credit. Value is the
+	// credit granted per point and MaxAmount caps the credit per order.
+	DiscountTypePointsRedemption DiscountType = "points_redemption"
+
+	// DiscountTypeReferral is a code shared by a referrer. It only applies when the buyer
+	// enters the code, and every use earns the referrer ReferrerReward.
+	DiscountTypeReferral DiscountType = "referral"
+
+	// DiscountTypeWallet is an offer for paying with a wallet. ApplicableTo lists the wallet
+	// providers it accepts, every provider when empty.
+	DiscountTypeWallet DiscountType = "wallet"
+
+	// DiscountTypeSpendTier takes more off bigger carts, e.g. 200 off over 2000 and 600 off
+	// over 5000. SpendTiers lists the tiers, reached on the subtotal left after brand discounts.
+	DiscountTypeSpendTier DiscountType = "spend_tier"
+
+	// DiscountTypeEmployee is a staff discount for customers marked IsEmployee. MonthlyCap
+	// limits what it gives each employee per calendar month.
+	DiscountTypeEmployee DiscountType = "employee"
 )
 
+// typeOrder breaks priority ties between discount types: item discounts first, then
+// discounts on the cart, then those on the payment, as a checkout applies them
+var typeOrder = map[DiscountType]int{
+	DiscountTypeBrand:            1,
+	DiscountTypeCategory:         2,
+	DiscountTypeSpendTier:        3,
+	DiscountTypeVoucher:          4,
+	DiscountTypeEmployee:         5,
+	DiscountTypeReferral:         6,
+	DiscountTypePointsRedemption: 7,
+	DiscountTypeWallet:           8,
+	DiscountTypeBank:             9,
+}
+
+// AppliesBefore reports whether d is applied before other: by descending priority, then by
+// type in typeOrder, with types of the embedder's own after the built-in ones in name order,
+// then by ID. Every calculation and listing orders discounts this way, so MaxAmount caps
+// and budgets shared by equal priorities give the same result on every run.
+func (d *Discount) AppliesBefore(other *Discount) bool {
+	if d.Priority != other.Priority {
+		return d.Priority > other.Priority
+	}
+	if d.Type != other.Type {
+		ti, tj := typeOrder[d.Type], typeOrder[other.Type]
+		switch {
+		case ti == 0 && tj == 0:
+			return d.Type < other.Type
+		case ti == 0 || tj == 0:
+			return tj == 0
+		default:
+			return ti < tj
+		}
+	}
+	return d.ID < other.ID
+}
+
 type Discount struct {
 	ID            string          `json:"id"`
+	Ref           DiscountRef     `json:"ref,omitempty"`       // Stable identifier for analytics, assigned on create when empty
+	TenantID      string          `json:"tenant_id,omitempty"` // Storefront owning the discount, empty for the default tenant
 	Name          string          `json:"name"`
 	Type          DiscountType    `json:"type"`
 	Value         decimal.Decimal `json:"value"`          // Percentage or fixed amount
 	IsPercentage  bool            `json:"is_percentage"`  // True for percentage, false for fixed amount
 	MinAmount     decimal.Decimal `json:"min_amount"`     // Minimum order amount
 	MaxAmount     decimal.Decimal `json:"max_amount"`     // Maximum discount amount
-	ApplicableTo  []string        `json:"applicable_to"`  // Brand names, categories, bank names, etc.
-	ExcludedItems []string        `json:"excluded_items"` // Excluded brand ids, category ids, etc.
+	ApplicableTo  []string        `json:"applicable_to"`  // Brand names, categories, bank names, wallet providers, or typed ItemRef entries
+	ExcludedItems []string        `json:"excluded_items"` // Excluded brand/category ids, or typed ItemRef entries (product, sku)
 	CustomerTiers []string        `json:"customer_tiers"` // Applicable customer tiers
 	Code          string          `json:"code"`           // Voucher code (for voucher discounts)
 	ValidFrom     time.Time       `json:"valid_from"`
 	ValidTo       time.Time       `json:"valid_to"`
 	IsActive      bool            `json:"is_active"`
-	UsageLimit    int             `json:"usage_limit"` // Maximum number of uses
-	UsedCount     int             `json:"used_count"`  // Current usage count
-	Priority      int             `json:"priority"`    // Higher number = higher priority
+	State         LifecycleState  `json:"state,omitempty"`        // Publication workflow state, ACTIVE when empty
+	SubmittedBy   string          `json:"submitted_by,omitempty"` // Actor who submitted the discount for approval
+	UsageLimit    int             `json:"usage_limit"`            // Maximum number of uses
+	UsedCount     int             `json:"used_count"`             // Current usage count
+	Version       int             `json:"version"`                // Bumped by every update, which must name the stored version
+	Priority      int             `json:"priority"`               // Higher number = higher priority
+	Schedule      *Schedule       `json:"schedule,omitempty"`     // Optional recurring windows within ValidFrom/ValidTo
+	CampaignID    string          `json:"campaign_id,omitempty"`  // Campaign whose budget this discount draws from
+	MaxTotalSpend decimal.Decimal `json:"max_total_spend"`        // Total amount the discount may give away, zero for no limit
+	MonthlyCap    decimal.Decimal `json:"monthly_cap"`            // Most an employee discount gives each employee per calendar month, zero for no limit
+
+	// Currency is the ISO 4217 code of the discount's fixed amounts, empty for
+	// DefaultCurrency. Those amounts must be whole minor units of it, see CheckMinorUnits.
+	Currency string `json:"currency,omitempty"`
+
+	// Rounding overrides how the service rounds the amounts the discount takes off, e.g. the
+	// floor rounding some bank partners mandate for their instant discounts
+	Rounding RoundingMode `json:"rounding,omitempty"`
+
+	FirstOrderOnly    bool `json:"first_order_only,omitempty"`    // Only customers without previous orders qualify
+	MaxPreviousOrders *int `json:"max_previous_orders,omitempty"` // Customers with more previous orders do not qualify
+
+	// IntroductoryOrders runs the discount as an introductory program covering each of a
+	// customer's first N orders, tracked through the redemption log, see IntroductoryOrdersLeft
+	IntroductoryOrders int `json:"introductory_orders,omitempty"`
+
+	// Experiment runs the discount as an A/B test, giving it to a share of customers only
+	Experiment *DiscountExperiment `json:"experiment,omitempty"`
+
+	// Segments narrow the discount to customers in at least one of the segments, and
+	// customers in any of ExcludedSegments never qualify. See MatchesSegments.
+	Segments         []string `json:"segments,omitempty"`
+	ExcludedSegments []string `json:"excluded_segments,omitempty"`
+
+	// MembershipPlans limits the discount to customers with an active membership of one of
+	// the plans. Without a code it applies to members automatically, as in "club members get
+	// an extra 5% on everything". See AdmitsMemberAt.
+	MembershipPlans []string `json:"membership_plans,omitempty"`
+
+	// Occasion limits the discount to the days around the customer's birthday or signup
+	// anniversary, see IsOccasionAt
+	Occasion *Occasion `json:"occasion,omitempty"`
+
+	// AllowedCustomerIDs and AllowedEmailDomains bind a voucher to named customers, or to
+	// customers with an email address at one of the domains such as a corporate "acme.com";
+	// other customers cannot use its code. See IsBoundToCustomer.
+	AllowedCustomerIDs  []string `json:"allowed_customer_ids,omitempty"`
+	AllowedEmailDomains []string `json:"allowed_email_domains,omitempty"`
+
+	// AllowedRegions limits the discount to orders shipping within one of the regions, and
+	// orders shipping within any of ExcludedRegions never qualify. Entries name states or
+	// are typed region entries, see RegionRef and IsAvailableIn.
+	AllowedRegions  []string `json:"allowed_regions,omitempty"`
+	ExcludedRegions []string `json:"excluded_regions,omitempty"`
+
+	// Channels are the sales channels the discount is sold on, every channel when empty,
+	// see IsSoldOn
+	Channels []SalesChannel `json:"channels,omitempty"`
+
+	// CodeAliases are further codes unlocking the discount, e.g. one per influencer of a
+	// campaign, recorded on redemptions so each can be credited separately
+	CodeAliases []string `json:"code_aliases,omitempty"`
+
+	// GeneratedCodes limits the discount to checkouts entering one of the single-use codes
+	// minted for it, see CouponCode
+	GeneratedCodes bool `json:"generated_codes,omitempty"`
+
+	// CardTypes, CardBINs and CardNetworks narrow a bank discount to payment cards: credit
+	// or debit, BINs starting with a prefix or in a range such as 414000-414999, and card
+	// networks. Empty lists accept every card, see MatchesCard.
+	CardTypes    []CardType    `json:"card_types,omitempty"`
+	CardBINs     []string      `json:"card_bins,omitempty"`
+	CardNetworks []CardNetwork `json:"card_networks,omitempty"`
+
+	// PaymentMethods are the methods a bank discount accepts the bank's payments by, CARD
+	// only when empty. See AcceptedPaymentMethods.
+	PaymentMethods []PaymentMethod `json:"payment_methods,omitempty"`
+
+	// AppliesAfterTax takes the discount off after tax is charged, so tax is computed on
+	// the price before it, as for manufacturer coupons. Other discounts lower the taxable
+	// amount and tax is computed on the discounted price.
+	AppliesAfterTax bool `json:"applies_after_tax,omitempty"`
+
+	// Cashback pays the discount back after the purchase instead of taking it off the price,
+	// as many bank offers do: the amount is reported in DiscountedPrice.PendingCashback and
+	// FinalPrice is not lowered. CashbackDelayDays is how long until it is credited.
+	Cashback          bool `json:"cashback,omitempty"`
+	CashbackDelayDays int  `json:"cashback_delay_days,omitempty"`
+
+	// MinStockThreshold and MaxStockThreshold limit a brand or category discount to products
+	// with at least or at most that many units in stock, as the service's inventory
+	// provider reports when the cart is priced, e.g. a clearance discount that stops once
+	// stock falls to 10. Products of unknown stock are not discounted. See StockAllows.
+	MinStockThreshold *int `json:"min_stock_threshold,omitempty"`
+	MaxStockThreshold *int `json:"max_stock_threshold,omitempty"`
+
+	// MaxUnitsPerCart limits a brand or category discount to the first matching units of the
+	// cart, in cart order, e.g. 20% off up to 2 units per cart. Zero discounts every unit.
+	MaxUnitsPerCart int `json:"max_units_per_cart,omitempty"`
+
+	// TargetSelection picks the targeted units a brand, category or voucher discount is taken
+	// off, TargetCount of them for CHEAPEST_N; MinQuantity is how many targeted units the cart
+	// must hold for it to apply. "Cheapest item free when you buy 3" is a 100% discount
+	// selecting CHEAPEST_ITEM with MinQuantity 3. See SelectedAmount.
+	TargetSelection TargetSelection `json:"target_selection,omitempty"`
+	TargetCount     int             `json:"target_count,omitempty"`
+	MinQuantity     int             `json:"min_quantity,omitempty"`
+
+	// Basis is the amount a cart-wide discount is calculated on: what earlier discounts left
+	// of the cart when empty, or the original price or targeted lines however many discounts
+	// stacked before it. It never takes off more than is left. See PricingBase.
+	Basis DiscountBasis `json:"basis,omitempty"`
+
+	// SpendTiers are the tiers of a spend tier discount, ascending by threshold; Value is
+	// unused. See ReachedSpendTier.
+	SpendTiers []SpendTier `json:"spend_tiers,omitempty"`
+
+	ReferrerID     string          `json:"referrer_id,omitempty"` // Customer who owns a referral code
+	ReferrerReward decimal.Decimal `json:"referrer_reward"`       // Reward recorded for the referrer per use
+
+	compiled *compiledLists // Compiled ApplicableTo/ExcludedItems, see Compile
+}
+
+// Compile pre-compiles the wildcard patterns in ApplicableTo and ExcludedItems and parses
+// the Schedule. Repositories call it when a discount is stored so matching during pricing
+// does no compilation work; uncompiled or since-edited discounts are compiled on demand.
+func (d *Discount) Compile() {
+	d.compiled = compileLists(d.ApplicableTo, d.ExcludedItems)
+	if d.Schedule != nil {
+		// The schedule may be shared with the caller's copy of the discount
+		schedule := *d.Schedule
+		schedule.compiled = schedule.compile()
+		d.Schedule = &schedule
+	}
+}
+
+func (d *Discount) lists() *compiledLists {
+	if d.compiled != nil && d.compiled.isFor(d.ApplicableTo, d.ExcludedItems) {
+		return d.compiled
+	}
+	return compileLists(d.ApplicableTo, d.ExcludedItems)
+}
+
+// MatchesApplicableValue reports whether a plain value, such as a bank name, matches any
+// ApplicableTo entry, honouring wildcard patterns
+func (d *Discount) MatchesApplicableValue(value string) bool {
+	for _, m := range d.lists().applicableTo {
+		if m.matchesValue(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Codes returns the discount's code followed by its aliases
+func (d *Discount) Codes() []string {
+	if d.Code == "" {
+		return d.CodeAliases
+	}
+	return append([]string{d.Code}, d.CodeAliases...)
+}
+
+// CurrencyCode returns the code of the discount's currency, resolving the default
+func (d *Discount) CurrencyCode() string {
+	if d.Currency == "" {
+		return DefaultCurrency.Code
+	}
+	return d.Currency
+}
+
+// RequiresCode reports whether the discount only applies when its code is entered at checkout
+func (d *Discount) RequiresCode() bool {
+	return d.Type == DiscountTypeReferral || d.GeneratedCodes
+}
+
+// HasSpendLimit reports whether the discount caps the total amount it gives away
+func (d *Discount) HasSpendLimit() bool {
+	return d.MaxTotalSpend.IsPositive()
 }
 
 func (d *Discount) IsValid() bool {
-	now := time.Now()
+	return d.IsValidAt(time.Now())
+}
+
+// IsValidAt reports whether the discount can be used at the given instant
+func (d *Discount) IsValidAt(now time.Time) bool {
 	return d.IsActive &&
+		d.IsPublished() &&
 		now.After(d.ValidFrom) &&
 		now.Before(d.ValidTo) &&
-		(d.UsageLimit == 0 || d.UsedCount < d.UsageLimit)
+		(d.UsageLimit == 0 || d.UsedCount < d.UsageLimit) &&
+		(d.Schedule == nil || d.Schedule.IsActiveAt(now))
 }
 
+// IsExcluded reports whether any ExcludedItems entry refers to the product. Untyped
+// entries match the product's brand or category; typed entries (see ItemRef) can also
+// exclude individual products and SKUs.
 func (d *Discount) IsExcluded(product Product) bool {
-	for _, excluded := range d.ExcludedItems {
-		if excluded == product.Brand.ID || excluded == product.Category.ID {
+	for _, m := range d.lists().excluded {
+		if m.matchesProduct(product, ItemRefBrand, ItemRefCategory) {
 			return true
 		}
 	}
 	return false
 }
 
+// MatchesProduct reports whether the discount targets the product. Untyped ApplicableTo
+// entries refer to the discount type's own dimension (brand IDs for brand discounts,
+// category IDs for category discounts); typed entries may name any dimension.
 func (d *Discount) MatchesProduct(product Product) bool {
 	if d.IsExcluded(product) {
 		return false
@@ -109,17 +464,55 @@ func (d *Discount) MatchesProduct(product Product) bool {
 
 	switch d.Type {
 	case DiscountTypeBrand:
-		return d.isInList(product.Brand.ID, d.ApplicableTo)
+		return d.appliesTo(product, ItemRefBrand)
 	case DiscountTypeCategory:
-		return d.isInList(product.Category.ID, d.ApplicableTo)
+		return d.appliesTo(product, ItemRefCategory)
 	case DiscountTypeVoucher:
-		return true
+		return d.appliesTo(product, ItemRefBrand, ItemRefCategory)
 	default:
 		return true
 	}
 }
 
+func (d *Discount) appliesTo(product Product, untyped ...ItemRefKind) bool {
+	if len(d.ApplicableTo) == 0 {
+		return true // No restrictions
+	}
+	for _, m := range d.lists().applicableTo {
+		if m.matchesProduct(product, untyped...) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckUnitLimit reports a MaxUnitsPerCart the discount cannot honour
+func (d *Discount) CheckUnitLimit() error {
+	switch {
+	case d.MaxUnitsPerCart < 0:
+		return fmt.Errorf("max units per cart cannot be negative")
+	case d.MaxUnitsPerCart > 0 && d.Type != DiscountTypeBrand && d.Type != DiscountTypeCategory:
+		return fmt.Errorf("only brand and category discounts can limit their units per cart")
+	}
+	return nil
+}
+
 func (d *Discount) IsApplicableToCustomer(customer CustomerProfile) bool {
+	if d.FirstOrderOnly && customer.OrderCount > 0 {
+		return false
+	}
+	if d.MaxPreviousOrders != nil && customer.OrderCount > *d.MaxPreviousOrders {
+		return false
+	}
+	if d.IntroductoryOrders > 0 && customer.OrderCount >= d.IntroductoryOrders {
+		return false
+	}
+	if !d.MatchesSegments(customer.Segments) {
+		return false
+	}
+	if !d.IsBoundToCustomer(customer) {
+		return false
+	}
 	if len(d.CustomerTiers) == 0 {
 		return true // No tier restrictions
 	}
@@ -138,6 +531,15 @@ func (d *Discount) isInList(item string, list []string) bool {
 	return false
 }
 
+// PointsForCredit returns how many loyalty points pay for the given credit under a points
+// redemption discount, rounding up so customers never get credit they did not pay for
+func (d *Discount) PointsForCredit(credit decimal.Decimal) int64 {
+	if !d.Value.IsPositive() {
+		return 0
+	}
+	return credit.Div(d.Value).Ceil().IntPart()
+}
+
 func (d *Discount) CalculateDiscount(price decimal.Decimal) decimal.Decimal {
 	if d.IsPercentage {
 		discount := price.Mul(d.Value).Div(decimal.NewFromInt(PercentageBase))
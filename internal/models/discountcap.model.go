@@ -0,0 +1,24 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// CheckMaxDiscountPercent reports a cart discount cap outside 0 to 100 percent
+func CheckMaxDiscountPercent(percent decimal.Decimal) error {
+	if percent.IsNegative() || percent.GreaterThan(decimal.NewFromInt(PercentageBase)) {
+		return fmt.Errorf("maximum discount %s%% is outside 0 to 100 percent", percent)
+	}
+	return nil
+}
+
+// DiscountCapAdjustment reports a discount scaled back so the cart's discounts stayed within
+// the configured share of its original price
+type DiscountCapAdjustment struct {
+	DiscountID string          `json:"discount_id"`
+	Name       string          `json:"name"`
+	Requested  decimal.Decimal `json:"requested"` // Amount the discount came to
+	Applied    decimal.Decimal `json:"applied"`   // Amount left under the cap, zero when dropped
+}
@@ -0,0 +1,252 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// DiscountFilter narrows a discount query to the discounts that may apply to a checkout;
+// zero values match everything. Filters are deliberately generous: a matching discount
+// may still be rejected by its strategy, but a discount that could apply always matches.
+type DiscountFilter struct {
+	Types []DiscountType // Only discounts of these types
+
+	// BrandIDs, CategoryIDs, ProductIDs and SKUs describe the items being priced. When any
+	// is set, brand, category and voucher discounts restricted to items must be able to
+	// target one of them.
+	BrandIDs    []string
+	CategoryIDs []string
+	ProductIDs  []string
+	SKUs        []string
+
+	// PaymentMethod, Bank and WalletProvider describe how the order is paid: when
+	// PaymentMethod is set, bank and wallet discounts need a payment by a method and from a
+	// bank or wallet they accept. WithoutPayment says the order has no payment details, so
+	// no payment discount applies.
+	PaymentMethod  PaymentMethod
+	Bank           string
+	WalletProvider string
+	WithoutPayment bool
+
+	CustomerTier string    // Discounts restricted to tiers must include this one
+	ActiveAt     time.Time // Only discounts usable at this instant, see Discount.IsValidAt
+	From         time.Time // Only discounts still valid after From
+	To           time.Time // Only discounts valid before To
+}
+
+// CartDiscountFilter selects the discounts that may apply to pricing the cart at now
+func CartDiscountFilter(cart []CartItem, customer CustomerProfile, payment *PaymentInfo, now time.Time) DiscountFilter {
+	f := DiscountFilter{CustomerTier: customer.Tier, ActiveAt: now, WithoutPayment: payment == nil}
+	for _, item := range cart {
+		f.BrandIDs = append(f.BrandIDs, item.Product.Brand.ID)
+		f.CategoryIDs = append(f.CategoryIDs, item.Product.Category.ID)
+		f.ProductIDs = append(f.ProductIDs, item.Product.ID)
+		if item.Product.SKU != "" {
+			f.SKUs = append(f.SKUs, item.Product.SKU)
+		}
+	}
+	if payment != nil {
+		f.PaymentMethod = payment.Method
+		if payment.BankName != nil {
+			f.Bank = *payment.BankName
+		}
+		if payment.WalletProvider != nil {
+			f.WalletProvider = *payment.WalletProvider
+		}
+	}
+	return f
+}
+
+// Matches reports whether the discount satisfies the filter
+func (f DiscountFilter) Matches(d *Discount) bool {
+	if len(f.Types) > 0 && !containsType(f.Types, d.Type) {
+		return false
+	}
+	if f.hasItems() && !f.matchesItems(d) {
+		return false
+	}
+	if (d.Type == DiscountTypeBank || d.Type == DiscountTypeWallet) && !f.matchesPayment(d) {
+		return false
+	}
+	if f.CustomerTier != "" && len(d.CustomerTiers) > 0 && !d.isInList(f.CustomerTier, d.CustomerTiers) {
+		return false
+	}
+	if !f.ActiveAt.IsZero() && !d.IsValidAt(f.ActiveAt) {
+		return false
+	}
+	if !f.From.IsZero() && !d.ValidTo.After(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !d.ValidFrom.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// CandidateKeys returns the index keys a matching discount must be stored under, see
+// Discount.CandidateKeys. It reports false when the filter does not constrain both items
+// and payment, in which case an index cannot narrow the search.
+func (f DiscountFilter) CandidateKeys() ([]string, bool) {
+	if !f.hasItems() || (f.PaymentMethod == "" && !f.WithoutPayment) {
+		return nil, false
+	}
+
+	keys := append([]string{AnyCandidateKey}, f.itemKeys()...)
+	if f.PaymentMethod != "" {
+		keys = append(keys, paymentCandidatePrefix+string(f.PaymentMethod))
+	}
+	if f.Bank != "" && f.PaymentMethod != Wallet {
+		keys = append(keys, bankCandidatePrefix+f.Bank)
+	}
+	if f.WalletProvider != "" && f.PaymentMethod == Wallet {
+		keys = append(keys, walletCandidatePrefix+f.WalletProvider)
+	}
+	return keys, true
+}
+
+func (f DiscountFilter) hasItems() bool {
+	return len(f.BrandIDs)+len(f.CategoryIDs)+len(f.ProductIDs)+len(f.SKUs) > 0
+}
+
+func (f DiscountFilter) itemKeys() []string {
+	keys := make([]string, 0, len(f.BrandIDs)+len(f.CategoryIDs)+len(f.ProductIDs)+len(f.SKUs))
+	for _, attrs := range []struct {
+		kind   ItemRefKind
+		values []string
+	}{
+		{ItemRefBrand, f.BrandIDs},
+		{ItemRefCategory, f.CategoryIDs},
+		{ItemRefProduct, f.ProductIDs},
+		{ItemRefSKU, f.SKUs},
+	} {
+		for _, v := range attrs.values {
+			keys = append(keys, ItemRef(attrs.kind, v))
+		}
+	}
+	return keys
+}
+
+func (f DiscountFilter) matchesItems(d *Discount) bool {
+	switch d.Type {
+	case DiscountTypeBrand, DiscountTypeCategory, DiscountTypeVoucher:
+	default:
+		return true
+	}
+
+	itemKeys := f.itemKeys()
+	for _, key := range d.CandidateKeys() {
+		if key == AnyCandidateKey {
+			return true
+		}
+		for _, itemKey := range itemKeys {
+			if key == itemKey {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f DiscountFilter) matchesPayment(d *Discount) bool {
+	switch {
+	case f.WithoutPayment:
+		return false
+	case f.PaymentMethod == "":
+		return true
+	}
+
+	provider := f.Bank
+	if d.Type == DiscountTypeWallet {
+		provider = f.WalletProvider
+	}
+	payment := &PaymentInfo{Method: f.PaymentMethod}
+	if provider != "" {
+		payment.BankName, payment.WalletProvider = &provider, &provider
+	}
+	return d.MatchesPayment(payment)
+}
+
+func containsType(types []DiscountType, t DiscountType) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Candidate keys let repositories index discounts by what a cart must contain for them to
+// apply. A discount is a candidate for a cart when they share a key; keys only narrow the
+// search, so every candidate is still checked by its strategy.
+const (
+	// AnyCandidateKey marks discounts that cannot be narrowed down and are candidates for
+	// every cart: unrestricted discounts, wildcard patterns and types without an index
+	AnyCandidateKey = "*"
+
+	bankCandidatePrefix    = "bank:"
+	walletCandidatePrefix  = "wallet:"
+	paymentCandidatePrefix = "payment:"
+)
+
+// CandidateKeys returns the keys the discount is indexed under
+func (d *Discount) CandidateKeys() []string {
+	switch d.Type {
+	case DiscountTypeBrand:
+		return itemCandidateKeys(d.ApplicableTo, ItemRefBrand)
+	case DiscountTypeCategory:
+		return itemCandidateKeys(d.ApplicableTo, ItemRefCategory)
+	case DiscountTypeVoucher:
+		return itemCandidateKeys(d.ApplicableTo, ItemRefBrand, ItemRefCategory)
+	case DiscountTypeBank:
+		return providerCandidateKeys(d.ApplicableTo, bankCandidatePrefix, d.AcceptedPaymentMethods()...)
+	case DiscountTypeWallet:
+		return providerCandidateKeys(d.ApplicableTo, walletCandidatePrefix, Wallet)
+	default:
+		return []string{AnyCandidateKey}
+	}
+}
+
+// providerCandidateKeys keys a payment discount by each bank or wallet provider it accepts,
+// or by its payment methods when it accepts any provider
+func providerCandidateKeys(applicableTo []string, prefix string, methods ...PaymentMethod) []string {
+	anyProvider := make([]string, 0, len(methods))
+	for _, m := range methods {
+		anyProvider = append(anyProvider, paymentCandidatePrefix+string(m))
+	}
+	if len(applicableTo) == 0 {
+		return anyProvider
+	}
+
+	keys := make([]string, 0, len(applicableTo))
+	for _, provider := range applicableTo {
+		if strings.ContainsAny(provider, patternWildcards) {
+			return anyProvider
+		}
+		keys = append(keys, prefix+provider)
+	}
+	return keys
+}
+
+// itemCandidateKeys keys a product discount by each ApplicableTo entry, untyped entries
+// once per dimension they may refer to
+func itemCandidateKeys(applicableTo []string, untyped ...ItemRefKind) []string {
+	if len(applicableTo) == 0 {
+		return []string{AnyCandidateKey}
+	}
+
+	keys := make([]string, 0, len(applicableTo))
+	for _, entry := range applicableTo {
+		kind, value := parseItemRef(entry)
+		if strings.ContainsAny(value, patternWildcards) {
+			return []string{AnyCandidateKey}
+		}
+		if kind != "" {
+			keys = append(keys, ItemRef(kind, value))
+			continue
+		}
+		for _, k := range untyped {
+			keys = append(keys, ItemRef(k, value))
+		}
+	}
+	return keys
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// DiscountRef identifies a discount for analytics and monitoring. Unlike the display Name it
+// never changes, so results, events, redemptions and metrics can be joined on it however
+// often an offer is renamed. Derived refs are unique across tenants, unlike IDs; a discount
+// recreated under a new ID may carry its old ref over instead.
+type DiscountRef string
+
+// discountRefPrefix starts every derived ref, telling refs apart from IDs at a glance
+const discountRefPrefix = "dref_"
+
+// NewDiscountRef derives the ref of a discount stored without one from its tenant and ID,
+// so every instance assigns a seeded discount the same ref
+func NewDiscountRef(tenantID, id string) DiscountRef {
+	sum := sha256.Sum256([]byte(tenantID + "\x00" + id))
+	return DiscountRef(discountRefPrefix + hex.EncodeToString(sum[:8]))
+}
+
+// StableRef returns the discount's ref, derived from its tenant and ID when it has none
+func (d *Discount) StableRef() DiscountRef {
+	if d.Ref != "" {
+		return d.Ref
+	}
+	return NewDiscountRef(d.TenantID, d.ID)
+}
+
+// DiscountRefMapping ties a ref to the discount carrying it and its current display name
+type DiscountRefMapping struct {
+	Ref      DiscountRef  `json:"ref"`
+	ID       string       `json:"id"`
+	TenantID string       `json:"tenant_id,omitempty"`
+	Name     string       `json:"name"`
+	Type     DiscountType `json:"type"`
+}
+
+// RefMapping returns the discount's ref mapping
+func (d *Discount) RefMapping() DiscountRefMapping {
+	return DiscountRefMapping{Ref: d.StableRef(), ID: d.ID, TenantID: d.TenantID, Name: d.Name, Type: d.Type}
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// HasMonthlyCap reports whether the discount caps what it gives each customer per
+// calendar month
+func (d *Discount) HasMonthlyCap() bool {
+	return d.MonthlyCap.IsPositive()
+}
+
+// CheckMonthlyCap reports monthly caps on discounts other than employee discounts
+func (d *Discount) CheckMonthlyCap() error {
+	switch {
+	case d.MonthlyCap.IsNegative():
+		return fmt.Errorf("monthly cap cannot be negative")
+	case d.HasMonthlyCap() && d.Type != DiscountTypeEmployee:
+		return fmt.Errorf("only employee discounts can have a monthly cap")
+	}
+	return nil
+}
+
+// MonthlySpendKey is the spend tracker key of what the discount gave the customer in the
+// calendar month of at, in at's location
+func MonthlySpendKey(discountID, customerID string, at time.Time) string {
+	return discountID + "/" + customerID + "/" + at.Format("2006-01")
+}
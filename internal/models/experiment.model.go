@@ -0,0 +1,178 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Default variant names of discount experiments
+const (
+	DefaultTreatmentVariant = "treatment"
+	DefaultControlVariant   = "control"
+)
+
+// DiscountExperiment runs a discount as an A/B test: customers are bucketed by ID, those in
+// the first TrafficPercent buckets get the discount as Variant and the rest form the control
+// group, which never sees it. Anonymous customers are always in the control group.
+type DiscountExperiment struct {
+	ID             string `json:"id"`
+	Variant        string `json:"variant,omitempty"`         // Variant of customers given the discount, DefaultTreatmentVariant when empty
+	ControlVariant string `json:"control_variant,omitempty"` // Variant of the control group, DefaultControlVariant when empty
+	TrafficPercent int    `json:"traffic_percent"`           // Share of customers given the discount, 0 to 100
+}
+
+// ExperimentExposure records the variant a calculation saw a customer in for one of the
+// discount experiments it considered
+type ExperimentExposure struct {
+	ExperimentID string `json:"experiment_id"`
+	DiscountID   string `json:"discount_id"`
+	Variant      string `json:"variant"`
+	Treated      bool   `json:"treated"` // Whether the variant is given the discount
+}
+
+// ExperimentBucket deterministically places the customer in one of 100 buckets of the
+// experiment. Every experiment buckets customers independently.
+func ExperimentBucket(experimentID, customerID string) int {
+	sum := sha256.Sum256([]byte(experimentID + "\x00" + customerID))
+	return int(binary.BigEndian.Uint64(sum[:8]) % PercentageBase)
+}
+
+// Assign returns the variant the customer is in and whether it is given the discount
+func (e *DiscountExperiment) Assign(customerID string) (string, bool) {
+	if customerID != "" && ExperimentBucket(e.ID, customerID) < e.TrafficPercent {
+		return e.treatmentVariant(), true
+	}
+	return e.controlVariant(), false
+}
+
+// ExposureOf returns the customer's exposure to the discount's experiment
+func (e *DiscountExperiment) ExposureOf(discountID, customerID string) ExperimentExposure {
+	variant, treated := e.Assign(customerID)
+	return ExperimentExposure{ExperimentID: e.ID, DiscountID: discountID, Variant: variant, Treated: treated}
+}
+
+func (e *DiscountExperiment) treatmentVariant() string {
+	if e.Variant == "" {
+		return DefaultTreatmentVariant
+	}
+	return e.Variant
+}
+
+func (e *DiscountExperiment) controlVariant() string {
+	if e.ControlVariant == "" {
+		return DefaultControlVariant
+	}
+	return e.ControlVariant
+}
+
+// IsExposedTo reports whether the customer is given the discount, which holds for every
+// customer of discounts outside an experiment
+func (d *Discount) IsExposedTo(customerID string) bool {
+	if d.Experiment == nil {
+		return true
+	}
+	_, treated := d.Experiment.Assign(customerID)
+	return treated
+}
+
+// CheckExperiment reports an experiment the discount cannot run
+func (d *Discount) CheckExperiment() error {
+	e := d.Experiment
+	switch {
+	case e == nil:
+		return nil
+	case e.ID == "":
+		return fmt.Errorf("experiment requires an ID")
+	case e.TrafficPercent < 0 || e.TrafficPercent > PercentageBase:
+		return fmt.Errorf("experiment traffic must be between 0 and 100 percent")
+	case e.treatmentVariant() == e.controlVariant():
+		return fmt.Errorf("experiment variant and control variant must differ")
+	}
+	return nil
+}
+
+// ExperimentAssignment records which variant of an experiment a customer was bucketed into
+type ExperimentAssignment struct {
+	ExperimentID string    `json:"experiment_id"`
+	CustomerID   string    `json:"customer_id"`
+	Variant      string    `json:"variant"`
+	AssignedAt   time.Time `json:"assigned_at"`
+}
+
+// Order is a placed order as reported back by the checkout system
+type Order struct {
+	ID         string          `json:"id"`
+	CustomerID string          `json:"customer_id"`
+	Total      decimal.Decimal `json:"total"` // Amount paid after discounts
+	PlacedAt   time.Time       `json:"placed_at"`
+}
+
+// Redemption records a single application of a discount to a customer's cart
+type Redemption struct {
+	DiscountID  string          `json:"discount_id"`
+	DiscountRef DiscountRef     `json:"discount_ref,omitempty"`
+	CampaignID  string          `json:"campaign_id,omitempty"` // Campaign the discount belonged to when redeemed
+	CustomerID  string          `json:"customer_id"`
+	OrderID     string          `json:"order_id,omitempty"`
+	Code        string          `json:"code,omitempty"` // Code or alias entered that unlocked the discount
+	Amount      decimal.Decimal `json:"amount"`
+	OrderTotal  decimal.Decimal `json:"order_total"` // Amount the customer paid for the whole order
+	RedeemedAt  time.Time       `json:"redeemed_at"`
+}
+
+// RedemptionFilter narrows a redemption listing; zero values match everything
+type RedemptionFilter struct {
+	DiscountID string
+	CampaignID string
+	CustomerID string
+	From       time.Time
+	To         time.Time
+}
+
+// Matches reports whether the redemption satisfies the filter
+func (f RedemptionFilter) Matches(r Redemption) bool {
+	if f.DiscountID != "" && r.DiscountID != f.DiscountID {
+		return false
+	}
+	if f.CampaignID != "" && r.CampaignID != f.CampaignID {
+		return false
+	}
+	if f.CustomerID != "" && r.CustomerID != f.CustomerID {
+		return false
+	}
+	if !f.From.IsZero() && r.RedeemedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !r.RedeemedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// VariantMetrics aggregates the outcome of one experiment variant
+type VariantMetrics struct {
+	Variant            string          `json:"variant"`
+	Customers          int             `json:"customers"`           // Customers assigned to the variant
+	ConvertedCustomers int             `json:"converted_customers"` // Customers with at least one order after assignment
+	Orders             int             `json:"orders"`
+	Revenue            decimal.Decimal `json:"revenue"`
+	Redemptions        int             `json:"redemptions"`
+	DiscountCost       decimal.Decimal `json:"discount_cost"`
+	ConversionRate     decimal.Decimal `json:"conversion_rate"`     // Percentage of customers that converted
+	AverageOrderValue  decimal.Decimal `json:"average_order_value"` // Revenue / Orders
+	ConversionUplift   decimal.Decimal `json:"conversion_uplift"`   // Percentage change vs control
+	AOVUplift          decimal.Decimal `json:"aov_uplift"`          // Percentage change vs control
+}
+
+// IncrementalityReport compares every variant of an experiment against its control
+type IncrementalityReport struct {
+	ExperimentID   string           `json:"experiment_id"`
+	ControlVariant string           `json:"control_variant"`
+	From           time.Time        `json:"from"`
+	To             time.Time        `json:"to"`
+	Variants       []VariantMetrics `json:"variants"`
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DecisionOutcome is what happened to one discount during a calculation
+type DecisionOutcome string
+
+const (
+	// DecisionApplied means the discount reduced the cart
+	DecisionApplied DecisionOutcome = "applied"
+	// DecisionSkipped means the discount was not usable for reasons outside this cart, such
+	// as its validity window, campaign or remaining budget
+	DecisionSkipped DecisionOutcome = "skipped"
+	// DecisionRejected means the cart, customer or payment did not meet the discount's conditions
+	DecisionRejected DecisionOutcome = "rejected"
+)
+
+// DecisionReason says precisely why a discount was skipped or rejected
+type DecisionReason string
+
+const (
+	ReasonInactive          DecisionReason = "inactive"         // Switched off
+	ReasonUnpublished       DecisionReason = "unpublished"      // Lifecycle state other than ACTIVE
+	ReasonNotStarted        DecisionReason = "not_started"      // Before ValidFrom
+	ReasonExpired           DecisionReason = "expired"          // After ValidTo, or in the EXPIRED state
+	ReasonUsageExhausted    DecisionReason = "usage_exhausted"  // UsageLimit reached
+	ReasonOutsideSchedule   DecisionReason = "outside_schedule" // Between recurring windows
+	ReasonCodeRequired      DecisionReason = "code_required"    // Code not entered at checkout
+	ReasonCampaignPaused    DecisionReason = "campaign_inactive"
+	ReasonUnsupportedType   DecisionReason = "unsupported_type"  // No strategy for the discount type
+	ReasonCurrencyMismatch  DecisionReason = "currency_mismatch" // Fixed amounts in another currency than the cart
+	ReasonBudgetExhausted   DecisionReason = "budget_exhausted"  // Campaign budget spent, possibly by discounts stacked earlier
+	ReasonSpendCapReached   DecisionReason = "spend_cap_reached"
+	ReasonMonthlyCapReached DecisionReason = "monthly_cap_reached" // The customer used up the month's allowance
+	ReasonPriceFloor        DecisionReason = "price_floor"         // Items already at their price floors
+	ReasonDiscountCap       DecisionReason = "discount_cap"        // Higher-priority discounts used up the cart's discount cap
+
+	// ReasonIntroductoryOrdersUsed means the customer redeemed the introductory program on
+	// all the orders it covers
+	ReasonIntroductoryOrdersUsed DecisionReason = "introductory_orders_used"
+
+	// ReasonPriorityLoss means higher-priority discounts left nothing for this one to take off
+	ReasonPriorityLoss DecisionReason = "priority_loss"
+	// ReasonZeroAmount means the discount matched but its configured value came to nothing
+	ReasonZeroAmount DecisionReason = "zero_amount"
+
+	ReasonCustomerIneligible  DecisionReason = "customer_ineligible" // Tier, segments or order history do not qualify
+	ReasonExperimentControl   DecisionReason = "experiment_control"  // Customer is in the experiment's control group
+	ReasonRegionRestricted    DecisionReason = "region_restricted"   // Order ships outside the discount's regions
+	ReasonChannelMismatch     DecisionReason = "channel_mismatch"    // Order placed through a channel the discount is not sold on
+	ReasonOutsideOccasion     DecisionReason = "outside_occasion"    // Not around the customer's birthday or signup anniversary
+	ReasonMembershipRequired  DecisionReason = "membership_required" // Customer holds no active membership of the discount's plans
+	ReasonStockLevel          DecisionReason = "stock_level"         // Every targeted product's stock is outside the discount's thresholds
+	ReasonMinAmountNotMet     DecisionReason = "min_amount_not_met"
+	ReasonMinQuantityNotMet   DecisionReason = "min_quantity_not_met"   // Too few targeted units
+	ReasonSpendTierNotReached DecisionReason = "spend_tier_not_reached" // Subtotal below the lowest spend tier
+	ReasonNoMatchingItems     DecisionReason = "no_matching_items"
+	ReasonExcluded            DecisionReason = "excluded"        // The cart holds excluded items and nothing else matches
+	ReasonItemsOptedOut       DecisionReason = "items_opted_out" // Every cart line is excluded from promotions
+	ReasonPaymentMismatch     DecisionReason = "payment_mismatch"
+	ReasonCardMismatch        DecisionReason = "card_mismatch" // Card type, BIN or network not accepted
+	ReasonNoPoints            DecisionReason = "no_points"     // No loyalty balance to redeem
+	ReasonSelfReferral        DecisionReason = "self_referral"
+
+	// ReasonNotApplicable is reported when a strategy rejects a discount without saying why
+	ReasonNotApplicable DecisionReason = "not_applicable"
+)
+
+// DiscountDecision records what the engine decided about one discount
+type DiscountDecision struct {
+	DiscountID  string          `json:"discount_id"`
+	DiscountRef DiscountRef     `json:"discount_ref"`
+	Name        string          `json:"name"`
+	Type        DiscountType    `json:"type"`
+	Priority    int             `json:"priority"`
+	Outcome     DecisionOutcome `json:"outcome"`
+	Reason      DecisionReason  `json:"reason,omitempty"` // Empty when applied
+	Detail      string          `json:"detail,omitempty"` // Human readable explanation for support staff
+	Amount      decimal.Decimal `json:"amount"`           // Amount taken off, zero unless applied
+}
+
+// Explanation is a dry-run calculation together with a decision for every discount the
+// tenant has, in the order the engine considered them
+type Explanation struct {
+	Result    *DiscountedPrice   `json:"result"`
+	Decisions []DiscountDecision `json:"decisions"`
+}
+
+// Decision returns the decision made about the discount with the given ID
+func (e *Explanation) Decision(discountID string) (DiscountDecision, bool) {
+	for _, d := range e.Decisions {
+		if d.DiscountID == discountID {
+			return d, true
+		}
+	}
+	return DiscountDecision{}, false
+}
+
+// UnavailableReasonAt returns why the discount cannot be used at the given instant, or an
+// empty reason when IsValidAt holds
+func (d *Discount) UnavailableReasonAt(now time.Time) DecisionReason {
+	switch {
+	case !d.IsActive:
+		return ReasonInactive
+	case d.LifecycleState() == LifecycleExpired:
+		return ReasonExpired
+	case !d.IsPublished():
+		return ReasonUnpublished
+	case !now.After(d.ValidFrom):
+		return ReasonNotStarted
+	case !now.Before(d.ValidTo):
+		return ReasonExpired
+	case d.UsageLimit != 0 && d.UsedCount >= d.UsageLimit:
+		return ReasonUsageExhausted
+	case d.Schedule != nil && !d.Schedule.IsActiveAt(now):
+		return ReasonOutsideSchedule
+	}
+	return ""
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// GiftCard is prepaid store credit identified by its code. Unlike discounts, gift cards
+// pay for the discounted cart rather than reduce its price.
+type GiftCard struct {
+	Code      string          `json:"code"`
+	Balance   decimal.Decimal `json:"balance"`
+	IsActive  bool            `json:"is_active"`
+	ExpiresAt time.Time       `json:"expires_at,omitempty"` // Zero for cards that never expire
+}
+
+// IsUsableAt reports whether the card can pay for an order at the given instant
+func (g *GiftCard) IsUsableAt(now time.Time) bool {
+	return g.IsActive && g.Balance.IsPositive() && (g.ExpiresAt.IsZero() || now.Before(g.ExpiresAt))
+}
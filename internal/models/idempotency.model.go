@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// IdempotencyRecord remembers the outcome of a request made with an idempotency key
+type IdempotencyRecord struct {
+	Key         string           `json:"key"`
+	Fingerprint string           `json:"fingerprint"`      // Digest of the request payload the key was first used with
+	Result      *DiscountedPrice `json:"result,omitempty"` // Nil while the first request is still being processed
+	CreatedAt   time.Time        `json:"created_at"`
+}
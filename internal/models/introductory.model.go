@@ -0,0 +1,27 @@
+package models
+
+import "fmt"
+
+// IsIntroductory reports whether the discount runs an introductory program, see IntroductoryOrders
+func (d *Discount) IsIntroductory() bool {
+	return d.IntroductoryOrders > 0
+}
+
+// IntroductoryOrdersLeft returns how many more orders of the introductory program the
+// customer is entitled to, given how often they have redeemed it. Customers are as far into
+// the program as the greater of their previous orders and their redemptions, so neither an
+// outdated order count nor orders placed without the discount extend it.
+func (d *Discount) IntroductoryOrdersLeft(customer CustomerProfile, redeemed int) int {
+	return max(d.IntroductoryOrders-max(customer.OrderCount, redeemed), 0)
+}
+
+// CheckIntroductoryOrders reports an introductory program the discount cannot run
+func (d *Discount) CheckIntroductoryOrders() error {
+	switch {
+	case d.IntroductoryOrders < 0:
+		return fmt.Errorf("introductory orders cannot be negative")
+	case d.IntroductoryOrders > 0 && d.FirstOrderOnly:
+		return fmt.Errorf("first-order-only discounts cannot run an introductory program")
+	}
+	return nil
+}
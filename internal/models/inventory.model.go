@@ -0,0 +1,48 @@
+package models
+
+import "fmt"
+
+// HasStockCondition reports whether the discount depends on the stock of the products it
+// targets
+func (d *Discount) HasStockCondition() bool {
+	return d.MinStockThreshold != nil || d.MaxStockThreshold != nil
+}
+
+// StockAllows reports whether a product with the given stock may be discounted: at least
+// MinStockThreshold units and at most MaxStockThreshold units when set
+func (d *Discount) StockAllows(stock int) bool {
+	if d.MinStockThreshold != nil && stock < *d.MinStockThreshold {
+		return false
+	}
+	return d.MaxStockThreshold == nil || stock <= *d.MaxStockThreshold
+}
+
+// WithoutProducts returns a copy of the discount also excluding the products, so one
+// calculation can leave some of its targets out
+func (d Discount) WithoutProducts(ids []string) Discount {
+	excluded := make([]string, 0, len(d.ExcludedItems)+len(ids))
+	excluded = append(excluded, d.ExcludedItems...)
+	for _, id := range ids {
+		excluded = append(excluded, ItemRef(ItemRefProduct, id))
+	}
+	d.ExcludedItems = excluded
+	d.compiled = nil
+	return d
+}
+
+// CheckStockThresholds reports stock thresholds on discounts other than brand and category
+// discounts, negative thresholds and ranges no stock level falls in
+func (d *Discount) CheckStockThresholds() error {
+	if !d.HasStockCondition() {
+		return nil
+	}
+	switch {
+	case d.Type != DiscountTypeBrand && d.Type != DiscountTypeCategory:
+		return fmt.Errorf("only brand and category discounts can depend on stock")
+	case d.MinStockThreshold != nil && *d.MinStockThreshold < 0, d.MaxStockThreshold != nil && *d.MaxStockThreshold < 0:
+		return fmt.Errorf("stock thresholds cannot be negative")
+	case d.MinStockThreshold != nil && d.MaxStockThreshold != nil && *d.MinStockThreshold > *d.MaxStockThreshold:
+		return fmt.Errorf("min stock threshold %d is above max stock threshold %d", *d.MinStockThreshold, *d.MaxStockThreshold)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package models
+
+import "strings"
+
+// ItemRefKind names the product attribute an applicability or exclusion entry refers to.
+// Entries are written as "<kind>:<value>", e.g. "sku:TS-PUMA-M-BLK". Entries without a
+// kind prefix keep their historical meaning: a brand or category ID.
+type ItemRefKind string
+
+const (
+	ItemRefBrand    ItemRefKind = "brand"
+	ItemRefCategory ItemRefKind = "category"
+	ItemRefProduct  ItemRefKind = "product"
+	ItemRefSKU      ItemRefKind = "sku"
+)
+
+const itemRefSeparator = ":"
+
+// ItemRef builds a typed applicability or exclusion entry
+func ItemRef(kind ItemRefKind, value string) string {
+	return string(kind) + itemRefSeparator + value
+}
+
+// parseItemRef splits an entry into its kind and value. Untyped entries return an empty kind.
+func parseItemRef(entry string) (ItemRefKind, string) {
+	prefix, value, found := strings.Cut(entry, itemRefSeparator)
+	if !found {
+		return "", entry
+	}
+
+	switch kind := ItemRefKind(prefix); kind {
+	case ItemRefBrand, ItemRefCategory, ItemRefProduct, ItemRefSKU:
+		return kind, value
+	default:
+		// Not a known kind, so the colon is part of the value itself
+		return "", entry
+	}
+}
+
+// productAttribute returns the product's value for the given kind
+func productAttribute(product Product, kind ItemRefKind) string {
+	switch kind {
+	case ItemRefBrand:
+		return product.Brand.ID
+	case ItemRefCategory:
+		return product.Category.ID
+	case ItemRefProduct:
+		return product.ID
+	case ItemRefSKU:
+		return product.SKU
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// JobStatus is where a background job is in its lifecycle
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded" // Every cart was priced, though some may have failed on their own
+	JobFailed    JobStatus = "failed"    // The job stopped before pricing every cart, see Job.Error
+)
+
+// Job prices a batch of carts in the background, for work too large for one request such
+// as recomputing the discount badges of a whole catalog. Carts are priced in chunks, and
+// the job is saved after each one, so a job cut short by a restart resumes from Processed.
+type Job struct {
+	ID       string    `json:"id"`
+	TenantID string    `json:"tenant_id,omitempty"` // Storefront the carts are priced for
+	Status   JobStatus `json:"status"`
+	DryRun   bool      `json:"dry_run,omitempty"` // Carts are priced without recording usage, see BatchCalculationRequest
+
+	Total     int `json:"total"`     // Carts in the job
+	Processed int `json:"processed"` // Carts priced so far, the checkpoint a resumed job restarts from
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+
+	Error string `json:"error,omitempty"` // Why the job failed as a whole
+
+	SubmittedAt time.Time  `json:"submitted_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the job will make no more progress
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed
+}
+
+// JobResult is the outcome of one cart of a job: a result or the reason pricing it failed
+type JobResult struct {
+	Index  int              `json:"index"` // Position of the cart in the job
+	Result *DiscountedPrice `json:"result,omitempty"`
+	Error  string           `json:"error,omitempty"` // Safe to show; unexpected errors are not described
+}
+
+// JobResultPage is one page of a job's results, in cart order
+type JobResultPage struct {
+	Results []JobResult `json:"results"`
+
+	// NextOffset continues the listing, zero on the last page processed so far
+	NextOffset int `json:"next_offset,omitempty"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CodeStanding aggregates the redemptions attributed to one code of a campaign
+type CodeStanding struct {
+	Rank         int             `json:"rank"`
+	Code         string          `json:"code"`
+	DiscountID   string          `json:"discount_id"`
+	Redemptions  int             `json:"redemptions"`
+	Revenue      decimal.Decimal `json:"revenue"`       // Total paid for those orders
+	DiscountCost decimal.Decimal `json:"discount_cost"` // Total given away through the code
+}
+
+// CodeLeaderboard ranks the codes and aliases of a campaign by the revenue they brought in,
+// so partners owning a code can be paid out on what it earned
+type CodeLeaderboard struct {
+	CampaignID string         `json:"campaign_id"`
+	From       time.Time      `json:"from"`
+	To         time.Time      `json:"to"`
+	Standings  []CodeStanding `json:"standings"`
+
+	// Unattributed counts the campaign's redemptions made without entering any code
+	Unattributed int `json:"unattributed"`
+}
@@ -0,0 +1,95 @@
+package models
+
+import "fmt"
+
+// LifecycleState is where a discount is in its publication workflow. Only ACTIVE discounts
+// are ever priced; discounts stored without a state predate the workflow and count as ACTIVE.
+type LifecycleState string
+
+const (
+	LifecycleDraft           LifecycleState = "DRAFT"            // Being written, not yet submitted
+	LifecyclePendingApproval LifecycleState = "PENDING_APPROVAL" // Submitted, awaiting an approver
+	LifecycleActive          LifecycleState = "ACTIVE"           // Published
+	LifecyclePaused          LifecycleState = "PAUSED"           // Published but held back
+	LifecycleExpired         LifecycleState = "EXPIRED"          // Retired for good
+)
+
+// LifecycleAction moves a discount from one lifecycle state to another
+type LifecycleAction string
+
+const (
+	ActionSubmit  LifecycleAction = "submit"  // DRAFT -> PENDING_APPROVAL
+	ActionApprove LifecycleAction = "approve" // PENDING_APPROVAL -> ACTIVE
+	ActionReject  LifecycleAction = "reject"  // PENDING_APPROVAL -> DRAFT
+	ActionPause   LifecycleAction = "pause"   // ACTIVE -> PAUSED
+	ActionResume  LifecycleAction = "resume"  // PAUSED -> ACTIVE
+	ActionExpire  LifecycleAction = "expire"  // ACTIVE or PAUSED -> EXPIRED
+)
+
+// lifecycleTransitions maps every action to the states it moves discounts out of and the
+// state it moves them to
+var lifecycleTransitions = map[LifecycleAction]struct {
+	from []LifecycleState
+	to   LifecycleState
+}{
+	ActionSubmit:  {[]LifecycleState{LifecycleDraft}, LifecyclePendingApproval},
+	ActionApprove: {[]LifecycleState{LifecyclePendingApproval}, LifecycleActive},
+	ActionReject:  {[]LifecycleState{LifecyclePendingApproval}, LifecycleDraft},
+	ActionPause:   {[]LifecycleState{LifecycleActive}, LifecyclePaused},
+	ActionResume:  {[]LifecycleState{LifecyclePaused}, LifecycleActive},
+	ActionExpire:  {[]LifecycleState{LifecycleActive, LifecyclePaused}, LifecycleExpired},
+}
+
+// Validate reports actions that are not part of the workflow
+func (a LifecycleAction) Validate() error {
+	if _, ok := lifecycleTransitions[a]; !ok {
+		return fmt.Errorf("unknown lifecycle action %q", a)
+	}
+	return nil
+}
+
+// RequiredRole returns the role an actor needs to take the action
+func (a LifecycleAction) RequiredRole() Role {
+	if a == ActionSubmit {
+		return RoleEditor
+	}
+	return RoleApprover
+}
+
+// LifecycleState returns the discount's state, resolving the ACTIVE default
+func (d *Discount) LifecycleState() LifecycleState {
+	if d.State == "" {
+		return LifecycleActive
+	}
+	return d.State
+}
+
+// IsPublished reports whether the discount is ACTIVE, and so may be priced
+func (d *Discount) IsPublished() bool {
+	return d.LifecycleState() == LifecycleActive
+}
+
+// NextLifecycleState returns the state the action moves the discount to, or an error when
+// the action cannot be taken from its current state
+func (d *Discount) NextLifecycleState(action LifecycleAction) (LifecycleState, error) {
+	if err := action.Validate(); err != nil {
+		return "", err
+	}
+	transition := lifecycleTransitions[action]
+	current := d.LifecycleState()
+	for _, from := range transition.from {
+		if from == current {
+			return transition.to, nil
+		}
+	}
+	return "", fmt.Errorf("cannot %s a discount in state %s", action, current)
+}
+
+// CheckLifecycle reports a state outside the workflow
+func (d *Discount) CheckLifecycle() error {
+	switch d.State {
+	case "", LifecycleDraft, LifecyclePendingApproval, LifecycleActive, LifecyclePaused, LifecycleExpired:
+		return nil
+	}
+	return fmt.Errorf("unknown lifecycle state %q", d.State)
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ListSortField orders a discount listing; every order breaks ties by ID
+type ListSortField string
+
+const (
+	SortByID        ListSortField = "id"         // Ascending, the default
+	SortByName      ListSortField = "name"       // Ascending
+	SortByPriority  ListSortField = "priority"   // Highest first, the order discounts are applied in
+	SortByValidFrom ListSortField = "valid_from" // Earliest first
+)
+
+// ListFilter narrows and pages a discount listing; zero values match everything
+type ListFilter struct {
+	Type       DiscountType
+	Active     *bool  // Only discounts switched on, or off, whatever their validity window
+	CodePrefix string // Only discounts with a code or alias starting with the prefix
+	SortBy     ListSortField
+
+	// Cursor resumes the listing after the last discount of a previous page, see
+	// DiscountPage.NextCursor. It is only valid with the filter the page was listed with.
+	Cursor string
+
+	PageSize int // Maximum discounts per page, 0 for every match
+}
+
+// DiscountPage is one page of a discount listing
+type DiscountPage struct {
+	Discounts []Discount `json:"discounts"`
+
+	// NextCursor continues the listing, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Validate reports filters that cannot be listed
+func (f ListFilter) Validate() error {
+	switch f.SortBy {
+	case "", SortByID, SortByName, SortByPriority, SortByValidFrom:
+	default:
+		return fmt.Errorf("unknown sort field %q", f.SortBy)
+	}
+	if f.PageSize < 0 {
+		return fmt.Errorf("page size cannot be negative")
+	}
+	if _, err := f.position(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Matches reports whether the discount satisfies the filter, regardless of the cursor
+func (f ListFilter) Matches(d *Discount) bool {
+	if f.Type != "" && d.Type != f.Type {
+		return false
+	}
+	if f.Active != nil && d.IsActive != *f.Active {
+		return false
+	}
+	if f.CodePrefix != "" && !hasCodePrefix(d, f.CodePrefix) {
+		return false
+	}
+	return true
+}
+
+// Page sorts the matching discounts in the filter's order and cuts the page the cursor
+// points at, so every backend pages the same way. Call Validate first: an invalid cursor
+// is treated as the start of the listing.
+func (f ListFilter) Page(discounts []Discount) *DiscountPage {
+	matching := make([]Discount, 0, len(discounts))
+	for i := range discounts {
+		if f.Matches(&discounts[i]) {
+			matching = append(matching, discounts[i])
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool { return f.less(&matching[i], &matching[j]) })
+
+	if after, _ := f.position(); after != nil {
+		start := sort.Search(len(matching), func(i int) bool { return f.less(after, &matching[i]) })
+		matching = matching[start:]
+	}
+
+	page := &DiscountPage{Discounts: matching}
+	if f.PageSize > 0 && len(matching) > f.PageSize {
+		page.Discounts = matching[:f.PageSize]
+		page.NextCursor = f.cursorAfter(&page.Discounts[f.PageSize-1])
+	}
+	return page
+}
+
+func (f ListFilter) sortBy() ListSortField {
+	if f.SortBy == "" {
+		return SortByID
+	}
+	return f.SortBy
+}
+
+// less orders discounts by the sort field, then by ID
+func (f ListFilter) less(a, b *Discount) bool {
+	switch f.sortBy() {
+	case SortByName:
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+	case SortByPriority:
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+	case SortByValidFrom:
+		if !a.ValidFrom.Equal(b.ValidFrom) {
+			return a.ValidFrom.Before(b.ValidFrom)
+		}
+	}
+	return a.ID < b.ID
+}
+
+// listCursor is the position of the last discount of a page. It holds the sort key rather
+// than just the ID, so the listing resumes in place even if that discount changed or was
+// deleted since.
+type listCursor struct {
+	SortBy    ListSortField `json:"s"`
+	ID        string        `json:"id"`
+	Name      string        `json:"n,omitempty"`
+	Priority  int           `json:"p,omitempty"`
+	ValidFrom time.Time     `json:"f,omitempty"`
+}
+
+func (f ListFilter) cursorAfter(d *Discount) string {
+	c := listCursor{SortBy: f.sortBy(), ID: d.ID}
+	switch c.SortBy {
+	case SortByName:
+		c.Name = d.Name
+	case SortByPriority:
+		c.Priority = d.Priority
+	case SortByValidFrom:
+		c.ValidFrom = d.ValidFrom
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// position decodes the cursor into a discount sorting where the previous page ended, nil
+// without a cursor
+func (f ListFilter) position() (*Discount, error) {
+	if f.Cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(f.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	if c.SortBy != f.sortBy() {
+		return nil, fmt.Errorf("cursor was issued for a listing sorted by %s", c.SortBy)
+	}
+	return &Discount{ID: c.ID, Name: c.Name, Priority: c.Priority, ValidFrom: c.ValidFrom}, nil
+}
+
+func hasCodePrefix(d *Discount, prefix string) bool {
+	for _, code := range d.Codes() {
+		if strings.HasPrefix(code, prefix) {
+			return true
+		}
+	}
+	return false
+}
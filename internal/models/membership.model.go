@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Membership is a customer's paid subscription to a membership plan, such as "club"
+type Membership struct {
+	Plan      string    `json:"plan"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // Zero for memberships that do not expire
+}
+
+// IsActiveAt reports whether the membership is in force at now
+func (m *Membership) IsActiveAt(now time.Time) bool {
+	return m != nil && m.Plan != "" && (m.ExpiresAt.IsZero() || now.Before(m.ExpiresAt))
+}
+
+// IsMembersOnly reports whether the discount is limited to members of MembershipPlans
+func (d *Discount) IsMembersOnly() bool {
+	return len(d.MembershipPlans) > 0
+}
+
+// AdmitsMemberAt reports whether the customer may use the discount at now: members-only
+// discounts need a membership of one of their plans that has not expired. Plans match
+// whatever their case.
+func (d *Discount) AdmitsMemberAt(customer CustomerProfile, now time.Time) bool {
+	if !d.IsMembersOnly() {
+		return true
+	}
+	if !customer.Membership.IsActiveAt(now) {
+		return false
+	}
+	for _, plan := range d.MembershipPlans {
+		if strings.EqualFold(plan, customer.Membership.Plan) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckMembershipPlans reports empty plan names
+func (d *Discount) CheckMembershipPlans() error {
+	for _, plan := range d.MembershipPlans {
+		if strings.TrimSpace(plan) == "" {
+			return fmt.Errorf("membership plans cannot be empty")
+		}
+	}
+	return nil
+}
+
+// MemberSavings is what members-only discounts took off a member's cart
+type MemberSavings struct {
+	Plan   string          `json:"plan"`
+	Amount decimal.Decimal `json:"amount"`
+}
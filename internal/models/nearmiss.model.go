@@ -0,0 +1,16 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// NearMissDiscount is a discount the cart only missed through its minimum amount or
+// quantity, with what is missing, so storefronts can nudge customers towards bigger baskets
+type NearMissDiscount struct {
+	DiscountID  string          `json:"discount_id"`
+	DiscountRef DiscountRef     `json:"discount_ref"`
+	Name        string          `json:"name"`
+	Type        DiscountType    `json:"type"`
+	Reason      DecisionReason  `json:"reason"`              // min_amount_not_met or min_quantity_not_met
+	AmountGap   decimal.Decimal `json:"amount_gap"`          // Missing from the promotable total, zero when it is met
+	UnitsGap    int             `json:"units_gap,omitempty"` // Eligible units missing from the cart
+	Message     string          `json:"message"`
+}
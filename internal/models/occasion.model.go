@@ -0,0 +1,95 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxOccasionWindowDays bounds Occasion.WindowDays, keeping a customer's windows apart
+const MaxOccasionWindowDays = 31
+
+// OccasionKind is the yearly date of a customer an occasion discount is tied to
+type OccasionKind string
+
+const (
+	OccasionBirthday    OccasionKind = "birthday"           // CustomerProfile.DateOfBirth
+	OccasionAnniversary OccasionKind = "signup_anniversary" // CustomerProfile.RegisteredAt, from its first anniversary on
+)
+
+// Occasion limits a discount to the days around a yearly date of the customer: it applies
+// from WindowDays before the date to WindowDays after it, only on the day itself when zero.
+// Days are calendar days in the location of the pricing time.
+type Occasion struct {
+	Kind       OccasionKind `json:"kind"`
+	WindowDays int          `json:"window_days,omitempty"`
+}
+
+// Validate reports unknown kinds and windows outside 0 to MaxOccasionWindowDays
+func (o *Occasion) Validate() error {
+	switch o.Kind {
+	case OccasionBirthday, OccasionAnniversary:
+	default:
+		return fmt.Errorf("unknown occasion %q", o.Kind)
+	}
+	if o.WindowDays < 0 || o.WindowDays > MaxOccasionWindowDays {
+		return fmt.Errorf("occasion window must be between 0 and %d days", MaxOccasionWindowDays)
+	}
+	return nil
+}
+
+// IsOccasionAt reports whether now falls in the discount's occasion window for the
+// customer. Discounts without an Occasion always qualify; customers whose profile lacks
+// the date never do.
+func (d *Discount) IsOccasionAt(customer CustomerProfile, now time.Time) bool {
+	if d.Occasion == nil {
+		return true
+	}
+	var date time.Time
+	switch d.Occasion.Kind {
+	case OccasionBirthday:
+		date = customer.DateOfBirth
+	case OccasionAnniversary:
+		date = customer.RegisteredAt
+	}
+	if date.IsZero() {
+		return false
+	}
+
+	today := civilDate(now.Year(), now.Month(), now.Day())
+	// The nearest occurrence may fall in the year before or after now's
+	for year := now.Year() - 1; year <= now.Year()+1; year++ {
+		if d.Occasion.Kind == OccasionAnniversary && year <= date.Year() {
+			continue
+		}
+		days := int(today.Sub(occurrenceIn(date, year)).Hours() / hoursPerDay)
+		if days >= -d.Occasion.WindowDays && days <= d.Occasion.WindowDays {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOccasion reports an invalid Occasion
+func (d *Discount) CheckOccasion() error {
+	if d.Occasion == nil {
+		return nil
+	}
+	return d.Occasion.Validate()
+}
+
+// occurrenceIn returns the date's month and day in the year, 29 February falling on the
+// 28th in common years
+func occurrenceIn(date time.Time, year int) time.Time {
+	if date.Month() == time.February && date.Day() == 29 && !isLeapYear(year) {
+		return civilDate(year, time.February, 28)
+	}
+	return civilDate(year, date.Month(), date.Day())
+}
+
+func civilDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
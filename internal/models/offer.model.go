@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Offer is a discount currently available to a customer, as shown in offer listings
+type Offer struct {
+	DiscountID   string          `json:"discount_id"`
+	Name         string          `json:"name"`
+	Type         DiscountType    `json:"type"`
+	Code         string          `json:"code,omitempty"`
+	Value        decimal.Decimal `json:"value"`
+	IsPercentage bool            `json:"is_percentage"`
+	MinAmount    decimal.Decimal `json:"min_amount"`
+	MaxAmount    decimal.Decimal `json:"max_amount"`
+	Priority     int             `json:"priority"`
+	ExpiresAt    time.Time       `json:"expires_at"`
+	Urgency      OfferUrgency    `json:"urgency"`
+
+	// OrdersLeft is how many more orders of an introductory program the customer is
+	// entitled to, nil for other discounts
+	OrdersLeft *int `json:"orders_left,omitempty"`
+}
+
+// SavingsOn estimates what the offer takes off a cart worth amount
+func (o *Offer) SavingsOn(amount decimal.Decimal) decimal.Decimal {
+	savings := o.Value
+	if o.IsPercentage {
+		savings = amount.Mul(o.Value).Div(decimal.NewFromInt(PercentageBase))
+		if !o.MaxAmount.IsZero() && savings.GreaterThan(o.MaxAmount) {
+			savings = o.MaxAmount
+		}
+	}
+	return decimal.Min(savings, amount)
+}
+
+// ClaimedBandStep is the granularity, in percent, at which redemption progress is reported
+const ClaimedBandStep = 10
+
+// OfferUrgency is machine-friendly scarcity data frontends can turn into "ends in 2h" or
+// "90% claimed" labels
+type OfferUrgency struct {
+	SecondsUntilExpiry int64 `json:"seconds_until_expiry"`
+
+	// ClaimedPercent is the share of UsageLimit already redeemed, rounded down to a multiple
+	// of ClaimedBandStep so exact counts are not exposed. Nil when usage is unlimited.
+	ClaimedPercent *int `json:"claimed_percent,omitempty"`
+}
+
+// UrgencyAt computes the discount's urgency data as seen at now
+func (d *Discount) UrgencyAt(now time.Time) OfferUrgency {
+	urgency := OfferUrgency{}
+	if remaining := d.ValidTo.Sub(now); remaining > 0 {
+		urgency.SecondsUntilExpiry = int64(remaining / time.Second)
+	}
+
+	if d.UsageLimit > 0 {
+		claimed := d.UsedCount * PercentageBase / d.UsageLimit
+		if claimed > PercentageBase {
+			claimed = PercentageBase
+		}
+		claimed -= claimed % ClaimedBandStep
+		urgency.ClaimedPercent = &claimed
+	}
+	return urgency
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Pattern wildcards accepted in ApplicableTo and ExcludedItems values: '*' matches any run
+// of characters and '?' a single character, so "ICICI*" covers every ICICI card variant.
+const patternWildcards = "*?"
+
+// valuePattern matches a single value either literally or as a compiled wildcard pattern
+type valuePattern struct {
+	literal string
+	re      *regexp.Regexp
+}
+
+func compileValuePattern(pattern string) valuePattern {
+	if !strings.ContainsAny(pattern, patternWildcards) {
+		return valuePattern{literal: pattern}
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	// Every metacharacter other than the wildcards is quoted, so this cannot fail
+	return valuePattern{literal: pattern, re: regexp.MustCompile(expr.String())}
+}
+
+func (p valuePattern) matches(value string) bool {
+	if p.re != nil {
+		return p.re.MatchString(value)
+	}
+	return p.literal == value
+}
+
+// itemRefMatcher is a compiled ApplicableTo or ExcludedItems entry
+type itemRefMatcher struct {
+	kind    ItemRefKind // Empty for untyped entries
+	pattern valuePattern
+}
+
+func compileItemRef(entry string) itemRefMatcher {
+	kind, value := parseItemRef(entry)
+	return itemRefMatcher{kind: kind, pattern: compileValuePattern(value)}
+}
+
+// matchesValue matches a plain value such as a bank name, ignoring the entry's kind
+func (m itemRefMatcher) matchesValue(value string) bool {
+	return m.pattern.matches(value)
+}
+
+// matchesProduct reports whether the entry refers to the product. Untyped entries are
+// compared against each of the untyped kinds in turn.
+func (m itemRefMatcher) matchesProduct(product Product, untyped ...ItemRefKind) bool {
+	if m.kind != "" {
+		attr := productAttribute(product, m.kind)
+		return attr != "" && m.pattern.matches(attr)
+	}
+
+	for _, k := range untyped {
+		if m.pattern.matches(productAttribute(product, k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// compiledLists caches the compiled form of a discount's ApplicableTo and ExcludedItems
+// together with the entries it was built from, so edits made after compiling are noticed
+type compiledLists struct {
+	applicableSrc []string
+	excludedSrc   []string
+	applicableTo  []itemRefMatcher
+	excluded      []itemRefMatcher
+}
+
+func compileLists(applicableTo, excluded []string) *compiledLists {
+	c := &compiledLists{
+		applicableSrc: append([]string(nil), applicableTo...),
+		excludedSrc:   append([]string(nil), excluded...),
+		applicableTo:  make([]itemRefMatcher, len(applicableTo)),
+		excluded:      make([]itemRefMatcher, len(excluded)),
+	}
+	for i, entry := range applicableTo {
+		c.applicableTo[i] = compileItemRef(entry)
+	}
+	for i, entry := range excluded {
+		c.excluded[i] = compileItemRef(entry)
+	}
+	return c
+}
+
+func (c *compiledLists) isFor(applicableTo, excluded []string) bool {
+	return equalStrings(c.applicableSrc, applicableTo) && equalStrings(c.excludedSrc, excluded)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
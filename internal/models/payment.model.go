@@ -0,0 +1,67 @@
+package models
+
+import "fmt"
+
+// defaultBankPaymentMethods are the methods bank discounts accept when they name none
+var defaultBankPaymentMethods = []PaymentMethod{Card}
+
+// UsesCard reports whether payments by the method are charged to a card, so card rules can
+// judge them
+func (m PaymentMethod) UsesCard() bool {
+	return m == Card || m == EMI
+}
+
+// AcceptedPaymentMethods returns the payment methods of a bank discount, see PaymentMethods
+func (d *Discount) AcceptedPaymentMethods() []PaymentMethod {
+	if len(d.PaymentMethods) == 0 {
+		return defaultBankPaymentMethods
+	}
+	return d.PaymentMethods
+}
+
+// AcceptsPaymentMethod reports whether a bank discount accepts payments by the method
+func (d *Discount) AcceptsPaymentMethod(m PaymentMethod) bool {
+	for _, accepted := range d.AcceptedPaymentMethods() {
+		if accepted == m {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesPayment reports whether the payment is made by a method and from a bank or wallet
+// provider the discount accepts. Card rules are checked separately, see MatchesCard.
+func (d *Discount) MatchesPayment(payment *PaymentInfo) bool {
+	if payment == nil {
+		return false
+	}
+
+	switch d.Type {
+	case DiscountTypeBank:
+		return d.AcceptsPaymentMethod(payment.Method) && d.matchesProvider(payment.BankName)
+	case DiscountTypeWallet:
+		return payment.Method == Wallet && d.matchesProvider(payment.WalletProvider)
+	default:
+		return true
+	}
+}
+
+func (d *Discount) matchesProvider(provider *string) bool {
+	return len(d.ApplicableTo) == 0 || (provider != nil && d.MatchesApplicableValue(*provider))
+}
+
+// CheckPaymentMethods reports payment methods a bank discount cannot be paid by: only banks
+// take card, EMI and net banking payments
+func (d *Discount) CheckPaymentMethods() error {
+	if len(d.PaymentMethods) > 0 && d.Type != DiscountTypeBank {
+		return fmt.Errorf("only bank discounts can be restricted to payment methods")
+	}
+	for _, m := range d.PaymentMethods {
+		switch m {
+		case Card, EMI, NetBanking:
+		default:
+			return fmt.Errorf("bank discounts cannot accept payment method %q", m)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// PriceAdjustment is a change a dynamic pricing engine made to a cart line's unit price
+// before any discount applied, e.g. a surge or a markdown. The engine sets Line, UnitPrice
+// and Reason; the service fills in the rest.
+type PriceAdjustment struct {
+	Line      int             `json:"line"` // Index of the cart line
+	UnitPrice decimal.Decimal `json:"unit_price"`
+	Reason    string          `json:"reason,omitempty"` // e.g. "surge" or "markdown"
+
+	ProductID     string          `json:"product_id"`
+	PreviousPrice decimal.Decimal `json:"previous_price"` // CurrentPrice before the adjustment
+	Amount        decimal.Decimal `json:"amount"`         // Change to the line total, negative for markdowns
+}
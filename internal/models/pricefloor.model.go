@@ -0,0 +1,44 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// PriceFloorPolicy keeps stacked discounts from selling products below cost: no unit is
+// discounted below its cost plus MinMarginPercent of it. Products' own MinSellingPrice
+// floors apply whether or not a policy is configured.
+type PriceFloorPolicy struct {
+	MinMarginPercent decimal.Decimal `json:"min_margin_percent"`
+}
+
+// Validate reports negative margins
+func (p PriceFloorPolicy) Validate() error {
+	if p.MinMarginPercent.IsNegative() {
+		return fmt.Errorf("minimum margin cannot be negative")
+	}
+	return nil
+}
+
+// UnitFloor returns the lowest price a unit of the product may be sold at: the higher of
+// its MinSellingPrice and, under a policy, its cost plus the policy's margin. Products
+// without a cost price have no cost floor.
+func (p *PriceFloorPolicy) UnitFloor(product Product) decimal.Decimal {
+	floor := product.MinSellingPrice
+	if p != nil && product.CostPrice.IsPositive() {
+		margin := product.CostPrice.Mul(p.MinMarginPercent).Div(decimal.NewFromInt(PercentageBase))
+		floor = decimal.Max(floor, product.CostPrice.Add(margin))
+	}
+	// Items already priced below their floor are not raised, only kept from dropping further
+	return decimal.Min(floor, product.CurrentPrice)
+}
+
+// PriceFloorClamp reports a discount cut down so the items it was taken off stayed at or
+// above their price floors
+type PriceFloorClamp struct {
+	DiscountID string          `json:"discount_id"`
+	Name       string          `json:"name"`
+	Requested  decimal.Decimal `json:"requested"` // Amount the discount came to
+	Applied    decimal.Decimal `json:"applied"`   // Amount the floors left room for
+}
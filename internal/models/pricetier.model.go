@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// CustomerGroup is the commercial terms a customer buys under. Each group can have its own
+// price list, resolved before any promotion applies.
+type CustomerGroup string
+
+const (
+	CustomerGroupRetail    CustomerGroup = "retail" // Default for customers without a group
+	CustomerGroupWholesale CustomerGroup = "wholesale"
+	CustomerGroupFranchise CustomerGroup = "franchise"
+)
+
+// PriceTier is the unit price a customer group pays for a product once the cart holds at
+// least MinQuantity units of it
+type PriceTier struct {
+	Group       CustomerGroup   `json:"group"`
+	ProductID   string          `json:"product_id"`
+	MinQuantity int             `json:"min_quantity"`
+	UnitPrice   decimal.Decimal `json:"unit_price"`
+}
+
+// PriceMatrix holds the price tiers of every customer group
+type PriceMatrix struct {
+	Tiers []PriceTier `json:"tiers"`
+}
+
+// Validate reports tiers that cannot be priced from
+func (m PriceMatrix) Validate() error {
+	seen := make(map[PriceTier]bool, len(m.Tiers))
+	for _, tier := range m.Tiers {
+		switch {
+		case tier.Group == "" || tier.ProductID == "":
+			return fmt.Errorf("price tier needs a group and a product")
+		case tier.MinQuantity < 0:
+			return fmt.Errorf("price tier for %s/%s has negative minimum quantity %d", tier.Group, tier.ProductID, tier.MinQuantity)
+		case tier.UnitPrice.IsNegative():
+			return fmt.Errorf("price tier for %s/%s has negative unit price %s", tier.Group, tier.ProductID, tier.UnitPrice)
+		}
+
+		key := PriceTier{Group: tier.Group, ProductID: tier.ProductID, MinQuantity: tier.MinQuantity}
+		if seen[key] {
+			return fmt.Errorf("price tier for %s/%s from %d units is repeated", tier.Group, tier.ProductID, tier.MinQuantity)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// UnitPrice returns the price the group pays per unit when buying quantity units of the
+// product, from the tier with the highest minimum quantity reached. It reports false
+// when the group has no tier for the product at that quantity.
+func (m PriceMatrix) UnitPrice(group CustomerGroup, productID string, quantity int) (decimal.Decimal, bool) {
+	var best *PriceTier
+	for i := range m.Tiers {
+		tier := &m.Tiers[i]
+		if tier.Group != group || tier.ProductID != productID || quantity < tier.MinQuantity {
+			continue
+		}
+		if best == nil || tier.MinQuantity > best.MinQuantity {
+			best = tier
+		}
+	}
+	if best == nil {
+		return decimal.Zero, false
+	}
+	return best.UnitPrice, true
+}
+
+// Apply returns a copy of the cart priced for the group. Tiers are reached on the total
+// quantity of a product across the cart's lines, and a tier price replaces an item's
+// CurrentPrice only when it is lower, so a group never pays more than the shelf price.
+func (m PriceMatrix) Apply(group CustomerGroup, cart []CartItem) []CartItem {
+	quantities := make(map[string]int, len(cart))
+	for _, item := range cart {
+		quantities[item.Product.ID] += item.Quantity
+	}
+
+	priced := make([]CartItem, len(cart))
+	for i, item := range cart {
+		if price, ok := m.UnitPrice(group, item.Product.ID, quantities[item.Product.ID]); ok &&
+			price.LessThan(item.Product.CurrentPrice) {
+			item.Product.CurrentPrice = price
+		}
+		priced[i] = item
+	}
+	return priced
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// OrderPricingRecord is everything an order was priced from and the price it got, kept so
+// the price can be recalculated and verified later, e.g. when the customer disputes it
+type OrderPricingRecord struct {
+	OrderID    string             `json:"order_id"`
+	CustomerID string             `json:"customer_id,omitempty"`
+	PricedAt   time.Time          `json:"priced_at"`
+	Request    CalculationRequest `json:"request"`
+	Result     DiscountedPrice    `json:"result"`
+
+	// Discounts are the definitions of the applied discounts as they were priced, including
+	// their versions, in application order
+	Discounts []Discount `json:"discounts"`
+
+	// PointsBalance and CouponCodes are what the engine looked up for the request: the
+	// customer's loyalty balance and the single-use code unlocking each discount by id
+	PointsBalance int64             `json:"points_balance,omitempty"`
+	CouponCodes   map[string]string `json:"coupon_codes,omitempty"`
+}
+
+// DiscountVersions maps the id of every applied discount to the version it was priced at
+func (r *OrderPricingRecord) DiscountVersions() map[string]int {
+	versions := make(map[string]int, len(r.Discounts))
+	for _, d := range r.Discounts {
+		versions[d.ID] = d.Version
+	}
+	return versions
+}
+
+// PricingVerification compares an order's recorded price with the price recalculated from
+// its record
+type PricingVerification struct {
+	OrderID      string           `json:"order_id"`
+	Matches      bool             `json:"matches"`
+	Recorded     *DiscountedPrice `json:"recorded"`
+	Recalculated *DiscountedPrice `json:"recalculated"`
+	Differences  []string         `json:"differences,omitempty"` // One line per amount that differs
+}
+
+// PricingDifferences lists how the recalculated price differs from the recorded one: the
+// final price and the amount each discount of the breakdown took off
+func PricingDifferences(recorded, recalculated *DiscountedPrice) []string {
+	var differences []string
+	if !recorded.FinalPrice.Equal(recalculated.FinalPrice) {
+		differences = append(differences, fmt.Sprintf("final price: recorded %s, recalculated %s",
+			recorded.FinalPrice, recalculated.FinalPrice))
+	}
+
+	was, now := recorded.amountsByDiscount(), recalculated.amountsByDiscount()
+	ids := make([]string, 0, len(was)+len(now))
+	for id := range was {
+		ids = append(ids, id)
+	}
+	for id := range now {
+		if _, ok := was[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		before, wasApplied := was[id]
+		after, isApplied := now[id]
+		switch {
+		case !isApplied:
+			differences = append(differences, fmt.Sprintf("discount %s: recorded %s, no longer applied", id, before))
+		case !wasApplied:
+			differences = append(differences, fmt.Sprintf("discount %s: not recorded, recalculated %s", id, after))
+		case !before.Equal(after):
+			differences = append(differences, fmt.Sprintf("discount %s: recorded %s, recalculated %s", id, before, after))
+		}
+	}
+	return differences
+}
+
+// amountsByDiscount sums the breakdown by discount id
+func (p *DiscountedPrice) amountsByDiscount() map[string]decimal.Decimal {
+	amounts := make(map[string]decimal.Decimal, len(p.Breakdown))
+	for _, line := range p.Breakdown {
+		amounts[line.DiscountID] = amounts[line.DiscountID].Add(line.Amount)
+	}
+	return amounts
+}
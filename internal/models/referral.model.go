@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ReferralRewardStatus tracks a referrer's reward from the referred order to payout
+type ReferralRewardStatus string
+
+const (
+	ReferralRewardPending   ReferralRewardStatus = "pending"   // Recorded at checkout, not yet paid out
+	ReferralRewardPaid      ReferralRewardStatus = "paid"      // Paid out to the referrer
+	ReferralRewardCancelled ReferralRewardStatus = "cancelled" // Voided, e.g. because the order was returned
+)
+
+// ReferralReward is what a referrer is owed for one use of their referral code
+type ReferralReward struct {
+	DiscountID string               `json:"discount_id"`
+	ReferrerID string               `json:"referrer_id"`
+	RefereeID  string               `json:"referee_id"` // Customer who used the code
+	Amount     decimal.Decimal      `json:"amount"`
+	Status     ReferralRewardStatus `json:"status"`
+	CreatedAt  time.Time            `json:"created_at"`
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Location is where an order ships to, which region-restricted discounts are checked against
+type Location struct {
+	Region  string `json:"region,omitempty"` // Sales region, e.g. "south"
+	State   string `json:"state,omitempty"`  // State or province, e.g. "KA"
+	Pincode string `json:"pincode,omitempty"`
+}
+
+// RegionKind names the location attribute a region entry refers to. Entries are written as
+// "<kind>:<value>", e.g. "pincode:560*"; entries without a kind prefix name a state.
+// Values may use the wildcards of ApplicableTo.
+type RegionKind string
+
+const (
+	RegionKindRegion  RegionKind = "region"
+	RegionKindState   RegionKind = "state"
+	RegionKindPincode RegionKind = "pincode"
+)
+
+// RegionRef builds a typed region entry
+func RegionRef(kind RegionKind, value string) string {
+	return string(kind) + itemRefSeparator + value
+}
+
+// parseRegionRef splits a region entry into its kind and value
+func parseRegionRef(entry string) (RegionKind, string, error) {
+	prefix, value, found := strings.Cut(entry, itemRefSeparator)
+	if !found {
+		return RegionKindState, entry, nil
+	}
+	switch kind := RegionKind(prefix); kind {
+	case RegionKindRegion, RegionKindState, RegionKindPincode:
+		return kind, value, nil
+	default:
+		return "", "", fmt.Errorf("unknown region kind %q", prefix)
+	}
+}
+
+// attribute returns the location's value for the given kind
+func (l *Location) attribute(kind RegionKind) string {
+	switch kind {
+	case RegionKindRegion:
+		return l.Region
+	case RegionKindState:
+		return l.State
+	case RegionKindPincode:
+		return l.Pincode
+	default:
+		return ""
+	}
+}
+
+// InRegion reports whether the location matches any of the region entries
+func (l *Location) InRegion(entries []string) bool {
+	if l == nil {
+		return false
+	}
+	for _, entry := range entries {
+		kind, value, err := parseRegionRef(entry)
+		if err != nil {
+			continue
+		}
+		if attribute := l.attribute(kind); attribute != "" && compileValuePattern(value).matches(attribute) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAvailableIn reports whether the discount may be used for an order shipping to the
+// location: within one of AllowedRegions, unless it is empty, and outside every one of
+// ExcludedRegions. Orders of unknown location never get discounts limited to AllowedRegions.
+func (d *Discount) IsAvailableIn(location *Location) bool {
+	if len(d.AllowedRegions) > 0 && !location.InRegion(d.AllowedRegions) {
+		return false
+	}
+	return !location.InRegion(d.ExcludedRegions)
+}
+
+// CheckRegions reports region entries that are empty or of an unknown kind
+func (d *Discount) CheckRegions() error {
+	for _, entry := range append(append([]string(nil), d.AllowedRegions...), d.ExcludedRegions...) {
+		_, value, err := parseRegionRef(entry)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			return fmt.Errorf("region entries cannot be empty")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// DiscountRevision is a discount's definition as written by a create or update, in effect
+// from EffectiveFrom until the next revision. Usage counts are runtime state, not part of
+// the definition, and are as they were when the revision was written.
+type DiscountRevision struct {
+	Version       int       `json:"version"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	Discount      Discount  `json:"discount"`
+}
+
+// RevisionAt returns the revision in effect at the instant, false when at is before the
+// first one. Revisions are ordered by version.
+func RevisionAt(revisions []DiscountRevision, at time.Time) (*DiscountRevision, bool) {
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if !revisions[i].EffectiveFrom.After(at) {
+			return &revisions[i], true
+		}
+	}
+	return nil, false
+}
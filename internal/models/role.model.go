@@ -0,0 +1,37 @@
+package models
+
+import "fmt"
+
+// Role is what an actor is allowed to do with discounts
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"   // Reads the catalog, audit trail and reports
+	RoleEditor   Role = "editor"   // Writes discounts and submits them for approval
+	RoleApprover Role = "approver" // Approves, rejects, pauses, resumes and expires discounts
+	RoleSync     Role = "sync"     // Pulls discount changes and reports usage for edge stores
+	RoleAdmin    Role = "admin"    // Everything
+)
+
+// Validate reports roles that are not part of the model
+func (r Role) Validate() error {
+	switch r {
+	case RoleViewer, RoleEditor, RoleApprover, RoleSync, RoleAdmin:
+		return nil
+	}
+	return fmt.Errorf("unknown role %q", r)
+}
+
+// HasRole reports whether the roles grant the required one. Admins hold every role, and
+// editors and approvers may also view.
+func HasRole(roles []Role, required Role) bool {
+	for _, role := range roles {
+		switch {
+		case role == required, role == RoleAdmin:
+			return true
+		case required == RoleViewer && (role == RoleEditor || role == RoleApprover):
+			return true
+		}
+	}
+	return false
+}
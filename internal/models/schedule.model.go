@@ -0,0 +1,319 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	cronFieldCount = 5
+	minutesPerHour = 60
+	hoursPerDay    = 24
+	daysPerWeek    = 7
+	maxDayOfMonth  = 31
+	monthsPerYear  = 12
+)
+
+// Schedule restricts a discount to recurring windows inside its ValidFrom/ValidTo range,
+// e.g. weekend-only offers or a daily happy hour. The schedule is active whenever any
+// of its rules matches.
+type Schedule struct {
+	Timezone string         `json:"timezone,omitempty"` // IANA name; times are evaluated in UTC when empty
+	Rules    []ScheduleRule `json:"rules"`
+
+	compiled *compiledSchedule // Parsed Timezone and rules, see Discount.Compile
+}
+
+// ScheduleRule matches when every condition it sets matches; unset conditions are ignored
+type ScheduleRule struct {
+	Days       []time.Weekday   `json:"days,omitempty"`        // Days of week the rule applies on
+	TimeRanges []TimeOfDayRange `json:"time_ranges,omitempty"` // Any range must contain the time of day
+	Cron       string           `json:"cron,omitempty"`        // "minute hour day-of-month month day-of-week"
+}
+
+// TimeOfDayRange is a half-open [Start, End) wall-clock range in "HH:MM" form.
+// An End before Start wraps past midnight, e.g. 22:00-02:00.
+type TimeOfDayRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Validate checks the schedule's timezone, time ranges and cron expressions
+func (s *Schedule) Validate() error {
+	return s.parsed().err
+}
+
+// IsActiveAt reports whether the schedule allows the discount at the given instant.
+// An invalid schedule is never active.
+func (s *Schedule) IsActiveAt(t time.Time) bool {
+	c := s.parsed()
+	if c.err != nil {
+		return false
+	}
+	local := t.In(c.location)
+
+	for i, rule := range s.Rules {
+		if c.rules[i].matches(rule, local) {
+			return true
+		}
+	}
+	return false
+}
+
+// compiledSchedule is a schedule's timezone and rules parsed once, so checking validity
+// while pricing loads no location and parses no expression
+type compiledSchedule struct {
+	timezone string
+	rules    []compiledRule
+	location *time.Location
+	err      error // Why the schedule is invalid
+}
+
+type compiledRule struct {
+	cronSrc   string
+	rangesSrc []TimeOfDayRange
+	cron      *cronExpr
+	ranges    [][2]int // Minutes since midnight
+}
+
+// compile parses the schedule, recording in err why it is invalid
+func (s *Schedule) compile() *compiledSchedule {
+	c := &compiledSchedule{timezone: s.Timezone, rules: make([]compiledRule, len(s.Rules)), location: time.UTC}
+	for i, rule := range s.Rules {
+		c.rules[i] = compiledRule{cronSrc: rule.Cron, rangesSrc: append([]TimeOfDayRange(nil), rule.TimeRanges...)}
+	}
+
+	if s.Timezone != "" {
+		loc, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			c.err = fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+			return c
+		}
+		c.location = loc
+	}
+	if len(s.Rules) == 0 {
+		c.err = fmt.Errorf("schedule has no rules")
+		return c
+	}
+	for i, rule := range s.Rules {
+		for _, r := range rule.TimeRanges {
+			start, end, err := r.bounds()
+			if err != nil {
+				c.err = fmt.Errorf("rule %d: %w", i, err)
+				return c
+			}
+			c.rules[i].ranges = append(c.rules[i].ranges, [2]int{start, end})
+		}
+		if rule.Cron != "" {
+			expr, err := parseCron(rule.Cron)
+			if err != nil {
+				c.err = fmt.Errorf("rule %d: %w", i, err)
+				return c
+			}
+			c.rules[i].cron = expr
+		}
+	}
+	return c
+}
+
+// parsed returns the compiled schedule, compiling it again when it was edited since
+func (s *Schedule) parsed() *compiledSchedule {
+	if s.compiled != nil && s.compiled.isFor(s) {
+		return s.compiled
+	}
+	return s.compile()
+}
+
+func (c *compiledSchedule) isFor(s *Schedule) bool {
+	if c.timezone != s.Timezone || len(c.rules) != len(s.Rules) {
+		return false
+	}
+	for i, rule := range s.Rules {
+		if c.rules[i].cronSrc != rule.Cron || !equalRanges(c.rules[i].rangesSrc, rule.TimeRanges) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalRanges(a, b []TimeOfDayRange) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether the rule, compiled as c, matches t
+func (c compiledRule) matches(r ScheduleRule, t time.Time) bool {
+	if len(r.Days) > 0 && !containsWeekday(r.Days, t.Weekday()) {
+		return false
+	}
+
+	if len(c.ranges) > 0 {
+		inRange := false
+		for _, bounds := range c.ranges {
+			if minuteInRange(t, bounds[0], bounds[1]) {
+				inRange = true
+				break
+			}
+		}
+		if !inRange {
+			return false
+		}
+	}
+
+	if c.cron != nil && !c.cron.matches(t) {
+		return false
+	}
+
+	return true
+}
+
+// minuteInRange reports whether the time of day of t is in [start, end), wrapping past
+// midnight when end is before start
+func minuteInRange(t time.Time, start, end int) bool {
+	minute := t.Hour()*minutesPerHour + t.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// bounds returns the range as minutes since midnight
+func (r TimeOfDayRange) bounds() (int, int, error) {
+	start, err := parseClock(r.Start)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClock(r.End)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("empty time range %s-%s", r.Start, r.End)
+	}
+	return start, end, nil
+}
+
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return t.Hour()*minutesPerHour + t.Minute(), nil
+}
+
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// cronExpr is a parsed five-field cron expression matched at minute granularity
+type cronExpr struct {
+	minutes, hours, daysOfMonth, months, daysOfWeek map[int]bool
+	domRestricted, dowRestricted                    bool
+}
+
+func (c *cronExpr) matches(t time.Time) bool {
+	if !c.minutes[t.Minute()] || !c.hours[t.Hour()] || !c.months[int(t.Month())] {
+		return false
+	}
+
+	// As in classic cron, when both day fields are restricted either one may match
+	dom, dow := c.daysOfMonth[t.Day()], c.daysOfWeek[int(t.Weekday())]
+	if c.domRestricted && c.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func parseCron(expr string) (*cronExpr, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != cronFieldCount {
+		return nil, fmt.Errorf("invalid cron expression %q: expected %d fields", expr, cronFieldCount)
+	}
+
+	c := &cronExpr{}
+	var err error
+	if c.minutes, err = parseCronField(fields[0], 0, minutesPerHour-1); err != nil {
+		return nil, fmt.Errorf("invalid cron minute field: %w", err)
+	}
+	if c.hours, err = parseCronField(fields[1], 0, hoursPerDay-1); err != nil {
+		return nil, fmt.Errorf("invalid cron hour field: %w", err)
+	}
+	if c.daysOfMonth, err = parseCronField(fields[2], 1, maxDayOfMonth); err != nil {
+		return nil, fmt.Errorf("invalid cron day-of-month field: %w", err)
+	}
+	if c.months, err = parseCronField(fields[3], 1, monthsPerYear); err != nil {
+		return nil, fmt.Errorf("invalid cron month field: %w", err)
+	}
+	// 7 is accepted as an alias for Sunday
+	if c.daysOfWeek, err = parseCronField(fields[4], 0, daysPerWeek); err != nil {
+		return nil, fmt.Errorf("invalid cron day-of-week field: %w", err)
+	}
+	if c.daysOfWeek[daysPerWeek] {
+		c.daysOfWeek[int(time.Sunday)] = true
+	}
+
+	c.domRestricted = fields[2] != "*"
+	c.dowRestricted = fields[4] != "*"
+	return c, nil
+}
+
+// parseCronField expands lists, ranges, steps and wildcards into the set of allowed values
+func parseCronField(field string, lo, hi int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			var err error
+			rangePart = part[:idx]
+			step, err = strconv.Atoi(part[idx+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		start, end := lo, hi
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			v, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			start, end = v, v
+			if strings.Contains(part, "/") {
+				end = hi
+			}
+		}
+
+		if start < lo || end > hi || start > end {
+			return nil, fmt.Errorf("%q out of range %d-%d", part, lo, hi)
+		}
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,56 @@
+package models
+
+import "fmt"
+
+// MatchesSegments reports whether a customer in the segments passes the discount's segment
+// conditions: the customer must be in one of Segments, unless it is empty, and in none of
+// ExcludedSegments
+func (d *Discount) MatchesSegments(segments []string) bool {
+	if len(d.Segments) > 0 && !containsAny(segments, d.Segments) {
+		return false
+	}
+	return !containsAny(segments, d.ExcludedSegments)
+}
+
+// CheckSegments reports segments that are both required and excluded, which no customer
+// could pass
+func (d *Discount) CheckSegments() error {
+	for _, segment := range d.Segments {
+		if segment == "" {
+			return fmt.Errorf("segments cannot be empty")
+		}
+		if containsAny(d.ExcludedSegments, []string{segment}) {
+			return fmt.Errorf("segment %s is both required and excluded", segment)
+		}
+	}
+	for _, segment := range d.ExcludedSegments {
+		if segment == "" {
+			return fmt.Errorf("excluded segments cannot be empty")
+		}
+	}
+	return nil
+}
+
+// MergeSegments returns the segments of both lists, in order and without duplicates
+func MergeSegments(segments, more []string) []string {
+	merged := make([]string, 0, len(segments)+len(more))
+	seen := make(map[string]bool, cap(merged))
+	for _, segment := range append(append([]string(nil), segments...), more...) {
+		if !seen[segment] {
+			seen[segment] = true
+			merged = append(merged, segment)
+		}
+	}
+	return merged
+}
+
+func containsAny(items, wanted []string) bool {
+	for _, item := range items {
+		for _, w := range wanted {
+			if item == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// SpendTier is a step of a spend tier discount: carts whose subtotal reaches Threshold get
+// Value off, a fixed amount or a percentage of the subtotal as the discount's IsPercentage says
+type SpendTier struct {
+	Threshold decimal.Decimal `json:"threshold"`
+	Value     decimal.Decimal `json:"value"`
+}
+
+// SpendHint tells the customer how much more to spend to reach the next tier of a spend
+// tier discount
+type SpendHint struct {
+	DiscountID   string          `json:"discount_id"`
+	DiscountRef  DiscountRef     `json:"discount_ref"`
+	Name         string          `json:"name"`
+	SpendMore    decimal.Decimal `json:"spend_more"`    // Missing from the subtotal to reach the tier
+	Threshold    decimal.Decimal `json:"threshold"`     // Subtotal the tier starts at
+	Value        decimal.Decimal `json:"value"`         // What the tier takes off
+	IsPercentage bool            `json:"is_percentage"` // Whether Value is a percentage
+	Message      string          `json:"message"`
+}
+
+// CheckSpendTiers reports tiers a spend tier discount cannot be priced from. Tiers must
+// ascend by threshold and never give less than a lower tier, so spending more always saves more.
+func (d *Discount) CheckSpendTiers() error {
+	if d.Type != DiscountTypeSpendTier {
+		if len(d.SpendTiers) > 0 {
+			return fmt.Errorf("only spend tier discounts can have spend tiers")
+		}
+		return nil
+	}
+
+	if len(d.SpendTiers) == 0 {
+		return fmt.Errorf("spend tier discount requires at least one tier")
+	}
+	for i, tier := range d.SpendTiers {
+		switch {
+		case !tier.Threshold.IsPositive():
+			return fmt.Errorf("spend tier %d needs a positive threshold", i)
+		case !tier.Value.IsPositive():
+			return fmt.Errorf("spend tier %d needs a positive value", i)
+		case d.IsPercentage && tier.Value.GreaterThan(decimal.NewFromInt(PercentageBase)):
+			return fmt.Errorf("spend tier %d takes off more than 100%%", i)
+		case i > 0 && !tier.Threshold.GreaterThan(d.SpendTiers[i-1].Threshold):
+			return fmt.Errorf("spend tier %d does not start above the tier before it", i)
+		case i > 0 && tier.Value.LessThan(d.SpendTiers[i-1].Value):
+			return fmt.Errorf("spend tier %d gives less than the tier before it", i)
+		}
+	}
+	return nil
+}
+
+// ReachedSpendTier returns the highest tier the subtotal reaches, reporting false when it
+// is below every tier
+func (d *Discount) ReachedSpendTier(subtotal decimal.Decimal) (SpendTier, bool) {
+	var reached SpendTier
+	found := false
+	for _, tier := range d.SpendTiers {
+		if subtotal.GreaterThanOrEqual(tier.Threshold) && (!found || tier.Threshold.GreaterThan(reached.Threshold)) {
+			reached, found = tier, true
+		}
+	}
+	return reached, found
+}
+
+// NextSpendTier returns the lowest tier above the subtotal, reporting false once the
+// highest tier is reached
+func (d *Discount) NextSpendTier(subtotal decimal.Decimal) (SpendTier, bool) {
+	var next SpendTier
+	found := false
+	for _, tier := range d.SpendTiers {
+		if subtotal.LessThan(tier.Threshold) && (!found || tier.Threshold.LessThan(next.Threshold)) {
+			next, found = tier, true
+		}
+	}
+	return next, found
+}
+
+// SpendHintAt returns the hint towards the tier above the subtotal, reporting false once
+// the highest tier is reached
+func (d *Discount) SpendHintAt(subtotal decimal.Decimal) (SpendHint, bool) {
+	next, ok := d.NextSpendTier(subtotal)
+	if !ok {
+		return SpendHint{}, false
+	}
+
+	more := next.Threshold.Sub(subtotal)
+	saving := next.Value.String()
+	if d.IsPercentage {
+		saving += "%"
+	}
+	return SpendHint{
+		DiscountID:   d.ID,
+		DiscountRef:  d.StableRef(),
+		Name:         d.Name,
+		SpendMore:    more,
+		Threshold:    next.Threshold,
+		Value:        next.Value,
+		IsPercentage: d.IsPercentage,
+		Message:      fmt.Sprintf("Spend %s more to save %s", more.String(), saving),
+	}, true
+}
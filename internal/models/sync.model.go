@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// DiscountChange is the latest change to a discount in a change feed: its definition as
+// stored, or a tombstone when it was deleted
+type DiscountChange struct {
+	Cursor     int64     `json:"cursor"` // Position in the feed, increasing with every change
+	DiscountID string    `json:"discount_id"`
+	Discount   *Discount `json:"discount,omitempty"` // Nil for tombstones
+	Deleted    bool      `json:"deleted,omitempty"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// ChangePage is a page of changes after a cursor, oldest first. Passing NextCursor back
+// resumes after the last change of the page; More says whether changes remain.
+type ChangePage struct {
+	Changes    []DiscountChange `json:"changes"`
+	NextCursor int64            `json:"next_cursor"`
+	More       bool             `json:"more,omitempty"`
+}
+
+// UsageReport carries the uses an edge store counted since it last reported, by discount id.
+// Sequence increases with every new report of the edge, which sends a report again until
+// it is acknowledged; the feed drops reports at or below the last sequence it applied.
+type UsageReport struct {
+	EdgeID   string         `json:"edge_id,omitempty"`
+	Sequence int64          `json:"sequence,omitempty"`
+	Uses     map[string]int `json:"uses"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// TargetSelection picks which of the units a brand, category or voucher discount targets it
+// is taken off, e.g. only the cheapest one for "cheapest item free when you buy 3"
+type TargetSelection string
+
+const (
+	TargetAll               TargetSelection = "ALL"                 // Every targeted unit, the default
+	TargetCheapestItem      TargetSelection = "CHEAPEST_ITEM"       // The cheapest unit
+	TargetMostExpensiveItem TargetSelection = "MOST_EXPENSIVE_ITEM" // The most expensive unit
+	TargetCheapestN         TargetSelection = "CHEAPEST_N"          // The TargetCount cheapest units
+)
+
+// CheckTargetSelection reports a TargetSelection, TargetCount or MinQuantity the discount
+// cannot honour
+func (d *Discount) CheckTargetSelection() error {
+	switch d.TargetSelection {
+	case "", TargetAll, TargetCheapestItem, TargetMostExpensiveItem, TargetCheapestN:
+	default:
+		return fmt.Errorf("unknown target selection %q", d.TargetSelection)
+	}
+
+	switch {
+	case d.TargetSelection == TargetCheapestN && d.TargetCount <= 0:
+		return fmt.Errorf("CHEAPEST_N target selection requires a positive target count")
+	case d.TargetSelection != TargetCheapestN && d.TargetCount != 0:
+		return fmt.Errorf("only CHEAPEST_N target selection takes a target count")
+	case d.MinQuantity < 0:
+		return fmt.Errorf("min quantity cannot be negative")
+	}
+
+	if d.TargetSelection != "" || d.MinQuantity != 0 {
+		switch d.Type {
+		case DiscountTypeBrand, DiscountTypeCategory, DiscountTypeVoucher:
+		default:
+			return fmt.Errorf("only brand, category and voucher discounts can select target units")
+		}
+	}
+	return nil
+}
+
+// CountUnits returns the number of units on the lines
+func CountUnits(lines []CartItem) int {
+	units := 0
+	for _, line := range lines {
+		units += line.Quantity
+	}
+	return units
+}
+
+// SelectedAmount returns the price of the units of the targeted lines the discount is taken
+// off: those its TargetSelection picks, at most MaxUnitsPerCart of them when it limits its
+// units. Equally priced units are picked by product ID, then SKU, then cart order, so the
+// same cart always discounts the same units.
+func (d *Discount) SelectedAmount(lines []CartItem) decimal.Decimal {
+	limit := 0 // Every unit
+	switch d.TargetSelection {
+	case TargetCheapestItem, TargetMostExpensiveItem:
+		limit = 1
+	case TargetCheapestN:
+		limit = d.TargetCount
+	}
+	if d.MaxUnitsPerCart > 0 && (limit == 0 || d.MaxUnitsPerCart < limit) {
+		limit = d.MaxUnitsPerCart
+	}
+
+	ordered := lines
+	if d.TargetSelection != "" && d.TargetSelection != TargetAll {
+		ordered = append([]CartItem(nil), lines...)
+		descending := d.TargetSelection == TargetMostExpensiveItem
+		sort.SliceStable(ordered, func(i, j int) bool {
+			a, b := ordered[i].Product, ordered[j].Product
+			if !a.CurrentPrice.Equal(b.CurrentPrice) {
+				return a.CurrentPrice.LessThan(b.CurrentPrice) != descending
+			}
+			if a.ID != b.ID {
+				return a.ID < b.ID
+			}
+			return a.SKU < b.SKU
+		})
+	}
+
+	amount := decimal.Zero
+	remaining := limit
+	for _, line := range ordered {
+		units := line.Quantity
+		if limit > 0 {
+			units = min(units, remaining)
+			remaining -= units
+		}
+		amount = amount.Add(line.Product.CurrentPrice.Mul(decimal.NewFromInt(int64(units))))
+		if limit > 0 && remaining == 0 {
+			break
+		}
+	}
+	return amount
+}
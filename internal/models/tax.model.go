@@ -0,0 +1,20 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// TaxLine is the tax charged at one rate on the cart
+type TaxLine struct {
+	Rate          decimal.Decimal `json:"rate"`           // Percentage, e.g. 18 for 18% GST
+	TaxableAmount decimal.Decimal `json:"taxable_amount"` // Price of the lines at the rate less the discounts lowering it
+	Tax           decimal.Decimal `json:"tax"`
+}
+
+// HasTax reports whether any item of the cart is taxed
+func HasTax(items []CartItem) bool {
+	for _, item := range items {
+		if item.Product.TaxRate.IsPositive() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+// Package promotion moves discount definitions between environments. A campaign is
+// exported from staging as a Bundle, diffed against production into a Plan that is
+// reviewed, and the reviewed Plan is applied, so promotions are tested once and promoted
+// rather than re-keyed by hand.
+package promotion
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// Bundle is the definition of one campaign's discounts as exported from an environment
+type Bundle struct {
+	CampaignID string            `json:"campaign_id"`
+	Source     string            `json:"source,omitempty"` // Environment the bundle was exported from
+	ExportedAt time.Time         `json:"exported_at"`
+	Discounts  []models.Discount `json:"discounts"`
+}
+
+// Export collects every discount of the campaign from repo, sorted by ID. Usage counts
+// are runtime state of the source environment and are cleared.
+func Export(ctx context.Context, repo interfaces.IDiscountRepository, campaignID, source string,
+	now time.Time) (*Bundle, error) {
+
+	discounts, err := campaignDiscounts(ctx, repo, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if len(discounts) == 0 {
+		return nil, errors.NewNotFoundError("no discounts belong to campaign " + campaignID)
+	}
+	for i := range discounts {
+		discounts[i].UsedCount = 0
+		discounts[i].TenantID = ""
+		discounts[i].Version = 0
+	}
+	return &Bundle{CampaignID: campaignID, Source: source, ExportedAt: now, Discounts: discounts}, nil
+}
+
+// Validate checks the bundle is internally consistent before it is diffed
+func (b *Bundle) Validate() error {
+	if b.CampaignID == "" {
+		return errors.NewValidationError("bundle has no campaign")
+	}
+	seen := make(map[string]bool, len(b.Discounts))
+	for _, d := range b.Discounts {
+		switch {
+		case d.ID == "":
+			return errors.NewValidationError("bundle has a discount without an ID")
+		case seen[d.ID]:
+			return errors.NewValidationError("bundle has discount " + d.ID + " more than once")
+		case d.CampaignID != b.CampaignID:
+			return errors.NewValidationError(fmt.Sprintf("discount %s belongs to campaign %q, not %q",
+				d.ID, d.CampaignID, b.CampaignID))
+		}
+		seen[d.ID] = true
+	}
+	return nil
+}
+
+// campaignDiscounts lists the campaign's discounts in repo, sorted by ID
+func campaignDiscounts(ctx context.Context, repo interfaces.IDiscountRepository, campaignID string) ([]models.Discount, error) {
+	lister, ok := repo.(interfaces.DiscountLister)
+	if !ok {
+		return nil, fmt.Errorf("repository cannot list discounts")
+	}
+	all, err := lister.ListDiscounts(ctx, models.ListFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var discounts []models.Discount
+	for _, d := range all.Discounts {
+		if d.CampaignID == campaignID {
+			discounts = append(discounts, d)
+		}
+	}
+	sort.Slice(discounts, func(i, j int) bool { return discounts[i].ID < discounts[j].ID })
+	return discounts, nil
+}
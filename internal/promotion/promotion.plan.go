@@ -0,0 +1,232 @@
+package promotion
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// Action is what applying a plan does to one discount
+type Action string
+
+const (
+	ActionCreate     Action = "create"     // New in the bundle
+	ActionUpdate     Action = "update"     // Definition differs from the target
+	ActionDeactivate Action = "deactivate" // In the target's campaign but no longer in the bundle
+	ActionUnchanged  Action = "unchanged"
+)
+
+// runtimeFields are the target's own state, never compared or overwritten by a promotion
+var runtimeFields = map[string]bool{"used_count": true, "tenant_id": true, "version": true}
+
+// FieldChange is one definition field that differs, with both JSON values
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from,omitempty"`
+	To    json.RawMessage `json:"to,omitempty"`
+}
+
+// Change is the action planned for one discount
+type Change struct {
+	DiscountID string           `json:"discount_id"`
+	Action     Action           `json:"action"`
+	Fields     []FieldChange    `json:"fields,omitempty"`
+	Discount   *models.Discount `json:"discount,omitempty"` // Definition written by create and update
+}
+
+// Plan is the review artifact of a promotion: every change applying the bundle would make
+// to the target, and a digest of the target state it was computed against
+type Plan struct {
+	Bundle       Bundle   `json:"bundle"`
+	Changes      []Change `json:"changes"`
+	TargetDigest string   `json:"target_digest"`
+}
+
+// HasChanges reports whether applying the plan would modify the target
+func (p *Plan) HasChanges() bool {
+	for _, c := range p.Changes {
+		if c.Action != ActionUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff compares the bundle with the target's definitions, sorted by discount ID
+func Diff(ctx context.Context, target interfaces.IDiscountRepository, bundle *Bundle) (*Plan, error) {
+	if err := bundle.Validate(); err != nil {
+		return nil, err
+	}
+
+	current, err := campaignDiscounts(ctx, target, bundle.CampaignID)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]models.Discount, len(current))
+	for _, d := range current {
+		existing[d.ID] = d
+	}
+
+	plan := &Plan{Bundle: *bundle}
+	var touched []models.Discount
+	for _, want := range bundle.Discounts {
+		have, inCampaign := existing[want.ID]
+		if !inCampaign {
+			stored, err := target.GetDiscountByID(ctx, want.ID)
+			switch {
+			case errors.IsNotFoundError(err):
+				d := want
+				plan.Changes = append(plan.Changes, Change{DiscountID: want.ID, Action: ActionCreate, Discount: &d})
+				continue
+			case err != nil:
+				return nil, err
+			}
+			// Moving a discount into the campaign is an update like any other
+			have = *stored
+		}
+		delete(existing, want.ID)
+		touched = append(touched, have)
+
+		fields, err := diffFields(have, want)
+		if err != nil {
+			return nil, err
+		}
+		change := Change{DiscountID: want.ID, Action: ActionUnchanged}
+		if len(fields) > 0 {
+			d := want
+			change.Action, change.Fields, change.Discount = ActionUpdate, fields, &d
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	for _, stale := range existing {
+		touched = append(touched, stale)
+		if stale.IsActive {
+			plan.Changes = append(plan.Changes, Change{
+				DiscountID: stale.ID,
+				Action:     ActionDeactivate,
+				Fields:     []FieldChange{{Field: "is_active", From: json.RawMessage("true"), To: json.RawMessage("false")}},
+			})
+		}
+	}
+
+	sort.Slice(plan.Changes, func(i, j int) bool { return plan.Changes[i].DiscountID < plan.Changes[j].DiscountID })
+	if plan.TargetDigest, err = digest(touched); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// Apply makes the plan's changes to the target. The plan is recomputed first and refused
+// with a conflict error if the target has changed since it was reviewed.
+func Apply(ctx context.Context, target interfaces.IDiscountRepository, plan *Plan) error {
+	fresh, err := Diff(ctx, target, &plan.Bundle)
+	if err != nil {
+		return err
+	}
+	if fresh.TargetDigest != plan.TargetDigest {
+		return errors.NewConflictError("target changed since the plan was made, diff again and review the new plan")
+	}
+
+	for _, c := range fresh.Changes {
+		switch c.Action {
+		case ActionCreate:
+			err = target.CreateDiscount(ctx, c.Discount)
+		case ActionUpdate, ActionDeactivate:
+			err = update(ctx, target, c)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s discount %s: %w", c.Action, c.DiscountID, err)
+		}
+	}
+	return nil
+}
+
+// update writes the change, keeping the target's runtime state
+func update(ctx context.Context, target interfaces.IDiscountRepository, c Change) error {
+	stored, err := target.GetDiscountByID(ctx, c.DiscountID)
+	if err != nil {
+		return err
+	}
+
+	updated := *stored
+	if c.Action == ActionDeactivate {
+		updated.IsActive = false
+	} else {
+		updated = *c.Discount
+		updated.UsedCount = stored.UsedCount
+		updated.TenantID = stored.TenantID
+		updated.Version = stored.Version
+	}
+	return target.UpdateDiscount(ctx, &updated)
+}
+
+// diffFields lists the definition fields that differ, by JSON name
+func diffFields(have, want models.Discount) ([]FieldChange, error) {
+	from, err := fieldsOf(have)
+	if err != nil {
+		return nil, err
+	}
+	to, err := fieldsOf(want)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(from)+len(to))
+	for name := range from {
+		names[name] = true
+	}
+	for name := range to {
+		names[name] = true
+	}
+
+	var changes []FieldChange
+	for name := range names {
+		if runtimeFields[name] || string(from[name]) == string(to[name]) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: name, From: from[name], To: to[name]})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+func fieldsOf(d models.Discount) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	return fields, json.Unmarshal(data, &fields)
+}
+
+// digest fingerprints the definitions of the target discounts a plan depends on, so any
+// edit to them in the meantime invalidates the plan while checkouts using them do not
+func digest(discounts []models.Discount) (string, error) {
+	sort.Slice(discounts, func(i, j int) bool { return discounts[i].ID < discounts[j].ID })
+	definitions := make([]map[string]json.RawMessage, len(discounts))
+	for i, d := range discounts {
+		fields, err := fieldsOf(d)
+		if err != nil {
+			return "", err
+		}
+		for name := range runtimeFields {
+			delete(fields, name)
+		}
+		definitions[i] = fields
+	}
+
+	data, err := json.Marshal(definitions)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
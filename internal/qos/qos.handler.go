@@ -0,0 +1,18 @@
+package qos
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// NewHandler serves the scheduler's metrics:
+//
+//	GET /admin/qos  load and latencies per lane
+func NewHandler(s *Scheduler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/qos", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"lanes": s.Stats()})
+	})
+	return mux
+}
@@ -0,0 +1,201 @@
+// Package qos schedules calculations on separate worker pools per traffic lane, so batch
+// and backfill work sharing a process with checkouts cannot starve them. Each lane bounds
+// its queue and keeps latency metrics of its own.
+package qos
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/lane"
+)
+
+// latencySamples is how many recent calculations each lane's latency percentiles cover
+const latencySamples = 1024
+
+// retryAfter is how long callers rejected by a full queue are asked to back off
+const retryAfter = time.Second
+
+// LaneConfig sizes one lane
+type LaneConfig struct {
+	Workers    int // Calculations run at once, at least 1
+	QueueLimit int // Calculations waiting for a worker; more are rejected as overloaded
+}
+
+// DefaultLanes gives checkouts most of the machine and keeps batch work to a small pool
+func DefaultLanes() map[lane.Lane]LaneConfig {
+	cpus := runtime.GOMAXPROCS(0)
+	return map[lane.Lane]LaneConfig{
+		lane.Checkout: {Workers: 4 * cpus, QueueLimit: 64 * cpus},
+		lane.Batch:    {Workers: max(1, cpus/2), QueueLimit: 64},
+	}
+}
+
+// Scheduler runs work on the worker pool of its lane. It is safe for concurrent use.
+type Scheduler struct {
+	lanes map[lane.Lane]*pool
+}
+
+var _ interfaces.IWorkScheduler = (*Scheduler)(nil)
+
+// NewScheduler creates a scheduler with a pool for every configured lane. Work in a lane
+// without a pool is rejected.
+func NewScheduler(lanes map[lane.Lane]LaneConfig) (*Scheduler, error) {
+	s := &Scheduler{lanes: make(map[lane.Lane]*pool, len(lanes))}
+	for l, cfg := range lanes {
+		if cfg.Workers < 1 || cfg.QueueLimit < 0 {
+			return nil, fmt.Errorf("lane %s needs at least one worker and a non-negative queue limit", l)
+		}
+		s.lanes[l] = &pool{cfg: cfg, slots: make(chan struct{}, cfg.Workers)}
+	}
+	return s, nil
+}
+
+// Run waits for a worker of the context's lane, then runs fn on the calling goroutine. Work
+// arriving while the lane's queue is full is rejected with an overloaded error; work whose
+// context is done while queued returns the context's error.
+func (s *Scheduler) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	l := lane.FromContext(ctx)
+	p, ok := s.lanes[l]
+	if !ok {
+		return errors.NewValidationError(fmt.Sprintf("traffic lane %q is not served", l))
+	}
+
+	queued := time.Now()
+	if err := p.acquire(ctx, l); err != nil {
+		return err
+	}
+	started := time.Now()
+	defer func() {
+		<-p.slots
+		p.observe(started.Sub(queued), time.Since(started))
+	}()
+	return fn(ctx)
+}
+
+// Stats reports every lane's load and latencies
+func (s *Scheduler) Stats() map[lane.Lane]LaneStats {
+	stats := make(map[lane.Lane]LaneStats, len(s.lanes))
+	for l, p := range s.lanes {
+		stats[l] = p.stats()
+	}
+	return stats
+}
+
+// LaneStats is a snapshot of one lane
+type LaneStats struct {
+	Workers    int    `json:"workers"`
+	QueueLimit int    `json:"queue_limit"`
+	Busy       int    `json:"busy"`   // Workers running a calculation
+	Queued     int    `json:"queued"` // Calculations waiting for a worker
+	Completed  uint64 `json:"completed"`
+	Rejected   uint64 `json:"rejected"` // Turned away because the queue was full
+
+	// Wait is the time calculations spent queued and Run the time they took once started,
+	// over the most recent calculations
+	Wait Latency `json:"wait"`
+	Run  Latency `json:"run"`
+}
+
+// Latency summarises recent durations in milliseconds
+type Latency struct {
+	P50 float64 `json:"p50_ms"`
+	P95 float64 `json:"p95_ms"`
+	P99 float64 `json:"p99_ms"`
+	Max float64 `json:"max_ms"`
+}
+
+// pool is the capacity of one lane
+type pool struct {
+	cfg   LaneConfig
+	slots chan struct{} // Holds a token per busy worker
+
+	mu        sync.Mutex
+	queued    int
+	completed uint64
+	rejected  uint64
+	waits     ring
+	runs      ring
+}
+
+func (p *pool) acquire(ctx context.Context, l lane.Lane) error {
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	p.mu.Lock()
+	if p.queued >= p.cfg.QueueLimit {
+		p.rejected++
+		p.mu.Unlock()
+		return errors.NewOverloadedError(fmt.Sprintf("the %s lane is at capacity, retry later", l), retryAfter)
+	}
+	p.queued++
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.queued--
+		p.mu.Unlock()
+	}()
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *pool) observe(wait, run time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed++
+	p.waits.add(wait)
+	p.runs.add(run)
+}
+
+func (p *pool) stats() LaneStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return LaneStats{
+		Workers:    p.cfg.Workers,
+		QueueLimit: p.cfg.QueueLimit,
+		Busy:       len(p.slots),
+		Queued:     p.queued,
+		Completed:  p.completed,
+		Rejected:   p.rejected,
+		Wait:       p.waits.latency(),
+		Run:        p.runs.latency(),
+	}
+}
+
+// ring keeps the most recent latencySamples durations
+type ring struct {
+	samples [latencySamples]time.Duration
+	n       int // Durations added, the next slot is n % latencySamples
+}
+
+func (r *ring) add(d time.Duration) {
+	r.samples[r.n%latencySamples] = d
+	r.n++
+}
+
+func (r *ring) latency() Latency {
+	sorted := append([]time.Duration(nil), r.samples[:min(r.n, latencySamples)]...)
+	if len(sorted) == 0 {
+		return Latency{}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	at := func(q float64) float64 {
+		return float64(sorted[int(q*float64(len(sorted)-1))]) / float64(time.Millisecond)
+	}
+	return Latency{P50: at(0.50), P95: at(0.95), P99: at(0.99), Max: at(1)}
+}
@@ -0,0 +1,75 @@
+// Package recording captures request/response pairs of the HTTP API for replay tooling and
+// support investigations. Recording is opt-in and sampled; personal data is redacted from
+// both bodies before an exchange reaches a sink.
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Exchange is one recorded request and the response it got
+type Exchange struct {
+	At        time.Time     `json:"at"`
+	Method    string        `json:"method"`
+	Path      string        `json:"path"`
+	Version   string        `json:"version,omitempty"` // API version that served the request
+	TenantID  string        `json:"tenant_id,omitempty"`
+	Status    int           `json:"status"`
+	Duration  time.Duration `json:"duration_ns"`
+	Request   any           `json:"request"`
+	Response  any           `json:"response"`
+	Truncated bool          `json:"truncated,omitempty"` // A body exceeded the recorded size and was dropped
+}
+
+// Sink persists recorded exchanges
+type Sink interface {
+	Record(ctx context.Context, exchange Exchange) error
+}
+
+// JSONSink writes every exchange as one line of JSON
+type JSONSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONSink writes exchanges to w
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+// OpenFile appends exchanges to the file at path, creating it when missing. Close the sink
+// to close the file.
+func OpenFile(path string) (*JSONSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording file: %w", err)
+	}
+	return NewJSONSink(f), nil
+}
+
+// Record appends the exchange as a line of JSON
+func (s *JSONSink) Record(ctx context.Context, exchange Exchange) error {
+	line, err := json.Marshal(exchange)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the underlying writer when it is closable
+func (s *JSONSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package recording
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/pkg/clock"
+)
+
+// defaultMaxBodyBytes is the largest body recorded by default, matching the API's own limit
+const defaultMaxBodyBytes = 1 << 20
+
+// Config tunes what the middleware records
+type Config struct {
+	// SampleRate is the fraction of requests recorded, from 0 (none) to 1 (all)
+	SampleRate float64
+
+	// RedactedFields replaces DefaultRedactedFields when set, see DefaultRedactedFields for
+	// the syntax
+	RedactedFields []string
+
+	MaxBodyBytes int // Larger bodies are dropped from the exchange, default 1 MiB
+
+	// OnError is called when the sink fails; the request itself is never affected
+	OnError func(exchange Exchange, err error)
+
+	// Sample decides whether a request is recorded, defaulting to a random draw against
+	// SampleRate. Tests replace it to record deterministically.
+	Sample func() bool
+}
+
+// Middleware records the exchanges served by next to the sink
+func Middleware(next http.Handler, sink Sink, cfg Config) http.Handler {
+	if cfg.RedactedFields == nil {
+		cfg.RedactedFields = DefaultRedactedFields
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	if cfg.Sample == nil {
+		rate := cfg.SampleRate
+		cfg.Sample = func() bool { return rate > 0 && rand.Float64() < rate }
+	}
+	redact := newRedactor(cfg.RedactedFields)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Sample() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		started := time.Now()
+		exchange := Exchange{
+			At:     clock.FromContext(r.Context()),
+			Method: r.Method,
+			Path:   r.URL.Path,
+		}
+
+		// Read one byte past the limit to tell a body at the limit from a larger one
+		request, err := io.ReadAll(io.LimitReader(r.Body, int64(cfg.MaxBodyBytes)+1))
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r.Body = readCloser{io.MultiReader(bytes.NewReader(request), r.Body), r.Body}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK, limit: cfg.MaxBodyBytes}
+		next.ServeHTTP(rec, r)
+
+		exchange.Status = rec.status
+		exchange.Duration = time.Since(started)
+		exchange.Version = rec.Header().Get(api.ServedVersionHeader)
+		exchange.TenantID = r.Header.Get(api.TenantHeader)
+		if len(request) > cfg.MaxBodyBytes {
+			exchange.Truncated = true
+		} else {
+			exchange.Request = redact.body(request)
+		}
+		if rec.overflow {
+			exchange.Truncated = true
+		} else {
+			exchange.Response = redact.body(rec.body.Bytes())
+		}
+
+		if err := sink.Record(r.Context(), exchange); err != nil && cfg.OnError != nil {
+			cfg.OnError(exchange, err)
+		}
+	})
+}
+
+// readCloser replays the consumed part of a body before the rest of it
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// responseRecorder keeps a copy of what the handler writes, up to limit bytes
+type responseRecorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if !r.overflow {
+		if r.body.Len()+len(p) > r.limit {
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(p)
+		}
+	}
+	return r.ResponseWriter.Write(p)
+}
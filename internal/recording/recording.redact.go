@@ -0,0 +1,92 @@
+package recording
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Redacted replaces every redacted value in a recorded body
+const Redacted = "[redacted]"
+
+// DefaultRedactedFields are the request and response fields holding personal or payment
+// data. Plain names match the key anywhere in a body; dotted paths match a key under the
+// named parent wherever the parent is, e.g. in every cart of a batch, ignoring array indices.
+var DefaultRedactedFields = []string{
+	"customer.id",
+	"customer_id",
+	"referrer_id",
+	"bank_name",
+	"card_number",
+	"card_bin",
+	"email",
+	"date_of_birth",
+	"phone",
+	"address",
+}
+
+// redactor blanks out the values of configured fields in JSON bodies
+type redactor struct {
+	names map[string]bool // keys redacted wherever they appear
+	paths []string        // dotted paths redacted where the key's path ends with them
+}
+
+func newRedactor(fields []string) *redactor {
+	r := &redactor{names: make(map[string]bool)}
+	for _, field := range fields {
+		field = strings.ToLower(field)
+		if strings.Contains(field, ".") {
+			r.paths = append(r.paths, field)
+		} else {
+			r.names[field] = true
+		}
+	}
+	return r
+}
+
+// body decodes a JSON body with its personal data redacted. Bodies that are not JSON
+// cannot be redacted field by field, so they are replaced as a whole.
+func (r *redactor) body(data []byte) any {
+	if len(data) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return Redacted
+	}
+	return r.value("", v)
+}
+
+func (r *redactor) value(path string, v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			childPath := strings.ToLower(key)
+			if path != "" {
+				childPath = path + "." + childPath
+			}
+			if r.names[strings.ToLower(key)] || r.matchesPath(childPath) {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = r.value(childPath, child)
+		}
+		return v
+	case []any:
+		for i, child := range v {
+			v[i] = r.value(path, child)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// matchesPath reports whether the key at path is under a redacted path
+func (r *redactor) matchesPath(path string) bool {
+	for _, redacted := range r.paths {
+		if path == redacted || strings.HasSuffix(path, "."+redacted) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+// Package reports serves the reporting service's analytics to operators, and takes in the
+// orders placed elsewhere that experiment reports measure conversions by.
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// maxOrderBytes bounds the body of a recorded order
+const maxOrderBytes = 64 << 10
+
+// NewHandler serves the reports of reporting:
+//
+//	GET  /admin/reports/experiments/{id}?control=V  the incrementality of every variant against V
+//	GET  /admin/reports/campaigns/{id}/codes        the campaign's codes ranked by revenue, for partner payouts
+//	POST /admin/reports/orders                      records a placed models.Order body
+//
+// for the tenant named by the api.TenantHeader header. The from and to query parameters
+// (RFC 3339) bound a report's window; orders must be recorded in orders, the repository
+// reporting reads them from.
+func NewHandler(reporting interfaces.IReportingService, orders interfaces.IOrderRepository) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/reports/experiments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		from, to, err := parseWindow(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		control := r.URL.Query().Get("control")
+		if control == "" {
+			writeError(w, errors.NewValidationError("control variant is required"))
+			return
+		}
+
+		report, err := reporting.GetIncrementalityReport(requestContext(r), r.PathValue("id"), control, from, to)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	})
+	mux.HandleFunc("GET /admin/reports/campaigns/{id}/codes", func(w http.ResponseWriter, r *http.Request) {
+		from, to, err := parseWindow(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		board, err := reporting.GetCodeLeaderboard(requestContext(r), r.PathValue("id"), from, to)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, board)
+	})
+	mux.HandleFunc("POST /admin/reports/orders", func(w http.ResponseWriter, r *http.Request) {
+		var order models.Order
+		r.Body = http.MaxBytesReader(w, r.Body, maxOrderBytes)
+		if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+			writeError(w, errors.NewValidationError("invalid order: "+err.Error()))
+			return
+		}
+		if order.ID == "" || order.CustomerID == "" || order.PlacedAt.IsZero() {
+			writeError(w, errors.NewValidationError("order needs an id, customer_id and placed_at"))
+			return
+		}
+		if err := orders.RecordOrder(requestContext(r), order); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// parseWindow reads the optional from and to query parameters
+func parseWindow(r *http.Request) (from, to time.Time, err error) {
+	for name, t := range map[string]*time.Time{"from": &from, "to": &to} {
+		if v := r.URL.Query().Get(name); v != "" {
+			if *t, err = time.Parse(time.RFC3339, v); err != nil {
+				return time.Time{}, time.Time{}, errors.NewValidationError(fmt.Sprintf("invalid %s: %q is not an RFC 3339 time", name, v))
+			}
+		}
+	}
+	return from, to, nil
+}
+
+// requestContext scopes the request to the tenant it names
+func requestContext(r *http.Request) context.Context {
+	if tenantID := r.Header.Get(api.TenantHeader); tenantID != "" {
+		return tenant.NewContext(r.Context(), tenantID)
+	}
+	return r.Context()
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.IsValidationError(err):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.IsNotFoundError(err):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// hitScript increments KEYS[1], starting an ARGV[1] millisecond window on the first hit.
+// It returns {count, milliseconds until the window resets}.
+var hitScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// RedisAttemptCounter implements IAttemptCounter on Redis so limits are shared by every instance
+type RedisAttemptCounter struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedisAttemptCounter creates an attempt counter storing one expiring key per limited key
+// under prefix, namespaced by tenant for every tenant but the default
+func NewRedisAttemptCounter(client redis.Cmdable, prefix string) interfaces.IAttemptCounter {
+	return &RedisAttemptCounter{client: client, prefix: prefix}
+}
+
+func (c *RedisAttemptCounter) key(ctx context.Context, key string) string {
+	if tenantID := tenant.FromContext(ctx); tenantID != tenant.Default {
+		return c.prefix + "tenant:" + tenantID + ":attempts:" + key
+	}
+	return c.prefix + "attempts:" + key
+}
+
+// Hit atomically counts an attempt in the key's current window
+func (c *RedisAttemptCounter) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	res, err := hitScript.Run(ctx, c.client, []string{c.key(ctx, key)}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, errors.NewInternalError("failed to count attempt", err)
+	}
+	if len(res) != 2 {
+		return 0, 0, errors.NewInternalError("unexpected attempt script reply", fmt.Errorf("%v", res))
+	}
+	return res[0], time.Duration(res[1]) * time.Millisecond, nil
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+type attemptWindow struct {
+	count   int64
+	resetAt time.Time
+}
+
+// InMemoryAttemptCounter implements IAttemptCounter for a single instance
+type InMemoryAttemptCounter struct {
+	windows   map[string]*attemptWindow
+	nextSweep time.Time
+	mu        sync.Mutex
+}
+
+// NewInMemoryAttemptCounter creates a new in-memory attempt counter
+func NewInMemoryAttemptCounter() interfaces.IAttemptCounter {
+	return &InMemoryAttemptCounter{
+		windows: make(map[string]*attemptWindow),
+	}
+}
+
+// Hit counts an attempt in the key's window, starting a new window once the last one has
+// expired at the instant pinned in ctx
+func (c *InMemoryAttemptCounter) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := clock.FromContext(ctx)
+	c.sweep(now, window)
+
+	scoped := tenant.Key(ctx, key)
+	w, exists := c.windows[scoped]
+	if !exists || !now.Before(w.resetAt) {
+		w = &attemptWindow{resetAt: now.Add(window)}
+		c.windows[scoped] = w
+	}
+	w.count++
+	return w.count, w.resetAt.Sub(now), nil
+}
+
+// sweep drops expired windows about once per window, so keys seen once, such as the
+// addresses of a distributed guessing attack, do not accumulate
+func (c *InMemoryAttemptCounter) sweep(now time.Time, window time.Duration) {
+	if now.Before(c.nextSweep) {
+		return
+	}
+	for key, w := range c.windows {
+		if !now.Before(w.resetAt) {
+			delete(c.windows, key)
+		}
+	}
+	c.nextSweep = now.Add(window)
+}
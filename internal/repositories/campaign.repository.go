@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+	"github.com/shopspring/decimal"
+)
+
+// InMemoryCampaignRepository implements ICampaignRepository using in-memory storage
+type InMemoryCampaignRepository struct {
+	campaigns map[string]*models.Campaign
+	mu        sync.RWMutex
+}
+
+// NewInMemoryCampaignRepository creates a new in-memory campaign repository
+func NewInMemoryCampaignRepository() interfaces.ICampaignRepository {
+	return &InMemoryCampaignRepository{
+		campaigns: make(map[string]*models.Campaign),
+	}
+}
+
+// GetCampaignByID retrieves a campaign by its ID
+func (r *InMemoryCampaignRepository) GetCampaignByID(ctx context.Context, id string) (*models.Campaign, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	campaign, exists := r.campaigns[tenant.Key(ctx, id)]
+	if !exists {
+		return nil, errors.NewNotFoundError("campaign not found: " + id)
+	}
+
+	campaignCopy := *campaign
+	return &campaignCopy, nil
+}
+
+// CreateCampaign creates a new campaign
+func (r *InMemoryCampaignRepository) CreateCampaign(ctx context.Context, campaign *models.Campaign) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.campaigns[tenant.Key(ctx, campaign.ID)]; exists {
+		return errors.NewValidationError("campaign already exists: " + campaign.ID)
+	}
+	if campaign.Budget.IsNegative() {
+		return errors.NewValidationError("campaign budget cannot be negative: " + campaign.ID)
+	}
+
+	campaignCopy := *campaign
+	r.campaigns[tenant.Key(ctx, campaign.ID)] = &campaignCopy
+	return nil
+}
+
+// UpdateCampaign updates an existing campaign
+func (r *InMemoryCampaignRepository) UpdateCampaign(ctx context.Context, campaign *models.Campaign) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.campaigns[tenant.Key(ctx, campaign.ID)]; !exists {
+		return errors.NewNotFoundError("campaign not found: " + campaign.ID)
+	}
+	if campaign.Budget.IsNegative() {
+		return errors.NewValidationError("campaign budget cannot be negative: " + campaign.ID)
+	}
+
+	campaignCopy := *campaign
+	r.campaigns[tenant.Key(ctx, campaign.ID)] = &campaignCopy
+	return nil
+}
+
+// ConsumeBudget atomically adds amount to the campaign's spend
+func (r *InMemoryCampaignRepository) ConsumeBudget(ctx context.Context, id string, amount decimal.Decimal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	campaign, exists := r.campaigns[tenant.Key(ctx, id)]
+	if !exists {
+		return errors.NewNotFoundError("campaign not found: " + id)
+	}
+
+	if campaign.HasBudgetLimit() && amount.GreaterThan(campaign.RemainingBudget()) {
+		return errors.NewValidationError("campaign budget exhausted: " + id)
+	}
+
+	updatedCampaign := *campaign
+	updatedCampaign.Spent = updatedCampaign.Spent.Add(amount)
+	r.campaigns[tenant.Key(ctx, id)] = &updatedCampaign
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// InMemoryCouponCodeRepository implements ICouponCodeRepository using in-memory storage
+type InMemoryCouponCodeRepository struct {
+	codes map[string]*models.CouponCode
+	mu    sync.RWMutex
+}
+
+// NewInMemoryCouponCodeRepository creates a new in-memory coupon code repository
+func NewInMemoryCouponCodeRepository() interfaces.ICouponCodeRepository {
+	return &InMemoryCouponCodeRepository{
+		codes: make(map[string]*models.CouponCode),
+	}
+}
+
+// SaveCodes stores the codes under a single lock, returning the ones that already existed
+func (r *InMemoryCouponCodeRepository) SaveCodes(ctx context.Context, codes []models.CouponCode) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, code := range codes {
+		if code.Code == "" || code.DiscountID == "" {
+			return nil, errors.NewValidationError("coupon code and discount id cannot be empty")
+		}
+	}
+
+	var duplicates []string
+	for _, code := range codes {
+		key := tenant.Key(ctx, code.Code)
+		if _, exists := r.codes[key]; exists {
+			duplicates = append(duplicates, code.Code)
+			continue
+		}
+		codeCopy := code
+		r.codes[key] = &codeCopy
+	}
+	return duplicates, nil
+}
+
+// GetCode retrieves a coupon code
+func (r *InMemoryCouponCodeRepository) GetCode(ctx context.Context, code string) (*models.CouponCode, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, exists := r.codes[tenant.Key(ctx, code)]
+	if !exists {
+		return nil, errors.NewNotFoundError("coupon code not found: " + code)
+	}
+
+	codeCopy := *stored
+	return &codeCopy, nil
+}
+
+// RedeemCode atomically marks the code as used by the customer
+func (r *InMemoryCouponCodeRepository) RedeemCode(ctx context.Context, code, customerID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := tenant.Key(ctx, code)
+	stored, exists := r.codes[key]
+	if !exists {
+		return errors.NewNotFoundError("coupon code not found: " + code)
+	}
+	if stored.IsRedeemed() {
+		return errors.NewConflictError("coupon code already redeemed: " + code)
+	}
+
+	updated := *stored
+	updated.RedeemedBy = customerID
+	updated.RedeemedAt = at
+	r.codes[key] = &updated
+	return nil
+}
+
+// ReleaseCode clears the code's redemption
+func (r *InMemoryCouponCodeRepository) ReleaseCode(ctx context.Context, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := tenant.Key(ctx, code)
+	stored, exists := r.codes[key]
+	if !exists {
+		return errors.NewNotFoundError("coupon code not found: " + code)
+	}
+
+	updated := *stored
+	updated.RedeemedBy = ""
+	updated.RedeemedAt = time.Time{}
+	r.codes[key] = &updated
+	return nil
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// InMemoryCustomerProvider implements ICustomerProvider with in-memory group assignments,
+// for tests and deployments without a customer system of record
+type InMemoryCustomerProvider struct {
+	groups map[string]models.CustomerGroup
+	mu     sync.RWMutex
+}
+
+// NewInMemoryCustomerProvider creates a customer provider starting from the given
+// assignments, which belong to the default tenant
+func NewInMemoryCustomerProvider(groups map[string]models.CustomerGroup) *InMemoryCustomerProvider {
+	p := &InMemoryCustomerProvider{groups: make(map[string]models.CustomerGroup, len(groups))}
+	for customerID, group := range groups {
+		p.groups[customerID] = group
+	}
+	return p
+}
+
+var _ interfaces.ICustomerProvider = (*InMemoryCustomerProvider)(nil)
+
+// GetCustomerGroup returns the group the customer was assigned, retail when unassigned
+func (p *InMemoryCustomerProvider) GetCustomerGroup(ctx context.Context, customerID string) (models.CustomerGroup, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if group, exists := p.groups[tenant.Key(ctx, customerID)]; exists {
+		return group, nil
+	}
+	return models.CustomerGroupRetail, nil
+}
+
+// AssignGroup moves the context tenant's customer to the group
+func (p *InMemoryCustomerProvider) AssignGroup(ctx context.Context, customerID string, group models.CustomerGroup) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.groups[tenant.Key(ctx, customerID)] = group
+}
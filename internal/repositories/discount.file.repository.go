@@ -0,0 +1,286 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+	"github.com/ahsmha/discounts/pkg/yamljson"
+)
+
+// reloadDelay lets editors and git checkouts finish writing a batch of files before the
+// directory is read again
+const reloadDelay = 100 * time.Millisecond
+
+// FileDiscountRepository serves the discounts defined in a directory of YAML and JSON files,
+// so small deployments can manage offers through version control without a database. Each
+// *.yaml, *.yml or *.json file holds a discount or a list of them, with the field names of
+// the JSON API; discounts name their storefront with tenant_id.
+//
+// Reload reads the whole directory into a fresh set and swaps it in at once, so requests
+// see either the old or the new discounts, never a mix; a directory that fails to load
+// leaves the current set in place. Usage counts are runtime state kept across reloads.
+// Discounts are changed by editing the files, so the repository's write methods fail.
+type FileDiscountRepository struct {
+	dir     string
+	current atomic.Pointer[InMemoryDiscountRepository]
+	mu      sync.RWMutex // Held exclusively while swapping sets, so no usage is counted on a stale one
+
+	// OnReload and OnError, when set, are called by Watch after every reload of the
+	// directory, with the number of discounts loaded or the reason the reload failed
+	OnReload func(discounts int)
+	OnError  func(err error)
+}
+
+var (
+	_ interfaces.IDiscountRepository = (*FileDiscountRepository)(nil)
+	_ interfaces.DiscountLister      = (*FileDiscountRepository)(nil)
+	_ interfaces.CatalogInspector    = (*FileDiscountRepository)(nil)
+	_ interfaces.UsageReleaser       = (*FileDiscountRepository)(nil)
+	_ interfaces.UsageAdder          = (*FileDiscountRepository)(nil)
+)
+
+// NewFileDiscountRepository loads the discounts in dir
+func NewFileDiscountRepository(dir string) (*FileDiscountRepository, error) {
+	r := &FileDiscountRepository{dir: dir}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the directory again and swaps the discounts it defines in, returning how
+// many there are
+func (r *FileDiscountRepository) Reload() (int, error) {
+	discounts, err := LoadDiscountFiles(r.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	next := NewInMemoryDiscountRepository().(*InMemoryDiscountRepository)
+	for i := range discounts {
+		d := discounts[i]
+		if err := next.CreateDiscount(tenant.NewContext(context.Background(), d.TenantID), &d); err != nil {
+			return 0, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if previous := r.current.Load(); previous != nil {
+		for key, d := range next.discounts {
+			if old, exists := previous.discounts[key]; exists {
+				d.UsedCount = old.UsedCount
+			}
+		}
+	}
+	r.current.Store(next)
+	return len(discounts), nil
+}
+
+// Watch reloads the discounts whenever a file in the directory changes, until ctx is done
+func (r *FileDiscountRepository) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(r.dir); err != nil {
+		return err
+	}
+
+	// Changes arrive as bursts of events, so reloads wait for a burst to settle
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if isDiscountFile(event.Name) {
+				timer.Reset(reloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			if r.OnError != nil {
+				r.OnError(err)
+			}
+		case <-timer.C:
+			n, err := r.Reload()
+			if err != nil {
+				if r.OnError != nil {
+					r.OnError(err)
+				}
+				continue
+			}
+			if r.OnReload != nil {
+				r.OnReload(n)
+			}
+		}
+	}
+}
+
+// LoadDiscountFiles reads every discount defined in the directory's YAML and JSON files,
+// in file name order
+func LoadDiscountFiles(dir string) ([]models.Discount, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var discounts []models.Discount
+	for _, entry := range entries {
+		if entry.IsDir() || !isDiscountFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		loaded, err := LoadDiscountFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		discounts = append(discounts, loaded...)
+	}
+	return discounts, nil
+}
+
+// LoadDiscountFile reads the discounts defined in a YAML or JSON file, a single discount
+// or a list of them; fields the discount model does not know are errors
+func LoadDiscountFile(path string) ([]models.Discount, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Ext(path) != ".json" {
+		if data, err = yamljson.ToJSON(data); err != nil {
+			return nil, err
+		}
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	if data[0] != '[' {
+		data = append(append([]byte("["), data...), ']')
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var discounts []models.Discount
+	if err := dec.Decode(&discounts); err != nil {
+		return nil, err
+	}
+	return discounts, nil
+}
+
+func isDiscountFile(name string) bool {
+	if strings.HasPrefix(filepath.Base(name), ".") {
+		return false // Editor swap files and the like
+	}
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *FileDiscountRepository) readOnly(id string) error {
+	return errors.NewForbiddenError("discounts are managed in " + r.dir + " and cannot be changed at runtime: " + id)
+}
+
+// GetActiveDiscounts returns the context tenant's discounts valid at the instant pinned in ctx
+func (r *FileDiscountRepository) GetActiveDiscounts(ctx context.Context) ([]models.Discount, error) {
+	return r.current.Load().GetActiveDiscounts(ctx)
+}
+
+// GetApplicableDiscounts returns the context tenant's discounts matching the filter
+func (r *FileDiscountRepository) GetApplicableDiscounts(ctx context.Context, filter models.DiscountFilter) ([]models.Discount, error) {
+	return r.current.Load().GetApplicableDiscounts(ctx, filter)
+}
+
+// ListDiscounts lists the context tenant's discounts a page at a time
+func (r *FileDiscountRepository) ListDiscounts(ctx context.Context, filter models.ListFilter) (*models.DiscountPage, error) {
+	return r.current.Load().ListDiscounts(ctx, filter)
+}
+
+// CatalogStats summarises the context tenant's discounts
+func (r *FileDiscountRepository) CatalogStats(ctx context.Context, window time.Duration) (*models.CatalogStats, error) {
+	return r.current.Load().CatalogStats(ctx, window)
+}
+
+// GetDiscountByCode returns the discount a code or alias unlocks
+func (r *FileDiscountRepository) GetDiscountByCode(ctx context.Context, code string) (*models.Discount, error) {
+	return r.current.Load().GetDiscountByCode(ctx, code)
+}
+
+// GetDiscountByID returns the discount with the ID
+func (r *FileDiscountRepository) GetDiscountByID(ctx context.Context, id string) (*models.Discount, error) {
+	return r.current.Load().GetDiscountByID(ctx, id)
+}
+
+// CreateDiscount fails, discounts are added by adding files
+func (r *FileDiscountRepository) CreateDiscount(ctx context.Context, discount *models.Discount) error {
+	return r.readOnly(discount.ID)
+}
+
+// UpdateDiscount fails, discounts are changed by editing their files
+func (r *FileDiscountRepository) UpdateDiscount(ctx context.Context, discount *models.Discount) error {
+	return r.readOnly(discount.ID)
+}
+
+// DeleteDiscount fails, discounts are deleted by removing them from their files
+func (r *FileDiscountRepository) DeleteDiscount(ctx context.Context, id string) error {
+	return r.readOnly(id)
+}
+
+// IncrementUsageCount counts a use of the discount
+func (r *FileDiscountRepository) IncrementUsageCount(ctx context.Context, id string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.Load().IncrementUsageCount(ctx, id)
+}
+
+// CheckAndIncrementUsage counts a use of the discount unless it is at its usage limit
+func (r *FileDiscountRepository) CheckAndIncrementUsage(ctx context.Context, id string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.Load().CheckAndIncrementUsage(ctx, id)
+}
+
+// ReleaseUsage gives back a use claimed with CheckAndIncrementUsage
+func (r *FileDiscountRepository) ReleaseUsage(ctx context.Context, id string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.Load().ReleaseUsage(ctx, id)
+}
+
+// AddUsage counts many uses at once, see interfaces.UsageAdder
+func (r *FileDiscountRepository) AddUsage(ctx context.Context, uses map[string]int) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.Load().AddUsage(ctx, uses)
+}
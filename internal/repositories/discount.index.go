@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// candidateIndex maps each tenant's candidate keys to the storage keys of the discounts
+// indexed under them, see models.Discount.CandidateKeys
+type candidateIndex map[string]map[string]struct{}
+
+func (idx candidateIndex) add(storageKey string, d *models.Discount) {
+	for _, key := range d.CandidateKeys() {
+		key = tenant.Scoped(d.TenantID, key)
+		if idx[key] == nil {
+			idx[key] = make(map[string]struct{})
+		}
+		idx[key][storageKey] = struct{}{}
+	}
+}
+
+func (idx candidateIndex) remove(storageKey string, d *models.Discount) {
+	for _, key := range d.CandidateKeys() {
+		key = tenant.Scoped(d.TenantID, key)
+		delete(idx[key], storageKey)
+		if len(idx[key]) == 0 {
+			delete(idx, key)
+		}
+	}
+}
+
+// lookup returns the storage keys of the tenant's discounts indexed under any of keys
+func (idx candidateIndex) lookup(tenantID string, keys []string) map[string]struct{} {
+	found := make(map[string]struct{})
+	for _, key := range keys {
+		for storageKey := range idx[tenant.Scoped(tenantID, key)] {
+			found[storageKey] = struct{}{}
+		}
+	}
+	return found
+}
@@ -0,0 +1,278 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/breaker"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// ReadThroughDiscountRepository serves reads from a fast store, such as an in-memory
+// repository, filling it from the primary repository on a miss. Writes go to the primary
+// first and then to the fast store, so the two agree as long as every write goes through
+// here.
+//
+// A tenant's active and applicable discounts are served from the fast store once the
+// whole of the tenant's catalog has been copied into it, which needs a primary that is an
+// interfaces.DiscountLister; until then these reads go to the primary, their results kept
+// in the fast store. When the primary fails, its circuit breaker opens and reads are
+// answered from whatever the fast store holds, so checkouts keep pricing with the
+// discounts last seen; writes, usage counts included, fail with an overloaded error until
+// the primary is back.
+type ReadThroughDiscountRepository struct {
+	fast    FastDiscountStore
+	primary interfaces.IDiscountRepository
+
+	// Breaker guards the primary, opening after breaker.DefaultThreshold failures in a
+	// row unless set up otherwise
+	Breaker *breaker.Breaker
+
+	mu     sync.Mutex
+	warmed map[string]bool // tenants whose whole catalog is in the fast store
+}
+
+// FastDiscountStore is a repository that can hold copies of discounts stored elsewhere,
+// as the in-memory repository does
+type FastDiscountStore interface {
+	interfaces.IDiscountRepository
+	interfaces.DiscountSeeder
+}
+
+var (
+	_ interfaces.IDiscountRepository = (*ReadThroughDiscountRepository)(nil)
+	_ interfaces.UsageReleaser       = (*ReadThroughDiscountRepository)(nil)
+)
+
+// NewReadThroughDiscountRepository serves primary through fast, which should start empty
+func NewReadThroughDiscountRepository(fast FastDiscountStore, primary interfaces.IDiscountRepository) *ReadThroughDiscountRepository {
+	return &ReadThroughDiscountRepository{
+		fast:    fast,
+		primary: primary,
+		Breaker: &breaker.Breaker{Name: "primary discount repository"},
+		warmed:  make(map[string]bool),
+	}
+}
+
+func (r *ReadThroughDiscountRepository) GetActiveDiscounts(ctx context.Context) ([]models.Discount, error) {
+	if r.warm(ctx) {
+		return r.fast.GetActiveDiscounts(ctx)
+	}
+	var discounts []models.Discount
+	err := r.callPrimary(func() (err error) {
+		discounts, err = r.primary.GetActiveDiscounts(ctx)
+		return err
+	})
+	if err != nil {
+		return r.fast.GetActiveDiscounts(ctx)
+	}
+	r.keep(ctx, discounts...)
+	return discounts, nil
+}
+
+func (r *ReadThroughDiscountRepository) GetApplicableDiscounts(ctx context.Context, filter models.DiscountFilter) ([]models.Discount, error) {
+	if r.warm(ctx) {
+		return r.fast.GetApplicableDiscounts(ctx, filter)
+	}
+	var discounts []models.Discount
+	err := r.callPrimary(func() (err error) {
+		discounts, err = r.primary.GetApplicableDiscounts(ctx, filter)
+		return err
+	})
+	if err != nil {
+		return r.fast.GetApplicableDiscounts(ctx, filter)
+	}
+	r.keep(ctx, discounts...)
+	return discounts, nil
+}
+
+func (r *ReadThroughDiscountRepository) GetDiscountByCode(ctx context.Context, code string) (*models.Discount, error) {
+	return r.lookup(ctx, func(repo interfaces.IDiscountRepository) (*models.Discount, error) {
+		return repo.GetDiscountByCode(ctx, code)
+	})
+}
+
+func (r *ReadThroughDiscountRepository) GetDiscountByID(ctx context.Context, id string) (*models.Discount, error) {
+	return r.lookup(ctx, func(repo interfaces.IDiscountRepository) (*models.Discount, error) {
+		return repo.GetDiscountByID(ctx, id)
+	})
+}
+
+func (r *ReadThroughDiscountRepository) CreateDiscount(ctx context.Context, discount *models.Discount) error {
+	if err := r.callPrimary(func() error { return r.primary.CreateDiscount(ctx, discount) }); err != nil {
+		return err
+	}
+	r.refresh(ctx, discount.ID)
+	return nil
+}
+
+func (r *ReadThroughDiscountRepository) UpdateDiscount(ctx context.Context, discount *models.Discount) error {
+	if err := r.callPrimary(func() error { return r.primary.UpdateDiscount(ctx, discount) }); err != nil {
+		return err
+	}
+	r.refresh(ctx, discount.ID)
+	return nil
+}
+
+func (r *ReadThroughDiscountRepository) DeleteDiscount(ctx context.Context, id string) error {
+	if err := r.callPrimary(func() error { return r.primary.DeleteDiscount(ctx, id) }); err != nil {
+		return err
+	}
+	r.drop(ctx, id)
+	return nil
+}
+
+func (r *ReadThroughDiscountRepository) IncrementUsageCount(ctx context.Context, id string) error {
+	if err := r.callPrimary(func() error { return r.primary.IncrementUsageCount(ctx, id) }); err != nil {
+		return err
+	}
+	r.refresh(ctx, id)
+	return nil
+}
+
+func (r *ReadThroughDiscountRepository) CheckAndIncrementUsage(ctx context.Context, id string) error {
+	if err := r.callPrimary(func() error { return r.primary.CheckAndIncrementUsage(ctx, id) }); err != nil {
+		return err
+	}
+	r.refresh(ctx, id)
+	return nil
+}
+
+// ReleaseUsage gives back a use through the primary, which must be an
+// interfaces.UsageReleaser
+func (r *ReadThroughDiscountRepository) ReleaseUsage(ctx context.Context, id string) error {
+	releaser, ok := r.primary.(interfaces.UsageReleaser)
+	if !ok {
+		return fmt.Errorf("primary discount repository cannot release usage")
+	}
+	if err := r.callPrimary(func() error { return releaser.ReleaseUsage(ctx, id) }); err != nil {
+		return err
+	}
+	r.refresh(ctx, id)
+	return nil
+}
+
+// lookup reads a single discount from the fast store, then from the primary on a miss.
+// A miss of a warmed tenant is final, as is one while the primary is unavailable.
+func (r *ReadThroughDiscountRepository) lookup(ctx context.Context, get func(interfaces.IDiscountRepository) (*models.Discount, error)) (*models.Discount, error) {
+	discount, err := get(r.fast)
+	if !errors.IsNotFoundError(err) || r.isWarmed(ctx) {
+		return discount, err
+	}
+
+	miss := err
+	err = r.callPrimary(func() (err error) {
+		discount, err = get(r.primary)
+		return err
+	})
+	if err != nil {
+		return nil, miss
+	}
+	r.keep(ctx, *discount)
+	return discount, nil
+}
+
+// warm copies the context tenant's catalog into the fast store the first time it is
+// needed, reporting whether the fast store holds all of it
+func (r *ReadThroughDiscountRepository) warm(ctx context.Context) bool {
+	if r.isWarmed(ctx) {
+		return true
+	}
+	lister, ok := r.primary.(interfaces.DiscountLister)
+	if !ok {
+		return false
+	}
+
+	var page *models.DiscountPage
+	err := r.callPrimary(func() (err error) {
+		page, err = lister.ListDiscounts(ctx, models.ListFilter{})
+		return err
+	})
+	if err != nil {
+		return false
+	}
+	r.keep(ctx, page.Discounts...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.warmed[tenant.FromContext(ctx)] = true
+	return true
+}
+
+func (r *ReadThroughDiscountRepository) isWarmed(ctx context.Context) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.warmed[tenant.FromContext(ctx)]
+}
+
+// keep copies discounts read from the primary into the fast store
+func (r *ReadThroughDiscountRepository) keep(ctx context.Context, discounts ...models.Discount) {
+	copies := make([]models.Discount, len(discounts))
+	for i, d := range discounts {
+		copies[i] = d
+		copies[i].TenantID = tenant.FromContext(ctx)
+	}
+	if err := r.fast.SeedDiscounts(copies); err != nil {
+		// The fast store no longer agrees with the primary, so it cannot answer for the
+		// whole catalog either
+		r.forget(ctx)
+	}
+}
+
+// refresh copies the discount as the primary now stores it into the fast store, after a
+// write through the primary
+func (r *ReadThroughDiscountRepository) refresh(ctx context.Context, id string) {
+	discount, err := r.primary.GetDiscountByID(ctx, id)
+	if err != nil {
+		r.drop(ctx, id)
+		if !errors.IsNotFoundError(err) {
+			r.forget(ctx)
+		}
+		return
+	}
+	r.keep(ctx, *discount)
+}
+
+// drop removes the discount from the fast store
+func (r *ReadThroughDiscountRepository) drop(ctx context.Context, id string) {
+	if err := r.fast.DeleteDiscount(ctx, id); err != nil && !errors.IsNotFoundError(err) {
+		r.forget(ctx)
+	}
+}
+
+// forget stops serving the context tenant's catalog from the fast store until it is
+// warmed again
+func (r *ReadThroughDiscountRepository) forget(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.warmed, tenant.FromContext(ctx))
+}
+
+// callPrimary calls the primary through the breaker. Errors about the request itself,
+// such as not found, validation or conflict errors, show the primary is up.
+func (r *ReadThroughDiscountRepository) callPrimary(call func() error) error {
+	if err := r.Breaker.Allow(); err != nil {
+		return err
+	}
+	err := call()
+	if IsUnavailable(err) {
+		r.Breaker.Failure()
+		return fmt.Errorf("primary discount repository failed: %w", err)
+	}
+	r.Breaker.Success()
+	return err
+}
+
+// IsUnavailable reports whether a repository error says the repository could not serve
+// the request, rather than that the request was wrong
+func IsUnavailable(err error) bool {
+	return err != nil &&
+		!errors.IsNotFoundError(err) &&
+		!errors.IsValidationError(err) &&
+		!errors.IsConflictError(err) &&
+		!errors.IsForbiddenError(err)
+}
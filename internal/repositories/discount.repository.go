@@ -2,36 +2,46 @@ package repositories
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/ahsmha/discounts/internal/interfaces"
 	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/clock"
 	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
 )
 
 // InMemoryDiscountRepository implements DiscountRepository using in-memory storage
 type InMemoryDiscountRepository struct {
-	discounts map[string]*models.Discount
-	codeIndex map[string]string // code -> id mapping
-	mu        sync.RWMutex
+	discounts  map[string]*models.Discount
+	codeIndex  map[string]string // code -> id mapping
+	candidates candidateIndex
+	revisions  map[string][]models.DiscountRevision // tenant-scoped id -> revisions by version
+	mu         sync.RWMutex
 }
 
 // NewInMemoryDiscountRepository creates a new in-memory discount repository
 func NewInMemoryDiscountRepository() interfaces.IDiscountRepository {
 	return &InMemoryDiscountRepository{
-		discounts: make(map[string]*models.Discount),
-		codeIndex: make(map[string]string),
+		discounts:  make(map[string]*models.Discount),
+		codeIndex:  make(map[string]string),
+		candidates: make(candidateIndex),
+		revisions:  make(map[string][]models.DiscountRevision),
 	}
 }
 
-// GetActiveDiscounts retrieves all active discounts
+// GetActiveDiscounts retrieves the context tenant's discounts valid at the instant pinned in ctx
 func (r *InMemoryDiscountRepository) GetActiveDiscounts(ctx context.Context) ([]models.Discount, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	now := clock.FromContext(ctx)
+	tenantID := tenant.FromContext(ctx)
 	var activeDiscounts []models.Discount
 	for _, discount := range r.discounts {
-		if discount.IsValid() {
+		if discount.TenantID == tenantID && discount.IsValidAt(now) {
 			activeDiscounts = append(activeDiscounts, *discount)
 		}
 	}
@@ -39,22 +49,105 @@ func (r *InMemoryDiscountRepository) GetActiveDiscounts(ctx context.Context) ([]
 	return activeDiscounts, nil
 }
 
+// GetApplicableDiscounts retrieves the context tenant's discounts matching the filter.
+// Filters naming both the items and the payment are looked up by candidate key, so
+// discounts for other brands, categories and banks are never visited.
+func (r *InMemoryDiscountRepository) GetApplicableDiscounts(ctx context.Context, filter models.DiscountFilter) ([]models.Discount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+	var discounts []models.Discount
+	match := func(discount *models.Discount) {
+		if filter.Matches(discount) {
+			discounts = append(discounts, *discount)
+		}
+	}
+
+	if keys, ok := filter.CandidateKeys(); ok {
+		for key := range r.candidates.lookup(tenantID, keys) {
+			match(r.discounts[key])
+		}
+		return discounts, nil
+	}
+
+	for _, discount := range r.discounts {
+		if discount.TenantID == tenantID {
+			match(discount)
+		}
+	}
+	return discounts, nil
+}
+
+// ListDiscounts retrieves a page of the context tenant's discounts matching the filter
+func (r *InMemoryDiscountRepository) ListDiscounts(ctx context.Context, filter models.ListFilter) (*models.DiscountPage, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, errors.NewValidationError("invalid discount listing: " + err.Error())
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+	var discounts []models.Discount
+	for _, discount := range r.discounts {
+		if discount.TenantID == tenantID && filter.Matches(discount) {
+			discounts = append(discounts, *discount)
+		}
+	}
+
+	return filter.Page(discounts), nil
+}
+
+// CatalogStats summarises the context tenant's discounts as of the instant pinned in ctx.
+// It reports the tenant's entries in the code index, and the candidate keys and the
+// discounts indexed under them in the candidate index.
+func (r *InMemoryDiscountRepository) CatalogStats(ctx context.Context, window time.Duration) (*models.CatalogStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+	var discounts []models.Discount
+	for _, discount := range r.discounts {
+		if discount.TenantID == tenantID {
+			discounts = append(discounts, *discount)
+		}
+	}
+	stats := models.ComputeCatalogStats(discounts, clock.FromContext(ctx), window)
+
+	codes, keys, entries := 0, 0, 0
+	for key := range r.codeIndex {
+		if tenant.Owns(tenantID, key) {
+			codes++
+		}
+	}
+	for key, indexed := range r.candidates {
+		if tenant.Owns(tenantID, key) {
+			keys++
+			entries += len(indexed)
+		}
+	}
+	stats.Indexes = map[string]int{"codes": codes, "candidate_keys": keys, "candidate_entries": entries}
+	return stats, nil
+}
+
 // GetDiscountByCode retrieves a discount by its code
 func (r *InMemoryDiscountRepository) GetDiscountByCode(ctx context.Context, code string) (*models.Discount, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	id, exists := r.codeIndex[code]
+	id, exists := r.codeIndex[tenant.Key(ctx, code)]
 	if !exists {
 		return nil, errors.NewNotFoundError("discount code not found: " + code)
 	}
 
-	discount, exists := r.discounts[id]
+	discount, exists := r.discounts[tenant.Key(ctx, id)]
 	if !exists {
 		return nil, errors.NewNotFoundError("discount not found for code: " + code)
 	}
 
-	return discount, nil
+	discountCopy := *discount
+	return &discountCopy, nil
 }
 
 // GetDiscountByID retrieves a discount by its ID
@@ -62,12 +155,13 @@ func (r *InMemoryDiscountRepository) GetDiscountByID(ctx context.Context, id str
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	discount, exists := r.discounts[id]
+	discount, exists := r.discounts[tenant.Key(ctx, id)]
 	if !exists {
 		return nil, errors.NewNotFoundError("discount not found: " + id)
 	}
 
-	return discount, nil
+	discountCopy := *discount
+	return &discountCopy, nil
 }
 
 // CreateDiscount creates a new discount
@@ -75,26 +169,42 @@ func (r *InMemoryDiscountRepository) CreateDiscount(ctx context.Context, discoun
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if err := validateDiscount(discount); err != nil {
+		return err
+	}
+	if err := checkTenant(ctx, discount); err != nil {
+		return err
+	}
+
 	// Check if ID already exists
-	if _, exists := r.discounts[discount.ID]; exists {
+	if _, exists := r.discounts[tenant.Key(ctx, discount.ID)]; exists {
 		return errors.NewValidationError("discount already exists: " + discount.ID)
 	}
 
-	// Check if code already exists (for voucher discounts)
-	if discount.Code != "" {
-		if _, exists := r.codeIndex[discount.Code]; exists {
-			return errors.NewValidationError("discount code already exists: " + discount.Code)
-		}
+	// Check if a code or alias already exists
+	if err := r.checkCodes(ctx, discount); err != nil {
+		return err
+	}
+
+	// Derive a ref for discounts created without one, refusing refs already in use
+	if discount.Ref == "" {
+		discount.Ref = models.NewDiscountRef(tenant.FromContext(ctx), discount.ID)
+	}
+	if err := r.checkRef(ctx, discount); err != nil {
+		return err
 	}
 
 	// Create a copy to avoid external modifications
+	discount.Version = 1
 	discountCopy := *discount
-	r.discounts[discount.ID] = &discountCopy
+	discountCopy.TenantID = tenant.FromContext(ctx)
+	discountCopy.Compile()
+	r.discounts[tenant.Key(ctx, discount.ID)] = &discountCopy
+	r.candidates.add(tenant.Key(ctx, discount.ID), &discountCopy)
+	r.addRevision(tenant.Key(ctx, discount.ID), &discountCopy, clock.FromContext(ctx))
 
 	// Update code index if applicable
-	if discount.Code != "" {
-		r.codeIndex[discount.Code] = discount.ID
-	}
+	r.indexCodes(discountCopy.TenantID, &discountCopy)
 
 	return nil
 }
@@ -104,31 +214,50 @@ func (r *InMemoryDiscountRepository) UpdateDiscount(ctx context.Context, discoun
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if err := validateDiscount(discount); err != nil {
+		return err
+	}
+	if err := checkTenant(ctx, discount); err != nil {
+		return err
+	}
+
 	// Check if discount exists
-	existingDiscount, exists := r.discounts[discount.ID]
+	existingDiscount, exists := r.discounts[tenant.Key(ctx, discount.ID)]
 	if !exists {
 		return errors.NewNotFoundError("discount not found: " + discount.ID)
 	}
 
-	// Handle code changes
-	if existingDiscount.Code != discount.Code {
-		// Remove old code index
-		if existingDiscount.Code != "" {
-			delete(r.codeIndex, existingDiscount.Code)
-		}
+	// Refuse edits made against an older version than the stored one
+	if discount.Version != existingDiscount.Version {
+		return errors.NewConflictError(fmt.Sprintf("discount %s was changed concurrently: updating version %d, stored version is %d",
+			discount.ID, discount.Version, existingDiscount.Version))
+	}
 
-		// Add new code index
-		if discount.Code != "" {
-			if _, exists := r.codeIndex[discount.Code]; exists {
-				return errors.NewValidationError("discount code already exists: " + discount.Code)
-			}
-			r.codeIndex[discount.Code] = discount.ID
-		}
+	// Handle code changes, checking the new codes before touching the index so a rejected
+	// update leaves the old codes in place
+	if err := r.checkCodes(ctx, discount); err != nil {
+		return err
+	}
+
+	// The ref outlives renames, so updates keep the stored one
+	if discount.Ref == "" {
+		discount.Ref = existingDiscount.StableRef()
+	}
+	if discount.Ref != existingDiscount.StableRef() {
+		return errors.NewValidationError("discount ref cannot change: " + discount.ID)
 	}
 
 	// Update the discount
+	discount.Version++
 	discountCopy := *discount
-	r.discounts[discount.ID] = &discountCopy
+	discountCopy.TenantID = tenant.FromContext(ctx)
+	discountCopy.Compile()
+	r.unindexCodes(existingDiscount.TenantID, existingDiscount)
+	r.indexCodes(discountCopy.TenantID, &discountCopy)
+	r.candidates.remove(tenant.Key(ctx, discount.ID), existingDiscount)
+	r.discounts[tenant.Key(ctx, discount.ID)] = &discountCopy
+	r.candidates.add(tenant.Key(ctx, discount.ID), &discountCopy)
+	r.addRevision(tenant.Key(ctx, discount.ID), &discountCopy, clock.FromContext(ctx))
 
 	return nil
 }
@@ -138,18 +267,20 @@ func (r *InMemoryDiscountRepository) DeleteDiscount(ctx context.Context, id stri
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	discount, exists := r.discounts[id]
+	key := tenant.Key(ctx, id)
+	discount, exists := r.discounts[key]
 	if !exists {
 		return errors.NewNotFoundError("discount not found: " + id)
 	}
 
 	// Remove from code index if applicable
-	if discount.Code != "" {
-		delete(r.codeIndex, discount.Code)
-	}
+	r.unindexCodes(discount.TenantID, discount)
 
-	// Remove from main storage
-	delete(r.discounts, id)
+	// Remove from main storage, history included, so a discount created again under the
+	// ID starts over at version 1
+	r.candidates.remove(key, discount)
+	delete(r.discounts, key)
+	delete(r.revisions, key)
 
 	return nil
 }
@@ -159,7 +290,8 @@ func (r *InMemoryDiscountRepository) IncrementUsageCount(ctx context.Context, id
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	discount, exists := r.discounts[id]
+	key := tenant.Key(ctx, id)
+	discount, exists := r.discounts[key]
 	if !exists {
 		return errors.NewNotFoundError("discount not found: " + id)
 	}
@@ -167,23 +299,111 @@ func (r *InMemoryDiscountRepository) IncrementUsageCount(ctx context.Context, id
 	// Create a copy with incremented usage count
 	updatedDiscount := *discount
 	updatedDiscount.UsedCount++
-	r.discounts[id] = &updatedDiscount
+	r.discounts[key] = &updatedDiscount
 
 	return nil
 }
 
-// SeedDiscounts seeds the repository with initial discount data
+// CheckAndIncrementUsage increments the usage count unless the discount is at its usage
+// limit; the check and the write happen under one lock, so they act as a compare-and-set
+func (r *InMemoryDiscountRepository) CheckAndIncrementUsage(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := tenant.Key(ctx, id)
+	discount, exists := r.discounts[key]
+	if !exists {
+		return errors.NewNotFoundError("discount not found: " + id)
+	}
+
+	if discount.UsageLimit != 0 && discount.UsedCount >= discount.UsageLimit {
+		return errors.NewConflictError("discount usage limit reached: " + id)
+	}
+
+	updatedDiscount := *discount
+	updatedDiscount.UsedCount++
+	r.discounts[key] = &updatedDiscount
+
+	return nil
+}
+
+// ReleaseUsage gives back a use claimed with CheckAndIncrementUsage
+func (r *InMemoryDiscountRepository) ReleaseUsage(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := tenant.Key(ctx, id)
+	discount, exists := r.discounts[key]
+	if !exists {
+		return errors.NewNotFoundError("discount not found: " + id)
+	}
+	if discount.UsedCount == 0 {
+		return nil
+	}
+
+	updatedDiscount := *discount
+	updatedDiscount.UsedCount--
+	r.discounts[key] = &updatedDiscount
+
+	return nil
+}
+
+// AddUsage adds the uses to the usage counts under one lock, see interfaces.UsageAdder
+func (r *InMemoryDiscountRepository) AddUsage(ctx context.Context, uses map[string]int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, n := range uses {
+		if n < 0 {
+			return errors.NewValidationError(fmt.Sprintf("negative use count %d: %s", n, id))
+		}
+	}
+	for id, n := range uses {
+		key := tenant.Key(ctx, id)
+		discount, exists := r.discounts[key]
+		if !exists || n == 0 {
+			continue
+		}
+		updatedDiscount := *discount
+		updatedDiscount.UsedCount += n
+		r.discounts[key] = &updatedDiscount
+	}
+
+	return nil
+}
+
+// SeedDiscounts seeds the repository with initial discount data, each discount stored under
+// its own TenantID
 func (r *InMemoryDiscountRepository) SeedDiscounts(discounts []models.Discount) error {
+	// Seeds are not validated as a whole, but a schedule that cannot be parsed would leave
+	// the discount inactive without a word
+	for _, discount := range discounts {
+		if discount.Schedule != nil {
+			if err := discount.Schedule.Validate(); err != nil {
+				return errors.NewValidationError("invalid schedule: " + err.Error() + ": " + discount.ID)
+			}
+		}
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	for _, discount := range discounts {
 		discountCopy := discount
-		r.discounts[discount.ID] = &discountCopy
-
-		if discount.Code != "" {
-			r.codeIndex[discount.Code] = discount.ID
+		discountCopy.Ref = discount.StableRef()
+		discountCopy.Compile()
+		key := tenant.Scoped(discount.TenantID, discount.ID)
+		if existing, exists := r.discounts[key]; exists {
+			r.candidates.remove(key, existing)
+			r.unindexCodes(discount.TenantID, existing)
 		}
+		r.discounts[key] = &discountCopy
+		r.candidates.add(key, &discountCopy)
+		// Seeds have always been in effect, whatever their history before
+		delete(r.revisions, key)
+		r.addRevision(key, &discountCopy, time.Time{})
+
+		r.indexCodes(discount.TenantID, &discountCopy)
 	}
 
 	return nil
@@ -195,5 +415,172 @@ func (r *InMemoryDiscountRepository) ClearDiscounts() error {
 
 	r.discounts = make(map[string]*models.Discount)
 	r.codeIndex = make(map[string]string)
+	r.candidates = make(candidateIndex)
+	r.revisions = make(map[string][]models.DiscountRevision)
+	return nil
+}
+
+// ListRevisions returns the discount's revisions ordered by version, see
+// interfaces.DiscountHistory
+func (r *InMemoryDiscountRepository) ListRevisions(ctx context.Context, id string) ([]models.DiscountRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	revisions, exists := r.revisions[tenant.Key(ctx, id)]
+	if !exists {
+		return nil, errors.NewNotFoundError("discount not found: " + id)
+	}
+	return append([]models.DiscountRevision(nil), revisions...), nil
+}
+
+// GetDiscountAt returns the discount as defined at the instant, see interfaces.DiscountHistory
+func (r *InMemoryDiscountRepository) GetDiscountAt(ctx context.Context, id string, at time.Time) (*models.Discount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	revision, ok := models.RevisionAt(r.revisions[tenant.Key(ctx, id)], at)
+	if !ok {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("discount %s was not defined at %s", id, at.UTC().Format(time.RFC3339)))
+	}
+	discount := revision.Discount
+	return &discount, nil
+}
+
+// addRevision records the stored discount as a revision effective from the instant
+func (r *InMemoryDiscountRepository) addRevision(key string, discount *models.Discount, effectiveFrom time.Time) {
+	r.revisions[key] = append(r.revisions[key], models.DiscountRevision{
+		Version:       discount.Version,
+		EffectiveFrom: effectiveFrom,
+		Discount:      *discount,
+	})
+}
+
+// checkCodes rejects a discount whose code or aliases repeat each other or belong to
+// another discount of the context tenant
+func (r *InMemoryDiscountRepository) checkCodes(ctx context.Context, discount *models.Discount) error {
+	seen := make(map[string]bool)
+	for _, code := range discount.Codes() {
+		if code == "" {
+			return errors.NewValidationError("discount code alias cannot be empty: " + discount.ID)
+		}
+		if seen[code] {
+			return errors.NewValidationError("discount code repeated: " + code)
+		}
+		seen[code] = true
+
+		if id, exists := r.codeIndex[tenant.Key(ctx, code)]; exists && id != discount.ID {
+			return errors.NewValidationError("discount code already exists: " + code)
+		}
+	}
+	return nil
+}
+
+// checkRef rejects a ref carried by another of the context tenant's discounts
+func (r *InMemoryDiscountRepository) checkRef(ctx context.Context, discount *models.Discount) error {
+	tenantID := tenant.FromContext(ctx)
+	for _, other := range r.discounts {
+		if other.TenantID == tenantID && other.ID != discount.ID && other.StableRef() == discount.Ref {
+			return errors.NewValidationError(fmt.Sprintf("discount ref %s already belongs to %s", discount.Ref, other.ID))
+		}
+	}
+	return nil
+}
+
+// indexCodes maps the discount's code and aliases to its ID
+func (r *InMemoryDiscountRepository) indexCodes(tenantID string, discount *models.Discount) {
+	for _, code := range discount.Codes() {
+		r.codeIndex[tenant.Scoped(tenantID, code)] = discount.ID
+	}
+}
+
+// unindexCodes removes the discount's code and aliases from the code index
+func (r *InMemoryDiscountRepository) unindexCodes(tenantID string, discount *models.Discount) {
+	for _, code := range discount.Codes() {
+		delete(r.codeIndex, tenant.Scoped(tenantID, code))
+	}
+}
+
+// checkTenant rejects writes naming a tenant other than the context's
+func checkTenant(ctx context.Context, discount *models.Discount) error {
+	if discount.TenantID != "" && discount.TenantID != tenant.FromContext(ctx) {
+		return errors.NewValidationError("discount belongs to another tenant: " + discount.ID)
+	}
+	return nil
+}
+
+// validateDiscount rejects discounts that could never be priced correctly
+func validateDiscount(discount *models.Discount) error {
+	if discount.Schedule != nil {
+		if err := discount.Schedule.Validate(); err != nil {
+			return errors.NewValidationError("invalid schedule: " + err.Error())
+		}
+	}
+	if discount.Type == models.DiscountTypeReferral && (discount.Code == "" || discount.ReferrerID == "") {
+		return errors.NewValidationError("referral discount requires a code and a referrer: " + discount.ID)
+	}
+	currency, ok := models.LookupCurrency(discount.Currency)
+	if !ok {
+		return errors.NewValidationError(fmt.Sprintf("unknown currency %q: %s", discount.Currency, discount.ID))
+	}
+	if err := discount.CheckMinorUnits(currency); err != nil {
+		return errors.NewValidationError("invalid amount: " + err.Error())
+	}
+	if err := discount.CheckCardRules(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckPaymentMethods(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckCashback(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckUnitLimit(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckTargetSelection(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckBasis(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckIntroductoryOrders(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckSpendTiers(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckLifecycle(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckExperiment(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckSegments(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckRegions(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckCustomerBinding(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckOccasion(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckMembershipPlans(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckMonthlyCap(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckStockThresholds(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.CheckChannels(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
+	if err := discount.Rounding.Validate(); err != nil {
+		return errors.NewValidationError(err.Error() + ": " + discount.ID)
+	}
 	return nil
 }
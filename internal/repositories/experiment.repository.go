@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// InMemoryExperimentRepository implements IExperimentRepository using in-memory storage
+type InMemoryExperimentRepository struct {
+	assignments map[string]map[string]models.ExperimentAssignment // experiment id -> customer id -> assignment
+	mu          sync.RWMutex
+}
+
+// NewInMemoryExperimentRepository creates a new in-memory experiment repository
+func NewInMemoryExperimentRepository() interfaces.IExperimentRepository {
+	return &InMemoryExperimentRepository{
+		assignments: make(map[string]map[string]models.ExperimentAssignment),
+	}
+}
+
+// RecordAssignment stores the variant a customer was bucketed into. Customers keep
+// their first assignment; re-recording the same variant is a no-op.
+func (r *InMemoryExperimentRepository) RecordAssignment(ctx context.Context, assignment models.ExperimentAssignment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byCustomer, exists := r.assignments[tenant.Key(ctx, assignment.ExperimentID)]
+	if !exists {
+		byCustomer = make(map[string]models.ExperimentAssignment)
+		r.assignments[tenant.Key(ctx, assignment.ExperimentID)] = byCustomer
+	}
+
+	if existing, exists := byCustomer[assignment.CustomerID]; exists {
+		if existing.Variant != assignment.Variant {
+			return errors.NewValidationError("customer already assigned to variant " + existing.Variant + ": " + assignment.CustomerID)
+		}
+		return nil
+	}
+
+	byCustomer[assignment.CustomerID] = assignment
+	return nil
+}
+
+// ListAssignments returns every assignment of an experiment
+func (r *InMemoryExperimentRepository) ListAssignments(ctx context.Context, experimentID string) ([]models.ExperimentAssignment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	assignments := make([]models.ExperimentAssignment, 0, len(r.assignments[tenant.Key(ctx, experimentID)]))
+	for _, assignment := range r.assignments[tenant.Key(ctx, experimentID)] {
+		assignments = append(assignments, assignment)
+	}
+
+	return assignments, nil
+}
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+	"github.com/shopspring/decimal"
+)
+
+// InMemoryGiftCardRepository implements IGiftCardRepository using in-memory storage
+type InMemoryGiftCardRepository struct {
+	cards map[string]*models.GiftCard
+	mu    sync.RWMutex
+}
+
+// NewInMemoryGiftCardRepository creates a new in-memory gift card repository
+func NewInMemoryGiftCardRepository() interfaces.IGiftCardRepository {
+	return &InMemoryGiftCardRepository{
+		cards: make(map[string]*models.GiftCard),
+	}
+}
+
+// GetGiftCard retrieves a gift card by its code
+func (r *InMemoryGiftCardRepository) GetGiftCard(ctx context.Context, code string) (*models.GiftCard, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	card, exists := r.cards[tenant.Key(ctx, code)]
+	if !exists {
+		return nil, errors.NewNotFoundError("gift card not found: " + code)
+	}
+
+	cardCopy := *card
+	return &cardCopy, nil
+}
+
+// CreateGiftCard creates a new gift card
+func (r *InMemoryGiftCardRepository) CreateGiftCard(ctx context.Context, card *models.GiftCard) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if card.Code == "" {
+		return errors.NewValidationError("gift card code cannot be empty")
+	}
+	if card.Balance.IsNegative() {
+		return errors.NewValidationError("gift card balance cannot be negative: " + card.Code)
+	}
+	if _, exists := r.cards[tenant.Key(ctx, card.Code)]; exists {
+		return errors.NewValidationError("gift card already exists: " + card.Code)
+	}
+
+	cardCopy := *card
+	r.cards[tenant.Key(ctx, card.Code)] = &cardCopy
+	return nil
+}
+
+// Redeem atomically deducts amount from the card's balance
+func (r *InMemoryGiftCardRepository) Redeem(ctx context.Context, code string, amount decimal.Decimal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	card, exists := r.cards[tenant.Key(ctx, code)]
+	if !exists {
+		return errors.NewNotFoundError("gift card not found: " + code)
+	}
+	if amount.IsNegative() {
+		return errors.NewValidationError("cannot redeem a negative amount")
+	}
+	if !card.IsUsableAt(clock.FromContext(ctx)) {
+		return errors.NewValidationError("gift card is not usable: " + code)
+	}
+	if amount.GreaterThan(card.Balance) {
+		return errors.NewValidationError("insufficient gift card balance: " + code)
+	}
+
+	updatedCard := *card
+	updatedCard.Balance = updatedCard.Balance.Sub(amount)
+	r.cards[tenant.Key(ctx, code)] = &updatedCard
+	return nil
+}
+
+// Refund atomically returns amount to the card's balance
+func (r *InMemoryGiftCardRepository) Refund(ctx context.Context, code string, amount decimal.Decimal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	card, exists := r.cards[tenant.Key(ctx, code)]
+	if !exists {
+		return errors.NewNotFoundError("gift card not found: " + code)
+	}
+	if amount.IsNegative() {
+		return errors.NewValidationError("cannot refund a negative amount")
+	}
+
+	updatedCard := *card
+	updatedCard.Balance = updatedCard.Balance.Add(amount)
+	r.cards[tenant.Key(ctx, code)] = &updatedCard
+	return nil
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// InMemoryIdempotencyStore implements IIdempotencyStore using in-memory storage. Records
+// older than the TTL are forgotten, so keys can eventually be reused.
+type InMemoryIdempotencyStore struct {
+	records map[string]*models.IdempotencyRecord
+	ttl     time.Duration
+	mu      sync.Mutex
+}
+
+// NewInMemoryIdempotencyStore creates a new in-memory idempotency store; a zero ttl keeps
+// records forever
+func NewInMemoryIdempotencyStore(ttl time.Duration) interfaces.IIdempotencyStore {
+	return &InMemoryIdempotencyStore{
+		records: make(map[string]*models.IdempotencyRecord),
+		ttl:     ttl,
+	}
+}
+
+// Reserve claims key for a request with the given fingerprint
+func (s *InMemoryIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string) (*models.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := clock.FromContext(ctx)
+	if record, exists := s.records[tenant.Key(ctx, key)]; exists {
+		if s.ttl == 0 || now.Sub(record.CreatedAt) < s.ttl {
+			recordCopy := *record
+			return &recordCopy, false, nil
+		}
+	}
+
+	record := &models.IdempotencyRecord{Key: key, Fingerprint: fingerprint, CreatedAt: now}
+	s.records[tenant.Key(ctx, key)] = record
+
+	recordCopy := *record
+	return &recordCopy, true, nil
+}
+
+// Complete stores the result of the request that reserved key
+func (s *InMemoryIdempotencyStore) Complete(ctx context.Context, key string, result *models.DiscountedPrice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, exists := s.records[tenant.Key(ctx, key)]
+	if !exists {
+		return errors.NewNotFoundError("idempotency key not reserved: " + key)
+	}
+
+	updatedRecord := *record
+	updatedRecord.Result = result
+	s.records[tenant.Key(ctx, key)] = &updatedRecord
+	return nil
+}
+
+// Release forgets key
+func (s *InMemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, tenant.Key(ctx, key))
+	return nil
+}
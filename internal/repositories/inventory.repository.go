@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// InMemoryInventory implements InventoryProvider over stock levels held in memory, for
+// tests and deployments without an inventory system
+type InMemoryInventory struct {
+	stock map[string]int
+	mu    sync.RWMutex
+}
+
+// NewInMemoryInventory creates an inventory starting from the given stock levels by
+// product ID, which belong to the default tenant
+func NewInMemoryInventory(stock map[string]int) *InMemoryInventory {
+	inv := &InMemoryInventory{stock: make(map[string]int, len(stock))}
+	for id, n := range stock {
+		inv.stock[id] = n
+	}
+	return inv
+}
+
+var _ interfaces.InventoryProvider = (*InMemoryInventory)(nil)
+
+// GetStock returns the stock of the context tenant's products that are tracked
+func (inv *InMemoryInventory) GetStock(ctx context.Context, productIDs []string) (map[string]int, error) {
+	inv.mu.RLock()
+	defer inv.mu.RUnlock()
+
+	stock := make(map[string]int, len(productIDs))
+	for _, id := range productIDs {
+		if n, ok := inv.stock[tenant.Key(ctx, id)]; ok {
+			stock[id] = n
+		}
+	}
+	return stock, nil
+}
+
+// SetStock sets the stock of a product of the context tenant
+func (inv *InMemoryInventory) SetStock(ctx context.Context, productID string, stock int) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.stock[tenant.Key(ctx, productID)] = stock
+}
@@ -0,0 +1,230 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// jobCartsPerPush bounds the carts sent to Redis in one command when a job is created
+const jobCartsPerPush = 1000
+
+// RedisJobRepository implements IJobRepository on Redis, so jobs survive restarts and
+// can be resumed by any instance. Each job is a JSON string next to a list of its carts
+// and a hash of its results by cart index; the keys of unfinished jobs are kept in a set.
+type RedisJobRepository struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedisJobRepository creates a job repository storing its keys under prefix,
+// namespaced by tenant for every tenant but the default
+func NewRedisJobRepository(client redis.Cmdable, prefix string) interfaces.IJobRepository {
+	return &RedisJobRepository{client: client, prefix: prefix}
+}
+
+func (r *RedisJobRepository) key(tenantID, id string) string {
+	if tenantID != tenant.Default {
+		return r.prefix + "tenant:" + tenantID + ":job:" + id
+	}
+	return r.prefix + "job:" + id
+}
+
+func (r *RedisJobRepository) unfinishedKey() string {
+	return r.prefix + "jobs:unfinished"
+}
+
+// CreateJob stores a new job with its carts
+func (r *RedisJobRepository) CreateJob(ctx context.Context, job *models.Job, carts []*models.CalculationRequest) error {
+	stored := *job
+	stored.TenantID = tenant.FromContext(ctx)
+	key := r.key(stored.TenantID, job.ID)
+
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return errors.NewInternalError("failed to encode job", err)
+	}
+	created, err := r.client.SetNX(ctx, key, data, 0).Result()
+	if err != nil {
+		return errors.NewInternalError("failed to create job", err)
+	}
+	if !created {
+		return errors.NewConflictError("job already exists: " + job.ID)
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for start := 0; start < len(carts); start += jobCartsPerPush {
+			batch := carts[start:min(start+jobCartsPerPush, len(carts))]
+			values := make([]any, len(batch))
+			for i, cart := range batch {
+				encoded, err := json.Marshal(cart)
+				if err != nil {
+					return err
+				}
+				values[i] = encoded
+			}
+			pipe.RPush(ctx, key+":carts", values...)
+		}
+		if !stored.Finished() {
+			pipe.SAdd(ctx, r.unfinishedKey(), key)
+		}
+		return nil
+	})
+	if err != nil {
+		r.client.Del(ctx, key, key+":carts")
+		return errors.NewInternalError("failed to store job carts", err)
+	}
+	return nil
+}
+
+// GetJob retrieves a job by its ID
+func (r *RedisJobRepository) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	return r.load(ctx, r.key(tenant.FromContext(ctx), id), id)
+}
+
+func (r *RedisJobRepository) load(ctx context.Context, key, id string) (*models.Job, error) {
+	data, err := r.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, errors.NewNotFoundError("job not found: " + id)
+	}
+	if err != nil {
+		return nil, errors.NewInternalError("failed to read job", err)
+	}
+
+	var job models.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, errors.NewInternalError("failed to decode job", err)
+	}
+	return &job, nil
+}
+
+// GetJobCarts returns a window of the job's carts
+func (r *RedisJobRepository) GetJobCarts(ctx context.Context, id string, offset, limit int) ([]*models.CalculationRequest, error) {
+	job, err := r.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	from, to := window(job.Total, offset, limit)
+	if from == to {
+		return nil, nil
+	}
+
+	values, err := r.client.LRange(ctx, r.key(job.TenantID, id)+":carts", int64(from), int64(to-1)).Result()
+	if err != nil {
+		return nil, errors.NewInternalError("failed to read job carts", err)
+	}
+	carts := make([]*models.CalculationRequest, len(values))
+	for i, value := range values {
+		if err := json.Unmarshal([]byte(value), &carts[i]); err != nil {
+			return nil, errors.NewInternalError("failed to decode job cart", err)
+		}
+	}
+	return carts, nil
+}
+
+// SaveJobProgress stores the results and the job in one transaction
+func (r *RedisJobRepository) SaveJobProgress(ctx context.Context, job *models.Job, results []models.JobResult) error {
+	stored, err := r.GetJob(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if result.Index < 0 || result.Index >= stored.Total {
+			return errors.NewValidationError("job has no cart at the result's index")
+		}
+	}
+
+	updated := *job
+	updated.TenantID = stored.TenantID
+	key := r.key(updated.TenantID, job.ID)
+	data, err := json.Marshal(&updated)
+	if err != nil {
+		return errors.NewInternalError("failed to encode job", err)
+	}
+	fields := make(map[string]any, len(results))
+	for _, result := range results {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return errors.NewInternalError("failed to encode job result", err)
+		}
+		fields[strconv.Itoa(result.Index)] = encoded
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(fields) > 0 {
+			pipe.HSet(ctx, key+":results", fields)
+		}
+		pipe.Set(ctx, key, data, 0)
+		if updated.Finished() {
+			pipe.SRem(ctx, r.unfinishedKey(), key)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.NewInternalError("failed to save job progress", err)
+	}
+	return nil
+}
+
+// GetJobResults returns the stored results within a window of the job's carts
+func (r *RedisJobRepository) GetJobResults(ctx context.Context, id string, offset, limit int) ([]models.JobResult, error) {
+	job, err := r.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	from, to := window(job.Total, offset, limit)
+	if from == to {
+		return nil, nil
+	}
+
+	fields := make([]string, 0, to-from)
+	for i := from; i < to; i++ {
+		fields = append(fields, strconv.Itoa(i))
+	}
+	values, err := r.client.HMGet(ctx, r.key(job.TenantID, id)+":results", fields...).Result()
+	if err != nil {
+		return nil, errors.NewInternalError("failed to read job results", err)
+	}
+
+	results := make([]models.JobResult, 0, len(values))
+	for _, value := range values {
+		encoded, ok := value.(string)
+		if !ok {
+			continue // Not processed yet
+		}
+		var result models.JobResult
+		if err := json.Unmarshal([]byte(encoded), &result); err != nil {
+			return nil, errors.NewInternalError("failed to decode job result", err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// ListUnfinishedJobs returns the queued and running jobs of every tenant
+func (r *RedisJobRepository) ListUnfinishedJobs(ctx context.Context) ([]*models.Job, error) {
+	keys, err := r.client.SMembers(ctx, r.unfinishedKey()).Result()
+	if err != nil {
+		return nil, errors.NewInternalError("failed to list unfinished jobs", err)
+	}
+
+	jobs := make([]*models.Job, 0, len(keys))
+	for _, key := range keys {
+		job, err := r.load(ctx, key, key)
+		if errors.IsNotFoundError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
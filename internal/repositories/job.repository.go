@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// InMemoryJobRepository implements IJobRepository using in-memory storage. Jobs only
+// resume after a restart with a durable repository such as RedisJobRepository.
+type InMemoryJobRepository struct {
+	jobs map[string]*storedJob
+	mu   sync.RWMutex
+}
+
+type storedJob struct {
+	job     models.Job
+	carts   []*models.CalculationRequest
+	results map[int]models.JobResult // By cart index
+}
+
+// NewInMemoryJobRepository creates a new in-memory job repository
+func NewInMemoryJobRepository() interfaces.IJobRepository {
+	return &InMemoryJobRepository{jobs: make(map[string]*storedJob)}
+}
+
+// CreateJob stores a new job with its carts
+func (r *InMemoryJobRepository) CreateJob(ctx context.Context, job *models.Job, carts []*models.CalculationRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := tenant.Key(ctx, job.ID)
+	if _, exists := r.jobs[key]; exists {
+		return errors.NewConflictError("job already exists: " + job.ID)
+	}
+
+	stored := &storedJob{
+		job:     *job,
+		carts:   append([]*models.CalculationRequest(nil), carts...),
+		results: make(map[int]models.JobResult),
+	}
+	stored.job.TenantID = tenant.FromContext(ctx)
+	r.jobs[key] = stored
+	return nil
+}
+
+// GetJob retrieves a job by its ID
+func (r *InMemoryJobRepository) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, err := r.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	job := stored.job
+	return &job, nil
+}
+
+// GetJobCarts returns a window of the job's carts
+func (r *InMemoryJobRepository) GetJobCarts(ctx context.Context, id string, offset, limit int) ([]*models.CalculationRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, err := r.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	from, to := window(len(stored.carts), offset, limit)
+	return append([]*models.CalculationRequest(nil), stored.carts[from:to]...), nil
+}
+
+// SaveJobProgress stores the results and the job in one step
+func (r *InMemoryJobRepository) SaveJobProgress(ctx context.Context, job *models.Job, results []models.JobResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.get(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if result.Index < 0 || result.Index >= len(stored.carts) {
+			return errors.NewValidationError("job has no cart at the result's index")
+		}
+	}
+
+	for _, result := range results {
+		stored.results[result.Index] = result
+	}
+	tenantID := stored.job.TenantID
+	stored.job = *job
+	stored.job.TenantID = tenantID
+	return nil
+}
+
+// GetJobResults returns the stored results within a window of the job's carts
+func (r *InMemoryJobRepository) GetJobResults(ctx context.Context, id string, offset, limit int) ([]models.JobResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, err := r.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	from, to := window(len(stored.carts), offset, limit)
+	results := make([]models.JobResult, 0, to-from)
+	for i := from; i < to; i++ {
+		if result, ok := stored.results[i]; ok {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// ListUnfinishedJobs returns the queued and running jobs of every tenant
+func (r *InMemoryJobRepository) ListUnfinishedJobs(ctx context.Context) ([]*models.Job, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var jobs []*models.Job
+	for _, stored := range r.jobs {
+		if !stored.job.Finished() {
+			job := stored.job
+			jobs = append(jobs, &job)
+		}
+	}
+	return jobs, nil
+}
+
+func (r *InMemoryJobRepository) get(ctx context.Context, id string) (*storedJob, error) {
+	stored, exists := r.jobs[tenant.Key(ctx, id)]
+	if !exists {
+		return nil, errors.NewNotFoundError("job not found: " + id)
+	}
+	return stored, nil
+}
+
+// window clamps [offset, offset+limit) to a list of n entries
+func window(n, offset, limit int) (from, to int) {
+	from = min(max(offset, 0), n)
+	to = min(from+max(limit, 0), n)
+	return from, to
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// InMemoryLoyaltyProvider implements ILoyaltyProvider with in-memory balances, for tests
+// and deployments without an external loyalty programme
+type InMemoryLoyaltyProvider struct {
+	balances map[string]int64
+	mu       sync.Mutex
+}
+
+// NewInMemoryLoyaltyProvider creates a loyalty provider starting from the given balances,
+// which belong to the default tenant
+func NewInMemoryLoyaltyProvider(balances map[string]int64) *InMemoryLoyaltyProvider {
+	p := &InMemoryLoyaltyProvider{balances: make(map[string]int64, len(balances))}
+	for customerID, points := range balances {
+		p.balances[customerID] = points
+	}
+	return p
+}
+
+var _ interfaces.ILoyaltyProvider = (*InMemoryLoyaltyProvider)(nil)
+
+// GetPointsBalance returns the customer's redeemable points
+func (p *InMemoryLoyaltyProvider) GetPointsBalance(ctx context.Context, customerID string) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.balances[tenant.Key(ctx, customerID)], nil
+}
+
+// RedeemPoints deducts points from the customer's balance
+func (p *InMemoryLoyaltyProvider) RedeemPoints(ctx context.Context, customerID string, points int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if points < 0 {
+		return errors.NewValidationError("cannot redeem a negative number of points")
+	}
+	if p.balances[tenant.Key(ctx, customerID)] < points {
+		return errors.NewValidationError("insufficient points balance: " + customerID)
+	}
+
+	p.balances[tenant.Key(ctx, customerID)] -= points
+	return nil
+}
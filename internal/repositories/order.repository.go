@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// InMemoryOrderRepository implements IOrderRepository using in-memory storage
+type InMemoryOrderRepository struct {
+	orders map[string][]models.Order // tenant id -> orders in recording order
+	mu     sync.RWMutex
+}
+
+// NewInMemoryOrderRepository creates a new in-memory order repository
+func NewInMemoryOrderRepository() interfaces.IOrderRepository {
+	return &InMemoryOrderRepository{
+		orders: make(map[string][]models.Order),
+	}
+}
+
+// RecordOrder stores a placed order
+func (r *InMemoryOrderRepository) RecordOrder(ctx context.Context, order models.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenantID := tenant.FromContext(ctx)
+	r.orders[tenantID] = append(r.orders[tenantID], order)
+	return nil
+}
+
+// ListOrders returns the orders placed in [from, to); zero bounds are open
+func (r *InMemoryOrderRepository) ListOrders(ctx context.Context, from, to time.Time) ([]models.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var orders []models.Order
+	for _, order := range r.orders[tenant.FromContext(ctx)] {
+		if !from.IsZero() && order.PlacedAt.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !order.PlacedAt.Before(to) {
+			continue
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
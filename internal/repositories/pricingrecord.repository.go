@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// InMemoryPricingRecordRepository implements IPricingRecordRepository using in-memory storage
+type InMemoryPricingRecordRepository struct {
+	records map[string]models.OrderPricingRecord // scoped order id -> record
+	mu      sync.RWMutex
+}
+
+// NewInMemoryPricingRecordRepository creates a new in-memory pricing record repository
+func NewInMemoryPricingRecordRepository() interfaces.IPricingRecordRepository {
+	return &InMemoryPricingRecordRepository{
+		records: make(map[string]models.OrderPricingRecord),
+	}
+}
+
+// SavePricingRecord stores the order's pricing record, replacing an earlier one
+func (r *InMemoryPricingRecordRepository) SavePricingRecord(ctx context.Context, record models.OrderPricingRecord) error {
+	if record.OrderID == "" {
+		return errors.NewValidationError("pricing record needs an order id")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records[tenant.Key(ctx, record.OrderID)] = record
+	return nil
+}
+
+// GetPricingRecord returns the order's pricing record
+func (r *InMemoryPricingRecordRepository) GetPricingRecord(ctx context.Context, orderID string) (*models.OrderPricingRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record, ok := r.records[tenant.Key(ctx, orderID)]
+	if !ok {
+		return nil, errors.NewNotFoundError("no pricing record for order: " + orderID)
+	}
+	return &record, nil
+}
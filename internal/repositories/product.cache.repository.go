@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// DefaultProductCacheSize is how many products a CachedProductCatalog holds when its
+// MaxEntries is zero
+const DefaultProductCacheSize = 10000
+
+// CachedProductCatalog keeps the products a catalog returned for TTL, so carts of popular
+// products are priced without a catalog call. Only the products missing from the cache
+// are requested, in one call; products the catalog does not hold are asked for again
+// every time. Price changes reach checkouts once the cached copy expires. A full cache
+// drops its least recently used product.
+type CachedProductCatalog struct {
+	catalog interfaces.ProductCatalog
+	ttl     time.Duration
+
+	MaxEntries int         // Products held at most, DefaultProductCacheSize when zero
+	Clock      clock.Clock // Defaults to the system clock
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // tenant-scoped product id -> element of recent
+	recent  *list.List               // of *cachedProduct, most recently used first
+}
+
+type cachedProduct struct {
+	key       string
+	product   models.Product
+	expiresAt time.Time
+}
+
+var _ interfaces.ProductCatalog = (*CachedProductCatalog)(nil)
+
+// NewCachedProductCatalog caches the products of catalog for ttl
+func NewCachedProductCatalog(catalog interfaces.ProductCatalog, ttl time.Duration) *CachedProductCatalog {
+	return &CachedProductCatalog{
+		catalog: catalog,
+		ttl:     ttl,
+		Clock:   clock.System(),
+		entries: make(map[string]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// GetProducts returns the cached products and fetches the others from the catalog
+func (c *CachedProductCatalog) GetProducts(ctx context.Context, ids []string) (map[string]models.Product, error) {
+	products := make(map[string]models.Product, len(ids))
+	var missing []string
+
+	c.mu.Lock()
+	now := c.Clock.Now()
+	for _, id := range ids {
+		element, ok := c.entries[tenant.Key(ctx, id)]
+		if ok && now.Before(element.Value.(*cachedProduct).expiresAt) {
+			c.recent.MoveToFront(element)
+			products[id] = element.Value.(*cachedProduct).product
+			continue
+		}
+		missing = append(missing, id)
+	}
+	c.mu.Unlock()
+	if len(missing) == 0 {
+		return products, nil
+	}
+
+	fetched, err := c.catalog.GetProducts(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, product := range fetched {
+		products[id] = product
+		c.put(&cachedProduct{key: tenant.Key(ctx, id), product: product, expiresAt: now.Add(c.ttl)})
+	}
+	return products, nil
+}
+
+// Invalidate drops the context tenant's cached copies of the products, e.g. after a price
+// change that must reach checkouts at once
+func (c *CachedProductCatalog) Invalidate(ctx context.Context, ids ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		if element, ok := c.entries[tenant.Key(ctx, id)]; ok {
+			c.remove(element)
+		}
+	}
+}
+
+// put caches the product as the most recently used, dropping the least recently used
+// products while the cache is over its size
+func (c *CachedProductCatalog) put(entry *cachedProduct) {
+	if element, ok := c.entries[entry.key]; ok {
+		element.Value = entry
+		c.recent.MoveToFront(element)
+	} else {
+		c.entries[entry.key] = c.recent.PushFront(entry)
+	}
+	for len(c.entries) > c.maxEntries() {
+		c.remove(c.recent.Back())
+	}
+}
+
+func (c *CachedProductCatalog) remove(element *list.Element) {
+	c.recent.Remove(element)
+	delete(c.entries, element.Value.(*cachedProduct).key)
+}
+
+func (c *CachedProductCatalog) maxEntries() int {
+	if c.MaxEntries <= 0 {
+		return DefaultProductCacheSize
+	}
+	return c.MaxEntries
+}
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// InMemoryProductCatalog implements ProductCatalog over products held in memory, for tests
+// and deployments whose catalog fits in the process
+type InMemoryProductCatalog struct {
+	products map[string]models.Product
+	mu       sync.RWMutex
+}
+
+// NewInMemoryProductCatalog creates a catalog holding the given products, which belong to
+// the default tenant
+func NewInMemoryProductCatalog(products ...models.Product) *InMemoryProductCatalog {
+	c := &InMemoryProductCatalog{products: make(map[string]models.Product, len(products))}
+	for _, p := range products {
+		c.products[p.ID] = p
+	}
+	return c
+}
+
+var _ interfaces.ProductCatalog = (*InMemoryProductCatalog)(nil)
+
+// GetProducts returns the context tenant's products with the given IDs that exist
+func (c *InMemoryProductCatalog) GetProducts(ctx context.Context, ids []string) (map[string]models.Product, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	products := make(map[string]models.Product, len(ids))
+	for _, id := range ids {
+		if p, ok := c.products[tenant.Key(ctx, id)]; ok {
+			products[id] = p
+		}
+	}
+	return products, nil
+}
+
+// PutProduct adds or replaces a product of the context tenant
+func (c *InMemoryProductCatalog) PutProduct(ctx context.Context, product models.Product) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.products[tenant.Key(ctx, product.ID)] = product
+}
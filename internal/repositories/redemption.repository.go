@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// InMemoryRedemptionRepository implements IRedemptionRepository using in-memory storage
+type InMemoryRedemptionRepository struct {
+	redemptions map[string][]models.Redemption // tenant id -> redemptions in recording order
+	mu          sync.RWMutex
+}
+
+// NewInMemoryRedemptionRepository creates a new in-memory redemption repository
+func NewInMemoryRedemptionRepository() interfaces.IRedemptionRepository {
+	return &InMemoryRedemptionRepository{
+		redemptions: make(map[string][]models.Redemption),
+	}
+}
+
+// RecordRedemption stores a redemption
+func (r *InMemoryRedemptionRepository) RecordRedemption(ctx context.Context, redemption models.Redemption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenantID := tenant.FromContext(ctx)
+	r.redemptions[tenantID] = append(r.redemptions[tenantID], redemption)
+	return nil
+}
+
+// ListRedemptions returns the redemptions matching the filter in recording order
+func (r *InMemoryRedemptionRepository) ListRedemptions(ctx context.Context, filter models.RedemptionFilter) ([]models.Redemption, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var redemptions []models.Redemption
+	for _, redemption := range r.redemptions[tenant.FromContext(ctx)] {
+		if filter.Matches(redemption) {
+			redemptions = append(redemptions, redemption)
+		}
+	}
+
+	return redemptions, nil
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// InMemoryReferralLedger implements IReferralLedger using in-memory storage
+type InMemoryReferralLedger struct {
+	rewards map[string][]models.ReferralReward // tenant id -> rewards in recording order
+	mu      sync.RWMutex
+}
+
+// NewInMemoryReferralLedger creates a new in-memory referral ledger
+func NewInMemoryReferralLedger() interfaces.IReferralLedger {
+	return &InMemoryReferralLedger{
+		rewards: make(map[string][]models.ReferralReward),
+	}
+}
+
+// RecordReward stores a referral reward
+func (l *InMemoryReferralLedger) RecordReward(ctx context.Context, reward models.ReferralReward) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tenantID := tenant.FromContext(ctx)
+	l.rewards[tenantID] = append(l.rewards[tenantID], reward)
+	return nil
+}
+
+// ListRewards returns the referrer's rewards in recording order
+func (l *InMemoryReferralLedger) ListRewards(ctx context.Context, referrerID string) ([]models.ReferralReward, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var rewards []models.ReferralReward
+	for _, reward := range l.rewards[tenant.FromContext(ctx)] {
+		if reward.ReferrerID == referrerID {
+			rewards = append(rewards, reward)
+		}
+	}
+
+	return rewards, nil
+}
@@ -0,0 +1,594 @@
+// Package repositorytest holds conformance suites that every repository implementation must
+// pass, keeping the memory and any persistent backends behaviourally identical. Backends run
+// a suite from their own tests:
+//
+//	repositorytest.Run(t, func(t *testing.T) interfaces.IDiscountRepository {
+//		return newBackend(t)
+//	})
+package repositorytest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// Factory returns an empty repository; it is called once per test case
+type Factory func(t *testing.T) interfaces.IDiscountRepository
+
+// epoch anchors every discount the suite creates, so validity does not depend on wall time
+var epoch = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// at pins the instant repositories observe
+func at(now time.Time) context.Context {
+	return clock.NewContext(context.Background(), now)
+}
+
+func newDiscount(id, code string) *models.Discount {
+	return &models.Discount{
+		ID:           id,
+		Name:         "Discount " + id,
+		Type:         models.DiscountTypeVoucher,
+		Value:        decimal.NewFromInt(10),
+		IsPercentage: true,
+		Code:         code,
+		ValidFrom:    epoch.Add(-time.Hour),
+		ValidTo:      epoch.Add(time.Hour),
+		IsActive:     true,
+		Priority:     10,
+	}
+}
+
+// Run exercises every IDiscountRepository behaviour against repositories from factory
+func Run(t *testing.T, factory Factory) {
+	t.Run("CreateAndGet", func(t *testing.T) { testCreateAndGet(t, factory(t)) })
+	t.Run("StoredCopiesAreIsolated", func(t *testing.T) { testIsolation(t, factory(t)) })
+	t.Run("IDUniqueness", func(t *testing.T) { testIDUniqueness(t, factory(t)) })
+	t.Run("CodeUniqueness", func(t *testing.T) { testCodeUniqueness(t, factory(t)) })
+	t.Run("UpdateReindexesCodes", func(t *testing.T) { testUpdateCodes(t, factory(t)) })
+	t.Run("CodeAliases", func(t *testing.T) { testCodeAliases(t, factory(t)) })
+	t.Run("OptimisticVersions", func(t *testing.T) { testVersions(t, factory(t)) })
+	t.Run("UpdateMissing", func(t *testing.T) { testUpdateMissing(t, factory(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, factory(t)) })
+	t.Run("ActiveDiscountsFollowClock", func(t *testing.T) { testActiveDiscounts(t, factory(t)) })
+	t.Run("ConcurrentUsageCounts", func(t *testing.T) { testConcurrentUsage(t, factory(t)) })
+	t.Run("UsageLimitHoldsUnderContention", func(t *testing.T) { testCheckAndIncrementUsage(t, factory(t)) })
+	t.Run("TenantIsolation", func(t *testing.T) { testTenantIsolation(t, factory(t)) })
+	t.Run("ApplicableDiscounts", func(t *testing.T) { testApplicableDiscounts(t, factory(t)) })
+	t.Run("DiscountFilters", func(t *testing.T) { testDiscountFilters(t, factory(t)) })
+	t.Run("ListDiscounts", func(t *testing.T) { testListDiscounts(t, factory(t)) })
+	t.Run("Revisions", func(t *testing.T) { testRevisions(t, factory(t)) })
+}
+
+func testCreateAndGet(t *testing.T, repo interfaces.IDiscountRepository) {
+	ctx := at(epoch)
+	require.NoError(t, repo.CreateDiscount(ctx, newDiscount("d1", "CODE1")))
+
+	byID, err := repo.GetDiscountByID(ctx, "d1")
+	require.NoError(t, err)
+	assert.Equal(t, "CODE1", byID.Code)
+	assert.True(t, decimal.NewFromInt(10).Equal(byID.Value))
+
+	byCode, err := repo.GetDiscountByCode(ctx, "CODE1")
+	require.NoError(t, err)
+	assert.Equal(t, "d1", byCode.ID)
+
+	_, err = repo.GetDiscountByID(ctx, "missing")
+	assert.True(t, errors.IsNotFoundError(err))
+	_, err = repo.GetDiscountByCode(ctx, "MISSING")
+	assert.True(t, errors.IsNotFoundError(err))
+}
+
+func testIsolation(t *testing.T, repo interfaces.IDiscountRepository) {
+	ctx := at(epoch)
+	discount := newDiscount("d1", "CODE1")
+	require.NoError(t, repo.CreateDiscount(ctx, discount))
+
+	discount.Name = "changed after create"
+	fetched, err := repo.GetDiscountByID(ctx, "d1")
+	require.NoError(t, err)
+	assert.Equal(t, "Discount d1", fetched.Name)
+
+	fetched.Name = "changed after get"
+	again, err := repo.GetDiscountByID(ctx, "d1")
+	require.NoError(t, err)
+	assert.Equal(t, "Discount d1", again.Name)
+}
+
+func testIDUniqueness(t *testing.T, repo interfaces.IDiscountRepository) {
+	ctx := at(epoch)
+	require.NoError(t, repo.CreateDiscount(ctx, newDiscount("d1", "")))
+	err := repo.CreateDiscount(ctx, newDiscount("d1", ""))
+	assert.True(t, errors.IsValidationError(err))
+}
+
+func testCodeUniqueness(t *testing.T, repo interfaces.IDiscountRepository) {
+	ctx := at(epoch)
+	require.NoError(t, repo.CreateDiscount(ctx, newDiscount("d1", "SAME")))
+
+	err := repo.CreateDiscount(ctx, newDiscount("d2", "SAME"))
+	assert.True(t, errors.IsValidationError(err))
+	_, err = repo.GetDiscountByID(ctx, "d2")
+	assert.True(t, errors.IsNotFoundError(err), "a rejected discount must not be stored")
+
+	require.NoError(t, repo.CreateDiscount(ctx, newDiscount("d3", "OTHER")))
+	taken := newDiscount("d3", "SAME")
+	taken.Version = 1
+	err = repo.UpdateDiscount(ctx, taken)
+	assert.True(t, errors.IsValidationError(err))
+
+	byCode, err := repo.GetDiscountByCode(ctx, "SAME")
+	require.NoError(t, err)
+	assert.Equal(t, "d1", byCode.ID)
+
+	byCode, err = repo.GetDiscountByCode(ctx, "OTHER")
+	require.NoError(t, err, "a rejected update must keep the old code")
+	assert.Equal(t, "d3", byCode.ID)
+}
+
+func testUpdateCodes(t *testing.T, repo interfaces.IDiscountRepository) {
+	ctx := at(epoch)
+	require.NoError(t, repo.CreateDiscount(ctx, newDiscount("d1", "OLD")))
+	renamed := newDiscount("d1", "NEW")
+	renamed.Version = 1
+	require.NoError(t, repo.UpdateDiscount(ctx, renamed))
+
+	_, err := repo.GetDiscountByCode(ctx, "OLD")
+	assert.True(t, errors.IsNotFoundError(err))
+	byCode, err := repo.GetDiscountByCode(ctx, "NEW")
+	require.NoError(t, err)
+	assert.Equal(t, "d1", byCode.ID)
+
+	// The released code can be claimed again
+	assert.NoError(t, repo.CreateDiscount(ctx, newDiscount("d2", "OLD")))
+}
+
+func testCodeAliases(t *testing.T, repo interfaces.IDiscountRepository) {
+	ctx := at(epoch)
+	d1 := newDiscount("d1", "MAIN")
+	d1.CodeAliases = []string{"ANNA10", "BEN10"}
+	require.NoError(t, repo.CreateDiscount(ctx, d1))
+
+	for _, code := range []string{"MAIN", "ANNA10", "BEN10"} {
+		byCode, err := repo.GetDiscountByCode(ctx, code)
+		require.NoError(t, err, code)
+		assert.Equal(t, "d1", byCode.ID)
+	}
+
+	d2 := newDiscount("d2", "")
+	d2.CodeAliases = []string{"BEN10"}
+	assert.True(t, errors.IsValidationError(repo.CreateDiscount(ctx, d2)), "aliases share the code namespace")
+	d2.CodeAliases = []string{"CARA10", "CARA10"}
+	assert.True(t, errors.IsValidationError(repo.CreateDiscount(ctx, d2)), "aliases cannot repeat")
+
+	d1.CodeAliases = []string{"ANNA10", "DAN10"}
+	require.NoError(t, repo.UpdateDiscount(ctx, d1))
+	_, err := repo.GetDiscountByCode(ctx, "BEN10")
+	assert.True(t, errors.IsNotFoundError(err), "dropped aliases are released")
+	byCode, err := repo.GetDiscountByCode(ctx, "DAN10")
+	require.NoError(t, err)
+	assert.Equal(t, "d1", byCode.ID)
+
+	require.NoError(t, repo.DeleteDiscount(ctx, "d1"))
+	_, err = repo.GetDiscountByCode(ctx, "ANNA10")
+	assert.True(t, errors.IsNotFoundError(err))
+}
+
+func testVersions(t *testing.T, repo interfaces.IDiscountRepository) {
+	ctx := at(epoch)
+	d := newDiscount("d1", "")
+	require.NoError(t, repo.CreateDiscount(ctx, d))
+	assert.Equal(t, 1, d.Version, "creating sets the first version")
+
+	first, err := repo.GetDiscountByID(ctx, "d1")
+	require.NoError(t, err)
+	second := *first
+
+	first.Name = "first edit"
+	require.NoError(t, repo.UpdateDiscount(ctx, first))
+	assert.Equal(t, 2, first.Version, "updating sets the new version")
+
+	second.Name = "second edit"
+	err = repo.UpdateDiscount(ctx, &second)
+	assert.True(t, errors.IsConflictError(err), "an edit of an older version must not overwrite a newer one")
+
+	stored, err := repo.GetDiscountByID(ctx, "d1")
+	require.NoError(t, err)
+	assert.Equal(t, "first edit", stored.Name)
+	assert.Equal(t, 2, stored.Version)
+
+	require.NoError(t, repo.CheckAndIncrementUsage(ctx, "d1"))
+	first.Name = "after use"
+	assert.NoError(t, repo.UpdateDiscount(ctx, first), "usage does not change the version")
+}
+
+func testUpdateMissing(t *testing.T, repo interfaces.IDiscountRepository) {
+	err := repo.UpdateDiscount(at(epoch), newDiscount("missing", ""))
+	assert.True(t, errors.IsNotFoundError(err))
+}
+
+func testDelete(t *testing.T, repo interfaces.IDiscountRepository) {
+	ctx := at(epoch)
+	require.NoError(t, repo.CreateDiscount(ctx, newDiscount("d1", "CODE1")))
+	require.NoError(t, repo.DeleteDiscount(ctx, "d1"))
+
+	_, err := repo.GetDiscountByID(ctx, "d1")
+	assert.True(t, errors.IsNotFoundError(err))
+	_, err = repo.GetDiscountByCode(ctx, "CODE1")
+	assert.True(t, errors.IsNotFoundError(err))
+
+	active, err := repo.GetActiveDiscounts(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, active)
+
+	assert.True(t, errors.IsNotFoundError(repo.DeleteDiscount(ctx, "d1")))
+	assert.NoError(t, repo.CreateDiscount(ctx, newDiscount("d2", "CODE1")), "deleted codes are released")
+}
+
+func testActiveDiscounts(t *testing.T, repo interfaces.IDiscountRepository) {
+	ctx := at(epoch)
+	require.NoError(t, repo.CreateDiscount(ctx, newDiscount("current", "")))
+
+	later := newDiscount("later", "")
+	later.ValidFrom = epoch.Add(2 * time.Hour)
+	later.ValidTo = epoch.Add(3 * time.Hour)
+	require.NoError(t, repo.CreateDiscount(ctx, later))
+
+	inactive := newDiscount("inactive", "")
+	inactive.IsActive = false
+	require.NoError(t, repo.CreateDiscount(ctx, inactive))
+
+	exhausted := newDiscount("exhausted", "")
+	exhausted.UsageLimit = 1
+	exhausted.UsedCount = 1
+	require.NoError(t, repo.CreateDiscount(ctx, exhausted))
+
+	assert.ElementsMatch(t, []string{"current"}, activeIDs(t, repo, epoch))
+	assert.ElementsMatch(t, []string{"later"}, activeIDs(t, repo, epoch.Add(150*time.Minute)))
+}
+
+func testConcurrentUsage(t *testing.T, repo interfaces.IDiscountRepository) {
+	ctx := at(epoch)
+	limited := newDiscount("limited", "")
+	limited.UsageLimit = 50
+	require.NoError(t, repo.CreateDiscount(ctx, limited))
+
+	const workers = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.IncrementUsageCount(ctx, "limited")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	stored, err := repo.GetDiscountByID(ctx, "limited")
+	require.NoError(t, err)
+	assert.Equal(t, workers, stored.UsedCount, "no increment may be lost")
+	assert.Empty(t, activeIDs(t, repo, epoch), "a discount at its usage limit is no longer active")
+
+	assert.True(t, errors.IsNotFoundError(repo.IncrementUsageCount(ctx, "missing")))
+}
+
+func testCheckAndIncrementUsage(t *testing.T, repo interfaces.IDiscountRepository) {
+	ctx := at(epoch)
+	limited := newDiscount("limited", "")
+	limited.UsageLimit = 10
+	require.NoError(t, repo.CreateDiscount(ctx, limited))
+	require.NoError(t, repo.CreateDiscount(ctx, newDiscount("unlimited", "")))
+
+	const workers = 40
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.CheckAndIncrementUsage(ctx, "limited")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.True(t, errors.IsConflictError(err), "got %v", err)
+	}
+	assert.Equal(t, limited.UsageLimit, succeeded, "exactly the remaining uses are granted")
+
+	stored, err := repo.GetDiscountByID(ctx, "limited")
+	require.NoError(t, err)
+	assert.Equal(t, limited.UsageLimit, stored.UsedCount, "refused uses must not be recorded")
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, repo.CheckAndIncrementUsage(ctx, "unlimited"))
+	}
+	assert.True(t, errors.IsNotFoundError(repo.CheckAndIncrementUsage(ctx, "missing")))
+}
+
+func testTenantIsolation(t *testing.T, repo interfaces.IDiscountRepository) {
+	storeA := tenant.NewContext(at(epoch), "store-a")
+	storeB := tenant.NewContext(at(epoch), "store-b")
+
+	require.NoError(t, repo.CreateDiscount(storeA, newDiscount("d1", "SHARED")))
+	inB := newDiscount("d1", "SHARED")
+	require.NoError(t, repo.CreateDiscount(storeB, inB), "IDs and codes are unique per tenant")
+	require.NoError(t, repo.UpdateDiscount(storeB, inB))
+
+	fetched, err := repo.GetDiscountByID(storeA, "d1")
+	require.NoError(t, err)
+	assert.Equal(t, "store-a", fetched.TenantID)
+
+	_, err = repo.GetDiscountByID(at(epoch), "d1")
+	assert.True(t, errors.IsNotFoundError(err), "the default tenant sees no other tenant's discounts")
+	_, err = repo.GetDiscountByCode(tenant.NewContext(at(epoch), "store-c"), "SHARED")
+	assert.True(t, errors.IsNotFoundError(err))
+
+	active, err := repo.GetActiveDiscounts(storeA)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, "store-a", active[0].TenantID)
+
+	require.NoError(t, repo.IncrementUsageCount(storeA, "d1"))
+	require.NoError(t, repo.DeleteDiscount(storeA, "d1"))
+	fetched, err = repo.GetDiscountByID(storeB, "d1")
+	require.NoError(t, err, "deleting in one tenant leaves the other untouched")
+	assert.Equal(t, 0, fetched.UsedCount)
+
+	foreign := newDiscount("d2", "")
+	foreign.TenantID = "store-b"
+	assert.True(t, errors.IsValidationError(repo.CreateDiscount(storeA, foreign)), "writes cannot target another tenant")
+}
+
+func testApplicableDiscounts(t *testing.T, repo interfaces.IDiscountRepository) {
+	ctx := at(epoch)
+
+	typed := func(id string, typ models.DiscountType, applicableTo ...string) *models.Discount {
+		d := newDiscount(id, "")
+		d.Type = typ
+		d.ApplicableTo = applicableTo
+		return d
+	}
+	for _, d := range []*models.Discount{
+		typed("puma", models.DiscountTypeBrand, "PUMA"),
+		typed("nike", models.DiscountTypeBrand, "Nike"),
+		typed("any-brand", models.DiscountTypeBrand),
+		typed("shoes", models.DiscountTypeCategory, "Shoes"),
+		typed("puma-voucher", models.DiscountTypeVoucher, "PUMA"),
+		typed("sku", models.DiscountTypeVoucher, models.ItemRef(models.ItemRefSKU, "TS-1")),
+		typed("wildcard", models.DiscountTypeBrand, "PU*"),
+		typed("icici", models.DiscountTypeBank, "ICICI"),
+		typed("hdfc", models.DiscountTypeBank, "HDFC"),
+		typed("any-card", models.DiscountTypeBank, "ICICI*"),
+		typed("points", models.DiscountTypePointsRedemption),
+	} {
+		require.NoError(t, repo.CreateDiscount(ctx, d))
+	}
+	expired := typed("expired-puma", models.DiscountTypeBrand, "PUMA")
+	expired.ValidTo = epoch.Add(-time.Minute)
+	require.NoError(t, repo.CreateDiscount(ctx, expired))
+
+	product := models.Product{
+		ID:       "p1",
+		SKU:      "TS-1",
+		Brand:    models.Brand{ID: "PUMA"},
+		Category: models.Category{ID: "T-shirts"},
+	}
+	bank := "ICICI"
+	cart := []models.CartItem{{Product: product, Quantity: 1}}
+
+	ids := func(payment *models.PaymentInfo) []string {
+		t.Helper()
+		return filteredIDs(t, repo, ctx, models.CartDiscountFilter(cart, models.CustomerProfile{}, payment, epoch))
+	}
+
+	assert.ElementsMatch(t, []string{"puma", "any-brand", "puma-voucher", "sku", "wildcard", "points"}, ids(nil))
+	assert.ElementsMatch(t, []string{"puma", "any-brand", "puma-voucher", "sku", "wildcard", "points", "icici", "any-card"},
+		ids(&models.PaymentInfo{Method: models.Card, BankName: &bank}))
+
+	nike := typed("nike", models.DiscountTypeBrand, "PUMA")
+	nike.Version = 1
+	require.NoError(t, repo.UpdateDiscount(ctx, nike))
+	require.NoError(t, repo.DeleteDiscount(ctx, "puma"))
+	assert.ElementsMatch(t, []string{"nike", "any-brand", "puma-voucher", "sku", "wildcard", "points"}, ids(nil),
+		"updates and deletes are reindexed")
+
+	other := tenant.NewContext(ctx, "store-b")
+	found, err := repo.GetApplicableDiscounts(other, models.CartDiscountFilter(cart, models.CustomerProfile{}, nil, epoch))
+	require.NoError(t, err)
+	assert.Empty(t, found, "lookups stay within the tenant")
+	found, err = repo.GetApplicableDiscounts(other, models.DiscountFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, found, "scans stay within the tenant")
+}
+
+func testDiscountFilters(t *testing.T, repo interfaces.IDiscountRepository) {
+	ctx := at(epoch)
+
+	gold := newDiscount("gold", "")
+	gold.CustomerTiers = []string{"gold"}
+	later := newDiscount("later", "")
+	later.ValidFrom, later.ValidTo = epoch.Add(24*time.Hour), epoch.Add(48*time.Hour)
+	brand := newDiscount("brand", "")
+	brand.Type = models.DiscountTypeBrand
+	brand.ApplicableTo = []string{"PUMA"}
+	bank := newDiscount("bank", "")
+	bank.Type = models.DiscountTypeBank
+	bank.ApplicableTo = []string{"ICICI"}
+	for _, d := range []*models.Discount{newDiscount("open", ""), gold, later, brand, bank} {
+		require.NoError(t, repo.CreateDiscount(ctx, d))
+	}
+
+	for _, tc := range []struct {
+		name     string
+		filter   models.DiscountFilter
+		expected []string
+	}{
+		{"Empty filter matches everything", models.DiscountFilter{}, []string{"open", "gold", "later", "brand", "bank"}},
+		{"Types", models.DiscountFilter{Types: []models.DiscountType{models.DiscountTypeBrand, models.DiscountTypeBank}}, []string{"brand", "bank"}},
+		{"Brands", models.DiscountFilter{BrandIDs: []string{"Nike"}}, []string{"open", "gold", "later", "bank"}},
+		{"Bank", models.DiscountFilter{PaymentMethod: models.Card, Bank: "HDFC"}, []string{"open", "gold", "later", "brand"}},
+		{"Customer tier", models.DiscountFilter{CustomerTier: "silver", Types: []models.DiscountType{models.DiscountTypeVoucher}}, []string{"open", "later"}},
+		{"Active at", models.DiscountFilter{ActiveAt: epoch}, []string{"open", "gold", "brand", "bank"}},
+		{"Window", models.DiscountFilter{From: epoch.Add(2 * time.Hour), To: epoch.Add(36 * time.Hour)}, []string{"later"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tc.expected, filteredIDs(t, repo, ctx, tc.filter))
+		})
+	}
+}
+
+func filteredIDs(t *testing.T, repo interfaces.IDiscountRepository, ctx context.Context, filter models.DiscountFilter) []string {
+	t.Helper()
+	discounts, err := repo.GetApplicableDiscounts(ctx, filter)
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(discounts))
+	for _, d := range discounts {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
+
+func testListDiscounts(t *testing.T, repo interfaces.IDiscountRepository) {
+	lister, ok := repo.(interfaces.DiscountLister)
+	if !ok {
+		t.Skip("repository does not list discounts")
+	}
+	ctx := at(epoch)
+
+	for i, id := range []string{"e", "c", "a", "d", "b"} {
+		d := newDiscount(id, "CODE-"+strings.ToUpper(id))
+		d.Priority = i
+		d.IsActive = id != "d"
+		if id == "b" {
+			d.Type = models.DiscountTypeBrand
+		}
+		require.NoError(t, repo.CreateDiscount(ctx, d))
+	}
+
+	list := func(filter models.ListFilter) []string {
+		t.Helper()
+		var ids []string
+		for {
+			page, err := lister.ListDiscounts(ctx, filter)
+			require.NoError(t, err)
+			if filter.PageSize > 0 {
+				require.LessOrEqual(t, len(page.Discounts), filter.PageSize)
+			}
+			for _, d := range page.Discounts {
+				ids = append(ids, d.ID)
+			}
+			if page.NextCursor == "" {
+				return ids
+			}
+			filter.Cursor = page.NextCursor
+		}
+	}
+
+	active := true
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, list(models.ListFilter{}))
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, list(models.ListFilter{PageSize: 2}))
+	assert.Equal(t, []string{"b", "d", "a", "c", "e"}, list(models.ListFilter{SortBy: models.SortByPriority, PageSize: 2}))
+	assert.Equal(t, []string{"b"}, list(models.ListFilter{Type: models.DiscountTypeBrand}))
+	assert.Equal(t, []string{"a", "b", "c", "e"}, list(models.ListFilter{Active: &active, PageSize: 3}))
+	assert.Equal(t, []string{"c"}, list(models.ListFilter{CodePrefix: "CODE-C"}))
+
+	page, err := lister.ListDiscounts(ctx, models.ListFilter{PageSize: 2})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteDiscount(ctx, "b"))
+	require.NoError(t, repo.CreateDiscount(ctx, newDiscount("aa", "")))
+	next, err := lister.ListDiscounts(ctx, models.ListFilter{PageSize: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, next.Discounts, 2)
+	assert.Equal(t, "c", next.Discounts[0].ID, "a cursor survives its last discount being deleted")
+
+	for _, filter := range []models.ListFilter{
+		{SortBy: "colour"},
+		{PageSize: -1},
+		{Cursor: "not a cursor"},
+		{Cursor: page.NextCursor, SortBy: models.SortByName},
+	} {
+		_, err := lister.ListDiscounts(ctx, filter)
+		assert.True(t, errors.IsValidationError(err), "%+v", filter)
+	}
+}
+
+func activeIDs(t *testing.T, repo interfaces.IDiscountRepository, now time.Time) []string {
+	t.Helper()
+	active, err := repo.GetActiveDiscounts(at(now))
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(active))
+	for _, d := range active {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
+
+func testRevisions(t *testing.T, repo interfaces.IDiscountRepository) {
+	history, ok := repo.(interfaces.DiscountHistory)
+	if !ok {
+		t.Skip("repository does not keep discount revisions")
+	}
+
+	d := newDiscount("d1", "")
+	require.NoError(t, repo.CreateDiscount(at(epoch), d))
+	d.Value = decimal.NewFromInt(20)
+	require.NoError(t, repo.UpdateDiscount(at(epoch.Add(time.Minute)), d))
+	require.NoError(t, repo.CheckAndIncrementUsage(at(epoch.Add(2*time.Minute)), "d1"))
+	d.Value = decimal.NewFromInt(30)
+	require.NoError(t, repo.UpdateDiscount(at(epoch.Add(3*time.Minute)), d))
+
+	revisions, err := history.ListRevisions(at(epoch), "d1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 3, "usage is not a revision")
+	for i, revision := range revisions {
+		assert.Equal(t, i+1, revision.Version)
+		assert.Equal(t, i+1, revision.Discount.Version)
+	}
+	assert.True(t, revisions[1].EffectiveFrom.Equal(epoch.Add(time.Minute)))
+
+	valueAt := func(instant time.Time) string {
+		t.Helper()
+		d, err := history.GetDiscountAt(at(epoch), "d1", instant)
+		require.NoError(t, err)
+		return d.Value.String()
+	}
+	assert.Equal(t, "10", valueAt(epoch))
+	assert.Equal(t, "20", valueAt(epoch.Add(90*time.Second)))
+	assert.Equal(t, "30", valueAt(epoch.Add(time.Hour)))
+
+	_, err = history.GetDiscountAt(at(epoch), "d1", epoch.Add(-time.Second))
+	assert.True(t, errors.IsNotFoundError(err), "nothing was defined before the discount was created")
+	_, err = history.ListRevisions(tenant.NewContext(at(epoch), "other"), "d1")
+	assert.True(t, errors.IsNotFoundError(err), "revisions belong to their tenant")
+
+	require.NoError(t, repo.DeleteDiscount(at(epoch), "d1"))
+	_, err = history.ListRevisions(at(epoch), "d1")
+	assert.True(t, errors.IsNotFoundError(err))
+}
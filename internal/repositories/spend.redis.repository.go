@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+	"github.com/shopspring/decimal"
+)
+
+// spendScale is the number of decimal places kept by Redis, which stores spend as an
+// integer count of 10^-spendScale units so no floating point arithmetic is involved
+const spendScale = 4
+
+// addSpendScript adds ARGV[1] to KEYS[1] unless the result would exceed a positive ARGV[2].
+// It returns {1, new total} on success and {0, current total} when the cap would be exceeded.
+var addSpendScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local amount = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+if limit > 0 and current + amount > limit then
+	return {0, current}
+end
+return {1, redis.call('INCRBY', KEYS[1], amount)}
+`)
+
+// RedisSpendTracker implements ISpendTracker on Redis so caps are shared by every instance
+type RedisSpendTracker struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedisSpendTracker creates a spend tracker storing one key per discount under prefix,
+// namespaced by tenant for every tenant but the default
+func NewRedisSpendTracker(client redis.Cmdable, prefix string) interfaces.ISpendTracker {
+	return &RedisSpendTracker{client: client, prefix: prefix}
+}
+
+func (t *RedisSpendTracker) key(ctx context.Context, discountID string) string {
+	if tenantID := tenant.FromContext(ctx); tenantID != tenant.Default {
+		return t.prefix + "tenant:" + tenantID + ":spend:" + discountID
+	}
+	return t.prefix + "spend:" + discountID
+}
+
+// GetSpend returns the total amount given away by the discount so far
+func (t *RedisSpendTracker) GetSpend(ctx context.Context, discountID string) (decimal.Decimal, error) {
+	units, err := t.client.Get(ctx, t.key(ctx, discountID)).Int64()
+	if err == redis.Nil {
+		return decimal.Zero, nil
+	}
+	if err != nil {
+		return decimal.Zero, errors.NewInternalError("failed to read discount spend", err)
+	}
+	return decimal.New(units, -spendScale), nil
+}
+
+// AddSpend atomically adds amount to the discount's spend unless it would exceed limit
+func (t *RedisSpendTracker) AddSpend(ctx context.Context, discountID string, amount, limit decimal.Decimal) (decimal.Decimal, error) {
+	res, err := addSpendScript.Run(ctx, t.client, []string{t.key(ctx, discountID)},
+		toSpendUnits(amount), toSpendUnits(limit)).Int64Slice()
+	if err != nil {
+		return decimal.Zero, errors.NewInternalError("failed to add discount spend", err)
+	}
+	if len(res) != 2 {
+		return decimal.Zero, errors.NewInternalError("unexpected spend script reply", fmt.Errorf("%v", res))
+	}
+
+	total := decimal.New(res[1], -spendScale)
+	if res[0] == 0 {
+		return total, errors.NewValidationError("discount spend cap exceeded: " + discountID)
+	}
+	return total, nil
+}
+
+func toSpendUnits(amount decimal.Decimal) int64 {
+	return amount.Shift(spendScale).Round(0).IntPart()
+}
@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+	"github.com/shopspring/decimal"
+)
+
+// InMemorySpendTracker implements ISpendTracker for a single process
+type InMemorySpendTracker struct {
+	spend map[string]decimal.Decimal
+	mu    sync.Mutex
+}
+
+// NewInMemorySpendTracker creates a new in-memory spend tracker
+func NewInMemorySpendTracker() interfaces.ISpendTracker {
+	return &InMemorySpendTracker{
+		spend: make(map[string]decimal.Decimal),
+	}
+}
+
+// GetSpend returns the total amount given away by the discount so far
+func (t *InMemorySpendTracker) GetSpend(ctx context.Context, discountID string) (decimal.Decimal, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.spend[tenant.Key(ctx, discountID)], nil
+}
+
+// AddSpend atomically adds amount to the discount's spend unless it would exceed limit
+func (t *InMemorySpendTracker) AddSpend(ctx context.Context, discountID string, amount, limit decimal.Decimal) (decimal.Decimal, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	total := t.spend[tenant.Key(ctx, discountID)].Add(amount)
+	if limit.IsPositive() && total.GreaterThan(limit) {
+		return t.spend[tenant.Key(ctx, discountID)], errors.NewValidationError("discount spend cap exceeded: " + discountID)
+	}
+
+	t.spend[tenant.Key(ctx, discountID)] = total
+	return total, nil
+}
@@ -0,0 +1,19 @@
+package resilience
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// NewHandler serves the repository's metrics:
+//
+//	GET /admin/resilience  the breaker's state and the calls, failures, timeouts, retries
+//	                       and rejections of every operation
+func NewHandler(r *Repository) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/resilience", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(r.Stats())
+	})
+	return mux
+}
@@ -0,0 +1,370 @@
+// Package resilience guards calls to a discount repository backed by a remote database, so
+// a slow or failing database costs checkouts a bounded delay instead of hanging them. Every
+// call is bounded by a timeout and passes a circuit breaker; reads, which are safe to
+// repeat, are retried with exponential backoff. Counters of every operation are kept for
+// the /admin/resilience endpoint.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/pkg/breaker"
+)
+
+// Config tunes a Repository
+type Config struct {
+	// Timeout bounds every attempt, zero leaving attempts unbounded. Reads that time out
+	// are abandoned; writes are handed the deadline in their context and waited for, so
+	// a write reported as failed did not happen.
+	Timeout time.Duration
+
+	// Retries is how many times a failed read is tried again. Writes are tried once, as
+	// repeating them could count a use twice.
+	Retries int
+
+	// Backoff is the wait before the first retry, doubling with every retry up to
+	// MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// BreakerThreshold and BreakerCooldown set up the circuit breaker: the failures in a
+	// row that open it and how long it stays open, the breaker defaults when zero
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// DefaultConfig suits a database answering checkouts in milliseconds
+func DefaultConfig() Config {
+	return Config{
+		Timeout:    2 * time.Second,
+		Retries:    2,
+		Backoff:    20 * time.Millisecond,
+		MaxBackoff: 500 * time.Millisecond,
+	}
+}
+
+// Repository decorates a discount repository with timeouts, retries and a circuit breaker.
+// A call is a failure when it times out or fails with an error other than the not found,
+// validation, conflict and forbidden errors that say the call itself was wrong; calls the
+// caller abandons are neither. While the breaker is open calls fail at once with an
+// overloaded error.
+type Repository struct {
+	repo    interfaces.IDiscountRepository
+	cfg     Config
+	breaker *breaker.Breaker
+
+	mu    sync.Mutex
+	stats map[string]*OperationStats // by method name
+}
+
+var (
+	_ interfaces.IDiscountRepository = (*Repository)(nil)
+	_ interfaces.DiscountLister      = (*Repository)(nil)
+	_ interfaces.CatalogInspector    = (*Repository)(nil)
+	_ interfaces.DiscountHistory     = (*Repository)(nil)
+	_ interfaces.UsageReleaser       = (*Repository)(nil)
+	_ interfaces.UsageAdder          = (*Repository)(nil)
+)
+
+// NewRepository guards repo as cfg says
+func NewRepository(repo interfaces.IDiscountRepository, cfg Config) *Repository {
+	return &Repository{
+		repo: repo,
+		cfg:  cfg,
+		breaker: &breaker.Breaker{
+			Name:      "discount repository",
+			Threshold: cfg.BreakerThreshold,
+			Cooldown:  cfg.BreakerCooldown,
+		},
+		stats: make(map[string]*OperationStats),
+	}
+}
+
+// Breaker returns the circuit breaker guarding the repository
+func (r *Repository) Breaker() *breaker.Breaker {
+	return r.breaker
+}
+
+func (r *Repository) GetActiveDiscounts(ctx context.Context) ([]models.Discount, error) {
+	return read(r, ctx, "GetActiveDiscounts", func(ctx context.Context) ([]models.Discount, error) {
+		return r.repo.GetActiveDiscounts(ctx)
+	})
+}
+
+func (r *Repository) GetApplicableDiscounts(ctx context.Context, filter models.DiscountFilter) ([]models.Discount, error) {
+	return read(r, ctx, "GetApplicableDiscounts", func(ctx context.Context) ([]models.Discount, error) {
+		return r.repo.GetApplicableDiscounts(ctx, filter)
+	})
+}
+
+func (r *Repository) GetDiscountByCode(ctx context.Context, code string) (*models.Discount, error) {
+	return read(r, ctx, "GetDiscountByCode", func(ctx context.Context) (*models.Discount, error) {
+		return r.repo.GetDiscountByCode(ctx, code)
+	})
+}
+
+func (r *Repository) GetDiscountByID(ctx context.Context, id string) (*models.Discount, error) {
+	return read(r, ctx, "GetDiscountByID", func(ctx context.Context) (*models.Discount, error) {
+		return r.repo.GetDiscountByID(ctx, id)
+	})
+}
+
+func (r *Repository) CreateDiscount(ctx context.Context, discount *models.Discount) error {
+	return r.write(ctx, "CreateDiscount", func(ctx context.Context) error {
+		return r.repo.CreateDiscount(ctx, discount)
+	})
+}
+
+func (r *Repository) UpdateDiscount(ctx context.Context, discount *models.Discount) error {
+	return r.write(ctx, "UpdateDiscount", func(ctx context.Context) error {
+		return r.repo.UpdateDiscount(ctx, discount)
+	})
+}
+
+func (r *Repository) DeleteDiscount(ctx context.Context, id string) error {
+	return r.write(ctx, "DeleteDiscount", func(ctx context.Context) error {
+		return r.repo.DeleteDiscount(ctx, id)
+	})
+}
+
+func (r *Repository) IncrementUsageCount(ctx context.Context, id string) error {
+	return r.write(ctx, "IncrementUsageCount", func(ctx context.Context) error {
+		return r.repo.IncrementUsageCount(ctx, id)
+	})
+}
+
+func (r *Repository) CheckAndIncrementUsage(ctx context.Context, id string) error {
+	return r.write(ctx, "CheckAndIncrementUsage", func(ctx context.Context) error {
+		return r.repo.CheckAndIncrementUsage(ctx, id)
+	})
+}
+
+// ReleaseUsage gives back a use through the wrapped repository, see
+// interfaces.UsageReleaser
+func (r *Repository) ReleaseUsage(ctx context.Context, id string) error {
+	releaser, ok := r.repo.(interfaces.UsageReleaser)
+	if !ok {
+		return fmt.Errorf("repository cannot release usage")
+	}
+	return r.write(ctx, "ReleaseUsage", func(ctx context.Context) error {
+		return releaser.ReleaseUsage(ctx, id)
+	})
+}
+
+// AddUsage counts many uses at once through the wrapped repository, see
+// interfaces.UsageAdder
+func (r *Repository) AddUsage(ctx context.Context, uses map[string]int) error {
+	adder, ok := r.repo.(interfaces.UsageAdder)
+	if !ok {
+		return fmt.Errorf("repository cannot add usage: %w", errors.ErrUnsupported)
+	}
+	return r.write(ctx, "AddUsage", func(ctx context.Context) error {
+		return adder.AddUsage(ctx, uses)
+	})
+}
+
+// ListDiscounts lists through the wrapped repository, see interfaces.DiscountLister
+func (r *Repository) ListDiscounts(ctx context.Context, filter models.ListFilter) (*models.DiscountPage, error) {
+	lister, ok := r.repo.(interfaces.DiscountLister)
+	if !ok {
+		return nil, fmt.Errorf("repository cannot list discounts")
+	}
+	return read(r, ctx, "ListDiscounts", func(ctx context.Context) (*models.DiscountPage, error) {
+		return lister.ListDiscounts(ctx, filter)
+	})
+}
+
+// CatalogStats summarises through the wrapped repository, see interfaces.CatalogInspector
+func (r *Repository) CatalogStats(ctx context.Context, window time.Duration) (*models.CatalogStats, error) {
+	inspector, ok := r.repo.(interfaces.CatalogInspector)
+	if !ok {
+		return nil, fmt.Errorf("repository cannot summarise its catalog")
+	}
+	return read(r, ctx, "CatalogStats", func(ctx context.Context) (*models.CatalogStats, error) {
+		return inspector.CatalogStats(ctx, window)
+	})
+}
+
+// ListRevisions reads through the wrapped repository, see interfaces.DiscountHistory
+func (r *Repository) ListRevisions(ctx context.Context, id string) ([]models.DiscountRevision, error) {
+	history, ok := r.repo.(interfaces.DiscountHistory)
+	if !ok {
+		return nil, fmt.Errorf("repository does not keep discount revisions")
+	}
+	return read(r, ctx, "ListRevisions", func(ctx context.Context) ([]models.DiscountRevision, error) {
+		return history.ListRevisions(ctx, id)
+	})
+}
+
+// GetDiscountAt reads through the wrapped repository, see interfaces.DiscountHistory
+func (r *Repository) GetDiscountAt(ctx context.Context, id string, at time.Time) (*models.Discount, error) {
+	history, ok := r.repo.(interfaces.DiscountHistory)
+	if !ok {
+		return nil, fmt.Errorf("repository does not keep discount revisions")
+	}
+	return read(r, ctx, "GetDiscountAt", func(ctx context.Context) (*models.Discount, error) {
+		return history.GetDiscountAt(ctx, id, at)
+	})
+}
+
+// read calls a read, retrying it as configured
+func read[T any](r *Repository, ctx context.Context, op string, fn func(context.Context) (T, error)) (T, error) {
+	return call(r, ctx, op, 1+max(0, r.cfg.Retries), abandon, fn)
+}
+
+// write calls a write once, waiting for it to return
+func (r *Repository) write(ctx context.Context, op string, fn func(context.Context) error) error {
+	_, err := call(r, ctx, op, 1, await, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// call makes up to attempts attempts of fn through the breaker, backing off between them
+func call[T any](r *Repository, ctx context.Context, op string, attempts int, bounded attemptFunc[T],
+	fn func(context.Context) (T, error)) (T, error) {
+	var zero T
+	r.observe(op, func(s *OperationStats) { s.Calls++ })
+	for i := 0; ; i++ {
+		if err := r.breaker.Allow(); err != nil {
+			r.observe(op, func(s *OperationStats) { s.Rejected++ })
+			return zero, err
+		}
+
+		v, err := bounded(ctx, r.cfg.Timeout, fn)
+		switch {
+		case ctx.Err() != nil:
+			r.breaker.Ignore()
+			return zero, ctx.Err()
+		case !repositories.IsUnavailable(err):
+			r.breaker.Success()
+			return v, err
+		}
+
+		r.breaker.Failure()
+		timedOut := errors.Is(err, context.DeadlineExceeded)
+		r.observe(op, func(s *OperationStats) {
+			s.Failures++
+			if timedOut {
+				s.Timeouts++
+			}
+		})
+		if timedOut {
+			err = fmt.Errorf("%s timed out after %s: %w", op, r.cfg.Timeout, err)
+		}
+		if i+1 >= attempts {
+			return zero, err
+		}
+
+		// A retry the caller's deadline leaves no time for would only fail later
+		wait := r.backoff(i)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			return zero, err
+		}
+		r.observe(op, func(s *OperationStats) { s.Retries++ })
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return zero, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attemptFunc calls fn bounded by timeout
+type attemptFunc[T any] func(ctx context.Context, timeout time.Duration, fn func(context.Context) (T, error)) (T, error)
+
+// await calls fn with a context done once timeout has passed, and waits for it to return:
+// repositories need not stop when their context is done, but a write's outcome must be known
+func await[T any](ctx context.Context, timeout time.Duration, fn func(context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// abandon calls fn, giving up once timeout has passed. The call is left to finish in the
+// background, since repositories need not stop when their context is done.
+func abandon[T any](ctx context.Context, timeout time.Duration, fn func(context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := fn(ctx)
+		done <- result{v, err}
+	}()
+	select {
+	case res := <-done:
+		return res.v, res.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// backoff is the wait before the retry following attempt i, counting from zero
+func (r *Repository) backoff(i int) time.Duration {
+	wait := r.cfg.Backoff << i
+	if r.cfg.MaxBackoff > 0 && (wait > r.cfg.MaxBackoff || wait <= 0) {
+		return r.cfg.MaxBackoff
+	}
+	return wait
+}
+
+// Stats reports the breaker's state and the counters of every operation called so far
+func (r *Repository) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := Stats{Breaker: r.breaker.State(), Operations: make([]OperationStats, 0, len(r.stats))}
+	for _, s := range r.stats {
+		stats.Operations = append(stats.Operations, *s)
+	}
+	sort.Slice(stats.Operations, func(i, j int) bool { return stats.Operations[i].Operation < stats.Operations[j].Operation })
+	return stats
+}
+
+func (r *Repository) observe(op string, update func(*OperationStats)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.stats[op]
+	if !ok {
+		s = &OperationStats{Operation: op}
+		r.stats[op] = s
+	}
+	update(s)
+}
+
+// Stats is a snapshot of a Repository
+type Stats struct {
+	Breaker    breaker.State    `json:"breaker"`
+	Operations []OperationStats `json:"operations"` // Ordered by operation
+}
+
+// OperationStats counts the calls of one repository method
+type OperationStats struct {
+	Operation string `json:"operation"`
+	Calls     uint64 `json:"calls"`
+	Failures  uint64 `json:"failures"` // Failed attempts, timeouts included
+	Timeouts  uint64 `json:"timeouts"`
+	Retries   uint64 `json:"retries"`
+	Rejected  uint64 `json:"rejected"` // Refused by the open breaker
+}
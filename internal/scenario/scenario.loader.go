@@ -0,0 +1,163 @@
+// Package scenario runs pricing acceptance tests written as YAML files, so scenarios can be
+// authored without writing Go. A file holds one or more YAML documents, each a scenario:
+//
+//	name: PUMA T-shirt stacks brand and category discounts
+//	now: 2024-06-01T12:00:00Z            # optional, pins the clock
+//	discounts:
+//	  - id: puma-40
+//	    name: PUMA 40%
+//	    type: brand
+//	    value: 40
+//	    is_percentage: true
+//	    applicable_to: [PUMA]
+//	cart:
+//	  - product: {id: tee, brand: {id: PUMA}, category: {id: T-shirts}, current_price: 1000}
+//	    quantity: 1
+//	customer: {id: alice, tier: regular}
+//	expect:
+//	  final_price: 600
+//	  applied:
+//	    PUMA 40%: 400
+//
+// Discounts, cart items, customers and payments use the same field names as the JSON API.
+// Discounts are active and valid for a day either side of now unless they say otherwise.
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/yamljson"
+)
+
+// DefaultNow is the instant scenarios run at when they do not set now, keeping them
+// deterministic
+var DefaultNow = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// Scenario is one acceptance test: the discounts in force, a checkout, and its expected outcome
+type Scenario struct {
+	Name      string                 `json:"name"`
+	Now       time.Time              `json:"now"`
+	Discounts []models.Discount      `json:"discounts"`
+	Cart      []models.CartItem      `json:"cart"`
+	Customer  models.CustomerProfile `json:"customer"`
+	Payment   *models.PaymentInfo    `json:"payment,omitempty"`
+	Codes     []string               `json:"codes,omitempty"`
+	Expect    Expectation            `json:"expect"`
+	File      string                 `json:"-"` // File the scenario was loaded from
+}
+
+// Expectation describes the outcome a scenario requires
+type Expectation struct {
+	OriginalPrice *decimal.Decimal `json:"original_price,omitempty"`
+	FinalPrice    *decimal.Decimal `json:"final_price,omitempty"`
+
+	// Applied lists exactly the discounts that must apply, by name. A null amount accepts
+	// any amount.
+	Applied map[string]*decimal.Decimal `json:"applied,omitempty"`
+
+	// Error, when set, is a substring of the error the calculation must fail with
+	Error string `json:"error,omitempty"`
+}
+
+// LoadFile reads every scenario in a YAML file
+func LoadFile(path string) ([]Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	scenarios, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i := range scenarios {
+		scenarios[i].File = path
+	}
+	return scenarios, nil
+}
+
+// Parse reads every scenario in a YAML stream
+func Parse(data []byte) ([]Scenario, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+
+	var scenarios []Scenario
+	for i := 1; ; i++ {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("scenario %d: %w", i, err)
+		}
+
+		s, err := decodeScenario(&doc)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %d: %w", i, err)
+		}
+		if s.Name == "" {
+			s.Name = fmt.Sprintf("scenario %d", i)
+		}
+		scenarios = append(scenarios, s)
+	}
+	return scenarios, nil
+}
+
+// decodeScenario converts the YAML document to JSON and decodes it with the models' JSON
+// field names. Numbers keep their exact text, so amounts lose no precision.
+func decodeScenario(doc *yaml.Node) (Scenario, error) {
+	value, err := yamljson.Value(doc)
+	if err != nil {
+		return Scenario{}, err
+	}
+
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return Scenario{}, fmt.Errorf("a scenario must be a mapping")
+	}
+	if discounts, ok := fields["discounts"].([]any); ok {
+		for _, d := range discounts {
+			if discount, ok := d.(map[string]any); ok {
+				if _, set := discount["is_active"]; !set {
+					discount["is_active"] = true
+				}
+			}
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return Scenario{}, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var s Scenario
+	if err := dec.Decode(&s); err != nil {
+		return Scenario{}, err
+	}
+
+	if s.Now.IsZero() {
+		s.Now = DefaultNow
+	}
+	for i := range s.Discounts {
+		d := &s.Discounts[i]
+		if d.ValidFrom.IsZero() {
+			d.ValidFrom = s.Now.Add(-24 * time.Hour)
+		}
+		if d.ValidTo.IsZero() {
+			d.ValidTo = s.Now.Add(24 * time.Hour)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,88 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+)
+
+// Run prices the scenario's checkout against a fresh repository holding its discounts and
+// returns every way the outcome differs from the expectation; none means the scenario passed
+func Run(ctx context.Context, s Scenario, opts ...services.Option) ([]string, error) {
+	repo := repositories.NewInMemoryDiscountRepository()
+	if err := repo.(interfaces.DiscountSeeder).SeedDiscounts(s.Discounts); err != nil {
+		return nil, err
+	}
+
+	opts = append([]services.Option{services.WithClock(clock.NewFixed(s.Now))}, opts...)
+	service := services.NewDiscountService(repo, opts...)
+
+	result, err := service.CalculateCart(ctx, &models.CalculationRequest{
+		CartItems:   s.Cart,
+		Customer:    s.Customer,
+		PaymentInfo: s.Payment,
+		Codes:       s.Codes,
+	})
+	return check(s.Expect, result, err), nil
+}
+
+func check(expect Expectation, result *models.DiscountedPrice, err error) []string {
+	if expect.Error != "" {
+		switch {
+		case err == nil:
+			return []string{fmt.Sprintf("expected error containing %q, calculation succeeded", expect.Error)}
+		case !strings.Contains(err.Error(), expect.Error):
+			return []string{fmt.Sprintf("expected error containing %q, got %q", expect.Error, err.Error())}
+		default:
+			return nil
+		}
+	}
+	if err != nil {
+		return []string{"calculation failed: " + err.Error()}
+	}
+
+	var failures []string
+	if expect.OriginalPrice != nil && !expect.OriginalPrice.Equal(result.OriginalPrice) {
+		failures = append(failures, fmt.Sprintf("original price: expected %s, got %s",
+			expect.OriginalPrice.String(), result.OriginalPrice.String()))
+	}
+	if expect.FinalPrice != nil && !expect.FinalPrice.Equal(result.FinalPrice) {
+		failures = append(failures, fmt.Sprintf("final price: expected %s, got %s",
+			expect.FinalPrice.String(), result.FinalPrice.String()))
+	}
+
+	if expect.Applied != nil {
+		for _, name := range sortedKeys(expect.Applied) {
+			amount, applied := result.AppliedDiscounts[name]
+			switch want := expect.Applied[name]; {
+			case !applied:
+				failures = append(failures, fmt.Sprintf("expected %q to apply", name))
+			case want != nil && !want.Equal(amount):
+				failures = append(failures, fmt.Sprintf("%q: expected %s off, got %s", name, want.String(), amount.String()))
+			}
+		}
+		for _, name := range sortedKeys(result.AppliedDiscounts) {
+			if _, expected := expect.Applied[name]; !expected {
+				failures = append(failures, fmt.Sprintf("unexpected discount %q applied (%s off)",
+					name, result.AppliedDiscounts[name].String()))
+			}
+		}
+	}
+	return failures
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/codegen"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// maxMintRounds bounds how often Mint regenerates codes that collided with stored ones
+const maxMintRounds = 10
+
+type couponCodeService struct {
+	discountRepo interfaces.IDiscountRepository
+	codeRepo     interfaces.ICouponCodeRepository
+	clock        clock.Clock
+}
+
+// NewCouponCodeService mints and checks generated codes, deciding them at c's current time
+// unless the request already pinned an instant
+func NewCouponCodeService(discountRepo interfaces.IDiscountRepository,
+	codeRepo interfaces.ICouponCodeRepository, c clock.Clock) interfaces.ICouponCodeService {
+	return &couponCodeService{
+		discountRepo: discountRepo,
+		codeRepo:     codeRepo,
+		clock:        c,
+	}
+}
+
+// instant returns the instant pinned in ctx by an earlier stage of the request, or the
+// service clock's current time
+func (cs *couponCodeService) instant(ctx context.Context) time.Time {
+	if now, ok := clock.Pinned(ctx); ok {
+		return now
+	}
+	return cs.clock.Now()
+}
+
+func (cs *couponCodeService) Mint(ctx context.Context, discountID string, n int, gen codegen.Generator) ([]string, error) {
+	if n <= 0 {
+		return nil, errors.NewValidationError("number of codes must be positive")
+	}
+	if err := gen.Validate(); err != nil {
+		return nil, errors.NewValidationError("invalid code generator: " + err.Error())
+	}
+
+	template, err := cs.discountRepo.GetDiscountByID(ctx, discountID)
+	if err != nil {
+		return nil, err
+	}
+	if !template.GeneratedCodes {
+		return nil, errors.NewValidationError("discount does not use generated codes: " + discountID)
+	}
+
+	now := cs.instant(ctx)
+	minted := make([]string, 0, n)
+	for round := 0; len(minted) < n; round++ {
+		if round == maxMintRounds {
+			return minted, fmt.Errorf("minted %d of %d codes: too many collisions with existing codes", len(minted), n)
+		}
+
+		candidates, err := gen.Generate(n - len(minted))
+		if err != nil {
+			return minted, errors.NewValidationError(err.Error())
+		}
+		batch := make([]models.CouponCode, len(candidates))
+		for i, code := range candidates {
+			batch[i] = models.CouponCode{Code: code, DiscountID: discountID, CreatedAt: now}
+		}
+
+		duplicates, err := cs.codeRepo.SaveCodes(ctx, batch)
+		if err != nil {
+			return minted, fmt.Errorf("failed to save codes: %w", err)
+		}
+		taken := make(map[string]bool, len(duplicates))
+		for _, code := range duplicates {
+			taken[code] = true
+		}
+		for _, code := range candidates {
+			if !taken[code] {
+				minted = append(minted, code)
+			}
+		}
+	}
+	return minted, nil
+}
+
+func (cs *couponCodeService) BulkValidate(ctx context.Context, codes []string) ([]models.CouponCodeStatus, error) {
+	ctx = clock.NewContext(ctx, cs.instant(ctx))
+	templates := make(map[string]*models.Discount)
+
+	statuses := make([]models.CouponCodeStatus, len(codes))
+	for i, code := range codes {
+		status, err := cs.status(ctx, code, templates)
+		if err != nil {
+			return nil, err
+		}
+		statuses[i] = status
+	}
+	return statuses, nil
+}
+
+func (cs *couponCodeService) BulkRedeem(ctx context.Context, customerID string, codes []string) ([]models.CouponCodeStatus, error) {
+	now := cs.instant(ctx)
+	ctx = clock.NewContext(ctx, now)
+	templates := make(map[string]*models.Discount)
+
+	statuses := make([]models.CouponCodeStatus, len(codes))
+	for i, code := range codes {
+		status, err := cs.status(ctx, code, templates)
+		if err != nil {
+			return nil, err
+		}
+
+		if status.State == models.CouponCodeAvailable {
+			err = cs.codeRepo.RedeemCode(ctx, code, customerID, now)
+			switch {
+			case err == nil:
+				status.State = models.CouponCodeRedeemed
+				status.RedeemedNow = true
+			case errors.IsConflictError(err):
+				// Lost a race with a concurrent redemption; report the code as already used
+				status.State = models.CouponCodeRedeemed
+			default:
+				return nil, fmt.Errorf("failed to redeem code: %w", err)
+			}
+		}
+		statuses[i] = status
+	}
+	return statuses, nil
+}
+
+// status looks up one code and the discount it unlocks, caching discounts in templates
+func (cs *couponCodeService) status(ctx context.Context, code string,
+	templates map[string]*models.Discount) (models.CouponCodeStatus, error) {
+
+	status := models.CouponCodeStatus{Code: code, State: models.CouponCodeUnknown}
+	stored, err := cs.codeRepo.GetCode(ctx, code)
+	if err != nil {
+		if errors.IsNotFoundError(err) {
+			return status, nil
+		}
+		return status, fmt.Errorf("failed to get code: %w", err)
+	}
+
+	status.DiscountID = stored.DiscountID
+	if stored.IsRedeemed() {
+		status.State = models.CouponCodeRedeemed
+		return status, nil
+	}
+
+	template, cached := templates[stored.DiscountID]
+	if !cached {
+		template, err = cs.discountRepo.GetDiscountByID(ctx, stored.DiscountID)
+		if err != nil && !errors.IsNotFoundError(err) {
+			return status, fmt.Errorf("failed to get discount: %w", err)
+		}
+		templates[stored.DiscountID] = template
+	}
+
+	status.State = models.CouponCodeUnavailable
+	if template != nil && template.IsValidAt(clock.FromContext(ctx)) {
+		status.State = models.CouponCodeAvailable
+	}
+	return status, nil
+}
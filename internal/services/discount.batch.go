@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/lane"
+)
+
+// defaultBatchConcurrency is how many carts of a batch are priced at once by default
+const defaultBatchConcurrency = 8
+
+// CalculateCartDiscountsBatch prices every cart of the batch on a bounded pool of workers.
+// A cart that fails to price does not fail the batch: its error is reported in its own
+// entry. Carts not yet started when ctx is done fail with the context's error. Batches run
+// in the batch traffic lane, so a scheduler keeps them from slowing checkouts down.
+func (ds *discountService) CalculateCartDiscountsBatch(ctx context.Context,
+	req *models.BatchCalculationRequest) (*models.BatchCalculationResult, error) {
+
+	ctx = lane.NewContext(ctx, lane.Batch)
+
+	if max := ds.maxBatchCarts(); max > 0 && len(req.Carts) > max {
+		return nil, errors.NewValidationError(fmt.Sprintf("batch has %d carts, more than the limit of %d", len(req.Carts), max))
+	}
+
+	results := make([]models.BatchItemResult, len(req.Carts))
+	indexes := make(chan int)
+	workers := min(ds.batchConcurrency, len(req.Carts))
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = ds.priceBatchCart(ctx, i, req.Carts[i], req.DryRun)
+			}
+		}()
+	}
+
+	for i := range req.Carts {
+		if ctx.Err() != nil {
+			results[i] = models.BatchItemResult{Index: i, Err: ctx.Err()}
+			continue
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	batch := &models.BatchCalculationResult{Results: results}
+	for _, r := range results {
+		if r.Err != nil {
+			batch.Failed++
+		} else {
+			batch.Succeeded++
+		}
+	}
+	return batch, nil
+}
+
+func (ds *discountService) priceBatchCart(ctx context.Context, i int, cart *models.CalculationRequest,
+	dryRun bool) models.BatchItemResult {
+
+	if cart == nil {
+		return models.BatchItemResult{Index: i, Err: errors.NewValidationError("cart is missing")}
+	}
+	if dryRun {
+		explanation, err := ds.ExplainCartDiscounts(ctx, cart)
+		if err != nil {
+			return models.BatchItemResult{Index: i, Err: err}
+		}
+		return models.BatchItemResult{Index: i, Result: explanation.Result}
+	}
+
+	result, err := ds.CalculateCart(ctx, cart)
+	return models.BatchItemResult{Index: i, Result: result, Err: err}
+}
+
+// maxBatchCarts is the most carts one batch may have, zero for no limit
+func (ds *discountService) maxBatchCarts() int {
+	return ds.limits.MaxBatchCarts
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"github.com/shopspring/decimal"
+
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// capDiscounts scales back the instant discounts of result so together they stay within
+// calc.maxDiscount. The discounts applied last, those of lowest priority, give way first:
+// each run of equal priorities, from the last applied, is dropped while it all fits in the
+// excess, and the run the excess ends in is scaled back in proportion to its amounts,
+// rounded down to the minor unit. Cashback is paid later and never counts towards the cap.
+// It returns the discounts left applied, with the result and decisions updated to match.
+func capDiscounts(calc *calculation, result *models.DiscountedPrice, applied []appliedDiscount,
+	decisions *[]models.DiscountDecision) []appliedDiscount {
+
+	if calc.maxDiscount == nil {
+		return applied
+	}
+	excess := result.GetTotalDiscount().Sub(*calc.maxDiscount)
+	if !excess.IsPositive() {
+		return applied
+	}
+
+	var instant []int // Indexes into applied, in application order
+	for i, a := range applied {
+		if !a.discount.Cashback {
+			instant = append(instant, i)
+		}
+	}
+	amounts := make(map[int]decimal.Decimal, len(instant))
+	for end := len(instant); end > 0 && excess.IsPositive(); {
+		start := end - 1
+		priority := applied[instant[start]].discount.Priority
+		for start > 0 && applied[instant[start-1]].discount.Priority == priority {
+			start--
+		}
+		run := instant[start:end]
+		end = start
+
+		runTotal := decimal.Zero
+		for _, i := range run {
+			runTotal = runTotal.Add(applied[i].amount)
+		}
+		if runTotal.LessThanOrEqual(excess) {
+			for _, i := range run {
+				amounts[i] = decimal.Zero
+			}
+			excess = excess.Sub(runTotal)
+			continue
+		}
+		kept := runTotal.Sub(excess)
+		for _, i := range run {
+			amounts[i] = calc.currency.RoundWith(applied[i].amount.Mul(kept).Div(runTotal), models.RoundFloor)
+		}
+		excess = decimal.Zero
+	}
+
+	// Lowest priority first, as they were scaled back
+	for k := len(instant) - 1; k >= 0; k-- {
+		a := applied[instant[k]]
+		if amount, ok := amounts[instant[k]]; ok && !amount.Equal(a.amount) {
+			result.DiscountCapAdjustments = append(result.DiscountCapAdjustments, models.DiscountCapAdjustment{
+				DiscountID: a.discount.ID, Name: a.discount.Name, Requested: a.amount, Applied: amount,
+			})
+		}
+	}
+
+	kept := applied[:0:0]
+	byID := make(map[string]decimal.Decimal, len(amounts))
+	result.PointsRedeemed = 0
+	for i, a := range applied {
+		if amount, ok := amounts[i]; ok {
+			byID[a.discount.ID] = amount
+			if a.discount.Type == models.DiscountTypePointsRedemption {
+				a.points = a.discount.PointsForCredit(amount)
+			}
+			a.amount = amount
+		}
+		if a.amount.IsPositive() {
+			kept = append(kept, a)
+			result.PointsRedeemed += a.points
+		}
+	}
+
+	breakdown := result.Breakdown[:0:0]
+	result.AppliedDiscounts = make(map[string]decimal.Decimal)
+	result.FinalPrice = result.OriginalPrice
+	for _, line := range result.Breakdown {
+		if amount, ok := byID[line.DiscountID]; ok {
+			line.Amount = amount
+			if line.PointsRedeemed > 0 {
+				line.PointsRedeemed = pointsOf(kept, line.DiscountID)
+			}
+		}
+		if line.Amount.IsPositive() {
+			breakdown = append(breakdown, line)
+			result.AppliedDiscounts[line.Name] = result.AppliedDiscounts[line.Name].Add(line.Amount)
+			result.FinalPrice = result.FinalPrice.Sub(line.Amount)
+		}
+	}
+	result.Breakdown = breakdown
+
+	if decisions != nil {
+		for i := range *decisions {
+			decision := &(*decisions)[i]
+			amount, ok := byID[decision.DiscountID]
+			if !ok || decision.Outcome != models.DecisionApplied {
+				continue
+			}
+			decision.Amount = amount
+			if !amount.IsPositive() {
+				decision.Outcome, decision.Reason = models.DecisionSkipped, models.ReasonDiscountCap
+				decision.Detail = "higher-priority discounts used up the cart's maximum discount of " + calc.maxDiscount.String()
+			}
+		}
+	}
+	return kept
+}
+
+// pointsOf returns the points burnt by the applied discount with the ID
+func pointsOf(applied []appliedDiscount, discountID string) int64 {
+	for _, a := range applied {
+		if a.discount.ID == discountID {
+			return a.points
+		}
+	}
+	return 0
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// startingPrices returns the cart at the prices discounts are taken off: the lines' base
+// prices, or the prices the caller sent for legacy callers
+func (ds *discountService) startingPrices(items []models.CartItem) []models.CartItem {
+	if ds.legacyPrices {
+		return items
+	}
+	return models.StartFromBasePrices(items)
+}
+
+// enrichCart fills in the bare products of the cart's lines from the product catalog,
+// returning the lines unchanged without a catalog. Lines naming products the catalog does
+// not hold are left bare, for validateCart to reject.
+func (ds *discountService) enrichCart(ctx context.Context, items []models.CartItem) ([]models.CartItem, error) {
+	if ds.productCatalog == nil {
+		return items, nil
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if item.Product.IsBare() && !seen[item.Product.ID] {
+			seen[item.Product.ID] = true
+			ids = append(ids, item.Product.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return items, nil
+	}
+
+	products, err := ds.productCatalog.GetProducts(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+
+	enriched := make([]models.CartItem, len(items))
+	for i, item := range items {
+		enriched[i] = item
+		product, ok := products[item.Product.ID]
+		if !item.Product.IsBare() || !ok {
+			continue
+		}
+		// The line's SKU names the variant bought, the catalog's may be another
+		product.ID = item.Product.ID
+		if item.Product.SKU != "" {
+			product.SKU = item.Product.SKU
+		}
+		enriched[i].Product = product
+	}
+	return enriched, nil
+}
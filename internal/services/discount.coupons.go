@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/clock"
+)
+
+// GetAvailableCoupons lists the voucher codes the customer could enter for the cart: those
+// that apply, biggest estimated savings first, then those only missing the minimum amount
+// or quantity, in application order. Vouchers the customer can never get, and codes that
+// are private such as single-use codes, are left out.
+func (ds *discountService) GetAvailableCoupons(ctx context.Context, cartItems []models.CartItem,
+	customer models.CustomerProfile) ([]models.AvailableCoupon, error) {
+
+	now := ds.instant(ctx)
+	ctx = clock.NewContext(ctx, now)
+
+	cartItems, err := ds.enrichCart(ctx, cartItems)
+	if err != nil {
+		return nil, err
+	}
+	cartItems = ds.startingPrices(cartItems)
+
+	segments, err := ds.resolveSegments(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+	customer.Segments = segments
+
+	salesChannel, err := salesChannelOf(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	discounts, err := ds.discountRepo.GetActiveDiscounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get discounts: %w", err)
+	}
+	vouchers := discounts[:0]
+	for _, d := range discounts {
+		if d.Type == models.DiscountTypeVoucher && d.Code != "" && !d.RequiresCode() {
+			vouchers = append(vouchers, d)
+		}
+	}
+	sort.Slice(vouchers, func(i, j int) bool { return vouchers[i].AppliesBefore(&vouchers[j]) })
+
+	campaigns, err := ds.loadCampaigns(ctx, vouchers)
+	if err != nil {
+		return nil, err
+	}
+	spendLeft, err := ds.loadSpend(ctx, vouchers)
+	if err != nil {
+		return nil, err
+	}
+	introUsed, err := ds.loadIntroductoryRedemptions(ctx, vouchers, customer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	calc := &calculation{cartItems: cartItems, customer: customer, now: now, currency: ds.currency}
+	coupons := make([]models.AvailableCoupon, 0, len(vouchers))
+	for i := range vouchers {
+		d := &vouchers[i]
+		if !d.IsValidAt(now) || !d.IsApplicableToCustomer(customer) || !d.IsExposedTo(customer.ID) || !d.IsSoldOn(salesChannel) ||
+			!d.IsOccasionAt(customer, now) || !d.AdmitsMemberAt(customer, now) || d.CurrencyCode() != ds.currency.Code || !hasFundsLeft(d, campaigns, spendLeft) {
+			continue
+		}
+		if d.IsIntroductory() && d.IntroductoryOrdersLeft(customer, introUsed[d.ID]) == 0 {
+			continue
+		}
+		strategy := ds.strategyFactory.Get(d.Type)
+		if strategy == nil {
+			continue
+		}
+
+		coupon := models.AvailableCoupon{CodeValidation: *d.DescribeCode(d.Code)}
+		if strategy.IsApplicable(d, cartItems, customer, nil) {
+			coupon.Valid = true
+			coupon.EstimatedSavings = ds.estimateSavings(strategy, d, cartItems, customer, spendLeft)
+		} else if nearMiss, ok := nearMissOf(d, strategy, calc, customer); ok {
+			coupon.NearMiss = &nearMiss
+		} else {
+			continue
+		}
+		coupons = append(coupons, coupon)
+	}
+
+	sort.SliceStable(coupons, func(i, j int) bool {
+		if coupons[i].Valid != coupons[j].Valid {
+			return coupons[i].Valid
+		}
+		return coupons[i].EstimatedSavings.GreaterThan(coupons[j].EstimatedSavings)
+	})
+	return coupons, nil
+}
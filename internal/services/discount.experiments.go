@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// recordAssignments stores the variant the customer was bucketed into for every experiment
+// the calculation considered. Bucketing is deterministic, so recording the same customer
+// again agrees with the stored assignment, unless the experiment's traffic share changed
+// since: customers then stay reported under their first variant. Anonymous customers cannot
+// be followed up on and are not recorded.
+func (ds *discountService) recordAssignments(ctx context.Context, calc *calculation, exposures []models.ExperimentExposure) error {
+	if ds.experimentRepo == nil || calc.customer.ID == "" {
+		return nil
+	}
+
+	for _, exposure := range exposures {
+		err := ds.experimentRepo.RecordAssignment(ctx, models.ExperimentAssignment{
+			ExperimentID: exposure.ExperimentID,
+			CustomerID:   calc.customer.ID,
+			Variant:      exposure.Variant,
+			AssignedAt:   calc.now,
+		})
+		if err != nil && !errors.IsValidationError(err) {
+			return fmt.Errorf("failed to record experiment assignment: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// ExplainCartDiscounts prices the request as CalculateCart would without committing anything:
+// no usage, budget, spend, points or gift card balance is consumed. Alongside the result it
+// reports, for every discount of the tenant, whether it was applied, skipped or rejected and why.
+func (ds *discountService) ExplainCartDiscounts(ctx context.Context, req *models.CalculationRequest) (*models.Explanation, error) {
+	var explanation *models.Explanation
+	err := ds.schedule(ctx, func(ctx context.Context) (err error) {
+		explanation, err = ds.explainCart(ctx, req)
+		return err
+	})
+	return explanation, err
+}
+
+func (ds *discountService) explainCart(ctx context.Context, req *models.CalculationRequest) (*models.Explanation, error) {
+	run, err := ds.prepare(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	decisions := make([]models.DiscountDecision, 0, len(run.discounts))
+	result, _ := ds.applyDiscounts(run.calc, run.discounts, "", &decisions)
+	if run.calc.stopped != nil {
+		return nil, run.calc.stopped
+	}
+	result.Warnings = append(run.warnings, result.Warnings...)
+	applyGiftCards(result, run.giftCards)
+
+	return &models.Explanation{Result: result, Decisions: decisions}, nil
+}
+
+// describeDecision spells out a decision reason with the numbers behind it
+func describeDecision(reason models.DecisionReason, d *models.Discount, calc *calculation,
+	customer models.CustomerProfile, result *models.DiscountedPrice) string {
+
+	switch reason {
+	case "":
+		return ""
+	case models.ReasonInactive:
+		return "discount is switched off"
+	case models.ReasonUnpublished:
+		return fmt.Sprintf("discount is %s, not ACTIVE", d.LifecycleState())
+	case models.ReasonNotStarted:
+		return "discount starts at " + d.ValidFrom.UTC().Format(time.RFC3339)
+	case models.ReasonExpired:
+		if d.LifecycleState() == models.LifecycleExpired {
+			return "discount was expired by an approver"
+		}
+		return "discount expired at " + d.ValidTo.UTC().Format(time.RFC3339)
+	case models.ReasonUsageExhausted:
+		return fmt.Sprintf("discount has been used %d of %d times", d.UsedCount, d.UsageLimit)
+	case models.ReasonOutsideSchedule:
+		return "discount is outside its scheduled hours"
+	case models.ReasonCodeRequired:
+		if d.GeneratedCodes {
+			return "none of the discount's single-use codes was entered"
+		}
+		return "code " + d.Code + " was not entered"
+	case models.ReasonCampaignPaused:
+		return "campaign " + d.CampaignID + " is not running"
+	case models.ReasonUnsupportedType:
+		return fmt.Sprintf("discount type %q is not supported", d.Type)
+	case models.ReasonCurrencyMismatch:
+		return fmt.Sprintf("discount is in %s but the cart is priced in %s", d.CurrencyCode(), calc.currency.Code)
+	case models.ReasonBudgetExhausted:
+		return "campaign " + d.CampaignID + " has no budget left"
+	case models.ReasonSpendCapReached:
+		return "discount has given away its maximum of " + d.MaxTotalSpend.String()
+	case models.ReasonMonthlyCapReached:
+		if customer.ID == "" {
+			return "discount is capped per customer and the customer is anonymous"
+		}
+		return "customer has used this month's allowance of " + d.MonthlyCap.String()
+	case models.ReasonPriceFloor:
+		return "the items the discount targets are already at their minimum selling prices"
+	case models.ReasonIntroductoryOrdersUsed:
+		return fmt.Sprintf("customer already redeemed the program on its %d introductory orders", d.IntroductoryOrders)
+	case models.ReasonPriorityLoss:
+		return "higher-priority discounts already reduced the cart to " + result.FinalPrice.String()
+	case models.ReasonZeroAmount:
+		return "discount came to nothing on this cart"
+	case models.ReasonCustomerIneligible:
+		if !d.MatchesSegments(customer.Segments) {
+			return fmt.Sprintf("customer in segments %v is outside the discount's segments", customer.Segments)
+		}
+		if !d.IsBoundToCustomer(customer) {
+			return "code is reserved for other customers"
+		}
+		if d.Type == models.DiscountTypeEmployee && !customer.IsEmployee {
+			return "discount is for employees"
+		}
+		return fmt.Sprintf("customer with tier %q and %d previous order(s) does not qualify", customer.Tier, customer.OrderCount)
+	case models.ReasonExperimentControl:
+		variant, _ := d.Experiment.Assign(customer.ID)
+		return fmt.Sprintf("customer is in variant %q of experiment %s, which does not get the discount", variant, d.Experiment.ID)
+	case models.ReasonRegionRestricted:
+		if calc.location == nil {
+			return fmt.Sprintf("discount is limited to regions %v and the order has no location", d.AllowedRegions)
+		}
+		return fmt.Sprintf("discount is not available for orders shipping to state %q, pincode %q in region %q",
+			calc.location.State, calc.location.Pincode, calc.location.Region)
+	case models.ReasonChannelMismatch:
+		if calc.channel == "" {
+			return fmt.Sprintf("discount is sold on %v and the order names no channel", d.Channels)
+		}
+		return fmt.Sprintf("discount is sold on %v, not %s", d.Channels, calc.channel)
+	case models.ReasonOutsideOccasion:
+		if d.Occasion.Kind == models.OccasionBirthday {
+			return fmt.Sprintf("discount applies within %d day(s) of the customer's birthday", d.Occasion.WindowDays)
+		}
+		return fmt.Sprintf("discount applies within %d day(s) of the customer's signup anniversary", d.Occasion.WindowDays)
+	case models.ReasonMembershipRequired:
+		if calc.customer.Membership.IsActiveAt(calc.now) {
+			return fmt.Sprintf("discount is for members of %v, not %q", d.MembershipPlans, calc.customer.Membership.Plan)
+		}
+		return fmt.Sprintf("discount is for members of %v and the customer holds no active membership", d.MembershipPlans)
+	case models.ReasonStockLevel:
+		return fmt.Sprintf("stock of the targeted products is outside the discount's thresholds of %s", stockRange(d))
+	case models.ReasonMinAmountNotMet:
+		return fmt.Sprintf("cart total %s is below the minimum %s",
+			models.GetPromotableTotal(calc.cartItems).String(), d.MinAmount.String())
+	case models.ReasonMinQuantityNotMet:
+		return fmt.Sprintf("cart holds fewer than the %d eligible units required", d.MinQuantity)
+	case models.ReasonSpendTierNotReached:
+		if len(d.SpendTiers) == 0 {
+			return "discount has no spend tiers"
+		}
+		return "cart subtotal after brand discounts is below the lowest tier of " + d.SpendTiers[0].Threshold.String()
+	case models.ReasonNoMatchingItems:
+		return "no cart item is eligible for the discount"
+	case models.ReasonExcluded:
+		return "the cart's items are excluded from the discount"
+	case models.ReasonItemsOptedOut:
+		return "every cart item is excluded from promotions"
+	case models.ReasonPaymentMismatch:
+		return "payment method, bank or wallet does not qualify"
+	case models.ReasonCardMismatch:
+		return "the card's type, BIN or network does not qualify"
+	case models.ReasonNoPoints:
+		return "customer has no loyalty points to redeem"
+	case models.ReasonSelfReferral:
+		return "referrers cannot use their own code"
+	default:
+		return "discount conditions are not met"
+	}
+}
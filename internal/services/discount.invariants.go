@@ -0,0 +1,123 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// InvariantViolation describes a calculation whose result breaks the engine's own rules,
+// together with everything needed to reproduce it
+type InvariantViolation struct {
+	Problems []string
+	Request  *models.CalculationRequest
+	Result   *models.DiscountedPrice
+}
+
+func (v *InvariantViolation) Error() string {
+	request, _ := json.Marshal(v.Request)
+	result, _ := json.Marshal(v.Result)
+	return fmt.Sprintf("pricing invariants violated: %s; request: %s; result: %s",
+		strings.Join(v.Problems, "; "), request, result)
+}
+
+// checkInvariants verifies a priced result against the inputs it was computed from,
+// returning a description of every rule it breaks
+func checkInvariants(calc *calculation, applied []appliedDiscount, result *models.DiscountedPrice) []string {
+	var problems []string
+	fail := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if total := models.GetCartTotal(calc.cartItems); !result.OriginalPrice.Equal(total) {
+		fail("original price %s differs from cart total %s", result.OriginalPrice, total)
+	}
+	if result.FinalPrice.IsNegative() {
+		fail("final price %s is negative", result.FinalPrice)
+	}
+	if result.FinalPrice.GreaterThan(result.OriginalPrice) {
+		fail("final price %s is above the original price %s", result.FinalPrice, result.OriginalPrice)
+	}
+
+	allocated := decimal.Zero
+	for _, line := range result.Breakdown {
+		if !line.Amount.IsPositive() {
+			fail("discount %s has non-positive amount %s", line.DiscountID, line.Amount)
+		}
+		allocated = allocated.Add(line.Amount)
+	}
+	discounted := result.OriginalPrice.Sub(result.FinalPrice)
+	if !allocated.Equal(discounted) {
+		fail("discounts add up to %s but the price dropped by %s", allocated, discounted)
+	}
+	if total := result.GetTotalDiscount(); !total.Equal(discounted) {
+		fail("applied discounts add up to %s but the price dropped by %s", total, discounted)
+	}
+	if calc.maxDiscount != nil && discounted.GreaterThan(*calc.maxDiscount) {
+		fail("discounts add up to %s above the cart's maximum discount %s", discounted, *calc.maxDiscount)
+	}
+
+	taxed := decimal.Zero
+	for _, line := range result.TaxLines {
+		if line.Tax.IsNegative() || line.TaxableAmount.IsNegative() {
+			fail("tax line at %s%% has negative amounts", line.Rate)
+		}
+		taxed = taxed.Add(line.Tax)
+	}
+	if !taxed.Equal(result.TotalTax) {
+		fail("tax lines add up to %s but the total tax is %s", taxed, result.TotalTax)
+	}
+
+	for _, cashback := range result.PendingCashback {
+		if !cashback.Amount.IsPositive() {
+			fail("cashback %s has non-positive amount %s", cashback.DiscountID, cashback.Amount)
+		}
+	}
+
+	campaignSpend := make(map[string]decimal.Decimal)
+	var points int64
+	for _, a := range applied {
+		d := a.discount
+		if !d.MaxAmount.IsZero() && a.amount.GreaterThan(d.MaxAmount) {
+			fail("discount %s gave %s above its maximum %s", d.ID, a.amount, d.MaxAmount)
+		}
+		if remaining, capped := calc.spendLeft[d.ID]; capped && a.amount.GreaterThan(remaining) {
+			fail("discount %s gave %s with only %s of its spend cap left", d.ID, a.amount, remaining)
+		}
+		if campaign := calc.campaigns[d.CampaignID]; campaign != nil && campaign.HasBudgetLimit() {
+			campaignSpend[d.CampaignID] = campaignSpend[d.CampaignID].Add(a.amount)
+		}
+		points += a.points
+	}
+	for id, spent := range campaignSpend {
+		if remaining := calc.campaigns[id].RemainingBudget(); spent.GreaterThan(remaining) {
+			fail("campaign %s gave %s with only %s of its budget left", id, spent, remaining)
+		}
+	}
+
+	if points != result.PointsRedeemed {
+		fail("discounts burnt %d points but the result reports %d", points, result.PointsRedeemed)
+	}
+	if result.PointsRedeemed > calc.customer.PointsBalance {
+		fail("%d points redeemed from a balance of %d", result.PointsRedeemed, calc.customer.PointsBalance)
+	}
+
+	paid := decimal.Zero
+	for code, amount := range result.GiftCardsApplied {
+		if amount.IsNegative() {
+			fail("gift card %s has negative amount %s", code, amount)
+		}
+		paid = paid.Add(amount)
+	}
+	if result.AmountDue.IsNegative() {
+		fail("amount due %s is negative", result.AmountDue)
+	}
+	if due := result.FinalPrice.Add(result.TotalTax).Sub(paid); !result.FinalPrice.IsNegative() && !result.AmountDue.Equal(due) {
+		fail("amount due %s differs from final price %s plus tax %s less gift cards %s", result.AmountDue, result.FinalPrice, result.TotalTax, paid)
+	}
+
+	return problems
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// loadStock returns the stock of the cart's products when some discount depends on it,
+// read as the cart is priced so discounts follow the stock left. Without an inventory
+// provider the stock is unknown and nil is returned.
+func (ds *discountService) loadStock(ctx context.Context, discounts []models.Discount, cart []models.CartItem) (map[string]int, error) {
+	if ds.inventory == nil {
+		return nil, nil
+	}
+	needed := false
+	for i := range discounts {
+		needed = needed || discounts[i].HasStockCondition()
+	}
+	if !needed {
+		return nil, nil
+	}
+
+	var ids []string
+	seen := make(map[string]bool, len(cart))
+	for _, item := range cart {
+		if !seen[item.Product.ID] {
+			seen[item.Product.ID] = true
+			ids = append(ids, item.Product.ID)
+		}
+	}
+	stock, err := ds.inventory.GetStock(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock: %w", err)
+	}
+	return stock, nil
+}
+
+// stockedDiscount returns the discount narrowed to the cart's targeted products whose
+// stock it allows, reporting false when it targets some and allows none. Products of
+// unknown stock are never allowed.
+func stockedDiscount(calc *calculation, d models.Discount) (models.Discount, bool) {
+	if !d.HasStockCondition() {
+		return d, true
+	}
+	var targeted, out []string
+	for _, item := range calc.cartItems {
+		if !d.MatchesProduct(item.Product) {
+			continue
+		}
+		targeted = append(targeted, item.Product.ID)
+		if stock, known := calc.stock[item.Product.ID]; !known || !d.StockAllows(stock) {
+			out = append(out, item.Product.ID)
+		}
+	}
+	if len(targeted) > 0 && len(out) == len(targeted) {
+		return d, false
+	}
+	if len(out) == 0 {
+		return d, true
+	}
+	return d.WithoutProducts(out), true
+}
+
+// stockRange describes the discount's stock thresholds
+func stockRange(d *models.Discount) string {
+	switch {
+	case d.MinStockThreshold != nil && d.MaxStockThreshold != nil:
+		return fmt.Sprintf("%d to %d units", *d.MinStockThreshold, *d.MaxStockThreshold)
+	case d.MinStockThreshold != nil:
+		return fmt.Sprintf("at least %d units", *d.MinStockThreshold)
+	default:
+		return fmt.Sprintf("at most %d units", *d.MaxStockThreshold)
+	}
+}
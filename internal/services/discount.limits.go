@@ -0,0 +1,67 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// RequestLimits bounds the work a single calculation may cause. Zero fields are unlimited.
+type RequestLimits struct {
+	MaxCartItems  int // Cart lines, before malformed items are dropped
+	MaxCodes      int // Entries in each of Codes and GiftCardCodes
+	MaxDiscounts  int // Discounts considered, highest priority first
+	MaxBatchCarts int // Carts in one CalculateCartDiscountsBatch call
+}
+
+// limitRequest enforces the cart and code limits. In strict mode an oversized request is
+// rejected; in lenient mode the excess is dropped and described in the returned warnings.
+// The caller's request is never modified.
+func (ds *discountService) limitRequest(req *models.CalculationRequest) (*models.CalculationRequest, []string, error) {
+	limits := ds.limits
+	lenient := ds.modeFor(req) == models.ValidationLenient
+	limited := *req
+	var warnings []string
+
+	truncate := func(what string, n, max int) (int, error) {
+		if max <= 0 || n <= max {
+			return n, nil
+		}
+		if !lenient {
+			return 0, errors.NewValidationError(fmt.Sprintf("request has %d %s, more than the limit of %d", n, what, max))
+		}
+		warnings = append(warnings, fmt.Sprintf("ignored %d of %d %s over the limit of %d", n-max, n, what, max))
+		return max, nil
+	}
+
+	n, err := truncate("cart items", len(req.CartItems), limits.MaxCartItems)
+	if err != nil {
+		return nil, nil, err
+	}
+	limited.CartItems = req.CartItems[:n]
+
+	if n, err = truncate("codes", len(req.Codes), limits.MaxCodes); err != nil {
+		return nil, nil, err
+	}
+	limited.Codes = req.Codes[:n]
+
+	if n, err = truncate("gift card codes", len(req.GiftCardCodes), limits.MaxCodes); err != nil {
+		return nil, nil, err
+	}
+	limited.GiftCardCodes = req.GiftCardCodes[:n]
+
+	return &limited, warnings, nil
+}
+
+// limitDiscounts keeps the first MaxDiscounts of the sorted discounts. Discount counts are
+// outside the customer's control, so the excess is always dropped with a warning rather
+// than failing the request.
+func (ds *discountService) limitDiscounts(discounts []models.Discount) ([]models.Discount, []string) {
+	max := ds.limits.MaxDiscounts
+	if max <= 0 || len(discounts) <= max {
+		return discounts, nil
+	}
+	warning := fmt.Sprintf("considered only the %d highest-priority of %d discounts", max, len(discounts))
+	return discounts[:max], []string{warning}
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"github.com/shopspring/decimal"
+
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// memberSavings adds up what the members-only discounts took off the cart, cashback left
+// out as it is not taken off the price. It is nil when none of them applied.
+func memberSavings(customer models.CustomerProfile, applied []appliedDiscount) *models.MemberSavings {
+	total := decimal.Zero
+	for _, a := range applied {
+		if a.discount.IsMembersOnly() && !a.discount.Cashback {
+			total = total.Add(a.amount)
+		}
+	}
+	if !total.IsPositive() {
+		return nil
+	}
+	return &models.MemberSavings{Plan: customer.Membership.Plan, Amount: total}
+}
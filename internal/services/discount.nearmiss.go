@@ -0,0 +1,68 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ahsmha/discounts/internal/discount"
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// nearMissOf reports whether a discount its strategy rejected would have applied but for
+// its minimum amount or quantity, and what the cart is missing. The strategy is asked again
+// with those minimums lifted, so discounts failing any other condition are never nudged towards.
+func nearMissOf(d *models.Discount, strategy discount.DiscountStrategy, calc *calculation,
+	customer models.CustomerProfile) (models.NearMissDiscount, bool) {
+
+	if d.MinAmount.IsZero() && d.MinQuantity == 0 {
+		return models.NearMissDiscount{}, false
+	}
+	relaxed := *d
+	relaxed.MinAmount, relaxed.MinQuantity = decimal.Zero, 0
+	if !strategy.IsApplicable(&relaxed, calc.cartItems, customer, calc.paymentInfo) {
+		return models.NearMissDiscount{}, false
+	}
+
+	nearMiss := models.NearMissDiscount{
+		DiscountID:  d.ID,
+		DiscountRef: d.StableRef(),
+		Name:        d.Name,
+		Type:        d.Type,
+		AmountGap:   decimal.Zero,
+	}
+	if total := models.GetPromotableTotal(calc.cartItems); total.LessThan(d.MinAmount) {
+		nearMiss.Reason = models.ReasonMinAmountNotMet
+		nearMiss.AmountGap = d.MinAmount.Sub(total)
+	}
+	if units := eligibleUnits(d, calc.cartItems); units < d.MinQuantity {
+		if nearMiss.Reason == "" {
+			nearMiss.Reason = models.ReasonMinQuantityNotMet
+		}
+		nearMiss.UnitsGap = d.MinQuantity - units
+	}
+
+	switch {
+	case nearMiss.Reason == "":
+		return models.NearMissDiscount{}, false // Rejected for a reason the minimums do not explain
+	case nearMiss.UnitsGap == 0:
+		nearMiss.Message = fmt.Sprintf("Add %s more to unlock %s", nearMiss.AmountGap.String(), d.Name)
+	case nearMiss.AmountGap.IsZero():
+		nearMiss.Message = fmt.Sprintf("Add %d more eligible item(s) to unlock %s", nearMiss.UnitsGap, d.Name)
+	default:
+		nearMiss.Message = fmt.Sprintf("Add %s more, including %d eligible item(s), to unlock %s",
+			nearMiss.AmountGap.String(), nearMiss.UnitsGap, d.Name)
+	}
+	return nearMiss, true
+}
+
+// eligibleUnits counts the units of the lines open to promotions that the discount targets
+func eligibleUnits(d *models.Discount, cart []models.CartItem) int {
+	units := 0
+	for _, item := range cart {
+		if !item.ExcludeFromPromotions && d.MatchesProduct(item.Product) {
+			units += item.Quantity
+		}
+	}
+	return units
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/clock"
+)
+
+// ListOffers returns the discounts the customer can currently use, in priority order unless
+// the service has an offer ranker. Cart-dependent checks
+// such as minimum amounts are left to pricing; the offer carries them for display. Codes
+// that must be entered, such as referral codes, are private and never listed.
+func (ds *discountService) ListOffers(ctx context.Context, customer models.CustomerProfile) ([]models.Offer, error) {
+	now := ds.instant(ctx)
+	ctx = clock.NewContext(ctx, now)
+
+	segments, err := ds.resolveSegments(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+	customer.Segments = segments
+
+	salesChannel, err := salesChannelOf(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	discounts, err := ds.discountRepo.GetActiveDiscounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get discounts: %w", err)
+	}
+
+	// Application order, so equal priorities list the same way on every call
+	sort.Slice(discounts, func(i, j int) bool { return discounts[i].AppliesBefore(&discounts[j]) })
+
+	campaigns, err := ds.loadCampaigns(ctx, discounts)
+	if err != nil {
+		return nil, err
+	}
+
+	spendLeft, err := ds.loadSpend(ctx, discounts)
+	if err != nil {
+		return nil, err
+	}
+
+	introUsed, err := ds.loadIntroductoryRedemptions(ctx, discounts, customer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	offers := make([]models.Offer, 0, len(discounts))
+	for i := range discounts {
+		d := &discounts[i]
+		if d.RequiresCode() || !d.IsValidAt(now) || !d.IsApplicableToCustomer(customer) || !d.IsExposedTo(customer.ID) || !d.IsSoldOn(salesChannel) ||
+			!d.IsOccasionAt(customer, now) || !d.AdmitsMemberAt(customer, now) || !hasFundsLeft(d, campaigns, spendLeft) {
+			continue
+		}
+
+		var ordersLeft *int
+		if d.IsIntroductory() {
+			left := d.IntroductoryOrdersLeft(customer, introUsed[d.ID])
+			if left == 0 {
+				continue
+			}
+			ordersLeft = &left
+		}
+
+		offers = append(offers, models.Offer{
+			DiscountID:   d.ID,
+			Name:         d.Name,
+			Type:         d.Type,
+			Code:         d.Code,
+			Value:        d.Value,
+			IsPercentage: d.IsPercentage,
+			MinAmount:    d.MinAmount,
+			MaxAmount:    d.MaxAmount,
+			Priority:     d.Priority,
+			ExpiresAt:    d.ValidTo,
+			Urgency:      d.UrgencyAt(now),
+			OrdersLeft:   ordersLeft,
+		})
+	}
+
+	if ds.offerRanker != nil {
+		ds.offerRanker.Rank(offers, customer)
+	}
+	if ds.offerScorer != nil && len(offers) > 1 {
+		ds.scoreOffers(ctx, offers, customer)
+	}
+	return offers, nil
+}
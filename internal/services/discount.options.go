@@ -0,0 +1,334 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/ahsmha/discounts/internal/discount"
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/shopspring/decimal"
+)
+
+// Option configures optional behaviour of the discount service
+type Option func(*discountService)
+
+// WithCounterfactualPrices makes CalculateCartDiscounts report, for every applied
+// discount, the final price the cart would have had without that single discount.
+func WithCounterfactualPrices() Option {
+	return func(ds *discountService) {
+		ds.counterfactuals = true
+	}
+}
+
+// WithRedemptionRepository records a redemption for every discount applied by
+// CalculateCartDiscounts, feeding the reporting service.
+func WithRedemptionRepository(repo interfaces.IRedemptionRepository) Option {
+	return func(ds *discountService) {
+		ds.redemptionRepo = repo
+	}
+}
+
+// WithPricingRecords keeps a pricing record of every order CalculateCart commits with an
+// OrderID, so VerifyOrderPricing can recalculate it later
+func WithPricingRecords(repo interfaces.IPricingRecordRepository) Option {
+	return func(ds *discountService) {
+		ds.pricingRecords = repo
+	}
+}
+
+// WithTotalTolerance sets how far a request's ExpectedTotal may drift from the computed
+// cart total before CalculateCart rejects it. Defaults to 0.01.
+func WithTotalTolerance(tolerance decimal.Decimal) Option {
+	return func(ds *discountService) {
+		ds.totalTolerance = tolerance.Abs()
+	}
+}
+
+// WithCurrency sets the currency carts are priced in, defaulting to models.DefaultCurrency.
+// Applied amounts are rounded half-up to its minor unit, and discounts in other currencies
+// are skipped. The currency must be valid, see models.Currency.Validate.
+func WithCurrency(currency models.Currency) Option {
+	return func(ds *discountService) {
+		ds.currency = currency
+	}
+}
+
+// WithRounding sets how applied amounts are rounded to the currency's minor unit for
+// discounts without their own Rounding. Defaults to half-up.
+func WithRounding(mode models.RoundingMode) Option {
+	return func(ds *discountService) {
+		ds.rounding = mode
+	}
+}
+
+// WithScheduler runs every calculation and explanation on the capacity the scheduler
+// reserves for the request's traffic lane, see lane.NewContext. Batch carts always run in
+// the batch lane.
+func WithScheduler(scheduler interfaces.IWorkScheduler) Option {
+	return func(ds *discountService) {
+		ds.scheduler = scheduler
+	}
+}
+
+// WithClock sets the clock used for every time-based check, letting tests freeze time
+func WithClock(c clock.Clock) Option {
+	return func(ds *discountService) {
+		ds.clock = c
+	}
+}
+
+// WithValidationMode sets how malformed cart items are handled when a request does not
+// choose for itself. Defaults to strict.
+func WithValidationMode(mode models.ValidationMode) Option {
+	return func(ds *discountService) {
+		ds.validationMode = mode
+	}
+}
+
+// WithCampaignRepository enforces campaign switches and budgets: discounts belonging to an
+// inactive campaign are skipped, and applied amounts are capped by and drawn from the
+// campaign's remaining budget
+func WithCampaignRepository(repo interfaces.ICampaignRepository) Option {
+	return func(ds *discountService) {
+		ds.campaignRepo = repo
+	}
+}
+
+// WithSpendTracker enforces Discount.MaxTotalSpend: applied amounts are capped by what the
+// discount has left, accumulated in the tracker on commit, and the discount is deactivated,
+// where the repository allows it, once its cap is reached. Employee discounts' MonthlyCap is kept in the same tracker, per
+// customer and calendar month, see models.MonthlySpendKey. Defaults to an in-memory tracker,
+// which only counts the spend of this process.
+func WithSpendTracker(tracker interfaces.ISpendTracker) Option {
+	return func(ds *discountService) {
+		ds.spendTracker = tracker
+	}
+}
+
+// WithSpendCapErrorHandler passes onError the discounts that reached their MaxTotalSpend but
+// could not be deactivated, e.g. in a read-only repository, typically to be logged. The
+// checkout that reached the cap still succeeds, the spend tracker keeps the discount from
+// giving anything more.
+func WithSpendCapErrorHandler(onError func(discountID string, err error)) Option {
+	return func(ds *discountService) {
+		ds.onSpendCapError = onError
+	}
+}
+
+// WithLoyaltyProvider lets requests that opt in with RedeemPoints burn the customer's
+// loyalty points through points redemption discounts
+func WithLoyaltyProvider(provider interfaces.ILoyaltyProvider) Option {
+	return func(ds *discountService) {
+		ds.loyaltyProvider = provider
+	}
+}
+
+// WithGroupPricing prices every cart from the price tiers of the customer's group, looked up
+// in customers, before any discount applies. The matrix must be valid, see
+// models.PriceMatrix.Validate.
+func WithGroupPricing(customers interfaces.ICustomerProvider, matrix models.PriceMatrix) Option {
+	return func(ds *discountService) {
+		ds.customerProvider = customers
+		ds.priceMatrix = matrix
+	}
+}
+
+// WithProductCatalog fills in the products of cart lines naming only a product ID, and
+// optionally a SKU, from the catalog, see models.Product.IsBare. Lines naming products the
+// catalog does not hold are invalid. Wrap remote catalogs in a
+// repositories.CachedProductCatalog.
+func WithProductCatalog(catalog interfaces.ProductCatalog) Option {
+	return func(ds *discountService) {
+		ds.productCatalog = catalog
+	}
+}
+
+// WithInventoryProvider reads the stock of the products of every cart priced against
+// discounts depending on stock, see models.Discount.StockAllows. Without one those
+// discounts never apply.
+func WithInventoryProvider(inventory interfaces.InventoryProvider) Option {
+	return func(ds *discountService) {
+		ds.inventory = inventory
+	}
+}
+
+// WithPricingHook lets the hook reprice every cart priced, after group pricing and before
+// any discount applies. The adjustments are reported in DiscountedPrice.PriceAdjustments.
+func WithPricingHook(hook interfaces.PricingHook) Option {
+	return func(ds *discountService) {
+		ds.pricingHook = hook
+	}
+}
+
+// WithLegacyCurrentPrices prices carts from the CurrentPrice of their lines as callers send
+// it, instead of starting from BasePrice, for callers that still bake brand discounts into
+// CurrentPrice themselves. Brand and category discounts set up in the service are taken off
+// again, so such callers should not have any.
+func WithLegacyCurrentPrices() Option {
+	return func(ds *discountService) {
+		ds.legacyPrices = true
+	}
+}
+
+// WithSegmentResolver looks up the segments of every customer priced, validated or listed
+// offers in the resolver, adding them to any segments the caller supplied
+func WithSegmentResolver(resolver interfaces.SegmentResolver) Option {
+	return func(ds *discountService) {
+		ds.segmentResolver = resolver
+	}
+}
+
+// WithPriceFloor keeps discounts from taking any unit below its cost plus the policy's
+// margin. The policy must be valid, see models.PriceFloorPolicy.Validate.
+func WithPriceFloor(policy models.PriceFloorPolicy) Option {
+	return func(ds *discountService) {
+		ds.priceFloor = &policy
+	}
+}
+
+// WithMaxDiscountPercent caps the instant discounts of every cart at percent of its original
+// price, e.g. 70 for "no cart may exceed 70% total discount". Once every discount has been
+// applied, those of lowest priority are scaled back to fit, see
+// models.DiscountedPrice.DiscountCapAdjustments. The percentage must be valid, see
+// models.CheckMaxDiscountPercent.
+func WithMaxDiscountPercent(percent decimal.Decimal) Option {
+	return func(ds *discountService) {
+		ds.maxDiscountPct = &percent
+	}
+}
+
+// WithPointsEarnRate reports in every result the loyalty points earned on the final price,
+// at rate points per currency unit (e.g. 0.01 for one point per 100 spent)
+func WithPointsEarnRate(rate decimal.Decimal) Option {
+	return func(ds *discountService) {
+		ds.pointsEarnRate = rate
+	}
+}
+
+// WithExperimentRepository records the variant every customer priced is bucketed into for
+// each discount experiment, feeding the incrementality reports of the reporting service.
+// Explanations and dry runs record nothing.
+func WithExperimentRepository(repo interfaces.IExperimentRepository) Option {
+	return func(ds *discountService) {
+		ds.experimentRepo = repo
+	}
+}
+
+// WithIdempotencyStore honours CalculationRequest.IdempotencyKey: a repeated request returns
+// the stored result without consuming usage, budget or points again, and a key replayed
+// with a different payload fails with a conflict error
+func WithIdempotencyStore(store interfaces.IIdempotencyStore) Option {
+	return func(ds *discountService) {
+		ds.idempotencyStore = store
+	}
+}
+
+// WithReferralLedger records a pending reward for the referrer whenever a referral code is
+// applied
+func WithReferralLedger(ledger interfaces.IReferralLedger) Option {
+	return func(ds *discountService) {
+		ds.referralLedger = ledger
+	}
+}
+
+// WithGiftCardRepository lets requests pay with the gift cards listed in GiftCardCodes. Cards
+// are drawn down after every discount has been applied.
+func WithGiftCardRepository(repo interfaces.IGiftCardRepository) Option {
+	return func(ds *discountService) {
+		ds.giftCardRepo = repo
+	}
+}
+
+// WithCouponCodeRepository accepts the single-use codes minted by the coupon code service:
+// entering one at checkout unlocks its discount, and the code is used up on commit
+func WithCouponCodeRepository(repo interfaces.ICouponCodeRepository) Option {
+	return func(ds *discountService) {
+		ds.couponCodeRepo = repo
+	}
+}
+
+// WithOfferRanker sets the order in which ListOffers returns offers. Defaults to priority.
+func WithOfferRanker(ranker interfaces.IOfferRanker) Option {
+	return func(ds *discountService) {
+		ds.offerRanker = ranker
+	}
+}
+
+// WithPersonalizationScorer orders ListOffers by the scores scorer gives the offers whenever
+// more than one is listed, the offer ranker only breaking ties. A scorer that fails, times
+// out or returns the wrong number of scores is replaced for that call by cfg.Fallback.
+func WithPersonalizationScorer(scorer interfaces.PersonalizationScorer, cfg PersonalizationConfig) Option {
+	return func(ds *discountService) {
+		ds.offerScorer = scorer
+		ds.personalization = cfg
+	}
+}
+
+// WithPipelineConfig applies discounts phase by phase as configured instead of purely by
+// priority. It panics on an invalid config so misconfiguration fails at startup; configs
+// loaded at runtime should be checked with PipelineConfig.Validate first.
+func WithPipelineConfig(cfg PipelineConfig) Option {
+	if err := cfg.Validate(); err != nil {
+		panic("invalid pipeline config: " + err.Error())
+	}
+	return func(ds *discountService) {
+		ds.phases = cfg.phaseIndex()
+	}
+}
+
+// WithStrategy prices the discounts of discountType with strategy, adding a discount type
+// of the embedder's own, e.g. "affiliate", or replacing a built-in one for this service
+// only. Custom types cannot be named in pipeline phases, so they apply after every phase.
+// It panics on an empty type or nil strategy so misconfiguration fails at startup.
+func WithStrategy(discountType models.DiscountType, strategy discount.DiscountStrategy) Option {
+	if discountType == "" || strategy == nil {
+		panic(fmt.Sprintf("invalid strategy registration for discount type %q", discountType))
+	}
+	return func(ds *discountService) {
+		_ = ds.strategyFactory.Register(discountType, strategy)
+	}
+}
+
+// WithInvariantChecks verifies every calculation before anything is committed: discounts
+// add up to the price reduction, no line or price is negative or above the original price,
+// tax lines add up to the total tax, and maximum amounts, spend caps, campaign budgets, the
+// cart discount cap and points balances are respected. Violations are passed to onViolation, typically to be
+// logged; a nil onViolation panics with the violation instead. Meant for staging and
+// tests, where logic drift should surface loudly.
+func WithInvariantChecks(onViolation func(*InvariantViolation)) Option {
+	return func(ds *discountService) {
+		ds.checkInvariants = true
+		ds.onViolation = onViolation
+	}
+}
+
+// WithRequestLimits caps cart size, entered codes and discounts considered per calculation
+// so pathological requests cannot exhaust memory. Oversized carts and code lists are
+// rejected in strict mode and truncated with warnings in lenient mode; surplus discounts
+// are always dropped lowest priority first, with a warning.
+func WithRequestLimits(limits RequestLimits) Option {
+	return func(ds *discountService) {
+		ds.limits = limits
+	}
+}
+
+// WithCodeValidationLimits rate limits ValidateDiscountCode per customer and per client
+// address, counting attempts in counter, so voucher codes cannot be brute-forced. Callers
+// over a limit get a too many attempts error until its window resets.
+func WithCodeValidationLimits(counter interfaces.IAttemptCounter, limits CodeValidationLimits) Option {
+	return func(ds *discountService) {
+		ds.attemptCounter = counter
+		ds.codeLimits = limits
+	}
+}
+
+// WithBatchConcurrency sets how many carts CalculateCartDiscountsBatch prices at once.
+// Defaults to 8.
+func WithBatchConcurrency(n int) Option {
+	return func(ds *discountService) {
+		if n > 0 {
+			ds.batchConcurrency = n
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ahsmha/discounts/internal/discount"
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// PipelinePhase is a step of the discount pipeline; its discounts are applied in priority
+// order before those of any later phase
+type PipelinePhase struct {
+	Name  string                `json:"name"`
+	Types []models.DiscountType `json:"types"`
+}
+
+// PipelineConfig sets the order in which discount types are applied, letting each business
+// unit choose its own sequencing, e.g. brand, then voucher, then bank. Types not named in
+// any phase are applied after every phase. Without a config, discounts apply purely by
+// priority.
+type PipelineConfig struct {
+	Phases []PipelinePhase `json:"phases"`
+}
+
+// Validate checks that every phase is named, lists known discount types, and that no type
+// appears twice
+func (c PipelineConfig) Validate() error {
+	factory := discount.NewStrategyFactory()
+	seen := make(map[models.DiscountType]string)
+	names := make(map[string]bool)
+
+	for i, phase := range c.Phases {
+		if phase.Name == "" {
+			return fmt.Errorf("pipeline phase %d has no name", i)
+		}
+		if names[phase.Name] {
+			return fmt.Errorf("duplicate pipeline phase %q", phase.Name)
+		}
+		names[phase.Name] = true
+
+		if len(phase.Types) == 0 {
+			return fmt.Errorf("pipeline phase %q has no discount types", phase.Name)
+		}
+		for _, discountType := range phase.Types {
+			if factory.Get(discountType) == nil {
+				return fmt.Errorf("pipeline phase %q: unknown discount type %q", phase.Name, discountType)
+			}
+			if other, dup := seen[discountType]; dup {
+				return fmt.Errorf("discount type %q is in both phase %q and phase %q", discountType, other, phase.Name)
+			}
+			seen[discountType] = phase.Name
+		}
+	}
+	return nil
+}
+
+// phaseIndex maps every configured type to the position of its phase
+func (c PipelineConfig) phaseIndex() map[models.DiscountType]int {
+	index := make(map[models.DiscountType]int)
+	for i, phase := range c.Phases {
+		for _, discountType := range phase.Types {
+			index[discountType] = i
+		}
+	}
+	return index
+}
+
+// sortDiscounts orders discounts for application: by phase when a pipeline is configured,
+// then as models.Discount.AppliesBefore does
+func (ds *discountService) sortDiscounts(discounts []models.Discount) {
+	phase := func(d models.Discount) int {
+		if i, ok := ds.phases[d.Type]; ok {
+			return i
+		}
+		return len(ds.phases)
+	}
+
+	sort.Slice(discounts, func(i, j int) bool {
+		if ds.phases != nil {
+			if pi, pj := phase(discounts[i]), phase(discounts[j]); pi != pj {
+				return pi < pj
+			}
+		}
+		return discounts[i].AppliesBefore(&discounts[j])
+	})
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// priceFloors tracks what is left of every cart line's price as discounts are taken off,
+// so no discount pushes a line below its floor
+type priceFloors struct {
+	cart  []models.CartItem
+	net   []decimal.Decimal // Line price less the discounts taken off it so far
+	floor []decimal.Decimal // Lowest price the line may be sold at
+}
+
+// newPriceFloors returns the floors of the cart's lines under the policy, nil when no line
+// has a floor
+func newPriceFloors(cart []models.CartItem, policy *models.PriceFloorPolicy) *priceFloors {
+	f := &priceFloors{cart: cart, net: make([]decimal.Decimal, len(cart)), floor: make([]decimal.Decimal, len(cart))}
+	floored := false
+	for i, item := range cart {
+		f.net[i] = item.GetTotalPrice()
+		f.floor[i] = policy.UnitFloor(item.Product).Mul(decimal.NewFromInt(int64(item.Quantity)))
+		floored = floored || f.floor[i].IsPositive()
+	}
+	if !floored {
+		return nil
+	}
+	return f
+}
+
+// headroom returns how much can still be taken off the lines the discount targets, falling
+// back to every promotable line for discounts targeting none of them
+func (f *priceFloors) headroom(d *models.Discount) ([]int, decimal.Decimal) {
+	targets := discountedLines(f.cart, f.net, d)
+	if len(targets) == 0 {
+		targets = discountedLines(f.cart, f.net, nil)
+	}
+
+	room := decimal.Zero
+	for _, i := range targets {
+		room = room.Add(decimal.Max(f.net[i].Sub(f.floor[i]), decimal.Zero))
+	}
+	return targets, room
+}
+
+// take takes an amount within the targets' headroom off them, in proportion to the room
+// each has left
+func (f *priceFloors) take(targets []int, amount decimal.Decimal) {
+	room := make([]decimal.Decimal, len(f.cart))
+	var roomy []int
+	for _, i := range targets {
+		if room[i] = f.net[i].Sub(f.floor[i]); room[i].IsPositive() {
+			roomy = append(roomy, i)
+		}
+	}
+	left := append([]decimal.Decimal(nil), room...)
+	spreadDiscount(left, roomy, amount)
+	for _, i := range roomy {
+		f.net[i] = f.net[i].Sub(room[i].Sub(left[i]))
+	}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// adjustPrices lets the pricing hook reprice the cart's lines, returning the repriced
+// cart and the adjustments made. Adjustments of lines the cart does not have, repeated
+// adjustments and negative prices fail the calculation.
+func (ds *discountService) adjustPrices(ctx context.Context, calc *calculation) ([]models.CartItem, []models.PriceAdjustment, error) {
+	if ds.pricingHook == nil {
+		return calc.cartItems, nil, nil
+	}
+
+	adjustments, err := ds.pricingHook.AdjustPrices(ctx, calc.cartItems, calc.customer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("pricing hook failed: %w", err)
+	}
+	if len(adjustments) == 0 {
+		return calc.cartItems, nil, nil
+	}
+
+	cart := append([]models.CartItem(nil), calc.cartItems...)
+	seen := make(map[int]bool, len(adjustments))
+	applied := make([]models.PriceAdjustment, 0, len(adjustments))
+	for _, adj := range adjustments {
+		switch {
+		case adj.Line < 0 || adj.Line >= len(cart):
+			return nil, nil, fmt.Errorf("pricing hook adjusted line %d of a %d-line cart", adj.Line, len(cart))
+		case seen[adj.Line]:
+			return nil, nil, fmt.Errorf("pricing hook adjusted line %d twice", adj.Line)
+		case adj.UnitPrice.IsNegative():
+			return nil, nil, fmt.Errorf("pricing hook priced line %d at %s", adj.Line, adj.UnitPrice)
+		}
+		seen[adj.Line] = true
+
+		item := &cart[adj.Line]
+		adj.ProductID = item.Product.ID
+		adj.PreviousPrice = item.Product.CurrentPrice
+		adj.UnitPrice = calc.currency.Round(adj.UnitPrice)
+		adj.Amount = adj.UnitPrice.Sub(adj.PreviousPrice).Mul(decimal.NewFromInt(int64(item.Quantity)))
+		if adj.Amount.IsZero() {
+			continue
+		}
+		item.Product.CurrentPrice = adj.UnitPrice
+		applied = append(applied, adj)
+	}
+	return cart, applied, nil
+}
@@ -2,108 +2,1493 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
 	"fmt"
-	"sort"
+	"time"
 
 	"github.com/ahsmha/discounts/internal/discount"
 	"github.com/ahsmha/discounts/internal/interfaces"
 	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/pkg/channel"
+	"github.com/ahsmha/discounts/pkg/clock"
 	"github.com/ahsmha/discounts/pkg/errors"
 	"github.com/shopspring/decimal"
 )
 
+// defaultTotalTolerance absorbs rounding differences between client and engine totals
+var defaultTotalTolerance = decimal.New(1, -2)
+
+// maxDeactivateAttempts bounds how often deactivating a capped discount is retried after
+// losing the race to another edit
+const maxDeactivateAttempts = 5
+
 type discountService struct {
-	discountRepo    interfaces.IDiscountRepository
-	strategyFactory *discount.StrategyFactory
+	discountRepo     interfaces.IDiscountRepository
+	strategyFactory  *discount.StrategyFactory
+	redemptionRepo   interfaces.IRedemptionRepository
+	pricingRecords   interfaces.IPricingRecordRepository
+	experimentRepo   interfaces.IExperimentRepository
+	campaignRepo     interfaces.ICampaignRepository
+	spendTracker     interfaces.ISpendTracker
+	loyaltyProvider  interfaces.ILoyaltyProvider
+	customerProvider interfaces.ICustomerProvider
+	segmentResolver  interfaces.SegmentResolver
+	productCatalog   interfaces.ProductCatalog
+	inventory        interfaces.InventoryProvider
+	pricingHook      interfaces.PricingHook
+	priceMatrix      models.PriceMatrix
+	priceFloor       *models.PriceFloorPolicy // nil floors products at their MinSellingPrice only
+	maxDiscountPct   *decimal.Decimal         // Most of a cart's original price its discounts may take off, nil for no cap
+	idempotencyStore interfaces.IIdempotencyStore
+	referralLedger   interfaces.IReferralLedger
+	giftCardRepo     interfaces.IGiftCardRepository
+	couponCodeRepo   interfaces.ICouponCodeRepository
+	offerRanker      interfaces.IOfferRanker
+	offerScorer      interfaces.PersonalizationScorer
+	personalization  PersonalizationConfig
+	scheduler        interfaces.IWorkScheduler
+	phases           map[models.DiscountType]int // discount type -> pipeline phase, nil without a pipeline
+	limits           RequestLimits
+	batchConcurrency int
+	attemptCounter   interfaces.IAttemptCounter
+	codeLimits       CodeValidationLimits
+	checkInvariants  bool
+	onViolation      func(*InvariantViolation) // nil panics
+	onSpendCapError  func(discountID string, err error)
+	pointsEarnRate   decimal.Decimal
+	counterfactuals  bool
+	totalTolerance   decimal.Decimal
+	currency         models.Currency
+	rounding         models.RoundingMode
+	clock            clock.Clock
+	validationMode   models.ValidationMode
+	legacyPrices     bool // Price from the CurrentPrice callers send, see WithLegacyCurrentPrices
+}
+
+// calculation holds the inputs of a single pricing request once they have been validated
+type calculation struct {
+	cartItems   []models.CartItem
+	customer    models.CustomerProfile
+	paymentInfo *models.PaymentInfo
+	location    *models.Location
+	channel     models.SalesChannel
+	orderID     string
+	currency    models.Currency   // currency the cart is priced in
+	codes       map[string]bool   // codes entered at checkout
+	couponCodes map[string]string // discount id -> unused single-use code entered for it
+	now         time.Time
+	campaigns   map[string]*models.Campaign // campaign id -> campaign, for the discounts being priced
+	spendLeft   map[string]decimal.Decimal  // discount id -> amount left under MaxTotalSpend
+	monthLeft   map[string]decimal.Decimal  // discount id -> amount left under the customer's MonthlyCap
+	introUsed   map[string]int              // discount id -> customer's redemptions of an introductory program
+	stock       map[string]int              // product id -> units in stock, nil unless some discount depends on stock
+	adjustments []models.PriceAdjustment    // Made by the pricing hook, already in cartItems
+	deadline    func() error                // Reports the request's context error
+	partial     bool                        // The request accepts a partial result once its deadline passes
+	stopped     error                       // Set by applyDiscounts when the deadline stopped a request not accepting a partial result
+	maxDiscount *decimal.Decimal            // Most the cart's instant discounts may add up to, nil without a cap
+}
+
+// deadlineErr reports the request's context error, nil while it is still running
+func (c *calculation) deadlineErr() error {
+	if c.deadline == nil {
+		return nil
+	}
+	return c.deadline()
+}
+
+// checkpoint stops a request that does not accept a partial result once its deadline has
+// passed, saying which stage it reached
+func (c *calculation) checkpoint(stage string) error {
+	if err := c.deadlineErr(); err != nil && !c.partial {
+		return errors.NewDeadlineExceededError(stage, 0, 0, nil, err)
+	}
+	return nil
+}
+
+// hasCode reports whether the checkout entered a code unlocking the discount
+func (c *calculation) hasCode(d *models.Discount) bool {
+	return c.enteredCode(d) != ""
+}
+
+// enteredCode returns the code or alias entered at checkout for the discount, empty when
+// none was
+func (c *calculation) enteredCode(d *models.Discount) string {
+	if d.GeneratedCodes {
+		return c.couponCodes[d.ID]
+	}
+	for _, code := range d.Codes() {
+		if c.codes[code] {
+			return code
+		}
+	}
+	return ""
+}
+
+// appliedDiscount is a discount that reduced the cart and the amount it took off
+type appliedDiscount struct {
+	discount models.Discount
+	amount   decimal.Decimal
+	points   int64 // Loyalty points burnt, for points redemption discounts
 }
 
-func NewDiscountService(discountRepo interfaces.IDiscountRepository) interfaces.IDiscountService {
-	return &discountService{
-		discountRepo:    discountRepo,
-		strategyFactory: discount.NewStrategyFactory(),
+// appliedIDs lists the IDs of the applied discounts in application order
+func appliedIDs(applied []appliedDiscount) []string {
+	ids := make([]string, len(applied))
+	for i, a := range applied {
+		ids[i] = a.discount.ID
+	}
+	return ids
+}
+
+func NewDiscountService(discountRepo interfaces.IDiscountRepository, opts ...Option) interfaces.IDiscountService {
+	ds := &discountService{
+		discountRepo:     discountRepo,
+		strategyFactory:  discount.NewStrategyFactory(),
+		totalTolerance:   defaultTotalTolerance,
+		currency:         models.DefaultCurrency,
+		rounding:         models.RoundHalfUp,
+		batchConcurrency: defaultBatchConcurrency,
+		clock:            clock.System(),
+		validationMode:   models.ValidationStrict,
+		spendTracker:     repositories.NewInMemorySpendTracker(),
+	}
+	for _, opt := range opts {
+		opt(ds)
 	}
+	return ds
 }
 
 func (ds *discountService) CalculateCartDiscounts(ctx context.Context, cartItems []models.CartItem,
 	customer models.CustomerProfile, paymentInfo *models.PaymentInfo) (*models.DiscountedPrice, error) {
 
-	if len(cartItems) == 0 {
+	return ds.CalculateCart(ctx, &models.CalculationRequest{
+		CartItems:   cartItems,
+		Customer:    customer,
+		PaymentInfo: paymentInfo,
+	})
+}
+
+func (ds *discountService) CalculateCart(ctx context.Context, req *models.CalculationRequest) (*models.DiscountedPrice, error) {
+	now := ds.instant(ctx)
+	ctx = clock.NewContext(ctx, now)
+	if req.IdempotencyKey == "" || ds.idempotencyStore == nil {
+		return ds.calculateCart(ctx, req)
+	}
+
+	fingerprint, err := requestFingerprint(req)
+	if err != nil {
+		return nil, err
+	}
+
+	record, created, err := ds.idempotencyStore.Reserve(ctx, req.IdempotencyKey, fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if !created {
+		if record.Fingerprint != fingerprint {
+			return nil, errors.NewConflictError("idempotency key " + req.IdempotencyKey + " was already used with a different request")
+		}
+		if record.Result == nil {
+			return nil, errors.NewConflictError("request with idempotency key " + req.IdempotencyKey + " is still in progress")
+		}
+		return record.Result, nil
+	}
+
+	result, err := ds.calculateCart(ctx, req)
+	if isCommitted(err) {
+		// The key stays reserved, as a retry would consume usage and budgets again
+		return nil, err
+	}
+	if err != nil {
+		// Nothing was committed, so the key may be retried
+		if releaseErr := ds.idempotencyStore.Release(ctx, req.IdempotencyKey); releaseErr != nil {
+			return nil, fmt.Errorf("failed to release idempotency key: %w", releaseErr)
+		}
+		return nil, err
+	}
+
+	if err := ds.idempotencyStore.Complete(ctx, req.IdempotencyKey, result); err != nil {
+		return nil, fmt.Errorf("failed to store idempotent result: %w", err)
+	}
+	return result, nil
+}
+
+// requestFingerprint digests everything in the request except its idempotency key, so a
+// replayed key can be told apart from a genuine retry
+func requestFingerprint(req *models.CalculationRequest) (string, error) {
+	payload := *req
+	payload.IdempotencyKey = ""
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to fingerprint request: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// calculateCart prices and commits the request on the capacity of its traffic lane
+func (ds *discountService) calculateCart(ctx context.Context, req *models.CalculationRequest) (*models.DiscountedPrice, error) {
+	var result *models.DiscountedPrice
+	err := ds.schedule(ctx, func(ctx context.Context) (err error) {
+		result, err = ds.priceCart(ctx, req)
+		return err
+	})
+	return result, err
+}
+
+// schedule runs fn on the scheduler, right away without one
+func (ds *discountService) schedule(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ds.scheduler == nil {
+		return fn(ctx)
+	}
+	return ds.scheduler.Run(ctx, fn)
+}
+
+func (ds *discountService) priceCart(ctx context.Context, req *models.CalculationRequest) (*models.DiscountedPrice, error) {
+	run, err := ds.prepare(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	ctx, calc := run.ctx, run.calc
+
+	result, applied := ds.applyDiscounts(calc, run.discounts, "", nil)
+	if calc.stopped != nil {
+		return nil, calc.stopped
+	}
+	result.Warnings = append(run.warnings, result.Warnings...)
+	paid := applyGiftCards(result, run.giftCards)
+
+	if !result.Partial {
+		if err := calc.checkpoint("committing"); err != nil {
+			return nil, err
+		}
+	}
+	// Once committing starts it runs to the end, even past the deadline, so usage, budgets
+	// and the result returned stay consistent. A partial result is committed as priced.
+	ctx = context.WithoutCancel(ctx)
+
+	if ds.checkInvariants {
+		if problems := checkInvariants(calc, applied, result); len(problems) > 0 {
+			violation := &InvariantViolation{Problems: problems, Request: req, Result: result}
+			if ds.onViolation == nil {
+				panic(violation)
+			}
+			ds.onViolation(violation)
+		}
+	}
+
+	// Single-use codes and gift cards are redeemed first so a code or balance spent
+	// concurrently fails the request before any discount usage is consumed
+	redeemedCodes, err := ds.redeemCouponCodes(ctx, calc, applied)
+	if err != nil {
+		return nil, err
+	}
+	if err := ds.redeemGiftCards(ctx, paid); err != nil {
+		if releaseErr := ds.releaseCouponCodes(ctx, redeemedCodes); releaseErr != nil {
+			return nil, releaseErr
+		}
+		return nil, err
+	}
+
+	if err := ds.commitCheckout(ctx, req, calc, applied, result); err != nil {
+		// The checkout fails, so the codes and balances it was to be paid with are given back
+		if releaseErr := ds.releaseCouponCodes(ctx, redeemedCodes); releaseErr != nil {
+			return nil, fmt.Errorf("%w, and %v", err, releaseErr)
+		}
+		if refundErr := ds.refundGiftCards(ctx, paid); refundErr != nil {
+			return nil, fmt.Errorf("%w, and %v", err, refundErr)
+		}
+		return nil, err
+	}
+
+	if ds.counterfactuals && len(applied) > 0 && !result.Partial {
+		result.PriceWithout = make(map[string]decimal.Decimal, len(applied))
+		for _, a := range applied {
+			without, _ := ds.applyDiscounts(calc, run.discounts, a.discount.ID, nil)
+			if calc.stopped != nil {
+				// The order is priced and committed; only the comparison is cut short
+				result.PriceWithout = nil
+				result.Warnings = append(result.Warnings, "deadline reached, prices without each discount were not calculated")
+				break
+			}
+			result.PriceWithout[a.discount.Name] = without.FinalPrice
+		}
+	}
+
+	return result, nil
+}
+
+// commitCheckout commits the discounts applied and records the experiment assignments and
+// the pricing record of the checkout. Recording fails after the discounts are committed, so
+// its failures are committedErrors.
+func (ds *discountService) commitCheckout(ctx context.Context, req *models.CalculationRequest, calc *calculation,
+	applied []appliedDiscount, result *models.DiscountedPrice) error {
+	if err := ds.commit(ctx, calc, applied, result.FinalPrice); err != nil {
+		return err
+	}
+	if err := ds.recordAssignments(ctx, calc, result.Experiments); err != nil {
+		return &committedError{err: err}
+	}
+	if err := ds.recordPricing(ctx, req, calc, applied, result); err != nil {
+		return &committedError{err: err}
+	}
+	return nil
+}
+
+// pricingRun is a validated request together with everything loaded to price it: the
+// snapshot the whole calculation is made against. It is taken once, at one instant, and
+// nothing is read again while discounts are applied, so discounts edited, expiring or
+// deleted meanwhile cannot give one request a mix of old and new terms. The service's
+// own settings are fixed when it is created.
+type pricingRun struct {
+	ctx       context.Context // Pinned to calc.now
+	calc      *calculation
+	discounts []models.Discount // In application order
+	giftCards []*models.GiftCard
+	warnings  []string
+}
+
+// prepare validates the request and loads the discounts, balances and budgets it is priced
+// against. With everyDiscount set, discounts that are not currently valid are loaded too
+// when the repository can list them, so explanations can say why they were left out.
+func (ds *discountService) prepare(ctx context.Context, req *models.CalculationRequest, everyDiscount bool) (*pricingRun, error) {
+	if len(req.CartItems) == 0 {
 		return nil, errors.NewValidationError("cart is empty")
 	}
 
-	originalPrice := decimal.Zero
-	for _, item := range cartItems {
-		originalPrice = originalPrice.Add(item.GetTotalPrice())
+	req, warnings, err := ds.limitRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if ds.productCatalog != nil {
+		enriched := *req
+		if enriched.CartItems, err = ds.enrichCart(ctx, req.CartItems); err != nil {
+			return nil, err
+		}
+		req = &enriched
+	}
+
+	cartItems, invalid, err := ds.validateCart(req)
+	if err != nil {
+		return nil, err
+	}
+	warnings = append(warnings, invalid...)
+	cartItems = ds.startingPrices(cartItems)
+
+	salesChannel, err := salesChannelOf(ctx, req.SalesChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ExpectedTotal != nil {
+		actual := models.GetCartTotal(cartItems)
+		if actual.Sub(*req.ExpectedTotal).Abs().GreaterThan(ds.totalTolerance) {
+			return nil, errors.NewTotalMismatchError(*req.ExpectedTotal, actual, ds.totalTolerance)
+		}
+	}
+
+	// Every validity check made for this request observes the same instant
+	calc := &calculation{
+		cartItems:   cartItems,
+		customer:    req.Customer,
+		paymentInfo: req.PaymentInfo,
+		location:    req.Location,
+		channel:     salesChannel,
+		orderID:     req.OrderID,
+		currency:    ds.currency,
+		codes:       make(map[string]bool, len(req.Codes)),
+		now:         ds.instant(ctx),
+	}
+	if ds.maxDiscountPct != nil {
+		limit := models.GetCartTotal(cartItems).Mul(*ds.maxDiscountPct).Div(decimal.NewFromInt(models.PercentageBase))
+		limit = calc.currency.RoundWith(limit, models.RoundFloor)
+		calc.maxDiscount = &limit
+	}
+	for _, code := range req.Codes {
+		calc.codes[code] = true
+	}
+	ctx = clock.NewContext(ctx, calc.now)
+	calc.deadline, calc.partial = ctx.Err, req.AllowPartial
+
+	if err := calc.checkpoint("loading the customer"); err != nil {
+		return nil, err
+	}
+	calc.customer.PointsBalance, err = ds.loadPointsBalance(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	calc.customer.Group, err = ds.loadCustomerGroup(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	calc.customer.Segments, err = ds.resolveSegments(ctx, req.Customer)
+	if err != nil {
+		return nil, err
+	}
+	if ds.customerProvider != nil {
+		calc.cartItems = ds.priceMatrix.Apply(calc.customer.Group, calc.cartItems)
+	}
+
+	calc.cartItems, calc.adjustments, err = ds.adjustPrices(ctx, calc)
+	if err != nil {
+		return nil, err
+	}
+
+	giftCards, err := ds.loadGiftCards(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
-	allDiscounts, err := ds.discountRepo.GetActiveDiscounts(ctx)
+	calc.couponCodes, err = ds.loadCouponCodes(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := calc.checkpoint("loading discounts"); err != nil {
+		return nil, err
+	}
+
+	var allDiscounts []models.Discount
+	lister, canList := ds.discountRepo.(interfaces.DiscountLister)
+	switch {
+	case everyDiscount && canList:
+		var page *models.DiscountPage
+		if page, err = lister.ListDiscounts(ctx, models.ListFilter{}); err == nil {
+			allDiscounts = page.Discounts
+		}
+	case everyDiscount:
+		allDiscounts, err = ds.discountRepo.GetActiveDiscounts(ctx)
+	default:
+		allDiscounts, err = ds.discountRepo.GetApplicableDiscounts(ctx,
+			models.CartDiscountFilter(calc.cartItems, calc.customer, req.PaymentInfo, calc.now))
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get discounts: %w", err)
 	}
 
+	ds.sortDiscounts(allDiscounts)
+	allDiscounts, dropped := ds.limitDiscounts(allDiscounts)
+	warnings = append(warnings, dropped...)
+
+	if err := calc.checkpoint("loading budgets"); err != nil {
+		return nil, err
+	}
+
+	calc.campaigns, err = ds.loadCampaigns(ctx, allDiscounts)
+	if err != nil {
+		return nil, err
+	}
+
+	calc.spendLeft, err = ds.loadSpend(ctx, allDiscounts)
+	if err != nil {
+		return nil, err
+	}
+
+	calc.monthLeft, err = ds.loadMonthlySpend(ctx, allDiscounts, calc.customer.ID, calc.now)
+	if err != nil {
+		return nil, err
+	}
+
+	calc.introUsed, err = ds.loadIntroductoryRedemptions(ctx, allDiscounts, calc.customer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	calc.stock, err = ds.loadStock(ctx, allDiscounts, calc.cartItems)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pricingRun{
+		ctx:       ctx,
+		calc:      calc,
+		discounts: allDiscounts,
+		giftCards: giftCards,
+		warnings:  warnings,
+	}, nil
+}
+
+// validateCart checks every cart item. In strict mode the first malformed item fails the
+// request; in lenient mode malformed items are dropped and described in the returned warnings.
+func (ds *discountService) validateCart(req *models.CalculationRequest) ([]models.CartItem, []string, error) {
+	mode := ds.modeFor(req)
+
+	valid := make([]models.CartItem, 0, len(req.CartItems))
+	var warnings []string
+	for i, item := range req.CartItems {
+		err := item.Validate()
+		if err == nil && ds.productCatalog != nil && item.Product.IsBare() {
+			err = fmt.Errorf("product %s is not in the catalog", item.Product.ID)
+		}
+		if err == nil {
+			valid = append(valid, item)
+			continue
+		}
+
+		if mode != models.ValidationLenient {
+			return nil, nil, errors.NewValidationError(fmt.Sprintf("invalid cart item %d: %v", i, err))
+		}
+		warnings = append(warnings, fmt.Sprintf("skipped cart item %d: %v", i, err))
+	}
+
+	if len(valid) == 0 {
+		return nil, nil, errors.NewValidationError("cart has no valid items")
+	}
+
+	return valid, warnings, nil
+}
+
+// modeFor returns the validation mode the request is handled in
+func (ds *discountService) modeFor(req *models.CalculationRequest) models.ValidationMode {
+	if req.ValidationMode != "" {
+		return req.ValidationMode
+	}
+	return ds.validationMode
+}
+
+// loadGiftCards fetches the request's gift cards, rejecting codes that cannot pay
+func (ds *discountService) loadGiftCards(ctx context.Context, req *models.CalculationRequest) ([]*models.GiftCard, error) {
+	if len(req.GiftCardCodes) == 0 {
+		return nil, nil
+	}
+	if ds.giftCardRepo == nil {
+		return nil, errors.NewValidationError("gift cards are not accepted")
+	}
+
+	now := clock.FromContext(ctx)
+	seen := make(map[string]bool, len(req.GiftCardCodes))
+	cards := make([]*models.GiftCard, 0, len(req.GiftCardCodes))
+	for _, code := range req.GiftCardCodes {
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		card, err := ds.giftCardRepo.GetGiftCard(ctx, code)
+		if err != nil {
+			if errors.IsNotFoundError(err) {
+				return nil, errors.NewValidationError("unknown gift card: " + code)
+			}
+			return nil, fmt.Errorf("failed to get gift card: %w", err)
+		}
+		if !card.IsUsableAt(now) {
+			return nil, errors.NewValidationError("gift card is not usable: " + code)
+		}
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+// applyGiftCards pays the result's final price and tax with the cards in order, returning
+// the amount drawn from each card that was used
+func applyGiftCards(result *models.DiscountedPrice, cards []*models.GiftCard) map[string]decimal.Decimal {
+	result.AmountDue = result.FinalPrice.Add(result.TotalTax)
+	if len(cards) == 0 {
+		return nil
+	}
+
+	paid := make(map[string]decimal.Decimal, len(cards))
+	for _, card := range cards {
+		if !result.AmountDue.IsPositive() {
+			break
+		}
+		amount := decimal.Min(card.Balance, result.AmountDue)
+		paid[card.Code] = amount
+		result.AmountDue = result.AmountDue.Sub(amount)
+	}
+	result.GiftCardsApplied = paid
+	return paid
+}
+
+// redeemGiftCards draws the paid amounts from the cards, refunding the cards already
+// redeemed when one of them fails
+func (ds *discountService) redeemGiftCards(ctx context.Context, paid map[string]decimal.Decimal) error {
+	redeemed := make(map[string]decimal.Decimal, len(paid))
+	for code, amount := range paid {
+		if err := ds.giftCardRepo.Redeem(ctx, code, amount); err != nil {
+			if refundErr := ds.refundGiftCards(ctx, redeemed); refundErr != nil {
+				return refundErr
+			}
+			if errors.IsValidationError(err) {
+				return err
+			}
+			return fmt.Errorf("failed to redeem gift card: %w", err)
+		}
+		redeemed[code] = amount
+	}
+	return nil
+}
+
+// refundGiftCards gives back what was taken off the cards, by code
+func (ds *discountService) refundGiftCards(ctx context.Context, paid map[string]decimal.Decimal) error {
+	for code, amount := range paid {
+		if err := ds.giftCardRepo.Refund(ctx, code, amount); err != nil {
+			return fmt.Errorf("failed to refund gift card %s: %w", code, err)
+		}
+	}
+	return nil
+}
+
+// loadCouponCodes looks up the entered codes among the minted single-use codes, keeping the
+// first unused code entered for each discount. Codes that were not minted are ordinary
+// discount codes and are ignored here.
+func (ds *discountService) loadCouponCodes(ctx context.Context, req *models.CalculationRequest) (map[string]string, error) {
+	couponCodes := make(map[string]string)
+	if ds.couponCodeRepo == nil {
+		return couponCodes, nil
+	}
+
+	for _, code := range req.Codes {
+		stored, err := ds.couponCodeRepo.GetCode(ctx, code)
+		if err != nil {
+			if errors.IsNotFoundError(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get coupon code: %w", err)
+		}
+		if _, seen := couponCodes[stored.DiscountID]; !seen && !stored.IsRedeemed() {
+			couponCodes[stored.DiscountID] = stored.Code
+		}
+	}
+	return couponCodes, nil
+}
+
+// redeemCouponCodes uses up the single-use codes of the applied discounts, returning the
+// codes redeemed. A code redeemed concurrently fails the request with a conflict error after
+// the codes already redeemed are released.
+func (ds *discountService) redeemCouponCodes(ctx context.Context, calc *calculation, applied []appliedDiscount) ([]string, error) {
+	var redeemed []string
+	for _, a := range applied {
+		code, unlocked := calc.couponCodes[a.discount.ID]
+		if !unlocked || !a.discount.GeneratedCodes {
+			continue
+		}
+		if err := ds.couponCodeRepo.RedeemCode(ctx, code, calc.customer.ID, calc.now); err != nil {
+			if releaseErr := ds.releaseCouponCodes(ctx, redeemed); releaseErr != nil {
+				return nil, releaseErr
+			}
+			if errors.IsConflictError(err) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to redeem coupon code: %w", err)
+		}
+		redeemed = append(redeemed, code)
+	}
+	return redeemed, nil
+}
+
+func (ds *discountService) releaseCouponCodes(ctx context.Context, codes []string) error {
+	for _, code := range codes {
+		if err := ds.couponCodeRepo.ReleaseCode(ctx, code); err != nil {
+			return fmt.Errorf("failed to release coupon code %s: %w", code, err)
+		}
+	}
+	return nil
+}
+
+// loadCustomerGroup returns the group the cart is priced for. Like points balances, groups
+// only ever come from the customer provider, never from the caller.
+func (ds *discountService) loadCustomerGroup(ctx context.Context, req *models.CalculationRequest) (models.CustomerGroup, error) {
+	if ds.customerProvider == nil {
+		return "", nil
+	}
+	if req.Customer.ID == "" {
+		return models.CustomerGroupRetail, nil
+	}
+
+	group, err := ds.customerProvider.GetCustomerGroup(ctx, req.Customer.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get customer group: %w", err)
+	}
+	return group, nil
+}
+
+// salesChannelOf returns the channel a request is placed through: the one it names, or else
+// the channel of its context
+func salesChannelOf(ctx context.Context, requested models.SalesChannel) (models.SalesChannel, error) {
+	if requested == "" {
+		requested = models.SalesChannel(channel.FromContext(ctx))
+		if requested == "" {
+			return "", nil
+		}
+	}
+	if err := requested.Validate(); err != nil {
+		return "", errors.NewValidationError(err.Error())
+	}
+	return requested, nil
+}
+
+// resolveSegments returns the customer's segments: those the caller supplied and, with a
+// segment resolver, those the resolver reports. Anonymous customers are not looked up.
+func (ds *discountService) resolveSegments(ctx context.Context, customer models.CustomerProfile) ([]string, error) {
+	if ds.segmentResolver == nil || customer.ID == "" {
+		return customer.Segments, nil
+	}
+
+	segments, err := ds.segmentResolver.ResolveSegments(ctx, customer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve customer segments: %w", err)
+	}
+	return models.MergeSegments(customer.Segments, segments), nil
+}
+
+// loadPointsBalance returns the balance the customer may redeem in this request. Balances
+// only ever come from the loyalty provider, never from the caller.
+func (ds *discountService) loadPointsBalance(ctx context.Context, req *models.CalculationRequest) (int64, error) {
+	if !req.RedeemPoints || ds.loyaltyProvider == nil || req.Customer.ID == "" {
+		return 0, nil
+	}
+
+	balance, err := ds.loyaltyProvider.GetPointsBalance(ctx, req.Customer.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get points balance: %w", err)
+	}
+	return balance, nil
+}
+
+// loadCampaigns fetches the campaigns the discounts belong to. Without a campaign
+// repository campaign membership is ignored.
+func (ds *discountService) loadCampaigns(ctx context.Context, discounts []models.Discount) (map[string]*models.Campaign, error) {
+	campaigns := make(map[string]*models.Campaign)
+	if ds.campaignRepo == nil {
+		return campaigns, nil
+	}
+
+	for _, d := range discounts {
+		if d.CampaignID == "" {
+			continue
+		}
+		if _, loaded := campaigns[d.CampaignID]; loaded {
+			continue
+		}
+
+		campaign, err := ds.campaignRepo.GetCampaignByID(ctx, d.CampaignID)
+		if err != nil {
+			if errors.IsNotFoundError(err) {
+				// A discount pointing at an unknown campaign is treated as belonging to an inactive one
+				campaigns[d.CampaignID] = &models.Campaign{ID: d.CampaignID}
+				continue
+			}
+			return nil, fmt.Errorf("failed to get campaign: %w", err)
+		}
+		campaigns[d.CampaignID] = campaign
+	}
+
+	return campaigns, nil
+}
+
+// loadSpend returns how much each spend-capped discount can still give away. Without a
+// spend tracker, as when replaying an order, MaxTotalSpend is not enforced.
+func (ds *discountService) loadSpend(ctx context.Context, discounts []models.Discount) (map[string]decimal.Decimal, error) {
+	spendLeft := make(map[string]decimal.Decimal)
+	if ds.spendTracker == nil {
+		return spendLeft, nil
+	}
+
+	for _, d := range discounts {
+		if !d.HasSpendLimit() {
+			continue
+		}
+		spent, err := ds.spendTracker.GetSpend(ctx, d.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get discount spend: %w", err)
+		}
+		spendLeft[d.ID] = decimal.Max(d.MaxTotalSpend.Sub(spent), decimal.Zero)
+	}
+
+	return spendLeft, nil
+}
+
+// loadMonthlySpend returns how much each monthly-capped discount can still give the
+// customer this calendar month, nothing for anonymous customers, whose spend cannot be
+// tracked. Like MaxTotalSpend, MonthlyCap is not enforced when replaying an order, which
+// runs without a spend tracker.
+func (ds *discountService) loadMonthlySpend(ctx context.Context, discounts []models.Discount, customerID string,
+	now time.Time) (map[string]decimal.Decimal, error) {
+
+	monthLeft := make(map[string]decimal.Decimal)
+	if ds.spendTracker == nil {
+		return monthLeft, nil
+	}
+
+	for _, d := range discounts {
+		if !d.HasMonthlyCap() {
+			continue
+		}
+		if customerID == "" {
+			monthLeft[d.ID] = decimal.Zero
+			continue
+		}
+		spent, err := ds.spendTracker.GetSpend(ctx, models.MonthlySpendKey(d.ID, customerID, now))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get monthly spend: %w", err)
+		}
+		monthLeft[d.ID] = decimal.Max(d.MonthlyCap.Sub(spent), decimal.Zero)
+	}
+
+	return monthLeft, nil
+}
+
+// loadIntroductoryRedemptions counts the customer's redemptions of each introductory
+// program. Without a redemption repository, or for anonymous customers, programs only
+// follow the customer's order count.
+func (ds *discountService) loadIntroductoryRedemptions(ctx context.Context, discounts []models.Discount,
+	customerID string) (map[string]int, error) {
+
+	used := make(map[string]int)
+	if ds.redemptionRepo == nil || customerID == "" {
+		return used, nil
+	}
+
+	for _, d := range discounts {
+		if !d.IsIntroductory() {
+			continue
+		}
+		redemptions, err := ds.redemptionRepo.ListRedemptions(ctx, models.RedemptionFilter{DiscountID: d.ID, CustomerID: customerID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list redemptions: %w", err)
+		}
+		used[d.ID] = len(redemptions)
+	}
+
+	return used, nil
+}
+
+// applyDiscounts runs the discounts valid at calc.now in order against the cart without any
+// side effects, returning the priced result and the discounts that contributed to it. A
+// discount whose ID equals skipID is left out, which is how counterfactual prices are computed.
+// When decisions is not nil, what was decided about every discount is appended to it.
+func (ds *discountService) applyDiscounts(calc *calculation, discounts []models.Discount,
+	skipID string, decisions *[]models.DiscountDecision) (*models.DiscountedPrice, []appliedDiscount) {
+
+	originalPrice := models.GetCartTotal(calc.cartItems)
+
 	result := &models.DiscountedPrice{
 		OriginalPrice:    originalPrice,
 		FinalPrice:       originalPrice,
 		AppliedDiscounts: make(map[string]decimal.Decimal),
 		Message:          "No discounts applied",
+		CustomerGroup:    calc.customer.Group,
+		PriceAdjustments: calc.adjustments,
 	}
 
-	// Sort by priority
-	sort.Slice(allDiscounts, func(i, j int) bool {
-		return allDiscounts[i].Priority > allDiscounts[j].Priority
-	})
+	// Campaign budgets are drawn down as the run goes so stacked discounts share them
+	remainingBudget := make(map[string]decimal.Decimal)
+	for id, campaign := range calc.campaigns {
+		if campaign.HasBudgetLimit() {
+			remainingBudget[id] = campaign.RemainingBudget()
+		}
+	}
+
+	// Points are burnt as point discounts apply so two of them cannot spend the same balance
+	customer := calc.customer
+
+	// Spend tiers are reached on what is left of the promotable items once brand discounts
+	// have been taken off
+	brandSubtotal := models.GetPromotableTotal(calc.cartItems)
+
+	// Nil unless some line has a price floor
+	floors := newPriceFloors(calc.cartItems, ds.priceFloor)
+
+	decide := func(d *models.Discount, outcome models.DecisionOutcome, reason models.DecisionReason, amount decimal.Decimal) {
+		if decisions == nil {
+			return
+		}
+		*decisions = append(*decisions, models.DiscountDecision{
+			DiscountID:  d.ID,
+			DiscountRef: d.StableRef(),
+			Name:        d.Name,
+			Type:        d.Type,
+			Priority:    d.Priority,
+			Outcome:     outcome,
+			Reason:      reason,
+			Detail:      describeDecision(reason, d, calc, customer, result),
+			Amount:      amount,
+		})
+	}
 
-	for _, discount := range allDiscounts {
-		strategy := ds.strategyFactory.Get(discount.Type)
+	var applied []appliedDiscount
+	calc.stopped = nil
+	for i, d := range discounts {
+		if err := calc.deadlineErr(); err != nil {
+			if !calc.partial {
+				calc.stopped = errors.NewDeadlineExceededError("applying discounts", i, len(discounts), appliedIDs(applied), err)
+				break
+			}
+			result.Partial = true
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("deadline reached, %d of %d discounts were not considered", len(discounts)-i, len(discounts)))
+			break
+		}
+
+		if skipID != "" && d.ID == skipID {
+			continue
+		}
+
+		if !d.IsValidAt(calc.now) {
+			decide(&d, models.DecisionSkipped, d.UnavailableReasonAt(calc.now), decimal.Zero)
+			continue
+		}
+
+		if d.RequiresCode() && !calc.hasCode(&d) {
+			decide(&d, models.DecisionRejected, models.ReasonCodeRequired, decimal.Zero)
+			continue
+		}
+
+		if d.CurrencyCode() != calc.currency.Code {
+			decide(&d, models.DecisionSkipped, models.ReasonCurrencyMismatch, decimal.Zero)
+			continue
+		}
+
+		campaign := calc.campaigns[d.CampaignID]
+		if campaign != nil && !campaign.IsActive {
+			decide(&d, models.DecisionSkipped, models.ReasonCampaignPaused, decimal.Zero)
+			continue
+		}
+
+		// Customers past the program by order count alone are the strategy's to reject
+		if d.IsIntroductory() && customer.OrderCount < d.IntroductoryOrders &&
+			d.IntroductoryOrdersLeft(customer, calc.introUsed[d.ID]) == 0 {
+			decide(&d, models.DecisionRejected, models.ReasonIntroductoryOrdersUsed, decimal.Zero)
+			continue
+		}
+
+		if !d.IsSoldOn(calc.channel) {
+			decide(&d, models.DecisionRejected, models.ReasonChannelMismatch, decimal.Zero)
+			continue
+		}
+
+		if !d.IsAvailableIn(calc.location) {
+			decide(&d, models.DecisionRejected, models.ReasonRegionRestricted, decimal.Zero)
+			continue
+		}
+
+		if !d.IsOccasionAt(customer, calc.now) {
+			decide(&d, models.DecisionRejected, models.ReasonOutsideOccasion, decimal.Zero)
+			continue
+		}
+
+		if !d.AdmitsMemberAt(customer, calc.now) {
+			decide(&d, models.DecisionRejected, models.ReasonMembershipRequired, decimal.Zero)
+			continue
+		}
+
+		// Targets out of the stock range are left out of this cart's discount
+		var stocked bool
+		if d, stocked = stockedDiscount(calc, d); !stocked {
+			decide(&d, models.DecisionRejected, models.ReasonStockLevel, decimal.Zero)
+			continue
+		}
+
+		if d.Experiment != nil {
+			exposure := d.Experiment.ExposureOf(d.ID, customer.ID)
+			result.Experiments = append(result.Experiments, exposure)
+			if !exposure.Treated {
+				decide(&d, models.DecisionRejected, models.ReasonExperimentControl, decimal.Zero)
+				continue
+			}
+		}
+
+		strategy := ds.strategyFactory.Get(d.Type)
 		if strategy == nil {
+			decide(&d, models.DecisionSkipped, models.ReasonUnsupportedType, decimal.Zero)
 			continue
 		}
 
-		applicable := strategy.IsApplicable(&discount, cartItems, customer, paymentInfo)
+		if d.Type == models.DiscountTypeSpendTier && d.IsApplicableToCustomer(customer) {
+			if hint, ok := d.SpendHintAt(brandSubtotal); ok {
+				result.SpendHints = append(result.SpendHints, hint)
+			}
+		}
+
+		applicable := strategy.IsApplicable(&d, calc.cartItems, customer, calc.paymentInfo)
 		if !applicable {
+			if nearMiss, ok := nearMissOf(&d, strategy, calc, customer); ok {
+				result.NearMissDiscounts = append(result.NearMissDiscounts, nearMiss)
+			}
+			if decisions != nil {
+				reason := models.ReasonNotApplicable
+				if explainer, ok := strategy.(discount.Explainer); ok {
+					reason = explainer.Explain(&d, calc.cartItems, customer, calc.paymentInfo)
+				}
+				decide(&d, models.DecisionRejected, reason, decimal.Zero)
+			}
 			continue
 		}
 
-		amount := strategy.Calculate(&discount, cartItems, result.FinalPrice)
+		baseCart, baseTotal := d.PricingBase(calc.cartItems, result.OriginalPrice, result.FinalPrice)
+		amount := calculateAmount(strategy, &d, baseCart, customer, brandSubtotal, baseTotal)
+		// Nobody can be given a fraction of the minor unit off
+		rounding := ds.roundingFor(&d)
+		amount = calc.currency.RoundWith(amount, rounding)
+		// Brand and category discounts are priced on item totals, and others may be priced on
+		// the original price, so stacked on earlier discounts they could take off more than
+		// is left of the price
+		if !d.Cashback && amount.GreaterThan(result.FinalPrice) {
+			amount = decimal.Max(result.FinalPrice, decimal.Zero)
+		}
+		// The first limit that brings the amount to nothing is the reason it was skipped
+		var limitedBy models.DecisionReason
+		if !amount.IsPositive() {
+			limitedBy = models.ReasonZeroAmount
+			if _, reached := d.ReachedSpendTier(brandSubtotal); d.Type == models.DiscountTypeSpendTier && !reached {
+				limitedBy = models.ReasonSpendTierNotReached
+			} else if !result.FinalPrice.IsPositive() {
+				limitedBy = models.ReasonPriorityLoss
+			}
+		}
+		var floorTargets []int
+		if floors != nil && !d.Cashback && limitedBy == "" {
+			targets, room := floors.headroom(&d)
+			room = calc.currency.RoundWith(room, models.RoundFloor)
+			if amount.GreaterThan(room) {
+				result.PriceFloorClamps = append(result.PriceFloorClamps, models.PriceFloorClamp{
+					DiscountID: d.ID, Name: d.Name, Requested: amount, Applied: room,
+				})
+				amount = room
+				if !amount.IsPositive() {
+					limitedBy = models.ReasonPriceFloor
+				}
+			}
+			floorTargets = targets
+		}
+		if remaining, capped := calc.spendLeft[d.ID]; capped {
+			amount = decimal.Min(amount, remaining)
+			if limitedBy == "" && !amount.IsPositive() {
+				limitedBy = models.ReasonSpendCapReached
+			}
+		}
+		if remaining, capped := calc.monthLeft[d.ID]; capped {
+			amount = decimal.Min(amount, remaining)
+			if limitedBy == "" && !amount.IsPositive() {
+				limitedBy = models.ReasonMonthlyCapReached
+			}
+		}
+		if remaining, capped := remainingBudget[d.CampaignID]; campaign != nil && capped {
+			amount = decimal.Min(amount, remaining)
+			remainingBudget[d.CampaignID] = remaining.Sub(amount)
+			if limitedBy == "" && !amount.IsPositive() {
+				limitedBy = models.ReasonBudgetExhausted
+			}
+		}
+
 		if amount.GreaterThan(decimal.Zero) {
-			result.FinalPrice = result.FinalPrice.Sub(amount)
-			result.AppliedDiscounts[discount.Name] = amount
+			a := appliedDiscount{discount: d, amount: amount}
+			if d.Type == models.DiscountTypePointsRedemption {
+				a.points = d.PointsForCredit(amount)
+				customer.PointsBalance -= a.points
+				result.PointsRedeemed += a.points
+			}
 
-			// Track usage
-			err := ds.discountRepo.IncrementUsageCount(ctx, discount.ID)
-			if err != nil {
-				return nil, fmt.Errorf("failed to increment usage: %w", err)
+			if d.Cashback {
+				// Paid back later, so later discounts are still taken off the full price
+				result.PendingCashback = append(result.PendingCashback, models.PendingCashback{
+					DiscountID:  d.ID,
+					DiscountRef: d.StableRef(),
+					Name:        d.Name,
+					Type:        d.Type,
+					Amount:      amount,
+					DelayDays:   d.CashbackDelayDays,
+					CreditOn:    d.CashbackCreditOn(calc.now),
+				})
+			} else {
+				if floors != nil {
+					floors.take(floorTargets, amount)
+				}
+				result.FinalPrice = result.FinalPrice.Sub(amount)
+				// Same-named discounts share an entry, so the entries still add up to the reduction
+				result.AppliedDiscounts[d.Name] = result.AppliedDiscounts[d.Name].Add(amount)
+				if d.Type == models.DiscountTypeBrand {
+					brandSubtotal = brandSubtotal.Sub(amount)
+				}
+				result.Breakdown = append(result.Breakdown, models.AppliedDiscount{
+					DiscountID:     d.ID,
+					DiscountRef:    d.StableRef(),
+					Version:        d.Version,
+					Name:           d.Name,
+					Type:           d.Type,
+					Amount:         amount,
+					PointsRedeemed: a.points,
+					Rounding:       rounding,
+				})
 			}
+			applied = append(applied, a)
+			decide(&d, models.DecisionApplied, "", amount)
+		} else {
+			decide(&d, models.DecisionSkipped, limitedBy, decimal.Zero)
 		}
 	}
 
+	applied = capDiscounts(calc, result, applied, decisions)
+	result.MemberSavings = memberSavings(calc.customer, applied)
+
+	result.TaxLines, result.TotalTax = taxLines(calc, applied)
+
+	if ds.pointsEarnRate.IsPositive() {
+		result.PointsEarned = result.FinalPrice.Mul(ds.pointsEarnRate).Floor().IntPart()
+	}
+
 	if len(result.AppliedDiscounts) > 0 {
 		result.Message = fmt.Sprintf("Applied %d discount(s) - Savings: %s",
 			len(result.AppliedDiscounts), result.GetTotalDiscount().String())
 	}
+	if len(result.PendingCashback) > 0 {
+		if len(result.AppliedDiscounts) == 0 {
+			result.Message = "No instant discounts applied"
+		}
+		result.Message += fmt.Sprintf(" - Cashback: %s", result.GetPendingCashback().String())
+	}
 
-	return result, nil
+	return result, applied
+}
+
+// commit records the side effects of applying discounts: usage counts, redemptions,
+// campaign and discount spend, and burnt loyalty points. Every redemption is credited with
+// the whole orderTotal, so revenue can be attributed to the code that brought the order in.
+//
+// The uses of every discount are claimed before anything else is consumed, so a checkout
+// refused for a discount at its limit commits nothing. Once they are claimed a failure
+// leaves consumed whatever came before it, and is returned as a committedError.
+func (ds *discountService) commit(ctx context.Context, calc *calculation, applied []appliedDiscount,
+	orderTotal decimal.Decimal) error {
+	if err := ds.claimUsage(ctx, applied); err != nil {
+		return err
+	}
+	for _, a := range applied {
+		if err := ds.consume(ctx, calc, a, orderTotal); err != nil {
+			return &committedError{err: err}
+		}
+	}
+	return nil
+}
+
+// claimUsage claims a use of every applied discount, refusing the checkout when a
+// concurrent one took the last use. Uses claimed before a refusal are given back when the
+// repository is an interfaces.UsageReleaser; otherwise the refusal is a committedError.
+func (ds *discountService) claimUsage(ctx context.Context, applied []appliedDiscount) error {
+	for i, a := range applied {
+		err := ds.discountRepo.CheckAndIncrementUsage(ctx, a.discount.ID)
+		if err == nil {
+			continue
+		}
+		if errors.IsNotFoundError(err) {
+			err = errors.NewConflictError("discount " + a.discount.ID + " was deleted while the cart was priced, price it again")
+		} else {
+			err = fmt.Errorf("failed to increment usage: %w", err)
+		}
+		if releaseErr := ds.releaseUsage(ctx, applied[:i]); releaseErr != nil {
+			return &committedError{err: fmt.Errorf("%w, and %v", err, releaseErr)}
+		}
+		return err
+	}
+	return nil
+}
+
+// releaseUsage gives back the uses claimed for the applied discounts
+func (ds *discountService) releaseUsage(ctx context.Context, applied []appliedDiscount) error {
+	if len(applied) == 0 {
+		return nil
+	}
+	releaser, ok := ds.discountRepo.(interfaces.UsageReleaser)
+	if !ok {
+		return fmt.Errorf("the uses claimed cannot be released")
+	}
+	for _, a := range applied {
+		if err := releaser.ReleaseUsage(ctx, a.discount.ID); err != nil {
+			return fmt.Errorf("failed to release usage of %s: %w", a.discount.ID, err)
+		}
+	}
+	return nil
+}
+
+// consume records what an applied discount, whose use is claimed, uses up
+func (ds *discountService) consume(ctx context.Context, calc *calculation, a appliedDiscount,
+	orderTotal decimal.Decimal) error {
+	if ds.redemptionRepo != nil {
+		err := ds.redemptionRepo.RecordRedemption(ctx, models.Redemption{
+			DiscountID:  a.discount.ID,
+			DiscountRef: a.discount.StableRef(),
+			CampaignID:  a.discount.CampaignID,
+			CustomerID:  calc.customer.ID,
+			OrderID:     calc.orderID,
+			Code:        calc.enteredCode(&a.discount),
+			Amount:      a.amount,
+			OrderTotal:  orderTotal,
+			RedeemedAt:  calc.now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record redemption: %w", err)
+		}
+	}
+
+	if _, tracked := calc.campaigns[a.discount.CampaignID]; tracked {
+		if err := ds.campaignRepo.ConsumeBudget(ctx, a.discount.CampaignID, a.amount); err != nil {
+			return fmt.Errorf("failed to consume campaign budget: %w", err)
+		}
+	}
+
+	if _, tracked := calc.spendLeft[a.discount.ID]; tracked {
+		if err := ds.trackSpend(ctx, a); err != nil {
+			return err
+		}
+	}
+
+	if _, tracked := calc.monthLeft[a.discount.ID]; tracked {
+		key := models.MonthlySpendKey(a.discount.ID, calc.customer.ID, calc.now)
+		if _, err := ds.spendTracker.AddSpend(ctx, key, a.amount, a.discount.MonthlyCap); err != nil {
+			return fmt.Errorf("failed to track monthly spend: %w", err)
+		}
+	}
+
+	if a.points > 0 {
+		if err := ds.loyaltyProvider.RedeemPoints(ctx, calc.customer.ID, a.points); err != nil {
+			return fmt.Errorf("failed to redeem points: %w", err)
+		}
+	}
+
+	if a.discount.Type == models.DiscountTypeReferral && ds.referralLedger != nil {
+		err := ds.referralLedger.RecordReward(ctx, models.ReferralReward{
+			DiscountID: a.discount.ID,
+			ReferrerID: a.discount.ReferrerID,
+			RefereeID:  calc.customer.ID,
+			Amount:     a.discount.ReferrerReward,
+			Status:     models.ReferralRewardPending,
+			CreatedAt:  calc.now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record referral reward: %w", err)
+		}
+	}
+	return nil
+}
+
+// committedError is a checkout failing after it consumed usage, budgets, spend or points,
+// which are not given back: retrying it would consume them twice
+type committedError struct {
+	err error
+}
+
+func (e *committedError) Error() string { return e.err.Error() }
+func (e *committedError) Unwrap() error { return e.err }
+
+// isCommitted reports whether err is a checkout failing after it committed, see
+// committedError
+func isCommitted(err error) bool {
+	var committed *committedError
+	return stderrors.As(err, &committed)
+}
+
+// trackSpend adds the applied amount to the discount's spend and deactivates the
+// discount once its MaxTotalSpend has been reached
+func (ds *discountService) trackSpend(ctx context.Context, a appliedDiscount) error {
+	total, err := ds.spendTracker.AddSpend(ctx, a.discount.ID, a.amount, a.discount.MaxTotalSpend)
+	if err != nil {
+		return fmt.Errorf("failed to track discount spend: %w", err)
+	}
+	if total.LessThan(a.discount.MaxTotalSpend) {
+		return nil
+	}
+
+	// The tracker already keeps a capped discount from giving anything more, so deactivating
+	// it is best-effort: a read-only repository, or one that is down, must not fail the
+	// checkout that reached the cap
+	if err := ds.deactivateCapped(ctx, a.discount.ID); err != nil && ds.onSpendCapError != nil {
+		ds.onSpendCapError(a.discount.ID, err)
+	}
+	return nil
+}
+
+// deactivateCapped deactivates a discount that has reached its MaxTotalSpend
+func (ds *discountService) deactivateCapped(ctx context.Context, id string) error {
+	// Concurrent checkouts reaching the cap together race to deactivate the discount; one
+	// that loses the race to another edit reads the new version and tries again
+	for attempt := 1; ; attempt++ {
+		stored, err := ds.discountRepo.GetDiscountByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get capped discount: %w", err)
+		}
+		if !stored.IsActive {
+			return nil
+		}
+		deactivated := *stored
+		deactivated.IsActive = false
+		err = ds.discountRepo.UpdateDiscount(ctx, &deactivated)
+		if errors.IsConflictError(err) && attempt < maxDeactivateAttempts {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to deactivate capped discount: %w", err)
+		}
+		return nil
+	}
 }
 
 func (ds *discountService) ValidateDiscountCode(ctx context.Context, code string, cartItems []models.CartItem,
 	customer models.CustomerProfile) (bool, error) {
 
+	validation, err := ds.DescribeDiscountCode(ctx, code, cartItems, customer)
+	if err != nil {
+		return false, err
+	}
+	return validation.Valid, nil
+}
+
+func (ds *discountService) DescribeDiscountCode(ctx context.Context, code string, cartItems []models.CartItem,
+	customer models.CustomerProfile) (*models.CodeValidation, error) {
+
 	if code == "" {
-		return false, errors.NewValidationError("code cannot be empty")
+		return nil, errors.NewValidationError("discount code cannot be empty")
+	}
+
+	now := ds.instant(ctx)
+	ctx = clock.NewContext(ctx, now)
+
+	if err := ds.checkCodeValidationVelocity(ctx, customer.ID); err != nil {
+		return nil, err
+	}
+
+	cartItems, err := ds.enrichCart(ctx, cartItems)
+	if err != nil {
+		return nil, err
 	}
+	cartItems = ds.startingPrices(cartItems)
 
+	segments, err := ds.resolveSegments(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+	customer.Segments = segments
+
+	salesChannel, err := salesChannelOf(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	validation := &models.CodeValidation{Code: code, EstimatedSavings: decimal.Zero}
 	discount, err := ds.discountRepo.GetDiscountByCode(ctx, code)
+	if errors.IsNotFoundError(err) {
+		discount, err = ds.discountForCouponCode(ctx, code)
+	}
 	if err != nil {
 		if errors.IsNotFoundError(err) {
-			return false, nil
+			return validation, nil
 		}
-		return false, fmt.Errorf("repo error: %w", err)
+		return nil, fmt.Errorf("repo error: %w", err)
+	}
+	// Customers held out of an experiment, or the code is not bound to, are not told what
+	// the code would have given them
+	if !discount.IsExposedTo(customer.ID) || !discount.IsBoundToCustomer(customer) {
+		return validation, nil
+	}
+
+	validation = discount.DescribeCode(code)
+	if !discount.IsValidAt(now) || !discount.IsSoldOn(salesChannel) || !discount.IsOccasionAt(customer, now) ||
+		!discount.AdmitsMemberAt(customer, now) {
+		return validation, nil
+	}
+
+	campaigns, err := ds.loadCampaigns(ctx, []models.Discount{*discount})
+	if err != nil {
+		return nil, err
+	}
+	spendLeft, err := ds.loadSpend(ctx, []models.Discount{*discount})
+	if err != nil {
+		return nil, err
+	}
+	if !hasFundsLeft(discount, campaigns, spendLeft) {
+		return validation, nil
+	}
+	introUsed, err := ds.loadIntroductoryRedemptions(ctx, []models.Discount{*discount}, customer.ID)
+	if err != nil {
+		return nil, err
+	}
+	if discount.IsIntroductory() && discount.IntroductoryOrdersLeft(customer, introUsed[discount.ID]) == 0 {
+		return validation, nil
 	}
 
 	strat := ds.strategyFactory.Get(discount.Type)
-	if strat == nil {
-		return false, nil
+	if strat == nil || !strat.IsApplicable(discount, cartItems, customer, nil) {
+		return validation, nil
 	}
 
-	return strat.IsApplicable(discount, cartItems, customer, nil), nil
+	validation.Valid = true
+	validation.EstimatedSavings = ds.estimateSavings(strat, discount, cartItems, customer, spendLeft)
+	return validation, nil
+}
+
+// estimateSavings is what the discount would take off the cart were it the only one applied
+func (ds *discountService) estimateSavings(strategy discount.DiscountStrategy, d *models.Discount,
+	cartItems []models.CartItem, customer models.CustomerProfile, spendLeft map[string]decimal.Decimal) decimal.Decimal {
+
+	total := models.GetCartTotal(cartItems)
+	baseCart, baseTotal := d.PricingBase(cartItems, total, total)
+	amount := calculateAmount(strategy, d, baseCart, customer, models.GetPromotableTotal(cartItems), baseTotal)
+	amount = ds.currency.RoundWith(amount, ds.roundingFor(d))
+	if remaining, capped := spendLeft[d.ID]; capped {
+		amount = decimal.Min(amount, remaining)
+	}
+	if !d.Cashback {
+		amount = decimal.Min(amount, total)
+	}
+	return decimal.Max(amount, decimal.Zero)
+}
+
+// calculateAmount asks the strategy what the discount takes off the cart, through the richest
+// calculator interface it implements
+func calculateAmount(strategy discount.DiscountStrategy, d *models.Discount, cart []models.CartItem,
+	customer models.CustomerProfile, subtotal, total decimal.Decimal) decimal.Decimal {
+
+	if cc, ok := strategy.(discount.CustomerCalculator); ok {
+		return cc.CalculateForCustomer(d, cart, customer, total)
+	}
+	if sc, ok := strategy.(discount.SubtotalCalculator); ok {
+		return sc.CalculateOnSubtotal(d, cart, subtotal, total)
+	}
+	return strategy.Calculate(d, cart, total)
+}
+
+// roundingFor is the rounding mode of the amounts the discount takes off
+func (ds *discountService) roundingFor(d *models.Discount) models.RoundingMode {
+	if d.Rounding != "" {
+		return d.Rounding
+	}
+	return ds.rounding
+}
+
+// discountForCouponCode returns the discount an unused single-use code unlocks
+func (ds *discountService) discountForCouponCode(ctx context.Context, code string) (*models.Discount, error) {
+	if ds.couponCodeRepo == nil {
+		return nil, errors.NewNotFoundError("discount code not found: " + code)
+	}
+	stored, err := ds.couponCodeRepo.GetCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if stored.IsRedeemed() {
+		return nil, errors.NewNotFoundError("coupon code already redeemed: " + code)
+	}
+	return ds.discountRepo.GetDiscountByID(ctx, stored.DiscountID)
+}
+
+// hasFundsLeft reports whether the discount's campaign is running with budget to spare and
+// the discount has not reached its own spend cap
+func hasFundsLeft(d *models.Discount, campaigns map[string]*models.Campaign, spendLeft map[string]decimal.Decimal) bool {
+	if campaign := campaigns[d.CampaignID]; campaign != nil {
+		if !campaign.IsActive || (campaign.HasBudgetLimit() && campaign.RemainingBudget().IsZero()) {
+			return false
+		}
+	}
+	if remaining, capped := spendLeft[d.ID]; capped && remaining.IsZero() {
+		return false
+	}
+	return true
 }
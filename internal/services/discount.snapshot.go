@@ -0,0 +1,19 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/ahsmha/discounts/pkg/clock"
+)
+
+// instant returns the instant the calculation is priced at: the one pinned in ctx by an
+// earlier stage of the same request, see clock.NewContext, so a request waiting for a
+// worker or an idempotency key is still priced at the instant it arrived, and the discounts
+// valid then; or the service clock's current time when the calculation starts here
+func (ds *discountService) instant(ctx context.Context) time.Time {
+	if now, ok := clock.Pinned(ctx); ok {
+		return now
+	}
+	return ds.clock.Now()
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// taxLines computes the tax charged on the cart once the applied discounts are known. The
+// discounts taken off before tax are spread over the lines they target, in proportion to
+// the lines' prices, lowering each line's taxable amount; tax is then charged at each rate
+// on what is left. Cashback and discounts applying after tax leave taxable amounts alone.
+func taxLines(calc *calculation, applied []appliedDiscount) ([]models.TaxLine, decimal.Decimal) {
+	if !models.HasTax(calc.cartItems) {
+		return nil, decimal.Zero
+	}
+
+	taxable := make([]decimal.Decimal, len(calc.cartItems))
+	for i := range calc.cartItems {
+		taxable[i] = calc.cartItems[i].GetTotalPrice()
+	}
+	for _, a := range applied {
+		if a.discount.Cashback || a.discount.AppliesAfterTax {
+			continue
+		}
+		targets := discountedLines(calc.cartItems, taxable, &a.discount)
+		if len(targets) == 0 {
+			targets = discountedLines(calc.cartItems, taxable, nil)
+		}
+		spreadDiscount(taxable, targets, a.amount)
+	}
+
+	byRate := make(map[string]*models.TaxLine)
+	for i, item := range calc.cartItems {
+		rate := item.Product.TaxRate
+		line, exists := byRate[rate.String()]
+		if !exists {
+			line = &models.TaxLine{Rate: rate}
+			byRate[rate.String()] = line
+		}
+		line.TaxableAmount = line.TaxableAmount.Add(taxable[i])
+	}
+
+	lines := make([]models.TaxLine, 0, len(byRate))
+	total := decimal.Zero
+	for _, line := range byRate {
+		line.TaxableAmount = calc.currency.Round(line.TaxableAmount)
+		line.Tax = calc.currency.Round(line.TaxableAmount.Mul(line.Rate).Div(decimal.NewFromInt(models.PercentageBase)))
+		total = total.Add(line.Tax)
+		lines = append(lines, *line)
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].Rate.LessThan(lines[j].Rate) })
+	return lines, total
+}
+
+// discountedLines returns the indexes of the promotable lines with a taxable amount left
+// that the discount targets, every such line for a nil discount
+func discountedLines(cart []models.CartItem, taxable []decimal.Decimal, d *models.Discount) []int {
+	var targets []int
+	for i, item := range cart {
+		if item.ExcludeFromPromotions || !taxable[i].IsPositive() {
+			continue
+		}
+		if d == nil || d.MatchesProduct(item.Product) {
+			targets = append(targets, i)
+		}
+	}
+	return targets
+}
+
+// spreadDiscount takes the amount off the target lines' taxable amounts in proportion to
+// them, the last line taking the remainder so the shares add up to the amount
+func spreadDiscount(taxable []decimal.Decimal, targets []int, amount decimal.Decimal) {
+	base := decimal.Zero
+	for _, i := range targets {
+		base = base.Add(taxable[i])
+	}
+	if !base.IsPositive() {
+		return
+	}
+
+	left := decimal.Min(amount, base)
+	for n, i := range targets {
+		share := left
+		if n < len(targets)-1 {
+			share = decimal.Min(amount.Mul(taxable[i]).DivRound(base, 16), left)
+		}
+		share = decimal.Min(share, taxable[i])
+		taxable[i] = taxable[i].Sub(share)
+		left = left.Sub(share)
+	}
+}
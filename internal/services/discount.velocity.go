@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ahsmha/discounts/pkg/clientip"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// VelocityLimit allows MaxAttempts attempts per Window. A zero MaxAttempts disables it.
+type VelocityLimit struct {
+	MaxAttempts int64
+	Window      time.Duration
+}
+
+// CodeValidationLimits bounds how often ValidateDiscountCode may be called per customer and
+// per client address (see package clientip)
+type CodeValidationLimits struct {
+	PerCustomer VelocityLimit
+	PerIP       VelocityLimit
+}
+
+// checkCodeValidationVelocity counts a code validation attempt against every configured
+// limit, failing with a too many attempts error once one of them is exceeded. Requests
+// without a customer ID or client address are only limited by the key they do have.
+func (ds *discountService) checkCodeValidationVelocity(ctx context.Context, customerID string) error {
+	if ds.attemptCounter == nil {
+		return nil
+	}
+
+	type check struct {
+		key   string
+		limit VelocityLimit
+	}
+	var checks []check
+	if customerID != "" {
+		checks = append(checks, check{"validate-code:customer:" + customerID, ds.codeLimits.PerCustomer})
+	}
+	if ip := clientip.FromContext(ctx); ip != "" {
+		checks = append(checks, check{"validate-code:ip:" + ip, ds.codeLimits.PerIP})
+	}
+
+	for _, check := range checks {
+		if check.limit.MaxAttempts <= 0 {
+			continue
+		}
+		count, resetIn, err := ds.attemptCounter.Hit(ctx, check.key, check.limit.Window)
+		if err != nil {
+			return fmt.Errorf("failed to count code validation attempt: %w", err)
+		}
+		if count > check.limit.MaxAttempts {
+			return errors.NewTooManyAttemptsError("too many discount code attempts, retry later", resetIn)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// recordPricing stores the pricing record of a committed order
+func (ds *discountService) recordPricing(ctx context.Context, req *models.CalculationRequest, calc *calculation,
+	applied []appliedDiscount, result *models.DiscountedPrice) error {
+
+	if ds.pricingRecords == nil || calc.orderID == "" {
+		return nil
+	}
+
+	record := models.OrderPricingRecord{
+		OrderID:       calc.orderID,
+		CustomerID:    calc.customer.ID,
+		PricedAt:      calc.now,
+		Request:       *req,
+		Result:        *result,
+		Discounts:     make([]models.Discount, 0, len(applied)),
+		PointsBalance: calc.customer.PointsBalance,
+	}
+	for _, a := range applied {
+		record.Discounts = append(record.Discounts, a.discount)
+		if code, ok := calc.couponCodes[a.discount.ID]; ok {
+			if record.CouponCodes == nil {
+				record.CouponCodes = make(map[string]string)
+			}
+			record.CouponCodes[a.discount.ID] = code
+		}
+	}
+	if err := ds.pricingRecords.SavePricingRecord(ctx, record); err != nil {
+		return fmt.Errorf("failed to record pricing: %w", err)
+	}
+	return nil
+}
+
+// VerifyOrderPricing prices the recorded request again, at the instant it was priced and
+// against the recorded discount definitions alone, without committing anything. Campaign
+// budgets, spend caps and gift cards have moved on since, so they are left out; the points
+// balance and single-use codes are those recorded.
+func (ds *discountService) VerifyOrderPricing(ctx context.Context, orderID string) (*models.PricingVerification, error) {
+	if ds.pricingRecords == nil {
+		return nil, errors.NewNotFoundError("pricing records are not kept")
+	}
+	record, err := ds.pricingRecords.GetPricingRecord(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := repositories.NewInMemoryDiscountRepository()
+	for i := range record.Discounts {
+		d := record.Discounts[i]
+		if err := snapshot.CreateDiscount(ctx, &d); err != nil {
+			return nil, fmt.Errorf("failed to restore discount %s: %w", d.ID, err)
+		}
+	}
+
+	replay := *ds
+	replay.discountRepo = snapshot
+	replay.clock = clock.NewFixed(record.PricedAt)
+	replay.campaignRepo, replay.spendTracker, replay.giftCardRepo = nil, nil, nil
+	replay.couponCodeRepo, replay.loyaltyProvider, replay.redemptionRepo = nil, nil, nil
+
+	req := record.Request
+	req.GiftCardCodes, req.ExpectedTotal, req.AllowPartial = nil, nil, false
+	run, err := replay.prepare(ctx, &req, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay order %s: %w", orderID, err)
+	}
+	// The snapshot numbers its discounts from 1 again
+	versions := record.DiscountVersions()
+	for i := range run.discounts {
+		run.discounts[i].Version = versions[run.discounts[i].ID]
+	}
+	run.calc.customer.PointsBalance = record.PointsBalance
+	for id, code := range record.CouponCodes {
+		run.calc.couponCodes[id] = code
+	}
+	recalculated, _ := replay.applyDiscounts(run.calc, run.discounts, "", nil)
+	if run.calc.stopped != nil {
+		return nil, run.calc.stopped
+	}
+
+	differences := models.PricingDifferences(&record.Result, recalculated)
+	return &models.PricingVerification{
+		OrderID:      orderID,
+		Matches:      len(differences) == 0,
+		Recorded:     &record.Result,
+		Recalculated: recalculated,
+		Differences:  differences,
+	}, nil
+}
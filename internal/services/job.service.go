@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+const (
+	// defaultJobChunkSize is how many carts a job prices between checkpoints by default
+	defaultJobChunkSize = 100
+
+	// maxJobResultsPage caps the results returned by one GetJobResults call
+	maxJobResultsPage = 1000
+)
+
+// JobService runs batch pricing jobs in the background on top of a discount service. Each
+// job prices its carts a chunk at a time through CalculateCartDiscountsBatch, so it runs in
+// the batch traffic lane, and saves its results and checkpoint after every chunk.
+//
+// Carts priced after the last checkpoint when a job is cut short are priced again when it
+// resumes. Jobs that record usage should therefore give their carts idempotency keys;
+// repricing jobs should be dry runs.
+type JobService struct {
+	discounts interfaces.IDiscountService
+	repo      interfaces.IJobRepository
+
+	// ChunkSize is how many carts are priced between checkpoints, defaultJobChunkSize when
+	// not positive. Chunks are cut down to the discount service's batch limit.
+	ChunkSize int
+
+	// Clock timestamps jobs and their progress. Defaults to the system clock.
+	Clock clock.Clock
+
+	// OnError is called when a running job cannot read its carts or save its progress. The
+	// job stops and is picked up from its last checkpoint by the next Resume.
+	OnError func(job *models.Job, err error)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+var _ interfaces.IJobService = (*JobService)(nil)
+
+// NewJobService creates a job service pricing with discounts and keeping jobs in repo
+func NewJobService(discounts interfaces.IDiscountService, repo interfaces.IJobRepository) *JobService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JobService{discounts: discounts, repo: repo, Clock: clock.System(), ctx: ctx, cancel: cancel}
+}
+
+// SubmitJob stores the job and starts pricing its carts in the background
+func (s *JobService) SubmitJob(ctx context.Context, req *models.BatchCalculationRequest) (*models.Job, error) {
+	if len(req.Carts) == 0 {
+		return nil, errors.NewValidationError("job has no carts")
+	}
+	for i, cart := range req.Carts {
+		if cart == nil {
+			return nil, errors.NewValidationError(fmt.Sprintf("job cart %d is missing", i))
+		}
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, errors.NewInternalError("failed to generate job ID", err)
+	}
+	now := s.Clock.Now()
+	job := &models.Job{
+		ID:          id,
+		TenantID:    tenant.FromContext(ctx),
+		Status:      models.JobQueued,
+		DryRun:      req.DryRun,
+		Total:       len(req.Carts),
+		SubmittedAt: now,
+		UpdatedAt:   now,
+	}
+	if err := s.repo.CreateJob(ctx, job, req.Carts); err != nil {
+		return nil, err
+	}
+
+	s.start(*job)
+	return job, nil
+}
+
+// GetJob reports the job's status and progress
+func (s *JobService) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	return s.repo.GetJob(ctx, id)
+}
+
+// GetJobResults pages through the results of the carts processed so far
+func (s *JobService) GetJobResults(ctx context.Context, id string, offset, limit int) (*models.JobResultPage, error) {
+	if offset < 0 || limit < 0 {
+		return nil, errors.NewValidationError("offset and limit cannot be negative")
+	}
+	if limit == 0 || limit > maxJobResultsPage {
+		limit = maxJobResultsPage
+	}
+
+	job, err := s.repo.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	limit = min(limit, max(job.Processed-offset, 0))
+
+	page := &models.JobResultPage{Results: []models.JobResult{}}
+	if limit == 0 {
+		return page, nil
+	}
+	if page.Results, err = s.repo.GetJobResults(ctx, id, offset, limit); err != nil {
+		return nil, err
+	}
+	if next := offset + limit; next < job.Processed {
+		page.NextOffset = next
+	}
+	return page, nil
+}
+
+// Resume restarts every unfinished job from its checkpoint, e.g. after a restart
+func (s *JobService) Resume(ctx context.Context) error {
+	jobs, err := s.repo.ListUnfinishedJobs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		s.start(*job)
+	}
+	return nil
+}
+
+// Close stops every running job at its next chunk and waits for them to save their
+// progress, leaving them to be resumed
+func (s *JobService) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *JobService) start(job models.Job) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(tenant.NewContext(s.ctx, job.TenantID), &job)
+	}()
+}
+
+// run prices the job's remaining carts a chunk at a time, saving progress after each
+func (s *JobService) run(ctx context.Context, job *models.Job) {
+	if job.Status != models.JobRunning {
+		job.Status, job.UpdatedAt = models.JobRunning, s.Clock.Now()
+		if err := s.repo.SaveJobProgress(ctx, job, nil); err != nil {
+			s.stop(job, err)
+			return
+		}
+	}
+
+	for job.Processed < job.Total && ctx.Err() == nil {
+		carts, err := s.repo.GetJobCarts(ctx, job.ID, job.Processed, s.chunkSize())
+		if err != nil {
+			s.stop(job, err)
+			return
+		}
+		if len(carts) == 0 {
+			s.fail(ctx, job, fmt.Sprintf("job carts are missing from index %d", job.Processed))
+			return
+		}
+
+		results, err := s.priceChunk(ctx, job, carts)
+		if err != nil && err != ctx.Err() {
+			s.fail(ctx, job, jobErrorMessage(err))
+			return
+		}
+		for _, result := range results {
+			if result.Error != "" {
+				job.Failed++
+			} else {
+				job.Succeeded++
+			}
+		}
+		job.Processed += len(results)
+		job.UpdatedAt = s.Clock.Now()
+		if job.Processed == job.Total {
+			job.Status = models.JobSucceeded
+			job.FinishedAt = &job.UpdatedAt
+		}
+
+		// A stopped job still saves the carts priced before it stopped
+		if err := s.repo.SaveJobProgress(context.WithoutCancel(ctx), job, results); err != nil {
+			s.stop(job, err)
+			return
+		}
+	}
+}
+
+// priceChunk prices the carts, which start at the job's checkpoint, retrying the carts the
+// batch lane turned away as overloaded once it has capacity again. When ctx is done it
+// returns the results of the leading carts priced so far with the context's error.
+func (s *JobService) priceChunk(ctx context.Context, job *models.Job, carts []*models.CalculationRequest) ([]models.JobResult, error) {
+	results := make([]*models.JobResult, len(carts))
+	pending := make([]int, len(carts))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	for len(pending) > 0 {
+		req := &models.BatchCalculationRequest{Carts: make([]*models.CalculationRequest, len(pending)), DryRun: job.DryRun}
+		for i, index := range pending {
+			req.Carts[i] = carts[index]
+		}
+		batch, err := s.discounts.CalculateCartDiscountsBatch(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		var retry []int
+		var retryAfter time.Duration
+		for i, item := range batch.Results {
+			index := pending[i]
+			if errors.IsOverloadedError(item.Err) {
+				wait, _ := errors.RetryAfter(item.Err)
+				retry, retryAfter = append(retry, index), max(retryAfter, wait)
+				continue
+			}
+			if item.Err != nil && item.Err == ctx.Err() {
+				continue // Not priced, left for the resumed job
+			}
+			results[index] = &models.JobResult{Index: job.Processed + index, Result: item.Result, Error: jobErrorMessage(item.Err)}
+		}
+
+		pending = retry
+		if len(pending) > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryAfter):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	priced := make([]models.JobResult, 0, len(results))
+	for _, result := range results {
+		if result == nil {
+			return priced, ctx.Err()
+		}
+		priced = append(priced, *result)
+	}
+	return priced, nil
+}
+
+// stop reports an error the job cannot record, leaving it to be resumed
+func (s *JobService) stop(job *models.Job, err error) {
+	if s.OnError != nil {
+		s.OnError(job, err)
+	}
+}
+
+// fail records the job as failed with the reason
+func (s *JobService) fail(ctx context.Context, job *models.Job, reason string) {
+	job.Status, job.Error = models.JobFailed, reason
+	job.UpdatedAt = s.Clock.Now()
+	job.FinishedAt = &job.UpdatedAt
+	if err := s.repo.SaveJobProgress(context.WithoutCancel(ctx), job, nil); err != nil {
+		s.stop(job, err)
+	}
+}
+
+// batchLimiter is implemented by discount services that limit their batch size
+type batchLimiter interface {
+	maxBatchCarts() int
+}
+
+func (s *JobService) chunkSize() int {
+	size := defaultJobChunkSize
+	if s.ChunkSize > 0 {
+		size = s.ChunkSize
+	}
+	if limiter, ok := s.discounts.(batchLimiter); ok {
+		if max := limiter.maxBatchCarts(); max > 0 {
+			size = min(size, max)
+		}
+	}
+	return size
+}
+
+// jobErrorMessage describes a cart's failure the way the API would, without describing
+// unexpected errors
+func jobErrorMessage(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.IsValidationError(err), errors.IsTotalMismatchError(err), errors.IsConflictError(err),
+		errors.IsNotFoundError(err), errors.IsTooManyAttemptsError(err), errors.IsOverloadedError(err):
+		return err.Error()
+	default:
+		return "internal error"
+	}
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "job-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/actor"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// LifecycleService moves discounts through their publication workflow, checking every
+// transition with its authorizer. Transitions are stored through the repository's versioned
+// update, so two approvers acting at once cannot both move the same discount.
+type LifecycleService struct {
+	repo       interfaces.IDiscountRepository
+	authorizer interfaces.Authorizer
+}
+
+var _ interfaces.ILifecycleService = (*LifecycleService)(nil)
+
+// NewLifecycleService creates a lifecycle service for the discounts in repo
+func NewLifecycleService(repo interfaces.IDiscountRepository, authorizer interfaces.Authorizer) *LifecycleService {
+	return &LifecycleService{repo: repo, authorizer: authorizer}
+}
+
+// Transition takes the action on the discount, recording who submitted it for approval
+func (s *LifecycleService) Transition(ctx context.Context, id string, action models.LifecycleAction) (*models.Discount, error) {
+	if err := action.Validate(); err != nil {
+		return nil, errors.NewValidationError(err.Error())
+	}
+
+	discount, err := s.repo.GetDiscountByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	next, err := discount.NextLifecycleState(action)
+	if err != nil {
+		return nil, errors.NewConflictError(err.Error() + ": " + id)
+	}
+	if err := s.authorizer.AuthorizeTransition(ctx, discount, action); err != nil {
+		return nil, err
+	}
+
+	updated := *discount
+	updated.State = next
+	switch action {
+	case models.ActionSubmit:
+		updated.SubmittedBy = actor.FromContext(ctx)
+	case models.ActionReject:
+		updated.SubmittedBy = ""
+	}
+	if err := s.repo.UpdateDiscount(ctx, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// RoleAuthorizer authorises transitions by the roles of the acting actor: editors submit
+// discounts, approvers take every other action and admins take any. Approvers cannot
+// approve a discount they submitted themselves, so publishing always takes two people.
+type RoleAuthorizer struct {
+	Roles map[string][]models.Role // actor -> roles
+}
+
+// AuthorizeTransition checks the actor of ctx holds the role the action requires
+func (a RoleAuthorizer) AuthorizeTransition(ctx context.Context, discount *models.Discount, action models.LifecycleAction) error {
+	who := actor.FromContext(ctx)
+	if who == "" {
+		return errors.NewForbiddenError("lifecycle transitions require a known actor")
+	}
+
+	required := action.RequiredRole()
+	switch {
+	case !models.HasRole(a.Roles[who], required):
+		return errors.NewForbiddenError(fmt.Sprintf("%s needs the %s role to %s discounts", who, required, action))
+	case action == models.ActionApprove && discount.SubmittedBy == who:
+		return errors.NewForbiddenError(fmt.Sprintf("%s cannot approve a discount they submitted: %s", who, discount.ID))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// PriorityRanker lists offers by descending priority
+type PriorityRanker struct{}
+
+// Rank sorts offers by descending priority
+func (PriorityRanker) Rank(offers []models.Offer, customer models.CustomerProfile) {
+	sort.SliceStable(offers, func(i, j int) bool {
+		return offers[i].Priority > offers[j].Priority
+	})
+}
+
+// ExpiryRanker lists the offers ending soonest first
+type ExpiryRanker struct{}
+
+// Rank sorts offers by ascending expiry
+func (ExpiryRanker) Rank(offers []models.Offer, customer models.CustomerProfile) {
+	sort.SliceStable(offers, func(i, j int) bool {
+		return offers[i].ExpiresAt.Before(offers[j].ExpiresAt)
+	})
+}
+
+// SavingsRanker lists the offers saving the most on a reference cart first, letting
+// percentage and fixed amount offers be compared
+type SavingsRanker struct {
+	BasketTotal decimal.Decimal // Cart total the savings are estimated on
+}
+
+// Rank sorts offers by descending estimated savings
+func (r SavingsRanker) Rank(offers []models.Offer, customer models.CustomerProfile) {
+	sort.SliceStable(offers, func(i, j int) bool {
+		return offers[i].SavingsOn(r.BasketTotal).GreaterThan(offers[j].SavingsOn(r.BasketTotal))
+	})
+}
+
+// SegmentRanker personalises listings per customer segment (tier): offers of the types the
+// segment prefers come first, in preference order, and Fallback orders the rest and ties
+type SegmentRanker struct {
+	Preferences map[string][]models.DiscountType // customer tier -> preferred discount types
+	Fallback    interfaces.IOfferRanker
+}
+
+// Rank sorts offers by the customer's segment preferences
+func (r SegmentRanker) Rank(offers []models.Offer, customer models.CustomerProfile) {
+	if r.Fallback != nil {
+		r.Fallback.Rank(offers, customer)
+	}
+
+	preferred := r.Preferences[customer.Tier]
+	if len(preferred) == 0 {
+		return
+	}
+
+	rank := make(map[models.DiscountType]int, len(preferred))
+	for i, discountType := range preferred {
+		rank[discountType] = i
+	}
+	position := func(o models.Offer) int {
+		if i, ok := rank[o.Type]; ok {
+			return i
+		}
+		return len(preferred)
+	}
+
+	sort.SliceStable(offers, func(i, j int) bool {
+		return position(offers[i]) < position(offers[j])
+	})
+}
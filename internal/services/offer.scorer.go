@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+const (
+	// defaultScorerTimeout bounds a personalization scorer call by default
+	defaultScorerTimeout = 50 * time.Millisecond
+
+	// defaultHeuristicBasket is the cart total HeuristicScorer estimates savings on by default
+	defaultHeuristicBasket = 1000
+)
+
+// PersonalizationConfig tunes how a personalization scorer is invoked. Zero fields take
+// their defaults.
+type PersonalizationConfig struct {
+	Timeout  time.Duration                    // Longest a scorer call may take, default 50ms
+	Fallback interfaces.PersonalizationScorer // Scores offers when the scorer cannot, default HeuristicScorer{}
+
+	// OnError is called when the scorer fails and its fallback scores the offers instead
+	OnError func(err error)
+}
+
+// HeuristicScorer is the default personalization: offers score their estimated savings on a
+// reference basket, plus one for offers ending within a day so expiring savings are not missed
+type HeuristicScorer struct {
+	BasketTotal decimal.Decimal // Cart total the savings are estimated on, default 1000
+}
+
+// ScoreOffers scores every offer by estimated savings and urgency
+func (h HeuristicScorer) ScoreOffers(ctx context.Context, customer models.CustomerProfile, offers []models.Offer) ([]float64, error) {
+	basket := h.BasketTotal
+	if !basket.IsPositive() {
+		basket = decimal.NewFromInt(defaultHeuristicBasket)
+	}
+
+	scores := make([]float64, len(offers))
+	for i := range offers {
+		scores[i] = offers[i].SavingsOn(basket).InexactFloat64()
+		if offers[i].Urgency.SecondsUntilExpiry < int64(24*time.Hour/time.Second) {
+			scores[i]++
+		}
+	}
+	return scores, nil
+}
+
+// scoreOffers sorts the offers by descending score, keeping the current order of equal scores
+func (ds *discountService) scoreOffers(ctx context.Context, offers []models.Offer, customer models.CustomerProfile) {
+	scores, err := ds.invokeScorer(ctx, offers, customer)
+	if err != nil {
+		if ds.personalization.OnError != nil {
+			ds.personalization.OnError(err)
+		}
+		fallback := ds.personalization.Fallback
+		if fallback == nil {
+			fallback = HeuristicScorer{}
+		}
+		if scores, err = fallback.ScoreOffers(ctx, customer, offers); err != nil || len(scores) != len(offers) {
+			return // Left in ranker order
+		}
+	}
+
+	indexes := make([]int, len(offers))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return scores[indexes[i]] > scores[indexes[j]]
+	})
+	sorted := make([]models.Offer, len(offers))
+	for i, index := range indexes {
+		sorted[i] = offers[index]
+	}
+	copy(offers, sorted)
+}
+
+// invokeScorer calls the scorer within the configured timeout, on a copy of the offers so a
+// scorer still running after the timeout cannot touch the listing
+func (ds *discountService) invokeScorer(ctx context.Context, offers []models.Offer, customer models.CustomerProfile) ([]float64, error) {
+	timeout := ds.personalization.Timeout
+	if timeout <= 0 {
+		timeout = defaultScorerTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type scored struct {
+		scores []float64
+		err    error
+	}
+	done := make(chan scored, 1)
+	candidates := append([]models.Offer(nil), offers...)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- scored{err: fmt.Errorf("personalization scorer panicked: %v", r)}
+			}
+		}()
+		scores, err := ds.offerScorer.ScoreOffers(ctx, customer, candidates)
+		done <- scored{scores, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("personalization scorer: %w", ctx.Err())
+	case result := <-done:
+		switch {
+		case result.err != nil:
+			return nil, fmt.Errorf("personalization scorer: %w", result.err)
+		case len(result.scores) != len(offers):
+			return nil, fmt.Errorf("personalization scorer returned %d scores for %d offers", len(result.scores), len(offers))
+		}
+		for _, score := range result.scores {
+			if math.IsNaN(score) {
+				return nil, fmt.Errorf("personalization scorer returned NaN")
+			}
+		}
+		return result.scores, nil
+	}
+}
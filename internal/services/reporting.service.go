@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// reportPrecision is the number of decimal places kept in rates and uplifts
+const reportPrecision = 4
+
+type reportingService struct {
+	experimentRepo interfaces.IExperimentRepository
+	orderRepo      interfaces.IOrderRepository
+	redemptionRepo interfaces.IRedemptionRepository
+}
+
+func NewReportingService(experimentRepo interfaces.IExperimentRepository, orderRepo interfaces.IOrderRepository,
+	redemptionRepo interfaces.IRedemptionRepository) interfaces.IReportingService {
+	return &reportingService{
+		experimentRepo: experimentRepo,
+		orderRepo:      orderRepo,
+		redemptionRepo: redemptionRepo,
+	}
+}
+
+func (rs *reportingService) GetIncrementalityReport(ctx context.Context, experimentID, controlVariant string,
+	from, to time.Time) (*models.IncrementalityReport, error) {
+
+	if experimentID == "" {
+		return nil, errors.NewValidationError("experiment id cannot be empty")
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return nil, errors.NewValidationError("report window start must be before its end")
+	}
+
+	assignments, err := rs.experimentRepo.ListAssignments(ctx, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignments: %w", err)
+	}
+
+	orders, err := rs.orderRepo.ListOrders(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders: %w", err)
+	}
+
+	redemptions, err := rs.redemptionRepo.ListRedemptions(ctx, models.RedemptionFilter{From: from, To: to})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redemptions: %w", err)
+	}
+
+	byCustomer := make(map[string]models.ExperimentAssignment, len(assignments))
+	metrics := make(map[string]*models.VariantMetrics)
+	for _, assignment := range assignments {
+		byCustomer[assignment.CustomerID] = assignment
+		m, exists := metrics[assignment.Variant]
+		if !exists {
+			m = &models.VariantMetrics{Variant: assignment.Variant}
+			metrics[assignment.Variant] = m
+		}
+		m.Customers++
+	}
+
+	if _, exists := metrics[controlVariant]; !exists {
+		return nil, errors.NewNotFoundError("control variant has no assignments: " + controlVariant)
+	}
+
+	// Only activity after a customer was exposed to the experiment counts towards its variant
+	converted := make(map[string]bool)
+	for _, order := range orders {
+		assignment, exists := byCustomer[order.CustomerID]
+		if !exists || order.PlacedAt.Before(assignment.AssignedAt) {
+			continue
+		}
+		m := metrics[assignment.Variant]
+		m.Orders++
+		m.Revenue = m.Revenue.Add(order.Total)
+		if !converted[order.CustomerID] {
+			converted[order.CustomerID] = true
+			m.ConvertedCustomers++
+		}
+	}
+
+	for _, redemption := range redemptions {
+		assignment, exists := byCustomer[redemption.CustomerID]
+		if !exists || redemption.RedeemedAt.Before(assignment.AssignedAt) {
+			continue
+		}
+		m := metrics[assignment.Variant]
+		m.Redemptions++
+		m.DiscountCost = m.DiscountCost.Add(redemption.Amount)
+	}
+
+	hundred := decimal.NewFromInt(models.PercentageBase)
+	for _, m := range metrics {
+		m.ConversionRate = decimal.NewFromInt(int64(m.ConvertedCustomers)).
+			Div(decimal.NewFromInt(int64(m.Customers))).Mul(hundred).Round(reportPrecision)
+		if m.Orders > 0 {
+			m.AverageOrderValue = m.Revenue.Div(decimal.NewFromInt(int64(m.Orders))).Round(reportPrecision)
+		}
+	}
+
+	control := metrics[controlVariant]
+	report := &models.IncrementalityReport{
+		ExperimentID:   experimentID,
+		ControlVariant: controlVariant,
+		From:           from,
+		To:             to,
+		Variants:       make([]models.VariantMetrics, 0, len(metrics)),
+	}
+	for _, m := range metrics {
+		m.ConversionUplift = uplift(m.ConversionRate, control.ConversionRate)
+		m.AOVUplift = uplift(m.AverageOrderValue, control.AverageOrderValue)
+		report.Variants = append(report.Variants, *m)
+	}
+
+	// Control first, then variants by name, so reports are stable between runs
+	sort.Slice(report.Variants, func(i, j int) bool {
+		if report.Variants[i].Variant == controlVariant {
+			return true
+		}
+		if report.Variants[j].Variant == controlVariant {
+			return false
+		}
+		return report.Variants[i].Variant < report.Variants[j].Variant
+	})
+
+	return report, nil
+}
+
+func (rs *reportingService) GetCodeLeaderboard(ctx context.Context, campaignID string,
+	from, to time.Time) (*models.CodeLeaderboard, error) {
+
+	if campaignID == "" {
+		return nil, errors.NewValidationError("campaign id cannot be empty")
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return nil, errors.NewValidationError("report window start must be before its end")
+	}
+
+	redemptions, err := rs.redemptionRepo.ListRedemptions(ctx, models.RedemptionFilter{CampaignID: campaignID, From: from, To: to})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redemptions: %w", err)
+	}
+
+	board := &models.CodeLeaderboard{CampaignID: campaignID, From: from, To: to, Standings: []models.CodeStanding{}}
+	byCode := make(map[string]*models.CodeStanding)
+	for _, redemption := range redemptions {
+		if redemption.Code == "" {
+			board.Unattributed++
+			continue
+		}
+		standing, exists := byCode[redemption.Code]
+		if !exists {
+			standing = &models.CodeStanding{Code: redemption.Code, DiscountID: redemption.DiscountID}
+			byCode[redemption.Code] = standing
+		}
+		standing.Redemptions++
+		standing.Revenue = standing.Revenue.Add(redemption.OrderTotal)
+		standing.DiscountCost = standing.DiscountCost.Add(redemption.Amount)
+	}
+
+	for _, standing := range byCode {
+		board.Standings = append(board.Standings, *standing)
+	}
+
+	// Highest revenue first, ties broken by redemptions and then code so reports are stable
+	sort.Slice(board.Standings, func(i, j int) bool {
+		a, b := board.Standings[i], board.Standings[j]
+		if !a.Revenue.Equal(b.Revenue) {
+			return a.Revenue.GreaterThan(b.Revenue)
+		}
+		if a.Redemptions != b.Redemptions {
+			return a.Redemptions > b.Redemptions
+		}
+		return a.Code < b.Code
+	})
+	for i := range board.Standings {
+		board.Standings[i].Rank = i + 1
+	}
+
+	return board, nil
+}
+
+// uplift returns the percentage change of value relative to baseline, zero when there is no baseline
+func uplift(value, baseline decimal.Decimal) decimal.Decimal {
+	if baseline.IsZero() {
+		return decimal.Zero
+	}
+	return value.Sub(baseline).Div(baseline).Mul(decimal.NewFromInt(models.PercentageBase)).Round(reportPrecision)
+}
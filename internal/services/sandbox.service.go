@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/repositories"
+)
+
+// Sandbox is an isolated discount environment for partners to experiment in. It owns its
+// own in-memory repositories and trackers, so discounts created and carts priced in it
+// never touch production usage counts, spend or budgets. Reset restores the seed data.
+type Sandbox struct {
+	seed []models.Discount
+	opts []Option
+
+	mu             sync.RWMutex
+	discountRepo   interfaces.IDiscountRepository
+	campaignRepo   interfaces.ICampaignRepository
+	redemptionRepo interfaces.IRedemptionRepository
+	service        interfaces.IDiscountService
+}
+
+// NewSandbox creates a sandbox seeded with the given discounts. Options are applied to the
+// sandbox's service after its own isolated stores, so they must not point at production ones.
+func NewSandbox(seed []models.Discount, opts ...Option) (*Sandbox, error) {
+	s := &Sandbox{
+		seed: append([]models.Discount(nil), seed...),
+		opts: opts,
+	}
+	if err := s.Reset(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reset discards everything done in the sandbox and reloads the seed discounts
+func (s *Sandbox) Reset(ctx context.Context) error {
+	discountRepo := repositories.NewInMemoryDiscountRepository()
+	for i := range s.seed {
+		if err := discountRepo.CreateDiscount(ctx, &s.seed[i]); err != nil {
+			return fmt.Errorf("failed to seed sandbox: %w", err)
+		}
+	}
+
+	campaignRepo := repositories.NewInMemoryCampaignRepository()
+	redemptionRepo := repositories.NewInMemoryRedemptionRepository()
+
+	opts := append([]Option{
+		WithCampaignRepository(campaignRepo),
+		WithRedemptionRepository(redemptionRepo),
+		WithSpendTracker(repositories.NewInMemorySpendTracker()),
+	}, s.opts...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.discountRepo = discountRepo
+	s.campaignRepo = campaignRepo
+	s.redemptionRepo = redemptionRepo
+	s.service = NewDiscountService(discountRepo, opts...)
+	return nil
+}
+
+// Service returns the sandbox's discount service. Fetch it again after Reset.
+func (s *Sandbox) Service() interfaces.IDiscountService {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.service
+}
+
+// Discounts returns the sandbox's discount repository, where partners create their discounts
+func (s *Sandbox) Discounts() interfaces.IDiscountRepository {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.discountRepo
+}
+
+// Campaigns returns the sandbox's campaign repository
+func (s *Sandbox) Campaigns() interfaces.ICampaignRepository {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.campaignRepo
+}
+
+// Redemptions returns the redemptions recorded by calculations run in the sandbox
+func (s *Sandbox) Redemptions() interfaces.IRedemptionRepository {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.redemptionRepo
+}
@@ -0,0 +1,162 @@
+package warehouse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// exportPageSize bounds the discounts read from the repository at once
+const exportPageSize = 500
+
+// Exporter writes the context tenant's discounts and redemptions to a sink
+type Exporter struct {
+	Discounts   interfaces.DiscountLister
+	Redemptions interfaces.IRedemptionRepository
+	Sink        Sink
+
+	Format  Format                         // Defaults to CSV
+	Prefix  string                         // Prepended to every object path, e.g. "discounts-service/"
+	Clock   clock.Clock                    // Defaults to the system clock
+	OnError func(day time.Time, err error) // Called by Run when a scheduled export fails
+}
+
+// File is one object written by an export
+type File struct {
+	Path string `json:"path"`
+	Rows int    `json:"rows"`
+}
+
+// Manifest lists the objects written by an export of one day
+type Manifest struct {
+	Day   time.Time `json:"day"`
+	Files []File    `json:"files"`
+}
+
+// ExportDay writes the day's partition of every table: the current discount definitions
+// and the redemptions recorded during the UTC day containing day. Schemas are rewritten
+// alongside, so they always describe the data.
+func (e *Exporter) ExportDay(ctx context.Context, day time.Time) (*Manifest, error) {
+	start := day.UTC().Truncate(24 * time.Hour)
+	now := e.clock().Now()
+	ctx = clock.NewContext(ctx, now)
+	manifest := &Manifest{Day: start}
+
+	var rows [][]string
+	filter := models.ListFilter{PageSize: exportPageSize}
+	for {
+		page, err := e.Discounts.ListDiscounts(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list discounts: %w", err)
+		}
+		for _, d := range page.Discounts {
+			rows = append(rows, discountRow(d, now))
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+	if err := e.writeTable(ctx, manifest, DiscountsTable, start, rows); err != nil {
+		return nil, err
+	}
+
+	redemptions, err := e.Redemptions.ListRedemptions(ctx, redemptionWindow(start))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list redemptions: %w", err)
+	}
+	rows = make([][]string, len(redemptions))
+	for i, r := range redemptions {
+		rows[i] = redemptionRow(r)
+	}
+	if err := e.writeTable(ctx, manifest, RedemptionsTable, start, rows); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// Run exports the last complete UTC day immediately and then every interval, until ctx is
+// done. Each day is exported once per run; failures are reported to OnError and retried
+// at the next tick.
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var exported time.Time
+	for {
+		day := e.clock().Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+		if !day.Equal(exported) {
+			if _, err := e.ExportDay(ctx, day); err != nil {
+				if e.OnError != nil {
+					e.OnError(day, err)
+				}
+			} else {
+				exported = day
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Exporter) writeTable(ctx context.Context, manifest *Manifest, table Table, day time.Time, rows [][]string) error {
+	format := e.format()
+
+	schema, err := json.MarshalIndent(table, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := e.Sink.Put(ctx, e.Prefix+table.Name+"/_schema.json", schema); err != nil {
+		return fmt.Errorf("failed to write %s schema: %w", table.Name, err)
+	}
+
+	data, err := encode(format, table, rows)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", table.Name, err)
+	}
+	path := e.partitionPath(ctx, table, day) + "/part-0000." + format.Extension()
+	if err := e.Sink.Put(ctx, path, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	manifest.Files = append(manifest.Files, File{Path: path, Rows: len(rows)})
+	return nil
+}
+
+func (e *Exporter) partitionPath(ctx context.Context, table Table, day time.Time) string {
+	path := e.Prefix + table.Name
+	if tenantID := tenant.FromContext(ctx); tenantID != tenant.Default {
+		path += "/tenant=" + tenantID
+	}
+	return path + "/dt=" + day.Format(time.DateOnly)
+}
+
+func (e *Exporter) format() Format {
+	if e.Format == nil {
+		return CSV{}
+	}
+	return e.Format
+}
+
+func (e *Exporter) clock() clock.Clock {
+	if e.Clock == nil {
+		return clock.System()
+	}
+	return e.Clock
+}
+
+// redemptionWindow selects the redemptions of the UTC day starting at start
+func redemptionWindow(start time.Time) models.RedemptionFilter {
+	return models.RedemptionFilter{From: start, To: start.AddDate(0, 0, 1)}
+}
@@ -0,0 +1,149 @@
+// Package warehouse exports discount definitions and redemptions to object storage in a
+// layout analytics warehouses load directly, so reporting does not scrape the API.
+//
+// Every table is written as Hive-style daily partitions next to a machine-readable schema:
+//
+//	<prefix><table>/_schema.json
+//	<prefix><table>/[tenant=<id>/]dt=YYYY-MM-DD/part-0000.<ext>
+//
+// The tables are:
+//
+//   - discounts: one row per discount definition, a snapshot taken at export time
+//   - redemptions: one row per discount redemption recorded during the partition's day (UTC)
+//
+// Timestamps are RFC 3339 in UTC, money is a decimal string and list columns join their
+// entries with "|". Columns are only ever appended, so loaders may match them by name.
+package warehouse
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// Column types used in schemas
+const (
+	TypeString    = "string"
+	TypeDecimal   = "decimal"
+	TypeInteger   = "integer"
+	TypeBoolean   = "boolean"
+	TypeTimestamp = "timestamp"
+)
+
+// listSeparator joins the entries of list columns
+const listSeparator = "|"
+
+// Column documents one column of an exported table
+type Column struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// Table documents an exported table. It is written as _schema.json beside the partitions.
+type Table struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Columns     []Column `json:"columns"`
+}
+
+// DiscountsTable is the schema of the discounts export
+var DiscountsTable = Table{
+	Name:        "discounts",
+	Description: "Discount definitions as of snapshot_at",
+	Columns: []Column{
+		{"id", TypeString, "Discount ID, unique per tenant"},
+		{"tenant_id", TypeString, "Storefront owning the discount, empty for the default tenant"},
+		{"name", TypeString, "Display name, also the key of v1 API results"},
+		{"type", TypeString, "brand, category, bank, voucher, points_redemption or referral"},
+		{"value", TypeDecimal, "Percentage or fixed amount, see is_percentage"},
+		{"is_percentage", TypeBoolean, "Whether value is a percentage"},
+		{"min_amount", TypeDecimal, "Minimum order amount"},
+		{"max_amount", TypeDecimal, "Maximum amount taken off one order, 0 for no cap"},
+		{"applicable_to", TypeString, "Targeted brands, categories, banks or typed item refs, | separated"},
+		{"excluded_items", TypeString, "Excluded brands, categories or typed item refs, | separated"},
+		{"customer_tiers", TypeString, "Eligible customer tiers, | separated, empty for all"},
+		{"code", TypeString, "Code customers enter, empty for automatic discounts"},
+		{"code_aliases", TypeString, "Further codes for the discount, | separated"},
+		{"valid_from", TypeTimestamp, "Start of validity"},
+		{"valid_to", TypeTimestamp, "End of validity"},
+		{"is_active", TypeBoolean, "Whether the discount is switched on"},
+		{"usage_limit", TypeInteger, "Maximum number of uses, 0 for no limit"},
+		{"used_count", TypeInteger, "Uses so far"},
+		{"priority", TypeInteger, "Application order, higher first"},
+		{"campaign_id", TypeString, "Campaign whose budget the discount draws from"},
+		{"max_total_spend", TypeDecimal, "Total amount the discount may give away, 0 for no limit"},
+		{"currency", TypeString, "ISO 4217 currency of the fixed amounts"},
+		{"snapshot_at", TypeTimestamp, "When the definition was exported"},
+		{"ref", TypeString, "Stable discount ref, unchanged by renames"},
+	},
+}
+
+// RedemptionsTable is the schema of the redemptions export
+var RedemptionsTable = Table{
+	Name:        "redemptions",
+	Description: "Discount redemptions, partitioned by the UTC day they were redeemed",
+	Columns: []Column{
+		{"discount_id", TypeString, "Redeemed discount, joins discounts.id"},
+		{"customer_id", TypeString, "Customer who redeemed it"},
+		{"order_id", TypeString, "Order the redemption belongs to, when known"},
+		{"amount", TypeDecimal, "Amount taken off the order"},
+		{"redeemed_at", TypeTimestamp, "When the discount was redeemed"},
+		{"campaign_id", TypeString, "Campaign the discount belonged to when redeemed"},
+		{"code", TypeString, "Code or alias entered to unlock the discount, empty when none was"},
+		{"order_total", TypeDecimal, "Amount paid for the whole order"},
+		{"discount_ref", TypeString, "Stable ref of the redeemed discount, joins discounts.ref"},
+	},
+}
+
+func discountRow(d models.Discount, snapshotAt time.Time) []string {
+	return []string{
+		d.ID,
+		d.TenantID,
+		d.Name,
+		string(d.Type),
+		d.Value.String(),
+		strconv.FormatBool(d.IsPercentage),
+		d.MinAmount.String(),
+		d.MaxAmount.String(),
+		strings.Join(d.ApplicableTo, listSeparator),
+		strings.Join(d.ExcludedItems, listSeparator),
+		strings.Join(d.CustomerTiers, listSeparator),
+		d.Code,
+		strings.Join(d.CodeAliases, listSeparator),
+		timestamp(d.ValidFrom),
+		timestamp(d.ValidTo),
+		strconv.FormatBool(d.IsActive),
+		strconv.Itoa(d.UsageLimit),
+		strconv.Itoa(d.UsedCount),
+		strconv.Itoa(d.Priority),
+		d.CampaignID,
+		d.MaxTotalSpend.String(),
+		d.CurrencyCode(),
+		timestamp(snapshotAt),
+		string(d.StableRef()),
+	}
+}
+
+func redemptionRow(r models.Redemption) []string {
+	return []string{
+		r.DiscountID,
+		r.CustomerID,
+		r.OrderID,
+		r.Amount.String(),
+		timestamp(r.RedeemedAt),
+		r.CampaignID,
+		r.Code,
+		r.OrderTotal.String(),
+		string(r.DiscountRef),
+	}
+}
+
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
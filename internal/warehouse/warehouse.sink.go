@@ -0,0 +1,67 @@
+package warehouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Sink stores exported objects, typically in an object storage bucket. Put replaces any
+// object already stored at path, so re-running an export overwrites its partitions.
+type Sink interface {
+	Put(ctx context.Context, path string, data []byte) error
+}
+
+// DirSink writes objects as files under a root directory, such as a mounted bucket
+type DirSink struct {
+	Root string
+}
+
+// Put writes the object, creating its directories
+func (s DirSink) Put(ctx context.Context, path string, data []byte) error {
+	full := filepath.Join(s.Root, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(full, data, 0o644)
+}
+
+// Format encodes the rows of a table into one object
+type Format interface {
+	// Extension is the file extension of encoded objects, without the dot
+	Extension() string
+	Encode(w io.Writer, table Table, rows [][]string) error
+}
+
+// CSV encodes tables as RFC 4180 CSV with a header row of column names
+type CSV struct{}
+
+// Extension returns "csv"
+func (CSV) Extension() string { return "csv" }
+
+// Encode writes the header and the rows
+func (CSV) Encode(w io.Writer, table Table, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	header := make([]string, len(table.Columns))
+	for i, c := range table.Columns {
+		header[i] = c.Name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func encode(format Format, table Table, rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := format.Encode(&buf, table, rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,18 @@
+// Package actor carries the identity of whoever a request acts for, so changes can be
+// attributed without knowing about the transport or how the caller was authenticated.
+package actor
+
+import "context"
+
+type actorKey struct{}
+
+// NewContext returns a context recording who is acting
+func NewContext(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// FromContext returns the actor set by NewContext, or an empty string when unknown
+func FromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
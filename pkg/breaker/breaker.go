@@ -0,0 +1,157 @@
+// Package breaker stops calls to a failing dependency for a while, so callers fail fast or
+// fall back instead of queueing behind timeouts, and the dependency gets room to recover.
+package breaker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// Defaults used when a Breaker leaves Threshold or Cooldown zero
+const (
+	DefaultThreshold = 5
+	DefaultCooldown  = 30 * time.Second
+)
+
+// State is the position of a breaker
+type State string
+
+const (
+	// Closed lets every call through
+	Closed State = "closed"
+	// Open rejects every call until the cooldown has passed
+	Open State = "open"
+	// HalfOpen lets one trial call through; it closes the breaker on success and opens it
+	// again on failure
+	HalfOpen State = "half-open"
+)
+
+// Breaker opens after Threshold failures in a row and lets a trial call through once it
+// has been open for Cooldown. The zero value is usable. Callers report every call they
+// were allowed with Success, Failure or Ignore; errors saying the call was wrong, such as
+// not found or validation errors, are successes to the breaker.
+type Breaker struct {
+	Name      string        // Names the dependency in the errors of rejected calls
+	Threshold int           // Failures in a row that open the breaker, DefaultThreshold when zero
+	Cooldown  time.Duration // How long it stays open, DefaultCooldown when zero
+	Clock     clock.Clock   // Defaults to the system clock
+
+	// OnStateChange, when set, is called with every change of state, with the breaker locked
+	OnStateChange func(from, to State)
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trial    bool // A half-open trial call is in flight
+}
+
+// Allow reports whether a call may go through, returning an overloaded error saying when
+// to retry when it may not. An allowed call must be reported with Success, Failure or
+// Ignore.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.current() {
+	case Open:
+		if wait := b.openedAt.Add(b.cooldown()).Sub(b.now()); wait > 0 {
+			return b.rejected(wait)
+		}
+		b.set(HalfOpen)
+		b.trial = true
+		return nil
+	case HalfOpen:
+		if b.trial {
+			return b.rejected(b.cooldown())
+		}
+		b.trial = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Success records an allowed call that reached the dependency, closing the breaker
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures, b.trial = 0, false
+	b.set(Closed)
+}
+
+// Failure records an allowed call that the dependency failed, opening the breaker after
+// Threshold of them in a row or when a trial call fails
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.current() == HalfOpen || b.failures >= b.threshold() {
+		b.trial = false
+		b.openedAt = b.now()
+		b.set(Open)
+	}
+}
+
+// Ignore records an allowed call that says nothing about the dependency, such as one its
+// caller abandoned, leaving the breaker as it is
+func (b *Breaker) Ignore() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// State returns the position of the breaker
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current()
+}
+
+func (b *Breaker) current() State {
+	if b.state == "" {
+		return Closed
+	}
+	return b.state
+}
+
+func (b *Breaker) set(state State) {
+	from := b.current()
+	b.state = state
+	if from != state && b.OnStateChange != nil {
+		b.OnStateChange(from, state)
+	}
+}
+
+func (b *Breaker) rejected(retryAfter time.Duration) error {
+	name := b.Name
+	if name == "" {
+		name = "dependency"
+	}
+	return errors.NewOverloadedError(fmt.Sprintf("%s unavailable, circuit breaker open", name), retryAfter)
+}
+
+func (b *Breaker) threshold() int {
+	if b.Threshold <= 0 {
+		return DefaultThreshold
+	}
+	return b.Threshold
+}
+
+func (b *Breaker) cooldown() time.Duration {
+	if b.Cooldown <= 0 {
+		return DefaultCooldown
+	}
+	return b.Cooldown
+}
+
+func (b *Breaker) now() time.Time {
+	if b.Clock == nil {
+		return time.Now()
+	}
+	return b.Clock.Now()
+}
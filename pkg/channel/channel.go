@@ -0,0 +1,18 @@
+// Package channel carries the sales channel a request came through, such as the mobile app
+// or the website, so channel-restricted discounts are enforced on every service call.
+package channel
+
+import "context"
+
+type channelKey struct{}
+
+// NewContext returns a context recording the request's sales channel
+func NewContext(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, channelKey{}, name)
+}
+
+// FromContext returns the channel set by NewContext, or an empty string when unknown
+func FromContext(ctx context.Context) string {
+	name, _ := ctx.Value(channelKey{}).(string)
+	return name
+}
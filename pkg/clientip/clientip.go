@@ -0,0 +1,18 @@
+// Package clientip carries the address of the client a request came from, so services can
+// apply per-address limits without knowing about the transport.
+package clientip
+
+import "context"
+
+type ipKey struct{}
+
+// NewContext returns a context recording the client's address
+func NewContext(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ipKey{}, ip)
+}
+
+// FromContext returns the address set by NewContext, or an empty string when unknown
+func FromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(ipKey{}).(string)
+	return ip
+}
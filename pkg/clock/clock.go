@@ -0,0 +1,78 @@
+// Package clock provides an injectable source of the current time.
+package clock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock returns the current instant
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// System returns a clock backed by time.Now
+func System() Clock {
+	return systemClock{}
+}
+
+// FixedClock is a manually driven clock for tests
+type FixedClock struct {
+	now time.Time
+	mu  sync.RWMutex
+}
+
+// NewFixed creates a clock frozen at the given instant
+func NewFixed(now time.Time) *FixedClock {
+	return &FixedClock{now: now}
+}
+
+// Now returns the frozen instant
+func (c *FixedClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.now
+}
+
+// Set moves the clock to the given instant
+func (c *FixedClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward by d
+func (c *FixedClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type nowKey struct{}
+
+// NewContext returns a context pinned to the given instant, so every time-based check
+// made while serving one request observes the same time
+func NewContext(ctx context.Context, now time.Time) context.Context {
+	return context.WithValue(ctx, nowKey{}, now)
+}
+
+// FromContext returns the instant pinned by NewContext, or the wall clock when none was pinned
+func FromContext(ctx context.Context) time.Time {
+	if now, ok := Pinned(ctx); ok {
+		return now
+	}
+	return time.Now()
+}
+
+// Pinned returns the instant pinned by NewContext, reporting whether one was
+func Pinned(ctx context.Context) (time.Time, bool) {
+	now, ok := ctx.Value(nowKey{}).(time.Time)
+	return now, ok
+}
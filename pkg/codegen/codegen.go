@@ -0,0 +1,171 @@
+// Package codegen mints random coupon codes in bulk. Codes can carry a Luhn mod N check
+// character, letting front ends reject mistyped codes without a lookup.
+package codegen
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// DefaultCharset leaves out characters that are easily confused when read aloud or typed
+// (0/O, 1/I) and has 32 characters, so it supports checksums
+const DefaultCharset = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// DefaultLength is the number of random characters in a code when none is configured
+const DefaultLength = 10
+
+// Generator describes the shape of minted codes. The zero value produces DefaultLength
+// random characters from DefaultCharset without a prefix or check character.
+type Generator struct {
+	Prefix   string    // Prepended verbatim, e.g. "SPRING-"
+	Length   int       // Random characters, excluding prefix and check character
+	Charset  string    // Alphabet of the random part, at least two distinct characters (an even number with Checksum)
+	Checksum bool      // Append a Luhn mod N check character over the random part
+	Rand     io.Reader // Randomness source, crypto/rand when nil
+}
+
+func (g *Generator) length() int {
+	if g.Length > 0 {
+		return g.Length
+	}
+	return DefaultLength
+}
+
+func (g *Generator) charset() string {
+	if g.Charset != "" {
+		return g.Charset
+	}
+	return DefaultCharset
+}
+
+// Validate reports configuration errors that would produce broken or guessable codes
+func (g *Generator) Validate() error {
+	if g.Length < 0 {
+		return fmt.Errorf("code length cannot be negative: %d", g.Length)
+	}
+	charset := g.charset()
+	seen := make(map[rune]bool, len(charset))
+	for _, c := range charset {
+		if c > 127 {
+			return fmt.Errorf("charset must be ASCII: %q", c)
+		}
+		if seen[c] {
+			return fmt.Errorf("charset repeats %q", c)
+		}
+		seen[c] = true
+	}
+	if len(seen) < 2 {
+		return fmt.Errorf("charset needs at least two characters")
+	}
+	// Luhn mod N only catches every single-character typo when N is even
+	if g.Checksum && len(seen)%2 != 0 {
+		return fmt.Errorf("checksums need a charset with an even number of characters")
+	}
+	return nil
+}
+
+// Capacity returns how many distinct codes the generator can produce, capped at max
+func (g *Generator) Capacity(max int64) int64 {
+	capacity := big.NewInt(int64(len(g.charset())))
+	capacity.Exp(capacity, big.NewInt(int64(g.length())), nil)
+	if !capacity.IsInt64() || capacity.Int64() > max {
+		return max
+	}
+	return capacity.Int64()
+}
+
+// Generate returns n distinct codes
+func (g *Generator) Generate(n int) ([]string, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	// Leave at least half the code space unused so drawing distinct codes stays cheap
+	if g.Capacity(2*int64(n)) < 2*int64(n) {
+		return nil, fmt.Errorf("code space is too small for %d codes, use longer codes or a larger charset", n)
+	}
+
+	source := g.Rand
+	if source == nil {
+		source = rand.Reader
+	}
+	charset := g.charset()
+	size := big.NewInt(int64(len(charset)))
+
+	seen := make(map[string]bool, n)
+	codes := make([]string, 0, n)
+	body := make([]byte, g.length())
+	for len(codes) < n {
+		for i := range body {
+			index, err := rand.Int(source, size)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read randomness: %w", err)
+			}
+			body[i] = charset[index.Int64()]
+		}
+
+		code := g.format(string(body))
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func (g *Generator) format(body string) string {
+	var b strings.Builder
+	b.WriteString(g.Prefix)
+	b.WriteString(body)
+	if g.Checksum {
+		b.WriteByte(checkCharacter(body, g.charset()))
+	}
+	return b.String()
+}
+
+// Valid reports whether code has the generator's shape and, when checksums are enabled, a
+// correct check character. It does not tell whether the code was ever minted.
+func (g *Generator) Valid(code string) bool {
+	body, ok := strings.CutPrefix(code, g.Prefix)
+	if !ok {
+		return false
+	}
+	want := g.length()
+	if g.Checksum {
+		want++
+	}
+	if len(body) != want {
+		return false
+	}
+
+	charset := g.charset()
+	for i := 0; i < len(body); i++ {
+		if strings.IndexByte(charset, body[i]) < 0 {
+			return false
+		}
+	}
+	if !g.Checksum {
+		return true
+	}
+	return checkCharacter(body[:len(body)-1], charset) == body[len(body)-1]
+}
+
+// checkCharacter computes the Luhn mod N check character of body over charset
+func checkCharacter(body, charset string) byte {
+	n := len(charset)
+	factor := 2
+	sum := 0
+	for i := len(body) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(charset, body[i])
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+		sum += addend/n + addend%n
+	}
+	return charset[(n-sum%n)%n]
+}
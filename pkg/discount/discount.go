@@ -0,0 +1,98 @@
+// Package discount is the public API of the discount engine: the models carts are priced
+// with and the interfaces of the engine, its store and its strategies, for services that
+// call the engine or plug into it. The implementations stay internal to this module.
+//
+// The types are aliases of the engine's own, so values pass between this package and the
+// engine without conversion. Fields and constants are only ever added to them; renaming or
+// removing one is a breaking change to this package.
+package discount
+
+import "github.com/ahsmha/discounts/internal/models"
+
+// Carts and what they are priced for
+type (
+	Product            = models.Product
+	Brand              = models.Brand
+	BrandTier          = models.BrandTier
+	Category           = models.Category
+	CartItem           = models.CartItem
+	CustomerProfile    = models.CustomerProfile
+	CustomerGroup      = models.CustomerGroup
+	PaymentInfo        = models.PaymentInfo
+	PaymentMethod      = models.PaymentMethod
+	CardType           = models.CardType
+	CalculationRequest = models.CalculationRequest
+	ValidationMode     = models.ValidationMode
+)
+
+// Discounts
+type (
+	Discount       = models.Discount
+	DiscountType   = models.DiscountType
+	DiscountFilter = models.DiscountFilter
+	DiscountBasis  = models.DiscountBasis
+	Revision       = models.DiscountRevision
+	RoundingMode   = models.RoundingMode
+)
+
+// Results
+type (
+	DiscountedPrice         = models.DiscountedPrice
+	CodeValidation          = models.CodeValidation
+	AvailableCoupon         = models.AvailableCoupon
+	OrderPricingRecord      = models.OrderPricingRecord
+	PricingVerification     = models.PricingVerification
+	AppliedDiscount         = models.AppliedDiscount
+	TaxLine                 = models.TaxLine
+	BatchCalculationRequest = models.BatchCalculationRequest
+	BatchCalculationResult  = models.BatchCalculationResult
+	BatchItemResult         = models.BatchItemResult
+	Explanation             = models.Explanation
+	DiscountDecision        = models.DiscountDecision
+	DecisionOutcome         = models.DecisionOutcome
+	DecisionReason          = models.DecisionReason
+	Offer                   = models.Offer
+	OfferUrgency            = models.OfferUrgency
+)
+
+const (
+	TypeBrand            = models.DiscountTypeBrand
+	TypeCategory         = models.DiscountTypeCategory
+	TypeBank             = models.DiscountTypeBank
+	TypeVoucher          = models.DiscountTypeVoucher
+	TypePointsRedemption = models.DiscountTypePointsRedemption
+	TypeReferral         = models.DiscountTypeReferral
+	TypeWallet           = models.DiscountTypeWallet
+	TypeSpendTier        = models.DiscountTypeSpendTier
+)
+
+const (
+	UPI        = models.UPI
+	Card       = models.Card
+	EMI        = models.EMI
+	Wallet     = models.Wallet
+	NetBanking = models.NetBanking
+
+	Credit = models.Credit
+	Debit  = models.Debit
+)
+
+const (
+	ValidationStrict  = models.ValidationStrict
+	ValidationLenient = models.ValidationLenient
+
+	RoundHalfUp   = models.RoundHalfUp
+	RoundHalfEven = models.RoundHalfEven
+	RoundFloor    = models.RoundFloor
+	RoundCeil     = models.RoundCeil
+
+	BasisRunningTotal     = models.BasisRunningTotal
+	BasisOriginalPrice    = models.BasisOriginalPrice
+	BasisEligibleSubtotal = models.BasisEligibleSubtotal
+)
+
+const (
+	DecisionApplied  = models.DecisionApplied
+	DecisionSkipped  = models.DecisionSkipped
+	DecisionRejected = models.DecisionRejected
+)
@@ -0,0 +1,27 @@
+package discount
+
+import (
+	internal "github.com/ahsmha/discounts/internal/discount"
+	"github.com/ahsmha/discounts/internal/interfaces"
+)
+
+// Service prices carts; the engine's discount service implements it
+type Service = interfaces.IDiscountService
+
+// Repository stores discounts for the service
+type Repository = interfaces.IDiscountRepository
+
+// History is implemented by repositories keeping every revision of their discounts
+type History = interfaces.DiscountHistory
+
+// Strategy decides whether and by how much a discount of one type reduces a cart.
+// Time-based validity is checked by the caller before a strategy is consulted.
+type Strategy = internal.DiscountStrategy
+
+// Optional strategy interfaces the service prefers over Strategy.Calculate, or consults
+// to report why a discount did not apply
+type (
+	CustomerCalculator = internal.CustomerCalculator
+	SubtotalCalculator = internal.SubtotalCalculator
+	Explainer          = internal.Explainer
+)
@@ -0,0 +1,203 @@
+package discounttest
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ahsmha/discounts/pkg/discount"
+)
+
+// Discounts built here are valid over a window covering any clock a test is likely to use
+var (
+	ValidFrom = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	ValidTo   = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// Product is a product of the brand and category selling at price, e.g. "1299.50"
+func Product(id, brand, category, price string) discount.Product {
+	p := decimal.RequireFromString(price)
+	return discount.Product{
+		ID:           id,
+		Brand:        discount.Brand{ID: brand, Name: brand},
+		Category:     discount.Category{ID: category, Name: category},
+		BasePrice:    p,
+		CurrentPrice: p,
+	}
+}
+
+// CartBuilder builds a calculation request line by line
+type CartBuilder struct {
+	req discount.CalculationRequest
+}
+
+// Cart starts an empty cart of an anonymous customer without payment
+func Cart() *CartBuilder {
+	return &CartBuilder{}
+}
+
+// Add adds quantity units of the product
+func (b *CartBuilder) Add(product discount.Product, quantity int) *CartBuilder {
+	b.req.CartItems = append(b.req.CartItems, discount.CartItem{Product: product, Quantity: quantity})
+	return b
+}
+
+// AddExcluded adds quantity units of the product, opted out of every discount
+func (b *CartBuilder) AddExcluded(product discount.Product, quantity int) *CartBuilder {
+	b.req.CartItems = append(b.req.CartItems, discount.CartItem{Product: product, Quantity: quantity, ExcludeFromPromotions: true})
+	return b
+}
+
+// For sets the customer buying the cart
+func (b *CartBuilder) For(customer discount.CustomerProfile) *CartBuilder {
+	b.req.Customer = customer
+	return b
+}
+
+// PaidByCard pays with a card of the bank
+func (b *CartBuilder) PaidByCard(bank string, cardType discount.CardType) *CartBuilder {
+	b.req.PaymentInfo = &discount.PaymentInfo{Method: discount.Card, BankName: &bank, CardType: &cardType}
+	return b
+}
+
+// Paid sets the payment as given
+func (b *CartBuilder) Paid(payment *discount.PaymentInfo) *CartBuilder {
+	b.req.PaymentInfo = payment
+	return b
+}
+
+// WithCodes enters the discount codes at checkout
+func (b *CartBuilder) WithCodes(codes ...string) *CartBuilder {
+	b.req.Codes = append(b.req.Codes, codes...)
+	return b
+}
+
+// Items returns the cart's lines
+func (b *CartBuilder) Items() []discount.CartItem {
+	return append([]discount.CartItem(nil), b.req.CartItems...)
+}
+
+// Request returns the cart as a calculation request
+func (b *CartBuilder) Request() *discount.CalculationRequest {
+	req := b.req
+	req.CartItems = b.Items()
+	req.Codes = append([]string(nil), b.req.Codes...)
+	return &req
+}
+
+// DiscountBuilder builds a discount field by field
+type DiscountBuilder struct {
+	d discount.Discount
+}
+
+// Discount starts an active discount of the type, valid from ValidFrom to ValidTo, taking
+// nothing off until its value is set
+func Discount(id string, discountType discount.DiscountType) *DiscountBuilder {
+	return &DiscountBuilder{d: discount.Discount{
+		ID:        id,
+		Name:      "Discount " + id,
+		Type:      discountType,
+		ValidFrom: ValidFrom,
+		ValidTo:   ValidTo,
+		IsActive:  true,
+	}}
+}
+
+// Named sets the discount's display name
+func (b *DiscountBuilder) Named(name string) *DiscountBuilder {
+	b.d.Name = name
+	return b
+}
+
+// Percent takes the percentage, e.g. "10", off
+func (b *DiscountBuilder) Percent(value string) *DiscountBuilder {
+	b.d.Value, b.d.IsPercentage = decimal.RequireFromString(value), true
+	return b
+}
+
+// Fixed takes the fixed amount off
+func (b *DiscountBuilder) Fixed(value string) *DiscountBuilder {
+	b.d.Value, b.d.IsPercentage = decimal.RequireFromString(value), false
+	return b
+}
+
+// On limits the discount to the brands, categories, banks or wallets, by the discount's type
+func (b *DiscountBuilder) On(targets ...string) *DiscountBuilder {
+	b.d.ApplicableTo = append(b.d.ApplicableTo, targets...)
+	return b
+}
+
+// Excluding excludes the brands or categories
+func (b *DiscountBuilder) Excluding(items ...string) *DiscountBuilder {
+	b.d.ExcludedItems = append(b.d.ExcludedItems, items...)
+	return b
+}
+
+// Code makes the discount redeemable with the code
+func (b *DiscountBuilder) Code(code string) *DiscountBuilder {
+	b.d.Code = code
+	return b
+}
+
+// ForTiers limits the discount to customers of the tiers
+func (b *DiscountBuilder) ForTiers(tiers ...string) *DiscountBuilder {
+	b.d.CustomerTiers = append(b.d.CustomerTiers, tiers...)
+	return b
+}
+
+// MinAmount sets the cart amount the discount requires
+func (b *DiscountBuilder) MinAmount(amount string) *DiscountBuilder {
+	b.d.MinAmount = decimal.RequireFromString(amount)
+	return b
+}
+
+// MaxAmount caps what the discount takes off
+func (b *DiscountBuilder) MaxAmount(amount string) *DiscountBuilder {
+	b.d.MaxAmount = decimal.RequireFromString(amount)
+	return b
+}
+
+// Priority sets the discount's priority, higher applying first
+func (b *DiscountBuilder) Priority(priority int) *DiscountBuilder {
+	b.d.Priority = priority
+	return b
+}
+
+// UsageLimit caps how often the discount can be redeemed
+func (b *DiscountBuilder) UsageLimit(limit int) *DiscountBuilder {
+	b.d.UsageLimit = limit
+	return b
+}
+
+// Basis sets the amount the discount is calculated on
+func (b *DiscountBuilder) Basis(basis discount.DiscountBasis) *DiscountBuilder {
+	b.d.Basis = basis
+	return b
+}
+
+// Valid sets the discount's validity window
+func (b *DiscountBuilder) Valid(from, to time.Time) *DiscountBuilder {
+	b.d.ValidFrom, b.d.ValidTo = from, to
+	return b
+}
+
+// Inactive switches the discount off
+func (b *DiscountBuilder) Inactive() *DiscountBuilder {
+	b.d.IsActive = false
+	return b
+}
+
+// Tenant assigns the discount to the storefront
+func (b *DiscountBuilder) Tenant(tenantID string) *DiscountBuilder {
+	b.d.TenantID = tenantID
+	return b
+}
+
+// Build returns the discount
+func (b *DiscountBuilder) Build() discount.Discount {
+	d := b.d
+	d.ApplicableTo = append([]string(nil), b.d.ApplicableTo...)
+	d.ExcludedItems = append([]string(nil), b.d.ExcludedItems...)
+	d.CustomerTiers = append([]string(nil), b.d.CustomerTiers...)
+	return d
+}
@@ -0,0 +1,143 @@
+package discounttest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/pkg/discount"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// Repository is an in-memory discount.Repository whose methods can be made to fail, for
+// testing how callers handle an unavailable store
+type Repository struct {
+	discount.Repository
+
+	mu   sync.Mutex
+	errs map[string]error
+}
+
+var (
+	_ discount.Repository = (*Repository)(nil)
+	_ discount.History    = (*Repository)(nil)
+)
+
+// NewRepository returns a repository holding the discounts, each created in the tenant it
+// names; it fails the test when one of them is invalid
+func NewRepository(t testing.TB, discounts ...discount.Discount) *Repository {
+	t.Helper()
+	repo := &Repository{Repository: repository.NewInMemoryDiscountRepository(), errs: map[string]error{}}
+	for i := range discounts {
+		ctx := tenant.NewContext(context.Background(), discounts[i].TenantID)
+		if err := repo.Repository.CreateDiscount(ctx, &discounts[i]); err != nil {
+			t.Fatalf("discounttest: create discount %s: %v", discounts[i].ID, err)
+		}
+	}
+	return repo
+}
+
+// FailOn makes every later call of the named method, e.g. "GetApplicableDiscounts", return
+// err; a nil err lets the method succeed again
+func (r *Repository) FailOn(method string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		delete(r.errs, method)
+		return
+	}
+	r.errs[method] = err
+}
+
+func (r *Repository) failure(method string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.errs[method]
+}
+
+func (r *Repository) GetActiveDiscounts(ctx context.Context) ([]discount.Discount, error) {
+	if err := r.failure("GetActiveDiscounts"); err != nil {
+		return nil, err
+	}
+	return r.Repository.GetActiveDiscounts(ctx)
+}
+
+func (r *Repository) GetApplicableDiscounts(ctx context.Context, filter discount.DiscountFilter) ([]discount.Discount, error) {
+	if err := r.failure("GetApplicableDiscounts"); err != nil {
+		return nil, err
+	}
+	return r.Repository.GetApplicableDiscounts(ctx, filter)
+}
+
+func (r *Repository) GetDiscountByCode(ctx context.Context, code string) (*discount.Discount, error) {
+	if err := r.failure("GetDiscountByCode"); err != nil {
+		return nil, err
+	}
+	return r.Repository.GetDiscountByCode(ctx, code)
+}
+
+func (r *Repository) GetDiscountByID(ctx context.Context, id string) (*discount.Discount, error) {
+	if err := r.failure("GetDiscountByID"); err != nil {
+		return nil, err
+	}
+	return r.Repository.GetDiscountByID(ctx, id)
+}
+
+func (r *Repository) CreateDiscount(ctx context.Context, d *discount.Discount) error {
+	if err := r.failure("CreateDiscount"); err != nil {
+		return err
+	}
+	return r.Repository.CreateDiscount(ctx, d)
+}
+
+func (r *Repository) UpdateDiscount(ctx context.Context, d *discount.Discount) error {
+	if err := r.failure("UpdateDiscount"); err != nil {
+		return err
+	}
+	return r.Repository.UpdateDiscount(ctx, d)
+}
+
+func (r *Repository) DeleteDiscount(ctx context.Context, id string) error {
+	if err := r.failure("DeleteDiscount"); err != nil {
+		return err
+	}
+	return r.Repository.DeleteDiscount(ctx, id)
+}
+
+func (r *Repository) IncrementUsageCount(ctx context.Context, id string) error {
+	if err := r.failure("IncrementUsageCount"); err != nil {
+		return err
+	}
+	return r.Repository.IncrementUsageCount(ctx, id)
+}
+
+func (r *Repository) CheckAndIncrementUsage(ctx context.Context, id string) error {
+	if err := r.failure("CheckAndIncrementUsage"); err != nil {
+		return err
+	}
+	return r.Repository.CheckAndIncrementUsage(ctx, id)
+}
+
+func (r *Repository) ReleaseUsage(ctx context.Context, id string) error {
+	if err := r.failure("ReleaseUsage"); err != nil {
+		return err
+	}
+	return r.Repository.(interfaces.UsageReleaser).ReleaseUsage(ctx, id)
+}
+
+func (r *Repository) ListRevisions(ctx context.Context, id string) ([]discount.Revision, error) {
+	if err := r.failure("ListRevisions"); err != nil {
+		return nil, err
+	}
+	return r.Repository.(discount.History).ListRevisions(ctx, id)
+}
+
+func (r *Repository) GetDiscountAt(ctx context.Context, id string, at time.Time) (*discount.Discount, error) {
+	if err := r.failure("GetDiscountAt"); err != nil {
+		return nil, err
+	}
+	return r.Repository.(discount.History).GetDiscountAt(ctx, id, at)
+}
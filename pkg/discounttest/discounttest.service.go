@@ -0,0 +1,150 @@
+// Package discounttest holds fakes of the discount service, its repository and its
+// strategies, and builders for the carts and discounts they work with, so code consuming
+// the engine can be unit-tested without running it:
+//
+//	svc := &discounttest.Service{
+//		CalculateCartFunc: func(ctx context.Context, req *discount.CalculationRequest) (*discount.DiscountedPrice, error) {
+//			return discounttest.Price("1000", "900"), nil
+//		},
+//	}
+//
+// Fakes fail with ErrNotStubbed when a method without a stub is called.
+package discounttest
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ahsmha/discounts/pkg/discount"
+)
+
+// ErrNotStubbed is returned by fake methods the test did not stub
+var ErrNotStubbed = errors.New("discounttest: method not stubbed")
+
+// Service is an discount.Service calling the stub of each method. Calls lists
+// the methods called, in order; it is safe for concurrent use.
+type Service struct {
+	CalculateCartDiscountsFunc      func(ctx context.Context, cartItems []discount.CartItem, customer discount.CustomerProfile, paymentInfo *discount.PaymentInfo) (*discount.DiscountedPrice, error)
+	CalculateCartFunc               func(ctx context.Context, req *discount.CalculationRequest) (*discount.DiscountedPrice, error)
+	CalculateCartDiscountsBatchFunc func(ctx context.Context, req *discount.BatchCalculationRequest) (*discount.BatchCalculationResult, error)
+	ExplainCartDiscountsFunc        func(ctx context.Context, req *discount.CalculationRequest) (*discount.Explanation, error)
+	ValidateDiscountCodeFunc        func(ctx context.Context, code string, cartItems []discount.CartItem, customer discount.CustomerProfile) (bool, error)
+	DescribeDiscountCodeFunc        func(ctx context.Context, code string, cartItems []discount.CartItem, customer discount.CustomerProfile) (*discount.CodeValidation, error)
+	GetAvailableCouponsFunc         func(ctx context.Context, cartItems []discount.CartItem, customer discount.CustomerProfile) ([]discount.AvailableCoupon, error)
+	VerifyOrderPricingFunc          func(ctx context.Context, orderID string) (*discount.PricingVerification, error)
+	ListOffersFunc                  func(ctx context.Context, customer discount.CustomerProfile) ([]discount.Offer, error)
+
+	mu    sync.Mutex
+	calls []string
+}
+
+var _ discount.Service = (*Service)(nil)
+
+// Calls returns the names of the methods called so far
+func (s *Service) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+func (s *Service) record(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, method)
+}
+
+func (s *Service) CalculateCartDiscounts(ctx context.Context, cartItems []discount.CartItem,
+	customer discount.CustomerProfile, paymentInfo *discount.PaymentInfo) (*discount.DiscountedPrice, error) {
+	s.record("CalculateCartDiscounts")
+	if s.CalculateCartDiscountsFunc == nil {
+		return nil, ErrNotStubbed
+	}
+	return s.CalculateCartDiscountsFunc(ctx, cartItems, customer, paymentInfo)
+}
+
+func (s *Service) CalculateCart(ctx context.Context, req *discount.CalculationRequest) (*discount.DiscountedPrice, error) {
+	s.record("CalculateCart")
+	if s.CalculateCartFunc == nil {
+		return nil, ErrNotStubbed
+	}
+	return s.CalculateCartFunc(ctx, req)
+}
+
+func (s *Service) CalculateCartDiscountsBatch(ctx context.Context, req *discount.BatchCalculationRequest) (*discount.BatchCalculationResult, error) {
+	s.record("CalculateCartDiscountsBatch")
+	if s.CalculateCartDiscountsBatchFunc == nil {
+		return nil, ErrNotStubbed
+	}
+	return s.CalculateCartDiscountsBatchFunc(ctx, req)
+}
+
+func (s *Service) ExplainCartDiscounts(ctx context.Context, req *discount.CalculationRequest) (*discount.Explanation, error) {
+	s.record("ExplainCartDiscounts")
+	if s.ExplainCartDiscountsFunc == nil {
+		return nil, ErrNotStubbed
+	}
+	return s.ExplainCartDiscountsFunc(ctx, req)
+}
+
+func (s *Service) ValidateDiscountCode(ctx context.Context, code string, cartItems []discount.CartItem,
+	customer discount.CustomerProfile) (bool, error) {
+	s.record("ValidateDiscountCode")
+	if s.ValidateDiscountCodeFunc == nil {
+		return false, ErrNotStubbed
+	}
+	return s.ValidateDiscountCodeFunc(ctx, code, cartItems, customer)
+}
+
+func (s *Service) DescribeDiscountCode(ctx context.Context, code string, cartItems []discount.CartItem,
+	customer discount.CustomerProfile) (*discount.CodeValidation, error) {
+	s.record("DescribeDiscountCode")
+	if s.DescribeDiscountCodeFunc == nil {
+		return nil, ErrNotStubbed
+	}
+	return s.DescribeDiscountCodeFunc(ctx, code, cartItems, customer)
+}
+
+func (s *Service) GetAvailableCoupons(ctx context.Context, cartItems []discount.CartItem,
+	customer discount.CustomerProfile) ([]discount.AvailableCoupon, error) {
+	s.record("GetAvailableCoupons")
+	if s.GetAvailableCouponsFunc == nil {
+		return nil, ErrNotStubbed
+	}
+	return s.GetAvailableCouponsFunc(ctx, cartItems, customer)
+}
+
+func (s *Service) VerifyOrderPricing(ctx context.Context, orderID string) (*discount.PricingVerification, error) {
+	s.record("VerifyOrderPricing")
+	if s.VerifyOrderPricingFunc == nil {
+		return nil, ErrNotStubbed
+	}
+	return s.VerifyOrderPricingFunc(ctx, orderID)
+}
+
+func (s *Service) ListOffers(ctx context.Context, customer discount.CustomerProfile) ([]discount.Offer, error) {
+	s.record("ListOffers")
+	if s.ListOffersFunc == nil {
+		return nil, ErrNotStubbed
+	}
+	return s.ListOffersFunc(ctx, customer)
+}
+
+// Price is a result reducing original to final, with the whole reduction attributed to a
+// single discount named "discounttest"
+func Price(original, final string) *discount.DiscountedPrice {
+	o, f := decimal.RequireFromString(original), decimal.RequireFromString(final)
+	result := &discount.DiscountedPrice{
+		OriginalPrice:    o,
+		FinalPrice:       f,
+		AppliedDiscounts: map[string]decimal.Decimal{},
+		AmountDue:        f,
+		TotalTax:         decimal.Zero,
+	}
+	if saved := o.Sub(f); saved.IsPositive() {
+		result.AppliedDiscounts["discounttest"] = saved
+	}
+	return result
+}
@@ -0,0 +1,37 @@
+package discounttest
+
+import (
+	"github.com/shopspring/decimal"
+
+	"github.com/ahsmha/discounts/pkg/discount"
+)
+
+// Strategy is a discount.Strategy calling its stubs. Without stubs it applies to
+// every cart and takes Amount off, capped at the current total.
+type Strategy struct {
+	Amount decimal.Decimal
+
+	IsApplicableFunc func(d *discount.Discount, cart []discount.CartItem, customer discount.CustomerProfile, payment *discount.PaymentInfo) bool
+	CalculateFunc    func(d *discount.Discount, cart []discount.CartItem, currentTotal decimal.Decimal) decimal.Decimal
+}
+
+var _ discount.Strategy = (*Strategy)(nil)
+
+// FixedStrategy is a strategy taking amount off every cart
+func FixedStrategy(amount string) *Strategy {
+	return &Strategy{Amount: decimal.RequireFromString(amount)}
+}
+
+func (s *Strategy) IsApplicable(d *discount.Discount, cart []discount.CartItem, customer discount.CustomerProfile, payment *discount.PaymentInfo) bool {
+	if s.IsApplicableFunc == nil {
+		return true
+	}
+	return s.IsApplicableFunc(d, cart, customer, payment)
+}
+
+func (s *Strategy) Calculate(d *discount.Discount, cart []discount.CartItem, currentTotal decimal.Decimal) decimal.Decimal {
+	if s.CalculateFunc == nil {
+		return decimal.Min(s.Amount, currentTotal)
+	}
+	return s.CalculateFunc(d, cart, currentTotal)
+}
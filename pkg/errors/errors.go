@@ -3,6 +3,9 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // Error types for better error handling
@@ -10,6 +13,7 @@ var (
 	ErrValidation = errors.New("validation error")
 	ErrNotFound   = errors.New("not found")
 	ErrInternal   = errors.New("internal error")
+	ErrConflict   = errors.New("conflict")
 )
 
 // ValidationError represents a validation error
@@ -52,6 +56,46 @@ func IsNotFoundError(err error) bool {
 	return errors.As(err, &notFoundErr)
 }
 
+// ConflictError represents a request that conflicts with the current state
+type ConflictError struct {
+	Message string
+}
+
+func (e ConflictError) Error() string {
+	return e.Message
+}
+
+// NewConflictError creates a new conflict error
+func NewConflictError(message string) error {
+	return ConflictError{Message: message}
+}
+
+// IsConflictError checks if an error is a conflict error
+func IsConflictError(err error) bool {
+	var conflictErr ConflictError
+	return errors.As(err, &conflictErr)
+}
+
+// ForbiddenError is returned when the caller is not allowed to make a change
+type ForbiddenError struct {
+	Message string
+}
+
+func (e ForbiddenError) Error() string {
+	return e.Message
+}
+
+// NewForbiddenError creates a new forbidden error
+func NewForbiddenError(message string) error {
+	return ForbiddenError{Message: message}
+}
+
+// IsForbiddenError checks if an error is a forbidden error
+func IsForbiddenError(err error) bool {
+	var forbiddenErr ForbiddenError
+	return errors.As(err, &forbiddenErr)
+}
+
 // InternalError represents an internal server error
 type InternalError struct {
 	Message string
@@ -75,3 +119,119 @@ func IsInternalError(err error) bool {
 	var internalErr InternalError
 	return errors.As(err, &internalErr)
 }
+
+// TotalMismatchError is returned when a client-supplied cart total does not match the
+// total computed from the cart, usually because the client priced it with stale data
+type TotalMismatchError struct {
+	Expected  decimal.Decimal
+	Actual    decimal.Decimal
+	Tolerance decimal.Decimal
+}
+
+func (e TotalMismatchError) Error() string {
+	return fmt.Sprintf("cart total mismatch: expected %s, computed %s (tolerance %s)",
+		e.Expected.String(), e.Actual.String(), e.Tolerance.String())
+}
+
+// NewTotalMismatchError creates a new total mismatch error
+func NewTotalMismatchError(expected, actual, tolerance decimal.Decimal) error {
+	return TotalMismatchError{Expected: expected, Actual: actual, Tolerance: tolerance}
+}
+
+// IsTotalMismatchError checks if an error is a total mismatch error
+func IsTotalMismatchError(err error) bool {
+	var mismatchErr TotalMismatchError
+	return errors.As(err, &mismatchErr)
+}
+
+// TooManyAttemptsError is returned when a caller exceeds a velocity limit, such as
+// repeatedly guessing discount codes
+type TooManyAttemptsError struct {
+	Message    string
+	RetryAfter time.Duration // How long until the limit resets
+}
+
+func (e TooManyAttemptsError) Error() string {
+	return e.Message
+}
+
+// NewTooManyAttemptsError creates a new too many attempts error
+func NewTooManyAttemptsError(message string, retryAfter time.Duration) error {
+	return TooManyAttemptsError{Message: message, RetryAfter: retryAfter}
+}
+
+// IsTooManyAttemptsError checks if an error is a too many attempts error
+func IsTooManyAttemptsError(err error) bool {
+	var attemptsErr TooManyAttemptsError
+	return errors.As(err, &attemptsErr)
+}
+
+// OverloadedError rejects work because the capacity reserved for it is used up; retrying
+// later may succeed
+type OverloadedError struct {
+	Message    string
+	RetryAfter time.Duration // How long the caller should back off
+}
+
+func (e OverloadedError) Error() string {
+	return e.Message
+}
+
+// NewOverloadedError creates a new overloaded error
+func NewOverloadedError(message string, retryAfter time.Duration) error {
+	return OverloadedError{Message: message, RetryAfter: retryAfter}
+}
+
+// IsOverloadedError checks if an error is an overloaded error
+func IsOverloadedError(err error) bool {
+	var overloadedErr OverloadedError
+	return errors.As(err, &overloadedErr)
+}
+
+// RetryAfter returns how long a caller rejected with a too many attempts or overloaded
+// error should wait
+func RetryAfter(err error) (time.Duration, bool) {
+	var attemptsErr TooManyAttemptsError
+	if errors.As(err, &attemptsErr) {
+		return attemptsErr.RetryAfter, true
+	}
+	var overloadedErr OverloadedError
+	if errors.As(err, &overloadedErr) {
+		return overloadedErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// DeadlineExceededError is returned when a calculation's context is done before the
+// calculation finished, saying how far it got. Nothing was committed.
+type DeadlineExceededError struct {
+	Stage      string   // What the calculation was doing, e.g. "applying discounts"
+	Considered int      // Discounts evaluated before it stopped
+	Total      int      // Discounts loaded for it, zero when it stopped before loading them
+	Applied    []string // IDs of the discounts applied before it stopped
+	Cause      error    // The context's error
+}
+
+func (e DeadlineExceededError) Error() string {
+	message := fmt.Sprintf("calculation stopped while %s: %v", e.Stage, e.Cause)
+	if e.Total > 0 {
+		message += fmt.Sprintf(" (%d of %d discounts considered, %d applied)", e.Considered, e.Total, len(e.Applied))
+	}
+	return message
+}
+
+// Unwrap returns the context's error, so errors.Is(err, context.DeadlineExceeded) holds
+func (e DeadlineExceededError) Unwrap() error {
+	return e.Cause
+}
+
+// NewDeadlineExceededError creates a new deadline exceeded error
+func NewDeadlineExceededError(stage string, considered, total int, applied []string, cause error) error {
+	return DeadlineExceededError{Stage: stage, Considered: considered, Total: total, Applied: applied, Cause: cause}
+}
+
+// IsDeadlineExceededError checks if an error is a deadline exceeded error
+func IsDeadlineExceededError(err error) bool {
+	var deadlineErr DeadlineExceededError
+	return errors.As(err, &deadlineErr)
+}
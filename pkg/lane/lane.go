@@ -0,0 +1,41 @@
+// Package lane carries the traffic lane of a request, so interactive checkouts sharing a
+// process with batch and backfill work can be scheduled ahead of it.
+package lane
+
+import "context"
+
+// Lane is a class of traffic with its own capacity
+type Lane string
+
+const (
+	// Checkout is interactive traffic a shopper is waiting on, the lane of requests that
+	// name none
+	Checkout Lane = "checkout"
+	// Batch is bulk work such as batch pricing and backfills, which can wait
+	Batch Lane = "batch"
+)
+
+// Parse accepts the name of a known lane
+func Parse(s string) (Lane, bool) {
+	switch l := Lane(s); l {
+	case Checkout, Batch:
+		return l, true
+	default:
+		return "", false
+	}
+}
+
+type laneKey struct{}
+
+// NewContext returns a context whose work runs in the given lane
+func NewContext(ctx context.Context, l Lane) context.Context {
+	return context.WithValue(ctx, laneKey{}, l)
+}
+
+// FromContext returns the lane set by NewContext, or Checkout when none was set
+func FromContext(ctx context.Context) Lane {
+	if l, ok := ctx.Value(laneKey{}).(Lane); ok {
+		return l
+	}
+	return Checkout
+}
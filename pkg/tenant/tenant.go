@@ -0,0 +1,53 @@
+// Package tenant carries the storefront a request belongs to. Repositories scope every read
+// and write to the tenant in the request context, so storefronts sharing one deployment
+// never see each other's data.
+package tenant
+
+import (
+	"context"
+	"strings"
+)
+
+// Default is the tenant of requests that name none, which keeps single-tenant deployments
+// working unchanged
+const Default = ""
+
+// scopeSeparator ends the tenant prefix of scoped storage keys
+const scopeSeparator = "\x00"
+
+type tenantKey struct{}
+
+// NewContext returns a context scoped to the given tenant
+func NewContext(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// FromContext returns the tenant set by NewContext, or Default when none was set
+func FromContext(ctx context.Context) string {
+	if tenantID, ok := ctx.Value(tenantKey{}).(string); ok {
+		return tenantID
+	}
+	return Default
+}
+
+// Key namespaces a storage key under the context's tenant. Default-tenant keys are left
+// as they are, so data stored before tenants existed stays reachable.
+func Key(ctx context.Context, key string) string {
+	return Scoped(FromContext(ctx), key)
+}
+
+// Scoped namespaces a storage key under the given tenant
+func Scoped(tenantID, key string) string {
+	if tenantID == Default {
+		return key
+	}
+	return tenantID + scopeSeparator + key
+}
+
+// Owns reports whether a storage key built by Scoped belongs to the given tenant
+func Owns(tenantID, key string) bool {
+	if tenantID == Default {
+		return !strings.Contains(key, scopeSeparator)
+	}
+	return strings.HasPrefix(key, tenantID+scopeSeparator)
+}
@@ -0,0 +1,72 @@
+// Package yamljson reads YAML documents as the JSON they stand for, so types decoded from
+// JSON API payloads can be loaded from YAML files with the same field names. Numbers keep
+// their exact text, so decimal amounts lose no precision.
+package yamljson
+
+import (
+	"encoding/json"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ToJSON converts a single YAML document to JSON
+func ToJSON(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	value, err := Value(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// Value turns a YAML node into values encoding/json marshals faithfully
+func Value(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return Value(node.Content[0])
+
+	case yaml.AliasNode:
+		return Value(node.Alias)
+
+	case yaml.MappingNode:
+		m := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value, err := Value(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			m[node.Content[i].Value] = value
+		}
+		return m, nil
+
+	case yaml.SequenceNode:
+		s := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := Value(item)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, value)
+		}
+		return s, nil
+
+	default:
+		switch node.ShortTag() {
+		case "!!null":
+			return nil, nil
+		case "!!bool":
+			return strings.EqualFold(node.Value, "true"), nil
+		case "!!int", "!!float":
+			return json.Number(node.Value), nil
+		default:
+			return node.Value, nil
+		}
+	}
+}
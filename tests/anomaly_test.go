@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/anomaly"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestAnomalyDetector(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, time.November, 29, 10, 0, 0, 0, time.UTC)
+
+	var alerts []anomaly.Alert
+	detector := anomaly.NewDetector(anomaly.NotifierFunc(func(ctx context.Context, alert anomaly.Alert) error {
+		alerts = append(alerts, alert)
+		return nil
+	}), anomaly.Config{Window: 30, MinHistory: 10})
+
+	redeem := func(id string, at time.Time, amount int64) []anomaly.Alert {
+		return detector.Observe(ctx, models.Redemption{DiscountID: id, Amount: decimal.NewFromInt(amount), RedeemedAt: at})
+	}
+
+	// A steady two redemptions of 100 a minute
+	for m := 0; m < 30; m++ {
+		minute := start.Add(time.Duration(m) * time.Minute)
+		redeem("steady", minute, 100)
+		redeem("steady", minute.Add(30*time.Second), 100)
+	}
+	require.Empty(t, alerts, "steady traffic is not anomalous")
+
+	t.Run("A spike alerts once per metric", func(t *testing.T) {
+		spike := start.Add(30 * time.Minute)
+		for i := 0; i < 20; i++ {
+			redeem("steady", spike.Add(time.Duration(i)*time.Second), 100)
+		}
+		require.Len(t, alerts, 2)
+
+		byMetric := map[anomaly.Metric]anomaly.Alert{alerts[0].Metric: alerts[0], alerts[1].Metric: alerts[1]}
+		giveaway := byMetric[anomaly.MetricGiveaway]
+		assert.Equal(t, "steady", giveaway.DiscountID)
+		assert.True(t, spike.Equal(giveaway.Minute))
+		assert.InDelta(t, 200, giveaway.Mean, 0.001)
+		assert.Greater(t, giveaway.ZScore, 3.0)
+
+		redemptions := byMetric[anomaly.MetricRedemptions]
+		assert.InDelta(t, 2, redemptions.Mean, 0.001)
+		assert.Equal(t, 7.0, redemptions.Value, "fires as soon as the threshold is crossed")
+	})
+
+	t.Run("New and sparse discounts need history", func(t *testing.T) {
+		for i := 0; i < 50; i++ {
+			assert.Empty(t, redeem("brand-new", start, 1000))
+		}
+
+		redeem("sparse", start, 100)
+		assert.Empty(t, redeem("sparse", start.Add(20*time.Minute), 100), "an idle baseline is floored")
+	})
+}
+
+func TestAnomalyDetector_Webhook(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []anomaly.Alert
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert anomaly.Alert
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&alert))
+		mu.Lock()
+		received = append(received, alert)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	detector := anomaly.NewDetector(anomaly.WebhookNotifier{URL: server.URL}, anomaly.Config{
+		MinHistory: 5,
+		OnError:    func(alert anomaly.Alert, err error) { t.Errorf("webhook failed: %v", err) },
+	})
+
+	// Redemptions recorded by the service are observed as they happen
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(testdata.GetSampleDiscounts()))
+	fixed := clock.NewFixed(time.Now())
+	service := services.NewDiscountService(repo,
+		services.WithClock(fixed),
+		services.WithRedemptionRepository(anomaly.MonitorRedemptions(repository.NewInMemoryRedemptionRepository(), detector)))
+
+	cartItems, customer, _ := testdata.GetMultipleDiscountScenario()
+	ResetCartPricesToBase(cartItems)
+	checkout := func() {
+		t.Helper()
+		_, err := service.CalculateCartDiscounts(context.Background(), cartItems, customer, nil)
+		require.NoError(t, err)
+	}
+
+	for m := 0; m < 10; m++ {
+		checkout()
+		fixed.Advance(time.Minute)
+	}
+	for i := 0; i < 10; i++ {
+		checkout()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, received)
+	assert.NotEmpty(t, received[0].DiscountID)
+	assert.True(t, fixed.Now().UTC().Truncate(time.Minute).Equal(received[0].Minute))
+}
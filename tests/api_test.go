@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/api"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func newTestAPI(t *testing.T) *api.Handler {
+	t.Helper()
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(testdata.GetSampleDiscounts()))
+	return api.NewHandler(services.NewDiscountService(repo))
+}
+
+func doJSON(t *testing.T, h http.Handler, path string, header http.Header, body any) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	payload, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	for k, v := range header {
+		for _, value := range v {
+			req.Header.Add(k, value)
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &decoded), rec.Body.String())
+	return rec, decoded
+}
+
+func TestAPI_CalculateVersions(t *testing.T) {
+	cartItems, customer, paymentInfo := testdata.GetMultipleDiscountScenario()
+	body := map[string]any{"cart_items": cartItems, "customer": customer, "payment_info": paymentInfo}
+
+	t.Run("v1 keeps the name-keyed shape and is deprecated", func(t *testing.T) {
+		rec, resp := doJSON(t, newTestAPI(t), "/v1/cart/calculate", nil, body)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "v1", rec.Header().Get("API-Version"))
+		assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+		assert.Contains(t, rec.Header().Get("Link"), "</v2/cart/calculate>")
+
+		assert.IsType(t, map[string]any{}, resp["applied_discounts"])
+		assert.NotContains(t, resp, "discounts")
+		assert.NotContains(t, resp, "breakdown")
+	})
+
+	t.Run("v2 lists structured discounts", func(t *testing.T) {
+		rec, resp := doJSON(t, newTestAPI(t), "/v2/cart/calculate", nil, body)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "v2", rec.Header().Get("API-Version"))
+		assert.Empty(t, rec.Header().Get("Deprecation"))
+
+		discounts, ok := resp["discounts"].([]any)
+		require.True(t, ok)
+		require.NotEmpty(t, discounts)
+		first := discounts[0].(map[string]any)
+		assert.NotEmpty(t, first["discount_id"])
+		assert.NotEmpty(t, first["type"])
+		assert.NotContains(t, resp, "applied_discounts")
+	})
+
+	t.Run("Unversioned paths negotiate through the header", func(t *testing.T) {
+		rec, _ := doJSON(t, newTestAPI(t), "/cart/calculate", nil, body)
+		assert.Equal(t, "v1", rec.Header().Get("API-Version"))
+
+		rec, resp := doJSON(t, newTestAPI(t), "/cart/calculate", http.Header{"Accept-Version": {"2"}}, body)
+		assert.Equal(t, "v2", rec.Header().Get("API-Version"))
+		assert.Contains(t, resp, "discounts")
+
+		rec, _ = doJSON(t, newTestAPI(t), "/cart/calculate", http.Header{"Accept-Version": {"v9"}}, body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestAPI_Errors(t *testing.T) {
+	h := newTestAPI(t)
+
+	rec, resp := doJSON(t, h, "/v2/cart/calculate", nil, map[string]any{"cart_items": []any{}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "cart is empty", resp["error"])
+
+	rec, _ = doJSON(t, h, "/v2/cart/calculate", nil, map[string]any{"unknown_field": true})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec, _ = doJSON(t, h, "/v1/offers", nil, map[string]any{})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAPI_ValidateCodeAndOffers(t *testing.T) {
+	h := newTestAPI(t)
+	customer := testdata.GetSampleCustomers()[0]
+
+	rec, resp := doJSON(t, h, "/v2/codes/validate", nil, map[string]any{
+		"code":       "NOPE",
+		"cart_items": testdata.GetSampleCartItems(),
+		"customer":   customer,
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, false, resp["valid"])
+
+	rec, resp = doJSON(t, h, "/v2/offers", nil, map[string]any{"customer": customer})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, resp["offers"])
+}
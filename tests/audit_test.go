@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/audit"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/actor"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/tenant"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestAuditLog(t *testing.T) {
+	now := time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)
+	ctx := actor.NewContext(context.Background(), "alice@example.com")
+
+	var lines bytes.Buffer
+	store := audit.NewMemoryStore()
+	logger := audit.NewLogger(audit.NewJSONSink(&lines), store)
+	repo := audit.Discounts(repository.NewInMemoryDiscountRepository(), logger)
+
+	discount := &models.Discount{
+		ID:           "disc-audit",
+		Name:         "Audited 10%",
+		Type:         models.DiscountTypeVoucher,
+		Value:        decimal.NewFromInt(10),
+		IsPercentage: true,
+		ValidFrom:    now.Add(-time.Hour),
+		ValidTo:      now.Add(time.Hour),
+		IsActive:     true,
+	}
+	require.NoError(t, repo.CreateDiscount(ctx, discount))
+	discount.Value = decimal.NewFromInt(15)
+	require.NoError(t, repo.UpdateDiscount(actor.NewContext(ctx, "bob@example.com"), discount))
+
+	stale := *discount
+	stale.Version = 1
+	require.Error(t, repo.UpdateDiscount(ctx, &stale))
+
+	redemptions := repository.NewInMemoryRedemptionRepository()
+	service := services.NewDiscountService(repo,
+		services.WithClock(clock.NewFixed(now)),
+		services.WithRedemptionRepository(audit.Redemptions(redemptions, logger)))
+	_, err := service.CalculateCart(ctx, &models.CalculationRequest{
+		CartItems: []models.CartItem{{Product: testdata.GetSampleProducts()[3], Quantity: 1, Size: "32"}}, // 1200
+		Customer:  testdata.GetSampleCustomers()[1],
+		OrderID:   "ord-42",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteDiscount(ctx, discount.ID))
+	require.Error(t, repo.DeleteDiscount(ctx, discount.ID))
+
+	events, err := store.Query(context.Background(), audit.Filter{})
+	require.NoError(t, err)
+	require.Len(t, events, 4, "failed changes are not audited")
+
+	created, updated, applied, deleted := events[0], events[1], events[2], events[3]
+	assert.Equal(t, audit.ActionCreated, created.Action)
+	assert.Equal(t, "alice@example.com", created.Actor)
+	assert.Equal(t, 1, created.Discount.Version)
+	assert.Equal(t, audit.ActionUpdated, updated.Action)
+	assert.Equal(t, "bob@example.com", updated.Actor)
+	assert.True(t, decimal.NewFromInt(15).Equal(updated.Discount.Value))
+
+	assert.Equal(t, audit.ActionApplied, applied.Action)
+	assert.Equal(t, "disc-audit", applied.DiscountID)
+	assert.Equal(t, "ord-42", applied.OrderID)
+	assert.True(t, decimal.NewFromInt(180).Equal(applied.Amount))
+	assert.Equal(t, now, applied.At)
+
+	assert.Equal(t, audit.ActionDeleted, deleted.Action)
+	assert.Nil(t, deleted.Discount)
+
+	t.Run("JSON lines", func(t *testing.T) {
+		written := strings.Split(strings.TrimSpace(lines.String()), "\n")
+		require.Len(t, written, 4)
+		var event audit.Event
+		require.NoError(t, json.Unmarshal([]byte(written[2]), &event))
+		assert.Equal(t, "ord-42", event.OrderID)
+	})
+
+	t.Run("Queries stay within the tenant", func(t *testing.T) {
+		events, err := store.Query(tenant.NewContext(context.Background(), "store-b"), audit.Filter{})
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("Admin endpoint", func(t *testing.T) {
+		h := audit.NewHandler(store)
+		get := func(query string) (*httptest.ResponseRecorder, []audit.Event) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/audit?"+query, nil))
+			var body struct {
+				Events []audit.Event `json:"events"`
+			}
+			_ = json.Unmarshal(rec.Body.Bytes(), &body)
+			return rec, body.Events
+		}
+
+		rec, events := get("actor=bob@example.com")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Len(t, events, 1)
+		assert.Equal(t, audit.ActionUpdated, events[0].Action)
+
+		_, events = get("action=discount.applied&order_id=ord-42")
+		assert.Len(t, events, 1)
+
+		_, events = get("limit=2")
+		require.Len(t, events, 2)
+		assert.Equal(t, audit.ActionDeleted, events[1].Action, "the limit keeps the most recent events")
+
+		_, events = get("from=" + now.Add(time.Minute).Format(time.RFC3339))
+		assert.Len(t, events, 3, "changes are stamped with the wall clock, the application with the pinned instant")
+
+		rec, _ = get("from=yesterday")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/auth"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/actor"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	jwt := auth.JWT{Secret: []byte("s3cret"), Now: func() time.Time { return now }}
+	authn := auth.Chain{
+		auth.APIKeys{"viewer-key": {ID: "vic", Roles: []models.Role{models.RoleViewer}}},
+		jwt,
+	}
+
+	var seen string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		seen = actor.FromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h := auth.Middleware(mux, authn, auth.DefaultPolicy())
+
+	serve := func(method, path string, header http.Header) int {
+		req := httptest.NewRequest(method, path, nil)
+		for k, values := range header {
+			for _, v := range values {
+				req.Header.Add(k, v)
+			}
+		}
+		rec := httptest.NewRecorder()
+		seen = ""
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	bearer := func(token string) http.Header { return http.Header{"Authorization": {"Bearer " + token}} }
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header http.Header
+		status int
+		actor  string
+	}{
+		{"Public routes need no credentials", "POST", "/v2/cart/calculate", nil, http.StatusNoContent, ""},
+		{"Admin routes do", "GET", "/admin/catalog/stats", nil, http.StatusUnauthorized, ""},
+		{"Viewers read admin routes", "GET", "/admin/catalog/stats", http.Header{auth.APIKeyHeader: {"viewer-key"}}, http.StatusNoContent, "vic"},
+		{"Unknown keys are refused", "GET", "/admin/catalog/stats", http.Header{auth.APIKeyHeader: {"guess"}}, http.StatusUnauthorized, ""},
+		{"Viewers cannot transition", "POST", "/v2/discounts/d1/transitions", http.Header{auth.APIKeyHeader: {"viewer-key"}}, http.StatusForbidden, ""},
+		{"Editors can", "POST", "/v2/discounts/d1/transitions",
+			bearer(jwt.Issue(auth.Principal{ID: "erin", Roles: []models.Role{models.RoleEditor}}, now.Add(time.Hour))), http.StatusNoContent, "erin"},
+		{"Expired tokens are refused", "GET", "/admin/audit",
+			bearer(jwt.Issue(auth.Principal{ID: "erin", Roles: []models.Role{models.RoleAdmin}}, now)), http.StatusUnauthorized, ""},
+		{"Tokens signed with another secret are refused", "GET", "/admin/audit",
+			bearer(auth.JWT{Secret: []byte("other")}.Issue(auth.Principal{ID: "mallory", Roles: []models.Role{models.RoleAdmin}}, time.Time{})), http.StatusUnauthorized, ""},
+		{"Only admins change admin routes", "POST", "/admin/catalog/stats",
+			bearer(jwt.Issue(auth.Principal{ID: "alice", Roles: []models.Role{models.RoleApprover}}, time.Time{})), http.StatusForbidden, ""},
+		{"Admins hold every role", "POST", "/admin/catalog/stats",
+			bearer(jwt.Issue(auth.Principal{ID: "root", Roles: []models.Role{models.RoleAdmin}}, time.Time{})), http.StatusNoContent, "root"},
+		{"The sync feed needs credentials", "GET", "/sync/changes", nil, http.StatusUnauthorized, ""},
+		{"Viewers cannot sync", "POST", "/sync/usage", http.Header{auth.APIKeyHeader: {"viewer-key"}}, http.StatusForbidden, ""},
+		{"Edge stores with the sync role can", "POST", "/sync/usage",
+			bearer(jwt.Issue(auth.Principal{ID: "edge-1", Roles: []models.Role{models.RoleSync}}, time.Time{})), http.StatusNoContent, "edge-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.status, serve(tt.method, tt.path, tt.header))
+			assert.Equal(t, tt.actor, seen)
+		})
+	}
+}
+
+func TestAuth_PrincipalAuthorizer(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{ID: "draft", Type: models.DiscountTypeVoucher, State: models.LifecycleDraft}))
+	lifecycle := services.NewLifecycleService(repo, auth.PrincipalAuthorizer{})
+	authn := auth.APIKeys{
+		"editor-key": {ID: "erin", Roles: []models.Role{models.RoleEditor}},
+		"admin-key":  {ID: "root", Roles: []models.Role{models.RoleAdmin}},
+	}
+	h := auth.Middleware(api.NewHandler(services.NewDiscountService(repo), api.WithLifecycle(lifecycle)), authn, auth.DefaultPolicy())
+
+	transition := func(key, action string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v2/discounts/draft/transitions", strings.NewReader(`{"action":"`+action+`"}`))
+		req.Header.Set(auth.APIKeyHeader, key)
+		req.Header.Set(api.ActorHeader, "someone-else") // Ignored for authenticated callers
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, transition("editor-key", "submit"))
+	assert.Equal(t, http.StatusForbidden, transition("editor-key", "approve"), "editors do not approve")
+	assert.Equal(t, http.StatusOK, transition("admin-key", "approve"))
+
+	d, err := repo.GetDiscountByID(ctx, "draft")
+	require.NoError(t, err)
+	assert.Equal(t, models.LifecycleActive, d.State)
+	assert.Equal(t, "erin", d.SubmittedBy)
+}
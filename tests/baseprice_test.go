@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/testdata"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscountService_StartsFromBasePrice(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	brand := models.Discount{
+		ID: "puma-40", Name: "PUMA 40%", Type: models.DiscountTypeBrand, Value: decimal.NewFromInt(40), IsPercentage: true,
+		ApplicableTo: []string{"PUMA"}, ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true,
+	}
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &brand))
+	customer := testdata.GetSampleCustomers()[0]
+
+	// Base 1000, sent with the brand discount already taken off as 600
+	cartItems := []models.CartItem{{Product: testdata.GetSampleProducts()[0], Quantity: 1, Size: "M"}}
+
+	t.Run("Brand discounts are taken off the base price once", func(t *testing.T) {
+		result, err := services.NewDiscountService(repo).CalculateCartDiscounts(ctx, cartItems, customer, nil)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(1000).Equal(result.OriginalPrice), result.OriginalPrice.String())
+		assert.True(t, decimal.NewFromInt(600).Equal(result.FinalPrice), result.FinalPrice.String())
+		assert.True(t, decimal.NewFromInt(600).Equal(cartItems[0].Product.CurrentPrice), "caller's cart is left as sent")
+	})
+
+	t.Run("Lines without a base price keep their current price", func(t *testing.T) {
+		product := testdata.GetSampleProducts()[0]
+		product.BasePrice = decimal.Zero
+		result, err := services.NewDiscountService(repo).CalculateCartDiscounts(ctx,
+			[]models.CartItem{{Product: product, Quantity: 1, Size: "M"}}, customer, nil)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(360).Equal(result.FinalPrice), result.FinalPrice.String())
+	})
+
+	t.Run("Legacy callers are priced from their current price", func(t *testing.T) {
+		service := services.NewDiscountService(repo, services.WithLegacyCurrentPrices())
+		result, err := service.CalculateCartDiscounts(ctx, cartItems, customer, nil)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(600).Equal(result.OriginalPrice), result.OriginalPrice.String())
+		assert.True(t, decimal.NewFromInt(360).Equal(result.FinalPrice), result.FinalPrice.String())
+	})
+}
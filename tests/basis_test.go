@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/discounttest"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscountService_Basis(t *testing.T) {
+	ctx := context.Background()
+	cart := discounttest.Cart().
+		Add(discounttest.Product("tee", "PUMA", "tshirts", "1000"), 1).
+		Add(discounttest.Product("shoe", "NIKE", "shoes", "1000"), 1)
+	first := discounttest.Discount("first", models.DiscountTypeVoucher).Percent("25").Priority(2).Build()
+
+	tests := []struct {
+		name   string
+		second *discounttest.DiscountBuilder
+		amount int64 // Taken off by the second discount, after the first took 500 off 2000
+	}{
+		{"Running total by default", discounttest.Discount("second", models.DiscountTypeVoucher).Percent("10"), 150},
+		{"Running total", discounttest.Discount("second", models.DiscountTypeVoucher).Percent("10").Basis(models.BasisRunningTotal), 150},
+		{"Original price", discounttest.Discount("second", models.DiscountTypeVoucher).Percent("10").Basis(models.BasisOriginalPrice), 200},
+		{"Eligible subtotal", discounttest.Discount("second", models.DiscountTypeVoucher).Percent("10").On("NIKE").Basis(models.BasisEligibleSubtotal), 100},
+		{"Eligible subtotal of a bank offer", discounttest.Discount("second", models.DiscountTypeBank).Percent("10").Basis(models.BasisEligibleSubtotal), 200},
+		{"Never more than is left", discounttest.Discount("second", models.DiscountTypeVoucher).Percent("80").Basis(models.BasisOriginalPrice), 1500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := discounttest.NewRepository(t, first, tt.second.Build())
+			result, err := services.NewDiscountService(repo).CalculateCart(ctx, cart.PaidByCard("HDFC", models.Credit).Request())
+			require.NoError(t, err)
+			require.Len(t, result.Breakdown, 2)
+			assert.True(t, result.Breakdown[1].Amount.Equal(decimal.NewFromInt(tt.amount)), result.Breakdown[1].Amount.String())
+			assert.True(t, result.FinalPrice.Equal(decimal.NewFromInt(1500-tt.amount)))
+		})
+	}
+
+	t.Run("Invalid bases are refused", func(t *testing.T) {
+		repo := repository.NewInMemoryDiscountRepository()
+		for _, d := range []models.Discount{
+			discounttest.Discount("unknown", models.DiscountTypeVoucher).Percent("10").Basis("LIST_PRICE").Build(),
+			discounttest.Discount("brand", models.DiscountTypeBrand).Percent("10").On("PUMA").Basis(models.BasisOriginalPrice).Build(),
+		} {
+			err := repo.CreateDiscount(ctx, &d)
+			assert.True(t, errors.IsValidationError(err), "%s: %v", d.ID, err)
+		}
+	})
+}
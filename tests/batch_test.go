@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestDiscountService_CalculateCartDiscountsBatch(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID:        "disc-batch",
+		Name:      "Batch 100 off",
+		Type:      models.DiscountTypeVoucher,
+		Value:     decimal.NewFromInt(100),
+		ValidFrom: now.Add(-time.Hour),
+		ValidTo:   now.Add(time.Hour),
+		IsActive:  true,
+	}))
+	service := services.NewDiscountService(repo,
+		services.WithBatchConcurrency(4),
+		services.WithRequestLimits(services.RequestLimits{MaxBatchCarts: 100}))
+
+	item := models.CartItem{Product: testdata.GetSampleProducts()[3], Quantity: 1, Size: "32"} // 1200
+	customer := testdata.GetSampleCustomers()[1]
+	carts := func(n int) []*models.CalculationRequest {
+		carts := make([]*models.CalculationRequest, n)
+		for i := range carts {
+			if i%10 == 3 {
+				carts[i] = &models.CalculationRequest{Customer: customer} // empty cart
+				continue
+			}
+			items := []models.CartItem{item}
+			for j := 0; j < i%3; j++ {
+				items = append(items, item)
+			}
+			carts[i] = &models.CalculationRequest{CartItems: items, Customer: customer}
+		}
+		return carts
+	}
+
+	t.Run("Reports every cart in order", func(t *testing.T) {
+		batch, err := service.CalculateCartDiscountsBatch(ctx, &models.BatchCalculationRequest{Carts: carts(50), DryRun: true})
+		require.NoError(t, err)
+		require.Len(t, batch.Results, 50)
+		assert.Equal(t, 45, batch.Succeeded)
+		assert.Equal(t, 5, batch.Failed)
+
+		for i, r := range batch.Results {
+			assert.Equal(t, i, r.Index)
+			if i%10 == 3 {
+				assert.True(t, errors.IsValidationError(r.Err), "cart %d: %v", i, r.Err)
+				assert.Nil(t, r.Result)
+				continue
+			}
+			require.NoError(t, r.Err, "cart %d", i)
+			original := decimal.NewFromInt(int64(1200 * (1 + i%3)))
+			assert.True(t, original.Equal(r.Result.OriginalPrice), "cart %d", i)
+			assert.True(t, original.Sub(decimal.NewFromInt(100)).Equal(r.Result.FinalPrice), "cart %d", i)
+		}
+
+		stored, err := repo.GetDiscountByID(ctx, "disc-batch")
+		require.NoError(t, err)
+		assert.Zero(t, stored.UsedCount, "a dry run records no usage")
+	})
+
+	t.Run("Committing batches record usage", func(t *testing.T) {
+		batch, err := service.CalculateCartDiscountsBatch(ctx, &models.BatchCalculationRequest{Carts: carts(10)})
+		require.NoError(t, err)
+		assert.Equal(t, 9, batch.Succeeded)
+
+		stored, err := repo.GetDiscountByID(ctx, "disc-batch")
+		require.NoError(t, err)
+		assert.Equal(t, 9, stored.UsedCount)
+	})
+
+	t.Run("Missing carts and cancelled contexts fail per cart", func(t *testing.T) {
+		batch, err := service.CalculateCartDiscountsBatch(ctx, &models.BatchCalculationRequest{
+			Carts:  []*models.CalculationRequest{nil, {CartItems: []models.CartItem{item}, Customer: customer}},
+			DryRun: true,
+		})
+		require.NoError(t, err)
+		assert.True(t, errors.IsValidationError(batch.Results[0].Err))
+		assert.NoError(t, batch.Results[1].Err)
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		batch, err = service.CalculateCartDiscountsBatch(cancelled, &models.BatchCalculationRequest{Carts: carts(20), DryRun: true})
+		require.NoError(t, err)
+		assert.Equal(t, 20, batch.Failed)
+		for _, r := range batch.Results {
+			assert.ErrorIs(t, r.Err, context.Canceled)
+		}
+	})
+
+	t.Run("Oversized batches are rejected", func(t *testing.T) {
+		_, err := service.CalculateCartDiscountsBatch(ctx, &models.BatchCalculationRequest{Carts: carts(101)})
+		assert.True(t, errors.IsValidationError(err))
+	})
+
+	t.Run("API reports a status per cart", func(t *testing.T) {
+		h := api.NewHandler(service)
+		body := map[string]any{
+			"carts": []map[string]any{
+				{"cart_items": []models.CartItem{item}, "customer": customer},
+				{"customer": customer},
+			},
+			"dry_run": true,
+		}
+
+		rec, resp := doJSON(t, h, "/v2/cart/calculate/batch", nil, body)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.EqualValues(t, 1, resp["succeeded"])
+		assert.EqualValues(t, 1, resp["failed"])
+
+		results := resp["results"].([]any)
+		require.Len(t, results, 2)
+		first, second := results[0].(map[string]any), results[1].(map[string]any)
+		assert.EqualValues(t, http.StatusOK, first["status"])
+		assert.Equal(t, "1100", first["result"].(map[string]any)["final_price"])
+		assert.EqualValues(t, http.StatusBadRequest, second["status"])
+		assert.NotEmpty(t, second["error"])
+
+		rec, _ = doJSON(t, h, "/v1/cart/calculate/batch", nil, body)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
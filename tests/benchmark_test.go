@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+)
+
+const (
+	benchBrands     = 500
+	benchCategories = 100
+	benchBanks      = 20
+)
+
+var benchNow = time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// scanningRepository answers filtered queries by scanning every active discount, as the
+// service did before the repository could narrow them down
+type scanningRepository struct {
+	interfaces.IDiscountRepository
+}
+
+func (r scanningRepository) GetApplicableDiscounts(ctx context.Context, filter models.DiscountFilter) ([]models.Discount, error) {
+	active, err := r.GetActiveDiscounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var discounts []models.Discount
+	for i := range active {
+		if filter.Matches(&active[i]) {
+			discounts = append(discounts, active[i])
+		}
+	}
+	return discounts, nil
+}
+
+// generateDiscounts spreads n discounts over the brands, categories and banks of a large
+// catalog, with unique priorities so the application order never depends on load order
+func generateDiscounts(n int) []models.Discount {
+	types := []models.DiscountType{models.DiscountTypeBrand, models.DiscountTypeCategory,
+		models.DiscountTypeBank, models.DiscountTypeVoucher}
+
+	discounts := make([]models.Discount, n)
+	for i := range discounts {
+		d := models.Discount{
+			ID:           fmt.Sprintf("disc-%05d", i),
+			Name:         fmt.Sprintf("Discount %05d", i),
+			Type:         types[i%len(types)],
+			Value:        decimal.NewFromInt(int64(1 + i%5)),
+			IsPercentage: true,
+			ValidFrom:    benchNow.Add(-time.Hour),
+			ValidTo:      benchNow.Add(time.Hour),
+			IsActive:     true,
+			Priority:     i,
+		}
+		switch d.Type {
+		case models.DiscountTypeBrand, models.DiscountTypeVoucher:
+			d.ApplicableTo = []string{fmt.Sprintf("brand-%d", i%benchBrands)}
+		case models.DiscountTypeCategory:
+			d.ApplicableTo = []string{fmt.Sprintf("category-%d", i%benchCategories)}
+		case models.DiscountTypeBank:
+			d.ApplicableTo = []string{fmt.Sprintf("bank-%d", i%benchBanks)}
+		}
+		discounts[i] = d
+	}
+	return discounts
+}
+
+func benchRequest(r *rand.Rand) *models.CalculationRequest {
+	items := make([]models.CartItem, 1+r.IntN(4))
+	for i := range items {
+		price := decimal.NewFromInt(int64(500 + r.IntN(5000)))
+		items[i] = models.CartItem{
+			Product: models.Product{
+				ID:           fmt.Sprintf("product-%d", r.IntN(10000)),
+				Brand:        models.Brand{ID: fmt.Sprintf("brand-%d", r.IntN(benchBrands))},
+				Category:     models.Category{ID: fmt.Sprintf("category-%d", r.IntN(benchCategories))},
+				BasePrice:    price,
+				CurrentPrice: price,
+			},
+			Quantity: 1 + r.IntN(2),
+			Size:     "M",
+		}
+	}
+	bank := fmt.Sprintf("bank-%d", r.IntN(benchBanks))
+	return &models.CalculationRequest{
+		CartItems:   items,
+		Customer:    models.CustomerProfile{ID: "cust-bench"},
+		PaymentInfo: &models.PaymentInfo{Method: models.Card, BankName: &bank},
+	}
+}
+
+func newBenchService(tb testing.TB, discounts []models.Discount, scan bool) interfaces.IDiscountService {
+	tb.Helper()
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(tb, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(discounts))
+	if scan {
+		repo = scanningRepository{repo}
+	}
+	return services.NewDiscountService(repo, services.WithClock(clock.NewFixed(benchNow)))
+}
+
+func TestCandidateIndexMatchesFullScan(t *testing.T) {
+	discounts := generateDiscounts(4000)
+	indexed := newBenchService(t, discounts, false)
+	scanning := newBenchService(t, discounts, true)
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 100; i++ {
+		req := benchRequest(r)
+		want, err := scanning.CalculateCart(context.Background(), req)
+		require.NoError(t, err)
+		got, err := indexed.CalculateCart(context.Background(), req)
+		require.NoError(t, err)
+
+		assert.Equal(t, want.Breakdown, got.Breakdown, "request %d", i)
+		assert.True(t, want.FinalPrice.Equal(got.FinalPrice), "request %d", i)
+	}
+}
+
+func BenchmarkCalculateCart(b *testing.B) {
+	for _, n := range []int{1000, 50000} {
+		discounts := generateDiscounts(n)
+		for _, mode := range []string{"scan", "indexed"} {
+			b.Run(fmt.Sprintf("discounts=%d/%s", n, mode), func(b *testing.B) {
+				service := newBenchService(b, discounts, mode == "scan")
+				r := rand.New(rand.NewPCG(1, 2))
+				requests := make([]*models.CalculationRequest, 64)
+				for i := range requests {
+					requests[i] = benchRequest(r)
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := service.CalculateCart(context.Background(), requests[i%len(requests)]); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
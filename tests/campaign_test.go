@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestDiscountService_CampaignBudget(t *testing.T) {
+	ctx := context.Background()
+
+	discounts := testdata.GetSampleDiscounts()
+	discounts[0].CampaignID = "summer" // PUMA brand 40%
+	discounts[1].CampaignID = "summer" // T-shirts category 10%
+
+	repo := repository.NewInMemoryDiscountRepository()
+	memoryRepo := repo.(*repository.InMemoryDiscountRepository)
+	require.NoError(t, memoryRepo.SeedDiscounts(discounts))
+
+	campaignRepo := repository.NewInMemoryCampaignRepository()
+	require.NoError(t, campaignRepo.CreateCampaign(ctx, &models.Campaign{
+		ID:       "summer",
+		Name:     "Summer Sale",
+		Budget:   decimal.NewFromInt(900),
+		IsActive: true,
+	}))
+
+	service := services.NewDiscountService(repo, services.WithCampaignRepository(campaignRepo))
+	cartItems, customer, _ := testdata.GetMultipleDiscountScenario()
+	ResetCartPricesToBase(cartItems)
+
+	// Brand takes 800 of the budget, leaving only 100 of the category's 200
+	result, err := service.CalculateCartDiscounts(ctx, cartItems, customer, nil)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(800).Equal(result.AppliedDiscounts[discounts[0].Name]))
+	assert.True(t, decimal.NewFromInt(100).Equal(result.AppliedDiscounts[discounts[1].Name]))
+
+	campaign, err := campaignRepo.GetCampaignByID(ctx, "summer")
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(900).Equal(campaign.Spent))
+
+	// Once exhausted, none of the campaign's discounts apply
+	result, err = service.CalculateCartDiscounts(ctx, cartItems, customer, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, result.AppliedDiscounts, discounts[0].Name)
+	assert.NotContains(t, result.AppliedDiscounts, discounts[1].Name)
+
+	err = campaignRepo.ConsumeBudget(ctx, "summer", decimal.NewFromInt(1))
+	assert.True(t, errors.IsValidationError(err))
+}
+
+func TestDiscountService_InactiveCampaign(t *testing.T) {
+	ctx := context.Background()
+
+	discounts := testdata.GetSampleDiscounts()
+	discounts[5].CampaignID = "paused" // PREMIUM15 voucher
+
+	repo := repository.NewInMemoryDiscountRepository()
+	memoryRepo := repo.(*repository.InMemoryDiscountRepository)
+	require.NoError(t, memoryRepo.SeedDiscounts(discounts))
+
+	campaignRepo := repository.NewInMemoryCampaignRepository()
+	require.NoError(t, campaignRepo.CreateCampaign(ctx, &models.Campaign{ID: "paused", IsActive: false}))
+
+	service := services.NewDiscountService(repo, services.WithCampaignRepository(campaignRepo))
+	cartItems, customer, paymentInfo := testdata.GetMultipleDiscountScenario()
+
+	valid, err := service.ValidateDiscountCode(ctx, "PREMIUM15", cartItems, customer)
+	require.NoError(t, err)
+	assert.False(t, valid)
+
+	result, err := service.CalculateCartDiscounts(ctx, cartItems, customer, paymentInfo)
+	require.NoError(t, err)
+	assert.NotContains(t, result.AppliedDiscounts, discounts[5].Name)
+}
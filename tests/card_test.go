@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDetectCardNetwork(t *testing.T) {
+	for bin, network := range map[string]models.CardNetwork{
+		"41471234": models.Visa,
+		"545454":   models.Mastercard,
+		"222100":   models.Mastercard,
+		"272099":   models.Mastercard,
+		"508227":   models.RuPay,
+		"607384":   models.RuPay,
+		"371449":   models.Amex,
+		"999999":   "",
+	} {
+		assert.Equal(t, network, models.DetectCardNetwork(bin), bin)
+	}
+}
+
+func TestDiscount_MatchesCard(t *testing.T) {
+	credit, debit := models.Credit, models.Debit
+	d := &models.Discount{
+		Type:      models.DiscountTypeBank,
+		CardTypes: []models.CardType{models.Credit},
+		CardBINs:  []string{"414", "524000-524199"},
+	}
+	require.NoError(t, d.CheckCardRules())
+
+	card := func(bin string, cardType *models.CardType) *models.PaymentInfo {
+		return &models.PaymentInfo{Method: models.Card, CardBIN: bin, CardType: cardType}
+	}
+	assert.True(t, d.MatchesCard(card("414712", &credit)))
+	assert.True(t, d.MatchesCard(card("52410099", &credit)), "eight digit BINs fall in six digit ranges")
+	assert.False(t, d.MatchesCard(card("524200", &credit)))
+	assert.False(t, d.MatchesCard(card("414712", &debit)))
+	assert.False(t, d.MatchesCard(card("414712", nil)), "an unknown card type fails a card type rule")
+	assert.False(t, d.MatchesCard(card("", &credit)))
+	assert.False(t, d.MatchesCard(card("4147", &credit)), "BINs are at least six digits")
+	assert.False(t, d.MatchesCard(&models.PaymentInfo{Method: models.UPI}))
+
+	networks := &models.Discount{Type: models.DiscountTypeBank, CardNetworks: []models.CardNetwork{models.RuPay}}
+	assert.True(t, networks.MatchesCard(card("607384", nil)), "the network is detected from the BIN")
+	assert.True(t, networks.MatchesCard(&models.PaymentInfo{Method: models.Card, CardNetwork: "rupay"}))
+	assert.False(t, networks.MatchesCard(card("414712", nil)))
+
+	assert.True(t, (&models.Discount{}).MatchesCard(nil), "discounts without card rules accept any payment")
+
+	for _, invalid := range []*models.Discount{
+		{Type: models.DiscountTypeBank, CardTypes: []models.CardType{"PREPAID"}},
+		{Type: models.DiscountTypeBank, CardNetworks: []models.CardNetwork{"DISCOVER"}},
+		{Type: models.DiscountTypeBank, CardBINs: []string{"41x"}},
+		{Type: models.DiscountTypeBank, CardBINs: []string{"414999-414000"}},
+		{Type: models.DiscountTypeBank, CardBINs: []string{"4140-41499"}},
+		{Type: models.DiscountTypeVoucher, CardBINs: []string{"414"}},
+	} {
+		assert.Error(t, invalid.CheckCardRules(), "%+v", invalid)
+	}
+}
+
+func TestDiscountService_CardOffers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	repo := repository.NewInMemoryDiscountRepository()
+	offer := &models.Discount{
+		ID:           "icici-credit-414",
+		Name:         "10% on ICICI credit cards starting 414",
+		Type:         models.DiscountTypeBank,
+		Value:        decimal.NewFromInt(10),
+		IsPercentage: true,
+		ApplicableTo: []string{"ICICI"},
+		CardTypes:    []models.CardType{models.Credit},
+		CardBINs:     []string{"414"},
+		ValidFrom:    now.Add(-time.Hour),
+		ValidTo:      now.Add(time.Hour),
+		IsActive:     true,
+	}
+	require.NoError(t, repo.CreateDiscount(ctx, offer))
+
+	invalid := *offer
+	invalid.ID, invalid.CardBINs = "bad-bins", []string{"414-41"}
+	assert.True(t, errors.IsValidationError(repo.CreateDiscount(ctx, &invalid)))
+
+	service := services.NewDiscountService(repo)
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 1}}
+	bank, credit, debit := "ICICI", models.Credit, models.Debit
+
+	result, err := service.CalculateCartDiscounts(ctx, cart, models.CustomerProfile{},
+		&models.PaymentInfo{Method: models.Card, BankName: &bank, CardType: &credit, CardBIN: "41471234"})
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(900).Equal(result.FinalPrice))
+
+	req := &models.CalculationRequest{
+		CartItems:   cart,
+		PaymentInfo: &models.PaymentInfo{Method: models.Card, BankName: &bank, CardType: &debit, CardBIN: "41471234"},
+	}
+	result, err = service.CalculateCart(ctx, req)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(1000).Equal(result.FinalPrice), "debit cards of the bank do not qualify")
+
+	explanation, err := service.ExplainCartDiscounts(ctx, req)
+	require.NoError(t, err)
+	decision, ok := explanation.Decision(offer.ID)
+	require.True(t, ok)
+	assert.Equal(t, models.ReasonCardMismatch, decision.Reason)
+}
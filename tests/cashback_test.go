@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscountService_Cashback(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	cashback := &models.Discount{
+		ID:                "hdfc-cashback",
+		Name:              "10% cashback on HDFC cards",
+		Type:              models.DiscountTypeBank,
+		Value:             decimal.NewFromInt(10),
+		IsPercentage:      true,
+		MaxAmount:         decimal.NewFromInt(200),
+		ApplicableTo:      []string{"HDFC"},
+		Cashback:          true,
+		CashbackDelayDays: 30,
+		ValidFrom:         now.Add(-time.Hour),
+		ValidTo:           now.Add(time.Hour),
+		IsActive:          true,
+		Priority:          10,
+	}
+	instant := &models.Discount{
+		ID:           "sale",
+		Name:         "5% off",
+		Type:         models.DiscountTypeVoucher,
+		Value:        decimal.NewFromInt(5),
+		IsPercentage: true,
+		ValidFrom:    now.Add(-time.Hour),
+		ValidTo:      now.Add(time.Hour),
+		IsActive:     true,
+		Priority:     1,
+	}
+
+	repo := repository.NewInMemoryDiscountRepository()
+	for _, d := range []*models.Discount{cashback, instant} {
+		require.NoError(t, repo.CreateDiscount(ctx, d))
+	}
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+
+	bank := "HDFC"
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(3000)}, Quantity: 1}}
+	result, err := service.CalculateCartDiscounts(ctx, cart, models.CustomerProfile{},
+		&models.PaymentInfo{Method: models.Card, BankName: &bank})
+	require.NoError(t, err)
+
+	assert.True(t, decimal.NewFromInt(2850).Equal(result.FinalPrice), "cashback does not lower the price, got %s", result.FinalPrice)
+	require.Len(t, result.Breakdown, 1)
+	assert.Equal(t, instant.ID, result.Breakdown[0].DiscountID)
+	assert.NotContains(t, result.AppliedDiscounts, cashback.Name)
+
+	require.Len(t, result.PendingCashback, 1)
+	pending := result.PendingCashback[0]
+	assert.Equal(t, cashback.ID, pending.DiscountID)
+	assert.True(t, decimal.NewFromInt(200).Equal(pending.Amount), "the cap applies to cashback, got %s", pending.Amount)
+	assert.Equal(t, 30, pending.DelayDays)
+	assert.Equal(t, now.AddDate(0, 0, 30), pending.CreditOn)
+	assert.Contains(t, result.Message, "Cashback: 200")
+
+	stored, err := repo.GetDiscountByID(ctx, cashback.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.UsedCount, "earning cashback uses the discount")
+
+	for _, invalid := range []*models.Discount{
+		{ID: "negative-delay", Type: models.DiscountTypeBank, Cashback: true, CashbackDelayDays: -1},
+		{ID: "delay-only", Type: models.DiscountTypeBank, CashbackDelayDays: 7},
+		{ID: "points", Type: models.DiscountTypePointsRedemption, Cashback: true},
+	} {
+		assert.True(t, errors.IsValidationError(repo.CreateDiscount(ctx, invalid)), invalid.ID)
+	}
+}
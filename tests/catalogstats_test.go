@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/catalog"
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+func TestCatalogStats(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	discount := func(id string, typ models.DiscountType, validTo time.Time, applicableTo ...string) *models.Discount {
+		return &models.Discount{
+			ID:           id,
+			Name:         id,
+			Type:         typ,
+			Value:        decimal.NewFromInt(10),
+			IsPercentage: true,
+			ApplicableTo: applicableTo,
+			ValidFrom:    now.Add(-24 * time.Hour),
+			ValidTo:      validTo,
+			IsActive:     true,
+		}
+	}
+
+	puma := discount("puma", models.DiscountTypeBrand, now.AddDate(0, 0, 3), "PUMA")
+	puma.ExcludedItems = []string{models.ItemRef(models.ItemRefCategory, "socks")}
+	shirts := discount("shirts", models.DiscountTypeCategory, now.AddDate(0, 0, 1), "T-shirts")
+	voucher := discount("voucher", models.DiscountTypeVoucher, now.AddDate(0, 1, 0), models.ItemRef(models.ItemRefBrand, "NIKE"))
+	voucher.Code = "SAVE10"
+	bank := discount("bank", models.DiscountTypeBank, now.AddDate(0, 1, 0), "HDFC")
+	expired := discount("expired", models.DiscountTypeBrand, now.Add(-time.Hour), "PUMA")
+	paused := discount("paused", models.DiscountTypeBrand, now.AddDate(0, 0, 2), "ADIDAS")
+	paused.IsActive = false
+
+	repo := repository.NewInMemoryDiscountRepository()
+	for _, d := range []*models.Discount{puma, shirts, voucher, bank, expired, paused} {
+		require.NoError(t, repo.CreateDiscount(ctx, d))
+	}
+	other := tenant.NewContext(ctx, "other")
+	require.NoError(t, repo.CreateDiscount(other, discount("other", models.DiscountTypeBrand, now.AddDate(0, 0, 1), "REEBOK")))
+
+	inspector, ok := repo.(interfaces.CatalogInspector)
+	require.True(t, ok)
+	stats, err := inspector.CatalogStats(clock.NewContext(ctx, now), catalog.DefaultExpiryWindow)
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, stats.Total, "other tenants' discounts are not counted")
+	assert.Equal(t, 3, stats.ByType[models.DiscountTypeBrand])
+	assert.Equal(t, 1, stats.ByType[models.DiscountTypeBank])
+	assert.Equal(t, map[string]int{models.StatusAvailable: 4, string(models.ReasonExpired): 1, string(models.ReasonInactive): 1}, stats.ByStatus)
+	assert.Equal(t, 3, stats.DistinctBrands, "PUMA, NIKE and ADIDAS")
+	assert.Equal(t, 2, stats.DistinctCategories, "T-shirts and the excluded socks")
+	require.Len(t, stats.Expiring, 2, "only available discounts ending within the window are listed")
+	assert.Equal(t, shirts.ID, stats.Expiring[0].ID)
+	assert.Equal(t, puma.ID, stats.Expiring[1].ID)
+	assert.Equal(t, 1, stats.Indexes["codes"])
+	assert.Positive(t, stats.Indexes["candidate_keys"])
+	assert.Equal(t, now, stats.GeneratedAt)
+
+	h := catalog.NewHandler(repo, clock.NewFixed(now))
+	get := func(path, tenantID string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if tenantID != "" {
+			req.Header.Set(api.TenantHeader, tenantID)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &decoded), rec.Body.String())
+		return rec, decoded
+	}
+
+	rec, resp := get("/admin/catalog/stats?window=48h", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.EqualValues(t, 6, resp["total"])
+	assert.Len(t, resp["expiring"], 1)
+
+	rec, resp = get("/admin/catalog/stats", "other")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.EqualValues(t, 1, resp["total"])
+	assert.EqualValues(t, 1, resp["distinct_brands"])
+
+	rec, _ = get("/admin/catalog/stats?window=soon", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/channel"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscountService_ChannelRestrictions(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	hdfc := "HDFC"
+	cart := []models.CartItem{{
+		Product:  models.Product{ID: "p1", Brand: models.Brand{ID: "PUMA"}, Category: models.Category{ID: "shoes"}, CurrentPrice: decimal.NewFromInt(1000)},
+		Quantity: 1,
+	}}
+	payment := &models.PaymentInfo{Method: models.Card, BankName: &hdfc}
+
+	// Every strategy's discounts are held to their channels
+	for _, tt := range []struct {
+		discountType models.DiscountType
+		applicableTo []string
+	}{
+		{models.DiscountTypeBrand, []string{"PUMA"}},
+		{models.DiscountTypeCategory, []string{"shoes"}},
+		{models.DiscountTypeVoucher, nil},
+		{models.DiscountTypeBank, []string{"HDFC"}},
+	} {
+		t.Run(string(tt.discountType), func(t *testing.T) {
+			ctx := context.Background()
+			repo := repository.NewInMemoryDiscountRepository()
+			require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+				ID: "app-only", Name: "App only", Type: tt.discountType, ApplicableTo: tt.applicableTo,
+				Value: decimal.NewFromInt(10), IsPercentage: true,
+				ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true,
+				Channels: []models.SalesChannel{models.ChannelApp},
+			}))
+			service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+
+			price := func(ctx context.Context, salesChannel models.SalesChannel) decimal.Decimal {
+				result, err := service.CalculateCart(ctx, &models.CalculationRequest{CartItems: cart, PaymentInfo: payment, SalesChannel: salesChannel})
+				require.NoError(t, err)
+				return result.FinalPrice
+			}
+			assert.True(t, decimal.NewFromInt(900).Equal(price(ctx, models.ChannelApp)))
+			assert.True(t, decimal.NewFromInt(1000).Equal(price(ctx, models.ChannelWeb)), "app-only promotions do not leak to the website")
+			assert.True(t, decimal.NewFromInt(1000).Equal(price(ctx, "")), "nor to callers naming no channel")
+			assert.True(t, decimal.NewFromInt(900).Equal(price(channel.NewContext(ctx, "app"), "")), "the context names the channel")
+		})
+	}
+
+	ctx := context.Background()
+	_, err := services.NewDiscountService(repository.NewInMemoryDiscountRepository()).
+		CalculateCart(ctx, &models.CalculationRequest{CartItems: cart, SalesChannel: "fax"})
+	assert.True(t, errors.IsValidationError(err))
+
+	err = repository.NewInMemoryDiscountRepository().CreateDiscount(ctx, &models.Discount{
+		ID: "bad", Type: models.DiscountTypeVoucher, Channels: []models.SalesChannel{"fax"},
+	})
+	assert.Error(t, err)
+}
+
+func TestDiscountService_ChannelRestrictedCodes(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID: "app-code", Name: "App code", Type: models.DiscountTypeVoucher, Code: "APP10",
+		Value: decimal.NewFromInt(10), IsPercentage: true,
+		ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true,
+		Channels: []models.SalesChannel{models.ChannelApp},
+	}))
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 1}}
+
+	valid, err := service.ValidateDiscountCode(channel.NewContext(ctx, "web"), "APP10", cart, models.CustomerProfile{})
+	require.NoError(t, err)
+	assert.False(t, valid)
+	valid, err = service.ValidateDiscountCode(channel.NewContext(ctx, "app"), "APP10", cart, models.CustomerProfile{})
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	h := api.NewHandler(service)
+	validate := func(salesChannel string) (int, string) {
+		req := httptest.NewRequest(http.MethodPost, "/v2/codes/validate", strings.NewReader(`{"code":"APP10","cart_items":[{"product":{"id":"p1","current_price":"1000"},"quantity":1}]}`))
+		req.Header.Set(api.ChannelHeader, salesChannel)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+	status, body := validate("app")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"valid":true`)
+	_, body = validate("web")
+	assert.Contains(t, body, `"valid":false`)
+	status, _ = validate("fax")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/discounttest"
+)
+
+func TestDiscountService_DescribeDiscountCode(t *testing.T) {
+	ctx := context.Background()
+	customer := models.CustomerProfile{ID: "c1"}
+	cart := discounttest.Cart().Add(discounttest.Product("tee", "PUMA", "tshirts", "1000"), 2).Items()
+
+	save20 := discounttest.Discount("save20", models.DiscountTypeVoucher).Named("Save 20").
+		Percent("20").MaxAmount("300").Code("SAVE20").UsageLimit(10).Build()
+	save20.UsedCount = 4
+	flat := discounttest.Discount("flat", models.DiscountTypeVoucher).Fixed("150").Code("FLAT150").Build()
+	expired := discounttest.Discount("expired", models.DiscountTypeVoucher).Percent("10").Code("OLD10").
+		Valid(time.Now().Add(-48*time.Hour), time.Now().Add(-24*time.Hour)).Build()
+	svc := services.NewDiscountService(discounttest.NewRepository(t, save20, flat, expired))
+
+	t.Run("Valid codes are described", func(t *testing.T) {
+		validation, err := svc.DescribeDiscountCode(ctx, "SAVE20", cart, customer)
+		require.NoError(t, err)
+		assert.True(t, validation.Valid)
+		assert.Equal(t, "save20", validation.DiscountID)
+		assert.Equal(t, "Save 20", validation.Name)
+		assert.Equal(t, "20% off up to INR 300", validation.ValueSummary)
+		require.NotNil(t, validation.ExpiresAt)
+		assert.True(t, validation.ExpiresAt.Equal(discounttest.ValidTo))
+		require.NotNil(t, validation.RemainingUses)
+		assert.Equal(t, 6, *validation.RemainingUses)
+		// 20% of 2000 is held to the 300 cap
+		assert.True(t, validation.EstimatedSavings.Equal(decimal.NewFromInt(300)), validation.EstimatedSavings.String())
+
+		validation, err = svc.DescribeDiscountCode(ctx, "FLAT150", cart, customer)
+		require.NoError(t, err)
+		assert.Equal(t, "INR 150 off", validation.ValueSummary)
+		assert.Nil(t, validation.RemainingUses)
+		assert.True(t, validation.EstimatedSavings.Equal(decimal.NewFromInt(150)))
+	})
+
+	t.Run("Invalid codes save nothing", func(t *testing.T) {
+		validation, err := svc.DescribeDiscountCode(ctx, "OLD10", cart, customer)
+		require.NoError(t, err)
+		assert.False(t, validation.Valid)
+		assert.Equal(t, "expired", validation.DiscountID, "expired codes still say what they were")
+		assert.True(t, validation.EstimatedSavings.IsZero())
+
+		validation, err = svc.DescribeDiscountCode(ctx, "NOPE", cart, customer)
+		require.NoError(t, err)
+		assert.Equal(t, &models.CodeValidation{Code: "NOPE", EstimatedSavings: decimal.Zero}, validation)
+
+		valid, err := svc.ValidateDiscountCode(ctx, "OLD10", cart, customer)
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Codes are decided at the pinned instant", func(t *testing.T) {
+		pinned := clock.NewContext(ctx, time.Now().Add(-36*time.Hour))
+		validation, err := svc.DescribeDiscountCode(pinned, "OLD10", cart, customer)
+		require.NoError(t, err)
+		assert.True(t, validation.Valid, "OLD10 was valid at the instant the request was pinned to")
+	})
+
+	t.Run("The API returns the description", func(t *testing.T) {
+		h := api.NewHandler(svc)
+		rec, resp := doJSON(t, h, "/v2/codes/validate", nil, map[string]any{
+			"code": "SAVE20", "cart_items": cart, "customer": customer,
+		})
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, true, resp["valid"])
+		assert.Equal(t, "20% off up to INR 300", resp["value_summary"])
+		assert.Equal(t, float64(6), resp["remaining_uses"])
+		assert.Equal(t, "300", resp["estimated_savings"])
+	})
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/config"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/services"
+)
+
+func TestConfig_Load(t *testing.T) {
+	example := filepath.Join("..", "testdata", "config", "server.yaml")
+
+	cfg, err := config.Load(example)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, ":9090", cfg.HTTP.Addr)
+	assert.Equal(t, config.BackendMemory, cfg.Repository.Backend)
+	assert.Equal(t, 24*time.Hour, time.Duration(cfg.Cache.IdempotencyTTL))
+	assert.Equal(t, models.RoundHalfEven, cfg.Pricing.Rounding)
+	require.NotNil(t, cfg.Pricing.MinMarginPercent)
+	assert.True(t, decimal.NewFromInt(10).Equal(*cfg.Pricing.MinMarginPercent))
+	require.NotNil(t, cfg.Pricing.MaxDiscountPercent)
+	assert.True(t, decimal.NewFromInt(70).Equal(*cfg.Pricing.MaxDiscountPercent))
+	require.NotNil(t, cfg.Pricing.Pipeline)
+	assert.Len(t, cfg.Pricing.Pipeline.Phases, 2)
+	assert.Len(t, cfg.ServiceOptions(), 4)
+
+	t.Setenv("DISCOUNTS_HTTP_ADDR", ":7070")
+	t.Setenv("DISCOUNTS_PRICING_ROUNDING", "floor")
+	cfg, err = config.Load(example)
+	require.NoError(t, err)
+	assert.Equal(t, ":7070", cfg.HTTP.Addr, "the environment overrides the file")
+	assert.Equal(t, models.RoundFloor, cfg.Pricing.Rounding)
+
+	t.Setenv("DISCOUNTS_CACHE_IDEMPOTENCY_TTL", "a day")
+	_, err = config.Load(example)
+	assert.ErrorContains(t, err, "DISCOUNTS_CACHE_IDEMPOTENCY_TTL")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, config.Default().Validate())
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("http:\n  adress: :8080\n"), 0o644))
+	_, err := config.Load(path)
+	assert.ErrorContains(t, err, "adress", "misspelt settings are not ignored")
+
+	cfg := config.Default()
+	cfg.Repository.Backend = config.BackendFile
+	cfg.Pricing.Rounding = "bankers"
+	over := decimal.NewFromInt(120)
+	cfg.Pricing.MaxDiscountPercent = &over
+	cfg.Pricing.Pipeline = &services.PipelineConfig{Phases: []services.PipelinePhase{{Name: "first", Types: []models.DiscountType{"coupon"}}}}
+	err = cfg.Validate()
+	require.Error(t, err)
+	// Every problem is reported at once, naming its setting
+	assert.ErrorContains(t, err, "repository.dir")
+	assert.ErrorContains(t, err, "pricing.rounding")
+	assert.ErrorContains(t, err, "pricing.pipeline")
+	assert.ErrorContains(t, err, "pricing.max_discount_percent")
+}
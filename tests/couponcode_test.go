@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/codegen"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestCodeGenerator(t *testing.T) {
+	gen := codegen.Generator{Prefix: "MAIL-", Length: 8, Checksum: true}
+
+	codes, err := gen.Generate(1000)
+	require.NoError(t, err)
+	require.Len(t, codes, 1000)
+
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		assert.False(t, seen[code], "duplicate code %s", code)
+		seen[code] = true
+		assert.True(t, strings.HasPrefix(code, "MAIL-"))
+		assert.Len(t, code, len("MAIL-")+8+1)
+		assert.True(t, gen.Valid(code), code)
+	}
+
+	t.Run("Checksum catches single typos", func(t *testing.T) {
+		code := []byte(codes[0])
+		i := len("MAIL-") + 3
+		for _, c := range []byte(codegen.DefaultCharset) {
+			if c == code[i] {
+				continue
+			}
+			typo := append([]byte(nil), code...)
+			typo[i] = c
+			assert.False(t, gen.Valid(string(typo)), string(typo))
+		}
+		assert.False(t, gen.Valid(strings.TrimPrefix(codes[0], "MAIL-")))
+		assert.False(t, gen.Valid(codes[0]+"X"))
+	})
+
+	t.Run("Rejects bad configurations", func(t *testing.T) {
+		assert.Error(t, (&codegen.Generator{Charset: "AAB"}).Validate())
+		assert.Error(t, (&codegen.Generator{Charset: "A"}).Validate())
+		assert.Error(t, (&codegen.Generator{Charset: "ABC", Checksum: true}).Validate())
+		_, err := (&codegen.Generator{Length: 2, Charset: "AB"}).Generate(3)
+		assert.Error(t, err)
+	})
+}
+
+func TestCouponCodes(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	template := models.Discount{
+		ID:             "disc-mailer",
+		Name:           "Mailer 100 off",
+		Type:           models.DiscountTypeVoucher,
+		Value:          decimal.NewFromInt(100),
+		GeneratedCodes: true,
+		ValidFrom:      now.Add(-time.Hour),
+		ValidTo:        now.Add(time.Hour),
+		IsActive:       true,
+	}
+	plain := template
+	plain.ID, plain.Name, plain.GeneratedCodes = "disc-plain", "Plain voucher", false
+
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &template))
+	require.NoError(t, repo.CreateDiscount(ctx, &plain))
+	codeRepo := repository.NewInMemoryCouponCodeRepository()
+
+	codeService := services.NewCouponCodeService(repo, codeRepo, clock.System())
+	codes, err := codeService.Mint(ctx, template.ID, 10000, codegen.Generator{Prefix: "ML", Checksum: true})
+	require.NoError(t, err)
+	require.Len(t, codes, 10000)
+
+	t.Run("Only templates mint codes", func(t *testing.T) {
+		_, err := codeService.Mint(ctx, plain.ID, 10, codegen.Generator{})
+		assert.True(t, errors.IsValidationError(err))
+		_, err = codeService.Mint(ctx, "missing", 10, codegen.Generator{})
+		assert.True(t, errors.IsNotFoundError(err))
+	})
+
+	service := services.NewDiscountService(repo, services.WithCouponCodeRepository(codeRepo))
+	customer := testdata.GetSampleCustomers()[1]
+	cartItems := []models.CartItem{{Product: testdata.GetSampleProducts()[3], Quantity: 1, Size: "32"}} // 1200
+	price := func(codes ...string) *models.DiscountedPrice {
+		t.Helper()
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{CartItems: cartItems, Customer: customer, Codes: codes})
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("Template needs one of its codes", func(t *testing.T) {
+		result := price()
+		assert.NotContains(t, result.AppliedDiscounts, template.Name)
+		assert.Contains(t, result.AppliedDiscounts, plain.Name)
+
+		valid, err := service.ValidateDiscountCode(ctx, codes[0], cartItems, customer)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("A code unlocks the template once", func(t *testing.T) {
+		result := price(codes[0])
+		assert.True(t, decimal.NewFromInt(100).Equal(result.AppliedDiscounts[template.Name]))
+
+		stored, err := codeRepo.GetCode(ctx, codes[0])
+		require.NoError(t, err)
+		assert.Equal(t, customer.ID, stored.RedeemedBy)
+
+		assert.NotContains(t, price(codes[0]).AppliedDiscounts, template.Name)
+		valid, err := service.ValidateDiscountCode(ctx, codes[0], cartItems, customer)
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Bulk validate and redeem", func(t *testing.T) {
+		statuses, err := codeService.BulkValidate(ctx, []string{codes[0], codes[1], "NOPE"})
+		require.NoError(t, err)
+		assert.Equal(t, []models.CouponCodeStatus{
+			{Code: codes[0], DiscountID: template.ID, State: models.CouponCodeRedeemed},
+			{Code: codes[1], DiscountID: template.ID, State: models.CouponCodeAvailable},
+			{Code: "NOPE", State: models.CouponCodeUnknown},
+		}, statuses)
+
+		statuses, err = codeService.BulkRedeem(ctx, "cust-009", []string{codes[0], codes[1], codes[1]})
+		require.NoError(t, err)
+		assert.False(t, statuses[0].RedeemedNow)
+		assert.True(t, statuses[1].RedeemedNow)
+		assert.False(t, statuses[2].RedeemedNow, "a code is only redeemed once per batch")
+		for _, status := range statuses {
+			assert.Equal(t, models.CouponCodeRedeemed, status.State)
+		}
+	})
+
+	t.Run("Codes are checked at the service clock unless a request pinned an instant", func(t *testing.T) {
+		later := services.NewCouponCodeService(repo, codeRepo, clock.NewFixed(now.Add(2*time.Hour)))
+		statuses, err := later.BulkValidate(ctx, []string{codes[2]})
+		require.NoError(t, err)
+		assert.Equal(t, models.CouponCodeUnavailable, statuses[0].State, "the template has expired by the service clock")
+
+		statuses, err = later.BulkValidate(clock.NewContext(ctx, now), []string{codes[2]})
+		require.NoError(t, err)
+		assert.Equal(t, models.CouponCodeAvailable, statuses[0].State)
+	})
+
+	t.Run("Inactive template makes codes unavailable", func(t *testing.T) {
+		inactive := template
+		inactive.IsActive = false
+		require.NoError(t, repo.UpdateDiscount(ctx, &inactive))
+
+		statuses, err := codeService.BulkValidate(ctx, []string{codes[2]})
+		require.NoError(t, err)
+		assert.Equal(t, models.CouponCodeUnavailable, statuses[0].State)
+	})
+}
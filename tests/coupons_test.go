@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/discounttest"
+)
+
+func TestDiscountService_GetAvailableCoupons(t *testing.T) {
+	ctx := context.Background()
+	customer := models.CustomerProfile{ID: "c1", Tier: "silver"}
+	cart := discounttest.Cart().Add(discounttest.Product("tee", "PUMA", "tshirts", "1000"), 2).Items()
+
+	single := discounttest.Discount("single", models.DiscountTypeVoucher).Percent("50").Code("SINGLE").Build()
+	single.GeneratedCodes = true
+	svc := services.NewDiscountService(discounttest.NewRepository(t,
+		discounttest.Discount("small", models.DiscountTypeVoucher).Fixed("100").Code("SMALL").Build(),
+		discounttest.Discount("big", models.DiscountTypeVoucher).Percent("20").Code("BIG").Build(),
+		discounttest.Discount("min", models.DiscountTypeVoucher).Fixed("500").MinAmount("2500").Code("MIN").Build(),
+		discounttest.Discount("nike", models.DiscountTypeVoucher).Percent("30").On("NIKE").Code("NIKE").Build(),
+		discounttest.Discount("gold", models.DiscountTypeVoucher).Percent("40").ForTiers("gold").Code("GOLD").Build(),
+		discounttest.Discount("puma", models.DiscountTypeBrand).Percent("10").On("PUMA").Build(),
+		single,
+	))
+
+	coupons, err := svc.GetAvailableCoupons(ctx, cart, customer)
+	require.NoError(t, err)
+	// Applicable codes by savings, then the near miss; other vouchers cannot be unlocked
+	var codes []string
+	for _, coupon := range coupons {
+		codes = append(codes, coupon.Code)
+	}
+	require.Equal(t, []string{"BIG", "SMALL", "MIN"}, codes)
+
+	assert.True(t, coupons[0].Valid)
+	assert.True(t, coupons[0].EstimatedSavings.Equal(decimal.NewFromInt(400)))
+	assert.Equal(t, "20% off", coupons[0].ValueSummary)
+	assert.Nil(t, coupons[0].NearMiss)
+
+	assert.False(t, coupons[2].Valid)
+	require.NotNil(t, coupons[2].NearMiss)
+	assert.Equal(t, models.ReasonMinAmountNotMet, coupons[2].NearMiss.Reason)
+	assert.True(t, coupons[2].NearMiss.AmountGap.Equal(decimal.NewFromInt(500)))
+	assert.True(t, coupons[2].EstimatedSavings.IsZero())
+
+	t.Run("Through the API", func(t *testing.T) {
+		h := api.NewHandler(svc)
+		rec, resp := doJSON(t, h, "/v2/coupons", nil, map[string]any{"cart_items": cart, "customer": customer})
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, resp["coupons"], 3)
+
+		rec, _ = doJSON(t, h, "/v1/coupons", nil, map[string]any{"cart_items": cart, "customer": customer})
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
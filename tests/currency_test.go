@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestCurrency_MinorUnits(t *testing.T) {
+	assert.True(t, models.INR.IsExact(decimal.RequireFromString("10.50")))
+	assert.False(t, models.INR.IsExact(decimal.RequireFromString("10.005")))
+	assert.False(t, models.JPY.IsExact(decimal.RequireFromString("10.5")))
+	assert.True(t, models.KWD.IsExact(decimal.RequireFromString("10.005")))
+	assert.True(t, decimal.RequireFromString("120.00").Equal(models.INR.Round(decimal.RequireFromString("119.995"))))
+	assert.True(t, decimal.NewFromInt(11).Equal(models.JPY.Round(decimal.RequireFromString("10.5"))))
+
+	assert.Error(t, models.Currency{Code: "XYZ", MinorUnits: 5}.Validate())
+	assert.Error(t, models.Currency{MinorUnits: 2}.Validate())
+	assert.NoError(t, models.KWD.Validate())
+}
+
+func TestDiscountRepository_RejectsAmountsFinerThanTheMinorUnit(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInMemoryDiscountRepository()
+
+	fixed := func(id, currency, value string) *models.Discount {
+		return &models.Discount{
+			ID:       id,
+			Name:     id,
+			Type:     models.DiscountTypeVoucher,
+			Value:    decimal.RequireFromString(value),
+			Currency: currency,
+			IsActive: true,
+		}
+	}
+
+	for _, d := range []*models.Discount{
+		fixed("inr-fraction", "", "10.005"),
+		fixed("jpy-fraction", "JPY", "10.5"),
+		fixed("unknown-currency", "XYZ", "10"),
+	} {
+		err := repo.CreateDiscount(ctx, d)
+		assert.True(t, errors.IsValidationError(err), "%s: %v", d.ID, err)
+	}
+
+	require.NoError(t, repo.CreateDiscount(ctx, fixed("inr-paise", "", "10.50")))
+	require.NoError(t, repo.CreateDiscount(ctx, fixed("jpy-whole", "JPY", "500")))
+
+	capped := fixed("capped", "", "10")
+	capped.IsPercentage = true
+	capped.Value = decimal.RequireFromString("12.5")
+	capped.MaxAmount = decimal.RequireFromString("99.999")
+	assert.True(t, errors.IsValidationError(repo.CreateDiscount(ctx, capped)), "percentages may be fractional, caps may not")
+}
+
+func TestDiscountService_RoundsToTheMinorUnit(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	repo := repository.NewInMemoryDiscountRepository()
+	for _, d := range []*models.Discount{
+		{ID: "pct", Name: "12.5% off", Value: decimal.RequireFromString("12.5"), IsPercentage: true, Priority: 2},
+		{ID: "yen", Name: "500 yen off", Value: decimal.NewFromInt(500), Currency: "JPY", Priority: 1},
+	} {
+		d.Type = models.DiscountTypeVoucher
+		d.ValidFrom, d.ValidTo, d.IsActive = now.Add(-time.Hour), now.Add(time.Hour), true
+		require.NoError(t, repo.CreateDiscount(ctx, d))
+	}
+
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.RequireFromString("999.99")}, Quantity: 1}}
+
+	result, err := services.NewDiscountService(repo).CalculateCart(ctx, &models.CalculationRequest{CartItems: cart})
+	require.NoError(t, err)
+	require.Len(t, result.Breakdown, 1, "the yen discount does not apply to a rupee cart")
+	assert.True(t, decimal.RequireFromString("125").Equal(result.Breakdown[0].Amount), "124.99875 rounds to whole paise")
+	assert.True(t, decimal.RequireFromString("874.99").Equal(result.FinalPrice))
+
+	explanation, err := services.NewDiscountService(repo).ExplainCartDiscounts(ctx, &models.CalculationRequest{CartItems: cart})
+	require.NoError(t, err)
+	decision, ok := explanation.Decision("yen")
+	require.True(t, ok)
+	assert.Equal(t, models.ReasonCurrencyMismatch, decision.Reason)
+
+	result, err = services.NewDiscountService(repo, services.WithCurrency(models.JPY)).CalculateCart(ctx, &models.CalculationRequest{
+		CartItems: []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(2999)}, Quantity: 1}},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Breakdown, 1)
+	assert.Equal(t, "yen", result.Breakdown[0].DiscountID)
+	assert.True(t, decimal.NewFromInt(2499).Equal(result.FinalPrice))
+}
+
+func TestDiscountService_RoundingOverride(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	repo := repository.NewInMemoryDiscountRepository()
+	for _, d := range []*models.Discount{
+		{ID: "promo", Type: models.DiscountTypeVoucher, Value: decimal.RequireFromString("12.5"), Priority: 2},
+		{ID: "bank", Type: models.DiscountTypeBank, Value: decimal.NewFromInt(10), Rounding: models.RoundFloor, Priority: 1},
+	} {
+		d.Name, d.IsPercentage = d.ID, true
+		d.ValidFrom, d.ValidTo, d.IsActive = now.Add(-time.Hour), now.Add(time.Hour), true
+		require.NoError(t, repo.CreateDiscount(ctx, d))
+	}
+	assert.True(t, errors.IsValidationError(repo.CreateDiscount(ctx, &models.Discount{ID: "odd", Rounding: "truncate"})))
+
+	req := &models.CalculationRequest{
+		CartItems:   []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.RequireFromString("999.99")}, Quantity: 1}},
+		PaymentInfo: &models.PaymentInfo{Method: models.Card},
+	}
+
+	result, err := services.NewDiscountService(repo).CalculateCart(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, result.Breakdown, 2)
+	assert.True(t, decimal.RequireFromString("125").Equal(result.Breakdown[0].Amount), "124.99875 rounds half-up")
+	assert.Equal(t, models.RoundHalfUp, result.Breakdown[0].Rounding)
+	assert.True(t, decimal.RequireFromString("87.49").Equal(result.Breakdown[1].Amount), "the bank's 87.499 is floored")
+	assert.Equal(t, models.RoundFloor, result.Breakdown[1].Rounding)
+	assert.True(t, result.Breakdown[1].Amount.Equal(result.AppliedDiscounts["bank"]))
+	assert.True(t, decimal.RequireFromString("787.50").Equal(result.FinalPrice))
+
+	result, err = services.NewDiscountService(repo, services.WithRounding(models.RoundCeil)).CalculateCart(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, result.Breakdown, 2)
+	assert.True(t, decimal.RequireFromString("125").Equal(result.Breakdown[0].Amount))
+	assert.Equal(t, models.RoundCeil, result.Breakdown[0].Rounding)
+	assert.Equal(t, models.RoundFloor, result.Breakdown[1].Rounding, "the discount's own rounding wins")
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscountService_CustomerBoundVouchers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID: "acme-staff", Name: "Acme staff", Type: models.DiscountTypeVoucher, Code: "ACME20",
+		Value: decimal.NewFromInt(20), IsPercentage: true,
+		ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true,
+		AllowedCustomerIDs: []string{"founder"}, AllowedEmailDomains: []string{"@acme.com"},
+	}))
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 1}}
+	price := func(customer models.CustomerProfile) decimal.Decimal {
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{CartItems: cart, Customer: customer, Codes: []string{"ACME20"}})
+		require.NoError(t, err)
+		return result.FinalPrice
+	}
+
+	t.Run("Customers at the domain or named get the voucher", func(t *testing.T) {
+		for _, customer := range []models.CustomerProfile{
+			{ID: "c1", Email: "jo@acme.com"},
+			{ID: "c2", Email: "Jo@ACME.com"},
+			{ID: "founder"},
+		} {
+			validation, err := service.DescribeDiscountCode(ctx, "ACME20", cart, customer)
+			require.NoError(t, err)
+			assert.True(t, validation.Valid, customer)
+			assert.Equal(t, "acme-staff", validation.DiscountID)
+			assert.True(t, decimal.NewFromInt(800).Equal(price(customer)), customer)
+		}
+	})
+
+	t.Run("Other customers are not told about it", func(t *testing.T) {
+		for _, customer := range []models.CustomerProfile{
+			{ID: "c3", Email: "jo@mail.acme.com"},
+			{ID: "c4", Email: "acme.com@example.com"},
+			{ID: "c5"},
+		} {
+			validation, err := service.DescribeDiscountCode(ctx, "ACME20", cart, customer)
+			require.NoError(t, err)
+			assert.False(t, validation.Valid, customer)
+			assert.Empty(t, validation.DiscountID, customer)
+			assert.True(t, decimal.NewFromInt(1000).Equal(price(customer)), customer)
+		}
+	})
+
+	t.Run("Explanations say the code is reserved", func(t *testing.T) {
+		explanation, err := service.ExplainCartDiscounts(ctx, &models.CalculationRequest{
+			CartItems: cart, Customer: models.CustomerProfile{ID: "c5", Email: "jo@example.com"},
+		})
+		require.NoError(t, err)
+		decision, ok := explanation.Decision("acme-staff")
+		require.True(t, ok)
+		assert.Equal(t, models.ReasonCustomerIneligible, decision.Reason)
+		assert.Contains(t, decision.Detail, "reserved")
+	})
+
+	t.Run("The store validates bindings", func(t *testing.T) {
+		for name, d := range map[string]models.Discount{
+			"not a voucher": {ID: "b1", Type: models.DiscountTypeBrand, AllowedEmailDomains: []string{"acme.com"}},
+			"empty domain":  {ID: "b2", Type: models.DiscountTypeVoucher, AllowedEmailDomains: []string{""}},
+			"an address":    {ID: "b3", Type: models.DiscountTypeVoucher, AllowedEmailDomains: []string{"jo@acme.com"}},
+			"no dot":        {ID: "b4", Type: models.DiscountTypeVoucher, AllowedEmailDomains: []string{"acme"}},
+			"empty id":      {ID: "b5", Type: models.DiscountTypeVoucher, AllowedCustomerIDs: []string{" "}},
+		} {
+			err := repo.CreateDiscount(ctx, &d)
+			assert.True(t, errors.IsValidationError(err), name)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/discount"
+	"github.com/ahsmha/discounts/pkg/discounttest"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscountService_DeadlineExceeded(t *testing.T) {
+	cart := discounttest.Cart().Add(discounttest.Product("tee", "PUMA", "tshirts", "1000"), 1)
+	repo := discounttest.NewRepository(t,
+		discounttest.Discount("first", models.DiscountTypeBrand).Fixed("100").On("PUMA").Priority(3).Build(),
+		discounttest.Discount("second", models.DiscountTypeBrand).Fixed("100").On("PUMA").Priority(2).Build(),
+		discounttest.Discount("third", models.DiscountTypeBrand).Fixed("100").On("PUMA").Priority(1).Build(),
+	)
+
+	// The second discount's evaluation outlasts the deadline
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slow := &discounttest.Strategy{
+		CalculateFunc: func(d *discount.Discount, _ []discount.CartItem, total decimal.Decimal) decimal.Decimal {
+			if d.ID == "second" {
+				cancel()
+			}
+			return decimal.Min(decimal.NewFromInt(100), total)
+		},
+	}
+	svc := services.NewDiscountService(repo, services.WithStrategy(models.DiscountTypeBrand, slow))
+
+	_, err := svc.CalculateCart(ctx, cart.Request())
+	require.True(t, errors.IsDeadlineExceededError(err), err)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	var stopped errors.DeadlineExceededError
+	require.ErrorAs(t, err, &stopped)
+	assert.Equal(t, "applying discounts", stopped.Stage)
+	assert.Equal(t, 2, stopped.Considered)
+	assert.Equal(t, 3, stopped.Total)
+	assert.Equal(t, []string{"first", "second"}, stopped.Applied)
+
+	first, err := repo.GetDiscountByID(context.Background(), "first")
+	require.NoError(t, err)
+	assert.Zero(t, first.UsedCount, "stopped calculations commit nothing")
+
+	t.Run("The API answers 504", func(t *testing.T) {
+		body, err := json.Marshal(cart.Request())
+		require.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req := httptest.NewRequest(http.MethodPost, "/v2/cart/calculate", bytes.NewReader(body)).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		api.NewHandler(services.NewDiscountService(repo)).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusGatewayTimeout, rec.Code, rec.Body.String())
+		assert.Contains(t, rec.Body.String(), "calculation stopped while")
+	})
+}
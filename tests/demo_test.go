@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/demo"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDemoScenarios(t *testing.T) {
+	ctx := context.Background()
+	run := func(name string) *models.Explanation {
+		t.Helper()
+		outcome, err := demo.Run(ctx, name)
+		require.NoError(t, err)
+		return outcome.Explanation
+	}
+
+	t.Run("multiple-discount", func(t *testing.T) {
+		explanation := run("multiple-discount")
+		assert.True(t, decimal.NewFromInt(2000).Equal(explanation.Result.OriginalPrice))
+		assert.True(t, decimal.NewFromInt(900).Equal(explanation.Result.FinalPrice), explanation.Result.FinalPrice.String())
+		for id, amount := range map[string]int64{"demo-puma": 800, "demo-tshirts": 200, "demo-icici": 100} {
+			decision, ok := explanation.Decision(id)
+			require.True(t, ok, id)
+			assert.Equal(t, models.DecisionApplied, decision.Outcome, id)
+			assert.True(t, decimal.NewFromInt(amount).Equal(decision.Amount), id)
+		}
+	})
+
+	t.Run("cap-hit", func(t *testing.T) {
+		explanation := run("cap-hit")
+		assert.True(t, decimal.NewFromInt(9500).Equal(explanation.Result.FinalPrice), explanation.Result.FinalPrice.String())
+		decision, ok := explanation.Decision("demo-watch-week")
+		require.True(t, ok)
+		assert.True(t, decimal.NewFromInt(500).Equal(decision.Amount))
+	})
+
+	t.Run("exclusivity-conflict", func(t *testing.T) {
+		explanation := run("exclusivity-conflict")
+		assert.True(t, decimal.NewFromInt(3000).Equal(explanation.Result.FinalPrice), explanation.Result.FinalPrice.String())
+		decision, ok := explanation.Decision("demo-zara-winter")
+		require.True(t, ok)
+		assert.Equal(t, models.DecisionApplied, decision.Outcome)
+		decision, ok = explanation.Decision("demo-jackets-winter")
+		require.True(t, ok)
+		assert.Equal(t, models.DecisionSkipped, decision.Outcome)
+		assert.Equal(t, models.ReasonBudgetExhausted, decision.Reason)
+	})
+
+	t.Run("Runs are repeatable", func(t *testing.T) {
+		first, second := run("exclusivity-conflict"), run("exclusivity-conflict")
+		assert.Equal(t, first.Result.Breakdown, second.Result.Breakdown)
+	})
+
+	t.Run("Unknown scenario", func(t *testing.T) {
+		_, err := demo.Run(ctx, "nope")
+		assert.True(t, errors.IsNotFoundError(err))
+	})
+}
+
+func TestDemoHandler(t *testing.T) {
+	h := demo.NewHandler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/demo/scenarios", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Scenarios []struct{ Name string } `json:"scenarios"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Scenarios, 3)
+	assert.Equal(t, "multiple-discount", list.Scenarios[0].Name)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/demo/scenarios/cap-hit", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var outcome struct {
+		Name        string `json:"name"`
+		Explanation struct {
+			Decisions []models.DiscountDecision `json:"decisions"`
+		} `json:"explanation"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &outcome))
+	assert.Equal(t, "cap-hit", outcome.Name)
+	assert.NotEmpty(t, outcome.Explanation.Decisions)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/demo/scenarios/nope", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
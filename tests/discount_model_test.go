@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestDiscount_MatchesProduct_TypedEntries(t *testing.T) {
+	puma := testdata.GetSampleProducts()[0]
+	puma.SKU = "TS-PUMA-M-BLK"
+	clearance := puma
+	clearance.ID = "prod-clearance"
+	clearance.SKU = "TS-PUMA-CLEAR"
+
+	brand := testdata.GetSampleDiscounts()[0] // PUMA 40%
+
+	tests := []struct {
+		name          string
+		applicableTo  []string
+		excludedItems []string
+		product       models.Product
+		expected      bool
+	}{
+		{name: "Untyped brand entry", applicableTo: []string{"PUMA"}, product: puma, expected: true},
+		{name: "Excluded SKU", applicableTo: []string{"PUMA"}, excludedItems: []string{models.ItemRef(models.ItemRefSKU, "TS-PUMA-CLEAR")}, product: clearance, expected: false},
+		{name: "Other SKUs of the brand still match", applicableTo: []string{"PUMA"}, excludedItems: []string{models.ItemRef(models.ItemRefSKU, "TS-PUMA-CLEAR")}, product: puma, expected: true},
+		{name: "Excluded product id", applicableTo: []string{"PUMA"}, excludedItems: []string{"product:prod-001"}, product: puma, expected: false},
+		{name: "Legacy category exclusion", applicableTo: []string{"PUMA"}, excludedItems: []string{"T-shirts"}, product: puma, expected: false},
+		{name: "Applicable to a single product", applicableTo: []string{"product:prod-clearance"}, product: clearance, expected: true},
+		{name: "Applicable to a single product only", applicableTo: []string{"product:prod-clearance"}, product: puma, expected: false},
+		{name: "Product without SKU never matches a SKU entry", applicableTo: []string{"sku:"}, product: testdata.GetSampleProducts()[1], expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := brand
+			d.ApplicableTo = tt.applicableTo
+			d.ExcludedItems = tt.excludedItems
+			assert.Equal(t, tt.expected, d.MatchesProduct(tt.product))
+		})
+	}
+}
+
+func TestDiscount_WildcardPatterns(t *testing.T) {
+	products := testdata.GetSampleProducts()
+	products[0].SKU = "TS-PUMA-M-BLK"
+
+	category := testdata.GetSampleDiscounts()[1]
+	category.ApplicableTo = []string{"T-*"}
+	category.ExcludedItems = []string{"sku:*-BLK"}
+	category.Compile()
+
+	assert.False(t, category.MatchesProduct(products[0]), "black variants are excluded by SKU pattern")
+	assert.True(t, category.MatchesProduct(products[2]), "T-shirts match the category prefix")
+	assert.False(t, category.MatchesProduct(products[1]), "Shoes do not match T-*")
+
+	// Edits made after compiling are picked up
+	category.ApplicableTo = []string{"Sho?s"}
+	assert.True(t, category.MatchesProduct(products[1]))
+
+	bank := testdata.GetSampleDiscounts()[2]
+	bank.ApplicableTo = []string{"ICICI*"}
+	bank.Compile()
+	assert.True(t, bank.MatchesApplicableValue("ICICI"))
+	assert.True(t, bank.MatchesApplicableValue("ICICI Amazon Pay"))
+	assert.False(t, bank.MatchesApplicableValue("HDFC"))
+	assert.False(t, bank.MatchesApplicableValue("MY ICICI"))
+}
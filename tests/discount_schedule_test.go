@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestDiscount_IsValidAt_Schedule(t *testing.T) {
+	// Friday 2025-01-03 in Asia/Kolkata
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+
+	discount := testdata.GetSampleDiscounts()[0]
+	discount.ValidFrom = time.Date(2025, 1, 1, 0, 0, 0, 0, ist)
+	discount.ValidTo = time.Date(2025, 2, 1, 0, 0, 0, 0, ist)
+
+	tests := []struct {
+		name     string
+		schedule models.Schedule
+		at       time.Time
+		expected bool
+	}{
+		{
+			name:     "Weekend rule on a Friday",
+			schedule: models.Schedule{Timezone: "Asia/Kolkata", Rules: []models.ScheduleRule{{Days: []time.Weekday{time.Saturday, time.Sunday}}}},
+			at:       time.Date(2025, 1, 3, 12, 0, 0, 0, ist),
+			expected: false,
+		},
+		{
+			name:     "Weekend rule on a Saturday",
+			schedule: models.Schedule{Timezone: "Asia/Kolkata", Rules: []models.ScheduleRule{{Days: []time.Weekday{time.Saturday, time.Sunday}}}},
+			at:       time.Date(2025, 1, 4, 12, 0, 0, 0, ist),
+			expected: true,
+		},
+		{
+			name: "Happy hour evaluated in the schedule timezone",
+			schedule: models.Schedule{Timezone: "Asia/Kolkata", Rules: []models.ScheduleRule{
+				{TimeRanges: []models.TimeOfDayRange{{Start: "17:00", End: "19:00"}}},
+			}},
+			at:       time.Date(2025, 1, 3, 12, 0, 0, 0, time.UTC), // 17:30 IST
+			expected: true,
+		},
+		{
+			name: "Overnight range wraps midnight",
+			schedule: models.Schedule{Rules: []models.ScheduleRule{
+				{TimeRanges: []models.TimeOfDayRange{{Start: "22:00", End: "02:00"}}},
+			}},
+			at:       time.Date(2025, 1, 3, 1, 30, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "Cron on Friday evenings",
+			schedule: models.Schedule{Timezone: "Asia/Kolkata", Rules: []models.ScheduleRule{{Cron: "* 18-20 * * 5"}}},
+			at:       time.Date(2025, 1, 3, 20, 59, 0, 0, ist),
+			expected: true,
+		},
+		{
+			name:     "Cron outside its hours",
+			schedule: models.Schedule{Timezone: "Asia/Kolkata", Rules: []models.ScheduleRule{{Cron: "*/15 18-20 * * 5"}}},
+			at:       time.Date(2025, 1, 3, 18, 16, 0, 0, ist),
+			expected: false,
+		},
+		{
+			name:     "Schedule never extends the validity window",
+			schedule: models.Schedule{Rules: []models.ScheduleRule{{Cron: "* * * * *"}}},
+			at:       time.Date(2025, 3, 1, 0, 0, 0, 0, ist),
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := discount
+			schedule := tt.schedule
+			d.Schedule = &schedule
+			assert.Equal(t, tt.expected, d.IsValidAt(tt.at))
+		})
+	}
+}
+
+func TestDiscountRepository_RejectsInvalidSchedule(t *testing.T) {
+	repo := repository.NewInMemoryDiscountRepository()
+	discount := testdata.GetSampleDiscounts()[0]
+
+	for _, schedule := range []models.Schedule{
+		{Timezone: "Mars/Olympus", Rules: []models.ScheduleRule{{Days: []time.Weekday{time.Monday}}}},
+		{Rules: []models.ScheduleRule{{Cron: "61 * * * *"}}},
+		{Rules: []models.ScheduleRule{{TimeRanges: []models.TimeOfDayRange{{Start: "9am", End: "10:00"}}}}},
+		{},
+	} {
+		d := discount
+		s := schedule
+		d.Schedule = &s
+		err := repo.CreateDiscount(context.Background(), &d)
+		assert.True(t, errors.IsValidationError(err), "expected validation error for %+v, got %v", schedule, err)
+
+		err = repo.(interfaces.DiscountSeeder).SeedDiscounts([]models.Discount{d})
+		assert.True(t, errors.IsValidationError(err), "seeds are checked too: %+v, got %v", schedule, err)
+	}
+}
+
+func TestDiscount_CompiledSchedule(t *testing.T) {
+	friday := time.Date(2024, 6, 7, 19, 0, 0, 0, time.UTC)
+	discount := testdata.GetSampleDiscounts()[0]
+	discount.ValidFrom, discount.ValidTo = friday.Add(-24*time.Hour), friday.Add(24*time.Hour)
+	discount.Schedule = &models.Schedule{Rules: []models.ScheduleRule{{Cron: "* 18-20 * * 5"}}}
+
+	discount.Compile()
+	assert.True(t, discount.IsValidAt(friday))
+
+	discount.Schedule.Rules[0].Cron = "* 9-11 * * 5"
+	assert.False(t, discount.IsValidAt(friday), "schedules edited after compiling are parsed again")
+}
@@ -2,7 +2,9 @@ package tests
 
 import (
 	"context"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
@@ -12,6 +14,8 @@ import (
 	"github.com/ahsmha/discounts/internal/models"
 	repository "github.com/ahsmha/discounts/internal/repositories"
 	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
 	"github.com/ahsmha/discounts/testdata"
 )
 
@@ -181,8 +185,8 @@ func TestDiscountService_CalculateCartDiscounts(t *testing.T) {
 			},
 			customer:           testdata.GetSampleCustomers()[0],
 			paymentInfo:        &testdata.GetSamplePaymentInfo()[0],
-			expectedFinalPrice: decimal.NewFromFloat(344.25),
-			expectedDiscounts:  3,
+			expectedFinalPrice: decimal.NewFromFloat(382.5), // The ICICI offer needs 1000
+			expectedDiscounts:  2,
 			expectError:        false,
 		},
 	}
@@ -231,7 +235,7 @@ func TestDiscountService_ValidateDiscountCode(t *testing.T) {
 	cartItems := []models.CartItem{
 		{
 			Product:  testdata.GetSampleProducts()[0], // PUMA T-shirt
-			Quantity: 1,
+			Quantity: 4,                               // 4 x 1000 less the 40% PUMA discount is 2400, clearing SUPER69's 2000 minimum
 			Size:     "M",
 		},
 	}
@@ -402,3 +406,266 @@ func TestDiscountService_Integration_MultipleDiscountScenario(t *testing.T) {
 	t.Logf("Applied Discounts: %v", result.AppliedDiscounts)
 	t.Logf("Message: %s", result.Message)
 }
+
+func TestDiscountService_CounterfactualPrices(t *testing.T) {
+	repo := repository.NewInMemoryDiscountRepository()
+	memoryRepo := repo.(*repository.InMemoryDiscountRepository)
+	err := memoryRepo.SeedDiscounts(testdata.GetSampleDiscounts())
+	require.NoError(t, err)
+
+	service := services.NewDiscountService(repo, services.WithCounterfactualPrices())
+	ctx := context.Background()
+
+	cartItems, customer, paymentInfo := testdata.GetMultipleDiscountScenario()
+	ResetCartPricesToBase(cartItems)
+
+	result, err := service.CalculateCartDiscounts(ctx, cartItems, customer, paymentInfo)
+	require.NoError(t, err)
+	require.Len(t, result.PriceWithout, len(result.AppliedDiscounts))
+
+	// Without the PUMA brand discount the category cap (200) and bank cap (500) still hold:
+	// 2000 - 200 = 1800, bank 10% = 180 -> 1620, SUPER69 69% capped at 1000 -> 620, PREMIUM15 15% = 93 -> 527
+	withoutBrand := result.PriceWithout["PUMA Brand Discount - Min 40% off"]
+	assert.True(t, decimal.NewFromInt(527).Equal(withoutBrand),
+		"Expected counterfactual price 527 but got %s", withoutBrand.String())
+
+	for name := range result.AppliedDiscounts {
+		contribution := result.GetMarginalContribution(name)
+		assert.True(t, contribution.GreaterThan(decimal.Zero),
+			"Discount %s should have a positive marginal contribution, got %s", name, contribution.String())
+	}
+
+	// Counterfactual pricing must not be reported unless requested
+	plain := services.NewDiscountService(repo)
+	result, err = plain.CalculateCartDiscounts(ctx, cartItems, customer, paymentInfo)
+	require.NoError(t, err)
+	assert.Nil(t, result.PriceWithout)
+}
+
+func TestDiscountService_CalculateCart_ExpectedTotal(t *testing.T) {
+	repo := repository.NewInMemoryDiscountRepository()
+	memoryRepo := repo.(*repository.InMemoryDiscountRepository)
+	require.NoError(t, memoryRepo.SeedDiscounts(testdata.GetSampleDiscounts()))
+
+	service := services.NewDiscountService(repo)
+	ctx := context.Background()
+
+	cartItems, customer, paymentInfo := testdata.GetMultipleDiscountScenario()
+	ResetCartPricesToBase(cartItems) // 2 x 1000
+
+	tests := []struct {
+		name          string
+		expectedTotal decimal.Decimal
+		expectError   bool
+	}{
+		{name: "Matching total", expectedTotal: decimal.NewFromInt(2000)},
+		{name: "Within tolerance", expectedTotal: decimal.NewFromFloat(1999.99)},
+		{name: "Stale client price", expectedTotal: decimal.NewFromInt(1200), expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := tt.expectedTotal
+			result, err := service.CalculateCart(ctx, &models.CalculationRequest{
+				CartItems:     cartItems,
+				Customer:      customer,
+				PaymentInfo:   paymentInfo,
+				ExpectedTotal: &expected,
+			})
+
+			if tt.expectError {
+				require.Error(t, err)
+				assert.True(t, errors.IsTotalMismatchError(err))
+				var mismatch errors.TotalMismatchError
+				require.ErrorAs(t, err, &mismatch)
+				assert.True(t, decimal.NewFromInt(2000).Equal(mismatch.Actual))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, decimal.NewFromInt(2000).Equal(result.OriginalPrice))
+		})
+	}
+}
+
+func TestDiscountService_FrozenClock(t *testing.T) {
+	repo := repository.NewInMemoryDiscountRepository()
+	memoryRepo := repo.(*repository.InMemoryDiscountRepository)
+
+	// Sample discounts are valid from a day ago for the next 30 days
+	require.NoError(t, memoryRepo.SeedDiscounts(testdata.GetSampleDiscounts()))
+
+	frozen := clock.NewFixed(time.Now())
+	service := services.NewDiscountService(repo, services.WithClock(frozen))
+	ctx := context.Background()
+
+	cartItems, customer, paymentInfo := testdata.GetMultipleDiscountScenario()
+	ResetCartPricesToBase(cartItems)
+
+	result, err := service.CalculateCartDiscounts(ctx, cartItems, customer, paymentInfo)
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.AppliedDiscounts)
+
+	valid, err := service.ValidateDiscountCode(ctx, "PREMIUM15", cartItems, customer)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	// Past every discount's ValidTo nothing applies, even though they are still live on the wall clock
+	frozen.Advance(31 * 24 * time.Hour)
+
+	result, err = service.CalculateCartDiscounts(ctx, cartItems, customer, paymentInfo)
+	require.NoError(t, err)
+	assert.Empty(t, result.AppliedDiscounts)
+	assert.True(t, result.FinalPrice.Equal(result.OriginalPrice))
+
+	valid, err = service.ValidateDiscountCode(ctx, "PREMIUM15", cartItems, customer)
+	require.NoError(t, err)
+	assert.False(t, valid)
+}
+
+func TestDiscountService_CalculateCart_ValidationModes(t *testing.T) {
+	repo := repository.NewInMemoryDiscountRepository()
+	memoryRepo := repo.(*repository.InMemoryDiscountRepository)
+	require.NoError(t, memoryRepo.SeedDiscounts(testdata.GetSampleDiscounts()))
+	ctx := context.Background()
+
+	products := testdata.GetSampleProducts()
+	negative := products[2]
+	negative.CurrentPrice = decimal.NewFromInt(-100)
+
+	cartItems := []models.CartItem{
+		{Product: products[3], Quantity: 1, Size: "32"}, // Zara jeans, 1200
+		{Product: products[1], Quantity: 0, Size: "42"},
+		{Product: negative, Quantity: 1, Size: "L"},
+	}
+	customer := testdata.GetSampleCustomers()[1]
+
+	t.Run("Strict mode rejects the cart", func(t *testing.T) {
+		service := services.NewDiscountService(repo)
+		_, err := service.CalculateCart(ctx, &models.CalculationRequest{CartItems: cartItems, Customer: customer})
+		require.Error(t, err)
+		assert.True(t, errors.IsValidationError(err))
+		assert.Contains(t, err.Error(), "invalid cart item 1")
+	})
+
+	t.Run("Lenient service default skips offending items", func(t *testing.T) {
+		service := services.NewDiscountService(repo, services.WithValidationMode(models.ValidationLenient))
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{CartItems: cartItems, Customer: customer})
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(1200).Equal(result.OriginalPrice))
+		assert.Len(t, result.Warnings, 2)
+	})
+
+	t.Run("Request overrides the service default", func(t *testing.T) {
+		service := services.NewDiscountService(repo)
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems:      cartItems,
+			Customer:       customer,
+			ValidationMode: models.ValidationLenient,
+		})
+		require.NoError(t, err)
+		assert.Len(t, result.Warnings, 2)
+	})
+
+	t.Run("Lenient mode still fails when nothing is left", func(t *testing.T) {
+		service := services.NewDiscountService(repo, services.WithValidationMode(models.ValidationLenient))
+		_, err := service.CalculateCart(ctx, &models.CalculationRequest{CartItems: cartItems[1:], Customer: customer})
+		assert.True(t, errors.IsValidationError(err))
+	})
+}
+
+func TestDiscountService_FirstOrderVoucher(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	welcome := models.Discount{
+		ID:             "disc-welcome",
+		Name:           "WELCOME10 - 10% off your first order",
+		Type:           models.DiscountTypeVoucher,
+		Value:          decimal.NewFromInt(10),
+		IsPercentage:   true,
+		Code:           "WELCOME10",
+		ValidFrom:      now.Add(-time.Hour),
+		ValidTo:        now.Add(time.Hour),
+		IsActive:       true,
+		FirstOrderOnly: true,
+	}
+	maxTwo := 2
+	loyal := welcome
+	loyal.ID, loyal.Code, loyal.FirstOrderOnly, loyal.MaxPreviousOrders = "disc-early", "EARLY5", false, &maxTwo
+
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &welcome))
+	require.NoError(t, repo.CreateDiscount(ctx, &loyal))
+	service := services.NewDiscountService(repo)
+
+	cartItems := []models.CartItem{{Product: testdata.GetSampleProducts()[3], Quantity: 1, Size: "32"}}
+
+	tests := []struct {
+		name       string
+		code       string
+		orderCount int
+		expected   bool
+	}{
+		{name: "New customer gets the welcome code", code: "WELCOME10", orderCount: 0, expected: true},
+		{name: "Returning customer does not", code: "WELCOME10", orderCount: 1, expected: false},
+		{name: "Within the previous order limit", code: "EARLY5", orderCount: 2, expected: true},
+		{name: "Past the previous order limit", code: "EARLY5", orderCount: 3, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customer := models.CustomerProfile{ID: "cust-new", Tier: "regular", OrderCount: tt.orderCount}
+			valid, err := service.ValidateDiscountCode(ctx, tt.code, cartItems, customer)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, valid)
+		})
+	}
+}
+
+func TestDiscountService_UsageLimitUnderConcurrentCheckouts(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID:         "disc-last-one",
+		Name:       "Last one 100 off",
+		Type:       models.DiscountTypeVoucher,
+		Value:      decimal.NewFromInt(100),
+		UsageLimit: 1,
+		ValidFrom:  now.Add(-time.Hour),
+		ValidTo:    now.Add(time.Hour),
+		IsActive:   true,
+	}))
+	service := services.NewDiscountService(repo)
+
+	req := &models.CalculationRequest{
+		CartItems: []models.CartItem{{Product: testdata.GetSampleProducts()[3], Quantity: 1, Size: "32"}},
+		Customer:  testdata.GetSampleCustomers()[1],
+	}
+
+	const checkouts = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, checkouts)
+	for i := 0; i < checkouts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.CalculateCart(ctx, req)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			assert.True(t, errors.IsConflictError(err), "got %v", err)
+		}
+	}
+
+	stored, err := repo.GetDiscountByID(ctx, "disc-last-one")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.UsedCount, "the discount is never redeemed past its limit")
+}
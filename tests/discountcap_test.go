@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/discounttest"
+)
+
+func TestDiscountService_MaxDiscountPercent(t *testing.T) {
+	ctx := context.Background()
+	cart := discounttest.Cart().Add(discounttest.Product("tee", "PUMA", "tshirts", "1000"), 1).PaidByCard("HDFC", models.Credit)
+	// 500, then 100 and 100 off what is left, then 10% of the remaining 300: 730 in all
+	stack := func(t *testing.T) *discounttest.Repository {
+		return discounttest.NewRepository(t,
+			discounttest.Discount("puma", models.DiscountTypeBrand).Percent("50").On("PUMA").Priority(3).Build(),
+			discounttest.Discount("a", models.DiscountTypeVoucher).Percent("20").Priority(1).Build(),
+			discounttest.Discount("b", models.DiscountTypeVoucher).Fixed("100").Priority(1).Build(),
+			discounttest.Discount("bank", models.DiscountTypeBank).Percent("10").On("HDFC").Build(),
+		)
+	}
+	amounts := func(result *models.DiscountedPrice) map[string]int64 {
+		out := map[string]int64{}
+		for _, line := range result.Breakdown {
+			out[line.DiscountID] = line.Amount.IntPart()
+		}
+		return out
+	}
+
+	t.Run("Lowest priorities are scaled back", func(t *testing.T) {
+		repo := stack(t)
+		svc := services.NewDiscountService(repo,
+			services.WithMaxDiscountPercent(decimal.NewFromInt(60)), services.WithInvariantChecks(nil))
+
+		result, err := svc.CalculateCart(ctx, cart.Request())
+		require.NoError(t, err)
+		// 130 over the cap of 600: the bank offer goes, then the vouchers share the last 100
+		assert.True(t, result.FinalPrice.Equal(decimal.NewFromInt(400)), result.FinalPrice.String())
+		assert.Equal(t, map[string]int64{"puma": 500, "a": 50, "b": 50}, amounts(result))
+		assert.True(t, result.GetTotalDiscount().Equal(decimal.NewFromInt(600)))
+
+		var adjusted []string
+		for _, adj := range result.DiscountCapAdjustments {
+			adjusted = append(adjusted, adj.DiscountID+":"+adj.Requested.String()+"->"+adj.Applied.String())
+		}
+		assert.Equal(t, []string{"bank:30->0", "b:100->50", "a:100->50"}, adjusted)
+
+		// The dropped discount is not redeemed
+		bank, err := repo.GetDiscountByID(ctx, "bank")
+		require.NoError(t, err)
+		assert.Zero(t, bank.UsedCount)
+		voucher, err := repo.GetDiscountByID(ctx, "a")
+		require.NoError(t, err)
+		assert.Equal(t, 1, voucher.UsedCount)
+	})
+
+	t.Run("Explanations report the cap", func(t *testing.T) {
+		svc := services.NewDiscountService(stack(t), services.WithMaxDiscountPercent(decimal.NewFromInt(60)))
+		explanation, err := svc.ExplainCartDiscounts(ctx, cart.Request())
+		require.NoError(t, err)
+
+		bank, ok := explanation.Decision("bank")
+		require.True(t, ok)
+		assert.Equal(t, models.DecisionSkipped, bank.Outcome)
+		assert.Equal(t, models.ReasonDiscountCap, bank.Reason)
+		voucher, _ := explanation.Decision("a")
+		assert.Equal(t, models.DecisionApplied, voucher.Outcome)
+		assert.True(t, voucher.Amount.Equal(decimal.NewFromInt(50)))
+	})
+
+	t.Run("Carts under the cap are untouched", func(t *testing.T) {
+		svc := services.NewDiscountService(stack(t), services.WithMaxDiscountPercent(decimal.NewFromInt(75)))
+		result, err := svc.CalculateCart(ctx, cart.Request())
+		require.NoError(t, err)
+		assert.True(t, result.FinalPrice.Equal(decimal.NewFromInt(270)))
+		assert.Empty(t, result.DiscountCapAdjustments)
+	})
+
+	assert.Error(t, models.CheckMaxDiscountPercent(decimal.NewFromInt(101)))
+	assert.NoError(t, models.CheckMaxDiscountPercent(decimal.NewFromInt(70)))
+}
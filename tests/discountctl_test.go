@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/cli"
+	"github.com/ahsmha/discounts/internal/lint"
+)
+
+func runDiscountctl(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := cli.NewRootCommand()
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+	err := cmd.ExecuteContext(context.Background())
+	return out.String(), err
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestDiscountctl_ManageDiscounts(t *testing.T) {
+	dir := t.TempDir()
+	store := filepath.Join(dir, "discounts.json")
+	discount := writeFile(t, dir, "flat.json", `{
+		"id": "flat-50", "name": "Flat 50", "type": "voucher", "value": "50",
+		"is_active": true, "valid_from": "2020-01-01T00:00:00Z", "valid_to": "2099-01-01T00:00:00Z"
+	}`)
+
+	out, err := runDiscountctl(t, "--store", store, "create", "-f", discount)
+	require.NoError(t, err)
+	assert.Contains(t, out, "flat-50 created")
+
+	_, err = runDiscountctl(t, "--store", store, "create", "-f", discount)
+	assert.Error(t, err, "IDs stay unique across invocations")
+
+	_, err = runDiscountctl(t, "--store", store, "deactivate", "flat-50")
+	require.NoError(t, err)
+
+	out, err = runDiscountctl(t, "--store", store, "inspect", "flat-50")
+	require.NoError(t, err)
+	assert.Contains(t, out, `"is_active": false`)
+
+	_, err = runDiscountctl(t, "--store", store, "activate", "flat-50")
+	require.NoError(t, err)
+
+	out, err = runDiscountctl(t, "--store", store, "list")
+	require.NoError(t, err)
+	assert.Contains(t, out, "flat-50")
+	assert.Contains(t, out, "true")
+
+	cart := writeFile(t, dir, "cart.json", `{
+		"cart_items": [{"product": {"id": "p1", "brand": {"id": "B"}, "category": {"id": "C"},
+			"base_price": "100", "current_price": "100"}, "quantity": 2, "size": "M"}],
+		"customer": {"id": "c1"}
+	}`)
+	out, err = runDiscountctl(t, "--store", store, "simulate", "-f", cart)
+	require.NoError(t, err)
+	assert.Contains(t, out, "Flat 50")
+	assert.Contains(t, out, "150.00")
+
+	// Simulations do not consume usage
+	out, err = runDiscountctl(t, "--store", store, "inspect", "flat-50")
+	require.NoError(t, err)
+	assert.Contains(t, out, `"used_count": 0`)
+}
+
+func TestDiscountctl_Errors(t *testing.T) {
+	_, err := runDiscountctl(t, "--backend", "nope", "list")
+	assert.ErrorContains(t, err, "unknown backend")
+
+	_, err = runDiscountctl(t, "--backend", "sample", "inspect", "missing")
+	assert.Error(t, err)
+
+	out, err := runDiscountctl(t, "--backend", "sample", "list")
+	require.NoError(t, err)
+	assert.Contains(t, out, "SUPER69")
+}
+
+func TestDiscountctl_ListPages(t *testing.T) {
+	out, err := runDiscountctl(t, "--backend", "sample", "list", "--type", "brand", "--sort", "priority")
+	require.NoError(t, err)
+	assert.Regexp(t, `(?s)disc-001.*disc-005`, out)
+	assert.NotContains(t, out, "disc-004")
+
+	out, err = runDiscountctl(t, "--backend", "sample", "list", "--code-prefix", "SUPER")
+	require.NoError(t, err)
+	assert.Contains(t, out, "disc-004")
+	assert.NotContains(t, out, "disc-006")
+
+	out, err = runDiscountctl(t, "--backend", "sample", "list", "--limit", "4")
+	require.NoError(t, err)
+	assert.Contains(t, out, "disc-004")
+	assert.NotContains(t, out, "disc-005")
+	matches := regexp.MustCompile(`--cursor (\S+)`).FindStringSubmatch(out)
+	require.Len(t, matches, 2, out)
+
+	out, err = runDiscountctl(t, "--backend", "sample", "list", "--limit", "4", "--cursor", matches[1])
+	require.NoError(t, err)
+	assert.Contains(t, out, "disc-005")
+	assert.Contains(t, out, "disc-006")
+	assert.NotContains(t, out, "disc-004")
+	assert.NotContains(t, out, "--cursor", "the last page has no cursor")
+
+	_, err = runDiscountctl(t, "--backend", "sample", "list", "--sort", "name", "--cursor", matches[1])
+	assert.ErrorContains(t, err, "sorted by id")
+}
+
+func TestDiscountctl_Scenario(t *testing.T) {
+	out, err := runDiscountctl(t, "scenario", filepath.Join("..", "testdata", "scenarios", "stacking.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, out, "scenarios passed")
+
+	failing := writeFile(t, t.TempDir(), "failing.yaml", "name: wrong\ncustomer: {id: c}\nexpect:\n  error: something else\n")
+	out, err = runDiscountctl(t, "scenario", failing)
+	assert.ErrorContains(t, err, "1 of 1 scenarios failed")
+	assert.Contains(t, out, "FAIL")
+}
+
+func TestDiscountctl_Validate(t *testing.T) {
+	dir := t.TempDir()
+	valid := writeFile(t, dir, "valid.yaml", `
+- id: flat-50
+  name: Flat 50
+  type: voucher
+  code: FLAT50
+  value: 50
+  valid_from: 2024-01-01T00:00:00Z
+  valid_to: 2025-01-01T00:00:00Z
+`)
+	out, err := runDiscountctl(t, "validate", valid)
+	require.NoError(t, err)
+	assert.Contains(t, out, "1 files valid")
+
+	broken := writeFile(t, dir, "broken.json", `[
+		{"id": "too-much", "type": "brand", "value": "150", "is_percentage": true,
+			"valid_from": "2024-01-01T00:00:00Z", "valid_to": "2025-01-01T00:00:00Z"},
+		{"id": "backwards", "type": "voucher", "value": "-10", "code": "FLAT50",
+			"valid_from": "2025-01-01T00:00:00Z", "valid_to": "2024-01-01T00:00:00Z"},
+		{"id": "flat-50", "type": "coupon",
+			"valid_from": "2024-01-01T00:00:00Z", "valid_to": "2025-01-01T00:00:00Z"}
+	]`)
+	unparsable := writeFile(t, dir, "unparsable.yaml", "- id: x\n  colour: red\n")
+	out, err = runDiscountctl(t, "validate", "--json", valid, broken, unparsable)
+	assert.ErrorContains(t, err, "7 problems found")
+
+	var diagnostics []lint.Diagnostic
+	require.NoError(t, json.Unmarshal([]byte(out), &diagnostics), out)
+	found := make(map[string]lint.Rule)
+	for _, d := range diagnostics {
+		found[d.DiscountID+"/"+d.Field] = d.Rule
+	}
+	assert.Equal(t, map[string]lint.Rule{
+		"/":                  lint.RuleSchema,
+		"too-much/value":     lint.RulePercentage,
+		"backwards/valid_to": lint.RuleDateRange,
+		"backwards/value":    lint.RuleNegative,
+		"backwards/code":     lint.RuleDuplicateCode,
+		"flat-50/id":         lint.RuleDuplicateID,
+		"flat-50/type":       lint.RuleUnknownType,
+	}, found)
+	assert.Equal(t, unparsable, diagnostics[0].File, "files that do not parse come first")
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/audit"
+	"github.com/ahsmha/discounts/internal/catalog"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+func TestDiscountRefs(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	sale := &models.Discount{
+		ID:           "spring-sale",
+		Name:         "Spring sale",
+		Type:         models.DiscountTypeVoucher,
+		Value:        decimal.NewFromInt(10),
+		IsPercentage: true,
+		ValidFrom:    now.Add(-time.Hour),
+		ValidTo:      now.Add(time.Hour),
+		IsActive:     true,
+	}
+
+	store := audit.NewMemoryStore()
+	logger := audit.NewLogger(store)
+	redemptions := repository.NewInMemoryRedemptionRepository()
+	repo := audit.Discounts(repository.NewInMemoryDiscountRepository(), logger)
+	require.NoError(t, repo.CreateDiscount(ctx, sale))
+	ref := sale.Ref
+	assert.Equal(t, models.NewDiscountRef(tenant.Default, sale.ID), ref, "created discounts get a derived ref")
+	assert.NotEqual(t, ref, models.NewDiscountRef("other", sale.ID), "refs differ across tenants")
+
+	renamed := *sale
+	renamed.Name, renamed.Ref = "Summer sale", ""
+	require.NoError(t, repo.UpdateDiscount(ctx, &renamed))
+	assert.Equal(t, ref, renamed.Ref, "renames keep the ref")
+	changed := renamed
+	changed.Ref = "dref_other"
+	assert.True(t, errors.IsValidationError(repo.UpdateDiscount(ctx, &changed)))
+
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)),
+		services.WithRedemptionRepository(audit.Redemptions(redemptions, logger)))
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 1}}
+	result, err := service.CalculateCartDiscounts(ctx, cart, models.CustomerProfile{ID: "c1"}, nil)
+	require.NoError(t, err)
+	require.Len(t, result.Breakdown, 1)
+	assert.Equal(t, ref, result.Breakdown[0].DiscountRef)
+
+	recorded, err := redemptions.ListRedemptions(ctx, models.RedemptionFilter{})
+	require.NoError(t, err)
+	require.Len(t, recorded, 1)
+	assert.Equal(t, ref, recorded[0].DiscountRef)
+	events, err := store.Query(ctx, audit.Filter{})
+	require.NoError(t, err)
+	for _, event := range events {
+		assert.Equal(t, ref, event.DiscountRef, string(event.Action))
+	}
+
+	moved := renamed
+	moved.ID, moved.Version = "summer-sale", 0
+	assert.True(t, errors.IsValidationError(repo.CreateDiscount(ctx, &moved)), "refs belong to one discount")
+	require.NoError(t, repo.DeleteDiscount(ctx, sale.ID))
+	require.NoError(t, repo.CreateDiscount(ctx, &moved), "a recreated discount can carry its ref over")
+
+	h := catalog.NewHandler(repo, clock.NewFixed(now))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/catalog/refs/"+string(ref), nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var mapping models.DiscountRefMapping
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &mapping))
+	assert.Equal(t, models.DiscountRefMapping{Ref: ref, ID: "summer-sale", Name: "Summer sale", Type: models.DiscountTypeVoucher}, mapping)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/catalog/refs/dref_unknown", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
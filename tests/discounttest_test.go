@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/discounttest"
+)
+
+func TestDiscounttest(t *testing.T) {
+	ctx := context.Background()
+	tee := discounttest.Product("tee", "PUMA", "tshirts", "1000")
+
+	t.Run("Builders price through the engine", func(t *testing.T) {
+		repo := discounttest.NewRepository(t,
+			discounttest.Discount("puma", models.DiscountTypeBrand).Percent("40").On("PUMA").Priority(2).Build(),
+			discounttest.Discount("hdfc", models.DiscountTypeBank).Percent("10").On("HDFC").MaxAmount("50").Build(),
+			discounttest.Discount("off", models.DiscountTypeBrand).Percent("90").On("PUMA").Inactive().Build(),
+		)
+		req := discounttest.Cart().Add(tee, 2).PaidByCard("HDFC", models.Credit).Request()
+
+		result, err := services.NewDiscountService(repo).CalculateCart(ctx, req)
+		require.NoError(t, err)
+		assert.True(t, result.OriginalPrice.Equal(decimal.NewFromInt(2000)))
+		assert.True(t, result.FinalPrice.Equal(decimal.NewFromInt(1150)), result.FinalPrice.String())
+		assert.Len(t, result.Breakdown, 2)
+	})
+
+	t.Run("Repository failures reach the caller", func(t *testing.T) {
+		repo := discounttest.NewRepository(t)
+		unavailable := errors.New("store unavailable")
+		repo.FailOn("GetApplicableDiscounts", unavailable)
+		repo.FailOn("GetActiveDiscounts", unavailable)
+
+		svc := services.NewDiscountService(repo)
+		_, err := svc.CalculateCart(ctx, discounttest.Cart().Add(tee, 1).Request())
+		assert.ErrorIs(t, err, unavailable)
+
+		repo.FailOn("GetApplicableDiscounts", nil)
+		repo.FailOn("GetActiveDiscounts", nil)
+		_, err = svc.CalculateCart(ctx, discounttest.Cart().Add(tee, 1).Request())
+		assert.NoError(t, err)
+	})
+
+	t.Run("Service stubs and records calls", func(t *testing.T) {
+		svc := &discounttest.Service{
+			CalculateCartFunc: func(ctx context.Context, req *models.CalculationRequest) (*models.DiscountedPrice, error) {
+				return discounttest.Price("1000", "900"), nil
+			},
+		}
+		result, err := svc.CalculateCart(ctx, discounttest.Cart().Add(tee, 1).Request())
+		require.NoError(t, err)
+		assert.True(t, result.GetTotalDiscount().Equal(decimal.NewFromInt(100)))
+
+		_, err = svc.ListOffers(ctx, models.CustomerProfile{})
+		assert.ErrorIs(t, err, discounttest.ErrNotStubbed)
+		assert.Equal(t, []string{"CalculateCart", "ListOffers"}, svc.Calls())
+	})
+
+	t.Run("Fixed strategy is capped at the total", func(t *testing.T) {
+		strategy := discounttest.FixedStrategy("300")
+		d := discounttest.Discount("any", "employee").Build()
+		assert.True(t, strategy.IsApplicable(&d, nil, models.CustomerProfile{}, nil))
+		assert.True(t, strategy.Calculate(&d, nil, decimal.NewFromInt(200)).Equal(decimal.NewFromInt(200)))
+	})
+}
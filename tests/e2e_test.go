@@ -0,0 +1,106 @@
+//go:build e2e
+
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/testdata"
+)
+
+// The end-to-end suite runs against a live, freshly seeded server, normally the
+// docker-compose environment started by `make test-e2e`. Checkouts consume usage limits,
+// so the suite is not repeatable against the same server.
+
+const superVoucher = "SUPER69 Voucher - 69% off"
+
+func e2eURL(t *testing.T) string {
+	t.Helper()
+	url := os.Getenv("DISCOUNTS_E2E_URL")
+	if url == "" {
+		t.Skip("DISCOUNTS_E2E_URL is not set")
+	}
+	return url
+}
+
+func postE2E(t *testing.T, url string, body any) (int, map[string]any) {
+	t.Helper()
+	payload, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(payload))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var decoded map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+	return resp.StatusCode, decoded
+}
+
+func TestE2E_Calculate(t *testing.T) {
+	base := e2eURL(t)
+	cartItems, customer, paymentInfo := testdata.GetMultipleDiscountScenario()
+
+	status, resp := postE2E(t, base+"/v2/cart/calculate", map[string]any{
+		"cart_items":   cartItems,
+		"customer":     customer,
+		"payment_info": paymentInfo,
+	})
+	require.Equal(t, http.StatusOK, status, resp)
+	assert.NotEmpty(t, resp["discounts"])
+
+	status, resp = postE2E(t, base+"/v2/cart/calculate", map[string]any{"cart_items": []any{}})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "cart is empty", resp["error"])
+}
+
+// TestE2E_UsageLimitUnderConcurrency fires more concurrent checkouts than SUPER69's usage
+// limit of 100 and checks the voucher is never granted more often than that
+func TestE2E_UsageLimitUnderConcurrency(t *testing.T) {
+	base := e2eURL(t)
+	products := testdata.GetSampleProducts()
+	body := map[string]any{
+		"cart_items": []map[string]any{{"product": products[1], "quantity": 1, "size": "42"}}, // Nike 5000, above the 2000 minimum
+		"customer":   testdata.GetSampleCustomers()[0],
+	}
+
+	const checkouts = 150
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		granted int
+		failed  []int
+	)
+	for i := 0; i < checkouts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, resp := postE2E(t, base+"/v1/cart/calculate", body)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if status != http.StatusOK {
+				failed = append(failed, status)
+				return
+			}
+			if applied, _ := resp["applied_discounts"].(map[string]any); applied[superVoucher] != nil {
+				granted++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Empty(t, failed)
+	assert.Positive(t, granted, "the voucher should apply until its limit is reached")
+	assert.LessOrEqual(t, granted, 100)
+}
@@ -0,0 +1,260 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/auth"
+	"github.com/ahsmha/discounts/internal/edgesync"
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/discounttest"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+)
+
+// flakySource fails every call while down, standing in for a lost link
+type flakySource struct {
+	edgesync.Source
+	down bool
+}
+
+func (s *flakySource) Changes(ctx context.Context, cursor int64, limit int) (*models.ChangePage, error) {
+	if s.down {
+		return nil, fmt.Errorf("link down")
+	}
+	return s.Source.Changes(ctx, cursor, limit)
+}
+
+func (s *flakySource) ReportUsage(ctx context.Context, report models.UsageReport) error {
+	if s.down {
+		return fmt.Errorf("link down")
+	}
+	return s.Source.ReportUsage(ctx, report)
+}
+
+// lostAckSource applies reports but loses the answer to the next one, standing in for a
+// link dropping after the central store counted the uses
+type lostAckSource struct {
+	edgesync.Source
+	loseNext bool
+}
+
+func (s *lostAckSource) ReportUsage(ctx context.Context, report models.UsageReport) error {
+	if err := s.Source.ReportUsage(ctx, report); err != nil {
+		return err
+	}
+	if s.loseNext {
+		s.loseNext = false
+		return fmt.Errorf("connection reset")
+	}
+	return nil
+}
+
+// countFailingRepository counts uses one at a time, failing those of one discount
+type countFailingRepository struct {
+	interfaces.IDiscountRepository
+	failing string
+}
+
+func (r *countFailingRepository) IncrementUsageCount(ctx context.Context, id string) error {
+	if id == r.failing {
+		return fmt.Errorf("database unavailable")
+	}
+	return r.IDiscountRepository.IncrementUsageCount(ctx, id)
+}
+
+func (r *countFailingRepository) ReleaseUsage(ctx context.Context, id string) error {
+	return r.IDiscountRepository.(interfaces.UsageReleaser).ReleaseUsage(ctx, id)
+}
+
+func newCentralFeed(t *testing.T, discounts ...models.Discount) *edgesync.Feed {
+	t.Helper()
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(interfaces.DiscountSeeder).SeedDiscounts(discounts))
+	return edgesync.NewFeed(repo)
+}
+
+func TestEdgeSync(t *testing.T) {
+	ctx := context.Background()
+	puma := discounttest.Discount("puma", models.DiscountTypeBrand).Percent("10").On("PUMA").UsageLimit(5).Build()
+	cart := discounttest.Cart().Add(discounttest.Product("tee", "PUMA", "tshirts", "1000"), 1).Request()
+
+	t.Run("Edges pull creations, updates and deletions", func(t *testing.T) {
+		feed := newCentralFeed(t, puma)
+		edge := edgesync.NewEdge(feed)
+		edge.PageSize = 1
+		require.NoError(t, edge.Sync(ctx))
+
+		result, err := services.NewDiscountService(edge).CalculateCart(ctx, cart)
+		require.NoError(t, err)
+		assert.True(t, result.FinalPrice.Equal(decimal.NewFromInt(900)), result.FinalPrice.String())
+
+		updated := puma
+		updated.Value = decimal.NewFromInt(20)
+		require.NoError(t, feed.UpdateDiscount(ctx, &updated))
+		flat := discounttest.Discount("flat", models.DiscountTypeVoucher).Fixed("50").Code("FLAT50").Build()
+		require.NoError(t, feed.CreateDiscount(ctx, &flat))
+		require.NoError(t, edge.Sync(ctx))
+
+		d, err := edge.GetDiscountByID(ctx, "puma")
+		require.NoError(t, err)
+		assert.True(t, d.Value.Equal(decimal.NewFromInt(20)))
+		_, err = edge.GetDiscountByID(ctx, "flat")
+		require.NoError(t, err)
+
+		require.NoError(t, feed.DeleteDiscount(ctx, "flat"))
+		require.NoError(t, edge.Sync(ctx))
+		_, err = edge.GetDiscountByID(ctx, "flat")
+		assert.True(t, errors.IsNotFoundError(err), "tombstones delete the edge copy")
+
+		page, err := feed.Changes(ctx, edge.Cursor(ctx), 0)
+		require.NoError(t, err)
+		assert.Empty(t, page.Changes, "the edge is caught up")
+	})
+
+	t.Run("Edges do not change discounts themselves", func(t *testing.T) {
+		edge := edgesync.NewEdge(newCentralFeed(t))
+		d := puma
+		assert.True(t, errors.IsForbiddenError(edge.CreateDiscount(ctx, &d)))
+		assert.True(t, errors.IsForbiddenError(edge.DeleteDiscount(ctx, "puma")))
+	})
+
+	t.Run("Uses counted at the edge reach the central store", func(t *testing.T) {
+		feed := newCentralFeed(t, puma)
+		source := &flakySource{Source: feed}
+		edge := edgesync.NewEdge(source)
+		require.NoError(t, edge.Sync(ctx))
+
+		source.down = true
+		require.NoError(t, edge.CheckAndIncrementUsage(ctx, "puma"))
+		require.NoError(t, edge.CheckAndIncrementUsage(ctx, "puma"))
+		assert.Error(t, edge.Sync(ctx))
+		assert.Equal(t, map[string]int{"puma": 2}, edge.Pending(ctx), "uses are kept while the link is down")
+
+		source.down = false
+		require.NoError(t, edge.Sync(ctx))
+		assert.Empty(t, edge.Pending(ctx))
+		central, err := feed.GetDiscountByID(ctx, "puma")
+		require.NoError(t, err)
+		assert.Equal(t, 2, central.UsedCount)
+		local, err := edge.GetDiscountByID(ctx, "puma")
+		require.NoError(t, err)
+		assert.Equal(t, 2, local.UsedCount, "the central count is not added twice")
+	})
+
+	t.Run("Edges sync over HTTP per tenant", func(t *testing.T) {
+		feed := newCentralFeed(t)
+		acme := tenant.NewContext(ctx, "acme")
+		d := puma
+		require.NoError(t, feed.CreateDiscount(acme, &d))
+
+		server := httptest.NewServer(edgesync.NewHandler(feed, clock.System()))
+		defer server.Close()
+		edge := edgesync.NewEdge(edgesync.Client{BaseURL: server.URL})
+
+		require.NoError(t, edge.Sync(ctx))
+		_, err := edge.GetDiscountByID(ctx, "puma")
+		assert.True(t, errors.IsNotFoundError(err), "other tenants' discounts stay central")
+
+		require.NoError(t, edge.Sync(acme))
+		require.NoError(t, edge.IncrementUsageCount(acme, "puma"))
+		require.NoError(t, edge.Sync(acme))
+		central, err := feed.GetDiscountByID(acme, "puma")
+		require.NoError(t, err)
+		assert.Equal(t, 1, central.UsedCount)
+
+		_, err = edgesync.Client{BaseURL: server.URL}.Changes(ctx, -1, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("Reports sent again are counted once", func(t *testing.T) {
+		feed := newCentralFeed(t, puma)
+		source := &lostAckSource{Source: feed, loseNext: true}
+		edge := edgesync.NewEdge(source)
+		require.NoError(t, edge.Sync(ctx))
+
+		require.NoError(t, edge.CheckAndIncrementUsage(ctx, "puma"))
+		require.NoError(t, edge.CheckAndIncrementUsage(ctx, "puma"))
+		assert.Error(t, edge.Sync(ctx))
+		require.NoError(t, edge.CheckAndIncrementUsage(ctx, "puma"))
+		require.NoError(t, edge.Sync(ctx))
+		require.NoError(t, edge.Sync(ctx))
+
+		central, err := feed.GetDiscountByID(ctx, "puma")
+		require.NoError(t, err)
+		assert.Equal(t, 3, central.UsedCount, "the resent report is dropped, the use counted since is not")
+		assert.Empty(t, edge.Pending(ctx))
+	})
+
+	t.Run("Reports are counted all or nothing", func(t *testing.T) {
+		nike := discounttest.Discount("nike", models.DiscountTypeBrand).Percent("10").On("Nike").Build()
+		repo := repository.NewInMemoryDiscountRepository()
+		require.NoError(t, repo.(interfaces.DiscountSeeder).SeedDiscounts([]models.Discount{puma, nike}))
+		flaky := &countFailingRepository{IDiscountRepository: repo, failing: "puma"}
+		feed := edgesync.NewFeed(flaky)
+
+		report := models.UsageReport{EdgeID: "edge-1", Sequence: 1, Uses: map[string]int{"nike": 2, "puma": 1}}
+		assert.Error(t, feed.ReportUsage(ctx, report))
+		stored, err := repo.GetDiscountByID(ctx, "nike")
+		require.NoError(t, err)
+		assert.Zero(t, stored.UsedCount, "uses counted before the failure are given back")
+
+		flaky.failing = ""
+		require.NoError(t, feed.ReportUsage(ctx, report))
+		stored, err = repo.GetDiscountByID(ctx, "nike")
+		require.NoError(t, err)
+		assert.Equal(t, 2, stored.UsedCount)
+	})
+
+	t.Run("Edges authenticate to the feed", func(t *testing.T) {
+		authn := auth.APIKeys{"edge-key": {ID: "edge-1", Roles: []models.Role{models.RoleSync}}}
+		server := httptest.NewServer(auth.Middleware(edgesync.NewHandler(newCentralFeed(t, puma), clock.System()), authn, auth.DefaultPolicy()))
+		defer server.Close()
+
+		assert.Error(t, edgesync.NewEdge(edgesync.Client{BaseURL: server.URL}).Sync(ctx))
+		edge := edgesync.NewEdge(edgesync.Client{BaseURL: server.URL, Token: "edge-key"})
+		require.NoError(t, edge.Sync(ctx))
+		_, err := edge.GetDiscountByID(ctx, "puma")
+		require.NoError(t, err)
+	})
+
+	t.Run("Usage reports are bounded", func(t *testing.T) {
+		feed := newCentralFeed(t, puma)
+		err := feed.ReportUsage(ctx, models.UsageReport{Uses: map[string]int{"puma": 2_000_000_000}})
+		assert.True(t, errors.IsValidationError(err), "%v", err)
+		err = feed.ReportUsage(ctx, models.UsageReport{Uses: map[string]int{"puma": -1}})
+		assert.True(t, errors.IsValidationError(err), "%v", err)
+
+		server := httptest.NewServer(edgesync.NewHandler(feed, clock.System()))
+		defer server.Close()
+		oversized := `{"uses": {"puma": 1}, "edge_id": "` + strings.Repeat("x", 2<<20) + `"}`
+		resp, err := http.Post(server.URL+"/sync/usage", "application/json", strings.NewReader(oversized))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		central, err := feed.GetDiscountByID(ctx, "puma")
+		require.NoError(t, err)
+		assert.Zero(t, central.UsedCount)
+	})
+
+	t.Run("Reported uses are counted in one call", func(t *testing.T) {
+		feed := newCentralFeed(t, puma)
+		require.NoError(t, feed.ReportUsage(ctx, models.UsageReport{Uses: map[string]int{"puma": edgesync.MaxReportedUses, "gone": 3}}))
+		central, err := feed.GetDiscountByID(ctx, "puma")
+		require.NoError(t, err)
+		assert.Equal(t, edgesync.MaxReportedUses, central.UsedCount)
+	})
+}
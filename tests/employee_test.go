@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscountService_EmployeeMonthlyCap(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC)
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID: "staff", Name: "Staff 30%", Type: models.DiscountTypeEmployee,
+		Value: decimal.NewFromInt(30), IsPercentage: true,
+		ValidFrom: now.AddDate(-1, 0, 0), ValidTo: now.AddDate(1, 0, 0), IsActive: true,
+		MonthlyCap: decimal.NewFromInt(500),
+	}))
+	spend := repository.NewInMemorySpendTracker()
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)), services.WithSpendTracker(spend))
+
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 1}}
+	employee := models.CustomerProfile{ID: "emp-1", IsEmployee: true}
+	price := func(customer models.CustomerProfile) decimal.Decimal {
+		result, err := service.CalculateCartDiscounts(ctx, cart, customer, nil)
+		require.NoError(t, err)
+		return result.FinalPrice
+	}
+
+	t.Run("Only employees get it", func(t *testing.T) {
+		explanation, err := service.ExplainCartDiscounts(ctx, &models.CalculationRequest{CartItems: cart, Customer: models.CustomerProfile{ID: "c1"}})
+		require.NoError(t, err)
+		decision, ok := explanation.Decision("staff")
+		require.True(t, ok)
+		assert.Equal(t, models.ReasonCustomerIneligible, decision.Reason)
+		assert.Contains(t, decision.Detail, "employees")
+	})
+
+	t.Run("The month's allowance runs out", func(t *testing.T) {
+		assert.True(t, decimal.NewFromInt(700).Equal(price(employee)))
+		assert.True(t, decimal.NewFromInt(800).Equal(price(employee)), "capped at what is left")
+		assert.True(t, decimal.NewFromInt(1000).Equal(price(employee)))
+
+		explanation, err := service.ExplainCartDiscounts(ctx, &models.CalculationRequest{CartItems: cart, Customer: employee})
+		require.NoError(t, err)
+		decision, ok := explanation.Decision("staff")
+		require.True(t, ok)
+		assert.Equal(t, models.ReasonMonthlyCapReached, decision.Reason)
+
+		assert.True(t, decimal.NewFromInt(700).Equal(price(models.CustomerProfile{ID: "emp-2", IsEmployee: true})),
+			"each employee has an allowance")
+	})
+
+	t.Run("A new month brings a new allowance", func(t *testing.T) {
+		later := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now.AddDate(0, 0, 2))), services.WithSpendTracker(spend))
+		result, err := later.CalculateCartDiscounts(ctx, cart, employee, nil)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(700).Equal(result.FinalPrice))
+	})
+
+	t.Run("Caps hold without a configured spend tracker", func(t *testing.T) {
+		untracked := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+		customer := models.CustomerProfile{ID: "emp-3", IsEmployee: true}
+		for _, want := range []int64{700, 800, 1000} {
+			result, err := untracked.CalculateCartDiscounts(ctx, cart, customer, nil)
+			require.NoError(t, err)
+			assert.True(t, decimal.NewFromInt(want).Equal(result.FinalPrice), "got %s, want %d", result.FinalPrice, want)
+		}
+	})
+
+	t.Run("The store validates caps", func(t *testing.T) {
+		err := repo.CreateDiscount(ctx, &models.Discount{ID: "v", Type: models.DiscountTypeVoucher, MonthlyCap: decimal.NewFromInt(10)})
+		assert.True(t, errors.IsValidationError(err))
+		err = repo.CreateDiscount(ctx, &models.Discount{ID: "e", Type: models.DiscountTypeEmployee, MonthlyCap: decimal.NewFromInt(-1)})
+		assert.True(t, errors.IsValidationError(err))
+	})
+}
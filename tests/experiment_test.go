@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+)
+
+func TestExperimentBucketing(t *testing.T) {
+	e := &models.DiscountExperiment{ID: "exp-1", TrafficPercent: 30}
+	assert.Equal(t, models.ExperimentBucket("exp-1", "c1"), models.ExperimentBucket("exp-1", "c1"))
+
+	treated := 0
+	for i := 0; i < 10000; i++ {
+		if _, ok := e.Assign(fmt.Sprintf("customer-%d", i)); ok {
+			treated++
+		}
+	}
+	assert.InDelta(t, 3000, treated, 200, "about TrafficPercent of customers get the discount")
+
+	variant, ok := e.Assign("")
+	assert.False(t, ok, "anonymous customers are in the control group")
+	assert.Equal(t, models.DefaultControlVariant, variant)
+
+	assert.Error(t, (&models.Discount{Experiment: &models.DiscountExperiment{TrafficPercent: 10}}).CheckExperiment())
+	assert.Error(t, (&models.Discount{Experiment: &models.DiscountExperiment{ID: "e", TrafficPercent: 101}}).CheckExperiment())
+	assert.Error(t, (&models.Discount{Experiment: &models.DiscountExperiment{ID: "e", Variant: "control"}}).CheckExperiment())
+}
+
+func TestDiscountService_Experiment(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := repository.NewInMemoryDiscountRepository()
+	discount := &models.Discount{
+		ID: "half-off", Name: "Half off", Type: models.DiscountTypeVoucher,
+		Value: decimal.NewFromInt(50), IsPercentage: true,
+		ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true,
+		Experiment: &models.DiscountExperiment{ID: "half-off-test", Variant: "half", TrafficPercent: 50},
+	}
+	require.NoError(t, repo.CreateDiscount(ctx, discount))
+	experiments := repository.NewInMemoryExperimentRepository()
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)), services.WithExperimentRepository(experiments))
+
+	// Find a customer on either side of the split
+	var treated, control string
+	for i := 0; treated == "" || control == ""; i++ {
+		id := fmt.Sprintf("customer-%d", i)
+		if discount.IsExposedTo(id) {
+			treated = id
+		} else {
+			control = id
+		}
+	}
+
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", Brand: models.Brand{ID: "PUMA"}, CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 1}}
+	result, err := service.CalculateCartDiscounts(ctx, cart, models.CustomerProfile{ID: treated}, nil)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(500).Equal(result.FinalPrice), "got %s", result.FinalPrice)
+	assert.Equal(t, []models.ExperimentExposure{{ExperimentID: "half-off-test", DiscountID: "half-off", Variant: "half", Treated: true}}, result.Experiments)
+
+	result, err = service.CalculateCartDiscounts(ctx, cart, models.CustomerProfile{ID: control}, nil)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(1000).Equal(result.FinalPrice), "the control group does not get the discount")
+	assert.Equal(t, []models.ExperimentExposure{{ExperimentID: "half-off-test", DiscountID: "half-off", Variant: models.DefaultControlVariant}}, result.Experiments)
+
+	assignments, err := experiments.ListAssignments(ctx, "half-off-test")
+	require.NoError(t, err)
+	variants := make(map[string]string)
+	for _, a := range assignments {
+		variants[a.CustomerID] = a.Variant
+	}
+	assert.Equal(t, map[string]string{treated: "half", control: models.DefaultControlVariant}, variants)
+
+	explanation, err := service.ExplainCartDiscounts(ctx, &models.CalculationRequest{CartItems: cart, Customer: models.CustomerProfile{ID: control}})
+	require.NoError(t, err)
+	decision, ok := explanation.Decision("half-off")
+	require.True(t, ok)
+	assert.Equal(t, models.ReasonExperimentControl, decision.Reason)
+
+	offers, err := service.ListOffers(ctx, models.CustomerProfile{ID: control})
+	require.NoError(t, err)
+	assert.Empty(t, offers, "the control group is not offered the discount")
+	offers, err = service.ListOffers(ctx, models.CustomerProfile{ID: treated})
+	require.NoError(t, err)
+	assert.Len(t, offers, 1)
+
+	discount.ID, discount.Experiment = "invalid", &models.DiscountExperiment{ID: "x", TrafficPercent: 120}
+	assert.Error(t, repo.CreateDiscount(ctx, discount))
+}
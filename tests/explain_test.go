@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+// explainDiscounts returns one discount per reason a PUMA-only cart can run into
+func explainDiscounts() []models.Discount {
+	now := time.Now()
+	discount := func(id string, typ models.DiscountType, priority int) models.Discount {
+		return models.Discount{
+			ID:        id,
+			Name:      id,
+			Type:      typ,
+			Value:     decimal.NewFromInt(10),
+			ValidFrom: now.Add(-time.Hour),
+			ValidTo:   now.Add(time.Hour),
+			IsActive:  true,
+			Priority:  priority,
+		}
+	}
+
+	premium := discount("premium-only", models.DiscountTypeBrand, 60)
+	premium.CustomerTiers = []string{"premium"}
+
+	wholeItem := discount("whole-item", models.DiscountTypeBrand, 50)
+	wholeItem.Value = decimal.NewFromInt(1000)
+	wholeItem.ApplicableTo = []string{"PUMA"}
+
+	excluded := discount("no-puma", models.DiscountTypeVoucher, 45)
+	excluded.ExcludedItems = []string{"PUMA"}
+
+	minimum := discount("big-basket", models.DiscountTypeCategory, 40)
+	minimum.MinAmount = decimal.NewFromInt(5000)
+
+	expired := discount("expired", models.DiscountTypeVoucher, 30)
+	expired.ValidTo = now.Add(-time.Minute)
+
+	bank := discount("hdfc-card", models.DiscountTypeBank, 20)
+	bank.ApplicableTo = []string{"HDFC"}
+
+	referral := discount("friend-code", models.DiscountTypeReferral, 15)
+	referral.Code = "FRIEND"
+	referral.ReferrerID = "cust-001"
+
+	late := discount("late-voucher", models.DiscountTypeVoucher, 10)
+	late.IsPercentage = true
+
+	return []models.Discount{premium, wholeItem, excluded, minimum, expired, bank, referral, late}
+}
+
+func TestDiscountService_ExplainCartDiscounts(t *testing.T) {
+	ctx := context.Background()
+
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(explainDiscounts()))
+	service := services.NewDiscountService(repo)
+
+	req := &models.CalculationRequest{
+		CartItems: []models.CartItem{{Product: testdata.GetSampleProducts()[0], Quantity: 1, Size: "M"}}, // PUMA 1000
+		Customer:  testdata.GetSampleCustomers()[1],                                                      // regular tier
+	}
+
+	explanation, err := service.ExplainCartDiscounts(ctx, req)
+	require.NoError(t, err)
+	assert.True(t, decimal.Zero.Equal(explanation.Result.FinalPrice))
+
+	expected := []struct {
+		id      string
+		outcome models.DecisionOutcome
+		reason  models.DecisionReason
+	}{
+		{"premium-only", models.DecisionRejected, models.ReasonCustomerIneligible},
+		{"whole-item", models.DecisionApplied, ""},
+		{"no-puma", models.DecisionRejected, models.ReasonExcluded},
+		{"big-basket", models.DecisionRejected, models.ReasonMinAmountNotMet},
+		{"expired", models.DecisionSkipped, models.ReasonExpired},
+		{"hdfc-card", models.DecisionRejected, models.ReasonPaymentMismatch},
+		{"friend-code", models.DecisionRejected, models.ReasonCodeRequired},
+		{"late-voucher", models.DecisionSkipped, models.ReasonPriorityLoss},
+	}
+	require.Len(t, explanation.Decisions, len(expected))
+	for i, want := range expected {
+		got := explanation.Decisions[i]
+		assert.Equal(t, want.id, got.DiscountID)
+		assert.Equal(t, want.outcome, got.Outcome, want.id)
+		assert.Equal(t, want.reason, got.Reason, want.id)
+		if want.reason != "" {
+			assert.NotEmpty(t, got.Detail, want.id)
+		}
+	}
+
+	applied, ok := explanation.Decision("whole-item")
+	require.True(t, ok)
+	assert.True(t, decimal.NewFromInt(1000).Equal(applied.Amount))
+
+	minimum, _ := explanation.Decision("big-basket")
+	assert.Equal(t, "cart total 1000 is below the minimum 5000", minimum.Detail)
+
+	t.Run("Explaining commits nothing", func(t *testing.T) {
+		stored, err := repo.GetDiscountByID(ctx, "whole-item")
+		require.NoError(t, err)
+		assert.Zero(t, stored.UsedCount)
+	})
+
+	t.Run("Agrees with the real calculation", func(t *testing.T) {
+		result, err := service.CalculateCart(ctx, req)
+		require.NoError(t, err)
+		assert.True(t, explanation.Result.FinalPrice.Equal(result.FinalPrice))
+		assert.Equal(t, explanation.Result.Breakdown, result.Breakdown)
+	})
+}
+
+func TestAPI_Explain(t *testing.T) {
+	h := newTestAPI(t)
+	body := map[string]any{
+		"cart_items": testdata.GetSampleCartItems(),
+		"customer":   testdata.GetSampleCustomers()[0],
+	}
+
+	rec, resp := doJSON(t, h, "/v2/cart/explain", nil, body)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, resp, "result")
+	decisions, ok := resp["decisions"].([]any)
+	require.True(t, ok)
+	assert.NotEmpty(t, decisions)
+
+	rec, _ = doJSON(t, h, "/v1/cart/explain", nil, body)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
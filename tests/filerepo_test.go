@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestFileDiscountRepository(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	write("brands.yaml", `
+- id: puma-40
+  name: PUMA 40% off
+  type: brand
+  applicable_to: [PUMA]
+  value: 40
+  is_percentage: true
+  valid_from: 2024-01-01T00:00:00Z
+  valid_to: 2030-01-01T00:00:00Z
+  is_active: true
+- id: nike-10
+  name: Nike 10% off
+  type: brand
+  applicable_to: [NIKE]
+  value: 10
+  is_percentage: true
+  valid_from: 2024-01-01T00:00:00Z
+  valid_to: 2030-01-01T00:00:00Z
+  is_active: true
+  usage_limit: 5
+`)
+	write("voucher.json", `{"id": "super-69", "name": "SUPER69", "type": "voucher", "code": "SUPER69", "value": "69", "valid_from": "2024-01-01T00:00:00Z", "valid_to": "2030-01-01T00:00:00Z", "is_active": true}`)
+	write("README.md", "Not a discount")
+
+	repo, err := repository.NewFileDiscountRepository(dir)
+	require.NoError(t, err)
+	active, err := repo.GetActiveDiscounts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, active, 3)
+	voucher, err := repo.GetDiscountByCode(ctx, "SUPER69")
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(69).Equal(voucher.Value))
+
+	require.NoError(t, repo.IncrementUsageCount(ctx, "nike-10"))
+
+	t.Run("Writes are refused", func(t *testing.T) {
+		err := repo.CreateDiscount(ctx, &models.Discount{ID: "new", Type: models.DiscountTypeVoucher})
+		assert.True(t, errors.IsForbiddenError(err))
+		assert.True(t, errors.IsForbiddenError(repo.DeleteDiscount(ctx, "puma-40")))
+	})
+
+	t.Run("Invalid files keep the current discounts", func(t *testing.T) {
+		write("broken.yaml", "- id: broken\n  type: brand\n  colour: red\n")
+		_, err := repo.Reload()
+		assert.Error(t, err, "unknown fields are rejected")
+		write("broken.yaml", "- id: puma-40\n  type: brand\n")
+		_, err = repo.Reload()
+		assert.Error(t, err, "duplicate IDs are rejected")
+		require.NoError(t, os.Remove(filepath.Join(dir, "broken.yaml")))
+
+		_, err = repo.GetDiscountByID(ctx, "puma-40")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Changed files are reloaded", func(t *testing.T) {
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		reloaded := make(chan int, 10)
+		repo.OnReload = func(discounts int) { reloaded <- discounts }
+		watching := make(chan error, 1)
+		go func() { watching <- repo.Watch(watchCtx) }()
+
+		// The watcher starts asynchronously, so keep touching the file until a reload lands
+		require.Eventually(t, func() bool {
+			require.NoError(t, os.Remove(filepath.Join(dir, "voucher.json")))
+			write("voucher.json", `[]`)
+			select {
+			case n := <-reloaded:
+				return n == 2
+			case <-time.After(500 * time.Millisecond):
+				return false
+			}
+		}, 5*time.Second, 10*time.Millisecond)
+
+		_, err := repo.GetDiscountByCode(ctx, "SUPER69")
+		assert.True(t, errors.IsNotFoundError(err))
+		nike, err := repo.GetDiscountByID(ctx, "nike-10")
+		require.NoError(t, err)
+		assert.Equal(t, 1, nike.UsedCount, "usage survives reloads")
+
+		cancel()
+		assert.NoError(t, <-watching)
+	})
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestDiscountService_GiftCards(t *testing.T) {
+	ctx := context.Background()
+
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(testdata.GetSampleDiscounts()))
+
+	giftCards := repository.NewInMemoryGiftCardRepository()
+	require.NoError(t, giftCards.CreateGiftCard(ctx, &models.GiftCard{Code: "GC-100", Balance: decimal.NewFromInt(100), IsActive: true}))
+	require.NoError(t, giftCards.CreateGiftCard(ctx, &models.GiftCard{Code: "GC-500", Balance: decimal.NewFromInt(500), IsActive: true}))
+	require.NoError(t, giftCards.CreateGiftCard(ctx, &models.GiftCard{Code: "GC-OFF", Balance: decimal.NewFromInt(500)}))
+
+	service := services.NewDiscountService(repo, services.WithGiftCardRepository(giftCards))
+
+	customer := testdata.GetSampleCustomers()[1]
+	cartItems := []models.CartItem{{Product: testdata.GetSampleProducts()[0], Quantity: 1, Size: "M"}} // PUMA 1000
+
+	t.Run("Cards pay the discounted price in order", func(t *testing.T) {
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems:     cartItems,
+			Customer:      customer,
+			GiftCardCodes: []string{"GC-100", "GC-500"},
+		})
+		require.NoError(t, err)
+
+		// Discounts still apply in full before gift cards
+		assert.True(t, decimal.NewFromInt(500).Equal(result.FinalPrice), result.FinalPrice.String())
+		assert.True(t, decimal.NewFromInt(100).Equal(result.GiftCardsApplied["GC-100"]))
+		assert.True(t, decimal.NewFromInt(400).Equal(result.GiftCardsApplied["GC-500"]))
+		assert.True(t, result.AmountDue.IsZero())
+
+		card, err := giftCards.GetGiftCard(ctx, "GC-500")
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(100).Equal(card.Balance))
+	})
+
+	t.Run("Unusable cards reject the request", func(t *testing.T) {
+		for _, code := range []string{"GC-100", "GC-OFF", "GC-MISSING"} {
+			_, err := service.CalculateCart(ctx, &models.CalculationRequest{
+				CartItems:     cartItems,
+				Customer:      customer,
+				GiftCardCodes: []string{code},
+			})
+			assert.True(t, errors.IsValidationError(err), code)
+		}
+	})
+
+	t.Run("Cards are refunded when the checkout fails to commit", func(t *testing.T) {
+		now := time.Now()
+		discounts := repository.NewInMemoryDiscountRepository()
+		require.NoError(t, discounts.CreateDiscount(ctx, &models.Discount{
+			ID: "puma-10", Name: "PUMA 10%", Type: models.DiscountTypeBrand, Value: decimal.NewFromInt(10), IsPercentage: true,
+			ApplicableTo: []string{"PUMA"}, ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true,
+		}))
+		refusing := &claimRefusingRepository{IDiscountRepository: discounts, refuse: "puma-10"}
+		service := services.NewDiscountService(refusing, services.WithGiftCardRepository(giftCards))
+
+		before, err := giftCards.GetGiftCard(ctx, "GC-500")
+		require.NoError(t, err)
+		_, err = service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems:     cartItems,
+			Customer:      customer,
+			GiftCardCodes: []string{"GC-500"},
+		})
+		require.Error(t, err)
+		assert.True(t, errors.IsConflictError(err))
+
+		after, err := giftCards.GetGiftCard(ctx, "GC-500")
+		require.NoError(t, err)
+		assert.True(t, before.Balance.Equal(after.Balance), "balance %s, was %s", after.Balance, before.Balance)
+	})
+}
+
+func TestInMemoryGiftCardRepository_RedeemRefund(t *testing.T) {
+	ctx := context.Background()
+	giftCards := repository.NewInMemoryGiftCardRepository()
+	require.NoError(t, giftCards.CreateGiftCard(ctx, &models.GiftCard{Code: "GC", Balance: decimal.NewFromInt(50), IsActive: true}))
+
+	err := giftCards.Redeem(ctx, "GC", decimal.NewFromInt(60))
+	assert.True(t, errors.IsValidationError(err))
+
+	require.NoError(t, giftCards.Redeem(ctx, "GC", decimal.NewFromInt(50)))
+	require.NoError(t, giftCards.Refund(ctx, "GC", decimal.NewFromInt(20)))
+
+	card, err := giftCards.GetGiftCard(ctx, "GC")
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(20).Equal(card.Balance))
+}
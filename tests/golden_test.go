@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/demo"
+	"github.com/ahsmha/discounts/internal/models"
+)
+
+// updateGolden rewrites the expected outputs from the current engine:
+//
+//	go test ./tests -run TestPricing_Golden -update
+//
+// Review the diff of testdata/golden before committing it.
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of TestPricing_Golden")
+
+// goldenCart is a representative checkout, priced against the demo catalog at demo.Now
+type goldenCart struct {
+	Description string                     `json:"description"`
+	Request     *models.CalculationRequest `json:"request"`
+}
+
+// TestPricing_Golden prices every cart in testdata/golden and compares the result with the
+// .golden.json file next to it, so changes to strategies show up as reviewable diffs
+func TestPricing_Golden(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("..", "testdata", "golden", "*.json"))
+	require.NoError(t, err)
+
+	var carts []string
+	for _, fixture := range fixtures {
+		if !strings.HasSuffix(fixture, ".golden.json") {
+			carts = append(carts, fixture)
+		}
+	}
+	require.NotEmpty(t, carts)
+
+	for _, fixture := range carts {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".json")
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			data, err := os.ReadFile(fixture)
+			require.NoError(t, err)
+			var cart goldenCart
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.DisallowUnknownFields()
+			require.NoError(t, dec.Decode(&cart))
+
+			// A fresh sandbox per cart, so usage and spend never carry over between carts
+			sandbox, err := demo.NewSandbox(ctx)
+			require.NoError(t, err)
+			result, err := sandbox.Service().CalculateCart(ctx, cart.Request)
+			require.NoError(t, err)
+			actual, err := json.MarshalIndent(result, "", "  ")
+			require.NoError(t, err)
+			actual = append(actual, '\n')
+
+			golden := strings.TrimSuffix(fixture, ".json") + ".golden.json"
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(golden, actual, 0o644))
+				return
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err, "run with -update to create the golden file")
+			assert.JSONEq(t, string(expected), string(actual), "%s: %s", name, cart.Description)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ahsmha/discounts/internal/health"
+)
+
+func TestHealthProbes(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Timeout = 50 * time.Millisecond
+	var redisDown error
+	checker.Add("redis", func(ctx context.Context) error { return redisDown })
+	checker.Add("discounts", func(ctx context.Context) error {
+		<-ctx.Done() // Hangs until the check times out
+		return ctx.Err()
+	})
+	h := health.NewHandler(checker)
+	probe := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	status, _ := probe("/healthz")
+	assert.Equal(t, http.StatusOK, status, "liveness does not depend on the checks")
+
+	status, body := probe("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Contains(t, body, "still starting up")
+	assert.Contains(t, body, "deadline exceeded", "hung checks time out")
+
+	checker = health.NewChecker()
+	checker.Add("redis", func(ctx context.Context) error { return redisDown })
+	checker.MarkReady()
+	h = health.NewHandler(checker)
+	status, body = probe("/readyz")
+	assert.Equal(t, http.StatusOK, status, body)
+
+	redisDown = fmt.Errorf("connection refused")
+	status, body = probe("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Contains(t, body, `"name":"redis","status":"unavailable","error":"connection refused"`)
+}
@@ -0,0 +1,198 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestDiscountService_IdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(testdata.GetSampleDiscounts()))
+
+	redemptions := repository.NewInMemoryRedemptionRepository()
+	service := services.NewDiscountService(repo,
+		services.WithRedemptionRepository(redemptions),
+		services.WithIdempotencyStore(repository.NewInMemoryIdempotencyStore(0)),
+	)
+
+	customer := testdata.GetSampleCustomers()[0]
+	cart := []models.CartItem{{Product: testdata.GetSampleProducts()[0], Quantity: 1, Size: "M"}}
+
+	first, err := service.CalculateCart(ctx, &models.CalculationRequest{
+		CartItems:      cart,
+		Customer:       customer,
+		IdempotencyKey: "checkout-1",
+	})
+	require.NoError(t, err)
+
+	recorded, err := redemptions.ListRedemptions(ctx, models.RedemptionFilter{})
+	require.NoError(t, err)
+	require.NotEmpty(t, recorded)
+
+	t.Run("Retry returns the first result without committing again", func(t *testing.T) {
+		retry, err := service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems:      cart,
+			Customer:       customer,
+			IdempotencyKey: "checkout-1",
+		})
+		require.NoError(t, err)
+		assert.True(t, first.FinalPrice.Equal(retry.FinalPrice))
+
+		again, err := redemptions.ListRedemptions(ctx, models.RedemptionFilter{})
+		require.NoError(t, err)
+		assert.Len(t, again, len(recorded))
+	})
+
+	t.Run("Replayed key with a different cart is a conflict", func(t *testing.T) {
+		tampered := []models.CartItem{{Product: testdata.GetSampleProducts()[0], Quantity: 5, Size: "M"}}
+		_, err := service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems:      tampered,
+			Customer:       customer,
+			IdempotencyKey: "checkout-1",
+		})
+		require.Error(t, err)
+		assert.True(t, errors.IsConflictError(err))
+	})
+
+	t.Run("Failed requests release their key", func(t *testing.T) {
+		_, err := service.CalculateCart(ctx, &models.CalculationRequest{
+			Customer:       customer,
+			IdempotencyKey: "checkout-2",
+		})
+		require.Error(t, err)
+		assert.True(t, errors.IsValidationError(err))
+
+		_, err = service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems:      cart,
+			Customer:       customer,
+			IdempotencyKey: "checkout-2",
+		})
+		assert.NoError(t, err)
+	})
+}
+
+// claimRefusingRepository refuses to claim a use of one discount, as though a concurrent
+// checkout had just taken its last use
+type claimRefusingRepository struct {
+	interfaces.IDiscountRepository
+	refuse string
+}
+
+func (r *claimRefusingRepository) CheckAndIncrementUsage(ctx context.Context, id string) error {
+	if id == r.refuse {
+		return errors.NewConflictError("discount usage limit reached: " + id)
+	}
+	return r.IDiscountRepository.CheckAndIncrementUsage(ctx, id)
+}
+
+func (r *claimRefusingRepository) ReleaseUsage(ctx context.Context, id string) error {
+	return r.IDiscountRepository.(interfaces.UsageReleaser).ReleaseUsage(ctx, id)
+}
+
+type failingRedemptionRepository struct {
+	interfaces.IRedemptionRepository
+}
+
+func (failingRedemptionRepository) RecordRedemption(context.Context, models.Redemption) error {
+	return fmt.Errorf("redemption store unavailable")
+}
+
+func TestDiscountService_CommitFailures(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	discounts := []models.Discount{
+		{
+			ID: "brand", Name: "PUMA 10%", Type: models.DiscountTypeBrand, Value: decimal.NewFromInt(10), IsPercentage: true,
+			ApplicableTo: []string{"PUMA"}, ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true, Priority: 20,
+		},
+		{
+			ID: "category", Name: "T-shirts 10%", Type: models.DiscountTypeCategory, Value: decimal.NewFromInt(10), IsPercentage: true,
+			ApplicableTo: []string{"T-shirts"}, ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true, Priority: 10,
+		},
+	}
+	req := func(key string) *models.CalculationRequest {
+		return &models.CalculationRequest{
+			CartItems:      []models.CartItem{{Product: testdata.GetSampleProducts()[0], Quantity: 1, Size: "M"}},
+			Customer:       testdata.GetSampleCustomers()[0],
+			IdempotencyKey: key,
+		}
+	}
+	usedCount := func(repo interfaces.IDiscountRepository, id string) int {
+		d, err := repo.GetDiscountByID(ctx, id)
+		require.NoError(t, err)
+		return d.UsedCount
+	}
+
+	t.Run("A refused claim gives back the uses claimed and releases the key", func(t *testing.T) {
+		repo := repository.NewInMemoryDiscountRepository()
+		require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(discounts))
+		refusing := &claimRefusingRepository{IDiscountRepository: repo, refuse: "category"}
+		service := services.NewDiscountService(refusing, services.WithIdempotencyStore(repository.NewInMemoryIdempotencyStore(0)))
+
+		_, err := service.CalculateCart(ctx, req("checkout-1"))
+		require.Error(t, err)
+		assert.True(t, errors.IsConflictError(err))
+		assert.Zero(t, usedCount(repo, "brand"))
+
+		refusing.refuse = ""
+		_, err = service.CalculateCart(ctx, req("checkout-1"))
+		require.NoError(t, err)
+		assert.Equal(t, 1, usedCount(repo, "brand"))
+		assert.Equal(t, 1, usedCount(repo, "category"))
+	})
+
+	t.Run("A failure after claiming keeps the key reserved", func(t *testing.T) {
+		repo := repository.NewInMemoryDiscountRepository()
+		require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(discounts))
+		service := services.NewDiscountService(repo,
+			services.WithRedemptionRepository(failingRedemptionRepository{}),
+			services.WithIdempotencyStore(repository.NewInMemoryIdempotencyStore(0)),
+		)
+
+		_, err := service.CalculateCart(ctx, req("checkout-1"))
+		require.Error(t, err)
+		assert.Equal(t, 1, usedCount(repo, "brand"))
+
+		_, err = service.CalculateCart(ctx, req("checkout-1"))
+		require.Error(t, err)
+		assert.True(t, errors.IsConflictError(err))
+		assert.Equal(t, 1, usedCount(repo, "brand"), "a retry must not claim the uses again")
+	})
+}
+
+func TestInMemoryIdempotencyStore_Expiry(t *testing.T) {
+	fixed := clock.NewFixed(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	store := repository.NewInMemoryIdempotencyStore(time.Hour)
+
+	_, created, err := store.Reserve(clock.NewContext(context.Background(), fixed.Now()), "key", "a")
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	record, created, err := store.Reserve(clock.NewContext(context.Background(), fixed.Now()), "key", "b")
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "a", record.Fingerprint)
+
+	fixed.Advance(2 * time.Hour)
+	record, created, err = store.Reserve(clock.NewContext(context.Background(), fixed.Now()), "key", "b")
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "b", record.Fingerprint)
+}
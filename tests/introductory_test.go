@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscountService_IntroductoryOrders(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	welcome := &models.Discount{
+		ID:                 "welcome",
+		Name:               "20% off your first 3 orders",
+		Type:               models.DiscountTypeVoucher,
+		Value:              decimal.NewFromInt(20),
+		IsPercentage:       true,
+		IntroductoryOrders: 3,
+		ValidFrom:          now.Add(-time.Hour),
+		ValidTo:            now.Add(time.Hour),
+		IsActive:           true,
+	}
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, welcome))
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)),
+		services.WithRedemptionRepository(repository.NewInMemoryRedemptionRepository()))
+
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 1}}
+	customer := models.CustomerProfile{ID: "new-customer"}
+	ordersLeft := func(customer models.CustomerProfile) *int {
+		offers, err := service.ListOffers(ctx, customer)
+		require.NoError(t, err)
+		for _, offer := range offers {
+			if offer.DiscountID == welcome.ID {
+				require.NotNil(t, offer.OrdersLeft)
+				return offer.OrdersLeft
+			}
+		}
+		return nil
+	}
+
+	for order := range 3 {
+		require.Equal(t, 3-order, *ordersLeft(customer))
+		result, err := service.CalculateCartDiscounts(ctx, cart, customer, nil)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(800).Equal(result.FinalPrice), "order %d", order+1)
+	}
+
+	assert.Nil(t, ordersLeft(customer), "the program is used up")
+	result, err := service.CalculateCartDiscounts(ctx, cart, customer, nil)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(1000).Equal(result.FinalPrice))
+
+	explanation, err := service.ExplainCartDiscounts(ctx, &models.CalculationRequest{CartItems: cart, Customer: customer})
+	require.NoError(t, err)
+	decision, ok := explanation.Decision(welcome.ID)
+	require.True(t, ok)
+	assert.Equal(t, models.ReasonIntroductoryOrdersUsed, decision.Reason)
+
+	// Customers are as far into the program as their order history, too
+	assert.Equal(t, 1, *ordersLeft(models.CustomerProfile{ID: "returning", OrderCount: 2}))
+	assert.Nil(t, ordersLeft(models.CustomerProfile{ID: "regular", OrderCount: 5}))
+
+	for _, invalid := range []*models.Discount{
+		{ID: "negative", Type: models.DiscountTypeVoucher, IntroductoryOrders: -1},
+		{ID: "first-only", Type: models.DiscountTypeVoucher, IntroductoryOrders: 2, FirstOrderOnly: true},
+	} {
+		assert.True(t, errors.IsValidationError(repo.CreateDiscount(ctx, invalid)), invalid.ID)
+	}
+}
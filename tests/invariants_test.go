@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestDiscountService_InvariantChecks(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Sample scenarios hold", func(t *testing.T) {
+		repo := repository.NewInMemoryDiscountRepository()
+		require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(testdata.GetSampleDiscounts()))
+		service := services.NewDiscountService(repo, services.WithInvariantChecks(nil))
+
+		bank := "ICICI"
+		credit := models.Credit
+		for _, customer := range testdata.GetSampleCustomers() {
+			assert.NotPanics(t, func() {
+				_, err := service.CalculateCartDiscounts(ctx, testdata.GetSampleCartItems(), customer,
+					&models.PaymentInfo{Method: models.Card, BankName: &bank, CardType: &credit})
+				require.NoError(t, err)
+			}, customer.ID)
+		}
+	})
+
+	// Brand and category discounts are priced on item totals, so together they would take
+	// off more than the item costs
+	now := time.Now()
+	overlapping := []models.Discount{
+		{
+			ID: "brand-1000", Name: "brand-1000", Type: models.DiscountTypeBrand, Value: decimal.NewFromInt(1000),
+			ApplicableTo: []string{"PUMA"}, ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true, Priority: 20,
+		},
+		{
+			ID: "category-1000", Name: "category-1000", Type: models.DiscountTypeCategory, Value: decimal.NewFromInt(1000),
+			ApplicableTo: []string{"T-shirts"}, ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true, Priority: 10,
+		},
+	}
+	cartItems := []models.CartItem{{Product: testdata.GetSampleProducts()[0], Quantity: 1, Size: "M"}} // PUMA 1000
+
+	t.Run("Stacked discounts stop at the price", func(t *testing.T) {
+		repo := repository.NewInMemoryDiscountRepository()
+		require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(overlapping))
+
+		var violations []*services.InvariantViolation
+		service := services.NewDiscountService(repo, services.WithInvariantChecks(func(v *services.InvariantViolation) {
+			violations = append(violations, v)
+		}))
+
+		result, err := service.CalculateCartDiscounts(ctx, cartItems, testdata.GetSampleCustomers()[0], nil)
+		require.NoError(t, err)
+		assert.True(t, result.FinalPrice.IsZero(), "got %s", result.FinalPrice)
+		require.Len(t, result.Breakdown, 1)
+		assert.Equal(t, "brand-1000", result.Breakdown[0].DiscountID)
+		assert.Empty(t, violations)
+
+		explanation, err := service.ExplainCartDiscounts(ctx, &models.CalculationRequest{CartItems: cartItems, Customer: testdata.GetSampleCustomers()[0]})
+		require.NoError(t, err)
+		decision, ok := explanation.Decision("category-1000")
+		require.True(t, ok)
+		assert.Equal(t, models.ReasonPriorityLoss, decision.Reason)
+	})
+
+	t.Run("Violations describe how to reproduce them", func(t *testing.T) {
+		violation := &services.InvariantViolation{
+			Problems: []string{"final price -600 is negative", "amount due -600 is negative"},
+			Request:  &models.CalculationRequest{CartItems: cartItems},
+			Result:   &models.DiscountedPrice{FinalPrice: decimal.NewFromInt(-600)},
+		}
+		assert.Contains(t, violation.Error(), "final price -600 is negative; amount due -600 is negative")
+		assert.Contains(t, violation.Error(), `"cart_items"`)
+		assert.Contains(t, violation.Error(), `"final_price":"-600"`)
+	})
+}
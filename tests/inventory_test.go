@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscountService_StockConditions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	minimum := 10
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID: "clearance", Name: "Clearance 50%", Type: models.DiscountTypeBrand, ApplicableTo: []string{"PUMA"},
+		Value: decimal.NewFromInt(50), IsPercentage: true,
+		ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true,
+		MinStockThreshold: &minimum,
+	}))
+	inventory := repository.NewInMemoryInventory(map[string]int{"tee": 40, "shorts": 3})
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)), services.WithInventoryProvider(inventory))
+
+	puma := func(id string) models.CartItem {
+		return models.CartItem{Product: models.Product{ID: id, Brand: models.Brand{ID: "PUMA"}, CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 1}
+	}
+	price := func(service interfaces.IDiscountService, items ...models.CartItem) decimal.Decimal {
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{CartItems: items})
+		require.NoError(t, err)
+		return result.FinalPrice
+	}
+
+	t.Run("Only products in the stock range are discounted", func(t *testing.T) {
+		assert.True(t, decimal.NewFromInt(1500).Equal(price(service, puma("tee"), puma("shorts"))))
+		assert.True(t, decimal.NewFromInt(1000).Equal(price(service, puma("socks"))), "unknown stock is not discounted")
+	})
+
+	t.Run("Stock is read as the cart is priced", func(t *testing.T) {
+		inventory.SetStock(ctx, "tee", 9)
+		defer inventory.SetStock(ctx, "tee", 40)
+		assert.True(t, decimal.NewFromInt(1000).Equal(price(service, puma("tee"))))
+
+		explanation, err := service.ExplainCartDiscounts(ctx, &models.CalculationRequest{CartItems: []models.CartItem{puma("tee")}})
+		require.NoError(t, err)
+		decision, ok := explanation.Decision("clearance")
+		require.True(t, ok)
+		assert.Equal(t, models.ReasonStockLevel, decision.Reason)
+		assert.Contains(t, decision.Detail, "at least 10 units")
+	})
+
+	t.Run("Without an inventory provider it never applies", func(t *testing.T) {
+		plain := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+		assert.True(t, decimal.NewFromInt(1000).Equal(price(plain, puma("tee"))))
+	})
+
+	t.Run("The store validates thresholds", func(t *testing.T) {
+		low, high := 5, 2
+		for name, d := range map[string]models.Discount{
+			"not an item discount": {ID: "s1", Type: models.DiscountTypeVoucher, MaxStockThreshold: &low},
+			"empty range":          {ID: "s2", Type: models.DiscountTypeBrand, MinStockThreshold: &low, MaxStockThreshold: &high},
+		} {
+			err := repo.CreateDiscount(ctx, &d)
+			assert.True(t, errors.IsValidationError(err), name)
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/tenant"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func jobRepositories(t *testing.T) map[string]func() interfaces.IJobRepository {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	return map[string]func() interfaces.IJobRepository{
+		"InMemory": repository.NewInMemoryJobRepository,
+		"Redis":    func() interfaces.IJobRepository { return repository.NewRedisJobRepository(client, "test:") },
+	}
+}
+
+func jobCarts(n int) []*models.CalculationRequest {
+	carts := make([]*models.CalculationRequest, n)
+	for i := range carts {
+		price := decimal.NewFromInt(int64(100 * (i + 1)))
+		carts[i] = &models.CalculationRequest{CartItems: []models.CartItem{{
+			Product:  models.Product{ID: "p1", BasePrice: price, CurrentPrice: price},
+			Quantity: 1,
+		}}}
+	}
+	carts[2].CartItems[0].Quantity = 0 // Fails on its own
+	return carts
+}
+
+func waitForJob(t *testing.T, jobs interfaces.IJobService, ctx context.Context, id string) *models.Job {
+	t.Helper()
+	var job *models.Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = jobs.GetJob(ctx, id)
+		require.NoError(t, err)
+		return job.Finished()
+	}, 5*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestJobService_RunsJobsInChunks(t *testing.T) {
+	for name, newRepo := range jobRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := tenant.NewContext(context.Background(), "shop")
+			jobs := services.NewJobService(services.NewDiscountService(repository.NewInMemoryDiscountRepository()), newRepo())
+			jobs.ChunkSize = 2
+			defer jobs.Close()
+
+			submitted, err := jobs.SubmitJob(ctx, &models.BatchCalculationRequest{Carts: jobCarts(5), DryRun: true})
+			require.NoError(t, err)
+			assert.Equal(t, 5, submitted.Total)
+
+			job := waitForJob(t, jobs, ctx, submitted.ID)
+			assert.Equal(t, models.JobSucceeded, job.Status)
+			assert.Equal(t, 5, job.Processed)
+			assert.Equal(t, 4, job.Succeeded)
+			assert.Equal(t, 1, job.Failed)
+			assert.Equal(t, "shop", job.TenantID)
+			assert.NotNil(t, job.FinishedAt)
+
+			page, err := jobs.GetJobResults(ctx, job.ID, 0, 3)
+			require.NoError(t, err)
+			require.Len(t, page.Results, 3)
+			assert.Equal(t, 3, page.NextOffset)
+			assert.True(t, decimal.NewFromInt(200).Equal(page.Results[1].Result.FinalPrice))
+			assert.Nil(t, page.Results[2].Result)
+			assert.Contains(t, page.Results[2].Error, "quantity")
+
+			page, err = jobs.GetJobResults(ctx, job.ID, 3, 3)
+			require.NoError(t, err)
+			require.Len(t, page.Results, 2)
+			assert.Equal(t, 4, page.Results[1].Index)
+			assert.Zero(t, page.NextOffset, "the last page has no next offset")
+
+			_, err = jobs.GetJob(context.Background(), job.ID)
+			assert.True(t, errors.IsNotFoundError(err), "jobs are scoped to their tenant")
+
+			_, err = jobs.SubmitJob(ctx, &models.BatchCalculationRequest{})
+			assert.True(t, errors.IsValidationError(err))
+		})
+	}
+}
+
+func TestJobService_ResumesFromCheckpoint(t *testing.T) {
+	for name, newRepo := range jobRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newRepo()
+
+			// A job cut short after its first chunk, as left behind by a restart
+			job := &models.Job{ID: "job-1", Status: models.JobQueued, DryRun: true, Total: 5}
+			require.NoError(t, repo.CreateJob(ctx, job, jobCarts(5)))
+			job.Status, job.Processed, job.Succeeded = models.JobRunning, 2, 2
+			priced := []models.JobResult{{Index: 0, Error: "first run"}, {Index: 1, Error: "first run"}}
+			require.NoError(t, repo.SaveJobProgress(ctx, job, priced))
+
+			jobs := services.NewJobService(services.NewDiscountService(repository.NewInMemoryDiscountRepository()), repo)
+			jobs.ChunkSize = 2
+			defer jobs.Close()
+			require.NoError(t, jobs.Resume(ctx))
+
+			resumed := waitForJob(t, jobs, ctx, job.ID)
+			assert.Equal(t, models.JobSucceeded, resumed.Status)
+			assert.Equal(t, 5, resumed.Processed)
+			assert.Equal(t, 4, resumed.Succeeded, "the carts of the first run are not counted twice")
+
+			page, err := jobs.GetJobResults(ctx, job.ID, 0, 0)
+			require.NoError(t, err)
+			require.Len(t, page.Results, 5)
+			assert.Equal(t, "first run", page.Results[1].Error, "carts before the checkpoint are not priced again")
+			assert.NotNil(t, page.Results[3].Result)
+
+			unfinished, err := repo.ListUnfinishedJobs(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, unfinished)
+		})
+	}
+}
+
+func TestJobService_ClockAndBatchLimit(t *testing.T) {
+	ctx := context.Background()
+	submittedAt := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	// The default chunk of 100 carts is over the batch limit, so chunks are cut down to it
+	discounts := services.NewDiscountService(repository.NewInMemoryDiscountRepository(),
+		services.WithRequestLimits(services.RequestLimits{MaxBatchCarts: 2}))
+	jobs := services.NewJobService(discounts, repository.NewInMemoryJobRepository())
+	jobs.Clock = clock.NewFixed(submittedAt)
+	defer jobs.Close()
+
+	submitted, err := jobs.SubmitJob(ctx, &models.BatchCalculationRequest{Carts: jobCarts(5), DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, submittedAt, submitted.SubmittedAt)
+
+	job := waitForJob(t, jobs, ctx, submitted.ID)
+	assert.Equal(t, models.JobSucceeded, job.Status, job.Error)
+	assert.Equal(t, 5, job.Processed)
+	assert.Equal(t, submittedAt, job.UpdatedAt.UTC())
+	require.NotNil(t, job.FinishedAt)
+	assert.Equal(t, submittedAt, job.FinishedAt.UTC())
+}
+
+func TestAPI_Jobs(t *testing.T) {
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(testdata.GetSampleDiscounts()))
+	jobs := services.NewJobService(services.NewDiscountService(repo), repository.NewInMemoryJobRepository())
+	defer jobs.Close()
+	h := api.NewHandler(services.NewDiscountService(repo), api.WithJobs(jobs))
+
+	cartItems, customer, paymentInfo := testdata.GetMultipleDiscountScenario()
+	cart := map[string]any{"cart_items": cartItems, "customer": customer, "payment_info": paymentInfo}
+
+	rec, resp := doJSON(t, h, "/v2/jobs", nil, map[string]any{"carts": []any{cart, cart, cart}, "dry_run": true})
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	id, _ := resp["id"].(string)
+	require.NotEmpty(t, id)
+	assert.Equal(t, "/v2/jobs/"+id, rec.Header().Get("Location"))
+	assert.EqualValues(t, 3, resp["total"])
+
+	get := func(path string) (*httptest.ResponseRecorder, map[string]any) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &decoded), rec.Body.String())
+		return rec, decoded
+	}
+	require.Eventually(t, func() bool {
+		_, job := get("/v2/jobs/" + id)
+		return job["status"] == string(models.JobSucceeded)
+	}, 5*time.Second, 5*time.Millisecond)
+
+	rec, resp = get("/v2/jobs/" + id + "/results?limit=2")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, resp["results"], 2)
+	assert.EqualValues(t, 2, resp["next_offset"])
+	first := resp["results"].([]any)[0].(map[string]any)
+	assert.Contains(t, first["result"], "final_price")
+
+	rec, _ = get("/v2/jobs/" + id + "/results?offset=-1")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, _ = get("/v2/jobs/unknown")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec, _ = get("/v1/jobs/" + id)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "jobs are only served from v2")
+
+	assert.Contains(t, h.OpenAPI().Paths, "/v2/jobs/{id}/results")
+	assert.NotContains(t, newTestAPI(t).OpenAPI().Paths, "/v2/jobs", "jobs are only served with a job service")
+}
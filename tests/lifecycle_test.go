@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/actor"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestLifecycleService_Transitions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID:           "spring-sale",
+		Name:         "Spring sale",
+		Type:         models.DiscountTypeVoucher,
+		Value:        decimal.NewFromInt(10),
+		IsPercentage: true,
+		State:        models.LifecycleDraft,
+		ValidFrom:    now.Add(-time.Hour),
+		ValidTo:      now.Add(time.Hour),
+		IsActive:     true,
+	}))
+	lifecycle := services.NewLifecycleService(repo, services.RoleAuthorizer{Roles: map[string][]models.Role{
+		"erin":  {models.RoleEditor},
+		"alice": {models.RoleApprover},
+		"sam":   {models.RoleEditor, models.RoleApprover},
+	}})
+	as := func(who string) context.Context { return clock.NewContext(actor.NewContext(ctx, who), now) }
+	active := func() []string {
+		discounts, err := repo.GetActiveDiscounts(clock.NewContext(ctx, now))
+		require.NoError(t, err)
+		var ids []string
+		for _, d := range discounts {
+			ids = append(ids, d.ID)
+		}
+		return ids
+	}
+
+	assert.Empty(t, active(), "drafts are not priced")
+	_, err := lifecycle.Transition(as("erin"), "spring-sale", models.ActionApprove)
+	assert.True(t, errors.IsConflictError(err), "drafts must be submitted first, got %v", err)
+	_, err = lifecycle.Transition(as("alice"), "spring-sale", models.ActionSubmit)
+	assert.True(t, errors.IsForbiddenError(err), "approvers do not submit, got %v", err)
+
+	d, err := lifecycle.Transition(as("sam"), "spring-sale", models.ActionSubmit)
+	require.NoError(t, err)
+	assert.Equal(t, models.LifecyclePendingApproval, d.State)
+	assert.Equal(t, "sam", d.SubmittedBy)
+	_, err = lifecycle.Transition(as("sam"), "spring-sale", models.ActionApprove)
+	assert.True(t, errors.IsForbiddenError(err), "nobody approves their own submission, got %v", err)
+	assert.Empty(t, active(), "pending discounts are not priced")
+
+	d, err = lifecycle.Transition(as("alice"), "spring-sale", models.ActionApprove)
+	require.NoError(t, err)
+	assert.Equal(t, models.LifecycleActive, d.State)
+	assert.Equal(t, []string{"spring-sale"}, active())
+
+	_, err = lifecycle.Transition(as("alice"), "spring-sale", models.ActionPause)
+	require.NoError(t, err)
+	assert.Empty(t, active(), "paused discounts are not priced")
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 1}}
+	explanation, err := service.ExplainCartDiscounts(ctx, &models.CalculationRequest{CartItems: cart})
+	require.NoError(t, err)
+	decision, ok := explanation.Decision("spring-sale")
+	require.True(t, ok)
+	assert.Equal(t, models.ReasonUnpublished, decision.Reason)
+
+	_, err = lifecycle.Transition(as("alice"), "spring-sale", models.ActionResume)
+	require.NoError(t, err)
+	_, err = lifecycle.Transition(as("alice"), "spring-sale", models.ActionExpire)
+	require.NoError(t, err)
+	assert.Empty(t, active())
+	_, err = lifecycle.Transition(as("alice"), "spring-sale", models.ActionResume)
+	assert.True(t, errors.IsConflictError(err), "expiry is final, got %v", err)
+	_, err = lifecycle.Transition(as("alice"), "spring-sale", "publish")
+	assert.True(t, errors.IsValidationError(err))
+
+	assert.True(t, errors.IsValidationError(repo.CreateDiscount(ctx, &models.Discount{ID: "odd", Type: models.DiscountTypeVoucher, State: "LIVE"})))
+}
+
+func TestAPI_LifecycleTransitions(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{ID: "draft", Type: models.DiscountTypeVoucher, State: models.LifecycleDraft}))
+	lifecycle := services.NewLifecycleService(repo, services.RoleAuthorizer{Roles: map[string][]models.Role{"erin": {models.RoleEditor}}})
+	h := api.NewHandler(services.NewDiscountService(repo), api.WithLifecycle(lifecycle), api.WithTrustedActorHeader())
+	untrusted := api.NewHandler(services.NewDiscountService(repo), api.WithLifecycle(lifecycle))
+
+	send := func(h http.Handler, who, action string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v2/discounts/draft/transitions", strings.NewReader(`{"action":"`+action+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if who != "" {
+			req.Header.Set(api.ActorHeader, who)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	transition := func(who, action string) *httptest.ResponseRecorder { return send(h, who, action) }
+
+	assert.Equal(t, http.StatusForbidden, transition("", "submit").Code, "anonymous callers cannot transition")
+	assert.Equal(t, http.StatusForbidden, send(untrusted, "erin", "submit").Code, "the actor header is only trusted behind a gateway")
+	rec := transition("erin", "submit")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var d models.Discount
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &d))
+	assert.Equal(t, models.LifecyclePendingApproval, d.State)
+	assert.Equal(t, http.StatusConflict, transition("erin", "submit").Code)
+}
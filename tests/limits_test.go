@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestDiscountService_RequestLimits(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	var discounts []models.Discount
+	for i := 1; i <= 5; i++ {
+		discounts = append(discounts, models.Discount{
+			ID:        fmt.Sprintf("disc-%d", i),
+			Name:      fmt.Sprintf("Voucher %d", i),
+			Type:      models.DiscountTypeVoucher,
+			Value:     decimal.NewFromInt(10),
+			ValidFrom: now.Add(-time.Hour),
+			ValidTo:   now.Add(time.Hour),
+			IsActive:  true,
+			Priority:  i,
+		})
+	}
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(discounts))
+
+	service := services.NewDiscountService(repo, services.WithRequestLimits(services.RequestLimits{
+		MaxCartItems: 3,
+		MaxCodes:     2,
+		MaxDiscounts: 2,
+	}))
+
+	item := models.CartItem{Product: testdata.GetSampleProducts()[3], Quantity: 1, Size: "32"} // 1200
+	customer := testdata.GetSampleCustomers()[1]
+
+	t.Run("Strict mode rejects oversized requests", func(t *testing.T) {
+		_, err := service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems: []models.CartItem{item, item, item, item},
+			Customer:  customer,
+		})
+		require.True(t, errors.IsValidationError(err))
+		assert.Contains(t, err.Error(), "4 cart items, more than the limit of 3")
+
+		_, err = service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems: []models.CartItem{item},
+			Customer:  customer,
+			Codes:     []string{"A", "B", "C"},
+		})
+		assert.True(t, errors.IsValidationError(err))
+	})
+
+	t.Run("Lenient mode truncates with warnings", func(t *testing.T) {
+		req := &models.CalculationRequest{
+			CartItems:      []models.CartItem{item, item, item, item, item},
+			Customer:       customer,
+			Codes:          []string{"A", "B", "C"},
+			ValidationMode: models.ValidationLenient,
+		}
+		result, err := service.CalculateCart(ctx, req)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(3600).Equal(result.OriginalPrice))
+		assert.Contains(t, result.Warnings, "ignored 2 of 5 cart items over the limit of 3")
+		assert.Contains(t, result.Warnings, "ignored 1 of 3 codes over the limit of 2")
+		assert.Len(t, req.CartItems, 5, "the caller's request is left as it was")
+	})
+
+	t.Run("Only the highest-priority discounts are considered", func(t *testing.T) {
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems: []models.CartItem{item},
+			Customer:  customer,
+		})
+		require.NoError(t, err)
+		assert.Len(t, result.Breakdown, 2)
+		assert.Equal(t, "disc-5", result.Breakdown[0].DiscountID)
+		assert.Equal(t, "disc-4", result.Breakdown[1].DiscountID)
+		assert.Equal(t, []string{"considered only the 2 highest-priority of 5 discounts"}, result.Warnings)
+	})
+}
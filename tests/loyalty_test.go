@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestDiscountService_PointsRedemption(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	points := models.Discount{
+		ID:        "disc-points",
+		Name:      "Loyalty points",
+		Type:      models.DiscountTypePointsRedemption,
+		Value:     decimal.NewFromFloat(0.5), // 1 point = 0.50
+		MaxAmount: decimal.NewFromInt(300),
+		ValidFrom: now.Add(-time.Hour),
+		ValidTo:   now.Add(time.Hour),
+		IsActive:  true,
+		Priority:  10,
+	}
+
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &points))
+
+	loyalty := repository.NewInMemoryLoyaltyProvider(map[string]int64{"cust-002": 1000})
+	service := services.NewDiscountService(repo,
+		services.WithLoyaltyProvider(loyalty),
+		services.WithPointsEarnRate(decimal.New(1, -2)), // 1 point per 100 spent
+	)
+
+	customer := testdata.GetSampleCustomers()[1]
+	cartItems := []models.CartItem{{Product: testdata.GetSampleProducts()[3], Quantity: 1, Size: "32"}} // 1200
+
+	t.Run("Points are only burnt on request", func(t *testing.T) {
+		customer := customer
+		customer.PointsBalance = 1000000 // balances supplied by the caller are ignored
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{CartItems: cartItems, Customer: customer})
+		require.NoError(t, err)
+		assert.Empty(t, result.AppliedDiscounts)
+		assert.Equal(t, int64(12), result.PointsEarned)
+	})
+
+	t.Run("Redemption is capped and burns the matching points", func(t *testing.T) {
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems:    cartItems,
+			Customer:     customer,
+			RedeemPoints: true,
+		})
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(300).Equal(result.AppliedDiscounts["Loyalty points"]))
+		assert.Equal(t, int64(600), result.PointsRedeemed)
+		assert.Equal(t, int64(9), result.PointsEarned) // on the 900 final price
+
+		balance, err := loyalty.GetPointsBalance(ctx, customer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(400), balance)
+	})
+
+	t.Run("Remaining balance limits the credit", func(t *testing.T) {
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems:    cartItems,
+			Customer:     customer,
+			RedeemPoints: true,
+		})
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(200).Equal(result.AppliedDiscounts["Loyalty points"]))
+		assert.Equal(t, int64(400), result.PointsRedeemed)
+	})
+}
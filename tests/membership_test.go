@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscountService_MembersOnlyPricing(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID: "club-extra", Name: "Club extra 5%", Type: models.DiscountTypeVoucher,
+		Value: decimal.NewFromInt(5), IsPercentage: true,
+		ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true,
+		MembershipPlans: []string{"club"},
+	}))
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 1}}
+	calculate := func(customer models.CustomerProfile) *models.DiscountedPrice {
+		result, err := service.CalculateCartDiscounts(ctx, cart, customer, nil)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("Members get the discount automatically", func(t *testing.T) {
+		result := calculate(models.CustomerProfile{ID: "ann", Membership: &models.Membership{Plan: "Club", ExpiresAt: now.AddDate(0, 1, 0)}})
+		assert.True(t, decimal.NewFromInt(950).Equal(result.FinalPrice))
+		require.NotNil(t, result.MemberSavings)
+		assert.Equal(t, "Club", result.MemberSavings.Plan)
+		assert.True(t, decimal.NewFromInt(50).Equal(result.MemberSavings.Amount))
+	})
+
+	t.Run("Others pay full price", func(t *testing.T) {
+		for _, customer := range []models.CustomerProfile{
+			{ID: "bob"},
+			{ID: "cat", Membership: &models.Membership{Plan: "club", ExpiresAt: now}},
+			{ID: "dan", Membership: &models.Membership{Plan: "gold"}},
+		} {
+			result := calculate(customer)
+			assert.True(t, decimal.NewFromInt(1000).Equal(result.FinalPrice), customer.ID)
+			assert.Nil(t, result.MemberSavings, customer.ID)
+		}
+	})
+
+	t.Run("Explanations name the plans", func(t *testing.T) {
+		explanation, err := service.ExplainCartDiscounts(ctx, &models.CalculationRequest{CartItems: cart, Customer: models.CustomerProfile{ID: "bob"}})
+		require.NoError(t, err)
+		decision, ok := explanation.Decision("club-extra")
+		require.True(t, ok)
+		assert.Equal(t, models.ReasonMembershipRequired, decision.Reason)
+		assert.Contains(t, decision.Detail, "club")
+	})
+
+	t.Run("Offers are for members only", func(t *testing.T) {
+		offers, err := service.ListOffers(ctx, models.CustomerProfile{ID: "bob"})
+		require.NoError(t, err)
+		assert.Empty(t, offers)
+		offers, err = service.ListOffers(ctx, models.CustomerProfile{ID: "ann", Membership: &models.Membership{Plan: "club"}})
+		require.NoError(t, err)
+		assert.Len(t, offers, 1)
+	})
+
+	err := repo.CreateDiscount(ctx, &models.Discount{ID: "bad", Type: models.DiscountTypeVoucher, MembershipPlans: []string{""}})
+	assert.True(t, errors.IsValidationError(err))
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+)
+
+func TestDiscountService_NearMissDiscounts(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	discount := func(id string, d models.Discount) *models.Discount {
+		d.ID, d.Name, d.Value, d.IsPercentage = id, id, decimal.NewFromInt(10), true
+		d.ValidFrom, d.ValidTo, d.IsActive = now.Add(-time.Hour), now.Add(time.Hour), true
+		return &d
+	}
+	repo := repository.NewInMemoryDiscountRepository()
+	for _, d := range []*models.Discount{
+		discount("over-2000", models.Discount{Type: models.DiscountTypeVoucher, MinAmount: decimal.NewFromInt(2000)}),
+		discount("three-pumas", models.Discount{Type: models.DiscountTypeBrand, ApplicableTo: []string{"PUMA"}, MinQuantity: 3}),
+		discount("gold-over-2000", models.Discount{Type: models.DiscountTypeVoucher, MinAmount: decimal.NewFromInt(2000), CustomerTiers: []string{"gold"}}),
+		discount("nike-over-2000", models.Discount{Type: models.DiscountTypeVoucher, MinAmount: decimal.NewFromInt(2000), ApplicableTo: []string{"NIKE"}}),
+		discount("over-1000", models.Discount{Type: models.DiscountTypeVoucher, MinAmount: decimal.NewFromInt(1000)}),
+	} {
+		require.NoError(t, repo.CreateDiscount(ctx, d))
+	}
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", Brand: models.Brand{ID: "PUMA"}, CurrentPrice: decimal.NewFromInt(600)}, Quantity: 2}}
+	result, err := service.CalculateCartDiscounts(ctx, cart, models.CustomerProfile{ID: "c1", Tier: "regular"}, nil)
+	require.NoError(t, err)
+
+	nearMisses := make(map[string]models.NearMissDiscount)
+	for _, nearMiss := range result.NearMissDiscounts {
+		nearMisses[nearMiss.DiscountID] = nearMiss
+	}
+	require.Len(t, nearMisses, 2, "applied discounts and those failing other conditions are left out")
+
+	amount := nearMisses["over-2000"]
+	assert.Equal(t, models.ReasonMinAmountNotMet, amount.Reason)
+	assert.True(t, decimal.NewFromInt(800).Equal(amount.AmountGap), "got %s", amount.AmountGap)
+	assert.Equal(t, "Add 800 more to unlock over-2000", amount.Message)
+
+	quantity := nearMisses["three-pumas"]
+	assert.Equal(t, models.ReasonMinQuantityNotMet, quantity.Reason)
+	assert.Equal(t, 1, quantity.UnitsGap)
+	assert.True(t, quantity.AmountGap.IsZero())
+}
+
+func TestDiscountService_BankOfferMinimum(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID: "icici-over-5000", Name: "icici-over-5000", Type: models.DiscountTypeBank, Value: decimal.NewFromInt(10),
+		IsPercentage: true, MinAmount: decimal.NewFromInt(5000), ApplicableTo: []string{"ICICI"},
+		ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true,
+	}))
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+
+	bank := "ICICI"
+	payment := &models.PaymentInfo{Method: models.Card, BankName: &bank}
+	line := func(price int64, optedOut bool) []models.CartItem {
+		return []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(price)},
+			Quantity: 1, ExcludeFromPromotions: optedOut}}
+	}
+	customer := models.CustomerProfile{ID: "c1"}
+
+	result, err := service.CalculateCartDiscounts(ctx, line(500, false), customer, payment)
+	require.NoError(t, err)
+	assert.Empty(t, result.AppliedDiscounts)
+	assert.True(t, decimal.NewFromInt(500).Equal(result.FinalPrice))
+	require.Len(t, result.NearMissDiscounts, 1)
+	assert.Equal(t, models.ReasonMinAmountNotMet, result.NearMissDiscounts[0].Reason)
+	assert.True(t, decimal.NewFromInt(4500).Equal(result.NearMissDiscounts[0].AmountGap))
+
+	result, err = service.CalculateCartDiscounts(ctx, line(6000, false), customer, payment)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(5400).Equal(result.FinalPrice), result.FinalPrice.String())
+
+	explain := func(cart []models.CartItem) models.DecisionReason {
+		explanation, err := service.ExplainCartDiscounts(ctx,
+			&models.CalculationRequest{CartItems: cart, Customer: customer, PaymentInfo: payment})
+		require.NoError(t, err)
+		decision, ok := explanation.Decision("icici-over-5000")
+		require.True(t, ok)
+		return decision.Reason
+	}
+	assert.Equal(t, models.ReasonMinAmountNotMet, explain(line(500, false)))
+	assert.Equal(t, models.ReasonItemsOptedOut, explain(line(6000, true)))
+}
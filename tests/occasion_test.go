@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscount_IsOccasionAt(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	birthday := &models.Discount{Occasion: &models.Occasion{Kind: models.OccasionBirthday, WindowDays: 3}}
+	anniversary := &models.Discount{Occasion: &models.Occasion{Kind: models.OccasionAnniversary}}
+
+	tests := []struct {
+		name     string
+		discount *models.Discount
+		customer models.CustomerProfile
+		now      time.Time
+		want     bool
+	}{
+		{"On the birthday", birthday, models.CustomerProfile{DateOfBirth: date(1990, 6, 15)}, date(2024, 6, 15).Add(20 * time.Hour), true},
+		{"Days before", birthday, models.CustomerProfile{DateOfBirth: date(1990, 6, 15)}, date(2024, 6, 12), true},
+		{"Days after", birthday, models.CustomerProfile{DateOfBirth: date(1990, 6, 15)}, date(2024, 6, 18).Add(23 * time.Hour), true},
+		{"Past the window", birthday, models.CustomerProfile{DateOfBirth: date(1990, 6, 15)}, date(2024, 6, 19), false},
+		{"Across new year", birthday, models.CustomerProfile{DateOfBirth: date(1990, 1, 1)}, date(2024, 12, 30), true},
+		{"Leap day in a common year", birthday, models.CustomerProfile{DateOfBirth: date(2000, 2, 29)}, date(2023, 3, 3), true},
+		{"No date of birth", birthday, models.CustomerProfile{}, date(2024, 6, 15), false},
+		{"Anniversary", anniversary, models.CustomerProfile{RegisteredAt: date(2022, 5, 1).Add(9 * time.Hour)}, date(2024, 5, 1), true},
+		{"Not the signup day itself", anniversary, models.CustomerProfile{RegisteredAt: date(2024, 5, 1)}, date(2024, 5, 1), false},
+		{"No occasion", &models.Discount{}, models.CustomerProfile{}, date(2024, 5, 1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.discount.IsOccasionAt(tt.customer, tt.now))
+		})
+	}
+}
+
+func TestDiscountService_BirthdayDiscount(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 14, 12, 0, 0, 0, time.UTC)
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID: "birthday", Name: "Happy birthday", Type: models.DiscountTypeVoucher,
+		Value: decimal.NewFromInt(15), IsPercentage: true,
+		ValidFrom: now.AddDate(-1, 0, 0), ValidTo: now.AddDate(1, 0, 0), IsActive: true,
+		Occasion: &models.Occasion{Kind: models.OccasionBirthday, WindowDays: 7},
+	}))
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 1}}
+	price := func(customer models.CustomerProfile) decimal.Decimal {
+		result, err := service.CalculateCartDiscounts(ctx, cart, customer, nil)
+		require.NoError(t, err)
+		return result.FinalPrice
+	}
+	celebrating := models.CustomerProfile{ID: "ann", DateOfBirth: time.Date(1990, 6, 20, 0, 0, 0, 0, time.UTC)}
+	assert.True(t, decimal.NewFromInt(850).Equal(price(celebrating)))
+	assert.True(t, decimal.NewFromInt(1000).Equal(price(models.CustomerProfile{ID: "bob", DateOfBirth: time.Date(1990, 7, 20, 0, 0, 0, 0, time.UTC)})))
+
+	offers, err := service.ListOffers(ctx, models.CustomerProfile{ID: "cat"})
+	require.NoError(t, err)
+	assert.Empty(t, offers, "customers without a date of birth are not offered it")
+
+	explanation, err := service.ExplainCartDiscounts(ctx, &models.CalculationRequest{CartItems: cart, Customer: models.CustomerProfile{ID: "cat"}})
+	require.NoError(t, err)
+	decision, ok := explanation.Decision("birthday")
+	require.True(t, ok)
+	assert.Equal(t, models.ReasonOutsideOccasion, decision.Reason)
+	assert.Contains(t, decision.Detail, "birthday")
+
+	for _, occasion := range []models.Occasion{{Kind: "christmas"}, {Kind: models.OccasionBirthday, WindowDays: -1}, {Kind: models.OccasionBirthday, WindowDays: 90}} {
+		err := repo.CreateDiscount(ctx, &models.Discount{ID: "bad", Type: models.DiscountTypeVoucher, Occasion: &occasion})
+		assert.True(t, errors.IsValidationError(err), occasion)
+	}
+}
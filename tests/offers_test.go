@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestDiscountService_ListOffers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	discounts := []models.Discount{
+		{
+			ID: "flash", Name: "Flash sale", Type: models.DiscountTypeVoucher, Code: "FLASH",
+			Value: decimal.NewFromInt(20), IsPercentage: true,
+			ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(2 * time.Hour),
+			IsActive: true, UsageLimit: 100, UsedCount: 95, Priority: 50,
+		},
+		{
+			ID: "evergreen", Name: "Evergreen", Type: models.DiscountTypeCategory,
+			Value: decimal.NewFromInt(5), IsPercentage: true,
+			ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(30 * 24 * time.Hour),
+			IsActive: true, Priority: 90,
+		},
+		{
+			ID: "vip", Name: "VIP only", Type: models.DiscountTypeBrand,
+			Value: decimal.NewFromInt(10), IsPercentage: true, CustomerTiers: []string{"premium"},
+			ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour),
+			IsActive: true, Priority: 70,
+		},
+		{
+			ID: "expired", Name: "Expired", Type: models.DiscountTypeBrand,
+			Value: decimal.NewFromInt(10), IsPercentage: true,
+			ValidFrom: now.Add(-48 * time.Hour), ValidTo: now.Add(-time.Hour),
+			IsActive: true, Priority: 100,
+		},
+	}
+
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(discounts))
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+
+	offers, err := service.ListOffers(ctx, models.CustomerProfile{ID: "cust", Tier: "regular"})
+	require.NoError(t, err)
+	require.Len(t, offers, 2)
+
+	assert.Equal(t, "evergreen", offers[0].DiscountID)
+	assert.Equal(t, int64(30*24*3600), offers[0].Urgency.SecondsUntilExpiry)
+	assert.Nil(t, offers[0].Urgency.ClaimedPercent)
+
+	flash := offers[1]
+	assert.Equal(t, "FLASH", flash.Code)
+	assert.Equal(t, now.Add(2*time.Hour), flash.ExpiresAt)
+	assert.Equal(t, int64(7200), flash.Urgency.SecondsUntilExpiry)
+	require.NotNil(t, flash.Urgency.ClaimedPercent)
+	assert.Equal(t, 90, *flash.Urgency.ClaimedPercent)
+}
+
+func TestOfferRankers(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	offers := func() []models.Offer {
+		return []models.Offer{
+			{DiscountID: "brand", Type: models.DiscountTypeBrand, Value: decimal.NewFromInt(40), IsPercentage: true, Priority: 100, ExpiresAt: now.Add(72 * time.Hour)},
+			{DiscountID: "bank", Type: models.DiscountTypeBank, Value: decimal.NewFromInt(10), IsPercentage: true, MaxAmount: decimal.NewFromInt(50), Priority: 80, ExpiresAt: now.Add(time.Hour)},
+			{DiscountID: "flat", Type: models.DiscountTypeVoucher, Value: decimal.NewFromInt(250), Priority: 60, ExpiresAt: now.Add(24 * time.Hour)},
+		}
+	}
+	ids := func(offers []models.Offer) []string {
+		var ids []string
+		for _, o := range offers {
+			ids = append(ids, o.DiscountID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name     string
+		ranker   interfaces.IOfferRanker
+		customer models.CustomerProfile
+		expected []string
+	}{
+		{"Priority", services.PriorityRanker{}, models.CustomerProfile{}, []string{"brand", "bank", "flat"}},
+		{"Expiry", services.ExpiryRanker{}, models.CustomerProfile{}, []string{"bank", "flat", "brand"}},
+		{"Savings on a small basket", services.SavingsRanker{BasketTotal: decimal.NewFromInt(500)}, models.CustomerProfile{}, []string{"flat", "brand", "bank"}},
+		{"Savings on a large basket", services.SavingsRanker{BasketTotal: decimal.NewFromInt(1000)}, models.CustomerProfile{}, []string{"brand", "flat", "bank"}},
+		{
+			"Segment preference",
+			services.SegmentRanker{
+				Preferences: map[string][]models.DiscountType{"premium": {models.DiscountTypeBank}},
+				Fallback:    services.ExpiryRanker{},
+			},
+			models.CustomerProfile{Tier: "premium"},
+			[]string{"bank", "flat", "brand"},
+		},
+		{
+			"Segment without preferences uses the fallback",
+			services.SegmentRanker{
+				Preferences: map[string][]models.DiscountType{"premium": {models.DiscountTypeBank}},
+				Fallback:    services.PriorityRanker{},
+			},
+			models.CustomerProfile{Tier: "regular"},
+			[]string{"brand", "bank", "flat"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranked := offers()
+			tt.ranker.Rank(ranked, tt.customer)
+			assert.Equal(t, tt.expected, ids(ranked))
+		})
+	}
+}
+
+func TestDiscountService_ListOffersWithRanker(t *testing.T) {
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(testdata.GetSampleDiscounts()))
+	service := services.NewDiscountService(repo, services.WithOfferRanker(services.ExpiryRanker{}))
+
+	offers, err := service.ListOffers(context.Background(), testdata.GetSampleCustomers()[0])
+	require.NoError(t, err)
+	require.NotEmpty(t, offers)
+	for i := 1; i < len(offers); i++ {
+		assert.False(t, offers[i].ExpiresAt.Before(offers[i-1].ExpiresAt))
+	}
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_OpenAPIDocument(t *testing.T) {
+	h := newTestAPI(t)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+
+	paths := doc["paths"].(map[string]any)
+	for _, path := range []string{"/v1/cart/calculate", "/v2/cart/calculate", "/v2/cart/calculate/batch",
+		"/v2/cart/explain", "/v1/codes/validate", "/v2/codes/validate", "/v2/offers"} {
+		assert.Contains(t, paths, path)
+	}
+	assert.NotContains(t, paths, "/v1/cart/explain", "routes a version does not serve are left out")
+
+	v1 := paths["/v1/cart/calculate"].(map[string]any)["post"].(map[string]any)
+	v2 := paths["/v2/cart/calculate"].(map[string]any)["post"].(map[string]any)
+	assert.Equal(t, true, v1["deprecated"])
+	assert.NotContains(t, v2, "deprecated")
+	assert.Equal(t, "v2CalculateCart", v2["operationId"])
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	property := func(schema, name string) map[string]any {
+		t.Helper()
+		require.Contains(t, schemas, schema)
+		properties := schemas[schema].(map[string]any)["properties"].(map[string]any)
+		require.Contains(t, properties, name, schema)
+		return properties[name].(map[string]any)
+	}
+	assert.Equal(t, map[string]any{"type": "string", "format": "decimal"}, property("V2CalculateResponse", "final_price"))
+	assert.Equal(t, "boolean", property("CartItem", "exclude_from_promotions")["type"])
+	assert.Equal(t, "#/components/schemas/Product", property("CartItem", "product")["$ref"])
+	assert.Equal(t, "array", property("V2CalculateResponse", "discounts")["type"])
+	assert.NotContains(t, schemas["V1CalculateResponse"].(map[string]any)["properties"], "discounts",
+		"v1 keeps its original shape")
+
+	t.Run("Every reference resolves", func(t *testing.T) {
+		var walk func(v any)
+		walk = func(v any) {
+			switch v := v.(type) {
+			case map[string]any:
+				if ref, ok := v["$ref"].(string); ok {
+					assert.Contains(t, schemas, strings.TrimPrefix(ref, "#/components/schemas/"), ref)
+				}
+				for _, child := range v {
+					walk(child)
+				}
+			case []any:
+				for _, child := range v {
+					walk(child)
+				}
+			}
+		}
+		walk(doc)
+	})
+
+	t.Run("Swagger UI", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+		assert.Contains(t, rec.Body.String(), `url: "/openapi.json"`)
+	})
+}
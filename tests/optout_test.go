@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+)
+
+func TestDiscountService_ItemsExcludedFromPromotions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	repo := repository.NewInMemoryDiscountRepository()
+	for _, d := range []*models.Discount{
+		{ID: "brand", Type: models.DiscountTypeBrand, Value: decimal.NewFromInt(10), IsPercentage: true,
+			ApplicableTo: []string{"puma"}, Priority: 3},
+		{ID: "voucher", Type: models.DiscountTypeVoucher, Value: decimal.NewFromInt(100),
+			MinAmount: decimal.NewFromInt(1000), Priority: 2},
+		{ID: "bank", Type: models.DiscountTypeBank, Value: decimal.NewFromInt(10), IsPercentage: true, Priority: 1},
+	} {
+		d.Name = d.ID
+		d.ValidFrom, d.ValidTo, d.IsActive = now.Add(-time.Hour), now.Add(time.Hour), true
+		require.NoError(t, repo.CreateDiscount(ctx, d))
+	}
+	service := services.NewDiscountService(repo)
+
+	puma := models.Brand{ID: "puma", Name: "PUMA"}
+	priceMatched := models.CartItem{
+		Product:               models.Product{ID: "p1", Brand: puma, CurrentPrice: decimal.NewFromInt(1000)},
+		Quantity:              1,
+		ExcludeFromPromotions: true,
+	}
+	regular := models.CartItem{Product: models.Product{ID: "p2", Brand: puma, CurrentPrice: decimal.NewFromInt(500)}, Quantity: 1}
+	card := &models.PaymentInfo{Method: models.Card}
+
+	req := &models.CalculationRequest{CartItems: []models.CartItem{priceMatched, regular}, PaymentInfo: card}
+	result, err := service.CalculateCart(ctx, req)
+	require.NoError(t, err)
+
+	assert.True(t, decimal.NewFromInt(1500).Equal(result.OriginalPrice), "excluded lines still count towards the total")
+	require.Len(t, result.Breakdown, 2)
+	assert.Equal(t, "brand", result.Breakdown[0].DiscountID)
+	assert.True(t, decimal.NewFromInt(50).Equal(result.Breakdown[0].Amount), "only the regular line is discounted")
+	assert.Equal(t, "bank", result.Breakdown[1].DiscountID)
+	assert.True(t, decimal.NewFromInt(45).Equal(result.Breakdown[1].Amount), "cart-wide discounts leave the excluded line alone")
+	assert.True(t, decimal.NewFromInt(1405).Equal(result.FinalPrice))
+
+	explanation, err := service.ExplainCartDiscounts(ctx, req)
+	require.NoError(t, err)
+	decision, _ := explanation.Decision("voucher")
+	assert.Equal(t, models.ReasonMinAmountNotMet, decision.Reason, "excluded lines do not count towards minimums")
+
+	t.Run("Every line excluded", func(t *testing.T) {
+		req := &models.CalculationRequest{CartItems: []models.CartItem{priceMatched}, PaymentInfo: card}
+		result, err := service.CalculateCart(ctx, req)
+		require.NoError(t, err)
+		assert.Empty(t, result.Breakdown)
+		assert.True(t, decimal.NewFromInt(1000).Equal(result.FinalPrice))
+
+		explanation, err := service.ExplainCartDiscounts(ctx, req)
+		require.NoError(t, err)
+		for _, id := range []string{"brand", "voucher"} {
+			decision, _ := explanation.Decision(id)
+			assert.Equal(t, models.ReasonItemsOptedOut, decision.Reason, id)
+		}
+	})
+}
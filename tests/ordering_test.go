@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/discounttest"
+)
+
+// reversedRepository returns discounts in the reverse of the store's order, to show
+// application order does not depend on the order a backend lists discounts in
+type reversedRepository struct {
+	*discounttest.Repository
+}
+
+func (r reversedRepository) GetApplicableDiscounts(ctx context.Context, filter models.DiscountFilter) ([]models.Discount, error) {
+	discounts, err := r.Repository.GetApplicableDiscounts(ctx, filter)
+	for i, j := 0, len(discounts)-1; i < j; i, j = i+1, j-1 {
+		discounts[i], discounts[j] = discounts[j], discounts[i]
+	}
+	return discounts, err
+}
+
+func TestDiscountService_TieBreaking(t *testing.T) {
+	ctx := context.Background()
+	cart := discounttest.Cart().Add(discounttest.Product("tee", "PUMA", "tshirts", "1000"), 1).PaidByCard("HDFC", models.Credit)
+
+	tests := []struct {
+		name      string
+		discounts []models.Discount
+		want      []string // Discount IDs in application order
+		final     int64
+	}{
+		{
+			// Voucher first: 300 off, then 20% of 700. Bank first would leave 500.
+			name: "Equal priorities apply by type",
+			discounts: []models.Discount{
+				discounttest.Discount("a-bank", models.DiscountTypeBank).Percent("20").On("HDFC").Build(),
+				discounttest.Discount("z-voucher", models.DiscountTypeVoucher).Percent("50").MaxAmount("300").Build(),
+			},
+			want:  []string{"z-voucher", "a-bank"},
+			final: 560,
+		},
+		{
+			name: "Equal priorities and types apply by ID",
+			discounts: []models.Discount{
+				discounttest.Discount("b", models.DiscountTypeVoucher).Percent("20").Build(),
+				discounttest.Discount("a", models.DiscountTypeVoucher).Percent("50").MaxAmount("300").Build(),
+			},
+			want:  []string{"a", "b"},
+			final: 560,
+		},
+		{
+			name: "Priority comes first",
+			discounts: []models.Discount{
+				discounttest.Discount("a", models.DiscountTypeVoucher).Percent("50").MaxAmount("300").Build(),
+				discounttest.Discount("b", models.DiscountTypeVoucher).Percent("20").Priority(1).Build(),
+			},
+			want:  []string{"b", "a"},
+			final: 500,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := discounttest.NewRepository(t, tt.discounts...)
+			for _, svc := range []interfaces.IDiscountService{
+				services.NewDiscountService(repo),
+				services.NewDiscountService(reversedRepository{repo}),
+			} {
+				for run := 0; run < 20; run++ {
+					result, err := svc.CalculateCart(ctx, cart.Request())
+					require.NoError(t, err)
+					var order []string
+					for _, line := range result.Breakdown {
+						order = append(order, line.DiscountID)
+					}
+					assert.Equal(t, tt.want, order)
+					assert.True(t, result.FinalPrice.Equal(decimal.NewFromInt(tt.final)), result.FinalPrice.String())
+				}
+			}
+		})
+	}
+}
+
+func TestDiscount_AppliesBefore(t *testing.T) {
+	d := func(id string, discountType models.DiscountType, priority int) *models.Discount {
+		return &models.Discount{ID: id, Type: discountType, Priority: priority}
+	}
+
+	assert.True(t, d("z", models.DiscountTypeBank, 2).AppliesBefore(d("a", models.DiscountTypeBrand, 1)))
+	assert.True(t, d("z", models.DiscountTypeBrand, 1).AppliesBefore(d("a", models.DiscountTypeCategory, 1)))
+	assert.True(t, d("z", models.DiscountTypeVoucher, 1).AppliesBefore(d("a", models.DiscountTypeBank, 1)))
+	assert.True(t, d("z", models.DiscountTypeBank, 1).AppliesBefore(d("a", "affiliate", 1)), "custom types come after built-in ones")
+	assert.True(t, d("z", "affiliate", 1).AppliesBefore(d("a", "influencer", 1)))
+	assert.True(t, d("a", models.DiscountTypeBrand, 1).AppliesBefore(d("b", models.DiscountTypeBrand, 1)))
+	assert.False(t, d("a", models.DiscountTypeBrand, 1).AppliesBefore(d("a", models.DiscountTypeBrand, 1)))
+}
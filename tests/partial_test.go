@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestDiscountService_PartialResults(t *testing.T) {
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(testdata.GetSampleDiscounts()))
+	service := services.NewDiscountService(repo)
+
+	req := func(allowPartial bool) *models.CalculationRequest {
+		return &models.CalculationRequest{
+			CartItems:    testdata.GetSampleCartItems(),
+			Customer:     testdata.GetSampleCustomers()[0],
+			AllowPartial: allowPartial,
+		}
+	}
+
+	full, err := service.CalculateCart(context.Background(), req(false))
+	require.NoError(t, err)
+	require.NotEmpty(t, full.Breakdown)
+	assert.False(t, full.Partial)
+
+	t.Run("Expired deadline returns the undiscounted price", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Millisecond))
+		defer cancel()
+
+		result, err := service.CalculateCart(ctx, req(true))
+		require.NoError(t, err)
+		assert.True(t, result.Partial)
+		assert.Empty(t, result.Breakdown)
+		assert.True(t, result.FinalPrice.Equal(result.OriginalPrice))
+		require.Len(t, result.Warnings, 1)
+		assert.Contains(t, result.Warnings[0], "deadline reached")
+	})
+
+	t.Run("Deadline met gives the full result", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		result, err := service.CalculateCart(ctx, req(true))
+		require.NoError(t, err)
+		assert.False(t, result.Partial)
+		assert.True(t, full.FinalPrice.Equal(result.FinalPrice))
+	})
+
+	t.Run("Without opting in the deadline stops the calculation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := service.CalculateCart(ctx, req(false))
+		assert.True(t, errors.IsDeadlineExceededError(err), err)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscount_MatchesPayment(t *testing.T) {
+	hdfc, icici, paytm, phonepe := "HDFC", "ICICI", "PAYTM", "PHONEPE"
+
+	card := &models.Discount{Type: models.DiscountTypeBank, ApplicableTo: []string{"HDFC"}}
+	assert.True(t, card.MatchesPayment(&models.PaymentInfo{Method: models.Card, BankName: &hdfc}))
+	assert.False(t, card.MatchesPayment(&models.PaymentInfo{Method: models.Card, BankName: &icici}))
+	assert.False(t, card.MatchesPayment(&models.PaymentInfo{Method: models.EMI, BankName: &hdfc}), "bank discounts take cards only by default")
+	assert.False(t, card.MatchesPayment(nil))
+
+	emi := &models.Discount{Type: models.DiscountTypeBank, ApplicableTo: []string{"HDFC"}, PaymentMethods: []models.PaymentMethod{models.EMI, models.NetBanking}}
+	require.NoError(t, emi.CheckPaymentMethods())
+	assert.True(t, emi.MatchesPayment(&models.PaymentInfo{Method: models.EMI, BankName: &hdfc}))
+	assert.True(t, emi.MatchesPayment(&models.PaymentInfo{Method: models.NetBanking, BankName: &hdfc}))
+	assert.False(t, emi.MatchesPayment(&models.PaymentInfo{Method: models.Card, BankName: &hdfc}))
+
+	wallet := &models.Discount{Type: models.DiscountTypeWallet, ApplicableTo: []string{"PAYTM"}}
+	assert.True(t, wallet.MatchesPayment(&models.PaymentInfo{Method: models.Wallet, WalletProvider: &paytm}))
+	assert.False(t, wallet.MatchesPayment(&models.PaymentInfo{Method: models.Wallet, WalletProvider: &phonepe}))
+	assert.False(t, wallet.MatchesPayment(&models.PaymentInfo{Method: models.Wallet}))
+	assert.False(t, wallet.MatchesPayment(&models.PaymentInfo{Method: models.UPI, WalletProvider: &paytm}))
+	assert.True(t, (&models.Discount{Type: models.DiscountTypeWallet}).MatchesPayment(&models.PaymentInfo{Method: models.Wallet}),
+		"wallet discounts without providers accept every wallet")
+
+	for _, invalid := range []*models.Discount{
+		{Type: models.DiscountTypeBank, PaymentMethods: []models.PaymentMethod{models.Wallet}},
+		{Type: models.DiscountTypeBank, PaymentMethods: []models.PaymentMethod{"CHEQUE"}},
+		{Type: models.DiscountTypeWallet, PaymentMethods: []models.PaymentMethod{models.Card}},
+	} {
+		assert.Error(t, invalid.CheckPaymentMethods(), "%+v", invalid)
+	}
+}
+
+func TestDiscountService_PaymentMethodOffers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	offer := func(id string, discountType models.DiscountType, value int64, applicableTo ...string) *models.Discount {
+		return &models.Discount{
+			ID:           id,
+			Name:         id,
+			Type:         discountType,
+			Value:        decimal.NewFromInt(value),
+			IsPercentage: true,
+			ApplicableTo: applicableTo,
+			ValidFrom:    now.Add(-time.Hour),
+			ValidTo:      now.Add(time.Hour),
+			IsActive:     true,
+		}
+	}
+	paytmOffer := offer("paytm-5", models.DiscountTypeWallet, 5, "PAYTM")
+	emiOffer := offer("hdfc-emi-10", models.DiscountTypeBank, 10, "HDFC")
+	emiOffer.PaymentMethods = []models.PaymentMethod{models.EMI}
+	emiOffer.CardTypes = []models.CardType{models.Credit}
+
+	repo := repository.NewInMemoryDiscountRepository()
+	for _, d := range []*models.Discount{paytmOffer, emiOffer} {
+		require.NoError(t, repo.CreateDiscount(ctx, d))
+	}
+	invalid := offer("bad-methods", models.DiscountTypeBank, 10, "HDFC")
+	invalid.PaymentMethods = []models.PaymentMethod{models.UPI}
+	assert.True(t, errors.IsValidationError(repo.CreateDiscount(ctx, invalid)))
+
+	service := services.NewDiscountService(repo)
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 1}}
+	paytm, phonepe, hdfc, credit := "PAYTM", "PHONEPE", "HDFC", models.Credit
+
+	for _, tc := range []struct {
+		name    string
+		payment *models.PaymentInfo
+		final   int64
+	}{
+		{"Wallet of the provider", &models.PaymentInfo{Method: models.Wallet, WalletProvider: &paytm}, 950},
+		{"Wallet of another provider", &models.PaymentInfo{Method: models.Wallet, WalletProvider: &phonepe}, 1000},
+		{"EMI on a credit card of the bank", &models.PaymentInfo{Method: models.EMI, BankName: &hdfc, CardType: &credit}, 900},
+		{"Card payment of the bank", &models.PaymentInfo{Method: models.Card, BankName: &hdfc, CardType: &credit}, 1000},
+		{"Net banking", &models.PaymentInfo{Method: models.NetBanking, BankName: &hdfc}, 1000},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := service.CalculateCartDiscounts(ctx, cart, models.CustomerProfile{}, tc.payment)
+			require.NoError(t, err)
+			assert.True(t, decimal.NewFromInt(tc.final).Equal(result.FinalPrice), "got %s", result.FinalPrice)
+
+			filter := models.CartDiscountFilter(cart, models.CustomerProfile{}, tc.payment, now)
+			assert.Equal(t, tc.final != 1000, filter.Matches(paytmOffer) || filter.Matches(emiOffer),
+				"the filter selects exactly the offers the payment qualifies for")
+		})
+	}
+
+	explanation, err := service.ExplainCartDiscounts(ctx, &models.CalculationRequest{
+		CartItems:   cart,
+		PaymentInfo: &models.PaymentInfo{Method: models.Wallet, WalletProvider: &phonepe},
+	})
+	require.NoError(t, err)
+	decision, ok := explanation.Decision(paytmOffer.ID)
+	require.True(t, ok)
+	assert.Equal(t, models.ReasonPaymentMismatch, decision.Reason)
+}
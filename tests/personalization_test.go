@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+type scorerFunc func(ctx context.Context, customer models.CustomerProfile, offers []models.Offer) ([]float64, error)
+
+func (f scorerFunc) ScoreOffers(ctx context.Context, customer models.CustomerProfile, offers []models.Offer) ([]float64, error) {
+	return f(ctx, customer, offers)
+}
+
+func TestDiscountService_PersonalizationScorer(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(testdata.GetSampleDiscounts()))
+	customer := testdata.GetSampleCustomers()[0]
+
+	ids := func(offers []models.Offer) []string {
+		out := make([]string, len(offers))
+		for i, offer := range offers {
+			out[i] = offer.DiscountID
+		}
+		return out
+	}
+	baseline, err := services.NewDiscountService(repo).ListOffers(ctx, customer)
+	require.NoError(t, err)
+	require.Greater(t, len(baseline), 1)
+
+	// Scores the offers in reverse of their listing
+	reverse := scorerFunc(func(ctx context.Context, customer models.CustomerProfile, offers []models.Offer) ([]float64, error) {
+		scores := make([]float64, len(offers))
+		for i := range offers {
+			scores[i] = float64(i)
+		}
+		return scores, nil
+	})
+	expected := ids(baseline)
+	for i, j := 0, len(expected)-1; i < j; i, j = i+1, j-1 {
+		expected[i], expected[j] = expected[j], expected[i]
+	}
+
+	t.Run("Scores order the offers", func(t *testing.T) {
+		service := services.NewDiscountService(repo, services.WithPersonalizationScorer(reverse, services.PersonalizationConfig{}))
+		offers, err := service.ListOffers(ctx, customer)
+		require.NoError(t, err)
+		assert.Equal(t, expected, ids(offers))
+	})
+
+	t.Run("Slow scorers fall back", func(t *testing.T) {
+		slow := scorerFunc(func(ctx context.Context, customer models.CustomerProfile, offers []models.Offer) ([]float64, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		var failures []error
+		service := services.NewDiscountService(repo, services.WithPersonalizationScorer(slow, services.PersonalizationConfig{
+			Timeout:  10 * time.Millisecond,
+			Fallback: reverse,
+			OnError:  func(err error) { failures = append(failures, err) },
+		}))
+		offers, err := service.ListOffers(ctx, customer)
+		require.NoError(t, err)
+		assert.Equal(t, expected, ids(offers))
+		assert.Len(t, failures, 1)
+	})
+
+	t.Run("Failing scorers use the heuristic", func(t *testing.T) {
+		failing := scorerFunc(func(ctx context.Context, customer models.CustomerProfile, offers []models.Offer) ([]float64, error) {
+			return nil, fmt.Errorf("model unavailable")
+		})
+		short := scorerFunc(func(ctx context.Context, customer models.CustomerProfile, offers []models.Offer) ([]float64, error) {
+			return []float64{1}, nil
+		})
+		heuristic, err := services.NewDiscountService(repo,
+			services.WithPersonalizationScorer(services.HeuristicScorer{}, services.PersonalizationConfig{})).ListOffers(ctx, customer)
+		require.NoError(t, err)
+
+		for name, scorer := range map[string]scorerFunc{"failing": failing, "short": short} {
+			var failures []error
+			service := services.NewDiscountService(repo, services.WithPersonalizationScorer(scorer, services.PersonalizationConfig{
+				OnError: func(err error) { failures = append(failures, err) },
+			}))
+			offers, err := service.ListOffers(ctx, customer)
+			require.NoError(t, err)
+			assert.Equal(t, ids(heuristic), ids(offers), name)
+			assert.Len(t, failures, 1, name)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestPipelineConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  services.PipelineConfig
+		wantErr bool
+	}{
+		{
+			name: "Valid phases",
+			config: services.PipelineConfig{Phases: []services.PipelinePhase{
+				{Name: "catalog", Types: []models.DiscountType{models.DiscountTypeBrand, models.DiscountTypeCategory}},
+				{Name: "payment", Types: []models.DiscountType{models.DiscountTypeBank}},
+			}},
+		},
+		{
+			name:    "Unnamed phase",
+			config:  services.PipelineConfig{Phases: []services.PipelinePhase{{Types: []models.DiscountType{models.DiscountTypeBank}}}},
+			wantErr: true,
+		},
+		{
+			name:    "Empty phase",
+			config:  services.PipelineConfig{Phases: []services.PipelinePhase{{Name: "empty"}}},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown type",
+			config:  services.PipelineConfig{Phases: []services.PipelinePhase{{Name: "x", Types: []models.DiscountType{"bogus"}}}},
+			wantErr: true,
+		},
+		{
+			name: "Type in two phases",
+			config: services.PipelineConfig{Phases: []services.PipelinePhase{
+				{Name: "first", Types: []models.DiscountType{models.DiscountTypeBank}},
+				{Name: "second", Types: []models.DiscountType{models.DiscountTypeBank}},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Panics(t, func() { services.WithPipelineConfig(tt.config) })
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDiscountService_PipelineOrder(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	// A percentage voucher taken off the running total saves more when it applies before a
+	// fixed brand discount
+	discounts := []models.Discount{
+		{
+			ID: "brand", Name: "Brand 100", Type: models.DiscountTypeBrand, Value: decimal.NewFromInt(100),
+			ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true, Priority: 100,
+		},
+		{
+			ID: "voucher", Name: "Voucher 50%", Type: models.DiscountTypeVoucher, Value: decimal.NewFromInt(50), IsPercentage: true,
+			ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true, Priority: 10,
+		},
+	}
+
+	cartItems := []models.CartItem{{Product: testdata.GetSampleProducts()[0], Quantity: 1, Size: "M"}} // 1000
+	customer := testdata.GetSampleCustomers()[0]
+
+	byPriority := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, byPriority.(*repository.InMemoryDiscountRepository).SeedDiscounts(discounts))
+	result, err := services.NewDiscountService(byPriority).CalculateCartDiscounts(ctx, cartItems, customer, nil)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(450).Equal(result.FinalPrice), result.FinalPrice.String()) // 1000 - 100 - 450
+
+	voucherFirst := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, voucherFirst.(*repository.InMemoryDiscountRepository).SeedDiscounts(discounts))
+	service := services.NewDiscountService(voucherFirst, services.WithPipelineConfig(services.PipelineConfig{
+		Phases: []services.PipelinePhase{{Name: "coupons", Types: []models.DiscountType{models.DiscountTypeVoucher}}},
+	}))
+	result, err = service.CalculateCartDiscounts(ctx, cartItems, customer, nil)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(400).Equal(result.FinalPrice), result.FinalPrice.String()) // 1000 - 500 - 100
+}
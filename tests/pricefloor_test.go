@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+)
+
+func TestDiscountService_PriceFloor(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := repository.NewInMemoryDiscountRepository()
+	for _, d := range []*models.Discount{
+		{ID: "puma-20", Name: "PUMA 20%", Type: models.DiscountTypeBrand, ApplicableTo: []string{"PUMA"}, Value: decimal.NewFromInt(20), IsPercentage: true, Priority: 3},
+		{ID: "flat-100", Name: "Flat 100", Type: models.DiscountTypeVoucher, Value: decimal.NewFromInt(100), Priority: 2},
+		{ID: "flat-50", Name: "Flat 50", Type: models.DiscountTypeVoucher, Value: decimal.NewFromInt(50), Priority: 1},
+	} {
+		d.ValidFrom, d.ValidTo, d.IsActive = now.Add(-time.Hour), now.Add(time.Hour), true
+		require.NoError(t, repo.CreateDiscount(ctx, d))
+	}
+	req := &models.CalculationRequest{CartItems: []models.CartItem{
+		{Product: models.Product{ID: "shirt", Brand: models.Brand{ID: "PUMA"}, CurrentPrice: decimal.NewFromInt(1000), CostPrice: decimal.NewFromInt(800)}, Quantity: 1},
+		{Product: models.Product{ID: "mug", Brand: models.Brand{ID: "HOME"}, CurrentPrice: decimal.NewFromInt(200), MinSellingPrice: decimal.NewFromInt(180)}, Quantity: 2},
+	}}
+
+	t.Run("Minimum selling prices always apply", func(t *testing.T) {
+		result, err := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now))).CalculateCart(ctx, req)
+		require.NoError(t, err)
+		// 200 off the shirt, then the vouchers spread over the shirt and the mugs' 40 of room
+		assert.True(t, decimal.NewFromInt(1050).Equal(result.FinalPrice), "got %s", result.FinalPrice)
+		assert.Empty(t, result.PriceFloorClamps)
+	})
+
+	t.Run("Margin policies floor products at cost plus margin", func(t *testing.T) {
+		service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)),
+			services.WithPriceFloor(models.PriceFloorPolicy{MinMarginPercent: decimal.NewFromInt(10)}))
+		result, err := service.CalculateCart(ctx, req)
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(1240).Equal(result.FinalPrice), "shirt at 880 and mugs at 180 each, got %s", result.FinalPrice)
+		expected := []struct {
+			id                 string
+			requested, applied int64
+		}{{"puma-20", 200, 120}, {"flat-100", 100, 40}, {"flat-50", 50, 0}}
+		require.Len(t, result.PriceFloorClamps, len(expected))
+		for i, clamp := range result.PriceFloorClamps {
+			assert.Equal(t, expected[i].id, clamp.DiscountID)
+			assert.True(t, decimal.NewFromInt(expected[i].requested).Equal(clamp.Requested), "%s requested %s", clamp.DiscountID, clamp.Requested)
+			assert.True(t, decimal.NewFromInt(expected[i].applied).Equal(clamp.Applied), "%s applied %s", clamp.DiscountID, clamp.Applied)
+		}
+
+		explanation, err := service.ExplainCartDiscounts(ctx, req)
+		require.NoError(t, err)
+		decision, ok := explanation.Decision("flat-50")
+		require.True(t, ok)
+		assert.Equal(t, models.DecisionSkipped, decision.Outcome)
+		assert.Equal(t, models.ReasonPriceFloor, decision.Reason)
+	})
+
+	assert.Error(t, models.PriceFloorPolicy{MinMarginPercent: decimal.NewFromInt(-5)}.Validate())
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestPriceMatrix(t *testing.T) {
+	matrix := models.PriceMatrix{Tiers: []models.PriceTier{
+		{Group: models.CustomerGroupWholesale, ProductID: "p1", MinQuantity: 1, UnitPrice: decimal.NewFromInt(900)},
+		{Group: models.CustomerGroupWholesale, ProductID: "p1", MinQuantity: 10, UnitPrice: decimal.NewFromInt(800)},
+		{Group: models.CustomerGroupFranchise, ProductID: "p1", UnitPrice: decimal.NewFromInt(700)},
+	}}
+	require.NoError(t, matrix.Validate())
+
+	price, ok := matrix.UnitPrice(models.CustomerGroupWholesale, "p1", 9)
+	require.True(t, ok)
+	assert.True(t, decimal.NewFromInt(900).Equal(price))
+	price, _ = matrix.UnitPrice(models.CustomerGroupWholesale, "p1", 10)
+	assert.True(t, decimal.NewFromInt(800).Equal(price), "the highest break reached wins")
+	_, ok = matrix.UnitPrice(models.CustomerGroupRetail, "p1", 10)
+	assert.False(t, ok)
+	_, ok = matrix.UnitPrice(models.CustomerGroupWholesale, "p2", 10)
+	assert.False(t, ok)
+
+	t.Run("Breaks count the whole cart", func(t *testing.T) {
+		line := models.CartItem{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 5}
+		cart := []models.CartItem{line, line}
+
+		priced := matrix.Apply(models.CustomerGroupWholesale, cart)
+		assert.True(t, decimal.NewFromInt(800).Equal(priced[0].Product.CurrentPrice))
+		assert.True(t, decimal.NewFromInt(800).Equal(priced[1].Product.CurrentPrice))
+		assert.True(t, decimal.NewFromInt(1000).Equal(cart[0].Product.CurrentPrice), "the cart is left as it was")
+
+		onSale := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(650)}, Quantity: 1}}
+		priced = matrix.Apply(models.CustomerGroupFranchise, onSale)
+		assert.True(t, decimal.NewFromInt(650).Equal(priced[0].Product.CurrentPrice), "tiers never raise a price")
+	})
+
+	t.Run("Invalid matrices", func(t *testing.T) {
+		for _, tier := range []models.PriceTier{
+			{ProductID: "p1", UnitPrice: decimal.NewFromInt(1)},
+			{Group: models.CustomerGroupWholesale, ProductID: "p1", MinQuantity: -1},
+			{Group: models.CustomerGroupWholesale, ProductID: "p1", UnitPrice: decimal.NewFromInt(-1)},
+			{Group: models.CustomerGroupWholesale, ProductID: "p1", MinQuantity: 10, UnitPrice: decimal.NewFromInt(1)},
+		} {
+			invalid := models.PriceMatrix{Tiers: append(append([]models.PriceTier(nil), matrix.Tiers...), tier)}
+			assert.Error(t, invalid.Validate(), "%+v", tier)
+		}
+	})
+}
+
+func TestDiscountService_GroupPricing(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID:           "disc-10",
+		Name:         "10% off",
+		Type:         models.DiscountTypeVoucher,
+		Value:        decimal.NewFromInt(10),
+		IsPercentage: true,
+		ValidFrom:    now.Add(-time.Hour),
+		ValidTo:      now.Add(time.Hour),
+		IsActive:     true,
+	}))
+
+	product := testdata.GetSampleProducts()[3] // 1200
+	customers := repository.NewInMemoryCustomerProvider(map[string]models.CustomerGroup{"cust-wholesale": models.CustomerGroupWholesale})
+	service := services.NewDiscountService(repo, services.WithGroupPricing(customers, models.PriceMatrix{Tiers: []models.PriceTier{
+		{Group: models.CustomerGroupWholesale, ProductID: product.ID, MinQuantity: 5, UnitPrice: decimal.NewFromInt(1000)},
+	}}))
+
+	price := func(customerID string, quantity int) *models.DiscountedPrice {
+		t.Helper()
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems: []models.CartItem{{Product: product, Quantity: quantity, Size: "32"}},
+			Customer:  models.CustomerProfile{ID: customerID, Group: models.CustomerGroupWholesale},
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	wholesale := price("cust-wholesale", 5)
+	assert.Equal(t, models.CustomerGroupWholesale, wholesale.CustomerGroup)
+	assert.True(t, decimal.NewFromInt(5000).Equal(wholesale.OriginalPrice), "group prices apply before promotions")
+	assert.True(t, decimal.NewFromInt(4500).Equal(wholesale.FinalPrice))
+
+	belowBreak := price("cust-wholesale", 4)
+	assert.True(t, decimal.NewFromInt(4800).Equal(belowBreak.OriginalPrice))
+
+	retail := price("cust-retail", 5)
+	assert.Equal(t, models.CustomerGroupRetail, retail.CustomerGroup, "groups come from the provider, not the request")
+	assert.True(t, decimal.NewFromInt(6000).Equal(retail.OriginalPrice))
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+)
+
+// pricingHookFunc adapts a function to interfaces.PricingHook
+type pricingHookFunc func(cart []models.CartItem) ([]models.PriceAdjustment, error)
+
+func (f pricingHookFunc) AdjustPrices(_ context.Context, cart []models.CartItem, _ models.CustomerProfile) ([]models.PriceAdjustment, error) {
+	return f(cart)
+}
+
+func TestDiscountService_PricingHook(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID: "puma", Name: "PUMA 10%", Type: models.DiscountTypeBrand, ApplicableTo: []string{"PUMA"},
+		Value: decimal.NewFromInt(10), IsPercentage: true,
+		ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true,
+	}))
+	cart := []models.CartItem{
+		{Product: models.Product{ID: "tee", Brand: models.Brand{ID: "PUMA"}, CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 2},
+		{Product: models.Product{ID: "cap", Brand: models.Brand{ID: "NIKE"}, CurrentPrice: decimal.NewFromInt(500)}, Quantity: 1},
+	}
+	calculate := func(hook pricingHookFunc) (*models.DiscountedPrice, error) {
+		service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)), services.WithPricingHook(hook))
+		return service.CalculateCart(ctx, &models.CalculationRequest{CartItems: cart})
+	}
+
+	t.Run("Discounts apply to the adjusted prices", func(t *testing.T) {
+		result, err := calculate(func(cart []models.CartItem) ([]models.PriceAdjustment, error) {
+			return []models.PriceAdjustment{
+				{Line: 0, UnitPrice: decimal.NewFromInt(1200), Reason: "surge"},
+				{Line: 1, UnitPrice: decimal.NewFromInt(500), Reason: "unchanged"},
+			}, nil
+		})
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(2900).Equal(result.OriginalPrice), result.OriginalPrice.String())
+		assert.True(t, decimal.NewFromInt(2660).Equal(result.FinalPrice), result.FinalPrice.String())
+
+		require.Len(t, result.PriceAdjustments, 1, "lines left at their price are not reported")
+		adj := result.PriceAdjustments[0]
+		assert.Equal(t, "tee", adj.ProductID)
+		assert.Equal(t, "surge", adj.Reason)
+		assert.True(t, decimal.NewFromInt(1000).Equal(adj.PreviousPrice))
+		assert.True(t, decimal.NewFromInt(400).Equal(adj.Amount))
+		assert.NotContains(t, result.AppliedDiscounts, "surge")
+		assert.True(t, cart[0].Product.CurrentPrice.Equal(decimal.NewFromInt(1000)), "the caller's cart is left alone")
+	})
+
+	t.Run("Invalid adjustments fail the calculation", func(t *testing.T) {
+		for name, adjustments := range map[string][]models.PriceAdjustment{
+			"missing line":   {{Line: 2, UnitPrice: decimal.NewFromInt(1)}},
+			"repeated line":  {{Line: 0, UnitPrice: decimal.NewFromInt(1)}, {Line: 0, UnitPrice: decimal.NewFromInt(2)}},
+			"negative price": {{Line: 1, UnitPrice: decimal.NewFromInt(-1)}},
+		} {
+			_, err := calculate(func([]models.CartItem) ([]models.PriceAdjustment, error) { return adjustments, nil })
+			assert.Error(t, err, name)
+		}
+
+		_, err := calculate(func([]models.CartItem) ([]models.PriceAdjustment, error) { return nil, fmt.Errorf("engine down") })
+		assert.ErrorContains(t, err, "engine down")
+	})
+}
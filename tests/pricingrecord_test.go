@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/discounttest"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscountService_VerifyOrderPricing(t *testing.T) {
+	ctx := context.Background()
+	repo := discounttest.NewRepository(t,
+		discounttest.Discount("puma", models.DiscountTypeBrand).Percent("10").On("PUMA").Build(),
+		discounttest.Discount("save", models.DiscountTypeVoucher).Fixed("100").Code("SAVE100").Build(),
+	)
+	records := repository.NewInMemoryPricingRecordRepository()
+	svc := services.NewDiscountService(repo, services.WithPricingRecords(records))
+
+	req := discounttest.Cart().Add(discounttest.Product("tee", "PUMA", "tshirts", "1000"), 1).WithCodes("SAVE100").Request()
+	req.OrderID = "o1"
+	result, err := svc.CalculateCart(ctx, req)
+	require.NoError(t, err)
+	require.True(t, result.FinalPrice.Equal(decimal.NewFromInt(800)))
+
+	record, err := records.GetPricingRecord(ctx, "o1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"puma": 1, "save": 1}, record.DiscountVersions())
+	assert.Equal(t, []string{"SAVE100"}, record.Request.Codes)
+
+	t.Run("Later changes to discounts do not alter the recalculation", func(t *testing.T) {
+		voucher, err := repo.GetDiscountByID(ctx, "save")
+		require.NoError(t, err)
+		voucher.Value = decimal.NewFromInt(500)
+		require.NoError(t, repo.UpdateDiscount(ctx, voucher))
+
+		verification, err := svc.VerifyOrderPricing(ctx, "o1")
+		require.NoError(t, err)
+		assert.True(t, verification.Matches, verification.Differences)
+		assert.True(t, verification.Recalculated.FinalPrice.Equal(decimal.NewFromInt(800)))
+	})
+
+	t.Run("Tampered records are reported", func(t *testing.T) {
+		tampered := *record
+		tampered.OrderID = "o2"
+		tampered.Result.FinalPrice = decimal.NewFromInt(700)
+		tampered.Result.Breakdown = append([]models.AppliedDiscount(nil), record.Result.Breakdown...)
+		tampered.Result.Breakdown[1].Amount = decimal.NewFromInt(200)
+		require.NoError(t, records.SavePricingRecord(ctx, tampered))
+
+		verification, err := svc.VerifyOrderPricing(ctx, "o2")
+		require.NoError(t, err)
+		assert.False(t, verification.Matches)
+		assert.Equal(t, []string{
+			"final price: recorded 700, recalculated 800",
+			"discount save: recorded 200, recalculated 100",
+		}, verification.Differences)
+	})
+
+	t.Run("Orders without records are not found", func(t *testing.T) {
+		_, err := svc.CalculateCart(ctx, discounttest.Cart().Add(discounttest.Product("tee", "PUMA", "tshirts", "1000"), 1).Request())
+		require.NoError(t, err)
+
+		_, err = svc.VerifyOrderPricing(ctx, "unknown")
+		assert.True(t, errors.IsNotFoundError(err))
+		_, err = services.NewDiscountService(repo).VerifyOrderPricing(ctx, "o1")
+		assert.True(t, errors.IsNotFoundError(err))
+	})
+
+	t.Run("Through the API", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		api.NewHandler(svc).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/orders/o1/pricing/verification", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"matches":true`)
+	})
+}
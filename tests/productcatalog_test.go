@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// countingCatalog records the IDs every call asked for
+type countingCatalog struct {
+	interfaces.ProductCatalog
+	calls [][]string
+}
+
+func (c *countingCatalog) GetProducts(ctx context.Context, ids []string) (map[string]models.Product, error) {
+	c.calls = append(c.calls, ids)
+	return c.ProductCatalog.GetProducts(ctx, ids)
+}
+
+func TestDiscountService_ProductCatalog(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID: "puma", Name: "PUMA 10%", Type: models.DiscountTypeBrand, ApplicableTo: []string{"PUMA"},
+		Value: decimal.NewFromInt(10), IsPercentage: true,
+		ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true,
+	}))
+	catalog := &countingCatalog{ProductCatalog: repository.NewInMemoryProductCatalog(
+		models.Product{ID: "tee", SKU: "tee-m", Brand: models.Brand{ID: "PUMA", Name: "PUMA"}, Category: models.Category{ID: "tshirts"},
+			BasePrice: decimal.NewFromInt(1000), CurrentPrice: decimal.NewFromInt(1000)},
+		models.Product{ID: "cap", Brand: models.Brand{ID: "NIKE"}, BasePrice: decimal.NewFromInt(500), CurrentPrice: decimal.NewFromInt(500)},
+	)}
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)), services.WithProductCatalog(catalog))
+
+	t.Run("Bare lines are priced from the catalog", func(t *testing.T) {
+		catalog.calls = nil
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{CartItems: []models.CartItem{
+			{Product: models.Product{ID: "tee", SKU: "tee-l"}, Quantity: 2, Size: "L"},
+			{Product: models.Product{ID: "cap"}, Quantity: 1},
+			{Product: models.Product{ID: "tee"}, Quantity: 1},
+		}})
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(3500).Equal(result.OriginalPrice), result.OriginalPrice.String())
+		assert.True(t, decimal.NewFromInt(3200).Equal(result.FinalPrice), result.FinalPrice.String())
+		assert.Equal(t, [][]string{{"tee", "cap"}}, catalog.calls, "one call per cart")
+	})
+
+	t.Run("Lines carrying their product are left as they are", func(t *testing.T) {
+		catalog.calls = nil
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{CartItems: []models.CartItem{
+			{Product: models.Product{ID: "tee", Brand: models.Brand{ID: "PUMA"}, CurrentPrice: decimal.NewFromInt(800)}, Quantity: 1},
+		}})
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(800).Equal(result.OriginalPrice))
+		assert.Empty(t, catalog.calls)
+	})
+
+	t.Run("Unknown products are invalid", func(t *testing.T) {
+		items := []models.CartItem{{Product: models.Product{ID: "tee"}, Quantity: 1}, {Product: models.Product{ID: "ghost"}, Quantity: 1}}
+		_, err := service.CalculateCart(ctx, &models.CalculationRequest{CartItems: items})
+		assert.True(t, errors.IsValidationError(err), err)
+
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{CartItems: items, ValidationMode: models.ValidationLenient})
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(1000).Equal(result.OriginalPrice))
+		assert.Len(t, result.Warnings, 1)
+	})
+}
+
+func TestCachedProductCatalog(t *testing.T) {
+	ctx := context.Background()
+	products := repository.NewInMemoryProductCatalog(models.Product{ID: "tee", CurrentPrice: decimal.NewFromInt(1000)})
+	catalog := &countingCatalog{ProductCatalog: products}
+	at := clock.NewFixed(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	cache := repository.NewCachedProductCatalog(catalog, time.Minute)
+	cache.Clock = at
+
+	get := func(ids ...string) map[string]models.Product {
+		got, err := cache.GetProducts(ctx, ids)
+		require.NoError(t, err)
+		return got
+	}
+
+	assert.Len(t, get("tee", "ghost"), 1)
+	assert.Len(t, get("tee", "ghost"), 1)
+	assert.Equal(t, [][]string{{"tee", "ghost"}, {"ghost"}}, catalog.calls, "only misses are fetched")
+
+	products.PutProduct(ctx, models.Product{ID: "tee", CurrentPrice: decimal.NewFromInt(900)})
+	assert.True(t, decimal.NewFromInt(1000).Equal(get("tee")["tee"].CurrentPrice), "cached until expiry")
+	at.Advance(time.Minute)
+	assert.True(t, decimal.NewFromInt(900).Equal(get("tee")["tee"].CurrentPrice))
+
+	products.PutProduct(ctx, models.Product{ID: "tee", CurrentPrice: decimal.NewFromInt(800)})
+	cache.Invalidate(ctx, "tee")
+	assert.True(t, decimal.NewFromInt(800).Equal(get("tee")["tee"].CurrentPrice))
+
+	t.Run("Full caches make room", func(t *testing.T) {
+		cache := repository.NewCachedProductCatalog(products, time.Minute)
+		cache.MaxEntries = 1
+		products.PutProduct(ctx, models.Product{ID: "cap"})
+		_, err := cache.GetProducts(ctx, []string{"tee", "cap"})
+		require.NoError(t, err)
+		got, err := cache.GetProducts(ctx, []string{"tee", "cap"})
+		require.NoError(t, err)
+		assert.Len(t, got, 2)
+	})
+
+	t.Run("Full caches drop the least recently used product", func(t *testing.T) {
+		catalog := &countingCatalog{ProductCatalog: products}
+		cache := repository.NewCachedProductCatalog(catalog, time.Minute)
+		cache.MaxEntries = 2
+		products.PutProduct(ctx, models.Product{ID: "sock"})
+		for _, id := range []string{"tee", "cap", "tee", "sock", "tee", "cap"} {
+			_, err := cache.GetProducts(ctx, []string{id})
+			require.NoError(t, err)
+		}
+		assert.Equal(t, [][]string{{"tee"}, {"cap"}, {"sock"}, {"cap"}}, catalog.calls,
+			"tee was used after cap, so cap made room for sock")
+	})
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscountctl_Promote(t *testing.T) {
+	dir := t.TempDir()
+	discount := func(id, campaign, value string, extra string) string {
+		return `{"id": "` + id + `", "name": "` + id + `", "type": "brand", "value": "` + value + `",
+			"is_percentage": true, "applicable_to": ["PUMA"], "campaign_id": "` + campaign + `", "is_active": true,
+			"valid_from": "2024-11-01T00:00:00Z", "valid_to": "2024-12-01T00:00:00Z"` + extra + `}`
+	}
+	staging := writeFile(t, dir, "staging.json", `[`+
+		discount("winter-puma", "winter", "20", "")+`,`+
+		discount("winter-zara", "winter", "15", "")+`,`+
+		discount("summer-nike", "summer", "5", "")+`]`)
+	prod := writeFile(t, dir, "prod.json", `[`+
+		discount("winter-puma", "winter", "10", `, "used_count": 5`)+`,`+
+		discount("winter-old", "winter", "30", "")+`,`+
+		discount("summer-nike", "summer", "50", "")+`]`)
+	bundle := filepath.Join(dir, "winter.json")
+	plan := filepath.Join(dir, "winter.plan.json")
+
+	out, err := runDiscountctl(t, "--store", staging, "promote", "export", "--campaign", "winter", "-o", bundle)
+	require.NoError(t, err)
+	assert.Contains(t, out, "exported 2 discounts of campaign winter")
+
+	out, err = runDiscountctl(t, "--store", prod, "promote", "diff", "-f", bundle, "-o", plan)
+	require.NoError(t, err)
+	assert.Contains(t, out, "create winter-zara")
+	assert.Contains(t, out, "update winter-puma\n    value: \"10\" -> \"20\"\n")
+	assert.Contains(t, out, "deactivate winter-old")
+	assert.NotContains(t, out, "summer-nike", "other campaigns are left alone")
+	assert.NotContains(t, out, "used_count")
+
+	out, err = runDiscountctl(t, "--store", prod, "promote", "apply", "-f", plan)
+	require.NoError(t, err)
+	assert.Contains(t, out, "campaign winter promoted")
+
+	out, err = runDiscountctl(t, "--store", prod, "inspect", "winter-puma")
+	require.NoError(t, err)
+	assert.Contains(t, out, `"value": "20"`)
+	assert.Contains(t, out, `"used_count": 5`, "production usage is kept")
+	out, err = runDiscountctl(t, "--store", prod, "inspect", "winter-old")
+	require.NoError(t, err)
+	assert.Contains(t, out, `"is_active": false`)
+	_, err = runDiscountctl(t, "--store", prod, "inspect", "winter-zara")
+	require.NoError(t, err)
+
+	out, err = runDiscountctl(t, "--store", prod, "promote", "diff", "-f", bundle)
+	require.NoError(t, err)
+	assert.Contains(t, out, "campaign winter is up to date")
+
+	t.Run("Stale plans are refused", func(t *testing.T) {
+		edited := writeFile(t, dir, "edited.json", discount("winter-puma", "winter", "25", ""))
+		_, err := runDiscountctl(t, "--store", staging, "update", "-f", edited)
+		require.NoError(t, err)
+		_, err = runDiscountctl(t, "--store", staging, "promote", "export", "--campaign", "winter", "-o", bundle)
+		require.NoError(t, err)
+		_, err = runDiscountctl(t, "--store", prod, "promote", "diff", "-f", bundle, "-o", plan)
+		require.NoError(t, err)
+
+		hotfix := writeFile(t, dir, "hotfix.json", discount("winter-puma", "winter", "22", `, "version": 1`))
+		_, err = runDiscountctl(t, "--store", prod, "update", "-f", hotfix)
+		require.NoError(t, err)
+
+		_, err = runDiscountctl(t, "--store", prod, "promote", "apply", "-f", plan)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "target changed since the plan was made")
+	})
+}
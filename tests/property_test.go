@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+)
+
+var propertyNow = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// pricingCase is a random cart priced against random stacked discounts
+type pricingCase struct {
+	Items       []models.CartItem
+	Discounts   []models.Discount
+	Bank        string
+	PriceFloor  bool
+	MaxDiscount bool
+}
+
+func (pricingCase) Generate(r *rand.Rand, size int) reflect.Value {
+	brands := []string{"PUMA", "NIKE", "ZARA"}
+	categories := []string{"shoes", "shirts", "bags"}
+	banks := []string{"HDFC", "ICICI"}
+	amount := func(max int64) decimal.Decimal {
+		return decimal.New(r.Int63n(max*100)+1, -2)
+	}
+
+	var c pricingCase
+	c.Bank = banks[r.Intn(len(banks))]
+	c.PriceFloor = r.Intn(3) == 0
+	c.MaxDiscount = r.Intn(3) == 0
+	for i := 0; i < 1+r.Intn(5); i++ {
+		price := amount(5000)
+		product := models.Product{
+			ID:           fmt.Sprintf("p%d", i),
+			Brand:        models.Brand{ID: brands[r.Intn(len(brands))]},
+			Category:     models.Category{ID: categories[r.Intn(len(categories))]},
+			BasePrice:    price,
+			CurrentPrice: price,
+			TaxRate:      []decimal.Decimal{decimal.Zero, decimal.NewFromInt(5), decimal.NewFromInt(18)}[r.Intn(3)],
+		}
+		if r.Intn(3) == 0 {
+			product.CostPrice = price.Mul(decimal.NewFromFloat(0.6)).Round(2)
+		}
+		if r.Intn(4) == 0 {
+			product.MinSellingPrice = price.Div(decimal.NewFromInt(2)).Round(2)
+		}
+		c.Items = append(c.Items, models.CartItem{Product: product, Quantity: 1 + r.Intn(4)})
+	}
+
+	types := []struct {
+		discountType models.DiscountType
+		targets      []string
+	}{
+		{models.DiscountTypeBrand, brands},
+		{models.DiscountTypeCategory, categories},
+		{models.DiscountTypeVoucher, nil},
+		{models.DiscountTypeBank, banks},
+	}
+	for i := 0; i < r.Intn(7); i++ {
+		kind := types[r.Intn(len(types))]
+		d := models.Discount{
+			ID:              fmt.Sprintf("d%d", i),
+			Name:            fmt.Sprintf("Discount %d", i%3), // Names repeat, IDs do not
+			Type:            kind.discountType,
+			IsPercentage:    r.Intn(2) == 0,
+			Priority:        r.Intn(5),
+			AppliesAfterTax: r.Intn(4) == 0,
+			Cashback:        kind.discountType == models.DiscountTypeBank && r.Intn(3) == 0,
+			ValidFrom:       propertyNow.Add(-time.Hour),
+			ValidTo:         propertyNow.Add(time.Hour),
+			IsActive:        true,
+		}
+		if kind.targets != nil {
+			d.ApplicableTo = []string{kind.targets[r.Intn(len(kind.targets))]}
+		}
+		if d.IsPercentage {
+			d.Value = decimal.NewFromInt(1 + r.Int63n(100))
+		} else {
+			d.Value = amount(3000).Round(0)
+		}
+		if r.Intn(3) == 0 {
+			d.MaxAmount = amount(1000).Round(0)
+		}
+		if r.Intn(4) == 0 {
+			d.MinAmount = amount(5000).Round(0)
+		}
+		c.Discounts = append(c.Discounts, d)
+	}
+	return reflect.ValueOf(c)
+}
+
+func (c pricingCase) price(t *testing.T) (*models.DiscountedPrice, []string) {
+	ctx := context.Background()
+	repo := repository.NewInMemoryDiscountRepository()
+	for _, d := range c.Discounts {
+		require.NoError(t, repo.CreateDiscount(ctx, &d))
+	}
+
+	var problems []string
+	opts := []services.Option{
+		services.WithClock(clock.NewFixed(propertyNow)),
+		services.WithInvariantChecks(func(v *services.InvariantViolation) { problems = append(problems, v.Problems...) }),
+	}
+	if c.PriceFloor {
+		opts = append(opts, services.WithPriceFloor(models.PriceFloorPolicy{MinMarginPercent: decimal.NewFromInt(10)}))
+	}
+	if c.MaxDiscount {
+		opts = append(opts, services.WithMaxDiscountPercent(decimal.NewFromInt(50)))
+	}
+	bank := c.Bank
+	result, err := services.NewDiscountService(repo, opts...).CalculateCart(ctx, &models.CalculationRequest{
+		CartItems:   c.Items,
+		PaymentInfo: &models.PaymentInfo{Method: models.Card, BankName: &bank},
+	})
+	require.NoError(t, err)
+	return result, problems
+}
+
+// TestDiscountService_PricingProperties prices random carts against random stacks of
+// discounts and checks the results against the rules every calculation must keep
+func TestDiscountService_PricingProperties(t *testing.T) {
+	property := func(c pricingCase) bool {
+		result, problems := c.price(t)
+
+		if result.FinalPrice.IsNegative() {
+			problems = append(problems, "negative final price "+result.FinalPrice.String())
+		}
+		if result.FinalPrice.GreaterThan(result.OriginalPrice) {
+			problems = append(problems, "final price above the original price")
+		}
+		discounted := result.OriginalPrice.Sub(result.FinalPrice)
+		if !result.GetTotalDiscount().Equal(discounted) {
+			problems = append(problems, fmt.Sprintf("applied discounts add up to %s, the price dropped by %s", result.GetTotalDiscount(), discounted))
+		}
+		broken := decimal.Zero
+		for _, line := range result.Breakdown {
+			broken = broken.Add(line.Amount)
+		}
+		if !broken.Equal(discounted) {
+			problems = append(problems, fmt.Sprintf("breakdown adds up to %s, the price dropped by %s", broken, discounted))
+		}
+		taxed := decimal.Zero
+		for _, line := range result.TaxLines {
+			taxed = taxed.Add(line.Tax)
+		}
+		if !taxed.Equal(result.TotalTax) {
+			problems = append(problems, fmt.Sprintf("tax lines add up to %s, the total tax is %s", taxed, result.TotalTax))
+		}
+
+		if len(problems) > 0 {
+			t.Logf("%s\n%+v", strings.Join(problems, "\n"), c)
+		}
+		return len(problems) == 0
+	}
+
+	config := &quick.Config{MaxCount: 500, Rand: rand.New(rand.NewSource(1))}
+	if testing.Short() {
+		config.MaxCount = 50
+	}
+	if err := quick.Check(property, config); err != nil {
+		t.Fatal(err)
+	}
+}
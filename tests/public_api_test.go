@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/discount"
+)
+
+// TestPublicAPI prices a cart written only against pkg/discount, as a consuming service would
+func TestPublicAPI(t *testing.T) {
+	ctx := context.Background()
+	var repo discount.Repository = repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &discount.Discount{
+		ID:           "puma",
+		Name:         "PUMA 20%",
+		Type:         discount.TypeBrand,
+		Value:        decimal.NewFromInt(20),
+		IsPercentage: true,
+		ApplicableTo: []string{"PUMA"},
+		ValidFrom:    time.Now().Add(-time.Hour),
+		ValidTo:      time.Now().Add(time.Hour),
+		IsActive:     true,
+	}))
+
+	var svc discount.Service = services.NewDiscountService(repo)
+	result, err := svc.CalculateCart(ctx, &discount.CalculationRequest{
+		CartItems: []discount.CartItem{{
+			Product: discount.Product{
+				ID:           "tee",
+				Brand:        discount.Brand{ID: "PUMA"},
+				BasePrice:    decimal.NewFromInt(500),
+				CurrentPrice: decimal.NewFromInt(500),
+			},
+			Quantity: 2,
+		}},
+		PaymentInfo: &discount.PaymentInfo{Method: discount.UPI},
+	})
+	require.NoError(t, err)
+
+	var breakdown []discount.AppliedDiscount = result.Breakdown
+	require.Len(t, breakdown, 1)
+	assert.Equal(t, discount.TypeBrand, breakdown[0].Type)
+	assert.True(t, result.FinalPrice.Equal(decimal.NewFromInt(800)))
+}
@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/qos"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/pkg/lane"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestScheduler_Lanes(t *testing.T) {
+	scheduler, err := qos.NewScheduler(map[lane.Lane]qos.LaneConfig{
+		lane.Checkout: {Workers: 2, QueueLimit: 4},
+		lane.Batch:    {Workers: 1, QueueLimit: 1},
+	})
+	require.NoError(t, err)
+	batch := lane.NewContext(context.Background(), lane.Batch)
+
+	release := make(chan struct{})
+	running := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, scheduler.Run(batch, func(ctx context.Context) error {
+			close(running)
+			<-release
+			return nil
+		}))
+	}()
+	<-running
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, scheduler.Run(batch, func(ctx context.Context) error { return nil }))
+	}()
+	require.Eventually(t, func() bool { return scheduler.Stats()[lane.Batch].Queued == 1 }, time.Second, time.Millisecond)
+
+	err = scheduler.Run(batch, func(ctx context.Context) error {
+		t.Error("work over the queue limit must not run")
+		return nil
+	})
+	assert.True(t, errors.IsOverloadedError(err), "%v", err)
+	retryAfter, ok := errors.RetryAfter(err)
+	assert.True(t, ok)
+	assert.Positive(t, retryAfter)
+
+	ran := false
+	require.NoError(t, scheduler.Run(context.Background(), func(ctx context.Context) error {
+		ran = true
+		return nil
+	}))
+	assert.True(t, ran, "checkouts run while the batch lane is saturated")
+
+	close(release)
+	wg.Wait()
+
+	stats := scheduler.Stats()
+	assert.Equal(t, uint64(2), stats[lane.Batch].Completed)
+	assert.Equal(t, uint64(1), stats[lane.Batch].Rejected)
+	assert.Zero(t, stats[lane.Batch].Busy)
+	assert.Equal(t, uint64(1), stats[lane.Checkout].Completed)
+	assert.Positive(t, stats[lane.Batch].Run.Max, "the blocked calculation's run time is tracked")
+
+	t.Run("Cancelled while queued", func(t *testing.T) {
+		scheduler, err := qos.NewScheduler(map[lane.Lane]qos.LaneConfig{lane.Checkout: {Workers: 1, QueueLimit: 1}})
+		require.NoError(t, err)
+		release := make(chan struct{})
+		running := make(chan struct{})
+		go func() {
+			_ = scheduler.Run(context.Background(), func(ctx context.Context) error {
+				close(running)
+				<-release
+				return nil
+			})
+		}()
+		<-running
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, scheduler.Run(ctx, func(ctx context.Context) error { return nil }), context.DeadlineExceeded)
+	})
+
+	t.Run("Lanes without a pool are rejected", func(t *testing.T) {
+		scheduler, err := qos.NewScheduler(map[lane.Lane]qos.LaneConfig{lane.Checkout: {Workers: 1}})
+		require.NoError(t, err)
+		assert.True(t, errors.IsValidationError(scheduler.Run(batch, func(ctx context.Context) error { return nil })))
+	})
+
+	_, err = qos.NewScheduler(map[lane.Lane]qos.LaneConfig{lane.Checkout: {Workers: 0}})
+	assert.Error(t, err)
+}
+
+// laneRecorder runs work right away, remembering the lane of each
+type laneRecorder struct {
+	mu    sync.Mutex
+	lanes []lane.Lane
+	err   error
+}
+
+func (r *laneRecorder) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	r.mu.Lock()
+	r.lanes = append(r.lanes, lane.FromContext(ctx))
+	r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	return fn(ctx)
+}
+
+func TestDiscountService_SchedulesByLane(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(testdata.GetSampleDiscounts()))
+	recorder := &laneRecorder{}
+	service := services.NewDiscountService(repo, services.WithScheduler(recorder))
+
+	cartItems, customer, paymentInfo := testdata.GetMultipleDiscountScenario()
+	req := &models.CalculationRequest{CartItems: cartItems, Customer: customer, PaymentInfo: paymentInfo}
+
+	_, err := service.CalculateCart(ctx, req)
+	require.NoError(t, err)
+	_, err = service.ExplainCartDiscounts(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, []lane.Lane{lane.Checkout, lane.Checkout}, recorder.lanes)
+
+	recorder.lanes = nil
+	_, err = service.CalculateCartDiscountsBatch(ctx, &models.BatchCalculationRequest{Carts: []*models.CalculationRequest{req, req}})
+	require.NoError(t, err)
+	assert.Equal(t, []lane.Lane{lane.Batch, lane.Batch}, recorder.lanes)
+
+	t.Run("API", func(t *testing.T) {
+		recorder.lanes = nil
+		h := api.NewHandler(service)
+		body := map[string]any{"cart_items": cartItems, "customer": customer, "payment_info": paymentInfo}
+
+		rec, _ := doJSON(t, h, "/v2/cart/calculate", http.Header{api.LaneHeader: {"batch"}}, body)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []lane.Lane{lane.Batch}, recorder.lanes)
+
+		rec, _ = doJSON(t, h, "/v2/cart/calculate", http.Header{api.LaneHeader: {"urgent"}}, body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		recorder.err = errors.NewOverloadedError("the checkout lane is at capacity, retry later", time.Second)
+		rec, resp := doJSON(t, h, "/v2/cart/calculate", nil, body)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+		assert.Contains(t, resp["error"], "at capacity")
+	})
+}
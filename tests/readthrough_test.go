@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/pkg/breaker"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/discounttest"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// downRepository fails every call while down and counts the calls that reach it
+type downRepository struct {
+	interfaces.IDiscountRepository
+	down  bool
+	calls int
+}
+
+func (r *downRepository) fail() error {
+	r.calls++
+	if r.down {
+		return fmt.Errorf("connection refused")
+	}
+	return nil
+}
+
+func (r *downRepository) GetActiveDiscounts(ctx context.Context) ([]models.Discount, error) {
+	if err := r.fail(); err != nil {
+		return nil, err
+	}
+	return r.IDiscountRepository.GetActiveDiscounts(ctx)
+}
+
+func (r *downRepository) GetDiscountByID(ctx context.Context, id string) (*models.Discount, error) {
+	if err := r.fail(); err != nil {
+		return nil, err
+	}
+	return r.IDiscountRepository.GetDiscountByID(ctx, id)
+}
+
+func (r *downRepository) GetDiscountByCode(ctx context.Context, code string) (*models.Discount, error) {
+	if err := r.fail(); err != nil {
+		return nil, err
+	}
+	return r.IDiscountRepository.GetDiscountByCode(ctx, code)
+}
+
+func (r *downRepository) CreateDiscount(ctx context.Context, discount *models.Discount) error {
+	if err := r.fail(); err != nil {
+		return err
+	}
+	return r.IDiscountRepository.CreateDiscount(ctx, discount)
+}
+
+func TestReadThroughDiscountRepository(t *testing.T) {
+	ctx := context.Background()
+	puma := discounttest.Discount("puma", models.DiscountTypeBrand).Percent("10").On("PUMA").Build()
+	save := discounttest.Discount("save", models.DiscountTypeVoucher).Fixed("100").Code("SAVE100").Build()
+
+	newRepo := func(t *testing.T) (*repository.ReadThroughDiscountRepository, *downRepository, *clock.FixedClock) {
+		primary := &downRepository{IDiscountRepository: discounttest.NewRepository(t, puma, save)}
+		fast := repository.NewInMemoryDiscountRepository().(repository.FastDiscountStore)
+		repo := repository.NewReadThroughDiscountRepository(fast, primary)
+		c := clock.NewFixed(time.Now())
+		repo.Breaker = &breaker.Breaker{Name: "primary", Threshold: 2, Cooldown: time.Minute, Clock: c}
+		return repo, primary, c
+	}
+
+	t.Run("Misses are filled from the primary", func(t *testing.T) {
+		repo, primary, _ := newRepo(t)
+		for range 3 {
+			d, err := repo.GetDiscountByCode(ctx, "SAVE100")
+			require.NoError(t, err)
+			assert.Equal(t, "save", d.ID)
+		}
+		assert.Equal(t, 1, primary.calls, "later reads are served by the fast store")
+
+		_, err := repo.GetDiscountByID(ctx, "nope")
+		assert.True(t, errors.IsNotFoundError(err))
+	})
+
+	t.Run("Pricing carries on from the fast store while the primary is down", func(t *testing.T) {
+		repo, primary, c := newRepo(t)
+		_, err := repo.GetActiveDiscounts(ctx)
+		require.NoError(t, err)
+
+		primary.down = true
+		for range 3 {
+			active, err := repo.GetActiveDiscounts(ctx)
+			require.NoError(t, err)
+			assert.Len(t, active, 2)
+		}
+		assert.Equal(t, breaker.Open, repo.Breaker.State())
+		calls := primary.calls
+
+		d := discounttest.Discount("new", models.DiscountTypeBrand).Percent("5").On("NIKE").Build()
+		err = repo.CreateDiscount(ctx, &d)
+		assert.True(t, errors.IsOverloadedError(err), "writes fail fast while the breaker is open")
+		assert.Equal(t, calls, primary.calls)
+
+		c.Advance(time.Minute)
+		primary.down = false
+		require.NoError(t, repo.CreateDiscount(ctx, &d), "a trial call closes the breaker again")
+		assert.Equal(t, breaker.Closed, repo.Breaker.State())
+		active, err := repo.GetActiveDiscounts(ctx)
+		require.NoError(t, err)
+		assert.Len(t, active, 3, "writes go through to the fast store")
+	})
+
+	t.Run("Errors about the request do not open the breaker", func(t *testing.T) {
+		repo, _, _ := newRepo(t)
+		for range 3 {
+			_, err := repo.GetDiscountByID(ctx, "nope")
+			assert.True(t, errors.IsNotFoundError(err))
+		}
+		assert.Equal(t, breaker.Closed, repo.Breaker.State())
+	})
+}
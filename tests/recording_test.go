@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/recording"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestRecordingMiddleware(t *testing.T) {
+	cartItems, customer, paymentInfo := testdata.GetMultipleDiscountScenario()
+	body := map[string]any{"cart_items": cartItems, "customer": customer, "payment_info": paymentInfo}
+
+	record := func(t *testing.T, cfg recording.Config, path string, header http.Header, body any) (map[string]any, []recording.Exchange) {
+		t.Helper()
+		var lines bytes.Buffer
+		h := recording.Middleware(newTestAPI(t), recording.NewJSONSink(&lines), cfg)
+		_, resp := doJSON(t, h, path, header, body)
+
+		var exchanges []recording.Exchange
+		for _, line := range strings.Split(strings.TrimSpace(lines.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var exchange recording.Exchange
+			require.NoError(t, json.Unmarshal([]byte(line), &exchange))
+			exchanges = append(exchanges, exchange)
+		}
+		return resp, exchanges
+	}
+
+	t.Run("Records the exchange with personal data redacted", func(t *testing.T) {
+		resp, exchanges := record(t, recording.Config{SampleRate: 1}, "/v2/cart/calculate", nil, body)
+		require.Len(t, exchanges, 1)
+		exchange := exchanges[0]
+
+		assert.Equal(t, http.MethodPost, exchange.Method)
+		assert.Equal(t, "/v2/cart/calculate", exchange.Path)
+		assert.Equal(t, "v2", exchange.Version)
+		assert.Equal(t, http.StatusOK, exchange.Status)
+
+		request := exchange.Request.(map[string]any)
+		assert.Equal(t, recording.Redacted, request["customer"].(map[string]any)["id"])
+		assert.Equal(t, customer.Tier, request["customer"].(map[string]any)["tier"], "only personal fields are redacted")
+		assert.Equal(t, recording.Redacted, request["payment_info"].(map[string]any)["bank_name"])
+		assert.Len(t, request["cart_items"], len(cartItems))
+
+		assert.Equal(t, resp["final_price"], exchange.Response.(map[string]any)["final_price"],
+			"the handler's response is recorded as sent")
+	})
+
+	t.Run("Paths are redacted wherever they are nested", func(t *testing.T) {
+		batch := map[string]any{"carts": []any{body, body}}
+		_, exchanges := record(t, recording.Config{SampleRate: 1}, "/v2/cart/calculate/batch", nil, batch)
+		require.Len(t, exchanges, 1)
+		carts := exchanges[0].Request.(map[string]any)["carts"].([]any)
+		require.Len(t, carts, 2)
+		for _, cart := range carts {
+			recorded := cart.(map[string]any)["customer"].(map[string]any)
+			assert.Equal(t, recording.Redacted, recorded["id"])
+			assert.Equal(t, customer.Tier, recorded["tier"])
+		}
+	})
+
+	t.Run("Dates of birth are redacted", func(t *testing.T) {
+		born := customer
+		born.DateOfBirth = time.Date(1990, time.March, 14, 0, 0, 0, 0, time.UTC)
+		_, exchanges := record(t, recording.Config{SampleRate: 1}, "/v2/cart/calculate", nil,
+			map[string]any{"cart_items": cartItems, "customer": born, "payment_info": paymentInfo})
+		require.Len(t, exchanges, 1)
+		recorded := exchanges[0].Request.(map[string]any)["customer"].(map[string]any)
+		assert.Equal(t, recording.Redacted, recorded["date_of_birth"])
+	})
+
+	t.Run("Card BINs are redacted", func(t *testing.T) {
+		payment := map[string]any{"method": "CARD", "bank_name": "HDFC", "card_type": "CREDIT", "card_bin": "411111"}
+		_, exchanges := record(t, recording.Config{SampleRate: 1}, "/v2/cart/calculate", nil,
+			map[string]any{"cart_items": cartItems, "customer": customer, "payment_info": payment})
+		require.Len(t, exchanges, 1)
+		recorded := exchanges[0].Request.(map[string]any)["payment_info"].(map[string]any)
+		assert.Equal(t, recording.Redacted, recorded["card_bin"])
+		assert.Equal(t, "CREDIT", recorded["card_type"])
+	})
+
+	t.Run("Custom fields replace the defaults", func(t *testing.T) {
+		_, exchanges := record(t, recording.Config{SampleRate: 1, RedactedFields: []string{"tier"}},
+			"/v2/cart/calculate", nil, body)
+		require.Len(t, exchanges, 1)
+		recorded := exchanges[0].Request.(map[string]any)["customer"].(map[string]any)
+		assert.Equal(t, recording.Redacted, recorded["tier"])
+		assert.Equal(t, customer.ID, recorded["id"])
+	})
+
+	t.Run("Failed requests are recorded too", func(t *testing.T) {
+		_, exchanges := record(t, recording.Config{SampleRate: 1}, "/v2/cart/calculate", nil,
+			map[string]any{"cart_items": "not a list"})
+		require.Len(t, exchanges, 1)
+		assert.Equal(t, http.StatusBadRequest, exchanges[0].Status)
+		assert.Contains(t, exchanges[0].Response.(map[string]any)["error"], "invalid request body")
+	})
+
+	t.Run("Oversized bodies are dropped", func(t *testing.T) {
+		resp, exchanges := record(t, recording.Config{SampleRate: 1, MaxBodyBytes: 64}, "/v2/cart/calculate", nil, body)
+		assert.NotNil(t, resp["final_price"], "the request is still served in full")
+		require.Len(t, exchanges, 1)
+		assert.True(t, exchanges[0].Truncated)
+		assert.Nil(t, exchanges[0].Request)
+	})
+
+	t.Run("Sampling", func(t *testing.T) {
+		_, exchanges := record(t, recording.Config{}, "/v2/cart/calculate", nil, body)
+		assert.Empty(t, exchanges, "a zero rate records nothing")
+
+		_, exchanges = record(t, recording.Config{SampleRate: 1, Sample: func() bool { return false }},
+			"/v2/cart/calculate", nil, body)
+		assert.Empty(t, exchanges)
+	})
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestDiscountService_ReferralCode(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	referral := models.Discount{
+		ID:             "ref-alice",
+		Name:           "Referred by Alice",
+		Type:           models.DiscountTypeReferral,
+		Code:           "ALICE10",
+		Value:          decimal.NewFromInt(10),
+		IsPercentage:   true,
+		MaxAmount:      decimal.NewFromInt(150),
+		ReferrerID:     "cust-alice",
+		ReferrerReward: decimal.NewFromInt(50),
+		FirstOrderOnly: true,
+		ValidFrom:      now.Add(-time.Hour),
+		ValidTo:        now.Add(time.Hour),
+		IsActive:       true,
+		Priority:       10,
+	}
+
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &referral))
+
+	ledger := repository.NewInMemoryReferralLedger()
+	service := services.NewDiscountService(repo, services.WithReferralLedger(ledger))
+
+	cartItems := []models.CartItem{{Product: testdata.GetSampleProducts()[0], Quantity: 1, Size: "M"}} // 1000
+	buyer := models.CustomerProfile{ID: "cust-bob", Tier: "regular"}
+
+	t.Run("Code must be entered", func(t *testing.T) {
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{CartItems: cartItems, Customer: buyer})
+		require.NoError(t, err)
+		assert.Empty(t, result.AppliedDiscounts)
+	})
+
+	t.Run("Referrer cannot use their own code", func(t *testing.T) {
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems: cartItems,
+			Customer:  models.CustomerProfile{ID: "cust-alice", Tier: "regular"},
+			Codes:     []string{"ALICE10"},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, result.AppliedDiscounts)
+	})
+
+	t.Run("Buyer gets the discount and referrer a pending reward", func(t *testing.T) {
+		result, err := service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems: cartItems,
+			Customer:  buyer,
+			Codes:     []string{"ALICE10"},
+		})
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(100).Equal(result.AppliedDiscounts["Referred by Alice"]))
+
+		rewards, err := ledger.ListRewards(ctx, "cust-alice")
+		require.NoError(t, err)
+		require.Len(t, rewards, 1)
+		assert.Equal(t, "cust-bob", rewards[0].RefereeID)
+		assert.Equal(t, models.ReferralRewardPending, rewards[0].Status)
+		assert.True(t, decimal.NewFromInt(50).Equal(rewards[0].Amount))
+	})
+
+	t.Run("Referral codes are hidden from offer listings", func(t *testing.T) {
+		offers, err := service.ListOffers(ctx, buyer)
+		require.NoError(t, err)
+		assert.Empty(t, offers)
+	})
+
+	t.Run("Referral discounts need a referrer", func(t *testing.T) {
+		invalid := referral
+		invalid.ID = "ref-nobody"
+		invalid.Code = "NOBODY"
+		invalid.ReferrerID = ""
+		err := repo.CreateDiscount(ctx, &invalid)
+		assert.True(t, errors.IsValidationError(err))
+	})
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+)
+
+func TestDiscount_IsAvailableIn(t *testing.T) {
+	karnataka := &models.Discount{AllowedRegions: []string{"KA"}}
+	bengaluru := &models.Discount{AllowedRegions: []string{models.RegionRef(models.RegionKindPincode, "560*")}}
+	notSouth := &models.Discount{ExcludedRegions: []string{models.RegionRef(models.RegionKindRegion, "south")}}
+
+	mysuru := &models.Location{Region: "south", State: "KA", Pincode: "570001"}
+	mumbai := &models.Location{Region: "west", State: "MH", Pincode: "400001"}
+
+	assert.True(t, karnataka.IsAvailableIn(mysuru))
+	assert.False(t, karnataka.IsAvailableIn(mumbai))
+	assert.False(t, karnataka.IsAvailableIn(nil), "orders of unknown location do not get region-limited discounts")
+	assert.False(t, bengaluru.IsAvailableIn(mysuru))
+	assert.True(t, bengaluru.IsAvailableIn(&models.Location{State: "KA", Pincode: "560034"}))
+	assert.False(t, notSouth.IsAvailableIn(mysuru))
+	assert.True(t, notSouth.IsAvailableIn(mumbai))
+	assert.True(t, notSouth.IsAvailableIn(nil))
+	assert.True(t, (&models.Discount{}).IsAvailableIn(nil))
+
+	assert.Error(t, (&models.Discount{AllowedRegions: []string{"city:Pune"}}).CheckRegions())
+	assert.Error(t, (&models.Discount{ExcludedRegions: []string{"pincode:"}}).CheckRegions())
+}
+
+func TestDiscountService_RegionRestrictions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID: "karnataka-10", Name: "10% off only in Karnataka", Type: models.DiscountTypeVoucher,
+		Value: decimal.NewFromInt(10), IsPercentage: true,
+		ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true,
+		AllowedRegions: []string{"KA"},
+	}))
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 1}}
+	req := func(location *models.Location) *models.CalculationRequest {
+		return &models.CalculationRequest{CartItems: cart, Location: location}
+	}
+
+	result, err := service.CalculateCart(ctx, req(&models.Location{State: "KA", Pincode: "560001"}))
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(900).Equal(result.FinalPrice), "got %s", result.FinalPrice)
+
+	result, err = service.CalculateCart(ctx, req(&models.Location{State: "TN", Pincode: "600001"}))
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(1000).Equal(result.FinalPrice))
+
+	explanation, err := service.ExplainCartDiscounts(ctx, req(nil))
+	require.NoError(t, err)
+	decision, ok := explanation.Decision("karnataka-10")
+	require.True(t, ok)
+	assert.Equal(t, models.ReasonRegionRestricted, decision.Reason)
+
+	err = repo.CreateDiscount(ctx, &models.Discount{ID: "bad", Type: models.DiscountTypeVoucher, AllowedRegions: []string{"city:Pune"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,274 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/auth"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/reports"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestReportingService_IncrementalityReport(t *testing.T) {
+	ctx := context.Background()
+	experimentRepo := repository.NewInMemoryExperimentRepository()
+	orderRepo := repository.NewInMemoryOrderRepository()
+	redemptionRepo := repository.NewInMemoryRedemptionRepository()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(7 * 24 * time.Hour)
+
+	assignments := []models.ExperimentAssignment{
+		{ExperimentID: "exp-1", CustomerID: "c1", Variant: "control", AssignedAt: start},
+		{ExperimentID: "exp-1", CustomerID: "c2", Variant: "control", AssignedAt: start},
+		{ExperimentID: "exp-1", CustomerID: "c3", Variant: "promo", AssignedAt: start},
+		{ExperimentID: "exp-1", CustomerID: "c4", Variant: "promo", AssignedAt: start},
+	}
+	for _, a := range assignments {
+		require.NoError(t, experimentRepo.RecordAssignment(ctx, a))
+	}
+
+	orders := []models.Order{
+		{ID: "o1", CustomerID: "c1", Total: decimal.NewFromInt(1000), PlacedAt: start.Add(time.Hour)},
+		{ID: "o2", CustomerID: "c3", Total: decimal.NewFromInt(1500), PlacedAt: start.Add(time.Hour)},
+		{ID: "o3", CustomerID: "c4", Total: decimal.NewFromInt(500), PlacedAt: start.Add(2 * time.Hour)},
+		{ID: "o4", CustomerID: "c2", Total: decimal.NewFromInt(9999), PlacedAt: start.Add(-time.Hour)}, // before window
+	}
+	for _, o := range orders {
+		require.NoError(t, orderRepo.RecordOrder(ctx, o))
+	}
+
+	require.NoError(t, redemptionRepo.RecordRedemption(ctx, models.Redemption{
+		DiscountID: "disc-001", CustomerID: "c3", Amount: decimal.NewFromInt(150), RedeemedAt: start.Add(time.Hour),
+	}))
+
+	reporting := services.NewReportingService(experimentRepo, orderRepo, redemptionRepo)
+	report, err := reporting.GetIncrementalityReport(ctx, "exp-1", "control", start, end)
+	require.NoError(t, err)
+	require.Len(t, report.Variants, 2)
+
+	control, promo := report.Variants[0], report.Variants[1]
+	assert.Equal(t, "control", control.Variant)
+	assert.Equal(t, 1, control.ConvertedCustomers)
+	assert.True(t, decimal.NewFromInt(50).Equal(control.ConversionRate), "got %s", control.ConversionRate)
+	assert.True(t, decimal.NewFromInt(1000).Equal(control.AverageOrderValue), "got %s", control.AverageOrderValue)
+
+	assert.Equal(t, "promo", promo.Variant)
+	assert.Equal(t, 2, promo.Orders)
+	assert.Equal(t, 1, promo.Redemptions)
+	assert.True(t, decimal.NewFromInt(150).Equal(promo.DiscountCost))
+	assert.True(t, decimal.NewFromInt(100).Equal(promo.ConversionUplift), "got %s", promo.ConversionUplift)
+	assert.True(t, decimal.Zero.Equal(promo.AOVUplift), "got %s", promo.AOVUplift)
+
+	_, err = reporting.GetIncrementalityReport(ctx, "exp-1", "missing", start, end)
+	assert.True(t, errors.IsNotFoundError(err))
+}
+
+func TestDiscountService_RecordsRedemptions(t *testing.T) {
+	repo := repository.NewInMemoryDiscountRepository()
+	memoryRepo := repo.(*repository.InMemoryDiscountRepository)
+	require.NoError(t, memoryRepo.SeedDiscounts(testdata.GetSampleDiscounts()))
+
+	redemptionRepo := repository.NewInMemoryRedemptionRepository()
+	service := services.NewDiscountService(repo, services.WithRedemptionRepository(redemptionRepo))
+	ctx := context.Background()
+
+	cartItems, customer, paymentInfo := testdata.GetMultipleDiscountScenario()
+	ResetCartPricesToBase(cartItems)
+
+	result, err := service.CalculateCartDiscounts(ctx, cartItems, customer, paymentInfo)
+	require.NoError(t, err)
+
+	redemptions, err := redemptionRepo.ListRedemptions(ctx, models.RedemptionFilter{CustomerID: customer.ID})
+	require.NoError(t, err)
+	assert.Len(t, redemptions, len(result.AppliedDiscounts))
+
+	total := decimal.Zero
+	for _, r := range redemptions {
+		total = total.Add(r.Amount)
+	}
+	assert.True(t, result.GetTotalDiscount().Equal(total))
+}
+
+func TestReportingService_CodeLeaderboard(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	influencers := models.Discount{
+		ID:           "disc-influencers",
+		Name:         "Influencer 10%",
+		Type:         models.DiscountTypeVoucher,
+		Value:        decimal.NewFromInt(10),
+		IsPercentage: true,
+		Code:         "SPRING10",
+		CodeAliases:  []string{"ANNA10", "BEN10"},
+		CampaignID:   "spring",
+		ValidFrom:    now.Add(-time.Hour),
+		ValidTo:      now.Add(time.Hour),
+		IsActive:     true,
+	}
+	discountRepo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, discountRepo.CreateDiscount(ctx, &influencers))
+	redemptionRepo := repository.NewInMemoryRedemptionRepository()
+	service := services.NewDiscountService(discountRepo,
+		services.WithClock(clock.NewFixed(now)),
+		services.WithRedemptionRepository(redemptionRepo))
+
+	product := testdata.GetSampleProducts()[3] // 1200
+	for _, checkout := range []struct {
+		quantity int
+		codes    []string
+	}{
+		{1, []string{"ANNA10"}},
+		{2, []string{"BEN10"}},
+		{1, []string{"BEN10"}},
+		{1, []string{"SPRING10", "ANNA10"}},
+		{1, nil},
+	} {
+		_, err := service.CalculateCart(ctx, &models.CalculationRequest{
+			CartItems: []models.CartItem{{Product: product, Quantity: checkout.quantity, Size: "32"}},
+			Customer:  testdata.GetSampleCustomers()[1],
+			Codes:     checkout.codes,
+		})
+		require.NoError(t, err)
+	}
+
+	reporting := services.NewReportingService(repository.NewInMemoryExperimentRepository(),
+		repository.NewInMemoryOrderRepository(), redemptionRepo)
+	board, err := reporting.GetCodeLeaderboard(ctx, "spring", now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, board.Unattributed)
+	require.Len(t, board.Standings, 3)
+	ben, anna, main := board.Standings[0], board.Standings[1], board.Standings[2]
+	assert.Equal(t, 1, ben.Rank)
+	assert.Equal(t, "BEN10", ben.Code)
+	assert.Equal(t, influencers.ID, ben.DiscountID)
+	assert.Equal(t, 2, ben.Redemptions)
+	assert.True(t, decimal.NewFromInt(3240).Equal(ben.Revenue), "got %s", ben.Revenue)
+	assert.True(t, decimal.NewFromInt(360).Equal(ben.DiscountCost), "got %s", ben.DiscountCost)
+	assert.Equal(t, "ANNA10", anna.Code)
+	assert.Equal(t, 1, anna.Redemptions)
+	assert.True(t, decimal.NewFromInt(1080).Equal(anna.Revenue))
+	assert.Equal(t, "SPRING10", main.Code, "the main code wins when several codes of the discount are entered")
+	assert.Equal(t, 3, main.Rank)
+
+	t.Run("Window and campaign narrow the board", func(t *testing.T) {
+		board, err := reporting.GetCodeLeaderboard(ctx, "spring", now.Add(time.Minute), now.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, board.Standings)
+
+		board, err = reporting.GetCodeLeaderboard(ctx, "autumn", time.Time{}, time.Time{})
+		require.NoError(t, err)
+		assert.Empty(t, board.Standings)
+		assert.Zero(t, board.Unattributed)
+
+		_, err = reporting.GetCodeLeaderboard(ctx, "", time.Time{}, time.Time{})
+		assert.True(t, errors.IsValidationError(err))
+	})
+}
+
+func TestReportsHandler_Experiments(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	experimentRepo := repository.NewInMemoryExperimentRepository()
+	orderRepo := repository.NewInMemoryOrderRepository()
+	for _, a := range []models.ExperimentAssignment{
+		{ExperimentID: "exp-1", CustomerID: "c1", Variant: "control", AssignedAt: start},
+		{ExperimentID: "exp-1", CustomerID: "c2", Variant: "promo", AssignedAt: start},
+	} {
+		require.NoError(t, experimentRepo.RecordAssignment(ctx, a))
+	}
+	reporting := services.NewReportingService(experimentRepo, orderRepo, repository.NewInMemoryRedemptionRepository())
+	authn := auth.APIKeys{
+		"viewer-key": {ID: "vic", Roles: []models.Role{models.RoleViewer}},
+		"admin-key":  {ID: "root", Roles: []models.Role{models.RoleAdmin}},
+	}
+	h := auth.Middleware(reports.NewHandler(reporting, orderRepo), authn, auth.DefaultPolicy())
+
+	serve := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(auth.APIKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	order := `{"id": "o1", "customer_id": "c2", "total": "800", "placed_at": "2025-01-01T10:00:00Z"}`
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/admin/reports/orders", "viewer-key", order).Code)
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodPost, "/admin/reports/orders", "admin-key", order).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/admin/reports/orders", "admin-key", `{"id": "o2"}`).Code)
+
+	path := "/admin/reports/experiments/exp-1?control=control&from=2025-01-01T00:00:00Z&to=2025-01-08T00:00:00Z"
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, path, "", "").Code)
+	rec := serve(http.MethodGet, path, "viewer-key", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var report models.IncrementalityReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	require.Len(t, report.Variants, 2)
+	assert.Equal(t, "promo", report.Variants[1].Variant)
+	assert.Equal(t, 1, report.Variants[1].Orders)
+	assert.True(t, decimal.NewFromInt(800).Equal(report.Variants[1].Revenue))
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/admin/reports/experiments/exp-1", "viewer-key", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/admin/reports/experiments/exp-1?control=control&from=yesterday", "viewer-key", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/admin/reports/experiments/exp-1?control=missing", "viewer-key", "").Code)
+}
+
+func TestReportsHandler_CodeLeaderboard(t *testing.T) {
+	ctx := context.Background()
+	redeemedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	redemptionRepo := repository.NewInMemoryRedemptionRepository()
+	for _, r := range []models.Redemption{
+		{DiscountID: "disc-influencers", CampaignID: "spring", CustomerID: "c1", Code: "ANNA10",
+			Amount: decimal.NewFromInt(100), OrderTotal: decimal.NewFromInt(900), RedeemedAt: redeemedAt},
+		{DiscountID: "disc-influencers", CampaignID: "spring", CustomerID: "c2", Code: "BEN10",
+			Amount: decimal.NewFromInt(200), OrderTotal: decimal.NewFromInt(1800), RedeemedAt: redeemedAt},
+	} {
+		require.NoError(t, redemptionRepo.RecordRedemption(ctx, r))
+	}
+	reporting := services.NewReportingService(repository.NewInMemoryExperimentRepository(),
+		repository.NewInMemoryOrderRepository(), redemptionRepo)
+	authn := auth.APIKeys{"viewer-key": {ID: "vic", Roles: []models.Role{models.RoleViewer}}}
+	h := auth.Middleware(reports.NewHandler(reporting, repository.NewInMemoryOrderRepository()), authn, auth.DefaultPolicy())
+
+	serve := func(path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if key != "" {
+			req.Header.Set(auth.APIKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve("/admin/reports/campaigns/spring/codes", "").Code)
+	rec := serve("/admin/reports/campaigns/spring/codes?from=2025-03-01T00:00:00Z", "viewer-key")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var board models.CodeLeaderboard
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &board))
+	require.Len(t, board.Standings, 2)
+	assert.Equal(t, "BEN10", board.Standings[0].Code)
+	assert.Equal(t, 1, board.Standings[0].Rank)
+
+	rec = serve("/admin/reports/campaigns/spring/codes?from=2025-03-02T00:00:00Z", "viewer-key")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &board))
+	assert.Empty(t, board.Standings, "redemptions before the window are left out")
+}
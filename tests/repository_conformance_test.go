@@ -0,0 +1,29 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/repositories/repositorytest"
+	"github.com/ahsmha/discounts/internal/resilience"
+)
+
+func TestInMemoryDiscountRepository_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) interfaces.IDiscountRepository {
+		return repository.NewInMemoryDiscountRepository()
+	})
+}
+
+func TestReadThroughDiscountRepository_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) interfaces.IDiscountRepository {
+		fast := repository.NewInMemoryDiscountRepository().(repository.FastDiscountStore)
+		return repository.NewReadThroughDiscountRepository(fast, repository.NewInMemoryDiscountRepository())
+	})
+}
+
+func TestResilientDiscountRepository_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) interfaces.IDiscountRepository {
+		return resilience.NewRepository(repository.NewInMemoryDiscountRepository(), resilience.DefaultConfig())
+	})
+}
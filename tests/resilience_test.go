@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/resilience"
+	"github.com/ahsmha/discounts/pkg/breaker"
+	"github.com/ahsmha/discounts/pkg/discounttest"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// unreliableRepository fails its first failures calls and stalls every call by delay, or
+// until its context is done
+type unreliableRepository struct {
+	interfaces.IDiscountRepository
+	failures int32
+	delay    time.Duration
+	calls    atomic.Int32
+	returned atomic.Int32
+}
+
+func (r *unreliableRepository) call(ctx context.Context) error {
+	defer r.returned.Add(1)
+	n := r.calls.Add(1)
+	select {
+	case <-time.After(r.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if n <= r.failures {
+		return fmt.Errorf("connection reset")
+	}
+	return nil
+}
+
+func (r *unreliableRepository) GetDiscountByID(ctx context.Context, id string) (*models.Discount, error) {
+	if err := r.call(ctx); err != nil {
+		return nil, err
+	}
+	return r.IDiscountRepository.GetDiscountByID(ctx, id)
+}
+
+func (r *unreliableRepository) CheckAndIncrementUsage(ctx context.Context, id string) error {
+	if err := r.call(ctx); err != nil {
+		return err
+	}
+	return r.IDiscountRepository.CheckAndIncrementUsage(ctx, id)
+}
+
+func TestResilientDiscountRepository(t *testing.T) {
+	ctx := context.Background()
+	puma := discounttest.Discount("puma", models.DiscountTypeBrand).Percent("10").On("PUMA").Build()
+	cfg := resilience.Config{Retries: 2, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond, BreakerThreshold: 3, BreakerCooldown: time.Minute}
+
+	stats := func(repo *resilience.Repository, op string) resilience.OperationStats {
+		for _, s := range repo.Stats().Operations {
+			if s.Operation == op {
+				return s
+			}
+		}
+		return resilience.OperationStats{}
+	}
+
+	t.Run("Failed reads are retried", func(t *testing.T) {
+		inner := &unreliableRepository{IDiscountRepository: discounttest.NewRepository(t, puma), failures: 2}
+		repo := resilience.NewRepository(inner, cfg)
+
+		d, err := repo.GetDiscountByID(ctx, "puma")
+		require.NoError(t, err)
+		assert.Equal(t, "puma", d.ID)
+		assert.Equal(t, int32(3), inner.calls.Load())
+		assert.Equal(t, resilience.OperationStats{Operation: "GetDiscountByID", Calls: 1, Failures: 2, Retries: 2}, stats(repo, "GetDiscountByID"))
+		assert.Equal(t, breaker.Closed, repo.Breaker().State())
+	})
+
+	t.Run("Writes are tried once", func(t *testing.T) {
+		inner := &unreliableRepository{IDiscountRepository: discounttest.NewRepository(t, puma), failures: 1}
+		repo := resilience.NewRepository(inner, cfg)
+
+		assert.Error(t, repo.CheckAndIncrementUsage(ctx, "puma"))
+		assert.Equal(t, int32(1), inner.calls.Load(), "a repeated use could be counted twice")
+		d, err := inner.IDiscountRepository.GetDiscountByID(ctx, "puma")
+		require.NoError(t, err)
+		assert.Equal(t, 0, d.UsedCount)
+	})
+
+	t.Run("Slow calls time out and open the breaker", func(t *testing.T) {
+		inner := &unreliableRepository{IDiscountRepository: discounttest.NewRepository(t, puma), delay: 50 * time.Millisecond}
+		timed := cfg
+		timed.Timeout = 5 * time.Millisecond
+		repo := resilience.NewRepository(inner, timed)
+
+		started := time.Now()
+		_, err := repo.GetDiscountByID(ctx, "puma")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(started), 50*time.Millisecond, "callers do not wait for the slow repository")
+		assert.Equal(t, uint64(3), stats(repo, "GetDiscountByID").Timeouts)
+		assert.Equal(t, breaker.Open, repo.Breaker().State())
+
+		_, err = repo.GetDiscountByID(ctx, "puma")
+		assert.True(t, errors.IsOverloadedError(err), "the open breaker fails calls at once")
+		assert.Equal(t, uint64(1), stats(repo, "GetDiscountByID").Rejected)
+
+		// The abandoned attempts stop once their context is done
+		assert.Eventually(t, func() bool { return inner.returned.Load() == 3 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("Timed out writes are waited for", func(t *testing.T) {
+		inner := &unreliableRepository{IDiscountRepository: discounttest.NewRepository(t, puma), delay: 50 * time.Millisecond}
+		timed := cfg
+		timed.Timeout = 5 * time.Millisecond
+		repo := resilience.NewRepository(inner, timed)
+
+		assert.ErrorIs(t, repo.CheckAndIncrementUsage(ctx, "puma"), context.DeadlineExceeded)
+		d, err := inner.IDiscountRepository.GetDiscountByID(ctx, "puma")
+		require.NoError(t, err)
+		assert.Equal(t, 0, d.UsedCount, "a write reported as failed did not happen")
+		assert.Equal(t, uint64(1), stats(repo, "CheckAndIncrementUsage").Timeouts)
+	})
+
+	t.Run("Errors about the request are not retried", func(t *testing.T) {
+		inner := &unreliableRepository{IDiscountRepository: discounttest.NewRepository(t, puma)}
+		repo := resilience.NewRepository(inner, cfg)
+
+		_, err := repo.GetDiscountByID(ctx, "nope")
+		assert.True(t, errors.IsNotFoundError(err))
+		assert.Equal(t, int32(1), inner.calls.Load())
+	})
+}
@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/discounttest"
+)
+
+func TestDiscountService_AppliedVersions(t *testing.T) {
+	ctx := context.Background()
+	repo := discounttest.NewRepository(t, discounttest.Discount("puma", models.DiscountTypeBrand).Percent("10").On("PUMA").Build())
+	svc := services.NewDiscountService(repo)
+	cart := discounttest.Cart().Add(discounttest.Product("tee", "PUMA", "tshirts", "1000"), 1)
+
+	result, err := svc.CalculateCart(ctx, cart.Request())
+	require.NoError(t, err)
+	require.Len(t, result.Breakdown, 1)
+	assert.Equal(t, 1, result.Breakdown[0].Version)
+	pricedAt := result.Breakdown[0]
+
+	puma, err := repo.GetDiscountByID(ctx, "puma")
+	require.NoError(t, err)
+	puma.Value = decimal.NewFromInt(20)
+	require.NoError(t, repo.UpdateDiscount(ctx, puma))
+
+	result, err = svc.CalculateCart(ctx, cart.Request())
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Breakdown[0].Version)
+	assert.True(t, result.FinalPrice.Equal(decimal.NewFromInt(800)))
+
+	// The revision applied first can still be looked up
+	revisions, err := repo.ListRevisions(ctx, "puma")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	first := revisions[pricedAt.Version-1]
+	assert.True(t, first.Discount.Value.Equal(decimal.NewFromInt(10)))
+	previous, err := repo.GetDiscountAt(ctx, "puma", revisions[1].EffectiveFrom.Add(-1))
+	require.NoError(t, err)
+	assert.Equal(t, 1, previous.Version)
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestSandbox_IsolatedAndResettable(t *testing.T) {
+	ctx := context.Background()
+
+	production := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, production.(*repository.InMemoryDiscountRepository).SeedDiscounts(testdata.GetSampleDiscounts()))
+
+	sandbox, err := services.NewSandbox(testdata.GetSampleDiscounts())
+	require.NoError(t, err)
+
+	partnerDiscount := testdata.GetSampleDiscounts()[5]
+	partnerDiscount.ID, partnerDiscount.Code, partnerDiscount.Name = "partner-1", "PARTNER20", "Partner 20"
+	require.NoError(t, sandbox.Discounts().CreateDiscount(ctx, &partnerDiscount))
+
+	cartItems, customer, paymentInfo := testdata.GetMultipleDiscountScenario()
+	ResetCartPricesToBase(cartItems)
+
+	result, err := sandbox.Service().CalculateCartDiscounts(ctx, cartItems, customer, paymentInfo)
+	require.NoError(t, err)
+	assert.Contains(t, result.AppliedDiscounts, "Partner 20")
+
+	// Production counters are untouched
+	brand, err := production.GetDiscountByID(ctx, "disc-001")
+	require.NoError(t, err)
+	assert.Equal(t, 0, brand.UsedCount)
+
+	sandboxBrand, err := sandbox.Discounts().GetDiscountByID(ctx, "disc-001")
+	require.NoError(t, err)
+	assert.Equal(t, 1, sandboxBrand.UsedCount)
+
+	redemptions, err := sandbox.Redemptions().ListRedemptions(ctx, models.RedemptionFilter{})
+	require.NoError(t, err)
+	assert.Len(t, redemptions, len(result.AppliedDiscounts))
+
+	// Reset drops partner discounts and counters
+	require.NoError(t, sandbox.Reset(ctx))
+
+	_, err = sandbox.Discounts().GetDiscountByID(ctx, "partner-1")
+	assert.Error(t, err)
+
+	sandboxBrand, err = sandbox.Discounts().GetDiscountByID(ctx, "disc-001")
+	require.NoError(t, err)
+	assert.Equal(t, 0, sandboxBrand.UsedCount)
+}
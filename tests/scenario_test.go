@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/scenario"
+)
+
+// TestScenarios runs every acceptance scenario under testdata/scenarios
+func TestScenarios(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "scenarios", "*.yaml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		scenarios, err := scenario.LoadFile(file)
+		require.NoError(t, err)
+
+		for _, s := range scenarios {
+			t.Run(filepath.Base(file)+"/"+s.Name, func(t *testing.T) {
+				failures, err := scenario.Run(context.Background(), s)
+				require.NoError(t, err)
+				assert.Empty(t, failures)
+			})
+		}
+	}
+}
+
+func TestScenario_ReportsMismatches(t *testing.T) {
+	scenarios, err := scenario.Parse([]byte(`
+name: wrong expectations
+discounts:
+  - {id: flat, name: Flat 10, type: voucher, value: 10}
+cart:
+  - product: {id: p, current_price: 100}
+    quantity: 1
+customer: {id: c}
+expect:
+  final_price: 95
+  applied:
+    Flat 10: 5
+    Missing: null
+`))
+	require.NoError(t, err)
+	require.Len(t, scenarios, 1)
+
+	failures, err := scenario.Run(context.Background(), scenarios[0])
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"final price: expected 95, got 90",
+		`"Flat 10": expected 5 off, got 10`,
+		`expected "Missing" to apply`,
+	}, failures)
+}
+
+func TestScenario_RejectsUnknownFields(t *testing.T) {
+	_, err := scenario.Parse([]byte("name: typo\nexpect:\n  final_prise: 10\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+)
+
+// segmentsByCustomer resolves segments from a fixed table, failing for unknown customers
+type segmentsByCustomer map[string][]string
+
+func (s segmentsByCustomer) ResolveSegments(_ context.Context, customer models.CustomerProfile) ([]string, error) {
+	segments, ok := s[customer.ID]
+	if !ok {
+		return nil, fmt.Errorf("unknown customer %s", customer.ID)
+	}
+	return segments, nil
+}
+
+func TestDiscountService_Segments(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, &models.Discount{
+		ID: "win-back", Name: "Win back", Type: models.DiscountTypeVoucher,
+		Value: decimal.NewFromInt(20), IsPercentage: true,
+		ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour), IsActive: true,
+		Segments: []string{"lapsed"}, ExcludedSegments: []string{"fraud-risk"},
+	}))
+	resolver := segmentsByCustomer{"ann": {"lapsed"}, "bob": {"lapsed", "fraud-risk"}, "cat": {"high-value"}}
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)), services.WithSegmentResolver(resolver))
+
+	cart := []models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(1000)}, Quantity: 1}}
+	price := func(customer models.CustomerProfile) decimal.Decimal {
+		result, err := service.CalculateCartDiscounts(ctx, cart, customer, nil)
+		require.NoError(t, err)
+		return result.FinalPrice
+	}
+	assert.True(t, decimal.NewFromInt(800).Equal(price(models.CustomerProfile{ID: "ann"})), "resolved segments qualify")
+	assert.True(t, decimal.NewFromInt(1000).Equal(price(models.CustomerProfile{ID: "bob"})), "excluded segments win")
+	assert.True(t, decimal.NewFromInt(1000).Equal(price(models.CustomerProfile{ID: "cat"})))
+	assert.True(t, decimal.NewFromInt(800).Equal(price(models.CustomerProfile{ID: "cat", Segments: []string{"lapsed"}})),
+		"resolved segments add to the caller's")
+
+	offers, err := service.ListOffers(ctx, models.CustomerProfile{ID: "bob"})
+	require.NoError(t, err)
+	assert.Empty(t, offers)
+
+	explanation, err := service.ExplainCartDiscounts(ctx, &models.CalculationRequest{CartItems: cart, Customer: models.CustomerProfile{ID: "cat"}})
+	require.NoError(t, err)
+	decision, ok := explanation.Decision("win-back")
+	require.True(t, ok)
+	assert.Equal(t, models.ReasonCustomerIneligible, decision.Reason)
+	assert.Contains(t, decision.Detail, "segments")
+
+	_, err = service.CalculateCartDiscounts(ctx, cart, models.CustomerProfile{ID: "dan"}, nil)
+	assert.Error(t, err, "resolver failures fail the calculation")
+
+	err = repo.CreateDiscount(ctx, &models.Discount{ID: "contradiction", Type: models.DiscountTypeVoucher,
+		Segments: []string{"lapsed"}, ExcludedSegments: []string{"lapsed"}})
+	assert.Error(t, err)
+}
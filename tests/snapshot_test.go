@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/discounttest"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+// steppingClock moves forward by step every time it is read
+type steppingClock struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+func (c *steppingClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+// editingRepository runs edit once the discounts of a calculation have been loaded, standing
+// in for an admin changing the catalog while the request runs
+type editingRepository struct {
+	interfaces.IDiscountRepository
+	edit func()
+}
+
+func (r *editingRepository) GetApplicableDiscounts(ctx context.Context, filter models.DiscountFilter) ([]models.Discount, error) {
+	discounts, err := r.IDiscountRepository.GetApplicableDiscounts(ctx, filter)
+	if r.edit != nil {
+		r.edit()
+		r.edit = nil
+	}
+	return discounts, err
+}
+
+func TestDiscountService_CalculationSnapshot(t *testing.T) {
+	ctx := context.Background()
+	cart := discounttest.Cart().Add(discounttest.Product("tee", "PUMA", "tshirts", "1000"), 1)
+	start := discounttest.ValidFrom.Add(time.Hour)
+
+	t.Run("A request is priced at the instant it arrived", func(t *testing.T) {
+		// The discount ends a minute after the request arrives; every later read of the
+		// clock is past its end
+		ending := discounttest.Discount("ending", models.DiscountTypeBrand).Fixed("100").On("PUMA").
+			Valid(discounttest.ValidFrom, start.Add(time.Minute)).Build()
+		svc := services.NewDiscountService(discounttest.NewRepository(t, ending),
+			services.WithClock(&steppingClock{now: start, step: time.Hour}),
+			services.WithIdempotencyStore(repository.NewInMemoryIdempotencyStore(time.Hour)))
+
+		req := cart.Request()
+		req.IdempotencyKey = "k1"
+		result, err := svc.CalculateCart(ctx, req)
+		require.NoError(t, err)
+		assert.True(t, result.FinalPrice.Equal(decimal.NewFromInt(900)), result.FinalPrice.String())
+	})
+
+	t.Run("Edits made while a request runs do not reach it", func(t *testing.T) {
+		puma := discounttest.Discount("puma", models.DiscountTypeBrand).Fixed("100").On("PUMA").Build()
+		inner := discounttest.NewRepository(t, puma)
+		repo := &editingRepository{IDiscountRepository: inner, edit: func() {
+			d, err := inner.GetDiscountByID(ctx, "puma")
+			require.NoError(t, err)
+			d.Value = decimal.NewFromInt(500)
+			require.NoError(t, inner.UpdateDiscount(ctx, d))
+		}}
+		svc := services.NewDiscountService(repo)
+
+		result, err := svc.CalculateCart(ctx, cart.Request())
+		require.NoError(t, err)
+		assert.True(t, result.FinalPrice.Equal(decimal.NewFromInt(900)), "priced with the terms loaded")
+		require.Len(t, result.Breakdown, 1)
+		assert.Equal(t, 1, result.Breakdown[0].Version)
+	})
+
+	t.Run("Discounts deleted while a request runs fail it with a conflict", func(t *testing.T) {
+		puma := discounttest.Discount("puma", models.DiscountTypeBrand).Fixed("100").On("PUMA").Build()
+		inner := discounttest.NewRepository(t, puma)
+		repo := &editingRepository{IDiscountRepository: inner, edit: func() {
+			require.NoError(t, inner.DeleteDiscount(ctx, "puma"))
+		}}
+		svc := services.NewDiscountService(repo)
+
+		_, err := svc.CalculateCart(ctx, cart.Request())
+		assert.True(t, errors.IsConflictError(err), err)
+	})
+}
@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestSpendTrackers(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	trackers := map[string]interfaces.ISpendTracker{
+		"memory": repository.NewInMemorySpendTracker(),
+		"redis":  repository.NewRedisSpendTracker(client, "test:"),
+	}
+
+	for name, tracker := range trackers {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			limit := decimal.NewFromInt(100)
+
+			total, err := tracker.AddSpend(ctx, "disc-1", decimal.NewFromFloat(40.25), limit)
+			require.NoError(t, err)
+			assert.True(t, decimal.NewFromFloat(40.25).Equal(total), "got %s", total)
+
+			_, err = tracker.AddSpend(ctx, "disc-1", decimal.NewFromInt(60), limit)
+			assert.True(t, errors.IsValidationError(err), "spend over the cap must be refused")
+
+			spent, err := tracker.GetSpend(ctx, "disc-1")
+			require.NoError(t, err)
+			assert.True(t, decimal.NewFromFloat(40.25).Equal(spent), "refused spend must not be recorded")
+
+			// Concurrent spends never overshoot the cap
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, _ = tracker.AddSpend(ctx, "disc-2", decimal.NewFromInt(10), limit)
+				}()
+			}
+			wg.Wait()
+
+			spent, err = tracker.GetSpend(ctx, "disc-2")
+			require.NoError(t, err)
+			assert.True(t, limit.Equal(spent), "got %s", spent)
+		})
+	}
+}
+
+func TestDiscountService_MaxTotalSpend(t *testing.T) {
+	ctx := context.Background()
+
+	discounts := testdata.GetSampleDiscounts()
+	discounts[0].MaxTotalSpend = decimal.NewFromInt(1000) // PUMA brand 40%, 800 per scenario cart
+
+	repo := repository.NewInMemoryDiscountRepository()
+	memoryRepo := repo.(*repository.InMemoryDiscountRepository)
+	require.NoError(t, memoryRepo.SeedDiscounts(discounts))
+
+	tracker := repository.NewInMemorySpendTracker()
+	service := services.NewDiscountService(repo, services.WithSpendTracker(tracker))
+
+	cartItems, customer, _ := testdata.GetMultipleDiscountScenario()
+	ResetCartPricesToBase(cartItems)
+
+	result, err := service.CalculateCartDiscounts(ctx, cartItems, customer, nil)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(800).Equal(result.AppliedDiscounts[discounts[0].Name]))
+
+	// Only 200 of the cap is left for the second cart
+	result, err = service.CalculateCartDiscounts(ctx, cartItems, customer, nil)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(200).Equal(result.AppliedDiscounts[discounts[0].Name]))
+
+	// Reaching the cap deactivates the discount
+	stored, err := repo.GetDiscountByID(ctx, discounts[0].ID)
+	require.NoError(t, err)
+	assert.False(t, stored.IsActive)
+
+	result, err = service.CalculateCartDiscounts(ctx, cartItems, customer, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, result.AppliedDiscounts, discounts[0].Name)
+}
+
+func TestDiscountService_MaxTotalSpendReadOnlyRepository(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "brands.yaml"), []byte(`
+- id: puma-40
+  name: PUMA 40% off
+  type: brand
+  applicable_to: [PUMA]
+  value: 40
+  is_percentage: true
+  max_total_spend: 400
+  valid_from: 2024-01-01T00:00:00Z
+  valid_to: 2030-01-01T00:00:00Z
+  is_active: true
+`), 0o644))
+	repo, err := repository.NewFileDiscountRepository(dir)
+	require.NoError(t, err)
+	var reported []string
+	service := services.NewDiscountService(repo, services.WithSpendTracker(repository.NewInMemorySpendTracker()),
+		services.WithSpendCapErrorHandler(func(discountID string, err error) {
+			assert.True(t, errors.IsForbiddenError(err), "%v", err)
+			reported = append(reported, discountID)
+		}))
+
+	cartItems := []models.CartItem{{Product: testdata.GetSampleProducts()[0], Quantity: 1, Size: "M"}}
+	customer := testdata.GetSampleCustomers()[0]
+
+	// The file repository refuses the deactivation, which must not fail the checkout
+	result, err := service.CalculateCartDiscounts(ctx, cartItems, customer, nil)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(400).Equal(result.AppliedDiscounts["PUMA 40% off"]))
+	assert.Equal(t, []string{"puma-40"}, reported)
+
+	stored, err := repo.GetDiscountByID(ctx, "puma-40")
+	require.NoError(t, err)
+	assert.True(t, stored.IsActive)
+
+	// The tracker still keeps the capped discount off later carts
+	result, err = service.CalculateCartDiscounts(ctx, cartItems, customer, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, result.AppliedDiscounts, "PUMA 40% off")
+}
+
+// alwaysConflictingRepository loses every update to a concurrent edit
+type alwaysConflictingRepository struct {
+	interfaces.IDiscountRepository
+	updates int
+}
+
+func (r *alwaysConflictingRepository) UpdateDiscount(ctx context.Context, discount *models.Discount) error {
+	r.updates++
+	return errors.NewConflictError("discount was changed concurrently: " + discount.ID)
+}
+
+func TestDiscountService_MaxTotalSpendDeactivationRetries(t *testing.T) {
+	ctx := context.Background()
+	discounts := testdata.GetSampleDiscounts()
+	discounts[0].MaxTotalSpend = decimal.NewFromInt(400) // PUMA brand 40%
+
+	memoryRepo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, memoryRepo.(interfaces.DiscountSeeder).SeedDiscounts(discounts))
+	repo := &alwaysConflictingRepository{IDiscountRepository: memoryRepo}
+	var failure error
+	service := services.NewDiscountService(repo, services.WithSpendCapErrorHandler(func(_ string, err error) { failure = err }))
+
+	cartItems := []models.CartItem{{Product: testdata.GetSampleProducts()[0], Quantity: 1, Size: "M"}}
+	_, err := service.CalculateCartDiscounts(ctx, cartItems, testdata.GetSampleCustomers()[0], nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5, repo.updates, "deactivation gives up after a bounded number of conflicts")
+	assert.True(t, errors.IsConflictError(failure), "%v", failure)
+}
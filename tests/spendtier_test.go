@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscountService_SpendTiers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tiers := &models.Discount{
+		ID:   "spend-more",
+		Name: "Spend more save more",
+		Type: models.DiscountTypeSpendTier,
+		SpendTiers: []models.SpendTier{
+			{Threshold: decimal.NewFromInt(2000), Value: decimal.NewFromInt(200)},
+			{Threshold: decimal.NewFromInt(5000), Value: decimal.NewFromInt(600)},
+		},
+		ValidFrom: now.Add(-time.Hour),
+		ValidTo:   now.Add(time.Hour),
+		IsActive:  true,
+	}
+	brand := &models.Discount{
+		ID:           "puma",
+		Name:         "10% off PUMA",
+		Type:         models.DiscountTypeBrand,
+		Value:        decimal.NewFromInt(10),
+		IsPercentage: true,
+		ApplicableTo: []string{"PUMA"},
+		Priority:     10, // Ahead of the tiers
+		ValidFrom:    now.Add(-time.Hour),
+		ValidTo:      now.Add(time.Hour),
+		IsActive:     true,
+	}
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, tiers))
+	require.NoError(t, repo.CreateDiscount(ctx, brand))
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+
+	cart := func(brand string, price int64) []models.CartItem {
+		return []models.CartItem{{Product: models.Product{ID: "p1", Brand: models.Brand{ID: brand}, CurrentPrice: decimal.NewFromInt(price)}, Quantity: 1}}
+	}
+	explain := func(t *testing.T, cart []models.CartItem) *models.Explanation {
+		t.Helper()
+		explanation, err := service.ExplainCartDiscounts(ctx, &models.CalculationRequest{CartItems: cart})
+		require.NoError(t, err)
+		return explanation
+	}
+
+	tests := []struct {
+		name      string
+		cart      []models.CartItem
+		final     int64
+		reason    models.DecisionReason
+		spendMore int64 // Zero when the highest tier is reached
+	}{
+		{"Below every tier", cart("NIKE", 1500), 1500, models.ReasonSpendTierNotReached, 500},
+		{"First tier", cart("NIKE", 3000), 2800, "", 2000},
+		{"Highest tier", cart("NIKE", 6000), 5400, "", 0},
+		{"Brand discount drops the subtotal below a tier", cart("PUMA", 5500), 4950 - 200, "", 50},
+		{"Brand discount drops the subtotal below every tier", cart("PUMA", 2100), 1890, models.ReasonSpendTierNotReached, 110},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := explain(t, tt.cart)
+			assert.True(t, decimal.NewFromInt(tt.final).Equal(e.Result.FinalPrice), "got %s", e.Result.FinalPrice)
+
+			decision, ok := e.Decision(tiers.ID)
+			require.True(t, ok)
+			assert.Equal(t, tt.reason, decision.Reason)
+
+			if tt.spendMore == 0 {
+				assert.Empty(t, e.Result.SpendHints)
+				return
+			}
+			require.Len(t, e.Result.SpendHints, 1)
+			hint := e.Result.SpendHints[0]
+			assert.Equal(t, tiers.ID, hint.DiscountID)
+			assert.True(t, decimal.NewFromInt(tt.spendMore).Equal(hint.SpendMore), "got %s", hint.SpendMore)
+			assert.NotEmpty(t, hint.Message)
+		})
+	}
+
+	t.Run("Invalid tiers are rejected", func(t *testing.T) {
+		tier := func(threshold, value int64) models.SpendTier {
+			return models.SpendTier{Threshold: decimal.NewFromInt(threshold), Value: decimal.NewFromInt(value)}
+		}
+		for _, invalid := range []*models.Discount{
+			{ID: "no-tiers", Type: models.DiscountTypeSpendTier},
+			{ID: "unordered", Type: models.DiscountTypeSpendTier, SpendTiers: []models.SpendTier{tier(5000, 600), tier(2000, 200)}},
+			{ID: "shrinking", Type: models.DiscountTypeSpendTier, SpendTiers: []models.SpendTier{tier(2000, 600), tier(5000, 200)}},
+			{ID: "over-100", Type: models.DiscountTypeSpendTier, IsPercentage: true, SpendTiers: []models.SpendTier{tier(2000, 150)}},
+			{ID: "voucher", Type: models.DiscountTypeVoucher, SpendTiers: []models.SpendTier{tier(2000, 200)}},
+		} {
+			assert.True(t, errors.IsValidationError(repo.CreateDiscount(ctx, invalid)), invalid.ID)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/discount"
+	"github.com/ahsmha/discounts/internal/models"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/discounttest"
+)
+
+func TestDiscountService_CustomStrategies(t *testing.T) {
+	ctx := context.Background()
+	const staffPerk models.DiscountType = "staff_perk"
+
+	// Staff take the discount's percentage off, other customers get nothing
+	staff := &discounttest.Strategy{
+		IsApplicableFunc: func(d *models.Discount, cart []models.CartItem, customer models.CustomerProfile, payment *models.PaymentInfo) bool {
+			return customer.Tier == "staff"
+		},
+		CalculateFunc: func(d *models.Discount, cart []models.CartItem, currentTotal decimal.Decimal) decimal.Decimal {
+			return currentTotal.Mul(d.Value).Div(decimal.NewFromInt(models.PercentageBase))
+		},
+	}
+	repo := discounttest.NewRepository(t, discounttest.Discount("staff", staffPerk).Percent("30").Build())
+	cart := discounttest.Cart().Add(discounttest.Product("tee", "PUMA", "tshirts", "1000"), 1)
+
+	t.Run("Registered types are priced", func(t *testing.T) {
+		svc := services.NewDiscountService(repo, services.WithStrategy(staffPerk, staff))
+
+		result, err := svc.CalculateCart(ctx, cart.For(models.CustomerProfile{ID: "c1", Tier: "staff"}).Request())
+		require.NoError(t, err)
+		assert.True(t, result.FinalPrice.Equal(decimal.NewFromInt(700)), result.FinalPrice.String())
+
+		result, err = svc.CalculateCart(ctx, cart.For(models.CustomerProfile{ID: "c2"}).Request())
+		require.NoError(t, err)
+		assert.True(t, result.FinalPrice.Equal(decimal.NewFromInt(1000)))
+	})
+
+	t.Run("Other services do not see the registration", func(t *testing.T) {
+		explanation, err := services.NewDiscountService(repo).ExplainCartDiscounts(ctx,
+			cart.For(models.CustomerProfile{ID: "c1", Tier: "staff"}).Request())
+		require.NoError(t, err)
+		decision, ok := explanation.Decision("staff")
+		require.True(t, ok)
+		assert.Equal(t, models.ReasonUnsupportedType, decision.Reason)
+	})
+
+	t.Run("Registrations replace built-in strategies", func(t *testing.T) {
+		repo := discounttest.NewRepository(t, discounttest.Discount("puma", models.DiscountTypeBrand).Percent("50").On("PUMA").Build())
+		svc := services.NewDiscountService(repo, services.WithStrategy(models.DiscountTypeBrand, discounttest.FixedStrategy("100")))
+
+		result, err := svc.CalculateCart(ctx, cart.Request())
+		require.NoError(t, err)
+		assert.True(t, result.FinalPrice.Equal(decimal.NewFromInt(900)), result.FinalPrice.String())
+	})
+
+	t.Run("Invalid registrations are refused", func(t *testing.T) {
+		factory := discount.NewStrategyFactory()
+		assert.Error(t, factory.Register("", staff))
+		assert.Error(t, factory.Register(staffPerk, nil))
+		assert.Nil(t, factory.Get(staffPerk))
+		require.NoError(t, factory.Register(staffPerk, staff))
+		assert.Same(t, staff, factory.Get(staffPerk))
+
+		assert.Panics(t, func() { services.WithStrategy(staffPerk, nil) })
+	})
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscountService_TargetSelection(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	line := func(id, brand string, price int64, quantity int) models.CartItem {
+		return models.CartItem{
+			Product:  models.Product{ID: id, Brand: models.Brand{ID: brand}, CurrentPrice: decimal.NewFromInt(price)},
+			Quantity: quantity,
+		}
+	}
+	cart := []models.CartItem{
+		line("shoes", "PUMA", 1000, 1),
+		line("tee-b", "PUMA", 300, 1),
+		line("tee-a", "PUMA", 300, 2),
+		line("socks", "NIKE", 100, 1),
+	}
+
+	price := func(t *testing.T, d *models.Discount, cart []models.CartItem) *models.Explanation {
+		t.Helper()
+		d.ID, d.Name, d.IsPercentage = "promo", "promo", true
+		d.ValidFrom, d.ValidTo, d.IsActive = now.Add(-time.Hour), now.Add(time.Hour), true
+		repo := repository.NewInMemoryDiscountRepository()
+		require.NoError(t, repo.CreateDiscount(ctx, d))
+		service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+		explanation, err := service.ExplainCartDiscounts(ctx, &models.CalculationRequest{CartItems: cart})
+		require.NoError(t, err)
+		return explanation
+	}
+	discounted := func(e *models.Explanation) decimal.Decimal {
+		return e.Result.OriginalPrice.Sub(e.Result.FinalPrice)
+	}
+
+	t.Run("Cheapest item free when buying 3", func(t *testing.T) {
+		promo := &models.Discount{Type: models.DiscountTypeVoucher, Value: decimal.NewFromInt(100),
+			TargetSelection: models.TargetCheapestItem, MinQuantity: 3}
+		e := price(t, promo, cart)
+		assert.True(t, decimal.NewFromInt(100).Equal(discounted(e)), "the socks, got %s", discounted(e))
+
+		promo = &models.Discount{Type: models.DiscountTypeVoucher, Value: decimal.NewFromInt(100),
+			TargetSelection: models.TargetCheapestItem, MinQuantity: 3}
+		e = price(t, promo, cart[:2])
+		decision, ok := e.Decision("promo")
+		require.True(t, ok)
+		assert.Equal(t, models.ReasonMinQuantityNotMet, decision.Reason)
+	})
+
+	t.Run("Equally priced cheapest units", func(t *testing.T) {
+		promo := &models.Discount{Type: models.DiscountTypeBrand, Value: decimal.NewFromInt(50), ApplicableTo: []string{"PUMA"},
+			TargetSelection: models.TargetCheapestItem}
+		e := price(t, promo, cart)
+		require.Len(t, e.Result.Breakdown, 1)
+		assert.True(t, decimal.NewFromInt(150).Equal(discounted(e)), "half of a tee, got %s", discounted(e))
+	})
+
+	t.Run("Most expensive item", func(t *testing.T) {
+		promo := &models.Discount{Type: models.DiscountTypeBrand, Value: decimal.NewFromInt(20), ApplicableTo: []string{"PUMA"},
+			TargetSelection: models.TargetMostExpensiveItem}
+		e := price(t, promo, cart)
+		assert.True(t, decimal.NewFromInt(200).Equal(discounted(e)), "20%% of the shoes, got %s", discounted(e))
+	})
+
+	t.Run("Cheapest N", func(t *testing.T) {
+		promo := &models.Discount{Type: models.DiscountTypeBrand, Value: decimal.NewFromInt(50), ApplicableTo: []string{"PUMA"},
+			TargetSelection: models.TargetCheapestN, TargetCount: 3}
+		e := price(t, promo, cart)
+		assert.True(t, decimal.NewFromInt(450).Equal(discounted(e)), "half of the three tees, got %s", discounted(e))
+
+		promo = &models.Discount{Type: models.DiscountTypeBrand, Value: decimal.NewFromInt(50), ApplicableTo: []string{"PUMA"},
+			TargetSelection: models.TargetCheapestN, TargetCount: 3, MaxUnitsPerCart: 2}
+		e = price(t, promo, cart)
+		assert.True(t, decimal.NewFromInt(300).Equal(discounted(e)), "the unit limit still applies, got %s", discounted(e))
+	})
+
+	t.Run("Invalid selections are rejected", func(t *testing.T) {
+		repo := repository.NewInMemoryDiscountRepository()
+		for _, invalid := range []*models.Discount{
+			{ID: "unknown", Type: models.DiscountTypeBrand, TargetSelection: "RANDOM"},
+			{ID: "no-count", Type: models.DiscountTypeBrand, TargetSelection: models.TargetCheapestN},
+			{ID: "stray-count", Type: models.DiscountTypeBrand, TargetSelection: models.TargetCheapestItem, TargetCount: 2},
+			{ID: "negative", Type: models.DiscountTypeCategory, MinQuantity: -1},
+			{ID: "bank", Type: models.DiscountTypeBank, TargetSelection: models.TargetCheapestItem},
+		} {
+			assert.True(t, errors.IsValidationError(repo.CreateDiscount(ctx, invalid)), invalid.ID)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscountService_Tax(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	cart := []models.CartItem{
+		{Product: models.Product{ID: "shirt", Brand: models.Brand{ID: "PUMA"}, CurrentPrice: decimal.NewFromInt(1000), TaxRate: decimal.NewFromInt(18)}, Quantity: 1},
+		{Product: models.Product{ID: "book", Brand: models.Brand{ID: "PENGUIN"}, CurrentPrice: decimal.NewFromInt(250), TaxRate: decimal.NewFromInt(5)}, Quantity: 2},
+	}
+	price := func(t *testing.T, voucherAfterTax bool) *models.DiscountedPrice {
+		ctx := context.Background()
+		repo := repository.NewInMemoryDiscountRepository()
+		for _, d := range []*models.Discount{
+			{ID: "puma-10", Name: "PUMA 10%", Type: models.DiscountTypeBrand, ApplicableTo: []string{"PUMA"}, Value: decimal.NewFromInt(10), IsPercentage: true, Priority: 1},
+			{ID: "flat-150", Name: "Flat 150", Type: models.DiscountTypeVoucher, Value: decimal.NewFromInt(150), AppliesAfterTax: voucherAfterTax},
+		} {
+			d.ValidFrom, d.ValidTo, d.IsActive = now.Add(-time.Hour), now.Add(time.Hour), true
+			require.NoError(t, repo.CreateDiscount(ctx, d))
+		}
+		result, err := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now))).
+			CalculateCart(ctx, &models.CalculationRequest{CartItems: cart})
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(1250).Equal(result.FinalPrice), "got %s", result.FinalPrice)
+		assert.True(t, result.FinalPrice.Add(result.TotalTax).Equal(result.AmountDue))
+		return result
+	}
+
+	t.Run("Discounts applying after tax leave taxable amounts alone", func(t *testing.T) {
+		result := price(t, true)
+		require.Len(t, result.TaxLines, 2)
+		book, shirt := result.TaxLines[0], result.TaxLines[1]
+		assert.True(t, decimal.NewFromInt(500).Equal(book.TaxableAmount), "got %s", book.TaxableAmount)
+		assert.True(t, decimal.NewFromInt(25).Equal(book.Tax), "got %s", book.Tax)
+		assert.True(t, decimal.NewFromInt(900).Equal(shirt.TaxableAmount), "pre-tax brand discounts lower the taxable amount, got %s", shirt.TaxableAmount)
+		assert.True(t, decimal.NewFromInt(162).Equal(shirt.Tax), "got %s", shirt.Tax)
+		assert.True(t, decimal.NewFromInt(187).Equal(result.TotalTax), "got %s", result.TotalTax)
+	})
+
+	t.Run("Discounts applying before tax are spread over the lines they target", func(t *testing.T) {
+		result := price(t, false)
+		require.Len(t, result.TaxLines, 2)
+		book, shirt := result.TaxLines[0], result.TaxLines[1]
+		assert.True(t, decimal.RequireFromString("446.43").Equal(book.TaxableAmount), "got %s", book.TaxableAmount)
+		assert.True(t, decimal.RequireFromString("22.32").Equal(book.Tax), "got %s", book.Tax)
+		assert.True(t, decimal.RequireFromString("803.57").Equal(shirt.TaxableAmount), "got %s", shirt.TaxableAmount)
+		assert.True(t, decimal.RequireFromString("144.64").Equal(shirt.Tax), "got %s", shirt.Tax)
+		assert.True(t, decimal.RequireFromString("166.96").Equal(result.TotalTax), "got %s", result.TotalTax)
+	})
+
+	t.Run("Untaxed carts report no tax", func(t *testing.T) {
+		result, err := services.NewDiscountService(repository.NewInMemoryDiscountRepository()).CalculateCartDiscounts(context.Background(),
+			[]models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(100)}, Quantity: 1}}, models.CustomerProfile{}, nil)
+		require.NoError(t, err)
+		assert.Empty(t, result.TaxLines)
+		assert.True(t, result.TotalTax.IsZero())
+		assert.True(t, decimal.NewFromInt(100).Equal(result.AmountDue))
+	})
+
+	t.Run("Tax rates are percentages", func(t *testing.T) {
+		_, err := services.NewDiscountService(repository.NewInMemoryDiscountRepository()).CalculateCartDiscounts(context.Background(),
+			[]models.CartItem{{Product: models.Product{ID: "p1", CurrentPrice: decimal.NewFromInt(100), TaxRate: decimal.NewFromInt(118)}, Quantity: 1}}, models.CustomerProfile{}, nil)
+		assert.True(t, errors.IsValidationError(err))
+	})
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/tenant"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestDiscountService_TenantIsolation(t *testing.T) {
+	discounts := testdata.GetSampleDiscounts()
+	for i := range discounts {
+		discounts[i].TenantID = "store-a"
+	}
+
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(discounts))
+	redemptions := repository.NewInMemoryRedemptionRepository()
+	service := services.NewDiscountService(repo, services.WithRedemptionRepository(redemptions))
+
+	cartItems, customer, paymentInfo := testdata.GetMultipleDiscountScenario()
+	storeA := tenant.NewContext(context.Background(), "store-a")
+	storeB := tenant.NewContext(context.Background(), "store-b")
+
+	result, err := service.CalculateCartDiscounts(storeA, cartItems, customer, paymentInfo)
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.AppliedDiscounts)
+
+	result, err = service.CalculateCartDiscounts(storeB, cartItems, customer, paymentInfo)
+	require.NoError(t, err)
+	assert.Empty(t, result.AppliedDiscounts)
+	assert.True(t, result.OriginalPrice.Equal(result.FinalPrice))
+
+	recordedA, err := redemptions.ListRedemptions(storeA, models.RedemptionFilter{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, recordedA)
+	recordedB, err := redemptions.ListRedemptions(storeB, models.RedemptionFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, recordedB)
+}
+
+func TestAPI_TenantHeader(t *testing.T) {
+	discounts := testdata.GetSampleDiscounts()
+	for i := range discounts {
+		discounts[i].TenantID = "store-a"
+	}
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(discounts))
+	h := api.NewHandler(services.NewDiscountService(repo))
+
+	cartItems, customer, paymentInfo := testdata.GetMultipleDiscountScenario()
+	body := map[string]any{"cart_items": cartItems, "customer": customer, "payment_info": paymentInfo}
+
+	_, withTenant := doJSON(t, h, "/v2/cart/calculate", http.Header{"X-Tenant-ID": {"store-a"}}, body)
+	_, without := doJSON(t, h, "/v2/cart/calculate", nil, body)
+
+	assert.NotEmpty(t, withTenant["discounts"])
+	assert.Empty(t, without["discounts"])
+	assert.Equal(t, without["original_price"], without["final_price"])
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+)
+
+func TestDiscountService_MaxUnitsPerCart(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	puma := &models.Discount{
+		ID:              "puma-two-units",
+		Name:            "20% off up to 2 PUMA units",
+		Type:            models.DiscountTypeBrand,
+		Value:           decimal.NewFromInt(20),
+		IsPercentage:    true,
+		ApplicableTo:    []string{"PUMA"},
+		MaxUnitsPerCart: 2,
+		ValidFrom:       now.Add(-time.Hour),
+		ValidTo:         now.Add(time.Hour),
+		IsActive:        true,
+	}
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.CreateDiscount(ctx, puma))
+	service := services.NewDiscountService(repo, services.WithClock(clock.NewFixed(now)))
+
+	item := func(id, brand string, price int64, quantity int) models.CartItem {
+		return models.CartItem{
+			Product:  models.Product{ID: id, Brand: models.Brand{ID: brand}, CurrentPrice: decimal.NewFromInt(price)},
+			Quantity: quantity,
+		}
+	}
+	cart := []models.CartItem{
+		item("shoes", "PUMA", 1000, 1),
+		item("socks", "NIKE", 200, 3),
+		item("tee", "PUMA", 500, 3),
+	}
+	result, err := service.CalculateCartDiscounts(ctx, cart, models.CustomerProfile{}, nil)
+	require.NoError(t, err)
+	// The shoes and one tee are discounted: 20% of 1500
+	assert.True(t, decimal.NewFromInt(300).Equal(result.Breakdown[0].Amount), "got %s", result.Breakdown[0].Amount)
+
+	result, err = service.CalculateCartDiscounts(ctx, cart[1:], models.CustomerProfile{}, nil)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(200).Equal(result.Breakdown[0].Amount), "two of the three tees, got %s", result.Breakdown[0].Amount)
+
+	for _, invalid := range []*models.Discount{
+		{ID: "negative", Type: models.DiscountTypeBrand, MaxUnitsPerCart: -1},
+		{ID: "voucher", Type: models.DiscountTypeVoucher, MaxUnitsPerCart: 2},
+	} {
+		assert.True(t, errors.IsValidationError(repo.CreateDiscount(ctx, invalid)), invalid.ID)
+	}
+}
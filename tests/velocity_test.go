@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/api"
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/services"
+	"github.com/ahsmha/discounts/pkg/clientip"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/errors"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+func TestAttemptCounters(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	start := time.Now()
+	advance := map[string]func(ctx context.Context, d time.Duration) context.Context{
+		"memory": func(ctx context.Context, d time.Duration) context.Context {
+			return clock.NewContext(ctx, clock.FromContext(ctx).Add(d))
+		},
+		"redis": func(ctx context.Context, d time.Duration) context.Context {
+			server.FastForward(d)
+			return ctx
+		},
+	}
+	for name, counter := range map[string]interfaces.IAttemptCounter{
+		"memory": repository.NewInMemoryAttemptCounter(),
+		"redis":  repository.NewRedisAttemptCounter(client, "test:"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := clock.NewContext(context.Background(), start)
+
+			for i := int64(1); i <= 3; i++ {
+				count, resetIn, err := counter.Hit(ctx, "k", time.Minute)
+				require.NoError(t, err)
+				assert.Equal(t, i, count)
+				assert.InDelta(t, time.Minute, resetIn, float64(time.Second))
+			}
+
+			count, _, err := counter.Hit(ctx, "other", time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), count, "keys are counted apart")
+
+			ctx = advance[name](ctx, time.Minute+time.Second)
+			count, _, err = counter.Hit(ctx, "k", time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), count, "a new window starts once the last expired")
+		})
+	}
+}
+
+func TestDiscountService_CodeValidationLimits(t *testing.T) {
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(testdata.GetSampleDiscounts()))
+
+	fixed := clock.NewFixed(time.Now())
+	service := services.NewDiscountService(repo,
+		services.WithClock(fixed),
+		services.WithCodeValidationLimits(repository.NewInMemoryAttemptCounter(), services.CodeValidationLimits{
+			PerCustomer: services.VelocityLimit{MaxAttempts: 3, Window: time.Minute},
+			PerIP:       services.VelocityLimit{MaxAttempts: 5, Window: time.Minute},
+		}))
+
+	cartItems := testdata.GetSampleCartItems()
+	customers := testdata.GetSampleCustomers()
+
+	t.Run("Per customer", func(t *testing.T) {
+		ctx := context.Background()
+		for i := 0; i < 3; i++ {
+			_, err := service.ValidateDiscountCode(ctx, "GUESS", cartItems, customers[0])
+			require.NoError(t, err)
+		}
+		_, err := service.ValidateDiscountCode(ctx, "SUPER69", cartItems, customers[0])
+		require.True(t, errors.IsTooManyAttemptsError(err))
+		retryAfter, ok := errors.RetryAfter(err)
+		require.True(t, ok)
+		assert.Equal(t, time.Minute, retryAfter)
+
+		fixed.Advance(time.Minute)
+		_, err = service.ValidateDiscountCode(ctx, "SUPER69", cartItems, customers[0])
+		assert.NoError(t, err, "the limit resets with its window")
+	})
+
+	t.Run("Per address across customers", func(t *testing.T) {
+		ctx := clientip.NewContext(context.Background(), "203.0.113.7")
+		for i := 0; i < 5; i++ {
+			_, err := service.ValidateDiscountCode(ctx, "GUESS", cartItems, customers[1+i%2])
+			require.NoError(t, err)
+		}
+		_, err := service.ValidateDiscountCode(ctx, "GUESS", cartItems, models.CustomerProfile{ID: "cust-new"})
+		assert.True(t, errors.IsTooManyAttemptsError(err), "a fresh customer from the same address is limited")
+
+		_, err = service.ValidateDiscountCode(clientip.NewContext(context.Background(), "198.51.100.1"),
+			"GUESS", cartItems, customers[2])
+		assert.NoError(t, err)
+	})
+
+	t.Run("API answers 429 with Retry-After", func(t *testing.T) {
+		h := api.NewHandler(service)
+		body := map[string]any{"code": "GUESS", "cart_items": cartItems, "customer": models.CustomerProfile{ID: "cust-api"}}
+
+		var rec *httptest.ResponseRecorder
+		for i := 0; i < 4; i++ {
+			rec, _ = doJSON(t, h, "/v2/codes/validate", nil, body)
+		}
+		require.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+		assert.True(t, strings.Contains(rec.Body.String(), "too many"))
+	})
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ahsmha/discounts/internal/interfaces"
+	"github.com/ahsmha/discounts/internal/models"
+	repository "github.com/ahsmha/discounts/internal/repositories"
+	"github.com/ahsmha/discounts/internal/warehouse"
+	"github.com/ahsmha/discounts/pkg/clock"
+	"github.com/ahsmha/discounts/pkg/tenant"
+	"github.com/ahsmha/discounts/testdata"
+)
+
+// memorySink keeps exported objects by path
+type memorySink struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *memorySink) Put(ctx context.Context, path string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.objects == nil {
+		s.objects = make(map[string][]byte)
+	}
+	s.objects[path] = data
+	return nil
+}
+
+func TestWarehouseExport(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, time.November, 29, 0, 0, 0, 0, time.UTC)
+
+	repo := repository.NewInMemoryDiscountRepository()
+	require.NoError(t, repo.(*repository.InMemoryDiscountRepository).SeedDiscounts(testdata.GetSampleDiscounts()))
+	redemptions := repository.NewInMemoryRedemptionRepository()
+	for _, r := range []models.Redemption{
+		{DiscountID: "disc-001", CustomerID: "cust-001", Amount: decimal.NewFromInt(800), RedeemedAt: day.Add(-time.Minute)},
+		{DiscountID: "disc-001", CustomerID: "cust-002", OrderID: "ord-1", Code: "SAVE", Amount: decimal.NewFromInt(400),
+			OrderTotal: decimal.NewFromInt(3600), RedeemedAt: day.Add(9 * time.Hour)},
+		{DiscountID: "disc-002", CustomerID: "cust-002", Amount: decimal.NewFromInt(100), RedeemedAt: day.Add(23 * time.Hour)},
+		{DiscountID: "disc-002", CustomerID: "cust-003", Amount: decimal.NewFromInt(100), RedeemedAt: day.AddDate(0, 0, 1)},
+	} {
+		require.NoError(t, redemptions.RecordRedemption(ctx, r))
+	}
+
+	dir := t.TempDir()
+	exporter := &warehouse.Exporter{
+		Discounts:   repo.(interfaces.DiscountLister),
+		Redemptions: redemptions,
+		Sink:        warehouse.DirSink{Root: dir},
+		Prefix:      "exports/",
+		Clock:       clock.NewFixed(day.AddDate(0, 0, 1).Add(time.Hour)),
+	}
+
+	manifest, err := exporter.ExportDay(ctx, day.Add(15*time.Hour))
+	require.NoError(t, err)
+	assert.True(t, day.Equal(manifest.Day))
+	assert.Equal(t, []warehouse.File{
+		{Path: "exports/discounts/dt=2024-11-29/part-0000.csv", Rows: len(testdata.GetSampleDiscounts())},
+		{Path: "exports/redemptions/dt=2024-11-29/part-0000.csv", Rows: 2},
+	}, manifest.Files)
+
+	readCSV := func(path string) [][]string {
+		t.Helper()
+		f, err := os.Open(filepath.Join(dir, path))
+		require.NoError(t, err)
+		defer f.Close()
+		records, err := csv.NewReader(f).ReadAll()
+		require.NoError(t, err)
+		return records
+	}
+
+	t.Run("Partitions follow the schema", func(t *testing.T) {
+		records := readCSV(manifest.Files[1].Path)
+		require.Len(t, records, 3)
+		assert.Equal(t, []string{"discount_id", "customer_id", "order_id", "amount", "redeemed_at",
+			"campaign_id", "code", "order_total", "discount_ref"}, records[0])
+		assert.Equal(t, []string{"disc-001", "cust-002", "ord-1", "400", "2024-11-29T09:00:00Z", "", "SAVE", "3600", ""}, records[1])
+
+		discounts := readCSV(manifest.Files[0].Path)
+		require.Len(t, discounts[0], len(warehouse.DiscountsTable.Columns))
+		for _, row := range discounts[1:] {
+			assert.Len(t, row, len(warehouse.DiscountsTable.Columns))
+			assert.Equal(t, "2024-11-30T01:00:00Z", row[len(row)-2], "snapshot_at")
+			assert.Equal(t, string(models.NewDiscountRef("", row[0])), row[len(row)-1], "ref")
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, "exports/redemptions/_schema.json"))
+		require.NoError(t, err)
+		var schema warehouse.Table
+		require.NoError(t, json.Unmarshal(data, &schema))
+		assert.Equal(t, warehouse.RedemptionsTable, schema)
+	})
+
+	t.Run("Tenants get their own partitions", func(t *testing.T) {
+		manifest, err := exporter.ExportDay(tenant.NewContext(ctx, "store-b"), day)
+		require.NoError(t, err)
+		assert.Equal(t, "exports/redemptions/tenant=store-b/dt=2024-11-29/part-0000.csv", manifest.Files[1].Path)
+		assert.Zero(t, manifest.Files[1].Rows)
+	})
+
+	t.Run("Run exports the last complete day", func(t *testing.T) {
+		sink := &memorySink{}
+		scheduled := *exporter
+		scheduled.Sink = sink
+		scheduled.OnError = func(day time.Time, err error) { t.Errorf("export of %s failed: %v", day, err) }
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.ErrorIs(t, scheduled.Run(cancelled, time.Hour), context.Canceled)
+		assert.Contains(t, sink.objects, "exports/redemptions/dt=2024-11-29/part-0000.csv")
+	})
+}